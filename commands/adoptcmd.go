@@ -0,0 +1,162 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// AdoptRunner encapsulates fields for the kpt live adopt command.
+type AdoptRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider provider.Provider
+	loader   manifestreader.ManifestLoader
+	dryRun   bool
+	force    bool
+}
+
+// GetAdoptRunner returns a pointer to an initial AdoptRunner structure.
+func GetAdoptRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *AdoptRunner {
+	r := &AdoptRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "adopt [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Take ownership of existing cluster resources"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().BoolVar(&r.dryRun, "dry-run", false,
+		"Only print the resources which would be adopted.")
+	cmd.Flags().BoolVar(&r.force, "force", false,
+		"Also adopt resources owned by other inventories.")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdAdopt returns the cobra command for the adopt command.
+func NewCmdAdopt(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetAdoptRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE adopts the cluster resources of the package in the passed
+// directory (or stdin) into its inventory, and stores the adopted
+// resources in the inventory.
+func (r *AdoptRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; adopt requires one directory argument (or stdin)")
+	}
+	return adoptPackage(cmd, r.ioStreams.Out, r.provider, r.loader, args, r.force, r.dryRun)
+}
+
+// adoptPackage adopts the objects of the package which already exist in
+// the cluster, prints the outcome for every object, and merges the
+// adopted objects into the inventory of the package.
+func adoptPackage(cmd *cobra.Command, out io.Writer, provider provider.Provider,
+	loader manifestreader.ManifestLoader, args []string, force, dryRun bool) error {
+	reader, err := loader.ManifestReader(cmd.InOrStdin(), args)
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	inv, objs, err := loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(provider.Factory(), dryRun)
+	if err != nil {
+		return err
+	}
+	results, adoptErr := live.Adopt(context.Background(), c, inv, objs, force)
+	printAdoptResults(out, results)
+	if adoptErr != nil {
+		if _, ok := adoptErr.(live.AdoptError); ok {
+			return fmt.Errorf("%s; use --force to adopt them", adoptErr)
+		}
+		return adoptErr
+	}
+	adopted := live.AdoptedObjs(results)
+	if len(adopted) == 0 {
+		return nil
+	}
+	invClient, err := provider.InventoryClient()
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		invClient.SetDryRunStrategy(common.DryRunClient)
+	}
+	_, err = invClient.Merge(inv, adopted)
+	return err
+}
+
+// printAdoptResults prints the outcome of adopting every object,
+// followed by a summary.
+func printAdoptResults(w io.Writer, results []live.AdoptResult) {
+	counts := map[live.AdoptStatus]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		name := fmt.Sprintf("%s/%s/%s", r.Object.GroupKind.Kind, r.Object.Namespace, r.Object.Name)
+		switch r.Status {
+		case live.Adopted:
+			if r.Owner != "" {
+				fmt.Fprintf(w, "%s adopted from inventory %q\n", name, r.Owner)
+			} else {
+				fmt.Fprintf(w, "%s adopted\n", name)
+			}
+		case live.AlreadyOwned:
+			fmt.Fprintf(w, "%s already owned\n", name)
+		case live.NotFound:
+			fmt.Fprintf(w, "%s not found\n", name)
+		case live.OwnedByOther:
+			fmt.Fprintf(w, "%s owned by inventory %q\n", name, r.Owner)
+		}
+	}
+	fmt.Fprintf(w, "%d adopted, %d already owned, %d not found, %d owned by other inventories\n",
+		counts[live.Adopted], counts[live.AlreadyOwned], counts[live.NotFound], counts[live.OwnedByOther])
+}
+
+// adoptObjects adopts the objects of the package in the passed directory
+// which exist in the target cluster without an owning inventory, if
+// --adopt is set. Dry runs only validate the adoption.
+func (w *ApplyRunnerWrapper) adoptObjects(cmd *cobra.Command, t applyTarget, args []string) error {
+	if !w.adopt {
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("--adopt requires a package directory")
+	}
+	return adoptPackage(cmd, t.out, t.provider, w.manifestLoader, args, false, isDryRun(cmd))
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrintAdoptResults(t *testing.T) {
+	results := []live.AdoptResult{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: live.Adopted},
+		{Object: object.UnstructuredToObjMeta(pod2), Status: live.OwnedByOther, Owner: "other-inv"},
+	}
+	var out bytes.Buffer
+	printAdoptResults(&out, results)
+	assert.Equal(t, "Pod/"+testNamespace+"/pod-1 adopted\n"+
+		"Pod/"+testNamespace+"/pod-2 owned by inventory \"other-inv\"\n"+
+		"1 adopted, 0 already owned, 0 not found, 1 owned by other inventories\n", out.String())
+}
+
+func TestAdoptObjects(t *testing.T) {
+	testCases := map[string]struct {
+		dryRun        string
+		expectedOwner bool
+	}{
+		"Objects are adopted": {
+			dryRun:        "false",
+			expectedOwner: true,
+		},
+		"Objects are not updated with --dry-run": {
+			dryRun:        "true",
+			expectedOwner: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			dir := podPackage(t)
+			tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+			defer tf.Cleanup()
+
+			c := client.NewFakeClient(pod1)
+			defer func(f func(cmdutil.Factory, bool) (client.Client, error)) { newClient = f }(newClient)
+			newClient = func(_ cmdutil.Factory, dryRun bool) (client.Client, error) {
+				return c.WithDryRun(dryRun), nil
+			}
+
+			var dryRun string
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVar(&dryRun, "dry-run", "", "")
+			assert.NoError(t, cmd.Flags().Set("dry-run", tc.dryRun))
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			ioStreams, _, _, _ := genericclioptions.NewTestIOStreams() //nolint:dogsled
+			w := GetApplyRunner(live.NewFakeResourceGroupProvider(tf, nil),
+				live.NewDualDelegatingManifestReader(tf), ioStreams)
+			w.adopt = true
+
+			if !assert.NoError(t, w.adoptObjects(cmd, w.defaultTarget(cmd), []string{dir})) {
+				t.FailNow()
+			}
+			assert.Contains(t, out.String(), "Pod/"+testNamespace+"/pod-1 adopted")
+			obj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(pod1))
+			assert.NoError(t, err)
+			_, found := client.OwningInventory(obj, client.DefaultOwningInventoryKeys)
+			assert.Equal(t, tc.expectedOwner, found)
+		})
+	}
+}
+
+// podPackage returns the directory of a package with a ConfigMap inventory
+// and pod1.
+func podPackage(t *testing.T) string {
+	return pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("app").
+		WithFile("inventory-template.yaml", cmInvStr).
+		WithFile("pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-1
+  namespace: `+testNamespace+`
+`))
+}
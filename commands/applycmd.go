@@ -4,17 +4,27 @@
 package commands
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"github.com/GoogleContainerTools/kpt/internal/util/readiness"
+	"github.com/GoogleContainerTools/kpt/internal/util/report"
 	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/live"
 	"github.com/spf13/cobra"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/cmd/apply"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/cli-utils/pkg/provider"
 )
 
@@ -23,8 +33,44 @@ import (
 func GetApplyRunner(provider provider.Provider, loader manifestreader.ManifestLoader, ioStreams genericclioptions.IOStreams) *ApplyRunnerWrapper {
 	applyRunner := apply.GetApplyRunner(provider, loader, ioStreams)
 	w := &ApplyRunnerWrapper{
-		applyRunner: applyRunner,
-		factory:     provider.Factory(),
+		applyRunner:    applyRunner,
+		factory:        provider.Factory(),
+		provider:       provider,
+		manifestLoader: loader,
+	}
+	// Apply waves are only supported by the loader which can filter
+	// the objects by wave.
+	if l, ok := loader.(*live.DualDelegatingManifestReader); ok {
+		w.loader = l
+	}
+	applyRunner.Command.Flags().StringVar(&w.planFile, "plan", "",
+		"Apply the plan computed by kpt live plan, failing if the cluster changed since")
+	applyRunner.Command.Flags().BoolVar(&w.adopt, "adopt", false,
+		"Take ownership of existing resources which are not owned by any inventory")
+	applyRunner.Command.Flags().StringVar(&w.ownershipPolicy, "ownership-policy", "",
+		"What to do with resources owned by another inventory: fail, skip, adopt-if-abandoned or force-adopt")
+	applyRunner.Command.Flags().StringVar(&w.statusConfig, "status-config", "",
+		"File declaring status readers, in addition to the ones in the Kptfile")
+	applyRunner.Command.Flags().IntVar(&w.concurrency, "concurrency", live.DefaultConcurrency,
+		"Number of resources applied or pruned at the same time, respecting depends-on annotations")
+	applyRunner.Command.Flags().StringVar(&w.selector, "selector", "",
+		"Only apply the resources matching the label selector, without pruning")
+	applyRunner.Command.Flags().StringSliceVar(&w.kinds, "kind", nil,
+		"Only apply the resources of these kinds, without pruning")
+	applyRunner.Command.Flags().StringSliceVar(&w.names, "name", nil,
+		"Only apply the resources with these names, without pruning")
+	applyRunner.Command.Flags().StringSliceVar(&w.targetContexts, "target-context", nil,
+		"Kubeconfig context of a cluster to apply the package to; may be repeated")
+	applyRunner.Command.Flags().StringVar(&w.targetsFile, "targets-file", "",
+		"File listing the clusters to apply the package to")
+	applyRunner.Command.Flags().BoolVar(&w.force, "force", false,
+		"Prune resources of protected kinds, such as Namespaces and PersistentVolumeClaims, without confirmation")
+	applyRunner.Command.Flags().StringSliceVar(&w.errorOn, "error-on", live.DefaultExitConditions,
+		"Conditions which make the apply fail: reconcile-timeout, skipped or prune-failure")
+	applyRunner.Command.Flags().StringVar(&w.conflictStrategy, "conflict-strategy", "",
+		"What to do when applying a resource conflicts with another field manager: fail, force or skip")
+	if f := applyRunner.Command.Flags().Lookup("output"); f != nil {
+		f.Usage = "Output format, must be one of events, table, junit or sarif"
 	}
 	// Set the wrapper run to be the RunE function for the wrapped command.
 	applyRunner.Command.RunE = w.RunE
@@ -37,6 +83,74 @@ func GetApplyRunner(provider provider.Provider, loader manifestreader.ManifestLo
 type ApplyRunnerWrapper struct {
 	applyRunner *apply.ApplyRunner
 	factory     cmdutil.Factory
+	loader      *live.DualDelegatingManifestReader
+	planFile    string
+	adopt       bool
+
+	ownershipPolicy string
+
+	provider       provider.Provider
+	manifestLoader manifestreader.ManifestLoader
+
+	statusConfig string
+	statusRules  *live.StatusRules
+
+	concurrency int
+
+	targetContexts []string
+	targetsFile    string
+
+	selector string
+	kinds    []string
+	names    []string
+
+	force bool
+
+	// reportFormat is the report format of --output, or empty. The report
+	// is written to reportOut, and the progress to stderr.
+	reportFormat string
+	reportOut    io.Writer
+
+	errorOn    []string
+	exitPolicy live.ExitPolicy
+
+	conflictStrategy string
+	conflicts        live.ConflictStrategy
+}
+
+// applyTarget is a cluster the package is applied to, with the clients
+// and the loader for the cluster, and the writer for its output.
+type applyTarget struct {
+	factory  cmdutil.Factory
+	provider provider.Provider
+	loader   *live.DualDelegatingManifestReader
+	out      io.Writer
+	// context is the kubeconfig context of the cluster, or empty for
+	// the current context.
+	context string
+	// inventoryClient is the name of the InventoryClient storing the
+	// inventory, or empty for the cluster.
+	inventoryClient string
+}
+
+// invClient returns the InventoryClient storing the inventory of the
+// target.
+func (t applyTarget) invClient() (live.InventoryClient, error) {
+	return live.NewInventoryClient(t.inventoryClient, t.provider)
+}
+
+// defaultTarget returns the cluster selected by the kubeconfig flags of
+// the command.
+func (w *ApplyRunnerWrapper) defaultTarget(cmd *cobra.Command) applyTarget {
+	return applyTarget{
+		factory:  w.factory,
+		provider: w.provider,
+		loader:   w.loader,
+		out:      cmd.OutOrStdout(),
+		context:  flagValue(cmd, "context"),
+
+		inventoryClient: flagValue(cmd, inventoryClientFlag),
+	}
 }
 
 // Command returns the wrapped ApplyRunner cobraCommand structure.
@@ -44,27 +158,904 @@ func (w *ApplyRunnerWrapper) Command() *cobra.Command {
 	return w.applyRunner.Command
 }
 
+// PreRunE checks that the required setters of the package are set, and
+// that its readiness gates are met, except Reconciled which the apply
+// itself records.
 func (w *ApplyRunnerWrapper) PreRunE(_ *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		if err := setters.CheckForRequiredSetters(args[0]); err != nil {
 			return err
 		}
+		if err := readiness.Check(args[0], kptfile.ConditionReconciled); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// RunE runs the ResourceGroup CRD installation as a pre-step if an
-// environment variable exists. Then the wrapped ApplyRunner is
-// invoked. Returns an error if one happened. Swallows the
-// "AlreadyExists" error for CRD installation.
+// RunE installs the ResourceGroup CRD if the package uses a ResourceGroup
+// inventory, runs the pre-apply hooks, and applies the package with the
+// wrapped ApplyRunner or the ParallelApplier. Successful applies are
+// recorded in the inventory.
 func (w *ApplyRunnerWrapper) RunE(cmd *cobra.Command, args []string) error {
-	if _, exists := os.LookupEnv(resourceGroupEnv); exists {
-		klog.V(4).Infoln("wrapper applyRunner detected environment variable")
-		err := live.ApplyResourceGroupCRD(w.factory)
-		if err != nil && !apierrors.IsAlreadyExists(err) {
+	if err := w.setFilter(cmd); err != nil {
+		return err
+	}
+	exitPolicy, err := live.ParseExitPolicy(w.errorOn)
+	if err != nil {
+		return err
+	}
+	w.exitPolicy = exitPolicy
+	if err := w.setConflicts(cmd); err != nil {
+		return err
+	}
+	targets, err := live.ReadTargets(w.targetsFile, w.targetContexts)
+	if err != nil {
+		return err
+	}
+	if format := flagValue(cmd, "output"); report.IsFormat(format) {
+		if w.loader == nil || len(args) == 0 || len(targets) > 0 {
+			return fmt.Errorf("--output=%s requires a package directory, and no targets", format)
+		}
+		w.reportFormat, w.reportOut = format, cmd.OutOrStdout()
+		cmd.SetOut(cmd.ErrOrStderr())
+		defer cmd.SetOut(w.reportOut)
+	}
+	if len(targets) > 0 {
+		return w.runTargets(cmd, args, targets)
+	}
+	var rgInv *unstructured.Unstructured
+	var plan *live.Plan
+	invType, _ := cmd.Flags().GetString(inventoryTypeFlag)
+	if w.planFile != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("a package directory can not be applied with --plan")
+		}
+		plan, err = w.loadPlan(cmd)
+		if err != nil {
 			return err
 		}
+		if plan.Inventory.GetKind() == live.ResourceGroupGVK.Kind {
+			rgInv = plan.Inventory
+		}
+	} else if len(args) > 0 && invType != live.InventoryTypeSecret {
+		rgInv = live.ReadResourceGroupInventory(args[0])
+	}
+	_, exists := os.LookupEnv(resourceGroupEnv)
+	if exists || rgInv != nil {
+		klog.V(4).Infoln("wrapper applyRunner installing ResourceGroup CRD")
+		var namespace string
+		if rgInv != nil && !isDryRun(cmd) {
+			namespace = rgInv.GetNamespace()
+		}
+		if err := installResourceGroup(w.factory, cmd.OutOrStdout(), namespace); err != nil {
+			return err
+		}
+	}
+	if err := w.readStatusRules(args); err != nil {
+		return err
+	}
+	if err := w.runPreApplyHooks(cmd, w.defaultTarget(cmd), args, true); err != nil {
+		return err
 	}
 	klog.V(4).Infoln("wrapper applyRunner run...")
-	return w.applyRunner.RunE(cmd, args)
+	err = w.runWaves(cmd, args)
+	recordReconciled(cmd, args, err)
+	if err != nil {
+		return err
+	}
+	if rgInv != nil {
+		if err := w.updateInventoryStatus(rgInv); err != nil {
+			klog.Warningf("unable to update ResourceGroup inventory status: %s", err)
+		}
+	}
+	if !isDryRun(cmd) && !w.isPartial() {
+		if err := recordRevision(w.defaultTarget(cmd), args, plan); err != nil {
+			klog.Warningf("unable to record the apply revision: %s", err)
+		}
+	}
+	return nil
+}
+
+// preApplyHook is a step run before the package in the passed directory
+// is applied to the target. The apply fails if it returns an error.
+type preApplyHook func(cmd *cobra.Command, t applyTarget, args []string) error
+
+// runPreApplyHooks runs the hooks adopting resources, resolving their
+// ownership and protecting them from pruning, in this order. The prune
+// confirmation is only asked if interactive is true.
+func (w *ApplyRunnerWrapper) runPreApplyHooks(cmd *cobra.Command, t applyTarget, args []string,
+	interactive bool) error {
+	hooks := []preApplyHook{
+		w.adoptObjects,
+		w.resolveOwnership,
+		func(cmd *cobra.Command, t applyTarget, args []string) error {
+			return w.confirmPrune(cmd, t, args, interactive)
+		},
+	}
+	for _, hook := range hooks {
+		if err := hook(cmd, t, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordReconciled records the Reconciled condition in the Kptfile of the
+// applied package if it declares readiness gates, and the apply waited for
+// its resources to reconcile.
+func recordReconciled(cmd *cobra.Command, args []string, err error) {
+	timeout, parseErr := time.ParseDuration(flagValue(cmd, "reconcile-timeout"))
+	if len(args) == 0 || isDryRun(cmd) || parseErr != nil || timeout == 0 {
+		return
+	}
+	c := kptfile.StatusCondition{Type: kptfile.ConditionReconciled, Status: kptfile.ConditionTrue}
+	if err != nil {
+		c.Status, c.Reason, c.Message = kptfile.ConditionFalse, "ApplyFailed", err.Error()
+		if _, ok := err.(live.ReconcileTimeoutError); ok {
+			c.Reason = "ReconcileTimeout"
+		}
+	}
+	if err := readiness.Record(args[0], c); err != nil {
+		klog.Warningf("unable to record the Reconciled condition: %s", err)
+	}
+}
+
+// recordRevision records the applied objects of the package in the
+// passed directory, or of the plan, as a new revision of the inventory
+// in the target cluster. Objects read from stdin are not recorded.
+func recordRevision(t applyTarget, args []string, plan *live.Plan) error {
+	if t.loader == nil {
+		return nil
+	}
+	var objs []*unstructured.Unstructured
+	switch {
+	case plan != nil:
+		objs = append([]*unstructured.Unstructured{plan.Inventory}, plan.Objects...)
+	case len(args) > 0:
+		var err error
+		if objs, err = readPackageObjs(t.loader, args); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	invClient, err := t.invClient()
+	if err != nil {
+		return err
+	}
+	store, ok := invClient.(live.RevisionStore)
+	if !ok {
+		return nil
+	}
+	rev, err := live.RecordRevision(context.Background(), store, inv, objs, live.DefaultRevisionHistoryLimit)
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("recorded revision %d of inventory %s", rev.Number, inv.ID())
+	return nil
+}
+
+// setFilter limits the objects read by the loader to the ones selected
+// by --selector, --kind and --name. Pruning is disabled for such partial
+// applies, so the objects which are not selected are left untouched.
+func (w *ApplyRunnerWrapper) setFilter(cmd *cobra.Command) error {
+	filter, err := live.NewResourceFilter(w.selector, w.kinds, w.names)
+	if err != nil || filter == nil {
+		return err
+	}
+	if w.loader == nil {
+		return fmt.Errorf("--selector, --kind and --name are not supported by the manifest loader")
+	}
+	w.loader.Filter = filter
+	if f := cmd.Flags().Lookup("no-prune"); f != nil {
+		return f.Value.Set("true")
+	}
+	return nil
+}
+
+// isPartial returns true if only the objects of the package selected by
+// the filter are applied. Partial applies are not recorded as revisions.
+func (w *ApplyRunnerWrapper) isPartial() bool {
+	return w.loader != nil && w.loader.Filter != nil
+}
+
+// readStatusRules compiles the status readers declared in the Kptfile
+// of the package in the passed directory and in the status config file.
+func (w *ApplyRunnerWrapper) readStatusRules(args []string) error {
+	var dir string
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	readers, err := live.ReadStatusReaders(dir, w.statusConfig)
+	if err != nil {
+		return err
+	}
+	w.statusRules, err = live.NewStatusRules(readers)
+	return err
+}
+
+// loadPlan reads the plan file, verifies the cluster has not changed
+// since the plan was computed, and sets the manifests of the plan as
+// the input of the command. Returns the plan or an error.
+func (w *ApplyRunnerWrapper) loadPlan(cmd *cobra.Command) (*live.Plan, error) {
+	f, err := os.Open(w.planFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	plan, err := live.ReadPlan(f)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(w.factory, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := live.VerifyPlan(context.Background(), c, plan); err != nil {
+		return nil, err
+	}
+	manifests, err := plan.Manifests()
+	if err != nil {
+		return nil, err
+	}
+	cmd.SetIn(manifests)
+	return plan, nil
+}
+
+// updateInventoryStatus records the status of the objects in the passed
+// ResourceGroup inventory in the status of the ResourceGroup.
+func (w *ApplyRunnerWrapper) updateInventoryStatus(rgInv *unstructured.Unstructured) error {
+	d, err := w.factory.DynamicClient()
+	if err != nil {
+		return err
+	}
+	mapper, err := w.factory.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	return live.UpdateResourceGroupStatus(context.Background(), d, mapper,
+		live.WrapInventoryInfoObj(rgInv))
+}
+
+// defaultWaveReconcileTimeout is the time to wait for each apply wave to
+// reconcile, if --reconcile-timeout is not set.
+const defaultWaveReconcileTimeout = 5 * time.Minute
+
+// runWaves invokes the wrapped ApplyRunner for every dependency group of
+// every apply wave of the package, in increasing order. Every run applies
+// the objects of the current and earlier waves and groups without
+// pruning, and waits for them to reconcile. The last run applies all
+// objects with the flags passed by the user, so pruning happens only once
+// all waves are applied.
+func (w *ApplyRunnerWrapper) runWaves(cmd *cobra.Command, args []string) error {
+	if w.concurrency > 1 {
+		return w.runParallel(cmd, args)
+	}
+	if err := w.requireConcurrency(cmd); err != nil {
+		return err
+	}
+	objs, err := w.readObjs(args)
+	if err != nil {
+		return err
+	}
+	waves, err := live.Waves(objs)
+	if err != nil {
+		return err
+	}
+	steps, err := applySteps(objs, waves)
+	if err != nil {
+		return err
+	}
+	if w.loader != nil {
+		// Resolve the apply-time substitutions of every wave when it is
+		// applied, so earlier waves can be the source of mutations.
+		if w.loader.Substitution, err = applyTimeSubstitution(w.factory, flagValue(cmd, "context")); err != nil {
+			return err
+		}
+		defer func() { w.loader.Substitution = nil }()
+	}
+	endActuation, err := beginActuation(cmd, w.defaultTarget(cmd), objs)
+	if err != nil {
+		return err
+	}
+	printWaves := len(waves) > 1
+	last := len(steps) - 1
+	if last > 0 {
+		if err := w.runSteps(cmd, args, objs, steps[:last], printWaves); err != nil {
+			return err
+		}
+	}
+	if err := w.pruneStaleDependents(cmd, w.defaultTarget(cmd), objs); err != nil {
+		return err
+	}
+	if printWaves && steps[last-1].wave != steps[last].wave {
+		fmt.Fprintf(cmd.OutOrStdout(), "applying wave %d...\n", steps[last].wave)
+	}
+	if err := runApplyRunner(w.applyRunner, cmd, args); err != nil {
+		return err
+	}
+	if err := endActuation(); err != nil {
+		return err
+	}
+	return w.waitForStatus(cmd, objs)
+}
+
+// requireConcurrency returns an error naming the first flag which is set,
+// but only supported by the ParallelApplier. The wrapped ApplyRunner is
+// used unless --concurrency is greater than 1, and ignores these flags.
+func (w *ApplyRunnerWrapper) requireConcurrency(cmd *cobra.Command) error {
+	var flag, reason string
+	name := flagValue(cmd, inventoryClientFlag)
+	switch {
+	case w.reportFormat != "":
+		flag, reason = "--output="+w.reportFormat, "reports the outcome of every resource"
+	case cmd.Flags().Changed("error-on"):
+		flag, reason = "--error-on", "checks the outcome of every resource by the exit policy"
+	case w.conflicts == live.ConflictSkip:
+		flag, reason = "--conflict-strategy=skip", "skips the resources with conflicts"
+	case name != "" && name != live.ClusterInventoryClientName:
+		flag, reason = "--"+inventoryClientFlag+"="+name, "stores the inventory with an inventory client"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s requires --concurrency greater than 1, since only the parallel applier %s",
+		flag, reason)
+}
+
+// runApplyRunner runs the wrapped ApplyRunner. It is a variable so tests
+// can record the runs instead of applying to a cluster.
+var runApplyRunner = func(r *apply.ApplyRunner, cmd *cobra.Command, args []string) error {
+	return r.RunE(cmd, args)
+}
+
+// applyStep is a run of the wrapped ApplyRunner, which applies the
+// objects of the apply waves up to wave, and of their dependency groups
+// up to group.
+type applyStep struct {
+	wave  int
+	group int
+}
+
+// applySteps returns the runs applying the passed objects in order: one
+// for every dependency group of the objects of every apply wave and the
+// earlier waves.
+func applySteps(objs []*unstructured.Unstructured, waves []int) ([]applyStep, error) {
+	var steps []applyStep
+	for _, wave := range waves {
+		waveObjs, err := live.FilterByWave(objs, wave)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := live.SortByDependencies(waveObjs)
+		if err != nil {
+			return nil, err
+		}
+		for group := range groups {
+			steps = append(steps, applyStep{wave: wave, group: group})
+		}
+	}
+	return steps, nil
+}
+
+// runSteps invokes the wrapped ApplyRunner for the passed steps, without
+// pruning, waiting for the objects of every step to reconcile before the
+// next one. The wave of the steps is printed when it changes if
+// printWaves is true.
+func (w *ApplyRunnerWrapper) runSteps(cmd *cobra.Command, args []string,
+	objs []*unstructured.Unstructured, steps []applyStep, printWaves bool) error {
+	restore, err := setWaveFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		w.loader.MaxWave, w.loader.MaxGroup = nil, nil
+	}()
+	for i, step := range steps {
+		if printWaves && (i == 0 || step.wave != steps[i-1].wave) {
+			fmt.Fprintf(cmd.OutOrStdout(), "applying wave %d...\n", step.wave)
+		}
+		step := step
+		w.loader.MaxWave, w.loader.MaxGroup = &step.wave, &step.group
+		err := runApplyRunner(w.applyRunner, cmd, args)
+		if err == nil {
+			var stepObjs []*unstructured.Unstructured
+			if stepObjs, err = live.FilterByWave(objs, step.wave); err == nil {
+				if stepObjs, err = live.FilterByGroup(stepObjs, step.group); err == nil {
+					err = w.waitForStatus(cmd, stepObjs)
+				}
+			}
+		}
+		if err != nil {
+			_ = restore()
+			return err
+		}
+	}
+	return restore()
+}
+
+// pruneStaleDependents deletes the objects of the inventory removed from
+// the package which depend on other removed objects, in the reverse order
+// of their dependencies, before the wrapped ApplyRunner prunes the
+// remaining objects all at once. Nothing is pruned for dry runs, with
+// --no-prune, or if the objects were not read from a package directory.
+func (w *ApplyRunnerWrapper) pruneStaleDependents(cmd *cobra.Command, t applyTarget,
+	objs []*unstructured.Unstructured) error {
+	if len(objs) == 0 || isDryRun(cmd) || flagValue(cmd, "no-prune") == "true" {
+		return nil
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	invClient, err := t.invClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	previous, err := invClient.Load(ctx, inv)
+	if err != nil {
+		return err
+	}
+	current := map[object.ObjMetadata]bool{}
+	for _, obj := range objs {
+		current[object.UnstructuredToObjMeta(obj)] = true
+	}
+	var stale []object.ObjMetadata
+	for _, obj := range previous {
+		if !current[obj] {
+			stale = append(stale, obj)
+		}
+	}
+	if len(stale) < 2 {
+		return nil
+	}
+	c, err := newClient(t.factory, false)
+	if err != nil {
+		return err
+	}
+	a := &live.ParallelApplier{
+		Client:          c,
+		InventoryClient: invClient,
+		StatusRules:     w.statusRules,
+		Progress:        actuationPrinter(t.out),
+	}
+	a.ReconcileTimeout, a.PollInterval = groupReconcileTimeout(cmd)
+	_, err = a.PruneDependents(ctx, inv, stale)
+	return err
+}
+
+// beginActuation records the apply of the passed objects of the package
+// to the target with the wrapped ApplyRunner, and reports the interrupted
+// apply it resumes. Returns the function deleting the record once the
+// apply succeeded. Nothing is recorded for dry runs, or if the objects
+// were not read from a package directory.
+func beginActuation(cmd *cobra.Command, t applyTarget,
+	objs []*unstructured.Unstructured) (func() error, error) {
+	if len(objs) == 0 || isDryRun(cmd) {
+		return func() error { return nil }, nil
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return nil, err
+	}
+	var ids []object.ObjMetadata
+	for _, obj := range objs {
+		ids = append(ids, object.UnstructuredToObjMeta(obj))
+	}
+	ctx := context.Background()
+	invClient, err := t.invClient()
+	if err != nil {
+		return nil, err
+	}
+	interrupted, err := live.BeginActuation(ctx, invClient, inv, ids)
+	if err != nil {
+		return nil, err
+	}
+	if interrupted != nil {
+		printResumed(t.out, *interrupted)
+	}
+	return func() error {
+		return live.EndActuation(ctx, invClient, inv)
+	}, nil
+}
+
+// printResumed reports the interrupted apply which is resumed.
+func printResumed(w io.Writer, rec live.ActuationRecord) {
+	fmt.Fprintf(w, "resuming the apply of %d resources interrupted at %s\n", len(rec.Objects),
+		rec.Started.Format(time.RFC3339))
+}
+
+// runParallel applies the package in the passed directory with the
+// ParallelApplier, which actuates up to --concurrency resources at the
+// same time, instead of the wrapped ApplyRunner. Apply waves are applied
+// in increasing order without pruning, and pruning happens once all
+// waves are applied, as with runWaves. If the reconcile timeout is set,
+// every wave waits for its resources to be Current. The report is
+// written even if the apply failed, and the error is checked by the
+// exit policy.
+func (w *ApplyRunnerWrapper) runParallel(cmd *cobra.Command, args []string) error {
+	if w.loader == nil || len(args) == 0 {
+		return fmt.Errorf("--concurrency requires a package directory")
+	}
+	t := w.defaultTarget(cmd)
+	results, err := w.applyParallel(cmd, t, args)
+	if w.reportFormat != "" {
+		if reportErr := w.writeApplyReport(cmd, results); err == nil {
+			err = reportErr
+		}
+	}
+	return w.checkExitPolicy(t, err, results)
+}
+
+// setConflicts sets the conflict strategy of the command from
+// --conflict-strategy, or from --force-conflicts if it is not set.
+// --conflict-strategy=force also sets --force-conflicts, so it is used by
+// the wrapped ApplyRunner with --server-side.
+func (w *ApplyRunnerWrapper) setConflicts(cmd *cobra.Command) error {
+	force := flagValue(cmd, "force-conflicts") == "true"
+	switch {
+	case w.conflictStrategy == "" && force:
+		w.conflicts = live.ConflictForce
+		return nil
+	case w.conflictStrategy == "":
+		w.conflicts = live.ConflictFail
+		return nil
+	}
+	conflicts, err := live.ParseConflictStrategy(w.conflictStrategy)
+	if err != nil {
+		return err
+	}
+	if force && conflicts != live.ConflictForce {
+		return fmt.Errorf("--force-conflicts can not be used with --conflict-strategy=%s", conflicts)
+	}
+	w.conflicts = conflicts
+	if f := cmd.Flags().Lookup("force-conflicts"); f != nil && conflicts == live.ConflictForce {
+		return f.Value.Set("true")
+	}
+	return nil
+}
+
+// checkExitPolicy returns the error of the apply to the target which
+// returned err and results, by the exit policy of the command. Skipped
+// objects include the ones excluded by the ownership policy.
+func (w *ApplyRunnerWrapper) checkExitPolicy(t applyTarget, err error, results []live.ActuationResult) error {
+	var skipped []object.ObjMetadata
+	for obj := range t.loader.Exclude {
+		skipped = append(skipped, obj)
+	}
+	checked := w.exitPolicy.Check(err, results, skipped)
+	if err != nil && checked == nil {
+		fmt.Fprintf(t.out, "ignored by --error-on: %s\n", err)
+	}
+	return checked
+}
+
+// applyParallel applies the package in the passed directory to the
+// target with the ParallelApplier, as described by runParallel. Returns
+// the outcome for every actuated object of all waves.
+func (w *ApplyRunnerWrapper) applyParallel(cmd *cobra.Command, t applyTarget,
+	args []string) ([]live.ActuationResult, error) {
+	objs, err := readPackageObjs(t.loader, args)
+	if err != nil {
+		return nil, err
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return nil, err
+	}
+	waves, err := live.Waves(objs)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := isDryRun(cmd)
+	c, err := newClient(t.factory, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	invClient, err := t.invClient()
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		if invClient, err = memoryInventoryCopy(context.Background(), invClient, inv); err != nil {
+			return nil, err
+		}
+	}
+	sub, err := applyTimeSubstitution(t.factory, t.context)
+	if err != nil {
+		return nil, err
+	}
+	a := &live.ParallelApplier{
+		Client:          c,
+		InventoryClient: invClient,
+		Concurrency:     w.concurrency,
+		FieldManager:    flagValue(cmd, "field-manager"),
+		Conflicts:       w.conflicts,
+		StatusRules:     w.statusRules,
+		Progress:        actuationPrinter(t.out),
+		Resumed:         func(rec live.ActuationRecord) { printResumed(t.out, rec) },
+	}
+	if !dryRun {
+		// Dependency groups wait for the objects they depend on like
+		// waves, even if --reconcile-timeout is not set.
+		a.ReconcileTimeout, a.PollInterval = groupReconcileTimeout(cmd)
+	}
+	var results []live.ActuationResult
+	for i, wave := range waves {
+		waveObjs := objs
+		a.NoPrune = flagValue(cmd, "no-prune") == "true"
+		if len(waves) > 1 {
+			fmt.Fprintf(t.out, "applying wave %d...\n", wave)
+		}
+		if i < len(waves)-1 {
+			if waveObjs, err = live.FilterByWave(objs, wave); err != nil {
+				return results, err
+			}
+			a.NoPrune = true
+		}
+		if waveObjs, err = sub.Substitute(context.Background(), waveObjs); err != nil {
+			printActuationSummary(t.out, results)
+			return results, err
+		}
+		waveResults, err := a.Run(context.Background(), inv, waveObjs)
+		results = append(results, waveResults...)
+		if err == nil && !dryRun {
+			err = w.waitForReconcile(cmd, t.out, c, waveObjs)
+			if _, ok := err.(live.ReconcileTimeoutError); ok && i < len(waves)-1 {
+				// Later waves may depend on the resources of this wave,
+				// so the timeout is an error whatever the exit policy.
+				err = fmt.Errorf("wave %d: %s", wave, err)
+			}
+		}
+		if err != nil {
+			printActuationSummary(t.out, results)
+			return results, err
+		}
+	}
+	printActuationSummary(t.out, results)
+	return results, nil
+}
+
+// groupReconcileTimeout returns how long the ParallelApplier waits for
+// every dependency group to reconcile, which is the reconcile timeout of
+// the command or defaultWaveReconcileTimeout if not set, and the interval
+// it polls the status at.
+func groupReconcileTimeout(cmd *cobra.Command) (time.Duration, time.Duration) {
+	timeout, err := time.ParseDuration(flagValue(cmd, "reconcile-timeout"))
+	if err != nil || timeout == 0 {
+		timeout = defaultWaveReconcileTimeout
+	}
+	period, err := time.ParseDuration(flagValue(cmd, "poll-period"))
+	if err != nil || period == 0 {
+		period = defaultStatusPollInterval
+	}
+	return timeout, period
+}
+
+// waitForReconcile waits for the passed objects to be Current, for at
+// most the reconcile timeout of the command, printing their status
+// changes. It returns immediately if the reconcile timeout is not set.
+func (w *ApplyRunnerWrapper) waitForReconcile(cmd *cobra.Command, out io.Writer, c client.Client,
+	objs []*unstructured.Unstructured) error {
+	timeout, err := time.ParseDuration(flagValue(cmd, "reconcile-timeout"))
+	if err != nil || timeout == 0 {
+		return nil
+	}
+	period, err := time.ParseDuration(flagValue(cmd, "poll-period"))
+	if err != nil || period == 0 {
+		period = defaultStatusPollInterval
+	}
+	var objMetas []object.ObjMetadata
+	for _, obj := range objs {
+		if !inventory.IsInventoryObject(obj) {
+			objMetas = append(objMetas, object.UnstructuredToObjMeta(obj))
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var statuses []live.ObjectStatus
+	poll := func(ctx context.Context) ([]live.ObjectStatus, error) {
+		var err error
+		statuses, err = live.ComputeStatuses(ctx, c, w.statusRules, objMetas)
+		return statuses, err
+	}
+	printer := eventsStatusPrinter{w: out}
+	if err := pollStatus(ctx, poll, period, pollUntilCurrent, printer); err != nil {
+		return err
+	}
+	var pending []object.ObjMetadata
+	for _, s := range statuses {
+		if !statusesDone([]live.ObjectStatus{s}, pollUntilCurrent) {
+			pending = append(pending, s.Object)
+		}
+	}
+	if len(pending) > 0 {
+		return live.ReconcileTimeoutError{Objects: pending}
+	}
+	return nil
+}
+
+// applyTimeSubstitution returns the substitution resolving the apply-time
+// setters from the passed kubeconfig context of the factory, or its
+// current context if empty, and the apply-time mutations from the live
+// objects of the cluster.
+func applyTimeSubstitution(f cmdutil.Factory, contextName string) (*live.ApplyTimeSubstitution, error) {
+	config, err := f.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(f, false)
+	if err != nil {
+		return nil, err
+	}
+	return &live.ApplyTimeSubstitution{
+		Setters: live.ClusterSetters(config, contextName),
+		Client:  c,
+	}, nil
+}
+
+// memoryInventoryCopy returns a MemoryInventoryClient storing the
+// objects of the inventory stored by the passed client. Dry runs only
+// read the inventory in the cluster, and keep the changes in memory.
+func memoryInventoryCopy(ctx context.Context, invClient live.InventoryClient,
+	inv inventory.InventoryInfo) (live.InventoryClient, error) {
+	objs, err := invClient.Load(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	memory := live.NewMemoryInventoryClient()
+	if err := memory.Store(ctx, inv, objs); err != nil {
+		return nil, err
+	}
+	return memory, nil
+}
+
+// isDryRun returns true if the dry-run flag of the command is set.
+func isDryRun(cmd *cobra.Command) bool {
+	value := flagValue(cmd, "dry-run")
+	return value != "" && value != "false" && value != "none"
+}
+
+// flagValue returns the value of the flag of the command as a string,
+// or an empty string if the command has no such flag.
+func flagValue(cmd *cobra.Command, name string) string {
+	f := cmd.Flags().Lookup(name)
+	if f == nil {
+		return ""
+	}
+	return f.Value.String()
+}
+
+// actuationPrinter returns a function printing the outcome of actuating
+// every object.
+func actuationPrinter(w io.Writer) func(live.ActuationResult) {
+	return func(r live.ActuationResult) {
+		name := statusResourceID(r.Object)
+		switch {
+		case r.Err != nil && r.Operation == live.OperationPruned:
+			fmt.Fprintf(w, "%s prune failed: %s\n", name, r.Err)
+		case r.Err != nil:
+			fmt.Fprintf(w, "%s apply failed: %s\n", name, r.Err)
+		case r.Operation == live.OperationApplied:
+			fmt.Fprintf(w, "%s applied\n", name)
+		case r.Operation == live.OperationPruned:
+			fmt.Fprintf(w, "%s pruned\n", name)
+		case r.Operation == live.OperationPruneSkipped:
+			fmt.Fprintf(w, "%s prune skipped: %s\n", name, r.Message)
+		case r.Operation == live.OperationApplySkipped:
+			fmt.Fprintf(w, "%s apply skipped: %s\n", name, r.Message)
+		}
+	}
+}
+
+// printActuationSummary prints the number of objects for every outcome.
+// Objects skipped because of conflicts are only counted if there are any.
+func printActuationSummary(w io.Writer, results []live.ActuationResult) {
+	counts := map[live.Operation]int{}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		counts[r.Operation]++
+	}
+	fmt.Fprintf(w, "%d resource(s) applied, %d pruned, %d prune skipped, %d failed\n",
+		counts[live.OperationApplied], counts[live.OperationPruned],
+		counts[live.OperationPruneSkipped], failed)
+	if skipped := counts[live.OperationApplySkipped]; skipped > 0 {
+		fmt.Fprintf(w, "%d resource(s) apply skipped because of field manager conflicts\n", skipped)
+	}
+}
+
+// readObjs returns the objects of the package in the passed directory.
+// Objects read from stdin are not returned, so they are applied in a
+// single wave.
+func (w *ApplyRunnerWrapper) readObjs(args []string) ([]*unstructured.Unstructured, error) {
+	return readPackageObjs(w.loader, args)
+}
+
+// readPackageObjs returns the objects of the package in the passed
+// directory read by the loader, or nothing if there is no loader or
+// directory.
+func readPackageObjs(loader *live.DualDelegatingManifestReader, args []string) ([]*unstructured.Unstructured, error) {
+	if loader == nil || len(args) == 0 {
+		return nil, nil
+	}
+	reader, err := loader.ManifestReader(nil, args)
+	if err != nil {
+		return nil, err
+	}
+	return reader.Read()
+}
+
+// defaultStatusPollInterval is the interval at which the status of the
+// objects handled by status readers is polled.
+const defaultStatusPollInterval = 2 * time.Second
+
+// waitForStatus waits for the passed objects handled by the status
+// readers to be ready, for at most the reconcile timeout of the command.
+// It returns immediately if the reconcile timeout is not set.
+func (w *ApplyRunnerWrapper) waitForStatus(cmd *cobra.Command, objs []*unstructured.Unstructured) error {
+	f := cmd.Flags().Lookup("reconcile-timeout")
+	if w.statusRules == nil || f == nil {
+		return nil
+	}
+	timeout, err := time.ParseDuration(f.Value.String())
+	if err != nil || timeout == 0 {
+		return err
+	}
+	var objMetas []object.ObjMetadata
+	for _, obj := range objs {
+		objMeta := object.UnstructuredToObjMeta(obj)
+		if w.statusRules.Handles(objMeta.GroupKind) {
+			objMetas = append(objMetas, objMeta)
+		}
+	}
+	if len(objMetas) == 0 {
+		return nil
+	}
+	c, err := newClient(w.factory, false)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	fmt.Fprintf(cmd.OutOrStdout(), "waiting for %d resources with status readers...\n", len(objMetas))
+	return live.WaitForStatus(ctx, c, w.statusRules, objMetas, defaultStatusPollInterval)
+}
+
+// setWaveFlags disables pruning and makes the apply wait for the objects
+// to reconcile. Returns a function restoring the flags set by the user.
+func setWaveFlags(cmd *cobra.Command) (func() error, error) {
+	values := map[string]string{"no-prune": "true"}
+	if f := cmd.Flags().Lookup("reconcile-timeout"); f != nil {
+		if timeout, err := time.ParseDuration(f.Value.String()); err == nil && timeout == 0 {
+			values["reconcile-timeout"] = defaultWaveReconcileTimeout.String()
+		}
+	}
+	original := map[string]string{}
+	for name, value := range values {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			continue
+		}
+		original[name] = f.Value.String()
+		if err := f.Value.Set(value); err != nil {
+			return nil, err
+		}
+	}
+	return func() error {
+		for name, value := range original {
+			if err := cmd.Flags().Lookup(name).Value.Set(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
 }
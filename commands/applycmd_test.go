@@ -0,0 +1,295 @@
+// Copyright 2020 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/cmd/apply"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestSetWaveFlags(t *testing.T) {
+	testCases := map[string]struct {
+		reconcileTimeout string
+		expectedTimeout  string
+	}{
+		"Unset reconcile timeout uses the wave default": {
+			reconcileTimeout: "0s",
+			expectedTimeout:  defaultWaveReconcileTimeout.String(),
+		},
+		"Reconcile timeout set by the user is kept": {
+			reconcileTimeout: "30s",
+			expectedTimeout:  "30s",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var noPrune bool
+			var reconcileTimeout time.Duration
+			cmd := &cobra.Command{}
+			cmd.Flags().BoolVar(&noPrune, "no-prune", false, "")
+			cmd.Flags().DurationVar(&reconcileTimeout, "reconcile-timeout", 0, "")
+			assert.NoError(t, cmd.Flags().Set("reconcile-timeout", tc.reconcileTimeout))
+
+			restore, err := setWaveFlags(cmd)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.True(t, noPrune)
+			assert.Equal(t, tc.expectedTimeout, reconcileTimeout.String())
+
+			assert.NoError(t, restore())
+			assert.False(t, noPrune)
+			assert.Equal(t, tc.reconcileTimeout, reconcileTimeout.String())
+		})
+	}
+}
+
+func TestRunWaves_dependencyGroups(t *testing.T) {
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("app").
+		WithFile("inventory-template.yaml", cmInvStr).
+		WithFile("pods.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-2
+  namespace: `+testNamespace+`
+  annotations:
+    config.kubernetes.io/depends-on: /namespaces/`+testNamespace+`/Pod/pod-1
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-1
+  namespace: `+testNamespace+`
+`))
+	tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+	defer tf.Cleanup()
+	defer func(f func(cmdutil.Factory, bool) (client.Client, error)) { newClient = f }(newClient)
+	newClient = func(_ cmdutil.Factory, dryRun bool) (client.Client, error) {
+		return client.NewFakeClient().WithDryRun(dryRun), nil
+	}
+	ioStreams, _, _, _ := genericclioptions.NewTestIOStreams() //nolint:dogsled
+	w := GetApplyRunner(live.NewFakeResourceGroupProvider(tf, nil),
+		live.NewDualDelegatingManifestReader(tf), ioStreams)
+
+	// Every run records the objects it applies, and whether it waits for
+	// them to reconcile.
+	var runs []string
+	defer func(f func(*apply.ApplyRunner, *cobra.Command, []string) error) { runApplyRunner = f }(runApplyRunner)
+	runApplyRunner = func(_ *apply.ApplyRunner, cmd *cobra.Command, args []string) error {
+		objs, err := readPackageObjs(w.loader, args)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, obj := range objs {
+			if !inventory.IsInventoryObject(obj) {
+				names = append(names, obj.GetName())
+			}
+		}
+		runs = append(runs, fmt.Sprintf("%s no-prune=%s reconcile-timeout=%s", strings.Join(names, ","),
+			flagValue(cmd, "no-prune"), flagValue(cmd, "reconcile-timeout")))
+		return nil
+	}
+
+	var dryRun string
+	var noPrune bool
+	var reconcileTimeout time.Duration
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(&dryRun, "dry-run", "true", "")
+	cmd.Flags().BoolVar(&noPrune, "no-prune", false, "")
+	cmd.Flags().DurationVar(&reconcileTimeout, "reconcile-timeout", 0, "")
+	cmd.SetOut(&bytes.Buffer{})
+
+	if !assert.NoError(t, w.runWaves(cmd, []string{dir})) {
+		t.FailNow()
+	}
+	// The dependent is only applied by the run after the one waiting for
+	// its dependency to be Current.
+	assert.Equal(t, []string{
+		"pod-1 no-prune=true reconcile-timeout=" + defaultWaveReconcileTimeout.String(),
+		"pod-1,pod-2 no-prune=false reconcile-timeout=0s",
+	}, runs)
+}
+
+func TestRequireConcurrency(t *testing.T) {
+	testCases := map[string]struct {
+		reportFormat    string
+		errorOn         string
+		conflicts       live.ConflictStrategy
+		inventoryClient string
+		expectedFlag    string
+	}{
+		"No flag of the parallel applier": {},
+		"Reports require concurrency": {
+			reportFormat: "junit",
+			expectedFlag: "--output=junit",
+		},
+		"Exit policies require concurrency": {
+			errorOn:      "reconcile-timeout",
+			expectedFlag: "--error-on",
+		},
+		"Skipping conflicts requires concurrency": {
+			conflicts:    live.ConflictSkip,
+			expectedFlag: "--conflict-strategy=skip",
+		},
+		"Forcing conflicts is supported by both appliers": {
+			conflicts: live.ConflictForce,
+		},
+		"Inventory clients require concurrency": {
+			inventoryClient: "git",
+			expectedFlag:    "--inventory-client=git",
+		},
+		"The cluster inventory client is the default": {
+			inventoryClient: live.ClusterInventoryClientName,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var inventoryClient string
+			var errorOn []string
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVar(&inventoryClient, inventoryClientFlag, "", "")
+			cmd.Flags().StringSliceVar(&errorOn, "error-on", live.DefaultExitConditions, "")
+			assert.NoError(t, cmd.Flags().Set(inventoryClientFlag, tc.inventoryClient))
+			if tc.errorOn != "" {
+				assert.NoError(t, cmd.Flags().Set("error-on", tc.errorOn))
+			}
+			w := &ApplyRunnerWrapper{reportFormat: tc.reportFormat, conflicts: tc.conflicts}
+
+			err := w.requireConcurrency(cmd)
+			if tc.expectedFlag == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), tc.expectedFlag+" requires --concurrency"))
+			}
+		})
+	}
+}
+
+func TestActuationPrinter(t *testing.T) {
+	pod1Meta := object.UnstructuredToObjMeta(pod1)
+	pod2Meta := object.UnstructuredToObjMeta(pod2)
+	results := []live.ActuationResult{
+		{Object: pod1Meta, Operation: live.OperationApplied},
+		{Object: pod2Meta, Operation: live.OperationApplied, Err: fmt.Errorf("forbidden")},
+		{Object: pod1Meta, Operation: live.OperationPruned},
+		{Object: pod2Meta, Operation: live.OperationPruneSkipped, Message: "owned by inventory \"other\""},
+		{Object: pod1Meta, Operation: live.OperationApplySkipped, Message: "conflict with \"kubectl\""},
+	}
+	out := &bytes.Buffer{}
+	printResult := actuationPrinter(out)
+	for _, r := range results {
+		printResult(r)
+	}
+	printActuationSummary(out, results)
+	assert.Equal(t, fmt.Sprintf(`Pod/%[1]s/pod-1 applied
+Pod/%[1]s/pod-2 apply failed: forbidden
+Pod/%[1]s/pod-1 pruned
+Pod/%[1]s/pod-2 prune skipped: owned by inventory "other"
+Pod/%[1]s/pod-1 apply skipped: conflict with "kubectl"
+1 resource(s) applied, 1 pruned, 1 prune skipped, 1 failed
+1 resource(s) apply skipped because of field manager conflicts
+`, testNamespace), out.String())
+}
+
+func TestFlagValue(t *testing.T) {
+	var fieldManager string
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(&fieldManager, "field-manager", "kubectl", "")
+	assert.Equal(t, "kubectl", flagValue(cmd, "field-manager"))
+	assert.Equal(t, "", flagValue(cmd, "force-conflicts"))
+}
+
+func TestSetConflicts(t *testing.T) {
+	testCases := map[string]struct {
+		strategy       string
+		forceConflicts bool
+		expected       live.ConflictStrategy
+		expectedForce  string
+		expectErr      bool
+	}{
+		"default":               {expected: live.ConflictFail, expectedForce: "false"},
+		"force-conflicts":       {forceConflicts: true, expected: live.ConflictForce, expectedForce: "true"},
+		"force strategy":        {strategy: "force", expected: live.ConflictForce, expectedForce: "true"},
+		"skip strategy":         {strategy: "skip", expected: live.ConflictSkip, expectedForce: "false"},
+		"skip with force":       {strategy: "skip", forceConflicts: true, expectErr: true},
+		"unknown strategy":      {strategy: "merge", expectErr: true},
+		"force with force flag": {strategy: "force", forceConflicts: true, expected: live.ConflictForce, expectedForce: "true"},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var force bool
+			cmd := &cobra.Command{}
+			cmd.Flags().BoolVar(&force, "force-conflicts", tc.forceConflicts, "")
+			w := &ApplyRunnerWrapper{conflictStrategy: tc.strategy}
+			err := w.setConflicts(cmd)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, w.conflicts)
+			assert.Equal(t, tc.expectedForce, flagValue(cmd, "force-conflicts"))
+		})
+	}
+}
+
+func TestSetFilter(t *testing.T) {
+	testCases := map[string]struct {
+		kinds     []string
+		noLoader  bool
+		isPartial bool
+		isError   bool
+	}{
+		"No filter applies the whole package": {},
+		"Kind filter disables pruning": {
+			kinds:     []string{"CustomResourceDefinition"},
+			isPartial: true,
+		},
+		"Filter requires the kpt loader": {
+			kinds:    []string{"CustomResourceDefinition"},
+			noLoader: true,
+			isError:  true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var noPrune bool
+			cmd := &cobra.Command{}
+			cmd.Flags().BoolVar(&noPrune, "no-prune", false, "")
+			w := &ApplyRunnerWrapper{kinds: tc.kinds}
+			if !tc.noLoader {
+				w.loader = live.NewDualDelegatingManifestReader(nil)
+			}
+			err := w.setFilter(cmd)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.isPartial, w.isPartial())
+			assert.Equal(t, tc.isPartial, noPrune)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// targetReport is the outcome of applying the package to a target.
+type targetReport struct {
+	target   string
+	results  []live.ActuationResult
+	statuses []live.ObjectStatus
+	err      error
+}
+
+// runTargets applies the package in the passed directory to all targets
+// in parallel, with the ParallelApplier. Every target has its own
+// inventory, ownership checks and revisions. The output of every target
+// is prefixed with its name, and a report of all targets is printed at
+// the end. Returns an error if the apply failed for any target.
+func (w *ApplyRunnerWrapper) runTargets(cmd *cobra.Command, args []string, targets []live.Target) error {
+	switch {
+	case w.loader == nil || len(args) == 0:
+		return fmt.Errorf("--target-context and --targets-file require a package directory")
+	case w.planFile != "":
+		return fmt.Errorf("--target-context and --targets-file can not be used with --plan")
+	case w.adopt:
+		return fmt.Errorf("--target-context and --targets-file can not be used with --adopt")
+	}
+	if err := w.readStatusRules(args); err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	reports := make([]targetReport, len(targets))
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out := &prefixWriter{w: cmd.OutOrStdout(), mu: &mu, prefix: "[" + targets[i].Name + "] "}
+			reports[i] = w.applyToTarget(cmd, args, targets[i], out)
+			out.Flush()
+		}(i)
+	}
+	wg.Wait()
+	printTargetReports(cmd.OutOrStdout(), reports)
+	failed := 0
+	for _, r := range reports {
+		if r.err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("apply failed for %d of %d targets", failed, len(targets))
+	}
+	return nil
+}
+
+// applyToTarget applies the package in the passed directory to the
+// target, and computes the status of the applied objects.
+func (w *ApplyRunnerWrapper) applyToTarget(cmd *cobra.Command, args []string, target live.Target,
+	out io.Writer) targetReport {
+	report := targetReport{target: target.Name}
+	f := target.Factory()
+	loader := live.NewDualDelegatingManifestReader(f)
+	loader.InventoryType = w.loader.InventoryType
+	loader.Filter = w.loader.Filter
+	t := applyTarget{
+		factory:  f,
+		provider: live.NewDualDelegatingProvider(f),
+		loader:   loader,
+		out:      out,
+		context:  target.Context,
+
+		inventoryClient: flagValue(cmd, inventoryClientFlag),
+	}
+	if loader.InventoryType != live.InventoryTypeSecret {
+		if rgInv := live.ReadResourceGroupInventory(args[0]); rgInv != nil {
+			var namespace string
+			if !isDryRun(cmd) {
+				namespace = rgInv.GetNamespace()
+			}
+			if report.err = installResourceGroup(f, out, namespace); report.err != nil {
+				return report
+			}
+		}
+	}
+	if report.err = w.runPreApplyHooks(cmd, t, args, false); report.err != nil {
+		return report
+	}
+	report.results, report.err = w.applyParallel(cmd, t, args)
+	if report.err = w.checkExitPolicy(t, report.err, report.results); report.err != nil {
+		return report
+	}
+	if isDryRun(cmd) {
+		return report
+	}
+	if !w.isPartial() {
+		if err := recordRevision(t, args, nil); err != nil {
+			fmt.Fprintf(out, "unable to record the apply revision: %s\n", err)
+		}
+	}
+	c, err := newClient(f, false)
+	if err != nil {
+		report.err = err
+		return report
+	}
+	var objMetas []object.ObjMetadata
+	for _, r := range report.results {
+		if r.Operation == live.OperationApplied {
+			objMetas = append(objMetas, r.Object)
+		}
+	}
+	report.statuses, report.err = live.ComputeStatuses(context.Background(), c, w.statusRules, objMetas)
+	return report
+}
+
+// printTargetReports prints a table with the outcome of the apply and
+// the number of Current resources for every target.
+func printTargetReports(w io.Writer, reports []targetReport) {
+	table := tablewriter.NewWriter(w)
+	table.SetRowLine(false)
+	table.SetBorder(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator(" ")
+	table.SetCenterSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Target", "Applied", "Pruned", "Failed", "Current", "Result"})
+	for _, r := range reports {
+		counts := map[live.Operation]int{}
+		failed := 0
+		for _, result := range r.results {
+			if result.Err != nil {
+				failed++
+				continue
+			}
+			counts[result.Operation]++
+		}
+		current := 0
+		for _, s := range r.statuses {
+			if s.Status == status.CurrentStatus {
+				current++
+			}
+		}
+		result := "Succeeded"
+		if r.err != nil {
+			result = "Failed: " + r.err.Error()
+		}
+		table.Append([]string{
+			r.target,
+			strconv.Itoa(counts[live.OperationApplied]),
+			strconv.Itoa(counts[live.OperationPruned]),
+			strconv.Itoa(failed),
+			fmt.Sprintf("%d/%d", current, len(r.statuses)),
+			result,
+		})
+	}
+	table.Render()
+}
+
+// prefixWriter prefixes every line written to w with the prefix. Lines
+// are written as a whole, holding the mutex, so the lines of several
+// prefixWriters sharing the mutex are not interleaved.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		if err := p.writeLine(p.buf.Next(i + 1)); err != nil {
+			return len(data), err
+		}
+	}
+}
+
+// Flush writes the last line if it does not end with a newline.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		_ = p.writeLine(append(p.buf.Next(p.buf.Len()), '\n'))
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
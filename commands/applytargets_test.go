@@ -0,0 +1,119 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	dev := &prefixWriter{w: &out, mu: &mu, prefix: "[dev] "}
+	prod := &prefixWriter{w: &out, mu: &mu, prefix: "[prod] "}
+
+	fmt.Fprint(dev, "pod-1 ")
+	fmt.Fprint(prod, "pod-2 applied\n")
+	fmt.Fprint(dev, "applied\npod-3 applied\nsummary")
+	dev.Flush()
+	prod.Flush()
+	assert.Equal(t, "[prod] pod-2 applied\n"+
+		"[dev] pod-1 applied\n"+
+		"[dev] pod-3 applied\n"+
+		"[dev] summary\n", out.String())
+}
+
+func TestPrintTargetReports(t *testing.T) {
+	pod1Meta := object.UnstructuredToObjMeta(pod1)
+	pod2Meta := object.UnstructuredToObjMeta(pod2)
+	reports := []targetReport{
+		{
+			target: "dev",
+			results: []live.ActuationResult{
+				{Object: pod1Meta, Operation: live.OperationApplied},
+				{Object: pod2Meta, Operation: live.OperationPruned},
+			},
+			statuses: []live.ObjectStatus{{Object: pod1Meta, Status: status.CurrentStatus}},
+		},
+		{
+			target: "prod",
+			results: []live.ActuationResult{
+				{Object: pod1Meta, Operation: live.OperationApplied, Err: fmt.Errorf("forbidden")},
+			},
+			err: fmt.Errorf("1 resources failed"),
+		},
+	}
+	var out bytes.Buffer
+	printTargetReports(&out, reports)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !assert.Len(t, lines, 3) {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"TARGET", "APPLIED", "PRUNED", "FAILED", "CURRENT", "RESULT"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{"dev", "1", "1", "0", "1/1", "Succeeded"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"prod", "0", "0", "1", "0/0", "Failed:", "1", "resources", "failed"},
+		strings.Fields(lines[2]))
+}
+
+func TestRunTargets_invalidFlags(t *testing.T) {
+	targets := []live.Target{{Name: "dev", Context: "dev"}}
+	testCases := map[string]struct {
+		args     []string
+		planFile string
+		adopt    bool
+		expected string
+	}{
+		"Package directory is required": {
+			expected: "require a package directory",
+		},
+		"Plans are not supported": {
+			args:     []string{"dir"},
+			planFile: "plan.json",
+			expected: "can not be used with --plan",
+		},
+		"Adopt is not supported": {
+			args:     []string{"dir"},
+			adopt:    true,
+			expected: "can not be used with --adopt",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			w := &ApplyRunnerWrapper{
+				loader:   live.NewDualDelegatingManifestReader(nil),
+				planFile: tc.planFile,
+				adopt:    tc.adopt,
+			}
+			if len(tc.args) == 0 {
+				w.loader = nil
+			}
+			err := w.runTargets(nil, tc.args, targets)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"github.com/GoogleContainerTools/kpt/internal/cmdcache"
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/cachedocs"
+	"github.com/spf13/cobra"
+)
+
+func GetCacheCommand(name string) *cobra.Command {
+	cache := &cobra.Command{
+		Use:     "cache",
+		Short:   cachedocs.CacheShort,
+		Long:    cachedocs.CacheLong,
+		Example: cachedocs.CacheExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			h, err := cmd.Flags().GetBool("help")
+			if err != nil {
+				return err
+			}
+			if h {
+				return cmd.Help()
+			}
+			return cmd.Usage()
+		},
+	}
+	cache.AddCommand(cmdcache.NewCommand(name))
+	return cache
+}
@@ -58,8 +58,9 @@ func GetKptCommands(name string, f util.Factory) []*cobra.Command {
 	ttlCmd := GetTTLCommand(name)
 	liveCmd := GetLiveCommand(name, f)
 	guideCmd := GetGuideCommand(name)
+	cacheCmd := GetCacheCommand(name)
 
-	c = append(c, cfgCmd, pkgCmd, fnCmd, ttlCmd, liveCmd, guideCmd)
+	c = append(c, cfgCmd, pkgCmd, fnCmd, ttlCmd, liveCmd, guideCmd, cacheCmd)
 
 	// apply cross-cutting issues to commands
 	NormalizeCommand(c...)
@@ -136,7 +136,8 @@ func CreateSubstCommand(parent string) *cobra.Command {
 }
 
 // SetCommand wraps the kustomize set command in order to automatically update
-// a project number if a project id is set.
+// a project number if a project id is set, to check the value against the
+// constraints of the setter, and to set the fields of map setters.
 func SetCommand(parent string) *cobra.Command {
 	fieldmeta.SetShortHandRef(ShortHandRef)
 	kustomizeCmd := configcobra.Set(parent)
@@ -150,10 +151,18 @@ func SetCommand(parent string) *cobra.Command {
 	setCmd.Flags().BoolVar(&autoRun, "auto-run", true,
 		`Automatically run functions after setting (if enabled for the package)`)
 	setCmd.RunE = func(c *cobra.Command, args []string) error {
+		if len(args) == 3 {
+			if err := setters.CheckValue(args[0], args[1], args[2]); err != nil {
+				return err
+			}
+		}
 		kustomizeCmd.SetArgs(args)
 		if err := kustomizeCmd.Execute(); err != nil {
 			return err
 		}
+		if err := setters.SetCollectionFields(args[0]); err != nil {
+			return err
+		}
 
 		if autoRun {
 			if err := functions.ReconcileFunctions(args[0]); err != nil {
@@ -0,0 +1,172 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// DestroyRunner encapsulates fields for the kpt live destroy command.
+type DestroyRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider              provider.Provider
+	loader                manifestreader.ManifestLoader
+	deletionTimeout       time.Duration
+	pollPeriod            time.Duration
+	forceFinalizerRemoval bool
+	dryRun                bool
+}
+
+// GetDestroyRunner returns a pointer to an initial DestroyRunner structure.
+func GetDestroyRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *DestroyRunner {
+	r := &DestroyRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "destroy [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Remove all previously applied resources in a package from the cluster"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().DurationVar(&r.deletionTimeout, "deletion-timeout", live.DefaultDeletionTimeout,
+		"How long to wait for the deleted resources of every group to disappear before they are stuck")
+	cmd.Flags().DurationVar(&r.pollPeriod, "poll-period", live.DefaultDeletionPollInterval,
+		"Polling period for the deleted resources.")
+	cmd.Flags().BoolVar(&r.forceFinalizerRemoval, "force-finalizer-removal", false,
+		"Remove the finalizers of the resources whose deletion is stuck")
+	cmd.Flags().BoolVar(&r.dryRun, "dry-run", false,
+		"Only validate the deletions with the server, without deleting any resource.")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdDestroy returns the cobra command for the destroy command.
+func NewCmdDestroy(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetDestroyRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE deletes the objects in the inventory of the package in the passed
+// directory (or stdin) with the Destroyer, in the reverse order of their
+// dependencies, waiting for every group to disappear. The inventory is
+// deleted once all objects are gone.
+func (r *DestroyRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; destroy requires one directory argument (or stdin)")
+	}
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), args)
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	inv, _, err := r.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), r.dryRun)
+	if err != nil {
+		return err
+	}
+	invClient, err := newInventoryClient(cmd, r.provider)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	d := &live.Destroyer{
+		Client:                c,
+		InventoryClient:       invClient,
+		Timeout:               r.deletionTimeout,
+		PollInterval:          r.pollPeriod,
+		ForceFinalizerRemoval: r.forceFinalizerRemoval,
+		DryRun:                r.dryRun,
+		Progress:              destroyPrinter(out),
+	}
+	results, err := d.Run(context.Background(), inv)
+	printDestroySummary(out, results)
+	if hasStuckDeletion(err) && !r.forceFinalizerRemoval {
+		return fmt.Errorf("%s\nrun destroy again once the resources are deleted, "+
+			"or pass --force-finalizer-removal to remove the finalizers of stuck resources", err)
+	}
+	return err
+}
+
+// hasStuckDeletion returns true if the error of destroy includes objects
+// whose deletion is stuck.
+func hasStuckDeletion(err error) bool {
+	actuationErr, ok := err.(live.ActuationError)
+	if !ok {
+		return false
+	}
+	for _, r := range actuationErr.Results {
+		if _, ok := r.Err.(live.StuckDeletionError); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// destroyPrinter returns a function printing the outcome of deleting
+// every object, in the format of the cli-utils destroy command.
+func destroyPrinter(w io.Writer) func(live.ActuationResult) {
+	return func(r live.ActuationResult) {
+		name := fmt.Sprintf("%s/%s", strings.ToLower(r.Object.GroupKind.Kind), r.Object.Name)
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(w, "%s delete failed: %s\n", name, r.Err)
+		case r.Operation == live.OperationDeleteSkipped:
+			fmt.Fprintf(w, "%s delete skipped: %s\n", name, r.Message)
+		case r.Message != "":
+			fmt.Fprintf(w, "%s deleted (%s)\n", name, r.Message)
+		default:
+			fmt.Fprintf(w, "%s deleted\n", name)
+		}
+	}
+}
+
+// printDestroySummary prints the number of objects for every outcome of
+// destroy.
+func printDestroySummary(w io.Writer, results []live.ActuationResult) {
+	counts := map[live.Operation]int{}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		counts[r.Operation]++
+	}
+	fmt.Fprintf(w, "%d resource(s) deleted, %d skipped, %d failed\n",
+		counts[live.OperationDeleted], counts[live.OperationDeleteSkipped], failed)
+}
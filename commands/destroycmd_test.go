@@ -0,0 +1,55 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestDestroyPrinter(t *testing.T) {
+	pod1Meta := object.UnstructuredToObjMeta(pod1)
+	pod2Meta := object.UnstructuredToObjMeta(pod2)
+	stuck := live.StuckDeletionError{Finalizers: []string{"example.com/cleanup"}}
+	results := []live.ActuationResult{
+		{Object: pod1Meta, Operation: live.OperationDeleted},
+		{Object: pod2Meta, Operation: live.OperationDeleted, Err: stuck},
+		{Object: pod1Meta, Operation: live.OperationDeleted, Message: "finalizers removed"},
+		{Object: pod2Meta, Operation: live.OperationDeleteSkipped, Message: "owned by inventory \"other\""},
+	}
+	out := &bytes.Buffer{}
+	printResult := destroyPrinter(out)
+	for _, r := range results {
+		printResult(r)
+	}
+	printDestroySummary(out, results)
+	assert.Equal(t, `pod/pod-1 deleted
+pod/pod-2 delete failed: deletion is stuck waiting for finalizers: example.com/cleanup
+pod/pod-1 deleted (finalizers removed)
+pod/pod-2 delete skipped: owned by inventory "other"
+2 resource(s) deleted, 1 skipped, 1 failed
+`, out.String())
+
+	assert.True(t, hasStuckDeletion(live.ActuationError{Results: results[1:2]}))
+	assert.False(t, hasStuckDeletion(live.ActuationError{Results: []live.ActuationResult{
+		{Object: pod1Meta, Operation: live.OperationDeleted, Err: fmt.Errorf("forbidden")},
+	}}))
+	assert.False(t, hasStuckDeletion(nil))
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// ANSI escape sequences used to colorize the diff.
+const (
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+	colorBold  = "\x1b[1m"
+)
+
+// DiffRunner encapsulates fields for the kpt live diff command.
+type DiffRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider provider.Provider
+	loader   manifestreader.ManifestLoader
+	output   string
+	color    string
+}
+
+// GetDiffRunner returns a pointer to an initial DiffRunner structure.
+func GetDiffRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *DiffRunner {
+	r := &DiffRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "diff [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Diff the local package config against the live cluster resources"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().StringVarP(&r.output, "output", "o", "text",
+		"Output format: text or json")
+	cmd.Flags().StringVar(&r.color, "color", "auto",
+		"Colorize the text output: auto, always or never")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdDiff returns the cobra command for the diff command.
+func NewCmdDiff(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetDiffRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE diffs the package in the passed directory (or stdin) against
+// the live cluster resources, and prints the diff in the output format.
+func (r *DiffRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; diff requires one directory argument (or stdin)")
+	}
+	if r.output != "text" && r.output != "json" {
+		return fmt.Errorf("unknown output format %q; must be text or json", r.output)
+	}
+	colorize, err := r.colorize()
+	if err != nil {
+		return err
+	}
+	_, objs, clusterObjs, err := readPackageState(cmd, r.provider, r.loader, args)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), false)
+	if err != nil {
+		return err
+	}
+	diffs, err := live.ComputeDiff(context.Background(), c, objs, clusterObjs)
+	if err != nil {
+		return err
+	}
+	if r.output == "json" {
+		encoder := json.NewEncoder(r.ioStreams.Out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diffs)
+	}
+	printDiff(r.ioStreams.Out, diffs, colorize)
+	return nil
+}
+
+// colorize returns true if the text output is colorized. The auto mode
+// colorizes the output only if it is written to a terminal.
+func (r *DiffRunner) colorize() (bool, error) {
+	switch r.color {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return isTerminal(r.ioStreams.Out), nil
+	default:
+		return false, fmt.Errorf("unknown color mode %q; must be auto, always or never", r.color)
+	}
+}
+
+// printDiff prints the diff of every changed object, followed by a
+// summary of the changes. Drifted fields are listed before the diff of
+// their object.
+func printDiff(w io.Writer, diffs []live.ObjectDiff, colorize bool) {
+	paint := func(color, s string) string {
+		if !colorize {
+			return s
+		}
+		return color + s + colorReset
+	}
+	counts := map[live.ActionType]int{}
+	for _, d := range diffs {
+		counts[d.Type]++
+		if d.Type == live.ActionUnchanged && len(d.Drifted) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, paint(colorBold, fmt.Sprintf("%s %s %s/%s", d.Type,
+			d.Object.GroupKind.Kind, d.Object.Namespace, d.Object.Name)))
+		if len(d.Drifted) > 0 {
+			fmt.Fprintf(w, "changed in the cluster since last apply: %s\n",
+				strings.Join(d.Drifted, ", "))
+		}
+		for _, line := range strings.SplitAfter(d.Diff, "\n") {
+			switch {
+			case line == "":
+				continue
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				fmt.Fprint(w, paint(colorBold, strings.TrimSuffix(line, "\n"))+"\n")
+			case strings.HasPrefix(line, "+"):
+				fmt.Fprint(w, paint(colorGreen, strings.TrimSuffix(line, "\n"))+"\n")
+			case strings.HasPrefix(line, "-"):
+				fmt.Fprint(w, paint(colorRed, strings.TrimSuffix(line, "\n"))+"\n")
+			case strings.HasPrefix(line, "@@"):
+				fmt.Fprint(w, paint(colorCyan, strings.TrimSuffix(line, "\n"))+"\n")
+			default:
+				fmt.Fprint(w, line)
+			}
+		}
+	}
+	fmt.Fprintf(w, "Diff: %d to create, %d to update, %d to prune, %d unchanged, %d kept\n",
+		counts[live.ActionCreate], counts[live.ActionUpdate], counts[live.ActionPrune],
+		counts[live.ActionUnchanged], counts[live.ActionPruneSkip])
+}
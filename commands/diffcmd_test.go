@@ -0,0 +1,73 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrintDiff(t *testing.T) {
+	diffs := []live.ObjectDiff{
+		{
+			Type:   live.ActionUpdate,
+			Object: object.UnstructuredToObjMeta(pod1),
+			Diff:   "--- live/pod-1\n+++ local/pod-1\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			Type:    live.ActionUnchanged,
+			Object:  object.UnstructuredToObjMeta(pod2),
+			Drifted: []string{"spec.replicas"},
+		},
+		{
+			Type: live.ActionUnchanged,
+			Object: object.ObjMetadata{Namespace: testNamespace, Name: "pod-3",
+				GroupKind: schema.GroupKind{Kind: "Pod"}},
+		},
+	}
+
+	var out bytes.Buffer
+	printDiff(&out, diffs, false)
+	assert.Contains(t, out.String(), "Update Pod "+testNamespace+"/pod-1\n")
+	assert.Contains(t, out.String(), "\n-a\n+b\n")
+	assert.Contains(t, out.String(), "changed in the cluster since last apply: spec.replicas\n")
+	assert.NotContains(t, out.String(), "pod-3")
+	assert.Contains(t, out.String(), "Diff: 0 to create, 1 to update, 0 to prune, 2 unchanged, 0 kept\n")
+	assert.NotContains(t, out.String(), colorReset)
+
+	out.Reset()
+	printDiff(&out, diffs, true)
+	assert.Contains(t, out.String(), colorRed+"-a"+colorReset)
+	assert.Contains(t, out.String(), colorGreen+"+b"+colorReset)
+}
+
+func TestDiffRunner_colorize(t *testing.T) {
+	r := &DiffRunner{}
+	r.ioStreams.Out = &bytes.Buffer{}
+	for color, expected := range map[string]bool{"always": true, "never": false, "auto": false} {
+		r.color = color
+		colorize, err := r.colorize()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, colorize, color)
+	}
+	r.color = "sometimes"
+	_, err := r.colorize()
+	assert.Error(t, err)
+}
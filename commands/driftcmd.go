@@ -0,0 +1,181 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// DriftRunner encapsulates fields for the kpt live drift command.
+type DriftRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider provider.Provider
+	loader   manifestreader.ManifestLoader
+	output   string
+}
+
+// GetDriftRunner returns a pointer to an initial DriftRunner structure.
+func GetDriftRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *DriftRunner {
+	r := &DriftRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "drift [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Report out-of-band changes to the last applied resources of a package"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().StringVarP(&r.output, "output", "o", "text",
+		"Output format: text or json")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdDrift returns the cobra command for the drift command.
+func NewCmdDrift(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetDriftRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// driftReport is the JSON output of the drift command.
+type driftReport struct {
+	Revision  int                `json:"revision"`
+	Timestamp time.Time          `json:"timestamp"`
+	InSync    int                `json:"inSync"`
+	Drifted   int                `json:"drifted"`
+	Objects   []live.ObjectDrift `json:"objects"`
+}
+
+// RunE compares the latest revision applied for the inventory of the
+// package in the passed directory (or stdin) with the live resources,
+// and prints the drifted fields in the output format. Returns an error
+// if any resource drifted, so the command can be used for alerting.
+func (r *DriftRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; drift requires one directory argument (or stdin)")
+	}
+	if r.output != "text" && r.output != "json" {
+		return fmt.Errorf("unknown output format %q; must be text or json", r.output)
+	}
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), args)
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	inv, _, err := r.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	revisions, err := live.NewClusterInventoryClient(r.provider).ListRevisions(ctx, inv)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("no revision recorded for inventory %s; apply the package first", inv.Name())
+	}
+	rev := revisions[len(revisions)-1]
+	// The recorded manifests are compared as they were applied, after the
+	// apply-time substitution.
+	sub, err := applyTimeSubstitution(r.provider.Factory(), flagValue(cmd, "context"))
+	if err != nil {
+		return err
+	}
+	applied, err := sub.Substitute(ctx, rev.Objects)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), false)
+	if err != nil {
+		return err
+	}
+	drifts, err := live.DetectDrift(ctx, c, applied)
+	if err != nil {
+		return err
+	}
+	report := driftReport{Revision: rev.Number, Timestamp: rev.Timestamp, Objects: []live.ObjectDrift{}}
+	for _, d := range drifts {
+		if !d.Drifted() {
+			report.InSync++
+			continue
+		}
+		report.Drifted++
+		report.Objects = append(report.Objects, d)
+	}
+	if r.output == "json" {
+		encoder := json.NewEncoder(r.ioStreams.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDrift(r.ioStreams.Out, report)
+	}
+	if report.Drifted > 0 {
+		return fmt.Errorf("%d resource(s) drifted from revision %d", report.Drifted, report.Revision)
+	}
+	return nil
+}
+
+// printDrift prints the drifted fields of every drifted object, followed
+// by a summary.
+func printDrift(w io.Writer, report driftReport) {
+	for _, d := range report.Objects {
+		id := fmt.Sprintf("%s %s/%s", d.Object.GroupKind.Kind, d.Object.Namespace, d.Object.Name)
+		if d.Missing {
+			fmt.Fprintf(w, "%s: deleted from the cluster\n", id)
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", id)
+		for _, f := range d.Fields {
+			fmt.Fprintf(w, "  %s: expected %s, actual %s\n", f.Path, driftValue(f.Expected), driftValue(f.Actual))
+		}
+	}
+	fmt.Fprintf(w, "Drift from revision %d: %d drifted, %d in sync\n",
+		report.Revision, report.Drifted, report.InSync)
+}
+
+// driftValue formats a field value as JSON, or <unset> for a removed
+// field.
+func driftValue(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrintDrift(t *testing.T) {
+	report := driftReport{
+		Revision: 3,
+		InSync:   1,
+		Drifted:  2,
+		Objects: []live.ObjectDrift{
+			{
+				Object: object.UnstructuredToObjMeta(pod1),
+				Fields: []live.FieldDrift{
+					{Path: "spec.containers[0].image", Expected: "app:v1", Actual: "app:v2"},
+					{Path: "metadata.labels.app", Expected: "a"},
+				},
+			},
+			{Object: object.UnstructuredToObjMeta(pod2), Missing: true},
+		},
+	}
+
+	var out bytes.Buffer
+	printDrift(&out, report)
+	assert.Contains(t, out.String(), "Pod "+testNamespace+"/pod-1:\n")
+	assert.Contains(t, out.String(), `  spec.containers[0].image: expected "app:v1", actual "app:v2"`+"\n")
+	assert.Contains(t, out.String(), `  metadata.labels.app: expected "a", actual <unset>`+"\n")
+	assert.Contains(t, out.String(), "Pod "+testNamespace+"/pod-2: deleted from the cluster\n")
+	assert.Contains(t, out.String(), "Drift from revision 3: 2 drifted, 1 in sync\n")
+}
@@ -15,15 +15,39 @@
 package commands
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/kustomize/cmd/config/configcobra"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
 
 	"github.com/GoogleContainerTools/kpt/internal/cmdexport"
+	"github.com/GoogleContainerTools/kpt/internal/cmdfntest"
+	"github.com/GoogleContainerTools/kpt/internal/cmdpull"
 	"github.com/GoogleContainerTools/kpt/internal/docs/generated/fndocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnenv"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnoutput"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnpolicy"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnrunner"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/internal/util/inputs"
+	"github.com/GoogleContainerTools/kpt/internal/util/roundtrip"
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
+	"github.com/GoogleContainerTools/kpt/internal/util/sync"
+	"github.com/GoogleContainerTools/kpt/internal/util/wasm"
+	"github.com/GoogleContainerTools/kpt/internal/util/watch"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 )
 
 func GetFnCommand(name string) *cobra.Command {
-	functions := &cobra.Command{
+	fn := &cobra.Command{
 		Use:     "fn",
 		Short:   fndocs.FnShort,
 		Long:    fndocs.FnLong,
@@ -41,10 +65,7 @@ func GetFnCommand(name string) *cobra.Command {
 		},
 	}
 
-	run := configcobra.RunFn(name)
-	run.Short = fndocs.RunShort
-	run.Long = fndocs.RunShort + "\n" + fndocs.RunLong
-	run.Example = fndocs.RunExamples
+	run := newRunCommand(name)
 
 	source := configcobra.Source(name)
 	source.Short = fndocs.SourceShort
@@ -55,7 +76,588 @@ func GetFnCommand(name string) *cobra.Command {
 	sink.Short = fndocs.SinkShort
 	sink.Long = fndocs.SinkShort + "\n" + fndocs.SinkLong
 	sink.Example = fndocs.SinkExamples
+	var sinkRoundtrip roundtripFlags
+	sinkRoundtrip.register(sink)
+	sink.RunE = sinkRoundtrip.wrap(sink.RunE)
+
+	fn.AddCommand(run, source, sink, cmdexport.ExportCommand(), cmdpull.NewCommand(name),
+		cmdfntest.NewCommand(name, func() *cobra.Command { return newRunCommand(name) }))
+	return fn
+}
+
+// newRunCommand returns the kpt fn run command.
+func newRunCommand(name string) *cobra.Command {
+	run := configcobra.RunFn(name)
+	run.Short = fndocs.RunShort
+	run.Long = fndocs.RunShort + "\n" + fndocs.RunLong
+	run.Example = fndocs.RunExamples
+	preRunE := run.PreRunE
+	run.PreRunE = func(c *cobra.Command, args []string) error {
+		if err := verifyFunctionImage(c); err != nil {
+			return err
+		}
+		if err := checkInputs(c, args); err != nil {
+			return err
+		}
+		if preRunE != nil {
+			return preRunE(c, args)
+		}
+		return nil
+	}
+	var flags runFlags
+	run.Flags().StringVar(&flags.wasmPath, "wasm-path", "",
+		"run a function compiled to WebAssembly with a WASI runtime.")
+	run.Flags().StringVar(&flags.runnerAddress, "runner-address", "",
+		"run function containers with the remote function runner at this address. "+
+			"Defaults to $"+fnrunner.AddressEnv+".")
+	run.Flags().BoolVar(&flags.offline, "offline", false,
+		"fail if the image of any function isn't in the local cache, instead of pulling it.")
+	run.Flags().BoolVar(&flags.failOnTagChange, "fail-on-tag-change", false,
+		"fail instead of warning if the digest of a function image changed since the package was last run.")
+	run.Flags().StringSliceVar(&flags.fnAllowlist, "fn-allowlist", nil,
+		"only run the function images matching these patterns, in addition to the function policy.")
+	run.Flags().BoolVar(&flags.watch, "watch", false,
+		"run the functions again whenever the files of the package change, until interrupted.")
+	run.Flags().BoolVar(&flags.liveDryRun, "live-dry-run", false,
+		"run kpt live apply --dry-run on the package after the functions, with --watch.")
+	run.Flags().StringVar(&flags.outputDir, "output-dir", "",
+		"write the resources of the rendered package to this directory, replacing its content, "+
+			"instead of changing the package.")
+	run.Flags().StringVar(&flags.output, "output", "",
+		"write the rendered package to stdout instead of changing the package. One of: "+
+			strings.Join(fnoutput.Formats, ", ")+".")
+	run.Flags().StringVar(&flags.resultsFormat, "results-format", "yaml",
+		"the format of the files written to --results-dir. One of: yaml, json.")
+	run.Flags().BoolVar(&flags.resultsStream, "results-stream", false,
+		"stream the results reported by the functions to stderr as newline-delimited JSON events.")
+	run.Flags().BoolVar(&flags.resultsAnnotate, "results-annotate", false,
+		"inject the results reported by the functions as the "+functions.ResultsAnnotation+
+			" annotation of the resources they are about.")
+	run.Flags().StringVar(&flags.envFromFile, "env-from-file", "",
+		"pass the KEY=value environment variables of this file to the function containers as secrets.")
+	run.Flags().StringArrayVar(&flags.secretEnv, "secret-env", nil,
+		"pass this environment variable to the function containers as a secret.")
+	run.Flags().StringVar(&flags.environment, "environment", "",
+		"hydrate the package for this environment of its Kptfile, with its setters and functions. "+
+			"Requires --output or --output-dir.")
+	flags.roundtrip.register(run)
+	runE := flags.roundtrip.wrap(flags.withResults(flags.withEnvironment(flags.withSecrets(run.RunE))))
+	run.RunE = func(c *cobra.Command, args []string) error {
+		if flags.outputDir != "" || flags.output != "" {
+			return flags.runOutput(c, args, runE)
+		}
+		if !flags.watch {
+			if flags.liveDryRun {
+				return errors.Errorf("--live-dry-run requires --watch")
+			}
+			return flags.run(c, args, runE)
+		}
+		return flags.runWatch(c, args, runE)
+	}
+	return run
+}
+
+// checkInputs fails if required inputs of the package the functions are run
+// on aren't set, or if its inputs or setters have invalid values.
+func checkInputs(c *cobra.Command, args []string) error {
+	dirs := args
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		dirs = args[:dash]
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	if err := inputs.Check(dirs[0]); err != nil {
+		return err
+	}
+	return setters.CheckValues(dirs[0], nil)
+}
+
+// runFlags are the flags kpt adds to kpt fn run.
+type runFlags struct {
+	wasmPath, runnerAddress  string
+	offline, failOnTagChange bool
+	fnAllowlist              []string
+	watch, liveDryRun        bool
+	outputDir, output        string
+	resultsFormat            string
+	resultsStream            bool
+	resultsAnnotate          bool
+	envFromFile              string
+	secretEnv                []string
+	environment              string
+	roundtrip                roundtripFlags
+}
+
+// roundtripFlags are the flags preserving the formatting of the resources
+// of the package written by kpt fn run and kpt fn sink.
+type roundtripFlags struct {
+	strict, verify bool
+}
+
+func (f *roundtripFlags) register(c *cobra.Command) {
+	c.Flags().BoolVar(&f.strict, "strict-roundtrip", false,
+		"preserve the comments, key order, anchors and indentation of the resources the functions didn't change.")
+	c.Flags().BoolVar(&f.verify, "verify-roundtrip", false,
+		"fail if the formatting of resources the functions didn't change was changed.")
+}
+
+// wrap returns runE restoring the formatting of the unchanged resources of
+// the package written by runE with --strict-roundtrip, or failing if their
+// formatting changed with --verify-roundtrip.
+func (f *roundtripFlags) wrap(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, args []string) error {
+		dirs := args
+		if dash := c.ArgsLenAtDash(); dash >= 0 {
+			dirs = args[:dash]
+		}
+		// nothing is written to the package without a directory or with
+		// --dry-run
+		if (!f.strict && !f.verify) || len(dirs) == 0 || flagValue(c, "dry-run") == "true" {
+			return runE(c, args)
+		}
+		snapshot, err := roundtrip.Take(dirs[0])
+		if err != nil {
+			return err
+		}
+		if err := runE(c, args); err != nil {
+			return err
+		}
+		if f.strict {
+			return snapshot.Restore()
+		}
+		return snapshot.Verify()
+	}
+}
+
+// withSecrets returns runE passing the variables of --env-from-file and
+// --secret-env to the function containers by name, so their values aren't
+// in the arguments of the container runtime, and redacting their values
+// from stderr, the results and errors.  The resources written to stdout are
+// passed through unchanged, since redacting them would corrupt the
+// resources.  The variables of --env-from-file are only set while the
+// functions run.
+func (f *runFlags) withSecrets(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, args []string) error {
+		if f.envFromFile == "" && len(f.secretEnv) == 0 {
+			return runE(c, args)
+		}
+		secrets := map[string]string{}
+		if f.envFromFile != "" {
+			env, err := fnenv.ReadFile(f.envFromFile)
+			if err != nil {
+				return err
+			}
+			unset, err := fnenv.Setenv(env)
+			if err != nil {
+				return err
+			}
+			defer unset()
+			for k, v := range env {
+				secrets[k] = v
+			}
+		}
+		for _, k := range f.secretEnv {
+			v, found := os.LookupEnv(k)
+			if !found {
+				return errors.Errorf("--secret-env %s is not set", k)
+			}
+			secrets[k] = v
+		}
+		var values []string
+		for k, v := range secrets {
+			if err := c.Flags().Set("env", k); err != nil {
+				return errors.Wrap(err)
+			}
+			values = append(values, v)
+		}
+		redactor := fnenv.NewRedactor(values)
+		restore, err := redactor.RedirectStderr()
+		if err != nil {
+			return err
+		}
+		err = runE(c, args)
+		restore()
+		// the results are written even if a function failed
+		if resultsDir := flagValue(c, "results-dir"); resultsDir != "" {
+			if redactErr := redactor.RedactDir(resultsDir); err == nil {
+				err = redactErr
+			}
+		}
+		if err != nil {
+			return errors.Errorf("%s", redactor.Redact(err.Error()))
+		}
+		return nil
+	}
+}
+
+// withEnvironment returns runE hydrating the package for --environment: the
+// setters of the environment are set before runE, and the functions of the
+// environment are run after it.  The package is only hydrated for an
+// environment with --output or --output-dir, which render a copy of it, so
+// the package itself isn't changed.
+func (f *runFlags) withEnvironment(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, args []string) error {
+		if f.environment == "" {
+			return runE(c, args)
+		}
+		if f.output == "" && f.outputDir == "" {
+			return errors.Errorf("--environment requires --output or --output-dir")
+		}
+		dirs := args
+		if dash := c.ArgsLenAtDash(); dash >= 0 {
+			dirs = args[:dash]
+		}
+		env, err := functions.Environment(dirs[0], f.environment)
+		if err != nil {
+			return err
+		}
+		if env == nil {
+			return errors.Errorf("environment %s is not declared in the Kptfile of %s", f.environment, dirs[0])
+		}
+		opts := functions.RunOptions{StdErr: c.ErrOrStderr()}
+		if len(env.Setters) > 0 {
+			setter, err := functions.SetterFunction(env.Setters)
+			if err != nil {
+				return err
+			}
+			if err := functions.RunFunctions(dirs[0], []kptfile.Function{setter}, opts); err != nil {
+				return err
+			}
+		}
+		if err := runE(c, args); err != nil {
+			return err
+		}
+		fns, err := sync.ResolveImports(dirs[0], env.Functions)
+		if err != nil {
+			return err
+		}
+		return functions.RunFunctions(dirs[0], fns, opts)
+	}
+}
+
+// environmentImages returns the images of the container functions of
+// --environment declared in the Kptfile of the package at dir, so they are
+// checked and pinned as the other functions of the package.
+func (f runFlags) environmentImages(dir string) ([]string, error) {
+	if f.environment == "" || dir == "" {
+		return nil, nil
+	}
+	env, err := functions.Environment(dir, f.environment)
+	if err != nil || env == nil {
+		return nil, err
+	}
+	fns, err := sync.ResolveImports(dir, env.Functions)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, fn := range fns {
+		if fn.Image != "" && (fn.Runtime == "" || fn.Runtime == kptfile.ContainerRuntime) && fn.Exec == nil {
+			images = append(images, fn.Image)
+		}
+	}
+	return images, nil
+}
+
+// withResults returns runE writing the results reported by the functions
+// in --results-format, streaming them with --results-stream, and injecting
+// them into the package with --results-annotate.  The results are written
+// even if a function fails.
+func (f *runFlags) withResults(runE func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(c *cobra.Command, args []string) error {
+		switch f.resultsFormat {
+		case "yaml", "json":
+		default:
+			return errors.Errorf("unsupported --results-format %q, must be one of: yaml, json", f.resultsFormat)
+		}
+		if f.resultsFormat == "yaml" && !f.resultsStream && !f.resultsAnnotate {
+			return runE(c, args)
+		}
+		dirs := args
+		if dash := c.ArgsLenAtDash(); dash >= 0 {
+			dirs = args[:dash]
+		}
+		if f.resultsAnnotate {
+			if len(dirs) == 0 {
+				return errors.Errorf("--results-annotate requires a package directory")
+			}
+			if flagValue(c, "dry-run") == "true" {
+				return errors.Errorf("--results-annotate can't be used with --dry-run")
+			}
+		}
+
+		resultsDir := flagValue(c, "results-dir")
+		if resultsDir == "" {
+			if f.resultsFormat != "yaml" {
+				return errors.Errorf("--results-format requires --results-dir")
+			}
+			// the results are read from files written by the functions
+			tmp, err := ioutil.TempDir("", "kpt-fn-results-")
+			if err != nil {
+				return errors.Wrap(err)
+			}
+			defer os.RemoveAll(tmp)
+			if err := c.Flags().Set("results-dir", tmp); err != nil {
+				return errors.Wrap(err)
+			}
+			defer func() { _ = c.Flags().Set("results-dir", "") }()
+			resultsDir = tmp
+		}
+
+		runErr := runE(c, args)
+		files, err := functions.ReadResultsDir(resultsDir)
+		if err != nil {
+			return err
+		}
+		if f.resultsStream {
+			if err := functions.StreamResults(c.ErrOrStderr(), files); err != nil {
+				return err
+			}
+		}
+		if f.resultsAnnotate {
+			var results []functions.Result
+			for _, file := range files {
+				results = append(results, file.Results...)
+			}
+			if err := functions.AnnotateResults(dirs[0], results); err != nil {
+				return err
+			}
+		}
+		if f.resultsFormat == "json" {
+			if err := functions.WriteResultsJSON(resultsDir, files); err != nil {
+				return err
+			}
+		}
+		return runErr
+	}
+}
+
+// runOutput runs the functions on a copy of the package, and writes the
+// rendered copy to --output-dir or to stdout in the --output format.
+func (f runFlags) runOutput(c *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	dash := c.ArgsLenAtDash()
+	dirs := args
+	if dash >= 0 {
+		dirs = args[:dash]
+	}
+	if len(dirs) == 0 {
+		return errors.Errorf("--output and --output-dir require a package directory")
+	}
+	for _, name := range []string{"dry-run", "watch"} {
+		if flagValue(c, name) == "true" {
+			return errors.Errorf("--output and --output-dir can't be used with --%s", name)
+		}
+	}
+	return fnoutput.Command{
+		Source: dirs[0],
+		Render: func(dir string) error {
+			return f.run(c, append([]string{dir}, args[1:]...), runE)
+		},
+		Format: f.output,
+		Dir:    f.outputDir,
+		Output: c.OutOrStdout(),
+	}.Run()
+}
+
+// runWatch runs the functions on the package, and again whenever its files
+// change, followed by kpt live apply --dry-run with --live-dry-run.
+func (f runFlags) runWatch(c *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	dirs := args
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		dirs = args[:dash]
+	}
+	if len(dirs) == 0 {
+		return errors.Errorf("--watch requires a package directory")
+	}
+	if flagValue(c, "dry-run") == "true" {
+		return errors.Errorf("--watch can't be used with --dry-run")
+	}
+	return watch.Command{
+		Dir: dirs[0],
+		OnChange: func() error {
+			if err := f.run(c, args, runE); err != nil {
+				return err
+			}
+			if !f.liveDryRun {
+				return nil
+			}
+			kpt, err := os.Executable()
+			if err != nil {
+				return errors.Wrap(err)
+			}
+			cmd := exec.Command(kpt, "live", "apply", dirs[0], "--dry-run")
+			cmd.Stdout, cmd.Stderr = c.OutOrStdout(), c.ErrOrStderr()
+			return errors.Wrap(cmd.Run())
+		},
+		Out: c.ErrOrStderr(),
+	}.Run()
+}
 
-	functions.AddCommand(run, source, sink, cmdexport.ExportCommand())
-	return functions
+// run runs the function of --wasm-path, or the function of --image with the
+// function runner if set.  Otherwise the WebAssembly functions declared in
+// the package are run before the functions run by runE, or before the
+// container functions run by the function runner.  The digests of the
+// images of the container functions are recorded in the Kptfile.
+func (f runFlags) run(c *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	wasmPath, runnerAddress := f.wasmPath, f.runnerAddress
+	if runnerAddress == "" {
+		runnerAddress = os.Getenv(fnrunner.AddressEnv)
+	}
+	dirs, fnArgs := args, []string(nil)
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		dirs, fnArgs = args[:dash], args[dash:]
+	}
+	dir := ""
+	if len(dirs) > 0 {
+		dir = dirs[0]
+	}
+	envImages, err := f.environmentImages(dir)
+	if err != nil {
+		return err
+	}
+	images := envImages
+	if wasmPath == "" {
+		fnImages, err := functionImages(c, args)
+		if err != nil {
+			return err
+		}
+		images = append(images, fnImages...)
+	}
+	policy, err := fnpolicy.DefaultPolicy(f.fnAllowlist)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(images...); err != nil {
+		return err
+	}
+	if f.offline {
+		if runnerAddress != "" {
+			return errors.Errorf("--offline can't be used with a function runner")
+		}
+		if err := fnimage.CheckCached(images); err != nil {
+			return err
+		}
+	}
+	dryRun := flagValue(c, "dry-run") == "true"
+	image := flagValue(c, "image")
+
+	if wasmPath != "" || (runnerAddress != "" && image != "") {
+		flag, conflicting := "runner-address", []string{"exec-path", "star-path"}
+		if wasmPath != "" {
+			flag, conflicting = "wasm-path", append(conflicting, "image")
+		}
+		for _, name := range conflicting {
+			if flagValue(c, name) != "" {
+				return errors.Errorf("--%s can't be used with --%s", flag, name)
+			}
+		}
+		data := map[string]string{}
+		for _, a := range fnArgs {
+			kv := strings.SplitN(a, "=", 2)
+			if len(kv) != 2 {
+				return errors.Errorf("function arguments must be of the form key=value: %s", a)
+			}
+			data[kv[0]] = kv[1]
+		}
+		if wasmPath != "" {
+			return wasm.Command{Path: wasmPath, Dir: dir, Data: data, DryRun: dryRun,
+				Input: c.InOrStdin(), Output: c.OutOrStdout()}.Run()
+		}
+		return fnrunner.Command{Address: runnerAddress, Image: image, Dir: dir, Data: data,
+			DryRun: dryRun, Input: c.InOrStdin(), Output: c.OutOrStdout()}.Run()
+	}
+
+	if dir == "" {
+		return runE(c, args)
+	}
+	for _, name := range []string{"image", "exec-path", "star-path", "fn-path"} {
+		// declarative functions are ignored when running imperatively
+		if flagValue(c, name) != "" && flagValue(c, name) != "[]" {
+			return runE(c, args)
+		}
+	}
+	// the images of offline runs are resolved by the container runtime
+	var digests map[string]string
+	if !f.offline {
+		if digests, err = fnimage.Pin(dir, f.failOnTagChange, c.ErrOrStderr(), envImages...); err != nil {
+			return err
+		}
+	}
+	pkg := dir
+	if dryRun {
+		// run the functions on a copy, which is printed
+		tmp, err := ioutil.TempDir("", "kpt-fn-run-")
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		defer os.RemoveAll(tmp)
+		if err := copyutil.CopyDir(dir, tmp); err != nil {
+			return errors.Wrap(err)
+		}
+		dir = tmp
+		args = append([]string{tmp}, args[1:]...)
+	}
+	if err := wasm.Run(dir); err != nil {
+		return err
+	}
+	if runnerAddress == "" {
+		err = runE(c, args)
+	} else {
+		// the container functions are only run by the runner
+		err = fnrunner.Run(dir, runnerAddress)
+	}
+	if err != nil {
+		return err
+	}
+	if !dryRun && digests != nil {
+		return fnimage.Record(pkg, digests)
+	}
+	if !dryRun {
+		return nil
+	}
+	if runnerAddress == "" {
+		return nil
+	}
+	return kio.Pipeline{
+		Inputs:  []kio.Reader{&kio.LocalPackageReader{PackagePath: dir}},
+		Outputs: []kio.Writer{kio.ByteWriter{Writer: c.OutOrStdout()}},
+	}.Execute()
+}
+
+// functionImages returns the images of the container functions run, of
+// --image or declared in the package or the --fn-path directories.
+func functionImages(c *cobra.Command, args []string) ([]string, error) {
+	if image := flagValue(c, "image"); image != "" {
+		return []string{image}, nil
+	}
+	if flagValue(c, "exec-path") != "" || flagValue(c, "star-path") != "" {
+		return nil, nil
+	}
+	dirs := args
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		dirs = args[:dash]
+	}
+	fnPaths, _ := c.Flags().GetStringSlice("fn-path")
+	if len(fnPaths) == 0 && len(dirs) > 0 {
+		fnPaths = dirs[:1]
+	}
+	if len(fnPaths) == 0 {
+		return nil, nil
+	}
+	return fnimage.Images(fnPaths...)
+}
+
+// verifyFunctionImage verifies the signature of the image of the function
+// run with --image, if the signature policy requires signed function images.
+func verifyFunctionImage(c *cobra.Command) error {
+	f := c.Flags().Lookup("image")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	policy, err := signature.DefaultPolicy()
+	if err != nil || policy == nil || !policy.FunctionImages {
+		return err
+	}
+	return policy.VerifyImage(f.Value.String())
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -38,14 +39,15 @@ undesired results. Use the --force flag to suppress this error.
 // KptInitOptions encapsulates fields for kpt init command. This init command
 // fills in inventory values in the Kptfile.
 type KptInitOptions struct {
-	factory     cmdutil.Factory
-	ioStreams   genericclioptions.IOStreams
-	dir         string // Directory with Kptfile
-	force       bool   // Set inventory values even if already set in Kptfile
-	name        string // Inventory object name
-	namespace   string // Inventory object namespace
-	inventoryID string // Inventory object unique identifier label
-	Quiet       bool   // Print output or not
+	factory       cmdutil.Factory
+	ioStreams     genericclioptions.IOStreams
+	dir           string // Directory with Kptfile
+	force         bool   // Set inventory values even if already set in Kptfile
+	name          string // Inventory object name
+	namespace     string // Inventory object namespace
+	inventoryID   string // Inventory object unique identifier label
+	inventoryType string // Inventory object type (resourcegroup or secret)
+	Quiet         bool   // Print output or not
 }
 
 // NewKptInitOptions returns a pointer to an initial KptInitOptions structure.
@@ -145,6 +147,7 @@ func (io *KptInitOptions) updateKptfile() error {
 		Namespace:   io.namespace,
 		Name:        io.name,
 		InventoryID: io.inventoryID,
+		Type:        io.inventoryType,
 	}
 	if err := kptfileutil.WriteFile(io.dir, kf); err != nil {
 		return err
@@ -166,6 +169,9 @@ func (io *KptInitOptions) validate() error {
 	if len(io.inventoryID) == 0 {
 		return fmt.Errorf("inventoryID is missing")
 	}
+	if err := live.ValidateInventoryType(io.inventoryType); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -189,5 +195,7 @@ func NewCmdInit(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra
 	cmd.Flags().StringVar(&io.name, "name", "", "Inventory object name")
 	cmd.Flags().BoolVar(&io.force, "force", false, "Set inventory values even if already set in Kptfile")
 	cmd.Flags().BoolVar(&io.Quiet, "quiet", false, "If true, do not print output during initialization of Kptfile")
+	cmd.Flags().StringVar(&io.inventoryType, inventoryTypeFlag, "",
+		"Type of the inventory object: resourcegroup (default) or secret")
 	return cmd
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
@@ -104,12 +105,13 @@ func TestKptInitOptions_generateID(t *testing.T) {
 
 func TestKptInitOptions_updateKptfile(t *testing.T) {
 	testCases := map[string]struct {
-		kptfile     string
-		name        string
-		namespace   string
-		inventoryID string
-		force       bool
-		isError     bool
+		kptfile       string
+		name          string
+		namespace     string
+		inventoryID   string
+		inventoryType string
+		force         bool
+		isError       bool
 	}{
 		"Empty inventory name is an error": {
 			kptfile:     kptFile,
@@ -151,6 +153,24 @@ func TestKptInitOptions_updateKptfile(t *testing.T) {
 			force:       false,
 			isError:     false,
 		},
+		"Unknown inventory type is an error": {
+			kptfile:       kptFile,
+			name:          inventoryName,
+			namespace:     inventoryNamespace,
+			inventoryID:   inventoryID,
+			inventoryType: "configmap",
+			force:         false,
+			isError:       true,
+		},
+		"KptInitOptions secret inventory type": {
+			kptfile:       kptFile,
+			name:          inventoryName,
+			namespace:     inventoryNamespace,
+			inventoryID:   inventoryID,
+			inventoryType: live.InventoryTypeSecret,
+			force:         false,
+			isError:       false,
+		},
 		"KptInitOptions force sets inventory values when already set": {
 			kptfile:     kptFileWithInventory,
 			name:        inventoryName,
@@ -182,6 +202,7 @@ func TestKptInitOptions_updateKptfile(t *testing.T) {
 			initOptions.name = tc.name
 			initOptions.namespace = tc.namespace
 			initOptions.inventoryID = tc.inventoryID
+			initOptions.inventoryType = tc.inventoryType
 			err = initOptions.updateKptfile()
 
 			// Check if there should be an error
@@ -199,6 +220,7 @@ func TestKptInitOptions_updateKptfile(t *testing.T) {
 			assert.Equal(t, inventoryName, kf.Inventory.Name)
 			assert.Equal(t, inventoryNamespace, kf.Inventory.Namespace)
 			assert.Equal(t, inventoryID, kf.Inventory.InventoryID)
+			assert.Equal(t, tc.inventoryType, kf.Inventory.Type)
 		})
 	}
 }
@@ -4,12 +4,12 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"github.com/GoogleContainerTools/kpt/pkg/live"
 	"github.com/spf13/cobra"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/i18n"
@@ -20,6 +20,8 @@ type InstallRGRunner struct {
 	Command   *cobra.Command
 	ioStreams genericclioptions.IOStreams
 	factory   cmdutil.Factory
+
+	upgrade bool
 }
 
 // GetInstallRGRunner returns a pointer to an initial InstallRGRunner structure.
@@ -36,6 +38,8 @@ func GetInstallRGRunner(factory cmdutil.Factory, ioStreams genericclioptions.IOS
 			return r.Run(ioStreams.In, args)
 		},
 	}
+	cmd.Flags().BoolVar(&r.upgrade, "upgrade", false,
+		"Upgrade the ResourceGroup custom resource definition if it was installed by an older kpt.")
 
 	r.Command = cmd
 	return r
@@ -48,23 +52,90 @@ func NewCmdInstallRG(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *
 
 // Run executes the installation of the ResourceGroup custom resource definition. Uses
 // the current context of the kube config file (or the kube config flags) to
-// determine the APIServer to install the CRD.
+// determine the APIServer to install the CRD. The namespace passed with the
+// --namespace flag is created if it does not exist.
 func (ir *InstallRGRunner) Run(reader io.Reader, args []string) error {
 	// Validate the number of arguments.
 	if len(args) > 0 {
 		return fmt.Errorf("too many arguments; install-resource-group takes no arguments")
 	}
+	namespace, explicit, err := ir.factory.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	if !explicit {
+		namespace = ""
+	}
+	d, err := ir.factory.DynamicClient()
+	if err != nil {
+		return err
+	}
+	mapper, err := ir.factory.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	result, err := live.InstallResourceGroup(context.Background(), d, mapper, namespace, ir.upgrade)
+	if result.NamespaceCreated {
+		fmt.Fprintf(ir.ioStreams.Out, "namespace %s created\n", namespace)
+	}
 	fmt.Fprint(ir.ioStreams.Out, "installing ResourceGroup custom resource definition...")
-	// Apply the ResourceGroup CRD to the cluster, swallowing an "AlreadyExists" error.
-	err := live.ApplyResourceGroupCRD(ir.factory)
-	if apierrors.IsAlreadyExists(err) {
+	if err != nil {
+		fmt.Fprintln(ir.ioStreams.Out, "failed")
+		return err
+	}
+	switch {
+	case result.Upgraded:
+		fmt.Fprintf(ir.ioStreams.Out, "upgraded from version %d to %d...",
+			result.InstalledVersion, live.ResourceGroupCRDVersion)
+	case result.Status == live.CRDOutdated:
+		fmt.Fprintln(ir.ioStreams.Out, "failed")
+		return fmt.Errorf("installed version %d is older than version %d of kpt; "+
+			"use --upgrade to upgrade it", result.InstalledVersion, live.ResourceGroupCRDVersion)
+	case result.Status != live.CRDNotInstalled:
 		fmt.Fprint(ir.ioStreams.Out, "already installed...")
-		err = nil
 	}
+	fmt.Fprintln(ir.ioStreams.Out, "success")
+	printCRDSkew(ir.ioStreams.Out, result)
+	return nil
+}
+
+// installResourceGroup installs the ResourceGroup custom resource
+// definition if it is not installed, and creates the namespace of the
+// inventory object if it is not empty and does not exist. A warning is
+// printed if the installed definition does not have the version of kpt.
+func installResourceGroup(f cmdutil.Factory, w io.Writer, namespace string) error {
+	d, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	mapper, err := f.ToRESTMapper()
 	if err != nil {
-		fmt.Fprintln(ir.ioStreams.Out, "failed")
-	} else {
-		fmt.Fprintln(ir.ioStreams.Out, "success")
+		return err
+	}
+	result, err := live.InstallResourceGroup(context.Background(), d, mapper, namespace, false)
+	if err != nil {
+		return err
+	}
+	if result.NamespaceCreated {
+		fmt.Fprintf(w, "namespace %s created\n", namespace)
+	}
+	printCRDSkew(w, result)
+	return nil
+}
+
+// printCRDSkew prints a warning if the installed ResourceGroup custom
+// resource definition is older or newer than the one of kpt.
+func printCRDSkew(w io.Writer, result live.InstallResult) {
+	switch {
+	case result.Upgraded:
+		return
+	case result.Status == live.CRDOutdated:
+		fmt.Fprintf(w, "warning: ResourceGroup custom resource definition version %d is older than "+
+			"version %d of kpt; run kpt live install-resource-group --upgrade\n",
+			result.InstalledVersion, live.ResourceGroupCRDVersion)
+	case result.Status == live.CRDNewer:
+		fmt.Fprintf(w, "warning: ResourceGroup custom resource definition version %d is newer than "+
+			"version %d of kpt; consider upgrading kpt\n",
+			result.InstalledVersion, live.ResourceGroupCRDVersion)
 	}
-	return err
 }
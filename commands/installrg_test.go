@@ -0,0 +1,49 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintCRDSkew(t *testing.T) {
+	testCases := map[string]struct {
+		result   live.InstallResult
+		expected string
+	}{
+		"No warning for a new CRD": {
+			result: live.InstallResult{Status: live.CRDNotInstalled},
+		},
+		"No warning for a current CRD": {
+			result: live.InstallResult{Status: live.CRDCurrent, InstalledVersion: live.ResourceGroupCRDVersion},
+		},
+		"No warning for an upgraded CRD": {
+			result: live.InstallResult{Status: live.CRDOutdated, Upgraded: true},
+		},
+		"Outdated CRD": {
+			result:   live.InstallResult{Status: live.CRDOutdated},
+			expected: "is older than version 1 of kpt; run kpt live install-resource-group --upgrade",
+		},
+		"Newer CRD": {
+			result:   live.InstallResult{Status: live.CRDNewer, InstalledVersion: 2},
+			expected: "version 2 is newer than version 1 of kpt",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var out bytes.Buffer
+			printCRDSkew(&out, tc.result)
+			if tc.expected == "" {
+				assert.Empty(t, out.String())
+				return
+			}
+			assert.Contains(t, out.String(), tc.expected)
+		})
+	}
+}
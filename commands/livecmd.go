@@ -15,7 +15,9 @@
 package commands
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/GoogleContainerTools/kpt/internal/cmdfetchk8sschema"
 	"github.com/GoogleContainerTools/kpt/internal/docs/generated/livedocs"
@@ -24,18 +26,38 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog"
 	"k8s.io/kubectl/pkg/cmd/util"
-	"sigs.k8s.io/cli-utils/cmd/destroy"
-	"sigs.k8s.io/cli-utils/cmd/diff"
 	"sigs.k8s.io/cli-utils/cmd/initcmd"
-	"sigs.k8s.io/cli-utils/cmd/status"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/provider"
 )
 
-// resourceGroupEnv is an environment variable which hides code to implement
-// the ResourceGroup inventory object and migrating to ResourceGroup inventory.
+// resourceGroupEnv is an environment variable which makes init create a
+// ResourceGroup inventory in the Kptfile instead of a ConfigMap inventory,
+// and always installs the ResourceGroup CRD before apply.
 const resourceGroupEnv = "RESOURCE_GROUP_INVENTORY"
 
+// inventoryTypeFlag is the flag selecting the type of the inventory
+// object generated from the Kptfile.
+const inventoryTypeFlag = "inventory-type"
+
+// inventoryClientFlag is the flag selecting the InventoryClient storing
+// the inventory of apply, destroy and status.
+const inventoryClientFlag = "inventory-client"
+
+// addInventoryClientFlag adds --inventory-client to the command.
+func addInventoryClientFlag(cmd *cobra.Command) {
+	cmd.Flags().String(inventoryClientFlag, live.ClusterInventoryClientName,
+		fmt.Sprintf("Inventory client storing the inventory: one of %s",
+			strings.Join(live.InventoryClientNames(), ", ")))
+}
+
+// newInventoryClient returns the InventoryClient selected by
+// --inventory-client, which stores the inventory in the cluster of the
+// provider unless another client is registered and selected.
+func newInventoryClient(cmd *cobra.Command, p provider.Provider) (live.InventoryClient, error) {
+	return live.NewInventoryClient(flagValue(cmd, inventoryClientFlag), p)
+}
+
 func GetLiveCommand(name string, f util.Factory) *cobra.Command {
 	liveCmd := &cobra.Command{
 		Use:   "live",
@@ -59,25 +81,36 @@ func GetLiveCommand(name string, f util.Factory) *cobra.Command {
 		ErrOut: os.Stderr,
 	}
 
-	// The default provider is for ConfigMap inventory, but if the magic env
-	// var exists, then the provider which handles both ConfigMap and ResourceGroup
-	// inventory objects is used. If a package has both inventory objects, then
-	// an error is thrown.
-	var p provider.Provider = provider.NewProvider(f)
-	var l manifestreader.ManifestLoader = manifestreader.NewManifestLoader(f)
-	if _, exists := os.LookupEnv(resourceGroupEnv); exists {
-		klog.V(2).Infoln("provider supports ResourceGroup and ConfigMap inventory")
-		p = live.NewDualDelegatingProvider(f)
-		l = live.NewDualDelegatingManifestReader(f)
-	}
+	// The provider handles both ConfigMap and ResourceGroup inventory
+	// objects. If a package has both inventory objects, then an error
+	// is thrown.
+	p := live.NewDualDelegatingProvider(f)
+	l := live.NewDualDelegatingManifestReader(f)
 
 	// The default init command creates the ConfigMap inventory yaml. If the magic
 	// env var exists, then we use the init command which updates a Kptfile for
 	// the ResourceGroup inventory object.
+	// Passing --inventory-type also selects the init command updating the
+	// Kptfile, since only Kptfile inventories support other types.
 	initCmd := initcmd.NewCmdInit(f, ioStreams)
+	kptInitCmd := NewCmdInit(f, ioStreams)
 	if _, exists := os.LookupEnv(resourceGroupEnv); exists {
 		klog.V(2).Infoln("init command updates Kptfile for ResourceGroup inventory")
-		initCmd = NewCmdInit(f, ioStreams)
+		initCmd = kptInitCmd
+	} else {
+		initCmd.Flags().AddFlag(kptInitCmd.Flags().Lookup(inventoryTypeFlag))
+		run, runE := initCmd.Run, initCmd.RunE
+		initCmd.Run = nil
+		initCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed(inventoryTypeFlag) {
+				return kptInitCmd.RunE(kptInitCmd, args)
+			}
+			if runE != nil {
+				return runE(cmd, args)
+			}
+			run(cmd, args)
+			return nil
+		}
 	}
 	initCmd.Short = livedocs.InitShort
 	initCmd.Long = livedocs.InitShort + "\n" + livedocs.InitLong
@@ -89,46 +122,73 @@ func GetLiveCommand(name string, f util.Factory) *cobra.Command {
 	applyCmd.Long = livedocs.ApplyShort + "\n" + livedocs.ApplyLong
 	applyCmd.Example = livedocs.ApplyExamples
 
+	applyCmd.Flags().StringVar(&l.InventoryType, inventoryTypeFlag, "",
+		"Override the type of the Kptfile inventory object: resourcegroup or secret")
+	addInventoryClientFlag(applyCmd)
+
 	previewCmd := GetPreviewRunner(p, l, ioStreams).Command()
 	previewCmd.Short = livedocs.PreviewShort
 	previewCmd.Long = livedocs.PreviewShort + "\n" + livedocs.PreviewLong
 	previewCmd.Example = livedocs.PreviewExamples
 
-	diffCmd := diff.NewCmdDiff(f, ioStreams)
+	diffCmd := GetDiffRunner(p, l, ioStreams).Command
 	diffCmd.Short = livedocs.DiffShort
 	diffCmd.Long = livedocs.DiffShort + "\n" + livedocs.DiffLong
 	diffCmd.Example = livedocs.DiffExamples
 
-	destroyCmd := destroy.GetDestroyRunner(p, l, ioStreams).Command
+	driftCmd := GetDriftRunner(p, l, ioStreams).Command
+	driftCmd.Short = livedocs.DriftShort
+	driftCmd.Long = livedocs.DriftShort + "\n" + livedocs.DriftLong
+	driftCmd.Example = livedocs.DriftExamples
+
+	destroyCmd := GetDestroyRunner(p, l, ioStreams).Command
 	destroyCmd.Short = livedocs.DestroyShort
 	destroyCmd.Long = livedocs.DestroyShort + "\n" + livedocs.DestroyLong
 	destroyCmd.Example = livedocs.DestroyExamples
+	destroyCmd.Flags().StringVar(&l.InventoryType, inventoryTypeFlag, "",
+		"Override the type of the Kptfile inventory object: resourcegroup or secret")
+	addInventoryClientFlag(destroyCmd)
 
-	statusCmd := status.GetStatusRunner(p, l).Command
+	statusCmd := GetStatusRunner(p, l, ioStreams).Command
 	statusCmd.Short = livedocs.StatusShort
 	statusCmd.Long = livedocs.StatusLong
 	statusCmd.Example = livedocs.StatusExamples
+	addInventoryClientFlag(statusCmd)
 
-	fetchOpenAPICmd := cmdfetchk8sschema.NewCommand(name, f, ioStreams)
+	planCmd := GetPlanRunner(p, l, ioStreams).Command
+	planCmd.Short = livedocs.PlanShort
+	planCmd.Long = livedocs.PlanShort + "\n" + livedocs.PlanLong
+	planCmd.Example = livedocs.PlanExamples
 
-	liveCmd.AddCommand(initCmd, applyCmd, previewCmd, diffCmd, destroyCmd,
-		fetchOpenAPICmd, statusCmd)
+	adoptCmd := GetAdoptRunner(p, l, ioStreams).Command
+	adoptCmd.Short = livedocs.AdoptShort
+	adoptCmd.Long = livedocs.AdoptShort + "\n" + livedocs.AdoptLong
+	adoptCmd.Example = livedocs.AdoptExamples
 
-	// If the magic env var exists, then add the migrate to change
-	// from ConfigMap to ResourceGroup inventory object. Also add
-	// the install-resource-group command.
-	if _, exists := os.LookupEnv(resourceGroupEnv); exists {
-		klog.V(2).Infoln("adding kpt live migrate command")
-		// Create a ConfigMap and a ResourceGroup provider for the
-		// migrate command, and add the migrate command to live command.
-		cmProvider := provider.NewProvider(f)
-		rgProvider := live.NewResourceGroupProvider(f)
-		cmLoader := manifestreader.NewManifestLoader(f)
-		rgLoader := live.NewResourceGroupManifestLoader(f)
-		migrateCmd := GetMigrateRunner(cmProvider, rgProvider, cmLoader, rgLoader, ioStreams).Command
-		installRGCmd := GetInstallRGRunner(f, ioStreams).Command
-		liveCmd.AddCommand(migrateCmd, installRGCmd)
-	}
+	rollbackCmd := GetRollbackRunner(p, l, ioStreams).Command
+	rollbackCmd.Short = livedocs.RollbackShort
+	rollbackCmd.Long = livedocs.RollbackShort + "\n" + livedocs.RollbackLong
+	rollbackCmd.Example = livedocs.RollbackExamples
+
+	fetchOpenAPICmd := cmdfetchk8sschema.NewCommand(name, f, ioStreams)
+
+	liveCmd.AddCommand(initCmd, applyCmd, previewCmd, planCmd, diffCmd, driftCmd, adoptCmd, rollbackCmd,
+		destroyCmd, fetchOpenAPICmd, statusCmd)
+
+	// Add the migrate command to change from ConfigMap to ResourceGroup
+	// inventory object, and the install-resource-group command.
+	// Create a ConfigMap and a ResourceGroup provider for the
+	// migrate command, and add the migrate command to live command.
+	cmProvider := provider.NewProvider(f)
+	rgProvider := live.NewResourceGroupProvider(f)
+	cmLoader := manifestreader.NewManifestLoader(f)
+	rgLoader := live.NewResourceGroupManifestLoader(f)
+	migrateCmd := GetMigrateRunner(cmProvider, rgProvider, cmLoader, rgLoader, ioStreams).Command
+	installRGCmd := GetInstallRGRunner(f, ioStreams).Command
+	installRGCmd.Short = livedocs.InstallResourceGroupShort
+	installRGCmd.Long = livedocs.InstallResourceGroupShort + "\n" + livedocs.InstallResourceGroupLong
+	installRGCmd.Example = livedocs.InstallResourceGroupExamples
+	liveCmd.AddCommand(migrateCmd, installRGCmd)
 
 	return liveCmd
 }
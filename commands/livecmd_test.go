@@ -0,0 +1,50 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+func TestNewInventoryClient(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+	defer tf.Cleanup()
+	p := live.NewDualDelegatingProvider(tf)
+	memory := live.NewMemoryInventoryClient()
+	assert.NoError(t, live.RegisterInventoryClient("commands-test", func(provider.Provider) (live.InventoryClient, error) {
+		return memory, nil
+	}))
+
+	cmd := &cobra.Command{}
+	addInventoryClientFlag(cmd)
+	c, err := newInventoryClient(cmd, p)
+	assert.NoError(t, err)
+	assert.IsType(t, &live.ClusterInventoryClient{}, c)
+
+	assert.NoError(t, cmd.Flags().Set(inventoryClientFlag, "commands-test"))
+	c, err = newInventoryClient(cmd, p)
+	assert.NoError(t, err)
+	assert.Equal(t, memory, c)
+
+	assert.NoError(t, cmd.Flags().Set(inventoryClientFlag, "does-not-exist"))
+	_, err = newInventoryClient(cmd, p)
+	assert.Error(t, err)
+}
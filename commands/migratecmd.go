@@ -13,9 +13,9 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/pkg/client"
 	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog"
@@ -29,6 +29,14 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/provider"
 )
 
+// dryRunInventoryID is the placeholder inventory ID used to preview the
+// owning inventory annotation rewrite during a dry run.
+const dryRunInventoryID = "dry-run"
+
+// migrationProgressInterval is the number of objects migrated between
+// progress reports.
+const migrationProgressInterval = 100
+
 // MigrateRunner encapsulates fields for the kpt migrate command.
 type MigrateRunner struct {
 	Command   *cobra.Command
@@ -36,6 +44,9 @@ type MigrateRunner struct {
 
 	dir         string
 	dryRun      bool
+	rollback    bool
+	qps         float32
+	burst       int
 	initOptions *KptInitOptions
 	cmProvider  provider.Provider
 	rgProvider  provider.Provider
@@ -50,6 +61,9 @@ func GetMigrateRunner(cmProvider provider.Provider, rgProvider provider.Provider
 	r := &MigrateRunner{
 		ioStreams:   ioStreams,
 		dryRun:      false,
+		rollback:    true,
+		qps:         client.DefaultQPS,
+		burst:       client.DefaultBurst,
 		initOptions: NewKptInitOptions(cmProvider.Factory(), ioStreams),
 		cmProvider:  cmProvider,
 		rgProvider:  rgProvider,
@@ -62,6 +76,12 @@ func GetMigrateRunner(cmProvider provider.Provider, rgProvider provider.Provider
 		Use:                   "migrate DIRECTORY",
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Migrate inventory from ConfigMap to ResourceGroup custom resource"),
+		Long: i18n.T(`Migrate inventory from ConfigMap to ResourceGroup custom resource.
+
+If any object fails to migrate, the objects already migrated are restored
+to the ConfigMap inventory unless --rollback-on-failure=false is passed.
+An interrupted migration is resumed by running migrate again; objects
+already owned by the ResourceGroup inventory are left unchanged.`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Fprint(ioStreams.Out, "inventory migration...\n")
 			if err := r.Run(ioStreams.In, args); err != nil {
@@ -76,6 +96,12 @@ func GetMigrateRunner(cmProvider provider.Provider, rgProvider provider.Provider
 	cmd.Flags().StringVar(&r.initOptions.name, "name", "", "Inventory object name")
 	cmd.Flags().BoolVar(&r.initOptions.force, "force", false, "Set inventory values even if already set in Kptfile")
 	cmd.Flags().BoolVar(&r.dryRun, "dry-run", false, "Do not actually migrate, but show steps")
+	cmd.Flags().BoolVar(&r.rollback, "rollback-on-failure", true,
+		"Restore the ConfigMap inventory ownership of migrated objects if any object fails to migrate")
+	cmd.Flags().Float32Var(&r.qps, "qps", client.DefaultQPS,
+		"Maximum requests per second sent while migrating objects. Zero disables throttling")
+	cmd.Flags().IntVar(&r.burst, "burst", client.DefaultBurst,
+		"Maximum burst of requests sent while migrating objects")
 
 	r.Command = cmd
 	return r
@@ -242,7 +268,16 @@ func (mr *MigrateRunner) migrateObjs(cmObjs []object.ObjMetadata, oldID string,
 		return nil
 	}
 	if mr.dryRun {
+		// The ResourceGroup inventory is not created during a dry run, so the
+		// owning inventory annotations are rewritten to a placeholder ID. The
+		// server-side dry run reports which objects would be updated.
+		results, err := mr.updateOwningInventoryAnnotation(cmObjs, oldID, dryRunInventoryID)
+		if err != nil {
+			fmt.Fprintln(mr.ioStreams.Out, "failed")
+			return err
+		}
 		fmt.Fprintln(mr.ioStreams.Out, "success")
+		printMigrationResults(mr.ioStreams.Out, results)
 		return nil
 	}
 	rgReader, err := mr.rgLoader.ManifestReader(reader, args)
@@ -263,10 +298,19 @@ func (mr *MigrateRunner) migrateObjs(cmObjs []object.ObjMetadata, oldID string,
 		return err
 	}
 	inv := live.WrapInventoryInfoObj(rgInv)
-	err = updateOwningInventoryAnnotation(mr.rgProvider.Factory(), cmObjs, oldID, inv.ID())
+	results, err := mr.updateOwningInventoryAnnotation(cmObjs, oldID, inv.ID())
 	if err != nil {
 		return err
 	}
+	if err = migrationError(results); err != nil {
+		fmt.Fprintln(mr.ioStreams.Out, "failed")
+		if mr.rollback {
+			if rbErr := mr.rollbackObjs(results, oldID, inv.ID()); rbErr != nil {
+				return fmt.Errorf("%s; rollback failed: %s", err, rbErr)
+			}
+		}
+		return err
+	}
 	_, err = rgInvClient.Merge(inv, cmObjs)
 	if err != nil {
 		fmt.Fprintln(mr.ioStreams.Out, "failed", err.Error())
@@ -336,35 +380,123 @@ func findResourceGroupInv(objs []*unstructured.Unstructured) (*unstructured.Unst
 	return nil, fmt.Errorf("resource group inventory object not found")
 }
 
-func updateOwningInventoryAnnotation(f cmdutil.Factory, objMetas []object.ObjMetadata, old, new string) error {
+// updateOwningInventoryAnnotation rewrites the owning inventory annotation
+// of the passed objects from the old to the new inventory ID, returning
+// the result for each object. The objects are listed and watched rather
+// than fetched one at a time, requests are throttled to the runner's
+// qps and burst, and sent as server-side dry-run requests if dryRun is set.
+func (mr *MigrateRunner) updateOwningInventoryAnnotation(objMetas []object.ObjMetadata,
+	old, new string) ([]client.MigrationResult, error) {
+	f := mr.rgProvider.Factory()
 	d, err := f.DynamicClient()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	mapper, err := f.ToRESTMapper()
 	if err != nil {
+		return nil, err
+	}
+	c := client.NewClient(d, mapper).
+		WithDryRun(mr.dryRun).
+		WithRateLimit(mr.qps, mr.burst).
+		WithWatch(true).
+		WithProgress(mr.printProgress)
+	return c.MigrateAnnotations(context.TODO(), old, new, objMetas), nil
+}
+
+// printProgress prints the number of objects migrated so far to stderr
+// every migrationProgressInterval objects, and once all are migrated.
+func (mr *MigrateRunner) printProgress(event client.MigrationEvent) {
+	if event.Completed%migrationProgressInterval == 0 || event.Completed == event.Total {
+		fmt.Fprintf(mr.ioStreams.ErrOut, "    %d/%d objects processed\n", event.Completed, event.Total)
+	}
+}
+
+// rollbackObjs restores the owning inventory of the objects updated
+// before the migration failed, so the ConfigMap inventory keeps owning
+// every object it owned, and the objects which had no owning inventory
+// are left without one. Objects which are not restored are still owned
+// by the ResourceGroup inventory, and are picked up by running migrate
+// again.
+func (mr *MigrateRunner) rollbackObjs(results []client.MigrationResult, oldID, newID string) error {
+	owned, unowned := updatedObjs(results)
+	fmt.Fprintf(mr.ioStreams.Out, "  rolling back %d migrated objects...", len(owned)+len(unowned))
+	err := mr.restoreOwningInventory(owned, newID, oldID)
+	if err == nil {
+		err = mr.restoreOwningInventory(unowned, newID, "")
+	}
+	if err != nil {
+		fmt.Fprintln(mr.ioStreams.Out, "failed")
 		return err
 	}
-	c := client.NewClient(d, mapper)
-	for _, meta := range objMetas {
-		obj, err := c.Get(context.TODO(), meta)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				continue
-			}
-			return err
-		}
-		changed, err := client.UpdateAnnotation(obj, old, new)
-		if err != nil {
-			return err
-		}
-		if !changed {
-			continue
+	fmt.Fprintln(mr.ioStreams.Out, "success")
+	return nil
+}
+
+// restoreOwningInventory rewrites the owning inventory of the passed
+// objects from the new ID back to id, or removes it if id is empty.
+func (mr *MigrateRunner) restoreOwningInventory(objs []object.ObjMetadata, newID, id string) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	results, err := mr.updateOwningInventoryAnnotation(objs, newID, id)
+	if err != nil {
+		return err
+	}
+	return migrationError(results)
+}
+
+// updatedObjs returns the objects whose owning inventory was updated,
+// split into the objects which were owned by the old inventory and the
+// objects which had no owning inventory.
+func updatedObjs(results []client.MigrationResult) ([]object.ObjMetadata, []object.ObjMetadata) {
+	var owned, unowned []object.ObjMetadata
+	for _, result := range results {
+		switch {
+		case result.Status != client.MigrationUpdated:
+		case result.Unowned:
+			unowned = append(unowned, result.Object)
+		default:
+			owned = append(owned, result.Object)
 		}
-		err = c.Update(context.TODO(), meta, obj, &metav1.UpdateOptions{})
-		if err != nil {
-			return err
+	}
+	return owned, unowned
+}
+
+// migrationError returns the error of the first object which failed
+// to migrate, or nil if all objects were migrated.
+func migrationError(results []client.MigrationResult) error {
+	for _, result := range results {
+		if result.Status == client.MigrationFailed {
+			return fmt.Errorf("failed to migrate %s/%s: %v",
+				result.Object.Namespace, result.Object.Name, result.Error)
 		}
 	}
 	return nil
 }
+
+// printMigrationResults prints a table of the objects whose owning
+// inventory annotation would be rewritten by the migration.
+func printMigrationResults(w io.Writer, results []client.MigrationResult) {
+	table := tablewriter.NewWriter(w)
+	table.SetRowLine(false)
+	table.SetBorder(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator(" ")
+	table.SetCenterSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Namespace", "Name", "Kind", "Result"})
+	for _, result := range results {
+		status := string(result.Status)
+		if result.Error != nil {
+			status = fmt.Sprintf("%s: %v", status, result.Error)
+		}
+		table.Append([]string{
+			result.Object.Namespace,
+			result.Object.Name,
+			result.Object.GroupKind.Kind,
+			status,
+		})
+	}
+	table.Render()
+}
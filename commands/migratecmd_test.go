@@ -4,11 +4,14 @@
 package commands
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/GoogleContainerTools/kpt/pkg/client"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"github.com/GoogleContainerTools/kpt/pkg/live"
 	"github.com/stretchr/testify/assert"
@@ -341,3 +344,55 @@ func TestKptMigrate_migrateObjs(t *testing.T) {
 		})
 	}
 }
+
+func TestKptMigrate_printMigrationResults(t *testing.T) {
+	results := []client.MigrationResult{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: client.MigrationUpdated},
+		{Object: object.UnstructuredToObjMeta(pod2), Status: client.MigrationNotFound},
+	}
+	var out bytes.Buffer
+	printMigrationResults(&out, results)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", out.String())
+	}
+	assert.Contains(t, lines[1], "pod-1")
+	assert.Contains(t, lines[1], string(client.MigrationUpdated))
+	assert.Contains(t, lines[2], "pod-2")
+	assert.Contains(t, lines[2], string(client.MigrationNotFound))
+}
+
+func TestKptMigrate_migrationError(t *testing.T) {
+	results := []client.MigrationResult{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: client.MigrationUpdated},
+	}
+	assert.NoError(t, migrationError(results))
+	results = append(results, client.MigrationResult{
+		Object: object.UnstructuredToObjMeta(pod2),
+		Status: client.MigrationFailed,
+		Error:  fmt.Errorf("forbidden"),
+	})
+	err := migrationError(results)
+	if err == nil {
+		t.Fatalf("expected error but received none")
+	}
+	assert.Contains(t, err.Error(), "pod-2")
+}
+
+func TestKptMigrate_updatedObjs(t *testing.T) {
+	results := []client.MigrationResult{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: client.MigrationUpdated},
+		{Object: object.UnstructuredToObjMeta(pod2), Status: client.MigrationFailed},
+	}
+	owned, unowned := updatedObjs(results)
+	assert.Equal(t, []object.ObjMetadata{object.UnstructuredToObjMeta(pod1)}, owned)
+	assert.Empty(t, unowned)
+	owned, unowned = updatedObjs(results[1:])
+	assert.Empty(t, owned)
+	assert.Empty(t, unowned)
+
+	results[0].Unowned = true
+	owned, unowned = updatedObjs(results)
+	assert.Empty(t, owned)
+	assert.Equal(t, []object.ObjMetadata{object.UnstructuredToObjMeta(pod1)}, unowned)
+}
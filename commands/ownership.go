@@ -0,0 +1,80 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// resolveOwnership applies the ownership policy to the objects of the
+// package in the passed directory which are owned by another inventory
+// in the target cluster. Skipped objects are excluded from the apply by
+// the loader of the target. Nothing is checked if no policy is set, or
+// the objects are read from stdin or a plan. Dry runs only validate the
+// adoptions.
+func (w *ApplyRunnerWrapper) resolveOwnership(cmd *cobra.Command, t applyTarget, args []string) error {
+	if w.ownershipPolicy == "" {
+		return nil
+	}
+	policy, err := live.ParseOwnershipPolicy(w.ownershipPolicy)
+	if err != nil {
+		return err
+	}
+	if t.loader == nil || len(args) == 0 {
+		return nil
+	}
+	t.loader.Exclude = nil
+	objs, err := readPackageObjs(t.loader, args)
+	if err != nil {
+		return err
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(t.factory, isDryRun(cmd))
+	if err != nil {
+		return err
+	}
+	d, err := t.factory.DynamicClient()
+	if err != nil {
+		return err
+	}
+	results, err := live.ResolveOwnership(context.Background(), c, inv, objs, policy,
+		live.NewInventoryExistsFunc(d))
+	for _, r := range results {
+		name := fmt.Sprintf("%s/%s/%s", r.Object.GroupKind.Kind, r.Object.Namespace, r.Object.Name)
+		switch {
+		case r.Status == live.Adopted:
+			fmt.Fprintf(t.out, "%s adopted from inventory %q\n", name, r.Owner)
+		case policy == live.OwnershipSkip:
+			fmt.Fprintf(t.out, "%s skipped: owned by inventory %q\n", name, r.Owner)
+			if t.loader.Exclude == nil {
+				t.loader.Exclude = map[object.ObjMetadata]bool{}
+			}
+			t.loader.Exclude[r.Object] = true
+		}
+	}
+	if _, ok := err.(live.AdoptError); ok {
+		return fmt.Errorf("%s; pass --ownership-policy=skip to leave them untouched, "+
+			"or --ownership-policy=force-adopt to take them over", err)
+	}
+	return err
+}
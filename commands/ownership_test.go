@@ -0,0 +1,89 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestResolveOwnership(t *testing.T) {
+	testCases := map[string]struct {
+		policy        string
+		dryRun        string
+		expectedOwner string
+	}{
+		"Nothing is checked without a policy": {
+			dryRun:        "false",
+			expectedOwner: "other-inv",
+		},
+		"Objects owned by other inventories are adopted": {
+			policy:        "force-adopt",
+			dryRun:        "false",
+			expectedOwner: testInventoryLabel,
+		},
+		"Objects are not updated with --dry-run": {
+			policy:        "force-adopt",
+			dryRun:        "true",
+			expectedOwner: "other-inv",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			dir := podPackage(t)
+			tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+			defer tf.Cleanup()
+
+			owned := pod1.DeepCopy()
+			owned.SetAnnotations(map[string]string{client.OwningInventoryAnnotation: "other-inv"})
+			c := client.NewFakeClient(owned)
+			defer func(f func(cmdutil.Factory, bool) (client.Client, error)) { newClient = f }(newClient)
+			newClient = func(_ cmdutil.Factory, dryRun bool) (client.Client, error) {
+				if tc.policy == "" {
+					t.Errorf("unexpected client without an ownership policy")
+				}
+				return c.WithDryRun(dryRun), nil
+			}
+
+			var dryRun string
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVar(&dryRun, "dry-run", "", "")
+			assert.NoError(t, cmd.Flags().Set("dry-run", tc.dryRun))
+			var out bytes.Buffer
+			w := &ApplyRunnerWrapper{ownershipPolicy: tc.policy}
+			target := applyTarget{
+				factory: tf,
+				loader:  live.NewDualDelegatingManifestReader(tf),
+				out:     &out,
+			}
+
+			assert.NoError(t, w.resolveOwnership(cmd, target, []string{dir}))
+			obj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(pod1))
+			assert.NoError(t, err)
+			owner, _ := client.OwningInventory(obj, client.DefaultOwningInventoryKeys)
+			assert.Equal(t, tc.expectedOwner, owner)
+		})
+	}
+}
@@ -19,9 +19,20 @@ import (
 	"github.com/GoogleContainerTools/kpt/internal/cmddiff"
 	"github.com/GoogleContainerTools/kpt/internal/cmdfix"
 	"github.com/GoogleContainerTools/kpt/internal/cmdget"
+	"github.com/GoogleContainerTools/kpt/internal/cmdgraph"
 	"github.com/GoogleContainerTools/kpt/internal/cmdinit"
+	"github.com/GoogleContainerTools/kpt/internal/cmdinputs"
+	"github.com/GoogleContainerTools/kpt/internal/cmdlint"
+	"github.com/GoogleContainerTools/kpt/internal/cmdlock"
+	"github.com/GoogleContainerTools/kpt/internal/cmdpush"
+	"github.com/GoogleContainerTools/kpt/internal/cmdresolve"
+	"github.com/GoogleContainerTools/kpt/internal/cmdsbom"
+	"github.com/GoogleContainerTools/kpt/internal/cmdsign"
+	"github.com/GoogleContainerTools/kpt/internal/cmdstatus"
 	"github.com/GoogleContainerTools/kpt/internal/cmdsync"
+	"github.com/GoogleContainerTools/kpt/internal/cmdtree"
 	"github.com/GoogleContainerTools/kpt/internal/cmdupdate"
+	"github.com/GoogleContainerTools/kpt/internal/cmdvariant"
 	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
 	"github.com/spf13/cobra"
 )
@@ -47,6 +58,9 @@ func GetPkgCommand(name string) *cobra.Command {
 	pkg.AddCommand(
 		cmddesc.NewCommand(name), cmdget.NewCommand(name), cmdinit.NewCommand(name),
 		cmdfix.NewCommand(name), cmdsync.NewCommand(name), cmdupdate.NewCommand(name), cmddiff.NewCommand(name),
+		cmdpush.NewCommand(name), cmdlock.NewCommand(name), cmdresolve.NewCommand(name), cmdvariant.NewCommand(name),
+		cmdgraph.NewCommand(name), cmdsign.NewCommand(name), cmdsbom.NewCommand(name), cmdtree.NewCommand(name),
+		cmdstatus.NewCommand(name), cmdinputs.NewCommand(name), cmdlint.NewCommand(name),
 	)
 	return pkg
 }
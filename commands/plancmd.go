@@ -0,0 +1,175 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// PlanRunner encapsulates fields for the kpt live plan command.
+type PlanRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider provider.Provider
+	loader   manifestreader.ManifestLoader
+	output   string
+}
+
+// GetPlanRunner returns a pointer to an initial PlanRunner structure.
+func GetPlanRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *PlanRunner {
+	r := &PlanRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "plan [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Compute the changes apply would make to the cluster"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().StringVarP(&r.output, "output", "o", "",
+		"Write the plan to this file, so it can be applied with kpt live apply --plan")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdPlan returns the cobra command for the plan command.
+func NewCmdPlan(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetPlanRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE computes the plan for the package in the passed directory (or
+// stdin), prints it, and writes it to the output file if set.
+func (r *PlanRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; plan requires one directory argument (or stdin)")
+	}
+	inv, objs, clusterObjs, err := readPackageState(cmd, r.provider, r.loader, args)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), true)
+	if err != nil {
+		return err
+	}
+	plan, err := live.ComputePlan(context.Background(), c, inv, objs, clusterObjs)
+	if err != nil {
+		return err
+	}
+	printPlan(r.ioStreams.Out, plan)
+	if r.output == "" {
+		return nil
+	}
+	out, err := os.Create(r.output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := live.WritePlan(out, plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.ioStreams.Out, "plan written to %s\n", r.output)
+	return nil
+}
+
+// readPackageState reads the package in the passed directory (or stdin),
+// and returns its inventory, its objects, and the objects currently
+// stored in the inventory by the InventoryClient of --inventory-client.
+func readPackageState(cmd *cobra.Command, provider provider.Provider, loader manifestreader.ManifestLoader,
+	args []string) (inventory.InventoryInfo, []*unstructured.Unstructured, []object.ObjMetadata, error) {
+	reader, err := loader.ManifestReader(cmd.InOrStdin(), args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	inv, objs, err := loader.InventoryInfo(objs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	invClient, err := newInventoryClient(cmd, provider)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	clusterObjs, err := invClient.Load(context.Background(), inv)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return inv, objs, clusterObjs, nil
+}
+
+// newClient returns a client for the cluster of the factory, which
+// only validates writes if dryRun is true. It is a variable so tests
+// can replace the cluster with a fake client.
+var newClient = func(f cmdutil.Factory, dryRun bool) (client.Client, error) {
+	d, err := f.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClient(d, mapper).WithDryRun(dryRun), nil
+}
+
+// printPlan prints the action of every object in the plan as a table,
+// followed by a summary of the actions.
+func printPlan(w io.Writer, plan *live.Plan) {
+	table := tablewriter.NewWriter(w)
+	table.SetRowLine(false)
+	table.SetBorder(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator(" ")
+	table.SetCenterSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Action", "Namespace", "Name", "Kind"})
+	counts := map[live.ActionType]int{}
+	for _, action := range plan.Actions {
+		counts[action.Type]++
+		table.Append([]string{
+			string(action.Type),
+			action.Object.Namespace,
+			action.Object.Name,
+			action.Object.GroupKind.Kind,
+		})
+	}
+	table.Render()
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to prune, %d unchanged, %d kept\n",
+		counts[live.ActionCreate], counts[live.ActionUpdate], counts[live.ActionPrune],
+		counts[live.ActionUnchanged], counts[live.ActionPruneSkip])
+}
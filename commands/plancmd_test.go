@@ -0,0 +1,45 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrintPlan(t *testing.T) {
+	plan := &live.Plan{
+		Actions: []live.Action{
+			{Type: live.ActionCreate, Object: object.UnstructuredToObjMeta(pod1)},
+			{Type: live.ActionPrune, Object: object.UnstructuredToObjMeta(pod2)},
+		},
+	}
+	var out bytes.Buffer
+	printPlan(&out, plan)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, 2 rows and summary, got %q", out.String())
+	}
+	assert.Contains(t, lines[1], "Create")
+	assert.Contains(t, lines[1], "pod-1")
+	assert.Contains(t, lines[2], "Prune")
+	assert.Contains(t, lines[2], "pod-2")
+	assert.Equal(t, "Plan: 1 to create, 0 to update, 1 to prune, 0 unchanged, 0 kept", lines[3])
+}
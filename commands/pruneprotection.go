@@ -0,0 +1,128 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// confirmPrune prints a preview of the resources pruned by the apply of
+// the package in the passed directory to the target, by kind. Pruning
+// resources of protected kinds requires --force, or a confirmation if
+// interactive is true and stdin is a terminal, except for dry runs.
+// Nothing is checked if pruning is disabled, or the objects are read
+// from stdin or a plan.
+func (w *ApplyRunnerWrapper) confirmPrune(cmd *cobra.Command, t applyTarget, args []string,
+	interactive bool) error {
+	if t.loader == nil || len(args) == 0 || w.planFile != "" || flagValue(cmd, "no-prune") == "true" {
+		return nil
+	}
+	objs, err := readPackageObjs(t.loader, args)
+	if err != nil {
+		return err
+	}
+	inv, objs, err := t.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	invClient, err := t.invClient()
+	if err != nil {
+		return err
+	}
+	stored, err := invClient.Load(ctx, inv)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(t.factory, false)
+	if err != nil {
+		return err
+	}
+	pruned, err := live.PrunePreview(ctx, c, inv, stored, objs)
+	if err != nil || len(pruned) == 0 {
+		return err
+	}
+	allow, err := live.ReadPruneAllowlist(args[0])
+	if err != nil {
+		return err
+	}
+	protection := live.NewPruneProtection(allow)
+	printPrunePreview(t.out, pruned, protection)
+	protected := protection.Protected(pruned)
+	if len(protected) == 0 || w.force || isDryRun(cmd) {
+		return nil
+	}
+	if interactive && isTerminal(cmd.InOrStdin()) {
+		fmt.Fprintf(t.out, "prune %d resources of protected kinds? [y/N]: ", len(protected))
+		answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing to prune %d resources of protected kinds; use --force to prune them",
+		len(protected))
+}
+
+// printPrunePreview prints the resources to prune grouped by kind, in
+// the order in which the kinds first appear. Protected kinds are marked.
+func printPrunePreview(w io.Writer, pruned []object.ObjMetadata, protection *live.PruneProtection) {
+	var kinds []schema.GroupKind
+	byKind := map[schema.GroupKind][]object.ObjMetadata{}
+	for _, obj := range pruned {
+		if _, found := byKind[obj.GroupKind]; !found {
+			kinds = append(kinds, obj.GroupKind)
+		}
+		byKind[obj.GroupKind] = append(byKind[obj.GroupKind], obj)
+	}
+	fmt.Fprintf(w, "%d resources to prune:\n", len(pruned))
+	for _, gk := range kinds {
+		kind := gk.Kind
+		if gk.Group != "" {
+			kind = kind + "." + gk.Group
+		}
+		if protection.Kinds[gk] {
+			kind += " (protected)"
+		}
+		fmt.Fprintf(w, "  %s:\n", kind)
+		for _, obj := range byKind[gk] {
+			name := obj.Name
+			if obj.Namespace != "" {
+				name = obj.Namespace + "/" + name
+			}
+			fmt.Fprintf(w, "    %s\n", name)
+		}
+	}
+}
+
+// isTerminal returns true if the passed reader or writer is a terminal.
+func isTerminal(stream interface{}) bool {
+	f, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPrintPrunePreview(t *testing.T) {
+	pruned := []object.ObjMetadata{
+		{GroupKind: schema.GroupKind{Kind: "ConfigMap"}, Namespace: "test", Name: "a"},
+		{GroupKind: schema.GroupKind{Kind: "Namespace"}, Name: "test"},
+		{GroupKind: schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, Namespace: "test", Name: "db"},
+		{GroupKind: schema.GroupKind{Kind: "ConfigMap"}, Namespace: "test", Name: "b"},
+	}
+	var out bytes.Buffer
+	printPrunePreview(&out, pruned, live.NewPruneProtection(nil))
+	expected := `4 resources to prune:
+  ConfigMap:
+    test/a
+    test/b
+  Namespace (protected):
+    test
+  StatefulSet.apps (protected):
+    test/db
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/report"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// writeApplyReport writes the outcome of the apply of every object, and
+// the status of the applied objects, in the report format of the
+// command. Without a reconcile timeout, only Failed statuses are
+// failures, since the apply did not wait for the objects to be Current.
+func (w *ApplyRunnerWrapper) writeApplyReport(cmd *cobra.Command, results []live.ActuationResult) error {
+	reportResults := actuationReportResults(results)
+	if !isDryRun(cmd) {
+		var applied []object.ObjMetadata
+		for _, r := range results {
+			if r.Err == nil && r.Operation == live.OperationApplied {
+				applied = append(applied, r.Object)
+			}
+		}
+		c, err := newClient(w.factory, false)
+		if err != nil {
+			return err
+		}
+		statuses, err := live.ComputeStatuses(context.Background(), c, w.statusRules, applied)
+		if err != nil {
+			return err
+		}
+		until := pollUntilForever
+		if timeout, err := time.ParseDuration(flagValue(cmd, "reconcile-timeout")); err == nil && timeout > 0 {
+			until = pollUntilCurrent
+		}
+		reportResults = append(reportResults, statusReportResults("reconcile", statuses, until)...)
+	}
+	return report.Write(w.reportOut, w.reportFormat, "kpt live apply", reportResults)
+}
+
+// actuationReportResults returns the report result of the actuation of
+// every object.
+func actuationReportResults(results []live.ActuationResult) []report.Result {
+	var reportResults []report.Result
+	for _, r := range results {
+		result := report.Result{
+			Category: "apply",
+			Resource: statusResourceID(r.Object),
+			Message:  string(r.Operation),
+		}
+		switch {
+		case r.Err != nil:
+			result.Failed = true
+			result.Message = fmt.Sprintf("%s failed: %s", r.Operation, r.Err)
+		case r.Message != "":
+			result.Message = fmt.Sprintf("%s: %s", r.Operation, r.Message)
+		}
+		reportResults = append(reportResults, result)
+	}
+	return reportResults
+}
+
+// statusReportResults returns the report results of the statuses in the
+// passed category. Failed statuses, and the statuses which do not meet
+// the until condition, are failures.
+func statusReportResults(category string, statuses []live.ObjectStatus, until string) []report.Result {
+	var reportResults []report.Result
+	for _, s := range statuses {
+		message := string(s.Status)
+		if s.Message != "" {
+			message = fmt.Sprintf("%s: %s", s.Status, s.Message)
+		}
+		reportResults = append(reportResults, report.Result{
+			Category: category,
+			Resource: statusResourceID(s.Object),
+			Failed: s.Status == status.FailedStatus ||
+				(until != pollUntilForever && !statusesDone([]live.ObjectStatus{s}, until)),
+			Message: message,
+		})
+	}
+	return reportResults
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/report"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestActuationReportResults(t *testing.T) {
+	pod1Meta := object.UnstructuredToObjMeta(pod1)
+	pod2Meta := object.UnstructuredToObjMeta(pod2)
+	results := actuationReportResults([]live.ActuationResult{
+		{Object: pod1Meta, Operation: live.OperationApplied},
+		{Object: pod2Meta, Operation: live.OperationPruned, Err: fmt.Errorf("forbidden")},
+		{Object: pod2Meta, Operation: live.OperationPruneSkipped, Message: "kept on remove"},
+	})
+	assert.Equal(t, []report.Result{
+		{Category: "apply", Resource: "Pod/" + testNamespace + "/pod-1", Message: "Applied"},
+		{Category: "apply", Resource: "Pod/" + testNamespace + "/pod-2", Failed: true,
+			Message: "Pruned failed: forbidden"},
+		{Category: "apply", Resource: "Pod/" + testNamespace + "/pod-2",
+			Message: "PruneSkipped: kept on remove"},
+	}, results)
+}
+
+func TestStatusReportResults(t *testing.T) {
+	statuses := []live.ObjectStatus{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: status.InProgressStatus},
+		{Object: object.UnstructuredToObjMeta(pod2), Status: status.FailedStatus, Message: "CrashLoopBackOff"},
+	}
+	testCases := map[string]struct {
+		until    string
+		expected []bool
+	}{
+		"current": {pollUntilCurrent, []bool{true, true}},
+		"known":   {pollUntilKnown, []bool{false, true}},
+		"forever": {pollUntilForever, []bool{false, true}},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			results := statusReportResults("status", statuses, tc.until)
+			var failed []bool
+			for _, r := range results {
+				failed = append(failed, r.Failed)
+			}
+			assert.Equal(t, tc.expected, failed)
+			assert.Equal(t, "InProgress", results[0].Message)
+			assert.Equal(t, "Failed: CrashLoopBackOff", results[1].Message)
+		})
+	}
+}
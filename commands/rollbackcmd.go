@@ -0,0 +1,139 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// RollbackRunner encapsulates fields for the kpt live rollback command.
+type RollbackRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider    provider.Provider
+	loader      manifestreader.ManifestLoader
+	toRevision  int
+	concurrency int
+	dryRun      bool
+}
+
+// GetRollbackRunner returns a pointer to an initial RollbackRunner structure.
+func GetRollbackRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *RollbackRunner {
+	r := &RollbackRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "rollback [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Re-apply a previous revision of a package"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().IntVar(&r.toRevision, "to-revision", 0,
+		"The revision to roll back to. The default is the revision before the latest one.")
+	cmd.Flags().IntVar(&r.concurrency, "concurrency", live.DefaultConcurrency,
+		"Number of resources applied or pruned at the same time, respecting depends-on annotations")
+	cmd.Flags().BoolVar(&r.dryRun, "dry-run", false,
+		"Only validate the changes with the server, without changing any resource.")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdRollback returns the cobra command for the rollback command.
+func NewCmdRollback(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetRollbackRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE applies the manifests of a previous revision of the inventory of
+// the package in the passed directory (or stdin), and prunes the objects
+// which are not in that revision. The rollback is recorded as a new
+// revision.
+func (r *RollbackRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; rollback requires one directory argument (or stdin)")
+	}
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), args)
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	inv, _, err := r.loader.InventoryInfo(objs)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	invClient := live.NewClusterInventoryClient(r.provider)
+	revisions, err := invClient.ListRevisions(ctx, inv)
+	if err != nil {
+		return err
+	}
+	rev, err := live.FindRevision(revisions, r.toRevision)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), r.dryRun)
+	if err != nil {
+		return err
+	}
+	out := r.ioStreams.Out
+	fmt.Fprintf(out, "rolling back to revision %d...\n", rev.Number)
+	a := &live.ParallelApplier{
+		Client:          c,
+		InventoryClient: invClient,
+		Concurrency:     r.concurrency,
+		Progress:        actuationPrinter(out),
+		Resumed:         func(rec live.ActuationRecord) { printResumed(out, rec) },
+	}
+	if r.dryRun {
+		if a.InventoryClient, err = memoryInventoryCopy(ctx, invClient, inv); err != nil {
+			return err
+		}
+	}
+	sub, err := applyTimeSubstitution(r.provider.Factory(), flagValue(cmd, "context"))
+	if err != nil {
+		return err
+	}
+	objs, err = sub.Substitute(ctx, rev.Objects)
+	if err != nil {
+		return err
+	}
+	results, err := a.Run(ctx, inv, objs)
+	printActuationSummary(out, results)
+	if err != nil || r.dryRun {
+		return err
+	}
+	recorded, err := live.RecordRevision(ctx, invClient, inv, rev.Objects, live.DefaultRevisionHistoryLimit)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "rolled back to revision %d as revision %d\n", rev.Number, recorded.Number)
+	return nil
+}
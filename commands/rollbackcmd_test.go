@@ -0,0 +1,50 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestRollbackRunner_tooManyArgs(t *testing.T) {
+	r := GetRollbackRunner(nil, nil, genericclioptions.NewTestIOStreamsDiscard())
+	err := r.RunE(r.Command, []string{"dir1", "dir2"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "too many arguments")
+	}
+}
+
+func TestMemoryInventoryCopy(t *testing.T) {
+	ctx := context.Background()
+	inv := live.WrapInventoryInfoObj(rgInvObj)
+	objs := []object.ObjMetadata{object.UnstructuredToObjMeta(pod1)}
+	cluster := live.NewMemoryInventoryClient()
+	assert.NoError(t, cluster.Store(ctx, inv, objs))
+
+	memory, err := memoryInventoryCopy(ctx, cluster, inv)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, memory.Store(ctx, inv, nil))
+	stored, err := cluster.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Equal(t, objs, stored)
+}
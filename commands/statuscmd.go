@@ -0,0 +1,349 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/report"
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// The conditions for the status command to stop polling.
+const (
+	pollUntilKnown   = "known"
+	pollUntilCurrent = "current"
+	pollUntilDeleted = "deleted"
+	pollUntilForever = "forever"
+)
+
+// The output formats of the status command.
+const (
+	statusOutputEvents = "events"
+	statusOutputTable  = "table"
+	statusOutputJSON   = "json"
+	statusOutputJUnit  = report.FormatJUnit
+	statusOutputSARIF  = report.FormatSARIF
+)
+
+// StatusRunner encapsulates fields for the kpt live status command.
+type StatusRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+
+	provider     provider.Provider
+	loader       manifestreader.ManifestLoader
+	pollPeriod   time.Duration
+	pollUntil    string
+	output       string
+	timeout      time.Duration
+	watch        bool
+	statusConfig string
+}
+
+// GetStatusRunner returns a pointer to an initial StatusRunner structure.
+func GetStatusRunner(provider provider.Provider, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *StatusRunner {
+	r := &StatusRunner{
+		ioStreams: ioStreams,
+		provider:  provider,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "status [DIRECTORY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the status of the resources in the inventory"),
+		RunE:                  r.RunE,
+	}
+	cmd.Flags().DurationVar(&r.pollPeriod, "poll-period", 2*time.Second,
+		"Polling period for resource statuses.")
+	cmd.Flags().StringVar(&r.pollUntil, "poll-until", pollUntilKnown,
+		"When to stop polling. Must be one of 'known', 'current', 'deleted', or 'forever'.")
+	cmd.Flags().StringVarP(&r.output, "output", "o", statusOutputEvents,
+		"Output format. Must be one of 'events', 'table' or 'json'.")
+	cmd.Flags().DurationVar(&r.timeout, "timeout", 0,
+		"How long to wait before exiting. The default is to wait forever.")
+	cmd.Flags().BoolVarP(&r.watch, "watch", "w", false,
+		"Keep reporting status changes until interrupted. Same as --poll-until=forever.")
+	cmd.Flags().StringVar(&r.statusConfig, "status-config", "",
+		"File declaring status readers, in addition to the ones in the Kptfile")
+	r.Command = cmd
+	return r
+}
+
+// NewCmdStatus returns the cobra command for the status command.
+func NewCmdStatus(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetStatusRunner(live.NewDualDelegatingProvider(f),
+		live.NewDualDelegatingManifestReader(f), ioStreams).Command
+}
+
+// RunE polls the status of the objects in the inventory of the package
+// in the passed directory (or stdin), and prints the status changes
+// until the poll-until condition is met or the timeout is reached.
+func (r *StatusRunner) RunE(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments; status requires one directory argument (or stdin)")
+	}
+	if r.watch {
+		r.pollUntil = pollUntilForever
+	}
+	switch r.pollUntil {
+	case pollUntilKnown, pollUntilCurrent, pollUntilDeleted, pollUntilForever:
+	default:
+		return fmt.Errorf("unknown value for --poll-until: %q", r.pollUntil)
+	}
+	printer, err := newStatusPrinter(r.output, r.ioStreams.Out)
+	if err != nil {
+		return err
+	}
+	_, _, clusterObjs, err := readPackageState(cmd, r.provider, r.loader, args)
+	if err != nil {
+		return err
+	}
+	var dir string
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	readers, err := live.ReadStatusReaders(dir, r.statusConfig)
+	if err != nil {
+		return err
+	}
+	rules, err := live.NewStatusRules(readers)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(r.provider.Factory(), false)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	poll := func(ctx context.Context) ([]live.ObjectStatus, error) {
+		return live.ComputeStatuses(ctx, c, rules, clusterObjs)
+	}
+	if err := pollStatus(ctx, poll, r.pollPeriod, r.pollUntil, printer); err != nil {
+		return err
+	}
+	if p, ok := printer.(*reportStatusPrinter); ok {
+		return p.write(r.pollUntil)
+	}
+	return nil
+}
+
+// pollStatus calls poll at every period, and passes the statuses and
+// the ones which changed since the previous poll to the printer. Returns
+// once the statuses meet the until condition, or the context is done.
+func pollStatus(ctx context.Context, poll func(context.Context) ([]live.ObjectStatus, error),
+	period time.Duration, until string, printer statusPrinter) error {
+	previous := map[object.ObjMetadata]live.ObjectStatus{}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		statuses, err := poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		var changed []live.ObjectStatus
+		for _, s := range statuses {
+			if prev, found := previous[s.Object]; !found || prev != s {
+				changed = append(changed, s)
+			}
+			previous[s.Object] = s
+		}
+		if len(changed) > 0 {
+			if err := printer.print(statuses, changed); err != nil {
+				return err
+			}
+		}
+		if statusesDone(statuses, until) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusesDone returns true if the statuses meet the until condition.
+func statusesDone(statuses []live.ObjectStatus, until string) bool {
+	if until == pollUntilForever {
+		return false
+	}
+	for _, s := range statuses {
+		switch {
+		case until == pollUntilKnown && s.Status == status.UnknownStatus,
+			until == pollUntilCurrent && s.Status != status.CurrentStatus,
+			until == pollUntilDeleted && s.Status != status.NotFoundStatus:
+			return false
+		}
+	}
+	return true
+}
+
+// statusPrinter prints the statuses of the polled objects. It is passed
+// all statuses, and the ones which changed since the previous poll.
+type statusPrinter interface {
+	print(statuses, changed []live.ObjectStatus) error
+}
+
+// newStatusPrinter returns the printer for the output format.
+func newStatusPrinter(output string, w io.Writer) (statusPrinter, error) {
+	switch output {
+	case statusOutputEvents:
+		return eventsStatusPrinter{w: w}, nil
+	case statusOutputTable:
+		return tableStatusPrinter{w: w}, nil
+	case statusOutputJSON:
+		return jsonStatusPrinter{w: w, now: time.Now}, nil
+	case statusOutputJUnit, statusOutputSARIF:
+		return &reportStatusPrinter{w: w, format: output}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q; must be events, table, json, junit or sarif", output)
+	}
+}
+
+// eventsStatusPrinter prints a line for every status change.
+type eventsStatusPrinter struct {
+	w io.Writer
+}
+
+func (p eventsStatusPrinter) print(_, changed []live.ObjectStatus) error {
+	for _, s := range changed {
+		if _, err := fmt.Fprintf(p.w, "%s is %s: %s\n", statusResourceID(s.Object),
+			s.Status, s.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableStatusPrinter prints a table of all statuses every time a status
+// changes.
+type tableStatusPrinter struct {
+	w io.Writer
+}
+
+func (p tableStatusPrinter) print(statuses, _ []live.ObjectStatus) error {
+	table := tablewriter.NewWriter(p.w)
+	table.SetRowLine(false)
+	table.SetBorder(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator(" ")
+	table.SetCenterSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Namespace", "Resource", "Status", "Message"})
+	for _, s := range statuses {
+		table.Append([]string{
+			s.Object.Namespace,
+			fmt.Sprintf("%s/%s", s.Object.GroupKind.Kind, s.Object.Name),
+			string(s.Status),
+			s.Message,
+		})
+	}
+	table.Render()
+	_, err := fmt.Fprintln(p.w)
+	return err
+}
+
+// jsonStatusEvent is a status change printed by the json output.
+type jsonStatusEvent struct {
+	Timestamp string `json:"timestamp"`
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
+// jsonStatusPrinter prints every status change as a JSON object on its
+// own line.
+type jsonStatusPrinter struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+func (p jsonStatusPrinter) print(_, changed []live.ObjectStatus) error {
+	encoder := json.NewEncoder(p.w)
+	for _, s := range changed {
+		err := encoder.Encode(jsonStatusEvent{
+			Timestamp: p.now().UTC().Format(time.RFC3339),
+			Group:     s.Object.GroupKind.Group,
+			Kind:      s.Object.GroupKind.Kind,
+			Namespace: s.Object.Namespace,
+			Name:      s.Object.Name,
+			Status:    string(s.Status),
+			Message:   s.Message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportStatusPrinter keeps the latest statuses, and writes them as a
+// JUnit or SARIF report once polling stopped.
+type reportStatusPrinter struct {
+	w        io.Writer
+	format   string
+	statuses []live.ObjectStatus
+}
+
+func (p *reportStatusPrinter) print(statuses, _ []live.ObjectStatus) error {
+	p.statuses = statuses
+	return nil
+}
+
+// write writes the report of the latest statuses. The statuses which
+// do not meet the until condition are failures.
+func (p *reportStatusPrinter) write(until string) error {
+	return report.Write(p.w, p.format, "kpt live status", statusReportResults("status", p.statuses, until))
+}
+
+// statusResourceID returns the identifier of the object printed by the
+// events output.
+func statusResourceID(obj object.ObjMetadata) string {
+	kind := obj.GroupKind.Kind
+	if obj.GroupKind.Group != "" {
+		kind = kind + "." + obj.GroupKind.Group
+	}
+	if obj.Namespace == "" {
+		return fmt.Sprintf("%s/%s", kind, obj.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, obj.Namespace, obj.Name)
+}
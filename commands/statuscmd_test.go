@@ -0,0 +1,152 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/live"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// recordingPrinter records the changed statuses of every print.
+type recordingPrinter struct {
+	changed [][]live.ObjectStatus
+}
+
+func (p *recordingPrinter) print(_, changed []live.ObjectStatus) error {
+	p.changed = append(p.changed, changed)
+	return nil
+}
+
+func TestPollStatus(t *testing.T) {
+	pod1Meta := object.UnstructuredToObjMeta(pod1)
+	pod2Meta := object.UnstructuredToObjMeta(pod2)
+	polls := [][]live.ObjectStatus{
+		{
+			{Object: pod1Meta, Status: status.InProgressStatus},
+			{Object: pod2Meta, Status: status.CurrentStatus},
+		},
+		{
+			{Object: pod1Meta, Status: status.InProgressStatus},
+			{Object: pod2Meta, Status: status.CurrentStatus},
+		},
+		{
+			{Object: pod1Meta, Status: status.CurrentStatus},
+			{Object: pod2Meta, Status: status.CurrentStatus},
+		},
+	}
+	i := 0
+	poll := func(context.Context) ([]live.ObjectStatus, error) {
+		statuses := polls[i]
+		if i < len(polls)-1 {
+			i++
+		}
+		return statuses, nil
+	}
+
+	printer := &recordingPrinter{}
+	err := pollStatus(context.Background(), poll, time.Millisecond, pollUntilCurrent, printer)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]live.ObjectStatus{
+		polls[0],
+		{{Object: pod1Meta, Status: status.CurrentStatus}},
+	}, printer.changed)
+
+	// Polling forever stops when the context is done.
+	i = 0
+	printer = &recordingPrinter{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = pollStatus(ctx, poll, time.Millisecond, pollUntilForever, printer)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(printer.changed))
+}
+
+func TestStatusesDone(t *testing.T) {
+	statuses := func(ss ...status.Status) []live.ObjectStatus {
+		var result []live.ObjectStatus
+		for _, s := range ss {
+			result = append(result, live.ObjectStatus{Status: s})
+		}
+		return result
+	}
+	testCases := map[string]struct {
+		statuses []live.ObjectStatus
+		until    string
+		expected bool
+	}{
+		"known":                {statuses(status.InProgressStatus, status.NotFoundStatus), pollUntilKnown, true},
+		"unknown":              {statuses(status.UnknownStatus), pollUntilKnown, false},
+		"current":              {statuses(status.CurrentStatus), pollUntilCurrent, true},
+		"in progress":          {statuses(status.CurrentStatus, status.InProgressStatus), pollUntilCurrent, false},
+		"deleted":              {statuses(status.NotFoundStatus), pollUntilDeleted, true},
+		"not deleted":          {statuses(status.CurrentStatus), pollUntilDeleted, false},
+		"forever":              {statuses(status.CurrentStatus), pollUntilForever, false},
+		"no objects are known": {nil, pollUntilKnown, true},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, statusesDone(tc.statuses, tc.until))
+		})
+	}
+}
+
+func TestStatusPrinters(t *testing.T) {
+	statuses := []live.ObjectStatus{
+		{Object: object.UnstructuredToObjMeta(pod1), Status: status.CurrentStatus, Message: "Pod is Ready"},
+		{Object: object.UnstructuredToObjMeta(pod2), Status: status.InProgressStatus, Message: "Pod is not Ready"},
+	}
+
+	var out bytes.Buffer
+	printer, err := newStatusPrinter(statusOutputEvents, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, printer.print(statuses, statuses[1:]))
+	assert.Equal(t, "Pod/"+testNamespace+"/pod-2 is InProgress: Pod is not Ready\n", out.String())
+
+	out.Reset()
+	printer, err = newStatusPrinter(statusOutputTable, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, printer.print(statuses, statuses[1:]))
+	assert.Contains(t, out.String(), "Pod/pod-1")
+	assert.Contains(t, out.String(), "Pod/pod-2")
+
+	out.Reset()
+	now := time.Date(2020, 12, 1, 10, 0, 0, 0, time.UTC)
+	printer = jsonStatusPrinter{w: &out, now: func() time.Time { return now }}
+	assert.NoError(t, printer.print(statuses, statuses))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, `{"timestamp":"2020-12-01T10:00:00Z","group":"","kind":"Pod",`+
+		`"namespace":"`+testNamespace+`","name":"pod-1","status":"Current","message":"Pod is Ready"}`, lines[0])
+
+	out.Reset()
+	printer, err = newStatusPrinter(statusOutputJUnit, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, printer.print(statuses, statuses))
+	assert.Empty(t, out.String())
+	assert.NoError(t, printer.(*reportStatusPrinter).write(pollUntilCurrent))
+	assert.Contains(t, out.String(), `<testsuite name="status" tests="2" failures="1">`)
+	assert.Contains(t, out.String(), `<failure message="InProgress: Pod is not Ready">`)
+
+	_, err = newStatusPrinter("yaml", &out)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdcache contains the cache prune command
+package cmdcache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/cachedocs"
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/spf13/cobra"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "prune",
+		Args:    cobra.NoArgs,
+		Short:   cachedocs.PruneShort,
+		Long:    cachedocs.PruneShort + "\n" + cachedocs.PruneLong,
+		Example: cachedocs.PruneExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().DurationVar(&r.OlderThan, "older-than", 0,
+		"only remove the repos and function outputs which were not used for the duration")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command   *cobra.Command
+	OlderThan time.Duration
+}
+
+func (r *Runner) preRunE(_ *cobra.Command, _ []string) error {
+	if r.OlderThan < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+	return nil
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	since := time.Now().Add(-r.OlderThan)
+	pruned, err := gitutil.PruneCache(since)
+	var freed int64
+	for _, e := range pruned {
+		fmt.Fprintf(c.OutOrStdout(), "removed %s\n", e.URI)
+		freed += e.Size
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.OutOrStdout(), "removed %d cached repos, freed %s\n", len(pruned), formatSize(freed))
+
+	outputs, freed, err := functions.PruneCache(since)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.OutOrStdout(), "removed %d cached function outputs, freed %s\n", outputs, formatSize(freed))
+	return nil
+}
+
+// formatSize formats a number of bytes for humans.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdcache_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdcache"
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_prune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-cache-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv(gitutil.CacheHomeEnv, dir)
+	defer os.Unsetenv(gitutil.CacheHomeEnv)
+	fnCache := filepath.Join(dir, "fn-cache")
+	os.Setenv(functions.CacheHomeEnv, fnCache)
+	defer os.Unsetenv(functions.CacheHomeEnv)
+
+	_, err = gitutil.CachedRepo("https://github.com/org/repo")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, os.MkdirAll(fnCache, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(fnCache, "output.yaml"), []byte("kind: ConfigMap\n"), 0600))
+
+	// the repo was used more recently than the duration
+	r := cmdcache.NewRunner("kpt")
+	out := &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{"--older-than", "1h"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "removed 0 cached repos, freed 0B\n"+
+		"removed 0 cached function outputs, freed 0B\n", out.String())
+
+	r = cmdcache.NewRunner("kpt")
+	out = &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{})
+	assert.NoError(t, r.Command.Execute())
+	assert.Contains(t, out.String(), "removed https://github.com/org/repo\nremoved 1 cached repos, freed ")
+	assert.Contains(t, out.String(), "removed 1 cached function outputs, freed 16B\n")
+
+	entries, err := gitutil.ListCache()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	outputs, err := ioutil.ReadDir(fnCache)
+	assert.NoError(t, err)
+	assert.Empty(t, outputs)
+}
+
+func TestCmd_pruneNegative(t *testing.T) {
+	r := cmdcache.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{"--older-than", "-1h"})
+	assert.EqualError(t, r.Command.Execute(), "--older-than must not be negative")
+}
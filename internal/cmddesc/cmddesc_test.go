@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/GoogleContainerTools/kpt/internal/cmddesc"
@@ -81,3 +82,44 @@ func TestCmd_defaultPkg(t *testing.T) {
 `
 	assert.Equal(t, exp, b.String())
 }
+
+// TestDesc_metadata tests that the metadata of packages is printed after the
+// table of the packages.
+func TestDesc_metadata(t *testing.T) {
+	d, err := ioutil.TempDir("", "kptdesc")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+
+	err = ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: mysql
+packageMetadata:
+  shortDescription: a mysql database
+  license: Apache-2.0
+  maintainers:
+  - name: Jane Doe
+    email: jane@example.com
+  - name: Platform Team
+  keywords: [database, mysql]
+  documentation: [https://example.com/mysql]
+`), 0600)
+	testutil.AssertNoError(t, err)
+
+	b := &bytes.Buffer{}
+	cmd := cmddesc.NewRunner("kpt")
+	cmd.Description.PrintBasePath = true
+	cmd.Command.SetArgs([]string{d})
+	cmd.Command.SetOut(b)
+	testutil.AssertNoError(t, cmd.Command.Execute())
+
+	assert.Assert(t, strings.HasSuffix(b.String(), `
+mysql:
+  Description:   a mysql database
+  License:       Apache-2.0
+  Maintainers:   Jane Doe <jane@example.com>, Platform Team
+  Keywords:      database, mysql
+  Documentation: https://example.com/mysql
+`), b.String())
+}
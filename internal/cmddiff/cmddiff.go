@@ -48,6 +48,8 @@ func NewRunner(parent string) *Runner {
 		"diff tool to use to show the changes")
 	c.Flags().StringVar(&r.DiffToolOpts, "diff-tool-opts", diffToolOpts,
 		"diff tool commandline options to use to show the changes")
+	c.Flags().StringVar(&r.output, "output", string(diff.OutputTool),
+		"how to show the changes e.g. "+diff.SupportedOutputFormatsLabel())
 	c.Flags().BoolVar(&r.Debug, "debug", false,
 		"when true, prints additional debug information and do not delete staged pkg dirs")
 	r.C = c
@@ -66,6 +68,7 @@ type Runner struct {
 	diff.Command
 	C        *cobra.Command
 	diffType string
+	output   string
 }
 
 func (r *Runner) preRunE(c *cobra.Command, args []string) error {
@@ -92,6 +95,7 @@ func (r *Runner) preRunE(c *cobra.Command, args []string) error {
 		r.DiffType = diff.DiffType(r.diffType)
 	}
 
+	r.OutputFormat = diff.OutputFormat(r.output)
 	r.Path = dir
 	r.Ref = version
 
@@ -15,6 +15,7 @@
 package cmddiff_test
 
 import (
+	"bytes"
 	"path/filepath"
 	"testing"
 
@@ -44,6 +45,36 @@ func TestCmdInvalidDiffTool(t *testing.T) {
 		"diff-tool 'nodiff' not found in the PATH.")
 }
 
+func TestCmdInvalidOutput(t *testing.T) {
+	runner := cmddiff.NewRunner("")
+	runner.C.SetArgs([]string{"--output", "yaml"})
+	runner.C.SilenceErrors = true
+	err := runner.C.Execute()
+	assert.EqualError(t,
+		err,
+		"invalid output 'yaml'. Supported outputs are: tool, unified, json")
+}
+
+func TestCmdExecute_json(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	dest := filepath.Join(w.WorkspaceDirectory, g.RepoName)
+
+	getRunner := cmdget.NewRunner("")
+	getRunner.Command.SetArgs([]string{"file://" + g.RepoDirectory + ".git/", "./"})
+	err := getRunner.Command.Execute()
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	runner := cmddiff.NewRunner("")
+	runner.C.SetArgs([]string{dest, "--output", "json"})
+	runner.C.SilenceErrors = true
+	runner.Output = out
+	err = runner.C.Execute()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"diffType": "local", "files": []}`, out.String())
+}
+
 func TestCmdExecute(t *testing.T) {
 	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
 	defer clean()
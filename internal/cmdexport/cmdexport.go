@@ -37,11 +37,11 @@ func ExportCommand() *cobra.Command {
 func GetExportRunner() *ExportRunner {
 	r := &ExportRunner{PipelineConfig: &types.PipelineConfig{}}
 	c := &cobra.Command{
-		Use:     "export DIR/",
+		Use:     "export [ORCHESTRATOR] DIR/",
 		Short:   fndocs.ExportShort,
 		Long:    fndocs.ExportLong,
 		Example: fndocs.ExportExamples,
-		Args:    cobra.ExactArgs(1),
+		Args:    cobra.RangeArgs(1, 2),
 		PreRunE: r.preRunE,
 		RunE:    r.runE,
 	}
@@ -52,7 +52,6 @@ func GetExportRunner() *ExportRunner {
 			"specify the workflow orchestrator that the pipeline is generated for. Supported workflow orchestrators are %s.",
 			listSupportedOrchestrators()),
 	)
-	_ = c.MarkFlagRequired("workflow")
 	c.Flags().StringSliceVar(
 		&r.FnPaths, "fn-path", []string{},
 		"read functions from these directories instead of the configuration directory.",
@@ -77,15 +76,30 @@ type ExportRunner struct {
 }
 
 func (r *ExportRunner) preRunE(cmd *cobra.Command, args []string) (err error) {
-	r.Dir = args[0]
+	r.Dir = args[len(args)-1]
+
+	if len(args) == 2 {
+		if r.WorkflowOrchestrator != "" && r.WorkflowOrchestrator != args[0] {
+			return fmt.Errorf(
+				"orchestrator %s conflicts with --workflow %s",
+				args[0],
+				r.WorkflowOrchestrator,
+			)
+		}
+		r.WorkflowOrchestrator = args[0]
+	}
 
 	if len(r.WorkflowOrchestrator) == 0 {
 		return fmt.Errorf(
-			"--workflow flag is required. It must be one of %s",
+			"an orchestrator argument or --workflow flag is required. It must be one of %s",
 			listSupportedOrchestrators(),
 		)
 	}
 
+	if name, found := orchestratorAliases[r.WorkflowOrchestrator]; found {
+		r.WorkflowOrchestrator = name
+	}
+
 	r.Pipeline = supportedOrchestrators()[r.WorkflowOrchestrator]
 	if r.Pipeline == nil {
 		return fmt.Errorf(
@@ -105,7 +119,12 @@ func (r *ExportRunner) preRunE(cmd *cobra.Command, args []string) (err error) {
 		return
 	}
 
-	return r.PipelineConfig.UseRelativePaths()
+	err = r.PipelineConfig.UseRelativePaths()
+	if err != nil {
+		return
+	}
+
+	return r.PipelineConfig.UseKptfile()
 }
 
 // runE generates the pipeline and writes it into a file or stdout.
@@ -142,6 +161,11 @@ func supportedOrchestrators() map[string]orchestrators.Pipeline {
 	}
 }
 
+// orchestratorAliases maps other names of orchestrators to their names.
+var orchestratorAliases = map[string]string{
+	"cloudbuild": "cloud-build",
+}
+
 func listSupportedOrchestrators() string {
 	var names []string
 
@@ -39,13 +39,13 @@ type TestCase struct {
 var testCases = []TestCase{
 	{
 		description: "fails on providing too many args",
-		params:      []string{"dir", "extra"},
-		err:         "accepts 1 arg(s), received 2",
+		params:      []string{"github-actions", "dir", "extra"},
+		err:         "accepts between 1 and 2 arg(s), received 3",
 	},
 	{
 		description: "fails on not providing working orchestrator",
 		params:      []string{"dir"},
-		err:         "--workflow flag is required. It must be one of circleci, cloud-build, github-actions, gitlab-ci, jenkins, tekton",
+		err:         "an orchestrator argument or --workflow flag is required. It must be one of circleci, cloud-build, github-actions, gitlab-ci, jenkins, tekton",
 	},
 	{
 		description: "fails on an unsupported workflow orchestrator",
@@ -340,6 +340,54 @@ workflows:
     main:
         jobs:
           - kpt/run-functions
+`,
+	},
+	{
+		description: "exports a pipeline for the orchestrator argument",
+		params:      []string{"cloudbuild", "."},
+		expected: `
+steps:
+  - name: gcr.io/kpt-dev/kpt:latest
+    args:
+      - fn
+      - run
+      - .
+`,
+	},
+	{
+		description: "fails on an orchestrator argument conflicting with --workflow",
+		params:      []string{"tekton", ".", "-w", "cloud-build"},
+		err:         "orchestrator tekton conflicts with --workflow cloud-build",
+	},
+	{
+		description: "exports a pipeline syncing the dependencies of the Kptfile",
+		files: map[string]string{
+			"resources/Kptfile": `
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: resources
+dependencies:
+- name: nginx
+  git:
+    repo: https://github.com/GoogleContainerTools/kpt
+    directory: package-examples/nginx
+    ref: v0.1
+`,
+		},
+		params: []string{"cloud-build", "resources"},
+		expected: `
+steps:
+  - name: gcr.io/kpt-dev/kpt:latest
+    args:
+      - pkg
+      - sync
+      - resources
+  - name: gcr.io/kpt-dev/kpt:latest
+    args:
+      - fn
+      - run
+      - resources
 `,
 	},
 }
@@ -91,11 +91,15 @@ func (orb *CircleCIOrb) Init(config *CircleCIOrbConfig) *CircleCIOrb {
 		command = fmt.Sprintf("%s --fn-path %s", command, fnPath)
 	}
 
+	var steps []*CircleCICommandStep
+	if config.Sync {
+		steps = append(steps, &CircleCICommandStep{Run: fmt.Sprintf("kpt pkg sync %s", config.Dir)})
+	}
+	steps = append(steps, &CircleCICommandStep{Run: command})
+
 	orb.Commands = map[string]*CircleCICommand{
 		config.CommandName: {
-			Steps: []*CircleCICommandStep{
-				{Run: command},
-			},
+			Steps: steps,
 		},
 	}
 
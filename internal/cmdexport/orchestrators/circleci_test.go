@@ -169,6 +169,37 @@ workflows:
     main:
         jobs:
           - kpt/run-functions
+`,
+	},
+	{
+		description: "generate a CircleCI workflow syncing dependencies",
+		config: &types.PipelineConfig{
+			Dir:  "resources",
+			Sync: true,
+		},
+		expected: `
+version: "2.1"
+orbs:
+    kpt:
+        executors:
+            kpt-container:
+                docker:
+                  - image: gcr.io/kpt-dev/kpt:latest
+        commands:
+            kpt-fn-run:
+                steps:
+                  - run: kpt pkg sync resources
+                  - run: kpt fn run resources
+        jobs:
+            run-functions:
+                executor: kpt-container
+                steps:
+                  - setup_remote_docker
+                  - kpt-fn-run
+workflows:
+    main:
+        jobs:
+          - kpt/run-functions
 `,
 	},
 }
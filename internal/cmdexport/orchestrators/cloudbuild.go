@@ -49,7 +49,14 @@ func (p *CloudBuild) Init(config *types.PipelineConfig) Pipeline {
 		)
 	}
 
-	p.Steps = []CloudBuildStep{step}
+	p.Steps = nil
+	if config.Sync {
+		p.Steps = append(p.Steps, CloudBuildStep{
+			Name: KptImage,
+			Args: []string{"pkg", "sync", config.Dir},
+		})
+	}
+	p.Steps = append(p.Steps, step)
 
 	return p
 }
@@ -65,6 +65,26 @@ steps:
       - --fn-path
       - config/function1.yaml
       - config/function2.yaml
+`,
+	},
+	{
+		description: "generates a Cloud Build pipeline syncing dependencies",
+		config: &types.PipelineConfig{
+			Dir:  "resources",
+			Sync: true,
+		},
+		expected: `
+steps:
+  - name: gcr.io/kpt-dev/kpt:latest
+    args:
+      - pkg
+      - sync
+      - resources
+  - name: gcr.io/kpt-dev/kpt:latest
+    args:
+      - fn
+      - run
+      - resources
 `,
 	},
 }
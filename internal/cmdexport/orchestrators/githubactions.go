@@ -60,6 +60,24 @@ func (p *GitHubActions) Init(config *types.PipelineConfig) Pipeline {
 		)
 	}
 
+	var steps []GitHubActionsStep
+	if config.Sync {
+		steps = append(steps, GitHubActionsStep{
+			Name: "Sync kpt dependencies",
+			Uses: "docker://" + KptImage,
+			With: GitHubActionStepArgs{
+				Args: fmt.Sprintf("pkg sync %s", config.Dir),
+			},
+		})
+	}
+	steps = append(steps, GitHubActionsStep{
+		Name: "Run all kpt functions",
+		Uses: "docker://" + KptImage,
+		With: GitHubActionStepArgs{
+			Args: runFnCommand,
+		},
+	})
+
 	p.Name = "kpt"
 	p.On = map[string]GitHubActionsTrigger{
 		"push": {Branches: []string{"master"}},
@@ -67,15 +85,7 @@ func (p *GitHubActions) Init(config *types.PipelineConfig) Pipeline {
 	p.Jobs = map[string]GitHubActionsJob{
 		"Kpt": {
 			RunsOn: "ubuntu-latest",
-			Steps: []GitHubActionsStep{
-				{
-					Name: "Run all kpt functions",
-					Uses: "docker://" + KptImage,
-					With: GitHubActionStepArgs{
-						Args: runFnCommand,
-					},
-				},
-			},
+			Steps:  steps,
 		},
 	}
 
@@ -82,6 +82,32 @@ jobs:
             uses: docker://gcr.io/kpt-dev/kpt:latest
             with:
                 args: fn run . --fn-path functions1/ functions2/
+`,
+	},
+	{
+		description: "generates a GitHub Actions pipeline syncing dependencies",
+		config: &types.PipelineConfig{
+			Dir:  ".",
+			Sync: true,
+		},
+		expected: `
+name: kpt
+"on":
+    push:
+        branches:
+          - master
+jobs:
+    Kpt:
+        runs-on: ubuntu-latest
+        steps:
+          - name: Sync kpt dependencies
+            uses: docker://gcr.io/kpt-dev/kpt:latest
+            with:
+                args: pkg sync .
+          - name: Run all kpt functions
+            uses: docker://gcr.io/kpt-dev/kpt:latest
+            with:
+                args: fn run .
 `,
 	},
 }
@@ -70,11 +70,15 @@ func (p *GitLabCI) generateScript(config *types.PipelineConfig) (script string)
 		fnPaths = append(fnPaths, path.Join(mountDir, p))
 	}
 
-	parts := []string{
+	dockerRun := strings.Join([]string{
 		"docker run",
 		fmt.Sprintf("-v $PWD:%s", mountDir),
 		"-v /var/run/docker.sock:/var/run/docker.sock",
 		KptImage,
+	}, " ")
+
+	parts := []string{
+		dockerRun,
 		"fn run",
 		path.Join(mountDir, config.Dir),
 	}
@@ -85,5 +89,15 @@ func (p *GitLabCI) generateScript(config *types.PipelineConfig) (script string)
 
 	script = strings.Join(parts, " ")
 
+	if config.Sync {
+		// sync the dependencies first, which runs their function pipelines.
+		script = fmt.Sprintf(
+			"%s pkg sync %s && %s",
+			dockerRun,
+			path.Join(mountDir, config.Dir),
+			script,
+		)
+	}
+
 	return
 }
@@ -60,9 +60,12 @@ type JenkinsStage struct {
 
 func (stage *JenkinsStage) Init(config *types.PipelineConfig) *JenkinsStage {
 	stage.Name = "Run kpt functions"
-	stage.Scripts = []string{
-		(&JenkinsStageStep{}).Init(config).Generate(),
+	stage.Scripts = nil
+	if config.Sync {
+		syncStep := &JenkinsStageStep{Sync: true}
+		stage.Scripts = append(stage.Scripts, syncStep.Init(config).Generate())
 	}
+	stage.Scripts = append(stage.Scripts, (&JenkinsStageStep{}).Init(config).Generate())
 
 	return stage
 }
@@ -73,7 +76,8 @@ stage('{{.Name}}') {
     steps {
         // This requires that docker is installed on the agent.
         // And your user, which is usually "jenkins", should be added to the "docker" group to access "docker.sock".
-        {{range $i, $script := .Scripts}}sh '''
+        {{range $i, $script := .Scripts}}{{if $i}}
+        {{end}}sh '''
             {{indent 12 $script}}
         '''{{end}}
     }
@@ -89,6 +93,8 @@ type JenkinsStageStep struct {
 	MountedWorkspace string
 	Dir              string
 	FnPaths          []string
+	// Sync syncs the dependencies of Dir instead of running functions.
+	Sync bool
 }
 
 func (step *JenkinsStageStep) Init(config *types.PipelineConfig) *JenkinsStageStep {
@@ -115,8 +121,14 @@ func (step *JenkinsStageStep) Generate() string {
 		fmt.Sprintf("-v $PWD:%s", step.MountedWorkspace),
 		"-v /var/run/docker.sock:/var/run/docker.sock",
 		KptImage,
-		fmt.Sprintf("fn run %s", path.Join(step.MountedWorkspace, step.Dir)),
 	}}
+	if step.Sync {
+		multilineScript.lines = append(multilineScript.lines,
+			fmt.Sprintf("pkg sync %s", path.Join(step.MountedWorkspace, step.Dir)))
+		return multilineScript.Generate()
+	}
+	multilineScript.lines = append(multilineScript.lines,
+		fmt.Sprintf("fn run %s", path.Join(step.MountedWorkspace, step.Dir)))
 	multilineScript.lines = append(multilineScript.lines, fnPaths...)
 
 	return multilineScript.Generate()
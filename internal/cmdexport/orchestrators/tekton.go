@@ -166,12 +166,21 @@ func (task *TektonTask) Init(config *TektonTaskConfig) *TektonTask {
 		}
 	}
 
-	step := &TektonTaskStep{
+	var steps []*TektonTaskStep
+	if config.Sync {
+		steps = append(steps, &TektonTaskStep{
+			Name:         "sync-dependencies",
+			Image:        KptImage,
+			Args:         []string{"pkg", "sync", path.Join(workspaceRoot, config.Dir)},
+			VolumeMounts: []*TektonVolumeMount{volumeMount},
+		})
+	}
+	steps = append(steps, &TektonTaskStep{
 		Name:         config.Name,
 		Image:        KptImage,
 		Args:         args,
 		VolumeMounts: []*TektonVolumeMount{volumeMount},
-	}
+	})
 
 	volume := &TektonVolume{
 		Name: volumeName,
@@ -188,7 +197,7 @@ func (task *TektonTask) Init(config *TektonTaskConfig) *TektonTask {
 
 	task.Spec = &TektonTaskSpec{
 		Workspaces: []*TektonWorkspace{workspace},
-		Steps:      []*TektonTaskStep{step},
+		Steps:      steps,
 		Volumes:    []*TektonVolume{volume},
 	}
 
@@ -138,6 +138,50 @@ spec:
         hostPath:
             path: /var/run/docker.sock
             type: Socket
+`,
+	},
+	{
+		description: "generate a tekton task syncing dependencies",
+		config: &TektonTaskConfig{
+			PipelineConfig: &types.PipelineConfig{
+				Dir:  "local-resources",
+				Sync: true,
+			},
+			Name: "run-kpt-functions",
+		},
+		expected: `
+apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+    name: run-kpt-functions
+spec:
+    workspaces:
+      - name: source
+        mountPath: /source
+    steps:
+      - name: sync-dependencies
+        image: gcr.io/kpt-dev/kpt:latest
+        args:
+          - pkg
+          - sync
+          - $(workspaces.source.path)/local-resources
+        volumeMounts:
+          - name: docker-socket
+            mountPath: /var/run/docker.sock
+      - name: run-kpt-functions
+        image: gcr.io/kpt-dev/kpt:latest
+        args:
+          - fn
+          - run
+          - $(workspaces.source.path)/local-resources
+        volumeMounts:
+          - name: docker-socket
+            mountPath: /var/run/docker.sock
+    volumes:
+      - name: docker-socket
+        hostPath:
+            path: /var/run/docker.sock
+            type: Socket
 `,
 	},
 }
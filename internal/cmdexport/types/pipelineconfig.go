@@ -16,10 +16,14 @@ package types
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/GoogleContainerTools/kpt/internal/cmdexport/pathutil"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 )
 
 // PipelineConfig describes configuration of a pipeline.
@@ -28,6 +32,29 @@ type PipelineConfig struct {
 	FnPaths []string
 	// Current working directory.
 	CWD string
+	// Sync runs `kpt pkg sync` on Dir before the functions, which runs the
+	// function pipelines of its dependencies.
+	Sync bool
+}
+
+// UseKptfile configures the pipeline from the Kptfile of Dir, if it has one.
+// Packages declaring dependencies are synced by the pipeline.
+func (config *PipelineConfig) UseKptfile() error {
+	_, err := os.Stat(filepath.Join(config.Dir, kptfile.KptFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	kf, err := kptfileutil.ReadFile(config.Dir)
+	if err != nil {
+		return err
+	}
+	config.Sync = len(kf.Dependencies) > 0
+
+	return nil
 }
 
 // UseRelativePaths converts all paths to relative paths to the current working directory.
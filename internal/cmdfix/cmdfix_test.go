@@ -237,3 +237,69 @@ spec:
 		})
 	}
 }
+
+func TestFixKptfileAndFunctionAnnotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "Kptfile"), []byte(`metadata:
+  name: app
+`), 0600)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "fn.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: labels
+  annotations:
+    config.kubernetes.io/container: gcr.io/example/set-labels:v1
+data:
+  app: web
+`), 0600)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gitRunner := gitutil.NewLocalGitRunner(dir)
+	if !assert.NoError(t, gitRunner.Run("init", ".")) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, gitRunner.Run("add", ".")) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, gitRunner.Run("commit", "-m", "commit local package")) {
+		t.FailNow()
+	}
+
+	out := &bytes.Buffer{}
+	r := cmdfix.NewRunner("kpt")
+	r.Command.SetArgs([]string{dir})
+	r.Command.SetOut(out)
+	if !assert.NoError(t, r.Command.Execute()) {
+		t.FailNow()
+	}
+	assert.Contains(t, out.String(),
+		`migrated Kptfile from apiVersion "" kind "" to kpt.dev/v1alpha1 Kptfile`)
+	assert.Contains(t, out.String(), "replaced deprecated annotation config.kubernetes.io/container "+
+		"of ConfigMap labels in fn.yaml with config.kubernetes.io/function")
+
+	kf, err := ioutil.ReadFile(filepath.Join(dir, "Kptfile"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, string(kf), "apiVersion: kpt.dev/v1alpha1\n")
+	assert.Contains(t, string(kf), "kind: Kptfile\n")
+
+	fn, err := ioutil.ReadFile(filepath.Join(dir, "fn.yaml"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotContains(t, string(fn), "config.kubernetes.io/container")
+	assert.Contains(t, string(fn), `    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/example/set-labels:v1
+`)
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdfntest contains the fn test command
+package cmdfntest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/fndocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner.  newRun returns a new kpt fn run
+// command, which runs the functions of each test case.
+func NewRunner(parent string, newRun func() *cobra.Command) *Runner {
+	r := &Runner{NewRun: newRun}
+	c := &cobra.Command{
+		Use:     "test DIR [flags] [-- args]",
+		Args:    cobra.MinimumNArgs(1),
+		Short:   fndocs.TestShort,
+		Long:    fndocs.TestShort + "\n" + fndocs.TestLong,
+		Example: fndocs.TestExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringSliceVar(&r.FnPaths, "fn-path", nil,
+		"run the functions declared in these directories instead of the functions declared in the input fixtures.")
+	c.Flags().StringVar(&r.Image, "image", "",
+		"run this container function instead of the functions declared in the input fixtures.")
+	c.Flags().BoolVar(&r.Update, "update", false,
+		"replace the golden files with the output of the functions.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string, newRun func() *cobra.Command) *cobra.Command {
+	return NewRunner(parent, newRun).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Dir     string
+	FnPaths []string
+	Image   string
+	Update  bool
+
+	// FnArgs are the arguments of the --image function
+	FnArgs []string
+
+	// NewRun returns a new kpt fn run command
+	NewRun func() *cobra.Command
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	dirs := args
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		dirs, r.FnArgs = args[:dash], args[dash:]
+	}
+	if len(dirs) != 1 {
+		return errors.Errorf("requires exactly one test directory, got %d", len(dirs))
+	}
+	r.Dir = dirs[0]
+	return nil
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	cases, err := r.testCases()
+	if err != nil {
+		return err
+	}
+	if len(cases) == 0 {
+		return errors.Errorf("no test cases found in %s", r.Dir)
+	}
+	failed := 0
+	for _, name := range cases {
+		passed, err := r.runTestCase(name, c.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		if !passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d test cases failed", failed, len(cases))
+	}
+	return nil
+}
+
+// testCases returns the names of the subdirectories of the test directory
+// containing an input directory.
+func (r *Runner) testCases() ([]string, error) {
+	infos, err := ioutil.ReadDir(r.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var cases []string
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		input, err := os.Stat(filepath.Join(r.Dir, info.Name(), "input"))
+		if err == nil && input.IsDir() {
+			cases = append(cases, info.Name())
+		}
+	}
+	return cases, nil
+}
+
+// runTestCase runs the functions on a copy of the input of the test case,
+// and compares the output to the expected directory, or replaces it with
+// --update.  It returns false if the test case failed.
+func (r *Runner) runTestCase(name string, out io.Writer) (bool, error) {
+	dir := filepath.Join(r.Dir, name)
+	tmp, err := ioutil.TempDir("", "kpt-fn-test-")
+	if err != nil {
+		return false, errors.Wrap(err)
+	}
+	defer os.RemoveAll(tmp)
+	// the copies are named for the diff of the test case
+	actual := filepath.Join(tmp, "actual")
+	if err := copyutil.CopyDir(filepath.Join(dir, "input"), actual); err != nil {
+		return false, errors.Wrap(err)
+	}
+
+	args := []string{actual}
+	for _, p := range r.FnPaths {
+		args = append(args, "--fn-path", p)
+	}
+	if r.Image != "" {
+		args = append(args, "--image", r.Image)
+	}
+	if len(r.FnArgs) > 0 {
+		args = append(append(args, "--"), r.FnArgs...)
+	}
+	var output bytes.Buffer
+	run := r.NewRun()
+	run.SetArgs(args)
+	run.SetOut(&output)
+	run.SetErr(&output)
+	run.SilenceErrors = true
+	run.SilenceUsage = true
+	if err := run.Execute(); err != nil {
+		fmt.Fprintf(out, "FAIL %s: %v\n%s", name, err, output.String())
+		return false, nil
+	}
+
+	expected := filepath.Join(dir, "expected")
+	if r.Update {
+		if err := os.RemoveAll(expected); err != nil {
+			return false, errors.Wrap(err)
+		}
+		if err := copyutil.CopyDir(actual, expected); err != nil {
+			return false, errors.Wrap(err)
+		}
+		fmt.Fprintf(out, "UPDATED %s\n", name)
+		return true, nil
+	}
+
+	// test cases without golden files fail with all of the output
+	if err := os.MkdirAll(filepath.Join(tmp, "expected"), 0700); err != nil {
+		return false, errors.Wrap(err)
+	}
+	if _, err := os.Stat(expected); err == nil {
+		if err := copyutil.CopyDir(expected, filepath.Join(tmp, "expected")); err != nil {
+			return false, errors.Wrap(err)
+		}
+	}
+	changes, err := diff(tmp)
+	if err != nil {
+		return false, err
+	}
+	if changes != "" {
+		fmt.Fprintf(out, "FAIL %s\n%s", name, changes)
+		return false, nil
+	}
+	fmt.Fprintf(out, "PASS %s\n", name)
+	return true, nil
+}
+
+// diff returns the differences between the expected and actual directories
+// within dir, or an empty string if they are the same.
+func diff(dir string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "--no-prefix", "expected", "actual")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 1 {
+		// git diff exits with 1 if the directories differ
+		return stdout.String(), nil
+	}
+	if err != nil {
+		return "", errors.Errorf("git diff failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return "", nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdfntest_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdfntest"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRun returns a fake kpt fn run command, setting the replicas of the
+// deployment to the replicas argument of the function.
+func newRun() *cobra.Command {
+	return &cobra.Command{
+		Use: "run",
+		RunE: func(c *cobra.Command, args []string) error {
+			path := filepath.Join(args[0], "deploy.yaml")
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			replicas := strings.TrimPrefix(args[len(args)-1], "replicas=")
+			return ioutil.WriteFile(path,
+				[]byte(strings.Replace(string(b), "replicas: 1", "replicas: "+replicas, 1)), 0600)
+		},
+	}
+}
+
+// setupTests returns a test directory with a test case.
+func setupTests(t *testing.T) string {
+	d, err := ioutil.TempDir("", "kpt-fn-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, dir := range []string{"input", "expected"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(d, "replicas", dir), 0700))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "replicas", "input", "deploy.yaml"),
+		[]byte("kind: Deployment\nreplicas: 1\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "replicas", "expected", "deploy.yaml"),
+		[]byte("kind: Deployment\nreplicas: 3\n"), 0600))
+	// directories without input aren't test cases
+	assert.NoError(t, os.MkdirAll(filepath.Join(d, "fixtures"), 0700))
+	return d
+}
+
+func TestCmd_args(t *testing.T) {
+	r := cmdfntest.NewRunner("kpt", newRun)
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"tests", "--fn-path", "pkg", "--image", "gcr.io/example/fn", "--", "a=b"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "tests", r.Dir)
+	assert.Equal(t, []string{"pkg"}, r.FnPaths)
+	assert.Equal(t, "gcr.io/example/fn", r.Image)
+	assert.Equal(t, []string{"a=b"}, r.FnArgs)
+}
+
+func TestCmd_pass(t *testing.T) {
+	d := setupTests(t)
+	defer os.RemoveAll(d)
+
+	out := &bytes.Buffer{}
+	r := cmdfntest.NewRunner("kpt", newRun)
+	r.Command.SetArgs([]string{d, "--", "replicas=3"})
+	r.Command.SetOut(out)
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "PASS replicas\n", out.String())
+}
+
+func TestCmd_fail(t *testing.T) {
+	d := setupTests(t)
+	defer os.RemoveAll(d)
+
+	out := &bytes.Buffer{}
+	r := cmdfntest.NewRunner("kpt", newRun)
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{d, "--", "replicas=5"})
+	r.Command.SetOut(out)
+	assert.EqualError(t, r.Command.Execute(), "1 of 1 test cases failed")
+	assert.Contains(t, out.String(), "FAIL replicas\n")
+	assert.Contains(t, out.String(), "--- expected/deploy.yaml\n+++ actual/deploy.yaml\n")
+	assert.Contains(t, out.String(), "-replicas: 3\n+replicas: 5\n")
+}
+
+func TestCmd_update(t *testing.T) {
+	d := setupTests(t)
+	defer os.RemoveAll(d)
+
+	out := &bytes.Buffer{}
+	r := cmdfntest.NewRunner("kpt", newRun)
+	r.Command.SetArgs([]string{d, "--update", "--", "replicas=5"})
+	r.Command.SetOut(out)
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "UPDATED replicas\n", out.String())
+	b, err := ioutil.ReadFile(filepath.Join(d, "replicas", "expected", "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kind: Deployment\nreplicas: 5\n", string(b))
+
+	// the input is not changed
+	b, err = ioutil.ReadFile(filepath.Join(d, "replicas", "input", "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kind: Deployment\nreplicas: 1\n", string(b))
+}
+
+func TestCmd_noTestCases(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	r := cmdfntest.NewRunner("kpt", newRun)
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{d})
+	assert.EqualError(t, r.Command.Execute(), "no test cases found in "+d)
+}
@@ -291,6 +291,34 @@ func TestCmd_Execute_flagAndArgParsing(t *testing.T) {
 	assert.Equal(t, "master", r.Get.Ref)
 	assert.Equal(t, filepath.Join(d, "package", "my-app"), r.Get.Destination)
 
+	// OCI image -- should use the repository name
+	r = cmdget.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"oci://ghcr.io/org/pkg:v1.2.3", filepath.Join(d, "package")})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "ghcr.io/org/pkg", r.Get.OCI.Image)
+	assert.Equal(t, "v1.2.3", r.Get.OCI.Ref)
+	assert.Equal(t, "", r.Get.Repo)
+	assert.Equal(t, filepath.Join(d, "package", "pkg"), r.Get.Destination)
+
+	// Helm chart -- should use the chart name
+	r = cmdget.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"helm://charts.bitnami.com/bitnami/nginx", filepath.Join(d, "package"),
+		"--version", "8.2.0", "--values", "a.yaml,b.yaml"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, kptfile.Helm{Repo: "https://charts.bitnami.com/bitnami", Chart: "nginx", Version: "8.2.0"},
+		r.Get.Helm)
+	assert.Equal(t, []string{"a.yaml", "b.yaml"}, r.Get.Values)
+	assert.Equal(t, filepath.Join(d, "package", "nginx"), r.Get.Destination)
+
+	r = cmdget.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = failRun
+	r.Command.SetArgs([]string{"https://github.com/foo/bar.git/baz", filepath.Join(d, "package"), "--version", "1"})
+	assert.EqualError(t, r.Command.Execute(), "--version may only be specified for helm:// charts")
+
 	r = cmdget.NewRunner("kpt")
 	r.Command.RunE = NoOpRunE
 	r.Command.SetArgs([]string{"https://github.com/foo/bar.git/baz@v1", filepath.Join(d, "package", "my-app")})
@@ -299,6 +327,25 @@ func TestCmd_Execute_flagAndArgParsing(t *testing.T) {
 	assert.Equal(t, "/baz", r.Get.Directory)
 	assert.Equal(t, "v1", r.Get.Ref)
 	assert.Equal(t, filepath.Join(d, "package", "my-app"), r.Get.Destination)
+	assert.Equal(t, 1, r.Get.Depth)
+	assert.False(t, r.Get.Sparse)
+	assert.False(t, r.Get.Submodules)
+
+	r = cmdget.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"https://github.com/foo/bar.git/baz@v1", filepath.Join(d, "package", "my-app"),
+		"--depth", "0", "--sparse", "--submodules"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, 0, r.Get.Depth)
+	assert.True(t, r.Get.Sparse)
+	assert.True(t, r.Get.Submodules)
+
+	r = cmdget.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = failRun
+	r.Command.SetArgs([]string{"https://github.com/foo/bar.git/baz", filepath.Join(d, "package"), "--depth", "-1"})
+	assert.EqualError(t, r.Command.Execute(), "--depth must not be negative")
 
 	r = cmdget.NewRunner("kpt")
 	r.Command.RunE = NoOpRunE
@@ -0,0 +1,73 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdgraph_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdgraph"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_mermaid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-graph-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	for _, pkg := range []string{"app", filepath.Join("app", "db")} {
+		if !assert.NoError(t, os.MkdirAll(filepath.Join(dir, pkg), 0700)) {
+			t.FailNow()
+		}
+		kf := kptfile.KptFile{ResourceMeta: kptfile.TypeMeta}
+		kf.Name = filepath.Base(pkg)
+		kf.Upstream = kptfile.Upstream{
+			Type: kptfile.GitOrigin,
+			Git:  kptfile.Git{Repo: "https://github.com/example/pkgs", Directory: filepath.Base(pkg), Ref: "v1"},
+		}
+		assert.NoError(t, kptfileutil.WriteFile(filepath.Join(dir, pkg), kf))
+	}
+
+	r := cmdgraph.NewRunner("kpt")
+	out := &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{dir, "--output", "mermaid"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, `graph LR
+  n0["app"]
+  n1["app/db"]
+  n2(["https://github.com/example/pkgs/app"])
+  n3(["https://github.com/example/pkgs/db"])
+  n0 -->|"upstream @v1"| n2
+  n0 -->|"subpackage"| n1
+  n1 -->|"upstream @v1"| n3
+`, out.String())
+}
+
+func TestCmd_output(t *testing.T) {
+	r := cmdgraph.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--output", "svg"})
+	assert.EqualError(t, r.Command.Execute(), "--output must be one of: dot, json, mermaid")
+}
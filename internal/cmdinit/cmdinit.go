@@ -16,21 +16,16 @@
 package cmdinit
 
 import (
-	"bytes"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
 
 	docs "github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
 	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
-	"github.com/GoogleContainerTools/kpt/internal/util/man"
-	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/internal/util/pkgtemplate"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/kustomize/kyaml/errors"
-	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 // NewRunner returns a command runner.
@@ -50,6 +45,9 @@ func NewRunner(parent string) *Runner {
 	c.Flags().StringVar(&r.Name, "name", "", "package name.  defaults to the directory base name.")
 	c.Flags().StringSliceVar(&r.Tags, "tag", []string{}, "list of tags for the package.")
 	c.Flags().StringVar(&r.URL, "url", "", "link to page with information about the package.")
+	c.Flags().StringVar(&r.Template, "template", pkgtemplate.Blank,
+		"template to scaffold the package from.  One of: "+strings.Join(pkgtemplate.Templates, ", ")+
+			", or a git package REPO_URI[.git]/PKG_PATH[@VERSION].")
 	cmdutil.FixDocs("kpt", parent, c)
 	r.Command = c
 	return r
@@ -66,6 +64,7 @@ type Runner struct {
 	Name        string
 	Description string
 	URL         string
+	Template    string
 }
 
 func (r *Runner) preRunE(_ *cobra.Command, args []string) error {
@@ -84,103 +83,27 @@ func (r *Runner) preRunE(_ *cobra.Command, args []string) error {
 }
 
 func (r *Runner) runE(c *cobra.Command, args []string) error {
-	var err error
-	if _, err = os.Stat(args[0]); os.IsNotExist(err) {
+	if _, err := os.Stat(args[0]); os.IsNotExist(err) {
 		return errors.Errorf("%s does not exist", err)
 	}
 
-	if _, err = os.Stat(filepath.Join(args[0], "Kptfile")); os.IsNotExist(err) {
-		fmt.Fprintf(c.OutOrStdout(), "writing %s\n", filepath.Join(args[0], "Kptfile"))
-		k := kptfile.KptFile{
-			ResourceMeta: yaml.ResourceMeta{
-				ObjectMeta: yaml.ObjectMeta{
-					NameMeta: yaml.NameMeta{
-						Name: r.Name,
-					},
-				},
-			},
-			PackageMeta: kptfile.PackageMeta{
-				ShortDescription: r.Description,
-				URL:              r.URL,
-				Tags:             r.Tags,
-			},
-		}
-
-		// serialize the gvk when writing the Kptfile
-		k.Kind = kptfile.TypeMeta.Kind
-		k.APIVersion = kptfile.TypeMeta.APIVersion
-
-		err = func() error {
-			f, err := os.Create(filepath.Join(args[0], "Kptfile"))
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-			e := yaml.NewEncoder(f)
-
-			defer e.Close()
-			return e.Encode(k)
-		}()
-		if err != nil {
-			return err
-		}
+	values := pkgtemplate.Values{Name: r.Name, Description: r.Description, Tags: r.Tags, URL: r.URL}
+	// template packages keep their description unless it is set
+	if !c.Flags().Changed("description") && !r.isBuiltin() {
+		values.Description = ""
 	}
+	written, err := pkgtemplate.Scaffold(args[0], r.Template, values)
+	for _, path := range written {
+		fmt.Fprintf(c.OutOrStdout(), "writing %s\n", path)
+	}
+	return err
+}
 
-	if _, err = os.Stat(filepath.Join(args[0], man.ManFilename)); os.IsNotExist(err) {
-		fmt.Fprintf(c.OutOrStdout(), "writing %s\n", filepath.Join(args[0], man.ManFilename))
-		buff := &bytes.Buffer{}
-		t, err := template.New("man").Parse(manTemplate)
-		if err != nil {
-			return err
-		}
-
-		err = t.Execute(buff, r)
-		if err != nil {
-			return err
-		}
-
-		// Replace single quotes with backticks.
-		content := strings.ReplaceAll(buff.String(), "'", "`")
-
-		err = ioutil.WriteFile(filepath.Join(args[0], man.ManFilename), []byte(content), 0600)
-		if err != nil {
-			return err
+func (r *Runner) isBuiltin() bool {
+	for _, t := range pkgtemplate.Templates {
+		if r.Template == t {
+			return true
 		}
 	}
-
-	return nil
+	return false
 }
-
-// manTemplate is the content for the automatically generated README.md file.
-// It uses ' instead of ` since golang doesn't allow using ` in a raw string
-// literal. We do a replace on the content before printing.
-var manTemplate = `# {{.Name}}
-
-## Description
-{{.Description}}
-
-## Usage
-
-### Fetch the package
-'kpt pkg get REPO_URI[.git]/PKG_PATH[@VERSION] {{.Name}}'
-Details: https://googlecontainertools.github.io/kpt/reference/pkg/get/
-
-### View package content
-'kpt cfg tree {{.Name}}'
-Details: https://googlecontainertools.github.io/kpt/reference/cfg/tree/
-
-### List setters
-'kpt cfg list-setters {{.Name}}'
-Details: https://googlecontainertools.github.io/kpt/reference/cfg/list-setters/
-
-### Set a value
-'kpt cfg set {{.Name}} NAME VALUE'
-Details: https://googlecontainertools.github.io/kpt/reference/cfg/set/
-
-### Apply the package
-'''
-kpt live init {{.Name}}
-kpt live apply {{.Name}} --reconcile-timeout=2m --output=table
-'''
-Details: https://googlecontainertools.github.io/kpt/reference/live/
-`
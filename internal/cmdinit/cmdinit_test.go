@@ -15,6 +15,7 @@
 package cmdinit_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -126,6 +127,36 @@ packageMetadata:
 `, string(b))
 }
 
+// TestCmd_template verifies the package is scaffolded from the template
+func TestCmd_template(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, os.Mkdir(filepath.Join(d, "my-pkg"), 0700))
+
+	r := cmdinit.NewRunner("kpt")
+	out := &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{filepath.Join(d, "my-pkg"), "--template", "deployment"})
+	assert.NoError(t, r.Command.Execute())
+	var expected string
+	for _, name := range []string{"Kptfile", man.ManFilename, "deployment.yaml", "functions.yaml", "service.yaml"} {
+		expected += "writing " + filepath.Join(d, "my-pkg", name) + "\n"
+	}
+	assert.Equal(t, expected, out.String())
+
+	b, err := ioutil.ReadFile(filepath.Join(d, "my-pkg", "deployment.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `  name: my-pkg # {"$openapi":"name"}`)
+
+	r = cmdinit.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{filepath.Join(d, "my-pkg"), "--template", "statefulset"})
+	assert.EqualError(t, r.Command.Execute(), `unknown template "statefulset", `+
+		`must be one of: blank, deployment, namespace, crd-operator, or a git package`)
+}
+
 // TestCmd_failExists verifies the command throws and error if the directory exists
 func TestCmd_failNotExists(t *testing.T) {
 	d, err := ioutil.TempDir("", "kpt")
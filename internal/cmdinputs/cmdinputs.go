@@ -0,0 +1,86 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdinputs contains the inputs command
+package cmdinputs
+
+import (
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/inputs"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "inputs [DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.InputsShort,
+		Long:    pkgdocs.InputsShort + "\n" + pkgdocs.InputsLong,
+		Example: pkgdocs.InputsExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringVarP(&r.Output, "output", "o", "text",
+		"output format.  One of: "+strings.Join(inputs.Formats, ", "))
+	c.Flags().BoolVar(&r.Check, "check", false,
+		"fail if required inputs aren't set or inputs have invalid values.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Dir     string
+	Output  string
+	Check   bool
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Dir = "."
+	if len(args) > 0 {
+		r.Dir = args[0]
+	}
+	for _, f := range inputs.Formats {
+		if r.Output == f {
+			return nil
+		}
+	}
+	return errors.Errorf("--output must be one of: %s", strings.Join(inputs.Formats, ", "))
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	values, err := inputs.List(r.Dir)
+	if err != nil {
+		return err
+	}
+	if err := inputs.Write(c.OutOrStdout(), values, r.Output); err != nil {
+		return err
+	}
+	if r.Check {
+		return inputs.Check(r.Dir)
+	}
+	return nil
+}
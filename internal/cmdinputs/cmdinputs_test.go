@@ -0,0 +1,86 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdinputs_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdinputs"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_args(t *testing.T) {
+	r := cmdinputs.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"apps", "-o", "json"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "apps", r.Dir)
+	assert.Equal(t, "json", r.Output)
+
+	r = cmdinputs.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, ".", r.Dir)
+	assert.Equal(t, "text", r.Output)
+}
+
+func TestCmd_output(t *testing.T) {
+	r := cmdinputs.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--output", "yaml"})
+	assert.EqualError(t, r.Command.Execute(), "--output must be one of: text, json")
+}
+
+func TestCmd_check(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-inputs")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+inputs:
+- name: replicas
+  type: int
+  default: "3"
+- name: domain
+  required: true
+  description: the domain of the app
+`), 0600))
+
+	r := cmdinputs.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	var b bytes.Buffer
+	r.Command.SetOut(&b)
+	r.Command.SetArgs([]string{d, "--check"})
+	assert.EqualError(t, r.Command.Execute(), "package "+d+" has invalid inputs:\n"+
+		"  - input domain is required, set it with: kpt cfg set "+d+" domain VALUE")
+	assert.Equal(t, `NAME      TYPE    REQUIRED  DEFAULT  VALUE    DESCRIPTION
+replicas  int     no        3        3        -
+domain    string  yes       -        <unset>  the domain of the app
+`, b.String())
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdlint contains the lint command
+package cmdlint
+
+import (
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/lint"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "lint [DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.LintShort,
+		Long:    pkgdocs.LintShort + "\n" + pkgdocs.LintLong,
+		Example: pkgdocs.LintExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringVarP(&r.Output, "output", "o", "text",
+		"output format.  One of: "+strings.Join(lint.Formats, ", "))
+	c.Flags().BoolVar(&r.Strict, "strict", false,
+		"fail on warnings too, not only on errors.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Dir     string
+	Output  string
+	Strict  bool
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Dir = "."
+	if len(args) > 0 {
+		r.Dir = args[0]
+	}
+	for _, f := range lint.Formats {
+		if r.Output == f {
+			return nil
+		}
+	}
+	return errors.Errorf("--output must be one of: %s", strings.Join(lint.Formats, ", "))
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	findings, err := lint.Package(r.Dir)
+	if err != nil {
+		return err
+	}
+	if err := lint.Write(c.OutOrStdout(), findings, r.Output); err != nil {
+		return err
+	}
+	failed := lint.Errors(findings)
+	if r.Strict {
+		failed = len(findings)
+	}
+	if failed > 0 {
+		return errors.Errorf("package %s failed %d lint check(s)", r.Dir, failed)
+	}
+	return nil
+}
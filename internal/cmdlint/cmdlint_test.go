@@ -0,0 +1,88 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdlint_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdlint"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_args(t *testing.T) {
+	r := cmdlint.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"apps", "-o", "json"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "apps", r.Dir)
+	assert.Equal(t, "json", r.Output)
+
+	r = cmdlint.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, ".", r.Dir)
+	assert.Equal(t, "text", r.Output)
+}
+
+func TestCmd_output(t *testing.T) {
+	r := cmdlint.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--output", "yaml"})
+	assert.EqualError(t, r.Command.Execute(), "--output must be one of: text, json")
+}
+
+func TestCmd_strict(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-lint")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+dependencies:
+- name: db
+  git:
+    repo: https://github.com/example/db
+    directory: /
+    ref: v1
+  functions:
+  - image: gcr.io/kpt-fn/set-labels
+`), 0600))
+
+	r := cmdlint.NewRunner("kpt")
+	var b bytes.Buffer
+	r.Command.SetOut(&b)
+	r.Command.SetArgs([]string{d})
+	assert.NoError(t, r.Command.Execute())
+	assert.Contains(t, b.String(), "isn't pinned to a version tag or digest")
+
+	r = cmdlint.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetOut(&b)
+	r.Command.SetArgs([]string{d, "--strict"})
+	assert.EqualError(t, r.Command.Execute(), "package "+d+" failed 1 lint check(s)")
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdlock contains the lock command
+package cmdlock
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/spf13/cobra"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "lock [LOCAL_PKG_DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.LockShort,
+		Long:    pkgdocs.LockShort + "\n" + pkgdocs.LockLong,
+		Example: pkgdocs.LockExamples,
+		RunE:    r.runE,
+	}
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+}
+
+func (r *Runner) runE(c *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = filepath.Clean(args[0])
+	}
+	lock, err := kptfileutil.Lock(dir)
+	if err != nil {
+		return err
+	}
+	if err := kptfileutil.WriteLockFile(dir, lock); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.OutOrStdout(), "wrote %s with %d packages\n",
+		filepath.Join(dir, kptfile.LockFileName), len(lock.Packages))
+	return nil
+}
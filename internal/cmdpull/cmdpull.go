@@ -0,0 +1,82 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdpull contains the fn pull command
+package cmdpull
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/fndocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "pull [DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   fndocs.PullShort,
+		Long:    fndocs.PullShort + "\n" + fndocs.PullLong,
+		Example: fndocs.PullExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringSliceVar(&r.FnPaths, "fn-path", nil,
+		"also pull the images of the functions in these directories.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Dir     string
+	FnPaths []string
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Dir = "."
+	if len(args) > 0 {
+		r.Dir = args[0]
+	}
+	return nil
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	images, err := fnimage.Images(append([]string{r.Dir}, r.FnPaths...)...)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return errors.Errorf("no function images found in %s", r.Dir)
+	}
+	for _, image := range images {
+		digest, err := fnimage.Pull(image)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.OutOrStdout(), "%s@%s\n", image, digest)
+	}
+	return nil
+}
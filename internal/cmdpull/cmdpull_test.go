@@ -0,0 +1,52 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdpull_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdpull"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_args(t *testing.T) {
+	r := cmdpull.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"pkg", "--fn-path", "fns,more-fns"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "pkg", r.Dir)
+	assert.Equal(t, []string{"fns", "more-fns"}, r.FnPaths)
+
+	r = cmdpull.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, ".", r.Dir)
+}
+
+func TestCmd_noImages(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-pull-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	r := cmdpull.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{d})
+	assert.EqualError(t, r.Command.Execute(), "no function images found in "+d)
+}
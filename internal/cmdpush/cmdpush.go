@@ -0,0 +1,113 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdpush contains the push command
+package cmdpush
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	docs "github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/push"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "push LOCAL_PKG_DIR {REPO_URI.git[/PKG_PATH]@TAG | oci://IMAGE[:TAG]}",
+		Args:    cobra.ExactArgs(2),
+		Short:   docs.PushShort,
+		Long:    docs.PushShort + "\n" + docs.PushLong,
+		Example: docs.PushExamples,
+		RunE:    r.runE,
+		PreRunE: r.preRunE,
+	}
+	cmdutil.FixDocs("kpt", parent, c)
+	r.Command = c
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Push    push.Command
+	Command *cobra.Command
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Push.Path = filepath.Clean(args[0])
+	if oci.IsReference(args[1]) {
+		ref, err := oci.ParseReference(args[1])
+		if err != nil {
+			return err
+		}
+		if oci.IsDigest(ref.Ref) {
+			return errors.Errorf("unable to push to digest %s; push to a tag", ref.Ref)
+		}
+		r.Push.OCI = kptfile.OCI{Image: ref.Image(), Ref: ref.Ref}
+		return nil
+	}
+	g, err := parseGit(args[1])
+	if err != nil {
+		return err
+	}
+	r.Push.Git = g
+	return nil
+}
+
+// parseGit parses a git destination of the form REPO_URI.git[/PKG_PATH]@TAG.
+// Unlike the source of kpt pkg get, the tag must be given explicitly.
+func parseGit(arg string) (kptfile.Git, error) {
+	parts := strings.SplitN(arg, ".git", 2)
+	if len(parts) != 2 {
+		return kptfile.Git{}, errors.Errorf(
+			"invalid destination %s: must be REPO_URI.git[/PKG_PATH]@TAG or oci://IMAGE[:TAG]", arg)
+	}
+	i := strings.LastIndex(parts[1], "@")
+	if i < 0 || parts[1][i+1:] == "" {
+		return kptfile.Git{}, errors.Errorf("invalid destination %s: must specify @TAG", arg)
+	}
+	dir := strings.Trim(parts[1][:i], "/")
+	return kptfile.Git{
+		Repo:      strings.TrimSuffix(parts[0], "/"),
+		Directory: "/" + dir,
+		Ref:       strings.TrimSuffix(parts[1][i+1:], "/"),
+	}, nil
+}
+
+func (r *Runner) runE(c *cobra.Command, args []string) error {
+	published, err := r.Push.Run()
+	if err != nil {
+		return err
+	}
+	if published.Type == kptfile.OCIOrigin {
+		fmt.Fprintf(c.OutOrStdout(), "pushed package %s to %s:%s@%s\n",
+			r.Push.Path, published.OCI.Image, published.OCI.Ref, published.OCI.Digest)
+		return nil
+	}
+	fmt.Fprintf(c.OutOrStdout(), "pushed package %s to %s%s@%s at commit %s\n",
+		r.Push.Path, published.Git.Repo, published.Git.Directory, published.Git.Ref, published.Git.Commit)
+	return nil
+}
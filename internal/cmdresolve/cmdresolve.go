@@ -0,0 +1,67 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdresolve contains the resolve command
+package cmdresolve
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/resolve"
+	"github.com/spf13/cobra"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "resolve [LOCAL_PKG_DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.ResolveShort,
+		Long:    pkgdocs.ResolveShort + "\n" + pkgdocs.ResolveLong,
+		Example: pkgdocs.ResolveExamples,
+		RunE:    r.runE,
+	}
+	c.Flags().StringVar(&r.keep, "keep", "",
+		"resolve all conflicts by keeping one of: "+strings.Join(resolve.Choices, ", "))
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	keep    string
+}
+
+func (r *Runner) runE(c *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = filepath.Clean(args[0])
+	}
+	return resolve.Command{
+		Path:   dir,
+		Keep:   resolve.Choice(r.keep),
+		Input:  c.InOrStdin(),
+		Output: c.OutOrStdout(),
+	}.Run()
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdsbom contains the sbom command
+package cmdsbom
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/sbom"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// SourceDateEpochEnv is the name of the environment variable setting the
+// creation time of the SBOM, in seconds since the epoch, for reproducible
+// builds.  Defaults to the current time.
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "sbom [DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.SbomShort,
+		Long:    pkgdocs.SbomShort + "\n" + pkgdocs.SbomLong,
+		Example: pkgdocs.SbomExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringVar(&r.Format, "format", "spdx",
+		"format of the SBOM.  One of: "+strings.Join(sbom.Formats, ", "))
+	c.Flags().BoolVar(&r.ResolveDigests, "resolve-digests", false,
+		"resolve the digests of the function images referenced by tag with their registries.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command        *cobra.Command
+	Dir            string
+	Format         string
+	ResolveDigests bool
+	Created        time.Time
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Dir = "."
+	if len(args) > 0 {
+		r.Dir = args[0]
+	}
+	found := false
+	for _, f := range sbom.Formats {
+		found = found || r.Format == f
+	}
+	if !found {
+		return errors.Errorf("--format must be one of: %s", strings.Join(sbom.Formats, ", "))
+	}
+	r.Created = time.Now()
+	if epoch := os.Getenv(SourceDateEpochEnv); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return errors.Errorf("invalid %s %q: %v", SourceDateEpochEnv, epoch, err)
+		}
+		r.Created = time.Unix(seconds, 0)
+	}
+	return nil
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	inv, err := sbom.Read(r.Dir)
+	if err != nil {
+		return err
+	}
+	if r.ResolveDigests {
+		if err := inv.ResolveDigests(); err != nil {
+			return err
+		}
+	}
+	return inv.Write(c.OutOrStdout(), r.Format, r.Created)
+}
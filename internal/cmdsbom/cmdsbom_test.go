@@ -0,0 +1,47 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdsbom_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdsbom"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_flags(t *testing.T) {
+	os.Setenv(cmdsbom.SourceDateEpochEnv, "1600000000")
+	defer os.Unsetenv(cmdsbom.SourceDateEpochEnv)
+
+	r := cmdsbom.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"platform", "--format", "cyclonedx"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "platform", r.Dir)
+	assert.Equal(t, "cyclonedx", r.Format)
+	assert.True(t, r.Created.Equal(time.Unix(1600000000, 0)))
+}
+
+func TestCmd_invalidFormat(t *testing.T) {
+	r := cmdsbom.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--format", "swid"})
+	assert.EqualError(t, r.Command.Execute(), "--format must be one of: spdx, cyclonedx")
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdsign_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdsign"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_image(t *testing.T) {
+	var commands []string
+	cosign := signature.Cosign
+	signature.Cosign = func(args ...string) ([]byte, error) {
+		commands = append(commands, strings.Join(args, " "))
+		return nil, nil
+	}
+	defer func() { signature.Cosign = cosign }()
+
+	r := cmdsign.NewRunner("kpt")
+	out := &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{"oci://ghcr.io/org/pkg:v1", "--key", "cosign.key"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, []string{"sign --yes --key cosign.key ghcr.io/org/pkg:v1"}, commands)
+	assert.Equal(t, "signed image ghcr.io/org/pkg:v1\n", out.String())
+}
+
+func TestCmd_invalidImage(t *testing.T) {
+	r := cmdsign.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.SetArgs([]string{"oci://pkg"})
+	assert.EqualError(t, r.Command.Execute(),
+		"invalid OCI reference oci://pkg; must be oci://REGISTRY/REPOSITORY[:TAG|@DIGEST]")
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdstatus contains the status command
+package cmdstatus
+
+import (
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/status"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "status [DIR]",
+		Args:    cobra.MaximumNArgs(1),
+		Short:   pkgdocs.StatusShort,
+		Long:    pkgdocs.StatusShort + "\n" + pkgdocs.StatusLong,
+		Example: pkgdocs.StatusExamples,
+		PreRunE: r.preRunE,
+		RunE:    r.runE,
+	}
+	c.Flags().StringVarP(&r.Output, "output", "o", "text",
+		"output format.  One of: "+strings.Join(status.Formats, ", "))
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Dir     string
+	Output  string
+}
+
+func (r *Runner) preRunE(c *cobra.Command, args []string) error {
+	r.Dir = "."
+	if len(args) > 0 {
+		r.Dir = args[0]
+	}
+	for _, f := range status.Formats {
+		if r.Output == f {
+			return nil
+		}
+	}
+	return errors.Errorf("--output must be one of: %s", strings.Join(status.Formats, ", "))
+}
+
+func (r *Runner) runE(c *cobra.Command, _ []string) error {
+	packages, err := status.Workspace(r.Dir)
+	if err != nil {
+		return err
+	}
+	return status.Write(c.OutOrStdout(), packages, r.Output)
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdstatus_test
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdstatus"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_args(t *testing.T) {
+	r := cmdstatus.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"apps", "-o", "json"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, "apps", r.Dir)
+	assert.Equal(t, "json", r.Output)
+
+	r = cmdstatus.NewRunner("kpt")
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, ".", r.Dir)
+	assert.Equal(t, "text", r.Output)
+}
+
+func TestCmd_output(t *testing.T) {
+	r := cmdstatus.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--output", "yaml"})
+	assert.EqualError(t, r.Command.Execute(), "--output must be one of: text, json")
+}
@@ -16,10 +16,14 @@
 package cmdsync
 
 import (
+	"os"
+
 	docs "github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
 	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
 	"github.com/GoogleContainerTools/kpt/internal/util/sync"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/errors"
 )
 
 // NewRunner returns a command runner.
@@ -39,6 +43,21 @@ func NewRunner(parent string) *Runner {
 		"print verbose logging information.")
 	c.Flags().BoolVar(&r.Sync.DryRun, "dry-run", false,
 		"print sync actions without performing them.")
+	c.Flags().BoolVar(&r.Sync.AllowExec, "allow-exec", false,
+		"allow exec functions to run the binaries in the execAllowlist of the Kptfile.")
+	c.Flags().IntVar(&r.Sync.Parallel, "parallel", 1,
+		"the number of dependencies synced at a time.")
+	c.Flags().BoolVar(&r.Sync.FnCache, "fn-cache", false,
+		"cache the output of the functions of the dependencies, and reuse it for the same input.")
+	c.Flags().Bool("fail-fast", true,
+		"stop running the functions of a dependency at the first failing function. "+
+			"Overrides the functions.failFast field of the Kptfile.")
+	c.Flags().StringVar(&r.Sync.Environment, "environment", "",
+		"hydrate the dependencies declaring this environment in their Kptfile with its setters and functions.")
+	c.Flags().BoolVar(&r.ShowTimings, "show-timings", false,
+		"print the time each function of the dependencies took to run, and to pull its image.")
+	c.Flags().StringVar(&r.TraceFile, "trace", "",
+		"write a JSON trace of the functions of the dependencies with their timings to the file.")
 	cmdutil.FixDocs("kpt", parent, c)
 	r.Command = c
 
@@ -53,15 +72,49 @@ func NewCommand(parent string) *cobra.Command {
 type Runner struct {
 	Sync    sync.Command
 	Command *cobra.Command
+
+	// ShowTimings prints the timings of the functions after syncing
+	ShowTimings bool
+
+	// TraceFile is the file the JSON trace of the functions is written to
+	TraceFile string
 }
 
 func (r *Runner) preRunE(c *cobra.Command, args []string) error {
 	r.Sync.Dir = args[0]
 	r.Sync.StdOut = c.OutOrStdout()
 	r.Sync.StdErr = c.ErrOrStderr()
+	if c.Flags().Changed("fail-fast") {
+		failFast, err := c.Flags().GetBool("fail-fast")
+		if err != nil {
+			return err
+		}
+		r.Sync.FailFast = &failFast
+	}
+	if r.ShowTimings || r.TraceFile != "" {
+		r.Sync.Trace = &functions.Trace{}
+	}
 	return nil
 }
 
 func (r *Runner) runE(c *cobra.Command, args []string) error {
-	return r.Sync.Run()
+	err := r.Sync.Run()
+	// the trace is written even if a function failed, to find the failing
+	// step
+	if r.ShowTimings {
+		if err := r.Sync.Trace.WriteTimings(c.ErrOrStderr()); err != nil {
+			return err
+		}
+	}
+	if r.TraceFile != "" {
+		f, err := os.Create(r.TraceFile)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		defer f.Close()
+		if err := r.Sync.Trace.WriteJSON(f); err != nil {
+			return err
+		}
+	}
+	return err
 }
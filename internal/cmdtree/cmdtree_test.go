@@ -0,0 +1,79 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdtree_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/cmdtree"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_json(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-tree-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Kptfile"), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+`), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "resources.yaml"), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+`), 0600))
+
+	r := cmdtree.NewRunner("kpt")
+	out := &bytes.Buffer{}
+	r.Command.SetOut(out)
+	r.Command.SetArgs([]string{dir, "--output", "json", "--kind", "ConfigMap"})
+	assert.NoError(t, r.Command.Execute())
+	assert.Equal(t, `{
+  "name": "app",
+  "path": ".",
+  "resourceCount": 1,
+  "resources": [
+    {
+      "file": "resources.yaml",
+      "apiVersion": "v1",
+      "kind": "ConfigMap",
+      "name": "app"
+    }
+  ]
+}
+`, out.String())
+}
+
+func TestCmd_output(t *testing.T) {
+	r := cmdtree.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.SilenceUsage = true
+	r.Command.RunE = func(*cobra.Command, []string) error { return nil }
+	r.Command.SetArgs([]string{"--output", "dot"})
+	assert.EqualError(t, r.Command.Execute(), "--output must be one of: text, json, yaml")
+}
@@ -23,6 +23,8 @@ import (
 
 	docs "github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
 	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
 	"github.com/GoogleContainerTools/kpt/internal/util/update"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/kustomize/kyaml/errors"
@@ -48,11 +50,23 @@ func NewRunner(parent string) *Runner {
 		"update strategy for preserving changes to the local package -- must be one of: "+
 			strings.Join(update.Strategies, ","))
 	c.Flags().BoolVar(&r.Update.DryRun, "dry-run", false,
-		"print the git patch rather than merging it.")
+		"print a summary of the changes rather than updating the package, or the git patch for alpha-git-patch.")
 	c.Flags().BoolVar(&r.AutoSet, "auto-set", true,
 		"automatically perform setters based off the environment")
 	c.Flags().BoolVar(&r.Update.Verbose, "verbose", false,
 		"print verbose logging information.")
+	c.Flags().IntVar(&r.Update.Depth, "depth", git.DefaultDepth,
+		"number of commits to fetch from the git repo.  0 fetches the full history.")
+	c.Flags().BoolVar(&r.recurse, "recurse-workspace", false,
+		"update every package with an upstream under LOCAL_PKG_DIR.  Same as LOCAL_PKG_DIR/...")
+	c.Flags().BoolVar(&r.Update.Sparse, "sparse", false,
+		"fetch only the files of the package directory, using a partial clone and sparse checkout of the git repo.")
+	c.Flags().BoolVar(&r.Update.Submodules, "submodules", false,
+		"initialize the git submodules of the package directory, and record it in the Kptfile.")
+	c.Flags().BoolVar(&r.verifySignature, "verify-signature", false,
+		"verify the updated upstream is signed by a key or identity of the signature policy before updating.")
+	c.Flags().StringVar(&r.signaturePolicy, "signature-policy", "",
+		"path to the signature policy.  Implies --verify-signature.  Defaults to ~/.kpt/signature-policy.yaml.")
 	cmdutil.FixDocs("kpt", parent, c)
 	r.Command = c
 	return r
@@ -65,14 +79,25 @@ func NewCommand(parent string) *cobra.Command {
 // Runner contains the run function.
 // TODO, support listing versions
 type Runner struct {
-	strategy string
-	AutoSet  bool
-	Update   update.Command
-	Command  *cobra.Command
+	strategy        string
+	recurse         bool
+	verifySignature bool
+	signaturePolicy string
+	AutoSet         bool
+	Update          update.Command
+	Command         *cobra.Command
 }
 
 func (r *Runner) preRunE(c *cobra.Command, args []string) error {
 	r.Update.Strategy = update.StrategyType(r.strategy)
+	// DIR/... updates every package under DIR, like go packages
+	if args[0] == "..." || strings.HasSuffix(args[0], "/...") {
+		r.recurse = true
+		args[0] = strings.TrimSuffix(strings.TrimSuffix(args[0], "..."), "/")
+		if args[0] == "" {
+			args[0] = "."
+		}
+	}
 	parts := strings.Split(args[0], "@")
 	if len(parts) > 2 {
 		return errors.Errorf("at most 1 version permitted")
@@ -88,11 +113,24 @@ func (r *Runner) preRunE(c *cobra.Command, args []string) error {
 		r.Update.Ref = parts[1]
 	}
 	r.Update.AutoSet = r.AutoSet
+	if err := git.ValidateDepth(r.Update.Depth); err != nil {
+		return err
+	}
+	if r.verifySignature || r.signaturePolicy != "" {
+		if r.Update.SignaturePolicy, err = signature.ReadPolicy(r.signaturePolicy); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 func (r *Runner) runE(c *cobra.Command, args []string) error {
+	if r.recurse {
+		r.Update.Output = c.OutOrStdout()
+		_, err := update.WorkspaceCommand{Command: r.Update, Dir: r.Update.Path}.Run()
+		return err
+	}
 	if len(r.Update.Ref) > 0 {
 		fmt.Fprintf(c.ErrOrStderr(), "updating package %s to %s\n",
 			r.Update.Path, r.Update.Ref)
@@ -214,6 +214,40 @@ func TestCmd_Execute_flagAndArgParsing(t *testing.T) {
 	assert.Equal(t, "foo", r.Update.Path)
 	assert.Equal(t, update.KResourceMerge, r.Update.Strategy)
 	assert.Equal(t, "", r.Update.Ref)
+	assert.Equal(t, 1, r.Update.Depth)
+	assert.False(t, r.Update.Sparse)
+	assert.False(t, r.Update.Submodules)
+
+	r = cmdupdate.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"foo", "--depth", "0", "--sparse", "--submodules"})
+	err = r.Command.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Update.Depth)
+	assert.True(t, r.Update.Sparse)
+	assert.True(t, r.Update.Submodules)
+
+	r = cmdupdate.NewRunner("kpt")
+	r.Command.SilenceErrors = true
+	r.Command.RunE = failRun
+	r.Command.SetArgs([]string{"foo", "--depth", "-2"})
+	err = r.Command.Execute()
+	assert.EqualError(t, err, "--depth must not be negative")
+
+	// verify DIR/... updates the packages of the workspace
+	r = cmdupdate.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"foo/..."})
+	err = r.Command.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", r.Update.Path)
+
+	r = cmdupdate.NewRunner("kpt")
+	r.Command.RunE = NoOpRunE
+	r.Command.SetArgs([]string{"./..."})
+	err = r.Command.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, ".", r.Update.Path)
 }
 
 // TestCmd_fail verifies that that command returns an error when it fails rather than exiting the process
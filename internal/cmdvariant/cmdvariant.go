@@ -0,0 +1,59 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmdvariant contains the variant command
+package cmdvariant
+
+import (
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/internal/docs/generated/pkgdocs"
+	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/variant"
+	"github.com/spf13/cobra"
+)
+
+// NewRunner returns a command runner.
+func NewRunner(parent string) *Runner {
+	r := &Runner{}
+	c := &cobra.Command{
+		Use:     "variant LOCAL_PKG_DIR",
+		Args:    cobra.ExactArgs(1),
+		Short:   pkgdocs.VariantShort,
+		Long:    pkgdocs.VariantShort + "\n" + pkgdocs.VariantLong,
+		Example: pkgdocs.VariantExamples,
+		RunE:    r.runE,
+	}
+	c.Flags().StringSliceVar(&r.Variant.Names, "name", nil,
+		"generate only the named variants.  May be repeated.")
+	r.Command = c
+	cmdutil.FixDocs("kpt", parent, c)
+	return r
+}
+
+func NewCommand(parent string) *cobra.Command {
+	return NewRunner(parent).Command
+}
+
+// Runner contains the run function
+type Runner struct {
+	Command *cobra.Command
+	Variant variant.Command
+}
+
+func (r *Runner) runE(c *cobra.Command, args []string) error {
+	r.Variant.Path = filepath.Clean(args[0])
+	r.Variant.Output = c.OutOrStdout()
+	return r.Variant.Run()
+}
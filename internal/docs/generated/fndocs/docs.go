@@ -42,16 +42,17 @@ var FnExamples = `
 
 var ExportShort = `Auto-generating function pipelines for different workflow orchestrators`
 var ExportLong = `
-  kpt fn export DIR/ [--fn-path FUNCTIONS_DIR/] --workflow ORCHESTRATOR [--output OUTPUT_FILENAME]
+  kpt fn export [ORCHESTRATOR] DIR/ [--fn-path FUNCTIONS_DIR/] [--workflow ORCHESTRATOR] [--output OUTPUT_FILENAME]
   
   DIR:
     Path to a package directory.
   FUNCTIONS_DIR:
     Read functions from the directory instead of the DIR/.
   ORCHESTRATOR:
+    The orchestrator is either the first argument or the value of --workflow.
     Supported orchestrators are:
       - github-actions
-      - cloud-build
+      - cloud-build (or cloudbuild)
       - gitlab-ci
       - jenkins
       - tekton
@@ -65,11 +66,38 @@ var ExportExamples = `
   # write the generated GitHub Actions pipeline to main.yaml.
   kpt fn export DIR/ --output main.yaml --workflow github-actions
 
+  # write the generated Tekton pipeline to stdout.
+  kpt fn export tekton DIR/
+
   # discover functions in FUNCTIONS_DIR and run them against resource in DIR.
   # write the generated Cloud Build pipeline to stdout.
   kpt fn export DIR/ --fn-path FUNCTIONS_DIR/ --workflow cloud-build
 `
 
+var PullShort = `Pull the function images of a package for running functions offline`
+var PullLong = `
+  kpt fn pull [DIR] [flags]
+  
+  DIR:
+    Path to a package directory.  Defaults to the current directory.
+
+Flags:
+
+  --fn-path:
+    Also pull the images of the functions in these directories.
+`
+var PullExamples = `
+  # pull the function images of the package in the current directory
+  kpt fn pull
+
+  # pull the function images of DIR and of the functions in FUNCTIONS_DIR
+  kpt fn pull DIR/ --fn-path FUNCTIONS_DIR/
+
+  # run the functions of DIR without pulling images, failing if any is missing
+  kpt fn pull DIR/
+  kpt fn run DIR/ --offline
+`
+
 var RunShort = `Locally execute one or more functions in containers`
 var RunLong = `
   kpt fn run DIR [flags]
@@ -94,20 +122,49 @@ var RunExamples = `
   # discover functions in DIR and run them against Resource in DIR.
   # functions may be scoped to a subset of Resources -- see ` + "`" + `kpt help fn run` + "`" + `
   kpt fn run DIR/
+
+  # run a function compiled to WebAssembly against the Resources in DIR,
+  # with the WASI runtime found on the PATH
+  kpt fn run DIR/ --wasm-path ./my-fn.wasm -- foo=bar
+
+  # run the function containers declared in DIR with a remote function runner,
+  # without pulling or running their images locally
+  kpt fn run DIR/ --runner-address https://fn-runner.example.com
+
+  # run the functions declared in DIR again whenever its files change, and
+  # preview applying the result
+  kpt fn run DIR/ --watch --live-dry-run
+
+  # write the resources of DIR rendered by its functions to OUT_DIR, without
+  # changing DIR
+  kpt fn run DIR/ --output-dir OUT_DIR/
+
+  # preview the changes the functions declared in DIR would make, as a patch
+  kpt fn run DIR/ --output patch
 `
 
 var SinkShort = `Specify a directory as an output sink package`
 var SinkLong = `
-  kpt fn sink [DIR]
+  kpt fn sink [DIR] [--strict-roundtrip] [--verify-roundtrip]
   
   DIR:
     Path to a package directory.  Defaults to stdout if unspecified.
+  --strict-roundtrip:
+    Keep the original text of the resources of DIR whose content is unchanged.
+  --verify-roundtrip:
+    Fail if the formatting of resources of DIR whose content is unchanged
+    changed.
 `
 var SinkExamples = `
   # run a function using explicit sources and sinks
   kpt fn source DIR/ |
     kpt fn run --image gcr.io/example.com/my-fn |
     kpt fn sink DIR/
+
+  # keep the formatting of the resources of DIR the function didn't change
+  kpt fn source DIR/ |
+    kpt fn run --image gcr.io/example.com/my-fn |
+    kpt fn sink DIR/ --strict-roundtrip
 `
 
 var SourceShort = `Specify a directory as an input source package`
@@ -126,3 +183,37 @@ var SourceExamples = `
     kpt fn run --image gcr.io/example.com/my-fn |
     kpt fn sink DIR/
 `
+
+var TestShort = `Run functions against input fixtures and compare the output to golden files`
+var TestLong = `
+  kpt fn test DIR [flags] [-- args]
+  
+  DIR:
+    Path to the directory of the test cases.
+  
+  args:
+    Arguments of the --image function, as key=value pairs.
+
+Flags:
+
+  --fn-path:
+    Run the functions declared in these directories instead of the functions
+    declared in the input fixtures.
+  
+  --image:
+    Run this container function instead of the functions declared in the
+    input fixtures.
+  
+  --update:
+    Replace the golden files with the output of the functions.
+`
+var TestExamples = `
+  # test the pipeline of the package in my-app/package
+  kpt fn test my-app/tests/ --fn-path my-app/package/
+
+  # regenerate the golden files after changing the pipeline
+  kpt fn test my-app/tests/ --fn-path my-app/package/ --update
+
+  # test a single function, with its arguments
+  kpt fn test tests/ --image gcr.io/example.com/my-fn -- foo=bar
+`
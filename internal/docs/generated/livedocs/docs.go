@@ -26,6 +26,33 @@ Live contains the next-generation versions of apply related commands for
 deploying local configuration packages to a cluster.
 `
 
+var AdoptShort = `Adopt takes ownership of existing cluster resources`
+var AdoptLong = `
+  kpt live adopt [DIRECTORY] [flags]
+
+Args:
+
+  DIRECTORY:
+    One directory that contain k8s manifests. The directory must contain
+    exactly one inventory object. If omitted, the manifests are read from stdin.
+
+Flags:
+
+  --dry-run:
+    Only validate the changes with the server, without adopting any resource.
+  
+  --force:
+    Also adopt resources owned by other inventories. Use it to move resources
+    between packages.
+`
+var AdoptExamples = `
+  # adopt the existing resources of a package
+  kpt live adopt my-dir/
+
+  # print which resources would be adopted
+  kpt live adopt my-dir/ --dry-run
+`
+
 var ApplyShort = `Apply a package to the cluster (create, update, delete)`
 var ApplyLong = `
   kpt live apply DIR [flags]
@@ -59,8 +86,86 @@ Flags:
   
   --output:
     This determines the output format of the command. The default value is
-    events, which will print the events as they happen. The other option is
-    table, which will show the output in a table format.
+    events, which will print the events as they happen. The other options are
+    table, which will show the output in a table format, and junit or sarif,
+    which write a report for CI systems as described in CI Reports, and require
+    --concurrency greater than 1.
+  
+  --conflict-strategy:
+    What to do when applying a resource conflicts with fields owned by another
+    field manager: fail, force or skip. The default is fail, or force if
+    --force-conflicts is set. skip requires --concurrency greater than 1. See
+    Client-Side Apply versus Server-Side Apply.
+  
+  --plan:
+    Apply the plan file written by kpt live plan instead of a package directory.
+    Fails if any planned resource changed in the cluster since the plan was computed.
+  
+  --adopt:
+    Take ownership of resources of the package which already exist in the cluster,
+    but are not owned by any inventory, before applying. See kpt live adopt.
+  
+  --ownership-policy:
+    What to do with resources of the package which already exist in the cluster,
+    but are owned by another inventory according to their
+    config.k8s.io/owning-inventory annotation. The ownership of the resources is
+    only checked if the policy is set. One of:
+      fail:               Fail before applying anything, listing the resources.
+      skip:               Leave the resources untouched, and apply the others.
+      adopt-if-abandoned: Take over the resources whose owning inventory no longer
+                          exists in the cluster, and fail for the others.
+      force-adopt:        Take over all the resources.
+  
+  --status-config:
+    File declaring status readers for custom resources, in addition to the ones
+    declared in the Kptfile. See Status Readers.
+  
+  --error-on:
+    The conditions which make kpt live apply exit with a non-zero code, besides
+    resources which failed to be applied: reconcile-timeout, skipped or
+    prune-failure. The default is reconcile-timeout,prune-failure. Requires
+    --concurrency greater than 1. See Exit Codes.
+  
+  --concurrency:
+    The number of resources applied or pruned at the same time. The default is 1.
+    With a value greater than 1, kpt applies the resources of the package itself
+    using server-side apply, and resources are only applied once the resources they
+    depend on (see Dependency Ordering) were applied. Requires a package directory.
+  
+  --selector:
+    Only apply the resources matching the label selector, without pruning. See
+    Partial Apply.
+  
+  --kind:
+    Only apply the resources of these kinds, without pruning. May be repeated,
+    or a comma separated list. See Partial Apply.
+  
+  --name:
+    Only apply the resources with these names, without pruning. May be repeated,
+    or a comma separated list. See Partial Apply.
+  
+  --target-context:
+    A kubeconfig context of a cluster to apply the package to. May be repeated
+    to apply the package to several clusters in parallel. See Multiple Clusters.
+  
+  --targets-file:
+    A file listing the clusters to apply the package to, by kubeconfig file and
+    context. See Multiple Clusters.
+  
+  --force:
+    Prune resources of protected kinds, such as Namespaces and
+    PersistentVolumeClaims, without confirmation. See Prune.
+  
+  --inventory-type:
+    Overrides the type of the inventory object configured in the Kptfile. Either
+    resourcegroup (the default) or secret. Secret inventories are useful in clusters
+    where ConfigMaps are readable by too many users.
+  
+  --inventory-client:
+    The inventory client storing the inventory. The default, cluster, stores it in
+    the inventory object in the cluster. Programs embedding kpt may register other
+    inventory clients, storing the inventory outside of the cluster. Other clients
+    require --concurrency greater than 1.
 `
 var ApplyExamples = `
   # apply resources and prune
@@ -75,47 +180,134 @@ var ApplyExamples = `
 
 var DestroyShort = `Remove all previously applied resources in a package from the cluster`
 var DestroyLong = `
-  kpt live destroy DIR
+  kpt live destroy [DIR | STDIN] [flags]
 
 Args:
 
-  DIR:
-    Path to a package directory.  The directory must contain exactly
-    one ConfigMap with the grouping object annotation.
+  DIR | STDIN:
+    Path to a package directory if an argument is provided, or reading from
+    stdin if left blank. The manifests must contain exactly one inventory
+    object.
+
+Flags:
+
+  --inventory-type:
+    Overrides the type of the inventory object configured in the Kptfile. Either
+    resourcegroup (the default) or secret.
+  
+  --inventory-client:
+    The inventory client storing the inventory. The default, cluster, stores it in
+    the inventory object in the cluster.
+  
+  --deletion-timeout (duration):
+    How long to wait for every group of deleted resources to disappear before
+    they are reported as stuck. The default is 1m.
+  
+  --poll-period (duration):
+    How often to check if the deleted resources disappeared. The default is 2s.
+  
+  --force-finalizer-removal:
+    Remove the finalizers of the resources whose deletion is stuck, and wait for
+    them to disappear again.
+  
+  --dry-run:
+    Only validate the deletions with the server, without deleting any resource
+    or changing the inventory.
 `
 var DestroyExamples = `
   # remove all resources in a package from the cluster
   kpt live destroy my-dir/
+
+  # remove all resources, removing the finalizers of the ones still present after 5 minutes
+  kpt live destroy --deletion-timeout=5m --force-finalizer-removal my-dir/
 `
 
 var DiffShort = `Diff the local package config against the live cluster resources`
 var DiffLong = `
-  kpt live diff DIR
-  
-  Output is always YAML.
-  
-  KUBECTL_EXTERNAL_DIFF environment variable can be used to select your own diff command. By default, the "diff" command
-  available in your path will be run with "-u" (unicode) and "-N" (treat new files as empty) options.
+  kpt live diff [DIR | -] [flags]
 
 Args:
 
-  DIR:
-    Path to a package directory.  The directory must contain exactly one ConfigMap with the inventory annotation.
+  DIR | -:
+    Path to a package directory.  The directory must contain exactly one ConfigMap
+    with the inventory annotation, or a Kptfile with inventory information. If
+    "-" is passed, the package is read from stdin.
 
-Exit Status:
+Flags:
 
-  The following exit values shall be returned:
+  --output, -o:
+    The output format: text (default) or json. The json output lists, for every
+    resource, the action apply would take, the unified diff and the drifted
+    fields.
+  
+  --color:
+    Colorize the text output: auto (default), always or never. The auto mode
+    colorizes the output only when it is written to a terminal.
+
+Three-way Diff:
+
+  Each resource is compared three ways: its local config, its live state, and
+  the config it was last applied with. The unified diff goes from the live state
+  to the local config as a server-side dry-run apply of it would store it, so
+  fields defaulted by the server or managed by other field managers are not
+  reported as changes. Fields which change on every write (managedFields, status,
+  resourceVersion, uid, generation, creationTimestamp and the last applied
+  configuration) are removed from both sides before comparing.
   
-  0 No differences were found. 1 Differences were found. >1 kpt live or diff failed with an error.
+  Fields which were set by the last apply but were changed in the cluster since
+  are listed as drifted, so changes made outside of kpt can be told apart from
+  changes made to the package.
   
-  Note: KUBECTL_EXTERNAL_DIFF, if used, is expected to follow that convention.
+  Resources stored in the inventory but removed from the package are reported as
+  pruned, or as kept if they have the cli-utils.sigs.k8s.io/on-remove: keep
+  annotation.
 `
 var DiffExamples = `
   # diff the config in "my-dir" against the live cluster resources
   kpt live diff my-dir/
   
-  # specify the local diff program to use
-  export KUBECTL_EXTERNAL_DIFF=meld; kpt live diff my-dir/
+  # print the diff as JSON for other tools
+  kpt live diff my-dir/ -o json
+`
+
+var DriftShort = `Report out-of-band changes to the last applied resources of a package`
+var DriftLong = `
+  kpt live drift [DIR | -] [flags]
+
+Args:
+
+  DIR | -:
+    Path to a package directory.  The directory must contain exactly one ConfigMap
+    with the inventory annotation, or a Kptfile with inventory information. If
+    "-" is passed, the package is read from stdin.
+
+Flags:
+
+  --output, -o:
+    The output format: text (default) or json. The json output holds the number
+    of the compared revision, the number of drifted and in sync resources, and
+    for every drifted resource, whether it was deleted and the path, expected
+    and actual value of every drifted field.
+
+Drift Detection:
+
+  The resources are compared as they were last applied, from the revision
+  recorded by apply, not as they are in the local package. Only the fields set
+  in the applied resources are compared, so fields defaulted or managed by the
+  cluster, and the status, are not drift. Lists are compared item by item if
+  their length did not change.
+  
+  Resources deleted from the cluster are reported as deleted.
+  
+  The command exits with a non-zero code if any resource drifted, so it can be
+  run periodically to alert on out-of-band changes.
+`
+var DriftExamples = `
+  # report the drift of the resources applied from "my-dir"
+  kpt live drift my-dir/
+  
+  # print the drift as JSON, e.g. for alerting from a cron job
+  kpt live drift my-dir/ -o json
 `
 
 var FetchK8sSchemaShort = `Fetch the OpenAPI schema from the cluster`
@@ -153,6 +345,10 @@ Flags:
     namespace for the inventory object. If not provided, kpt will check if all the resources
     in the package belong in the same namespace. If they are, that namespace will be used. If
     they are not, the namespace in the user's context will be chosen.
+  --inventory-type:
+    Type of the inventory object recorded in the Kptfile. Either resourcegroup
+    (the default) or secret. Secret inventories are useful in clusters where
+    ConfigMaps are readable by too many users.
 `
 var InitExamples = `
   # initialize a package
@@ -160,6 +356,57 @@ var InitExamples = `
 
   # initialize a package with a specific name for the group of resources
   kpt live init --namespace=test my-dir/
+
+  # initialize a package storing its inventory in a Secret
+  kpt live init --inventory-type=secret my-dir/
+`
+
+var InstallResourceGroupShort = `Install the ResourceGroup custom resource definition`
+var InstallResourceGroupLong = `
+  kpt live install-resource-group [flags]
+
+Flags:
+
+  --namespace:
+    The namespace to create if it does not exist.
+  
+  --upgrade:
+    Upgrade the definition if it was installed by an older kpt.
+`
+var InstallResourceGroupExamples = `
+  # install the ResourceGroup custom resource definition
+  kpt live install-resource-group
+
+  # install the definition and create the namespace of the inventory
+  kpt live install-resource-group --namespace=my-namespace
+
+  # upgrade a definition installed by an older kpt
+  kpt live install-resource-group --upgrade
+`
+
+var PlanShort = `Plan computes the changes apply would make to the cluster`
+var PlanLong = `
+  kpt live plan [DIRECTORY] [flags]
+
+Args:
+
+  DIRECTORY:
+    One directory that contain k8s manifests. The directory must contain
+    exactly one inventory object. If omitted, the manifests are read from stdin.
+
+Flags:
+
+  --output, -o:
+    File to write the plan to. The plan is a JSON document containing the
+    manifests to apply and the action planned for every resource.
+`
+var PlanExamples = `
+  # print the plan for a package
+  kpt live plan my-dir/
+
+  # write the plan to a file, and apply it later
+  kpt live plan my-dir/ --output plan.json
+  kpt live apply --plan plan.json
 `
 
 var PreviewShort = `Preview prints the changes apply would make to the cluster`
@@ -185,6 +432,37 @@ var PreviewExamples = `
   kpt live preview --destroy my-dir/
 `
 
+var RollbackShort = `Rollback re-applies a previous revision of a package`
+var RollbackLong = `
+  kpt live rollback [DIRECTORY] [flags]
+
+Args:
+
+  DIRECTORY:
+    One directory that contain k8s manifests. The directory must contain
+    exactly one inventory object. If omitted, the manifests are read from stdin.
+    Only the inventory object of the manifests is used.
+
+Flags:
+
+  --to-revision:
+    The number of the revision to roll back to. The default is the revision
+    before the latest one.
+  
+  --concurrency:
+    The number of resources applied or pruned at the same time. The default is 1.
+  
+  --dry-run:
+    Only validate the changes with the server, without changing any resource.
+`
+var RollbackExamples = `
+  # roll back to the revision before the latest one
+  kpt live rollback my-dir/
+
+  # roll back to revision 3
+  kpt live rollback my-dir/ --to-revision=3
+`
+
 var StatusShort = `Status shows the status for the resources in the cluster`
 var StatusLong = `
   kpt live status (DIR | STDIN) [flags]
@@ -214,14 +492,35 @@ Flags:
   --output (string):
     Determines the output format for the status information. Must be one of the following:
       events: The output will be a list of the status events as they become available.
-      table:  The output will be presented as a table that will be updated inline
-              as the status of resources become available.
+      table:  The output will be a table of the status of all resources, printed
+              again every time the status of a resource changes.
+      json:   The output will be a JSON object for every status event, on its own
+              line, with the timestamp, group, kind, namespace, name, status and
+              message of the resource.
+      junit:  A JUnit XML report of the last status of every resource is written
+              once polling stops, with a test case for every resource.
+      sarif:  A SARIF log of the last status of every resource is written once
+              polling stops, with an error for every failure.
+    In the junit and sarif reports, Failed resources are failures, and so are
+    the resources which did not meet the --poll-until condition.
     The default value is ‘events’.
   
+  --watch, -w:
+    Keep reporting status changes until interrupted or the timeout is reached.
+    Same as --poll-until=forever.
+  
+  --status-config (string):
+    File declaring status readers for custom resources, in addition to the ones
+    declared in the Kptfile. See the Status Readers section of kpt live apply.
+  
   --timeout (duration):
     Determines how long the command should run before exiting. This deadline will
     be enforced regardless of the value of the --poll-until flag. The default is
     to wait forever.
+  
+  --inventory-client:
+    The inventory client storing the inventory. The default, cluster, stores it in
+    the inventory object in the cluster.
 `
 var StatusExamples = `
   # Monitor status for a set of resources based on manifests. Wait until all
@@ -233,4 +532,10 @@ var StatusExamples = `
 
   # Check status for a set of resources read from stdin with output in events format
   kpt cfg cat my-app | kpt live status
+
+  # Keep reporting status changes as JSON lines, e.g. to stream them into CI logs
+  kpt live status my-app/ --watch --output=json
+
+  # Wait for the resources to be Current, and write a JUnit report for CI
+  kpt live status my-app/ --poll-until=current --timeout=5m --output=junit > status.xml
 `
@@ -27,6 +27,7 @@ syntax and pattern of inputs and outputs.
 | [cfg]         | examine and modify configuration files                                          | local directory | local directory |
 | [fn]          | generate, transform, validate configuration files using containerized functions | local directory | local directory |
 | [live]        | reconcile the live state with configuration files                               | local directory | remote cluster  |
+| [cache]       | manage the cache of fetched upstream repos                                      | local cache     | local cache     |
 `
 var ReferenceExamples = `
   # get a package
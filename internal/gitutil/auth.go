@@ -0,0 +1,287 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ConfigFileEnv is the name of the environment variable that controls the
+// location of the kpt config file.  Defaults to UserHomeDir/.kpt/config.yaml
+// if unspecified.
+const ConfigFileEnv = "KPT_CONFIG"
+
+// Config is the kpt config file.
+type Config struct {
+	// Credentials configures how git repositories are authenticated,
+	// per host.  Repositories of hosts without credentials use the
+	// ssh-agent and credential helpers of the local git configuration.
+	Credentials []Credential `yaml:"credentials,omitempty"`
+
+	// Proxy configures the proxies of git fetches and registry requests.
+	Proxy Proxy `yaml:"proxy,omitempty"`
+
+	// CAFile is a PEM file of the certificate authorities trusted by git
+	// fetches and registry requests, e.g. of a TLS-intercepting proxy.
+	// Registry requests trust them in addition to the system certificate
+	// authorities, while git trusts only the certificate authorities of
+	// the file.
+	CAFile string `yaml:"caFile,omitempty"`
+}
+
+// Credential configures the authentication to the git repositories of a host.
+type Credential struct {
+	// Host is the host the credential is used for, e.g. github.com.  May
+	// include a port, or start with a wildcard, e.g. *.example.com.
+	Host string `yaml:"host"`
+
+	// Username is the username sent with the token.  Defaults to
+	// x-access-token.
+	Username string `yaml:"username,omitempty"`
+
+	// Token is a personal access token for https repositories.
+	Token string `yaml:"token,omitempty"`
+
+	// TokenEnv is the name of an environment variable containing the
+	// token.  Preferred to Token, so the config file holds no secrets.
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+
+	// CredentialHelper is a git credential helper for https repositories,
+	// e.g. store or "!gcloud auth git-helper --ignore-unknown $@".
+	CredentialHelper string `yaml:"credentialHelper,omitempty"`
+
+	// SSHKeyFile is the private key for ssh repositories.  The key is used
+	// instead of the keys of the ssh config.
+	SSHKeyFile string `yaml:"sshKeyFile,omitempty"`
+
+	// SSHAgent may be set to false to not use the ssh-agent for ssh
+	// repositories.  Defaults to true.
+	SSHAgent *bool `yaml:"sshAgent,omitempty"`
+}
+
+// ReadConfig reads the kpt config file.  Returns an empty Config if the
+// file does not exist.
+func ReadConfig() (Config, error) {
+	var c Config
+	path := os.Getenv(ConfigFileEnv)
+	if path == "" {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return c, nil
+		}
+		path = filepath.Join(dir, ".kpt", "config.yaml")
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, errors.Errorf("unable to read kpt config %s: %v", path, err)
+	}
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return c, errors.Errorf("unable to parse kpt config %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// CredentialFor returns the credential configured for the host of the repo.
+// An exact host match takes precedence over a wildcard.
+func (c Config) CredentialFor(repo string) (Credential, bool) {
+	host := RepoHost(repo)
+	if host == "" {
+		return Credential{}, false
+	}
+	hostname := strings.Split(host, ":")[0]
+	var match *Credential
+	for i := range c.Credentials {
+		cred := &c.Credentials[i]
+		switch {
+		case cred.Host == host || cred.Host == hostname:
+			return *cred, true
+		case strings.HasPrefix(cred.Host, "*.") && match == nil &&
+			strings.HasSuffix(hostname, cred.Host[1:]):
+			match = cred
+		}
+	}
+	if match == nil {
+		return Credential{}, false
+	}
+	return *match, true
+}
+
+// RepoHost returns the host of a repo url, including the port if any.
+// Supports https, ssh and scp-like (user@host:path) urls.  Returns the
+// empty string for local repos.
+func RepoHost(repo string) string {
+	if strings.Contains(repo, "://") {
+		u, err := url.Parse(repo)
+		if err != nil || u.Scheme == "file" {
+			return ""
+		}
+		return u.Host
+	}
+	// scp-like syntax, e.g. git@github.com:org/repo
+	i := strings.Index(repo, ":")
+	if i < 0 || strings.Contains(repo[:i], "/") {
+		return ""
+	}
+	host := repo[:i]
+	if j := strings.LastIndex(host, "@"); j >= 0 {
+		host = host[j+1:]
+	}
+	return host
+}
+
+// AuthEnv returns the environment variables to add to git commands accessing
+// the repo, to authenticate with the credential configured for its host.
+// Configuration is passed through the environment so credentials are never
+// written to the git config of the cloned repos.
+func AuthEnv(repo string) ([]string, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	cred, found := config.CredentialFor(repo)
+	if !found {
+		return nil, nil
+	}
+	return cred.env(repo)
+}
+
+// env returns the environment variables applying the credential to repo.
+func (c Credential) env(repo string) ([]string, error) {
+	var configs [][2]string
+	u, err := url.Parse(repo)
+	insecure := err == nil && u.Scheme == "http"
+	// scope the configs to the https urls of the host, so they are never
+	// sent to other hosts, e.g. of submodules, or over plain http
+	prefix := "https://" + RepoHost(repo) + "/"
+
+	token := c.Token
+	if c.TokenEnv != "" {
+		token = os.Getenv(c.TokenEnv)
+		if token == "" {
+			return nil, errors.Errorf("environment variable %s for the token of %s is not set",
+				c.TokenEnv, c.Host)
+		}
+	}
+	if token != "" {
+		if insecure {
+			return nil, errors.Errorf("refusing to send the token of %s over http for %s; use an https url",
+				c.Host, repo)
+		}
+		username := c.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+		configs = append(configs, [2]string{"http." + prefix + ".extraHeader", "Authorization: Basic " + auth})
+	}
+	if c.CredentialHelper != "" {
+		if insecure {
+			prefix = "http://" + u.Host + "/"
+		}
+		configs = append(configs, [2]string{"credential." + prefix + ".helper", c.CredentialHelper})
+	}
+
+	var env []string
+	if len(configs) > 0 {
+		if err := checkConfigEnv(); err != nil {
+			return nil, errors.Errorf("unable to use the credentials of %s: %v", c.Host, err)
+		}
+		// append to the config already passed through the environment, if any
+		n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+		for i, kv := range configs {
+			env = append(env,
+				fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n+i, kv[0]),
+				fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n+i, kv[1]))
+		}
+		env = append(env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+len(configs)))
+	}
+
+	var ssh []string
+	if c.SSHKeyFile != "" {
+		keyFile := c.SSHKeyFile
+		if strings.HasPrefix(keyFile, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				keyFile = filepath.Join(home, keyFile[2:])
+			}
+		}
+		ssh = append(ssh, "-i", strconv.Quote(keyFile), "-o", "IdentitiesOnly=yes")
+	}
+	if c.SSHAgent != nil && !*c.SSHAgent {
+		ssh = append(ssh, "-o", "IdentityAgent=none")
+	}
+	if len(ssh) > 0 {
+		env = append(env, "GIT_SSH_COMMAND=ssh "+strings.Join(ssh, " "))
+	}
+	return env, nil
+}
+
+// GitVersion returns the output of git version, e.g. "git version 2.31.1".
+// Making it a var so that it can be overridden for testing.
+var GitVersion = func() (string, error) {
+	out, err := exec.Command("git", "version").Output()
+	if err != nil {
+		return "", errors.Errorf("unable to determine the git version: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+var gitVersionRegexp = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+var (
+	configEnvOnce sync.Once
+	configEnvErr  error
+)
+
+// checkConfigEnv returns an error if git doesn't read its config from the
+// GIT_CONFIG_COUNT, GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n> environment
+// variables, which were added in git 2.31.  Older versions silently ignore
+// them, so git would fail to authenticate without saying why.
+func checkConfigEnv() error {
+	configEnvOnce.Do(func() {
+		version, err := GitVersion()
+		if err != nil {
+			configEnvErr = err
+			return
+		}
+		m := gitVersionRegexp.FindStringSubmatch(version)
+		if m == nil {
+			configEnvErr = errors.Errorf("unable to parse the git version %q", version)
+			return
+		}
+		major, _ := strconv.Atoi(m[1])
+		minor, _ := strconv.Atoi(m[2])
+		if major < 2 || major == 2 && minor < 31 {
+			configEnvErr = errors.Errorf(
+				"%s is not supported, credentials require git 2.31 or later", version)
+		}
+	})
+	return configEnvErr
+}
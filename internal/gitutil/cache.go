@@ -0,0 +1,257 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// CacheHomeEnv is the name of the environment variable that controls the
+// directory of the cache of fetched upstream repos.  Defaults to
+// UserHomeDir/.kpt/cache if unspecified.
+const CacheHomeEnv = "KPT_CACHE_HOME"
+
+// cacheRefPrefix is the prefix of the refs keeping the fetched commits of a
+// cached repo reachable, so they are not garbage collected.
+const cacheRefPrefix = "refs/kpt/"
+
+// usedFile is the file in a cached repo whose modification time is the last
+// time the repo was used.
+const usedFile = "kpt-used"
+
+// CacheHome returns the directory of the cache of fetched upstream repos.
+func CacheHome() (string, error) {
+	if dir := os.Getenv(CacheHomeEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Errorf("trouble resolving cache directory: %v", err)
+	}
+	return filepath.Join(dir, ".kpt", "cache"), nil
+}
+
+// CachedRepo returns the bare git repo caching the objects fetched from the
+// upstream repo, creating it if it doesn't exist.  Repos are cached by the
+// sha256 of their uri, so packages sharing an upstream only fetch the changes
+// since the objects they share were fetched.
+func CachedRepo(uri string) (string, error) {
+	home, err := CacheHome()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(uri))
+	dir := filepath.Join(home, hex.EncodeToString(sum[:]))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(home, 0700); err != nil {
+			return "", errors.Errorf("trouble creating cache directory: %v", err)
+		}
+		// initialize the repo in a temporary directory so an interrupted
+		// initialization does not leave a broken repo in the cache
+		tmp, err := ioutil.TempDir(home, "tmp-")
+		if err != nil {
+			return "", errors.Wrap(err)
+		}
+		defer os.RemoveAll(tmp)
+		g := &GitRunner{Dir: tmp}
+		if err := g.Run("init", "--bare"); err != nil {
+			return "", errors.Errorf("trouble initializing cache for %s: %v: %s", uri, err, g.Stderr.String())
+		}
+		if err := g.Run("remote", "add", "origin", uri); err != nil {
+			return "", errors.Errorf("trouble initializing cache for %s: %v: %s", uri, err, g.Stderr.String())
+		}
+		if err := os.Rename(tmp, dir); err != nil && !exists(dir) {
+			return "", errors.Wrap(err)
+		}
+	}
+	// record the use of the repo for pruning
+	if err := ioutil.WriteFile(filepath.Join(dir, usedFile), []byte(uri+"\n"), 0600); err != nil {
+		return "", errors.Wrap(err)
+	}
+	return dir, nil
+}
+
+// KeepFetched keeps the commit fetched for ref in the cached repo reachable,
+// so it is not garbage collected.
+func KeepFetched(dir, ref, commit string) error {
+	g := &GitRunner{Dir: dir}
+	name := cacheRefPrefix + strings.Trim(ref, "/")
+	if err := g.Run("check-ref-format", name); err != nil {
+		name = cacheRefPrefix + commit
+	}
+	if err := g.Run("update-ref", name, commit); err != nil {
+		return errors.Errorf("trouble updating cache ref %s: %v: %s", name, err, g.Stderr.String())
+	}
+	return nil
+}
+
+// UseCachedObjects makes the objects of the cached repo available to the
+// git repo at dir, so they do not need to be fetched or copied.
+func UseCachedObjects(dir, cacheDir string) error {
+	info := filepath.Join(dir, ".git", "objects", "info")
+	if err := os.MkdirAll(info, 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	objects, err := filepath.Abs(filepath.Join(cacheDir, "objects"))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(info, "alternates"), []byte(objects+"\n"), 0600); err != nil {
+		return errors.Wrap(err)
+	}
+	// a shallow cache requires the repo to know where history ends
+	shallow, err := ioutil.ReadFile(filepath.Join(cacheDir, "shallow"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(dir, ".git", "shallow"), shallow, 0600))
+}
+
+// CacheEntry is a repo in the cache.
+type CacheEntry struct {
+	// Dir is the directory of the cached repo
+	Dir string
+
+	// URI is the uri of the upstream repo
+	URI string
+
+	// Used is the last time the repo was used
+	Used time.Time
+
+	// Size is the disk usage of the cached repo in bytes
+	Size int64
+}
+
+// ListCache returns the repos in the cache, least recently used first.
+func ListCache() ([]CacheEntry, error) {
+	home, err := CacheHome()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(home)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var entries []CacheEntry
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), "tmp-") {
+			continue
+		}
+		e := CacheEntry{Dir: filepath.Join(home, info.Name()), Used: info.ModTime()}
+		if used, err := os.Stat(filepath.Join(e.Dir, usedFile)); err == nil {
+			e.Used = used.ModTime()
+			if b, err := ioutil.ReadFile(filepath.Join(e.Dir, usedFile)); err == nil {
+				e.URI = strings.TrimSpace(string(b))
+			}
+		}
+		err := filepath.Walk(e.Dir, func(_ string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				e.Size += info.Size()
+			}
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Used.Before(entries[j].Used) })
+	return entries, nil
+}
+
+// PruneCache removes the repos in the cache which were not used since the
+// time, and returns them.
+func PruneCache(since time.Time) ([]CacheEntry, error) {
+	entries, err := ListCache()
+	if err != nil {
+		return nil, err
+	}
+	var pruned []CacheEntry
+	for _, e := range entries {
+		if !e.Used.Before(since) {
+			continue
+		}
+		if err := os.RemoveAll(e.Dir); err != nil {
+			return pruned, errors.Wrap(err)
+		}
+		pruned = append(pruned, e)
+	}
+	return pruned, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// CountCommits returns the number of commits from the commit from to the
+// commit to of the upstream repo which change files in the directory.  The
+// history of the repo is fetched into its cache to count them.
+func CountCommits(uri, directory, from, to string) (int, error) {
+	dir, err := CachedRepo(uri)
+	if err != nil {
+		return 0, err
+	}
+	env, err := AuthEnv(uri)
+	if err != nil {
+		return 0, err
+	}
+	g := &GitRunner{Dir: dir, Env: env}
+	args := []string{"fetch", "origin"}
+	if exists(filepath.Join(dir, "shallow")) {
+		args = append(args, "--unshallow")
+	}
+	if err := g.Run(args...); err != nil {
+		return 0, errors.Errorf("trouble fetching history of %s: %v: %s", uri, err, g.Stderr.String())
+	}
+	for _, commit := range []string{from, to} {
+		if err := g.Run("cat-file", "-e", commit+"^{commit}"); err == nil {
+			continue
+		}
+		// commits of tags which are not on a branch are fetched by sha
+		if err := g.Run("fetch", "origin", commit); err != nil {
+			return 0, errors.Errorf("trouble fetching %s of %s: %v: %s", commit, uri, err, g.Stderr.String())
+		}
+		if err := KeepFetched(dir, commit, commit); err != nil {
+			return 0, err
+		}
+	}
+
+	args = []string{"rev-list", "--count", from + ".." + to}
+	if d := strings.Trim(directory, "/"); d != "" {
+		args = append(args, "--", d)
+	}
+	if err := g.Run(args...); err != nil {
+		return 0, errors.Errorf("trouble counting commits of %s: %v: %s", uri, err, g.Stderr.String())
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(g.Stdout.String()))
+	return count, errors.Wrap(err)
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCacheHome(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-cache-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	original, set := os.LookupEnv(CacheHomeEnv)
+	os.Setenv(CacheHomeEnv, dir)
+	return dir, func() {
+		os.RemoveAll(dir)
+		if set {
+			os.Setenv(CacheHomeEnv, original)
+		} else {
+			os.Unsetenv(CacheHomeEnv)
+		}
+	}
+}
+
+func TestCachedRepo(t *testing.T) {
+	home, clean := setupCacheHome(t)
+	defer clean()
+
+	dir, err := CachedRepo("https://github.com/org/repo")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, home, filepath.Dir(dir))
+	assert.DirExists(t, filepath.Join(dir, "objects"))
+
+	// the same uri shares the cached repo
+	again, err := CachedRepo("https://github.com/org/repo")
+	assert.NoError(t, err)
+	assert.Equal(t, dir, again)
+
+	other, err := CachedRepo("https://github.com/org/other")
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir, other)
+
+	g := &GitRunner{Dir: dir}
+	assert.NoError(t, g.Run("config", "remote.origin.url"))
+	assert.Equal(t, "https://github.com/org/repo\n", g.Stdout.String())
+}
+
+func TestPruneCache(t *testing.T) {
+	_, clean := setupCacheHome(t)
+	defer clean()
+
+	old, err := CachedRepo("https://github.com/org/old")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(old, usedFile), week, week))
+	recent, err := CachedRepo("https://github.com/org/recent")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	entries, err := ListCache()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "https://github.com/org/old", entries[0].URI)
+		assert.Equal(t, "https://github.com/org/recent", entries[1].URI)
+		assert.True(t, entries[0].Size > 0)
+	}
+
+	pruned, err := PruneCache(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, pruned, 1) {
+		assert.Equal(t, old, pruned[0].Dir)
+	}
+	assert.NoDirExists(t, old)
+	assert.DirExists(t, recent)
+
+	pruned, err = PruneCache(time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, pruned, 1)
+	assert.NoDirExists(t, recent)
+}
+
+func TestCountCommits(t *testing.T) {
+	_, clean := setupCacheHome(t)
+	defer clean()
+
+	repo, err := ioutil.TempDir("", "kpt-count-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(repo)
+	g := &GitRunner{Dir: repo}
+	assert.NoError(t, g.Run("init"))
+	var commits []string
+	for _, file := range []string{"pkg/a", "other/b", "pkg/c"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(repo, filepath.Dir(file)), 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(repo, file), []byte(file), 0600))
+		assert.NoError(t, g.Run("add", "."))
+		assert.NoError(t, g.Run("-c", "user.name=kpt", "-c", "user.email=kpt@example.com",
+			"commit", "-m", file))
+		assert.NoError(t, g.Run("rev-parse", "HEAD"))
+		commits = append(commits, strings.TrimSpace(g.Stdout.String()))
+	}
+
+	count, err := CountCommits(repo, "", commits[0], commits[2])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// only the commits changing the directory are counted
+	count, err = CountCommits(repo, "/pkg/", commits[0], commits[2])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = CountCommits(repo, "pkg", commits[2], commits[2])
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
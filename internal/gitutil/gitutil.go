@@ -62,7 +62,12 @@ func branchExists(repo, branch string) (bool, error) {
 	}
 	stdOut := bytes.Buffer{}
 	stdErr := bytes.Buffer{}
+	env, err := AuthEnv(repo)
+	if err != nil {
+		return false, err
+	}
 	cmd := exec.Command(gitProgram, "ls-remote", repo, branch)
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stderr = &stdErr
 	cmd.Stdout = &stdOut
 	err = cmd.Run()
@@ -79,6 +84,54 @@ func branchExists(repo, branch string) (bool, error) {
 	return false, nil
 }
 
+// ListTags returns the names of the tags of the remote repo.
+func ListTags(repo string) ([]string, error) {
+	env, err := AuthEnv(repo)
+	if err != nil {
+		return nil, err
+	}
+	g := &GitRunner{Env: env}
+	if err := g.Run("ls-remote", "--tags", "--refs", repo); err != nil {
+		return nil, errors.Errorf("failed to list tags of %s: %v: %s",
+			repo, err, strings.TrimSpace(g.Stderr.String()))
+	}
+	var tags []string
+	for _, line := range strings.Split(g.Stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/tags/") {
+			tags = append(tags, strings.TrimPrefix(fields[1], "refs/tags/"))
+		}
+	}
+	return tags, nil
+}
+
+// ResolveRef returns the commit the branch, tag or ref of the remote repo
+// points to, or an empty string if the repo has no such ref.
+func ResolveRef(repo, ref string) (string, error) {
+	env, err := AuthEnv(repo)
+	if err != nil {
+		return "", err
+	}
+	g := &GitRunner{Env: env}
+	if err := g.Run("ls-remote", repo, ref, "refs/heads/"+ref, "refs/tags/"+ref, "refs/tags/"+ref+"^{}"); err != nil {
+		return "", errors.Errorf("failed to resolve %s of %s: %v: %s",
+			ref, repo, err, strings.TrimSpace(g.Stderr.String()))
+	}
+	commits := map[string]string{}
+	for _, line := range strings.Split(g.Stdout.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			commits[fields[1]] = fields[0]
+		}
+	}
+	// prefer the commit an annotated tag points to over the tag object
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref, ref} {
+		if commit, found := commits[name]; found {
+			return commit, nil
+		}
+	}
+	return "", nil
+}
+
 // NewUpstreamGitRunner returns a new GitRunner for an upstream package.
 //
 // The upstream package repo will be fetched to a local cache directory under $HOME/.kpt
@@ -121,6 +174,10 @@ type GitRunner struct {
 
 	// Verbose prints verbose command information
 	Verbose bool
+
+	// Env contains additional environment variables for the git commands,
+	// e.g. the credentials of the repo from AuthEnv
+	Env []string
 }
 
 // Run runs a git command.
@@ -133,7 +190,7 @@ func (g *GitRunner) Run(args ...string) error {
 
 	cmd := exec.Command(p, args...)
 	cmd.Dir = g.Dir
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), g.Env...)
 
 	g.Stdout = &bytes.Buffer{}
 	g.Stderr = &bytes.Buffer{}
@@ -186,8 +243,13 @@ func (g *GitRunner) cacheRepo(uri, dir string,
 			"failed to clone repo: trouble creating cache directory: %v", err)
 	}
 
+	env, err := AuthEnv(uri)
+	if err != nil {
+		return "", err
+	}
+
 	// create the repo directory if it doesn't exist yet
-	gitRunner := GitRunner{Dir: kptCacheDir}
+	gitRunner := GitRunner{Dir: kptCacheDir, Env: env}
 	uriSha := g.getRepoDir(uri)
 	repoCacheDir := filepath.Join(kptCacheDir, uriSha)
 	if _, err := os.Stat(repoCacheDir); os.IsNotExist(err) {
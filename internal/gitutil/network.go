@@ -0,0 +1,116 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// CAFileEnv is the name of the environment variable that sets the file of
+// the certificate authorities trusted by fetches.  Takes precedence over the
+// caFile of the kpt config file.
+const CAFileEnv = "KPT_CA_FILE"
+
+// Proxy configures the proxies of fetches.  The HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables take precedence over the values of the
+// config file.
+type Proxy struct {
+	// HTTP is the proxy of http requests, e.g. http://proxy.example.com:3128
+	HTTP string `yaml:"http,omitempty"`
+
+	// HTTPS is the proxy of https requests
+	HTTPS string `yaml:"https,omitempty"`
+
+	// NoProxy is a comma separated list of hosts, domains and ip ranges
+	// which are reached directly, e.g. localhost,.internal.example.com
+	NoProxy string `yaml:"noProxy,omitempty"`
+}
+
+// ConfigureNetwork applies the proxies and certificate authorities of the kpt
+// config file to the http requests of kpt, and to the environment of the
+// programs it runs such as git and helm.  Must be called before any http
+// request is made, as the proxy environment is only read once.
+func ConfigureNetwork() error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	return config.configureNetwork(http.DefaultTransport.(*http.Transport))
+}
+
+// configureNetwork sets the proxy environment variables which are not set
+// from the config, and adds the certificate authorities to the transport.
+func (c Config) configureNetwork(t *http.Transport) error {
+	for _, p := range []struct {
+		names []string
+		value string
+	}{
+		{[]string{"HTTP_PROXY", "http_proxy"}, c.Proxy.HTTP},
+		{[]string{"HTTPS_PROXY", "https_proxy"}, c.Proxy.HTTPS},
+		{[]string{"NO_PROXY", "no_proxy"}, c.Proxy.NoProxy},
+	} {
+		if p.value == "" || os.Getenv(p.names[0]) != "" || os.Getenv(p.names[1]) != "" {
+			continue
+		}
+		// git only reads the lower case http_proxy
+		for _, name := range p.names {
+			if err := os.Setenv(name, p.value); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+	}
+
+	caFile := os.Getenv(CAFileEnv)
+	if caFile == "" {
+		caFile = c.CAFile
+	}
+	if caFile == "" {
+		return nil
+	}
+	if strings.HasPrefix(caFile, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			caFile = filepath.Join(home, caFile[2:])
+		}
+	}
+	b, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return errors.Errorf("unable to read CA file %s: %v", caFile, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return errors.Errorf("no PEM certificates found in CA file %s", caFile)
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.RootCAs = pool
+
+	// git verifies certificates with the file instead of its CA bundle
+	if os.Getenv("GIT_SSL_CAINFO") == "" {
+		return errors.Wrap(os.Setenv("GIT_SSL_CAINFO", caFile))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitutil
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/testenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_configureNetwork_proxy(t *testing.T) {
+	testenv.SetenvAll(t, map[string]string{
+		"HTTP_PROXY": "", "http_proxy": "",
+		"HTTPS_PROXY": "http://env.example.com:3128", "https_proxy": "",
+		"NO_PROXY": "", "no_proxy": "",
+		CAFileEnv: "",
+	})
+
+	c := Config{Proxy: Proxy{
+		HTTP:    "http://proxy.example.com:3128",
+		HTTPS:   "http://proxy.example.com:3129",
+		NoProxy: "localhost,.internal.example.com",
+	}}
+	assert.NoError(t, c.configureNetwork(&http.Transport{}))
+
+	assert.Equal(t, "http://proxy.example.com:3128", os.Getenv("HTTP_PROXY"))
+	assert.Equal(t, "http://proxy.example.com:3128", os.Getenv("http_proxy"))
+	assert.Equal(t, "localhost,.internal.example.com", os.Getenv("no_proxy"))
+	// the environment takes precedence
+	assert.Equal(t, "http://env.example.com:3128", os.Getenv("HTTPS_PROXY"))
+	assert.Equal(t, "", os.Getenv("https_proxy"))
+}
+
+func TestConfig_configureNetwork_caFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kpt-ca-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	testenv.SetenvAll(t, map[string]string{CAFileEnv: "", "GIT_SSL_CAINFO": ""})
+
+	// the server is not trusted without the CA file
+	_, err = (&http.Client{Transport: &http.Transport{}}).Get(server.URL)
+	assert.Error(t, err)
+
+	transport := &http.Transport{}
+	assert.NoError(t, Config{CAFile: caFile}.configureNetwork(transport))
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+	assert.Equal(t, caFile, os.Getenv("GIT_SSL_CAINFO"))
+
+	// the environment takes precedence
+	testenv.SetenvAll(t, map[string]string{CAFileEnv: filepath.Join(dir, "missing.pem")})
+	err = Config{CAFile: caFile}.configureNetwork(&http.Transport{})
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+	testenv.SetenvAll(t, map[string]string{CAFileEnv: ""})
+	err = Config{CAFile: caFile}.configureNetwork(&http.Transport{})
+	assert.EqualError(t, err, "no PEM certificates found in CA file "+caFile)
+}
@@ -213,11 +213,22 @@ func (p *Pkg) WithResourceAndSetters(resourceName string, setterRefs []SetterRef
 	return p
 }
 
+// WithFile configures the package to include a file with the provided
+// content.  The name is slash separated, and may be in a sub directory.
 func (p *Pkg) WithFile(name, content string) *Pkg {
 	p.files[name] = content
 	return p
 }
 
+// WithFiles configures the package to include the provided files, keyed by
+// their slash separated names.
+func (p *Pkg) WithFiles(files map[string]string) *Pkg {
+	for name, content := range files {
+		p.WithFile(name, content)
+	}
+	return p
+}
+
 // WithSubPackages adds the provided packages as subpackages to the current
 // package
 func (p *Pkg) WithSubPackages(ps ...*Pkg) *Pkg {
@@ -279,7 +290,7 @@ func buildRecursive(path string, pkg *Pkg) error {
 	}
 
 	for name, content := range pkg.files {
-		filePath := filepath.Join(pkgPath, name)
+		filePath := filepath.Join(pkgPath, filepath.FromSlash(name))
 		_, err := os.Stat(filePath)
 		if err != nil && !os.IsNotExist(err) {
 			return err
@@ -287,6 +298,9 @@ func buildRecursive(path string, pkg *Pkg) error {
 		if !os.IsNotExist(err) {
 			return fmt.Errorf("file %s already exists", name)
 		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			return err
+		}
 		err = ioutil.WriteFile(filePath, []byte(content), 0600)
 		if err != nil {
 			return err
@@ -346,6 +360,8 @@ func buildKptfile(pkg *Pkg) string {
 	return result
 }
 
+// ExpandPkg builds the package in a temporary directory, which is removed
+// at the end of the test, and returns the path of the package.
 func ExpandPkg(t *testing.T, pkg *Pkg) string {
 	if pkg.Name == "" {
 		pkg.Name = "base"
@@ -354,6 +370,7 @@ func ExpandPkg(t *testing.T, pkg *Pkg) string {
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
 	err = pkg.Build(dir)
 	if !assert.NoError(t, err) {
 		t.FailNow()
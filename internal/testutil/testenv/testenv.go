@@ -0,0 +1,46 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testenv sets environment variables for the duration of a test.
+// It has no dependencies on kpt packages, so the tests of any package may
+// use it.
+package testenv
+
+import (
+	"os"
+	"testing"
+)
+
+// Setenv sets the environment variable key to value, and restores its
+// previous value, or unsets it, at the end of the test.
+func Setenv(t *testing.T, key, value string) {
+	previous, found := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("unable to set %s: %v", key, err)
+	}
+	t.Cleanup(func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// SetenvAll sets each environment variable of env like Setenv.
+func SetenvAll(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		Setenv(t, key, value)
+	}
+}
@@ -68,7 +68,7 @@ var AssertNoError = assertnow.NilError
 
 var KptfileSet = func() sets.String {
 	s := sets.String{}
-	s.Insert(kptfile.KptFileName)
+	s.Insert(kptfile.KptFileName, kptfile.LockFileName)
 	return s
 }()
 
@@ -470,10 +470,22 @@ func SetupDefaultRepoAndWorkspace(t *testing.T, dataset string) (*TestGitRepo, *
 	err = g.CheckoutBranch("master", false)
 	assert.NoError(t, err)
 
+	// cache the fetched repos in a tmp directory rather than the user's
+	cacheHome, err := ioutil.TempDir("", "kpt-test-cache-")
+	assert.NoError(t, err)
+	originalCacheHome, cacheHomeSet := os.LookupEnv(gitutil.CacheHomeEnv)
+	os.Setenv(gitutil.CacheHomeEnv, cacheHome)
+
 	return g, w, func() {
 		// ignore cleanup failures
 		_ = g.RemoveAll()
 		_ = w.RemoveAll()
+		_ = os.RemoveAll(cacheHome)
+		if cacheHomeSet {
+			os.Setenv(gitutil.CacheHomeEnv, originalCacheHome)
+		} else {
+			os.Unsetenv(gitutil.CacheHomeEnv)
+		}
 		_ = os.Chdir(cwd)
 	}
 }
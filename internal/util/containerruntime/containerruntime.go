@@ -0,0 +1,98 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package containerruntime selects the OCI runtime function containers are
+// run with.
+package containerruntime
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Env is the name of the environment variable that sets the container
+// runtime.  The --container-runtime flag takes precedence over it.
+const Env = "KPT_CONTAINER_RUNTIME"
+
+// Docker is the default container runtime.
+const Docker = "docker"
+
+// Runtimes are the supported container runtimes, in order of preference
+// when none is set.
+var Runtimes = []string{Docker, "podman", "nerdctl"}
+
+// Select returns the container runtime of name, or of Env if name is empty.
+// If neither is set, it returns the first of Runtimes found on the PATH, or
+// Docker if none is found.
+func Select(name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(Env)
+	}
+	if name == "" {
+		for _, r := range Runtimes {
+			if _, err := exec.LookPath(r); err == nil {
+				return r, nil
+			}
+		}
+		return Docker, nil
+	}
+	for _, r := range Runtimes {
+		if r == name {
+			return r, nil
+		}
+	}
+	return "", errors.Errorf("unsupported container runtime %q, must be one of: %s",
+		name, strings.Join(Runtimes, ", "))
+}
+
+// Use makes function containers run with the container runtime selected by
+// name.  Functions are run with the docker CLI, which podman and nerdctl are
+// compatible with, so other runtimes are put first on the PATH of kpt as
+// docker, with a link in ~/.kpt/runtimes.
+func Use(name string) error {
+	runtime, err := Select(name)
+	if err != nil || runtime == Docker {
+		return err
+	}
+	binary, err := exec.LookPath(runtime)
+	if err != nil {
+		return errors.Errorf("container runtime %s not found on the PATH: %v", runtime, err)
+	}
+	if binary, err = filepath.Abs(binary); err != nil {
+		return errors.Wrap(err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	dir := filepath.Join(home, ".kpt", "runtimes", runtime)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	link := filepath.Join(dir, Docker)
+	if target, err := os.Readlink(link); err != nil || target != binary {
+		// the runtime may have moved since the link was created
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err)
+		}
+		if err := os.Symlink(binary, link); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	return errors.Wrap(os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH")))
+}
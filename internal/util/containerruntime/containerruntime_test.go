@@ -0,0 +1,90 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerruntime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/testenv"
+	. "github.com/GoogleContainerTools/kpt/internal/util/containerruntime"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupBin returns a directory with fake runtime binaries.
+func setupBin(t *testing.T, names ...string) string {
+	dir, err := ioutil.TempDir("", "kpt-runtime-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, name := range names {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\necho "+name+"\n"), 0700)
+		assert.NoError(t, err)
+	}
+	return dir
+}
+
+func TestSelect(t *testing.T) {
+	bin := setupBin(t, "nerdctl")
+	defer os.RemoveAll(bin)
+	testenv.SetenvAll(t, map[string]string{"PATH": bin, Env: ""})
+
+	runtime, err := Select("podman")
+	assert.NoError(t, err)
+	assert.Equal(t, "podman", runtime)
+
+	// the first runtime found on the PATH is detected
+	runtime, err = Select("")
+	assert.NoError(t, err)
+	assert.Equal(t, "nerdctl", runtime)
+
+	os.Setenv(Env, "podman")
+	runtime, err = Select("")
+	assert.NoError(t, err)
+	assert.Equal(t, "podman", runtime)
+
+	_, err = Select("rkt")
+	assert.EqualError(t, err, `unsupported container runtime "rkt", must be one of: docker, podman, nerdctl`)
+}
+
+func TestUse(t *testing.T) {
+	bin := setupBin(t, "podman")
+	defer os.RemoveAll(bin)
+	home, err := ioutil.TempDir("", "kpt-home-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(home)
+	testenv.SetenvAll(t, map[string]string{"PATH": bin, "HOME": home, Env: ""})
+
+	if !assert.NoError(t, Use("podman")) {
+		t.FailNow()
+	}
+	docker, err := exec.LookPath("docker")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".kpt", "runtimes", "podman", "docker"), docker)
+	out, err := exec.Command("docker").Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "podman\n", string(out))
+
+	// using the runtime again keeps the link
+	assert.NoError(t, Use("podman"))
+
+	err = Use("nerdctl")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "container runtime nerdctl not found on the PATH")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
@@ -65,6 +66,7 @@ func (c Command) Run() error {
 	}
 
 	c.printPkgs(c.GetStdOut(), pkgs)
+	c.printMetadata(c.GetStdOut(), pkgs)
 	return nil
 }
 
@@ -92,6 +94,28 @@ func (c Command) printPkgs(w io.Writer, pkgs []pkgInfo) {
 		if c.PrintBasePath {
 			p = filepath.Base(p)
 		}
+		if pkg.Upstream.Type == kptfile.OCIOrigin {
+			table.Append([]string{
+				pkg.Name,
+				p,
+				pkg.Upstream.OCI.Image,
+				"",
+				pkg.Upstream.OCI.Ref,
+				shortSHA(strings.TrimPrefix(pkg.Upstream.OCI.Digest, "sha256:")),
+			})
+			continue
+		}
+		if pkg.Upstream.Type == kptfile.HelmOrigin {
+			table.Append([]string{
+				pkg.Name,
+				p,
+				pkg.Upstream.Helm.Repo,
+				pkg.Upstream.Helm.Chart,
+				pkg.Upstream.Helm.Version,
+				"",
+			})
+			continue
+		}
 		table.Append([]string{
 			pkg.Name,
 			p,
@@ -104,6 +128,37 @@ func (c Command) printPkgs(w io.Writer, pkgs []pkgInfo) {
 	table.Render()
 }
 
+// printMetadata prints the metadata of the packages which have any, after
+// the table of the packages.
+func (c Command) printMetadata(w io.Writer, pkgs []pkgInfo) {
+	for _, pkg := range pkgs {
+		meta := pkg.PackageMeta
+		var maintainers []string
+		for _, m := range meta.Maintainers {
+			maintainers = append(maintainers, m.String())
+		}
+		fields := [][2]string{
+			{"Description", meta.ShortDescription},
+			{"Version", meta.Version},
+			{"License", meta.License},
+			{"Maintainers", strings.Join(maintainers, ", ")},
+			{"Keywords", strings.Join(meta.Keywords, ", ")},
+			{"Documentation", strings.Join(meta.Documentation, ", ")},
+		}
+		printed := false
+		for _, f := range fields {
+			if f[1] == "" {
+				continue
+			}
+			if !printed {
+				fmt.Fprintf(w, "\n%s:\n", pkg.Name)
+				printed = true
+			}
+			fmt.Fprintf(w, "  %-15s%s\n", f[0]+":", f[1])
+		}
+	}
+}
+
 // shortSHA returns short form (first 7 letters) of the commit SHA.
 func shortSHA(sha string) string {
 	if len(sha) > 7 {
@@ -26,6 +26,7 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/git"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"sigs.k8s.io/kustomize/kyaml/copyutil"
@@ -61,6 +62,28 @@ func SupportedDiffTypesLabel() string {
 	return strings.Join(labels, ", ")
 }
 
+// OutputFormat is the format the changes are shown in.
+type OutputFormat string
+
+const (
+	// OutputTool shows the changes using the diff tool
+	OutputTool OutputFormat = "tool"
+	// OutputUnified writes the changes as a unified diff without a diff tool
+	OutputUnified OutputFormat = "unified"
+	// OutputJSON writes the changes as json without a diff tool
+	OutputJSON OutputFormat = "json"
+)
+
+var SupportedOutputFormats = []OutputFormat{OutputTool, OutputUnified, OutputJSON}
+
+func SupportedOutputFormatsLabel() string {
+	var labels []string
+	for _, f := range SupportedOutputFormats {
+		labels = append(labels, string(f))
+	}
+	return strings.Join(labels, ", ")
+}
+
 // Command shows changes in local package relative to upstream source pkg, changes in
 // upstream source package between original and target version etc.
 type Command struct {
@@ -79,6 +102,10 @@ type Command struct {
 	// DiffToolOpts refers to the commandline options to for the diffing tool.
 	DiffToolOpts string
 
+	// OutputFormat specifies how the changes are shown.  Defaults to
+	// OutputTool, which runs DiffTool.
+	OutputFormat OutputFormat
+
 	// When Debug is true, command will run with verbose logging and will not
 	// cleanup the staged packages to assist with debugging.
 	Debug bool
@@ -184,6 +211,20 @@ func (c *Command) Validate() error {
 			c.DiffType, SupportedDiffTypesLabel())
 	}
 
+	switch c.OutputFormat {
+	case "", OutputTool:
+	case OutputUnified, OutputJSON:
+		if c.DiffType == DiffType3Way {
+			return errors.Errorf("diff-type '%s' is not supported with output '%s'",
+				c.DiffType, c.OutputFormat)
+		}
+		// no diff tool is needed
+		return nil
+	default:
+		return errors.Errorf("invalid output '%s'. Supported outputs are: %s",
+			c.OutputFormat, SupportedOutputFormatsLabel())
+	}
+
 	path, err := exec.LookPath(c.DiffTool)
 	if err != nil {
 		return errors.Errorf("diff-tool '%s' not found in the PATH.", c.DiffTool)
@@ -200,6 +241,13 @@ func (c *Command) DefaultValues() {
 	if c.PkgGetter == nil {
 		c.PkgGetter = defaultPkgGetter{}
 	}
+	if c.PkgDiffer == nil && (c.OutputFormat == OutputUnified || c.OutputFormat == OutputJSON) {
+		c.PkgDiffer = &structuredPkgDiffer{
+			DiffType: c.DiffType,
+			Format:   c.OutputFormat,
+			Output:   c.Output,
+		}
+	}
 	if c.PkgDiffer == nil {
 		c.PkgDiffer = &defaultPkgDiffer{
 			DiffType:     c.DiffType,
@@ -237,13 +285,13 @@ type defaultPkgDiffer struct {
 
 func (d *defaultPkgDiffer) Diff(pkgs ...string) error {
 	for _, pkg := range pkgs {
-		if err := d.prepareForDiff(pkg); err != nil {
+		if err := prepareForDiff(pkg); err != nil {
 			return err
 		}
 	}
 	var args []string
 	if d.DiffToolOpts != "" {
-		args = strings.Split(d.DiffToolOpts, " ")
+		args = strings.Fields(d.DiffToolOpts)
 		args = append(args, pkgs...)
 	} else {
 		args = pkgs
@@ -269,7 +317,7 @@ func (d *defaultPkgDiffer) Diff(pkgs ...string) error {
 
 // prepareForDiff removes metadata such as .git and Kptfile from a staged package
 // to exclude them from diffing.
-func (d *defaultPkgDiffer) prepareForDiff(dir string) error {
+func prepareForDiff(dir string) error {
 	excludePaths := []string{".git", kptfile.KptFileName}
 	for _, path := range excludePaths {
 		path = filepath.Join(dir, path)
@@ -297,6 +345,7 @@ func (pg defaultPkgGetter) GetPkg(repo, path, ref string) (string, error) {
 		Git:         kptfile.Git{Repo: repo, Directory: path, Ref: ref},
 		Destination: dir,
 		Clean:       true,
+		Depth:       git.DefaultDepth,
 	}
 	err = cmdGet.Run()
 	return dir, err
@@ -0,0 +1,317 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// contextLines is the number of unchanged lines around the changes of a hunk.
+const contextLines = 3
+
+// FileStatus is how a file was changed.
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "added"
+	FileDeleted  FileStatus = "deleted"
+	FileModified FileStatus = "modified"
+)
+
+// FileDiff contains the changes of a file between two packages.
+type FileDiff struct {
+	// Path is the slash separated path of the file relative to the packages
+	Path string `json:"path"`
+
+	// Status is how the file was changed
+	Status FileStatus `json:"status"`
+
+	// Diff is the unified diff of the file contents
+	Diff string `json:"diff"`
+}
+
+// PackageDiff contains the changes between two packages.
+type PackageDiff struct {
+	// DiffType is the type of changes shown
+	DiffType DiffType `json:"diffType"`
+
+	// Files contains the changed files, sorted by path
+	Files []FileDiff `json:"files"`
+}
+
+// structuredPkgDiffer writes the changes between two packages as a unified
+// diff or as json, so they can be read by scripts.
+type structuredPkgDiffer struct {
+	// DiffType specifies the type of changes to show
+	DiffType DiffType
+
+	// Format is OutputUnified or OutputJSON
+	Format OutputFormat
+
+	// Output is where the changes are written
+	Output io.Writer
+}
+
+func (d *structuredPkgDiffer) Diff(pkgs ...string) error {
+	if len(pkgs) != 2 {
+		return errors.Errorf("--output %s does not support diff-type %s", d.Format, d.DiffType)
+	}
+	for _, pkg := range pkgs {
+		if err := prepareForDiff(pkg); err != nil {
+			return err
+		}
+	}
+	files, err := DiffPackages(pkgs[0], pkgs[1])
+	if err != nil {
+		return err
+	}
+
+	if d.Format == OutputJSON {
+		if files == nil {
+			files = []FileDiff{}
+		}
+		e := json.NewEncoder(d.Output)
+		e.SetIndent("", "  ")
+		return e.Encode(PackageDiff{DiffType: d.DiffType, Files: files})
+	}
+	for _, f := range files {
+		if _, err := io.WriteString(d.Output, f.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffPackages returns the changes to the files of package a in package b.
+func DiffPackages(a, b string) ([]FileDiff, error) {
+	aFiles, err := readFiles(a)
+	if err != nil {
+		return nil, err
+	}
+	bFiles, err := readFiles(b)
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]bool{}
+	for p := range aFiles {
+		paths[p] = true
+	}
+	for p := range bFiles {
+		paths[p] = true
+	}
+	var sorted []string
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var diffs []FileDiff
+	for _, p := range sorted {
+		aContent, inA := aFiles[p]
+		bContent, inB := bFiles[p]
+		status := FileModified
+		switch {
+		case !inA:
+			status = FileAdded
+		case !inB:
+			status = FileDeleted
+		case aContent == bContent:
+			continue
+		}
+		diffs = append(diffs, FileDiff{Path: p, Status: status, Diff: unifiedDiff(p, aContent, bContent, inA, inB)})
+	}
+	return diffs, nil
+}
+
+// readFiles returns the contents of the regular files of dir by slash
+// separated relative path.
+func readFiles(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	return files, errors.Wrap(err)
+}
+
+// unifiedDiff returns the unified diff of the file at path between a and b.
+func unifiedDiff(path, a, b string, inA, inB bool) string {
+	from, to := "a/"+path, "b/"+path
+	if !inA {
+		from = "/dev/null"
+	}
+	if !inB {
+		to = "/dev/null"
+	}
+	aLines, bLines := splitLines(a), splitLines(b)
+	edits := diffLines(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+	for start := 0; start < len(edits); {
+		// find the next change
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		// extend the hunk until contextLines*2 unchanged lines separate changes
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				end = i + 1
+			} else if i-end >= contextLines*2 {
+				break
+			}
+		}
+		hunkStart := max(start-contextLines, 0)
+		hunkEnd := min(end+contextLines, len(edits))
+
+		aStart, bStart, aCount, bCount := 0, 0, 0, 0
+		for _, e := range edits[:hunkStart] {
+			if e.op != '+' {
+				aStart++
+			}
+			if e.op != '-' {
+				bStart++
+			}
+		}
+		for _, e := range edits[hunkStart:hunkEnd] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, e := range edits[hunkStart:hunkEnd] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = hunkEnd
+	}
+	return sb.String()
+}
+
+// hunkRange formats the range of a hunk, with 1 based line numbers.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits s into lines, keeping the line endings.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edit is a line of a diff: ' ' if unchanged, '-' if deleted or '+' if added.
+type edit struct {
+	op   byte
+	line string
+}
+
+// diffLines returns the edits turning a into b, using the longest common
+// subsequence of their lines.
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{op: ' ', line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{op: '-', line: a[i]})
+			i++
+		default:
+			edits = append(edits, edit{op: '+', line: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{op: '-', line: a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{op: '+', line: b[j]})
+	}
+	return edits
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	. "github.com/GoogleContainerTools/kpt/internal/util/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPackages(t *testing.T) {
+	a := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"same.yaml":    "a: 1\n",
+		"deleted.yaml": "b: 1\n",
+		"sub/modified.yaml": `l1: 1
+l2: 2
+l3: 3
+l4: 4
+l5: 5
+l6: 6
+l7: 7
+l8: 8
+l9: 9
+l10: 10
+l11: 11
+`,
+	}))
+	b := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"same.yaml":  "a: 1\n",
+		"added.yaml": "c: 1",
+		"sub/modified.yaml": `l1: 1
+l2: two
+l3: 3
+l4: 4
+l5: 5
+l6: 6
+l7: 7
+l8: 8
+l9: 9
+l10: 10
+l11: 11
+l12: 12
+`,
+	}))
+
+	diffs, err := DiffPackages(a, b)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []FileDiff{
+		{
+			Path:   "added.yaml",
+			Status: FileAdded,
+			Diff: `--- /dev/null
++++ b/added.yaml
+@@ -0,0 +1 @@
++c: 1
+\ No newline at end of file
+`,
+		},
+		{
+			Path:   "deleted.yaml",
+			Status: FileDeleted,
+			Diff: `--- a/deleted.yaml
++++ /dev/null
+@@ -1 +0,0 @@
+-b: 1
+`,
+		},
+		{
+			Path:   "sub/modified.yaml",
+			Status: FileModified,
+			Diff: `--- a/sub/modified.yaml
++++ b/sub/modified.yaml
+@@ -1,5 +1,5 @@
+ l1: 1
+-l2: 2
++l2: two
+ l3: 3
+ l4: 4
+ l5: 5
+@@ -9,3 +9,4 @@
+ l9: 9
+ l10: 10
+ l11: 11
++l12: 12
+`,
+		},
+	}, diffs)
+}
+
+func TestDiffPackages_noChanges(t *testing.T) {
+	a := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{"a.yaml": "a: 1\n"}))
+	b := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{"a.yaml": "a: 1\n"}))
+
+	diffs, err := DiffPackages(a, b)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestCommand_Validate_output(t *testing.T) {
+	c := &Command{DiffType: DiffTypeLocal, DiffTool: "not-a-diff-tool", OutputFormat: OutputJSON}
+	assert.NoError(t, c.Validate())
+
+	c = &Command{DiffType: DiffType3Way, OutputFormat: OutputUnified}
+	assert.EqualError(t, c.Validate(), "diff-type '3way' is not supported with output 'unified'")
+
+	c = &Command{DiffType: DiffTypeLocal, OutputFormat: "yaml"}
+	assert.EqualError(t, c.Validate(), "invalid output 'yaml'. Supported outputs are: tool, unified, json")
+}
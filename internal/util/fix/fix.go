@@ -17,9 +17,29 @@ package fix
 import (
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	goyaml "gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/kyaml/errors"
 	"sigs.k8s.io/kustomize/kyaml/fix/fixsetters"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// functionAnnotation declares the function of a function config
+	functionAnnotation = "config.kubernetes.io/function"
+
+	// legacyFunctionAnnotation is the deprecated key of functionAnnotation
+	legacyFunctionAnnotation = "config.k8s.io/function"
+
+	// legacyContainerAnnotation is the deprecated annotation declaring the
+	// image of the container function of a function config
+	legacyContainerAnnotation = "config.kubernetes.io/container"
 )
 
 // Command fixes the local kpt package and upgrades it to use latest feature
@@ -39,7 +59,49 @@ type Command struct {
 func (c Command) Run() error {
 	printFunc := printFunc(c.StdOut, c.DryRun)
 	printFunc("processing resource configs to identify possible fixes... ")
-	return c.fixV1Setters()
+	if err := c.fixKptfileVersion(); err != nil {
+		return err
+	}
+	if err := c.fixV1Setters(); err != nil {
+		return err
+	}
+	return c.fixFunctionAnnotations()
+}
+
+// fixKptfileVersion migrates Kptfiles of older or missing apiVersions and
+// kinds to the current apiVersion.  Files of other kinds, e.g. used as the
+// OpenAPI file of setters, are left unchanged.
+func (c Command) fixKptfileVersion() error {
+	printFunc := printFunc(c.StdOut, c.DryRun)
+	path := filepath.Join(c.PkgPath, kptfile.KptFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	n, err := yaml.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	apiVersion, kind := fieldValue(n, "apiVersion"), fieldValue(n, "kind")
+	if kind != "" && !strings.EqualFold(kind, kptfile.KptFileName) {
+		return nil
+	}
+	if apiVersion != "" && !strings.HasPrefix(apiVersion, kptfile.KptFileGroup+"/") {
+		return nil
+	}
+	if apiVersion == kptfile.KptFileAPIVersion && kind == kptfile.KptFileName {
+		return nil
+	}
+	err = n.PipeE(yaml.SetField("apiVersion", yaml.NewScalarRNode(kptfile.KptFileAPIVersion)),
+		yaml.SetField("kind", yaml.NewScalarRNode(kptfile.KptFileName)))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	printFunc("migrated %s from apiVersion %q kind %q to %s %s", kptfile.KptFileName,
+		apiVersion, kind, kptfile.KptFileAPIVersion, kptfile.KptFileName)
+	if c.DryRun {
+		return nil
+	}
+	return errors.Wrap(yaml.WriteFile(n, path))
 }
 
 func (c Command) fixV1Setters() error {
@@ -79,6 +141,69 @@ func (c Command) fixV1Setters() error {
 	return err
 }
 
+// fieldValue returns the value of the field of n, or "" if n has no such
+// field.
+func fieldValue(n *yaml.RNode, field string) string {
+	if f := n.Field(field); f != nil {
+		return yaml.GetValue(f.Value)
+	}
+	return ""
+}
+
+// fixFunctionAnnotations replaces the deprecated annotations declaring the
+// functions of function configs with the config.kubernetes.io/function
+// annotation.
+func (c Command) fixFunctionAnnotations() error {
+	printFunc := printFunc(c.StdOut, c.DryRun)
+	rw := &kio.LocalPackageReadWriter{PackagePath: c.PkgPath}
+	nodes, err := rw.Read()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	fixed := 0
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		legacy := legacyFunctionAnnotation
+		fn := meta.Annotations[legacyFunctionAnnotation]
+		if image := meta.Annotations[legacyContainerAnnotation]; image != "" && fn == "" {
+			legacy = legacyContainerAnnotation
+			fn = fmt.Sprintf("container:\n  image: %s\n", image)
+		}
+		if fn == "" {
+			continue
+		}
+		if meta.Annotations[functionAnnotation] == "" {
+			value := yaml.NewScalarRNode(fn)
+			if strings.Contains(fn, "\n") {
+				value.YNode().Style = goyaml.LiteralStyle
+			}
+			err := n.PipeE(yaml.LookupCreate(yaml.MappingNode, "metadata", "annotations"),
+				yaml.SetField(functionAnnotation, value))
+			if err != nil {
+				return errors.Wrap(err)
+			}
+		}
+		err = n.PipeE(yaml.ClearAnnotation(legacyFunctionAnnotation), yaml.ClearAnnotation(legacyContainerAnnotation))
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		file, _, err := kioutil.GetFileAnnotations(n)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		printFunc("replaced deprecated annotation %s of %s %s in %s with %s",
+			legacy, meta.Kind, meta.Name, file, functionAnnotation)
+		fixed++
+	}
+	if fixed == 0 || c.DryRun {
+		return nil
+	}
+	return errors.Wrap(rw.Write(nodes))
+}
+
 type printerFunc func(format string, a ...interface{})
 
 func printFunc(w io.Writer, dryRun bool) printerFunc {
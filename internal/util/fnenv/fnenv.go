@@ -0,0 +1,200 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fnenv passes environment variables holding secrets to functions,
+// redacting their values from the output of the functions.
+package fnenv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Redacted replaces the values of secrets.
+const Redacted = "[REDACTED]"
+
+// ReadFile reads the environment variables of the file, with a KEY=value
+// variable per line.  Empty lines and lines starting with # are ignored.
+func ReadFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer f.Close()
+	env := map[string]string{}
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		kv := strings.SplitN(text, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			// the line isn't printed, as it may hold a secret
+			return nil, errors.Errorf("invalid line %d of %s, must be KEY=value", line, path)
+		}
+		env[kv[0]] = kv[1]
+	}
+	return env, errors.Wrap(s.Err())
+}
+
+// Setenv sets the environment variables of the process, which the container
+// runtime passes to the function containers by name, until the returned
+// function is called.  The function restores the previous values of the
+// variables, and unsets the ones which weren't set.
+func Setenv(env map[string]string) (func(), error) {
+	previous := map[string]*string{}
+	restore := func() {
+		for k, v := range previous {
+			if v == nil {
+				_ = os.Unsetenv(k)
+			} else {
+				_ = os.Setenv(k, *v)
+			}
+		}
+	}
+	for k, v := range env {
+		if old, found := os.LookupEnv(k); found {
+			previous[k] = &old
+		} else {
+			previous[k] = nil
+		}
+		if err := os.Setenv(k, v); err != nil {
+			restore()
+			return nil, errors.Wrap(err)
+		}
+	}
+	return restore, nil
+}
+
+// Redactor replaces the values of secrets in text.
+type Redactor struct {
+	replacer *strings.Replacer
+}
+
+// NewRedactor returns a Redactor of the values.  Longer values are
+// redacted first, so values containing others are redacted entirely.
+func NewRedactor(values []string) *Redactor {
+	values = append([]string(nil), values...)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	var oldnew []string
+	for _, v := range values {
+		if v != "" {
+			oldnew = append(oldnew, v, Redacted)
+		}
+	}
+	return &Redactor{replacer: strings.NewReplacer(oldnew...)}
+}
+
+// Redact returns the text with the values redacted.
+func (r *Redactor) Redact(text string) string {
+	return r.replacer.Replace(text)
+}
+
+// Writer returns a writer redacting the values from the lines written to w.
+// Lines are written once complete, so values written in several writes are
+// redacted too.  The writer must be closed to write the last line.
+func (r *Redactor) Writer(w io.Writer) io.WriteCloser {
+	return &redactingWriter{redactor: r, w: w}
+}
+
+type redactingWriter struct {
+	redactor *Redactor
+	w        io.Writer
+	mu       sync.Mutex
+	buf      bytes.Buffer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	if i := bytes.LastIndexByte(w.buf.Bytes(), '\n'); i >= 0 {
+		lines := string(w.buf.Next(i + 1))
+		if _, err := io.WriteString(w.w, w.redactor.Redact(lines)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *redactingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := io.WriteString(w.w, w.redactor.Redact(w.buf.String()))
+	w.buf.Reset()
+	return err
+}
+
+// RedactDir redacts the values from the files of the directory, e.g. the
+// results written by the functions.
+func (r *Redactor) RedactDir(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if redacted := r.Redact(string(b)); redacted != string(b) {
+			if err := ioutil.WriteFile(path, []byte(redacted), info.Mode()); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+// RedirectStderr redacts the values from what is written to the stderr of
+// the process, e.g. by the functions, until the returned function is
+// called.
+func (r *Redactor) RedirectStderr() (func(), error) {
+	read, write, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = write
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := r.Writer(stderr)
+		_, _ = io.Copy(w, read)
+		_ = w.Close()
+	}()
+	return func() {
+		os.Stderr = stderr
+		_ = write.Close()
+		<-done
+		_ = read.Close()
+	}, nil
+}
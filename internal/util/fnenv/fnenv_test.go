@@ -0,0 +1,124 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnenv_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFile(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fnenv")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, ".env")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("# registry credentials\nUSER=admin\n\nTOKEN=a=b\n"), 0600))
+	env, err := fnenv.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"USER": "admin", "TOKEN": "a=b"}, env)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("USER=admin\nhunter2\n"), 0600))
+	_, err = fnenv.ReadFile(path)
+	assert.EqualError(t, err, "invalid line 2 of "+path+", must be KEY=value")
+}
+
+func TestRedactor(t *testing.T) {
+	r := fnenv.NewRedactor([]string{"hunter2", "hunter2-admin", ""})
+	assert.Equal(t, "login with [REDACTED] and [REDACTED]", r.Redact("login with hunter2-admin and hunter2"))
+
+	b := &bytes.Buffer{}
+	w := r.Writer(b)
+	fmt.Fprint(w, "token: hun")
+	assert.Empty(t, b.String())
+	fmt.Fprint(w, "ter2\nuser: hunter")
+	assert.Equal(t, "token: [REDACTED]\n", b.String())
+	fmt.Fprint(w, "2")
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "token: [REDACTED]\nuser: [REDACTED]", b.String())
+}
+
+func TestRedactor_RedirectStderr(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fnenv")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	f, err := os.Create(filepath.Join(d, "stderr"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	restore, err := fnenv.NewRedactor([]string{"hunter2"}).RedirectStderr()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	fmt.Fprintln(os.Stderr, "token: hunter2")
+	restore()
+	assert.Equal(t, f, os.Stderr)
+	assert.NoError(t, f.Close())
+
+	b, err := ioutil.ReadFile(filepath.Join(d, "stderr"))
+	assert.NoError(t, err)
+	assert.Equal(t, "token: [REDACTED]\n", string(b))
+}
+
+func TestSetenv(t *testing.T) {
+	defer os.Setenv("KPT_FNENV_SET", os.Getenv("KPT_FNENV_SET"))
+	assert.NoError(t, os.Setenv("KPT_FNENV_SET", "old"))
+	assert.NoError(t, os.Unsetenv("KPT_FNENV_UNSET"))
+
+	restore, err := fnenv.Setenv(map[string]string{"KPT_FNENV_SET": "hunter2", "KPT_FNENV_UNSET": "hunter3"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "hunter2", os.Getenv("KPT_FNENV_SET"))
+	assert.Equal(t, "hunter3", os.Getenv("KPT_FNENV_UNSET"))
+
+	restore()
+	assert.Equal(t, "old", os.Getenv("KPT_FNENV_SET"))
+	_, found := os.LookupEnv("KPT_FNENV_UNSET")
+	assert.False(t, found)
+}
+
+func TestRedactor_RedactDir(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fnenv")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "results.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("message: token hunter2 is invalid\n"), 0600))
+
+	r := fnenv.NewRedactor([]string{"hunter2"})
+	assert.NoError(t, r.RedactDir(d))
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "message: token [REDACTED] is invalid\n", string(b))
+
+	assert.NoError(t, r.RedactDir(filepath.Join(d, "missing")))
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fnimage pulls the images of the functions of packages into the
+// local cache of the container runtime, so the functions can be run offline.
+package fnimage
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+// Runtime is the CLI of the container runtime.  Other runtimes than docker
+// are run as docker, see containerruntime.Use.
+var Runtime = "docker"
+
+// dockerHub is the registry of images without a registry host.
+const dockerHub = "registry-1.docker.io"
+
+// Images returns the images of the container functions declared in the
+// packages at the dirs and their subpackages, and by the dependencies of
+// their Kptfiles, sorted and without duplicates.
+func Images(dirs ...string) ([]string, error) {
+	images := map[string]bool{}
+	for _, dir := range dirs {
+		nodes, err := (&kio.LocalPackageReader{PackagePath: dir, IncludeSubpackages: true}).Read()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		for _, n := range nodes {
+			if spec := runtimeutil.GetFunctionSpec(n); spec != nil && spec.Container.Image != "" {
+				images[spec.Container.Image] = true
+			}
+		}
+		kf, err := kptfileutil.ReadFile(dir)
+		if err != nil {
+			// function directories don't need a Kptfile
+			continue
+		}
+		for _, d := range kf.Dependencies {
+			for _, f := range d.Functions {
+				if f.Image != "" && (f.Runtime == "" || f.Runtime == kptfile.ContainerRuntime) && f.Exec == nil {
+					images[f.Image] = true
+				}
+			}
+		}
+	}
+	var sorted []string
+	for image := range images {
+		sorted = append(sorted, image)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// Pull resolves the image to the digest of its tag with the registry, and
+// pulls it by digest with the container runtime.  Images referenced by tag
+// are tagged in the local cache with the tag, so functions are run with the
+// pinned image without pulling it again.  Returns the digest.
+func Pull(image string) (string, error) {
+	digest, err := Digest(image)
+	if err != nil {
+		return "", err
+	}
+	pinned := Name(image) + "@" + digest
+	if err := run("pull", pinned); err != nil {
+		return "", err
+	}
+	if !strings.Contains(image, "@") {
+		if err := run("tag", pinned, image); err != nil {
+			return "", err
+		}
+	}
+	return digest, nil
+}
+
+// Cached returns true if the image is in the local cache of the container
+// runtime.
+func Cached(image string) bool {
+	return exec.Command(Runtime, "image", "inspect", image).Run() == nil
+}
+
+// ID returns the id of the image in the local cache of the container
+// runtime, which changes whenever another digest of the image is pulled.
+func ID(image string) (string, error) {
+	out, err := exec.Command(Runtime, "image", "inspect", image, "--format", "{{.Id}}").Output()
+	if err != nil {
+		return "", errors.Errorf("function image %s not in the local cache", image)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// EnsureCached pulls the image unless it's in the local cache of the
+// container runtime.
+func EnsureCached(image string) error {
+	if Cached(image) {
+		return nil
+	}
+	return run("pull", image)
+}
+
+// Label returns the value of the label of the image, or an empty string if
+// the image doesn't have the label.  Images not in the local cache of the
+// container runtime are pulled.
+func Label(image, key string) (string, error) {
+	if err := EnsureCached(image); err != nil {
+		return "", err
+	}
+	out, err := exec.Command(Runtime, "image", "inspect", image, "--format", "{{json .Config.Labels}}").Output()
+	if err != nil {
+		return "", errors.Errorf("failed to inspect function image %s: %v", image, err)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(out, &labels); err != nil {
+		return "", errors.Errorf("invalid labels of function image %s: %v", image, err)
+	}
+	return labels[key], nil
+}
+
+// CheckCached returns an error listing the images which are not in the local
+// cache of the container runtime.
+func CheckCached(images []string) error {
+	var missing []string
+	for _, image := range images {
+		if !Cached(image) {
+			missing = append(missing, image)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("function images not in the local cache, pull them with kpt fn pull: %s",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Name returns the image without its tag or digest.
+func Name(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// Reference returns the registry reference of the image.  Images without a
+// registry host are Docker Hub images.
+func Reference(image string) (oci.Reference, error) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		if len(parts) == 1 {
+			image = "library/" + image
+		}
+		image = dockerHub + "/" + image
+	}
+	return oci.ParseReference(image)
+}
+
+func run(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(Runtime, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Errorf("%s %s failed: %v: %s", Runtime, strings.Join(args, " "), err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
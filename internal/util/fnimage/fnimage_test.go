@@ -0,0 +1,171 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnimage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+
+// setupRuntime sets a fake container runtime, which records its arguments and
+// has only the cached images in its cache.
+func setupRuntime(t *testing.T, cached ...string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-fnimage-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	log := filepath.Join(dir, "log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n" +
+		"if [ \"$1\" = image ]; then\n  case \"$3\" in\n"
+	for _, image := range cached {
+		script += "  " + image + ") [ \"$4\" = --format ] && case \"$5\" in\n" +
+			"    *Labels*) echo '{\"maintainer\":\"kpt\"}';;\n" +
+			"    *) echo sha256:id-$3;;\n" +
+			"  esac; exit 0;;\n"
+	}
+	script += "  esac\n  exit 1\nfi\n"
+	runtime := filepath.Join(dir, "docker")
+	if !assert.NoError(t, ioutil.WriteFile(runtime, []byte(script), 0700)) {
+		t.FailNow()
+	}
+	old := Runtime
+	Runtime = runtime
+	return log, func() {
+		Runtime = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-fnimage-pkg-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: pkg
+dependencies:
+- name: dep
+  functions:
+  - image: gcr.io/example/label:v1
+  - runtime: starlark
+    source: print("hello")
+`,
+		"fn.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/example/scale:v1
+`,
+		filepath.Join("sub", "fn.yaml"): `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/example/label:v1
+`,
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	images, err := Images(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gcr.io/example/label:v1", "gcr.io/example/scale:v1"}, images)
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "gcr.io/example/fn", Name("gcr.io/example/fn:v1"))
+	assert.Equal(t, "gcr.io/example/fn", Name("gcr.io/example/fn@"+digest))
+	assert.Equal(t, "localhost:5000/fn", Name("localhost:5000/fn"))
+}
+
+func TestReference(t *testing.T) {
+	for image, expected := range map[string]oci.Reference{
+		"gcr.io/example/fn:v1": {Registry: "gcr.io", Repository: "example/fn", Ref: "v1"},
+		"localhost:5000/fn":    {Registry: "localhost:5000", Repository: "fn", Ref: "latest"},
+		"nginx:1.19":           {Registry: "registry-1.docker.io", Repository: "library/nginx", Ref: "1.19"},
+		"example/fn":           {Registry: "registry-1.docker.io", Repository: "example/fn", Ref: "latest"},
+	} {
+		ref, err := Reference(image)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ref, image)
+	}
+}
+
+func TestPull(t *testing.T) {
+	log, clean := setupRuntime(t)
+	defer clean()
+
+	// images pinned by digest are not resolved with the registry
+	d, err := Pull("gcr.io/example/fn@" + digest)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, d)
+	b, err := ioutil.ReadFile(log)
+	assert.NoError(t, err)
+	assert.Equal(t, "pull gcr.io/example/fn@"+digest+"\n", string(b))
+}
+
+func TestCheckCached(t *testing.T) {
+	_, clean := setupRuntime(t, "gcr.io/example/fn:v1")
+	defer clean()
+
+	assert.True(t, Cached("gcr.io/example/fn:v1"))
+	assert.NoError(t, CheckCached([]string{"gcr.io/example/fn:v1"}))
+	err := CheckCached([]string{"gcr.io/example/fn:v1", "gcr.io/example/fn:v2", "gcr.io/example/other:v1"})
+	assert.EqualError(t, err, "function images not in the local cache, pull them with kpt fn pull: "+
+		strings.Join([]string{"gcr.io/example/fn:v2", "gcr.io/example/other:v1"}, ", "))
+}
+
+func TestID(t *testing.T) {
+	_, clean := setupRuntime(t, "gcr.io/example/fn:v1")
+	defer clean()
+
+	id, err := ID("gcr.io/example/fn:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:id-gcr.io/example/fn:v1", id)
+	_, err = ID("gcr.io/example/fn:v2")
+	assert.EqualError(t, err, "function image gcr.io/example/fn:v2 not in the local cache")
+}
+
+func TestLabel(t *testing.T) {
+	_, clean := setupRuntime(t, "gcr.io/example/fn:v1")
+	defer clean()
+
+	label, err := Label("gcr.io/example/fn:v1", "maintainer")
+	assert.NoError(t, err)
+	assert.Equal(t, "kpt", label)
+	label, err = Label("gcr.io/example/fn:v1", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, "", label)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnimage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Digest resolves the image to the digest of its tag with the registry.
+// Images referenced by digest are not resolved.  Making it a var so that it
+// can be overridden for local testing.
+var Digest = func(image string) (string, error) {
+	ref, err := Reference(image)
+	if err != nil {
+		return "", err
+	}
+	digest, err := oci.Digest(ref)
+	if err != nil {
+		return "", errors.Errorf("unable to resolve the digest of %s: %v", image, err)
+	}
+	return digest, nil
+}
+
+// Pin resolves the images of the container functions of the package at dir,
+// and the extra images, e.g. of the functions of an environment, to their
+// digests, and compares them with the digests recorded in its Kptfile by the
+// previous run.  Changed digests are reported to w, or fail with
+// failOnChange.  If the signature policy requires signed function images,
+// the images are verified by digest.  Returns the digests by image.
+func Pin(dir string, failOnChange bool, w io.Writer, extra ...string) (map[string]string, error) {
+	images, err := Images(dir)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, extra...)
+	policy, err := signature.DefaultPolicy()
+	if err != nil {
+		return nil, err
+	}
+	recorded := map[string]string{}
+	if kf, err := kptfileutil.ReadFile(dir); err == nil {
+		for _, d := range kf.Functions.ImageDigests {
+			recorded[d.Image] = d.Digest
+		}
+	}
+
+	digests := map[string]string{}
+	for _, image := range images {
+		digest, err := Digest(image)
+		if err != nil {
+			return nil, err
+		}
+		if old, found := recorded[image]; found && old != digest {
+			msg := fmt.Sprintf("function image %s changed from %s to %s since the package was last run",
+				image, old, digest)
+			if failOnChange {
+				return nil, errors.Errorf("%s", msg)
+			}
+			fmt.Fprintf(w, "warning: %s\n", msg)
+		}
+		if policy != nil && policy.FunctionImages {
+			if err := policy.VerifyImage(Name(image) + "@" + digest); err != nil {
+				return nil, err
+			}
+		}
+		digests[image] = digest
+	}
+	return digests, nil
+}
+
+// Record records the digests of the images in the Kptfile of the package at
+// dir, replacing the digests recorded by the previous run.  Packages without
+// a Kptfile are not recorded.
+func Record(dir string, digests map[string]string) error {
+	if _, err := os.Stat(filepath.Join(dir, kptfile.KptFileName)); os.IsNotExist(err) {
+		return nil
+	}
+	kf, err := kptfileutil.ReadFile(dir)
+	if err != nil {
+		return err
+	}
+	var records []kptfile.ImageDigest
+	for image, digest := range digests {
+		records = append(records, kptfile.ImageDigest{Image: image, Digest: digest})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Image < records[j].Image })
+	if reflect.DeepEqual(records, kf.Functions.ImageDigests) {
+		// don't rewrite the Kptfile if the digests are unchanged
+		return nil
+	}
+	kf.Functions.ImageDigests = records
+	return kptfileutil.WriteFile(dir, kf)
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnimage_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const otherDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+func setupPinned(t *testing.T, digests map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-fnimage-pin-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Kptfile"), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: pkg
+`), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fn.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/example/fn:v1
+`), 0600))
+
+	oldDigest, oldPolicy := Digest, os.Getenv(signature.PolicyFileEnv)
+	Digest = func(image string) (string, error) { return digests[image], nil }
+	os.Setenv(signature.PolicyFileEnv, filepath.Join(dir, "missing-policy.yaml"))
+	return dir, func() {
+		Digest = oldDigest
+		os.Setenv(signature.PolicyFileEnv, oldPolicy)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPin(t *testing.T) {
+	digests := map[string]string{"gcr.io/example/fn:v1": digest}
+	dir, clean := setupPinned(t, digests)
+	defer clean()
+
+	out := &bytes.Buffer{}
+	pinned, err := Pin(dir, true, out)
+	assert.NoError(t, err)
+	assert.Equal(t, digests, pinned)
+	assert.NoError(t, Record(dir, pinned))
+	kf, err := kptfileutil.ReadFile(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []kptfile.ImageDigest{{Image: "gcr.io/example/fn:v1", Digest: digest}},
+		kf.Functions.ImageDigests)
+
+	// the tag is moved to another image
+	digests["gcr.io/example/fn:v1"] = otherDigest
+	_, err = Pin(dir, true, out)
+	assert.EqualError(t, err, "function image gcr.io/example/fn:v1 changed from "+digest+" to "+
+		otherDigest+" since the package was last run")
+	assert.Empty(t, out.String())
+
+	pinned, err = Pin(dir, false, out)
+	assert.NoError(t, err)
+	assert.Equal(t, otherDigest, pinned["gcr.io/example/fn:v1"])
+	assert.True(t, strings.HasPrefix(out.String(), "warning: function image gcr.io/example/fn:v1 changed"))
+
+	// the extra images, e.g. of an environment, are pinned too
+	digests["gcr.io/example/env-fn:v1"] = digest
+	pinned, err = Pin(dir, false, out, "gcr.io/example/env-fn:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, digests, pinned)
+}
+
+func TestRecord_noKptfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-fnimage-pin-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, Record(dir, map[string]string{"gcr.io/example/fn:v1": digest}))
+	_, err = os.Stat(filepath.Join(dir, "Kptfile"))
+	assert.True(t, os.IsNotExist(err))
+}
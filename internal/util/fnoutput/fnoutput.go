@@ -0,0 +1,190 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fnoutput writes the package rendered by functions somewhere other
+// than in place: to another directory, or to stdout as resources or a patch.
+package fnoutput
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+// The formats the rendered package can be written to stdout in.
+const (
+	// Stdout writes the resources as a ResourceList
+	Stdout = "stdout"
+
+	// Unwrap writes the resources as a stream of YAML documents
+	Unwrap = "unwrap"
+
+	// Patch writes the changes to the package as a patch which can be
+	// applied with git apply
+	Patch = "patch"
+)
+
+// Formats are the formats the rendered package can be written to stdout in.
+var Formats = []string{Stdout, Unwrap, Patch}
+
+// Command renders a copy of a package, and writes the rendered package to
+// Output in Format, or to Dir.  The package itself is not changed.
+type Command struct {
+	// Source is the directory of the package
+	Source string
+
+	// Render renders the copy of the package at dir in place
+	Render func(dir string) error
+
+	// Format is the format the rendered package is written to Output in
+	Format string
+
+	// Dir is the directory the resources of the rendered package are
+	// written to instead, replacing its content
+	Dir string
+
+	Output io.Writer
+}
+
+// Run renders the package, and writes the rendered package.
+func (c Command) Run() error {
+	if (c.Format == "") == (c.Dir == "") {
+		return errors.Errorf("exactly one of an output format or an output directory must be set")
+	}
+	if c.Dir != "" {
+		if err := checkDir(c.Source, c.Dir); err != nil {
+			return err
+		}
+	} else if !isFormat(c.Format) {
+		return errors.Errorf("unsupported output %q, must be one of: %s",
+			c.Format, strings.Join(Formats, ", "))
+	}
+
+	tmp, err := ioutil.TempDir("", "kpt-fn-output-")
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer os.RemoveAll(tmp)
+	// the rendered copy is named b, for the paths of patches to be relative
+	// to the package
+	rendered := filepath.Join(tmp, "b")
+	if err := copyutil.CopyDir(c.Source, rendered); err != nil {
+		return errors.Wrap(err)
+	}
+	if err := c.Render(rendered); err != nil {
+		return err
+	}
+
+	if c.Dir != "" {
+		return writeDir(rendered, c.Dir)
+	}
+	switch c.Format {
+	case Patch:
+		if err := copyutil.CopyDir(c.Source, filepath.Join(tmp, "a")); err != nil {
+			return errors.Wrap(err)
+		}
+		return diff(tmp, c.Output)
+	case Stdout:
+		return kio.Pipeline{
+			Inputs: []kio.Reader{&kio.LocalPackageReader{PackagePath: rendered}},
+			Outputs: []kio.Writer{kio.ByteWriter{Writer: c.Output,
+				WrappingKind: kio.ResourceListKind, WrappingAPIVersion: kio.ResourceListAPIVersion}},
+		}.Execute()
+	default:
+		return kio.Pipeline{
+			Inputs:  []kio.Reader{&kio.LocalPackageReader{PackagePath: rendered}},
+			Outputs: []kio.Writer{kio.ByteWriter{Writer: c.Output}},
+		}.Execute()
+	}
+}
+
+func isFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDir returns an error if the output directory is, contains or is
+// within the package, as replacing it would change the package.
+func checkDir(source, dir string) error {
+	s, err := filepath.Abs(source)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	d, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	for _, pair := range [][2]string{{s, d}, {d, s}} {
+		rel, err := filepath.Rel(pair[0], pair[1])
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return errors.Errorf("output directory %s must be outside of the package %s", dir, source)
+		}
+	}
+	return nil
+}
+
+// writeDir replaces the content of dir with the resources of the rendered
+// package, keeping their paths.  The .git directory of dir is kept.
+func writeDir(rendered, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	for _, info := range infos {
+		if info.Name() == ".git" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, info.Name())); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	return kio.Pipeline{
+		Inputs:  []kio.Reader{&kio.LocalPackageReader{PackagePath: rendered}},
+		Outputs: []kio.Writer{&kio.LocalPackageWriter{PackagePath: dir}},
+	}.Execute()
+}
+
+// diff writes the changes between the packages a and b within dir to w, as
+// a patch relative to the package.
+func diff(dir string, w io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "--no-prefix", "a", "b")
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 1 {
+		// git diff exits with 1 if the packages differ
+		return nil
+	}
+	if err != nil {
+		return errors.Errorf("git diff failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnoutput_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnoutput"
+	"github.com/stretchr/testify/assert"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+`
+
+// setupPackage returns a package, and a Render function setting the
+// replicas of its deployment to 3.
+func setupPackage(t *testing.T) (string, func(dir string) error) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile("deploy.yaml", deployment))
+	return d, func(dir string) error {
+		return ioutil.WriteFile(filepath.Join(dir, "deploy.yaml"),
+			[]byte(strings.Replace(deployment, "replicas: 1", "replicas: 3", 1)), 0600)
+	}
+}
+
+func TestCommand_Run_stdout(t *testing.T) {
+	d, render := setupPackage(t)
+
+	var out bytes.Buffer
+	err := fnoutput.Command{Source: d, Render: render, Format: fnoutput.Stdout, Output: &out}.Run()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "kind: ResourceList")
+	assert.Contains(t, out.String(), "replicas: 3")
+
+	// the package is not changed
+	b, err := ioutil.ReadFile(filepath.Join(d, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, deployment, string(b))
+}
+
+func TestCommand_Run_unwrap(t *testing.T) {
+	d, render := setupPackage(t)
+
+	var out bytes.Buffer
+	err := fnoutput.Command{Source: d, Render: render, Format: fnoutput.Unwrap, Output: &out}.Run()
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "kind: ResourceList")
+	assert.Contains(t, out.String(), "replicas: 3")
+}
+
+func TestCommand_Run_patch(t *testing.T) {
+	d, render := setupPackage(t)
+
+	var out bytes.Buffer
+	err := fnoutput.Command{Source: d, Render: render, Format: fnoutput.Patch, Output: &out}.Run()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "--- a/deploy.yaml\n+++ b/deploy.yaml\n")
+	assert.Contains(t, out.String(), "-  replicas: 1\n+  replicas: 3\n")
+}
+
+func TestCommand_Run_dir(t *testing.T) {
+	d, render := setupPackage(t)
+	out, err := ioutil.TempDir("", "kpt-fn-output")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(out)
+	assert.NoError(t, os.MkdirAll(filepath.Join(out, ".git"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(out, "stale.yaml"), []byte("kind: Stale\n"), 0600))
+
+	err = fnoutput.Command{Source: d, Render: render, Dir: out}.Run()
+	assert.NoError(t, err)
+	b, err := ioutil.ReadFile(filepath.Join(out, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "replicas: 3")
+	_, err = os.Stat(filepath.Join(out, "stale.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(out, ".git"))
+	assert.NoError(t, err)
+}
+
+func TestCommand_Run_errors(t *testing.T) {
+	d, render := setupPackage(t)
+
+	err := fnoutput.Command{Source: d, Render: render, Dir: filepath.Join(d, "out")}.Run()
+	assert.EqualError(t, err, "output directory "+filepath.Join(d, "out")+" must be outside of the package "+d)
+
+	err = fnoutput.Command{Source: d, Render: render, Format: "json"}.Run()
+	assert.EqualError(t, err, `unsupported output "json", must be one of: stdout, unwrap, patch`)
+
+	err = fnoutput.Command{Source: d, Render: render, Format: fnoutput.Patch, Dir: "out"}.Run()
+	assert.EqualError(t, err, "exactly one of an output format or an output directory must be set")
+}
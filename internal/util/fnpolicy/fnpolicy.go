@@ -0,0 +1,164 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fnpolicy restricts the function images kpt may run, with an
+// organizational policy file.
+package fnpolicy
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// PolicyFileEnv is the name of the environment variable that controls the
+// location of the function policy.  Defaults to
+// UserHomeDir/.kpt/function-policy.yaml if unspecified.
+const PolicyFileEnv = "KPT_FUNCTION_POLICY"
+
+// Policy declares the function images kpt may run.  Images are matched by
+// their name without tag or digest, e.g. gcr.io/kpt-functions/set-namespace,
+// against patterns which may contain the wildcards of path.Match.  A
+// pattern ending in /** matches every image under the prefix, e.g.
+// gcr.io/my-org/** matches every image of my-org on gcr.io.
+type Policy struct {
+	yaml.ResourceMeta `yaml:",inline"`
+
+	// Allow are the patterns of the images which may be run.  If empty,
+	// every image which isn't denied may be run.
+	Allow []string `yaml:"allow,omitempty"`
+
+	// Deny are the patterns of the images which may not be run, even if
+	// they are allowed.
+	Deny []string `yaml:"deny,omitempty"`
+
+	// allowlist further restricts the images allowed by the policy
+	allowlist []string
+
+	// path is the path of the policy file
+	path string
+}
+
+// ReadPolicy reads the function policy at path.
+func ReadPolicy(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("unable to read function policy %s: %v", path, err)
+	}
+	p := &Policy{path: path}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, errors.Errorf("unable to parse function policy %s: %v", path, err)
+	}
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("function policy %s: %v", path, err)
+		}
+	}
+	return p, nil
+}
+
+// DefaultPolicy returns the function policy at the default location, with
+// the images allowed by the allowlist, e.g. of the --fn-allowlist flag.
+// Returns nil if there is neither a policy nor an allowlist.
+func DefaultPolicy(allowlist []string) (*Policy, error) {
+	path, err := policyPath()
+	if err != nil {
+		return nil, err
+	}
+	var p *Policy
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		if p, err = ReadPolicy(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(allowlist) == 0 {
+		return p, nil
+	}
+	for _, pattern := range allowlist {
+		if _, err := Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("--fn-allowlist: %v", err)
+		}
+	}
+	if p == nil {
+		p = &Policy{}
+	}
+	p.allowlist = allowlist
+	return p, nil
+}
+
+func policyPath() (string, error) {
+	if path := os.Getenv(PolicyFileEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Errorf("trouble resolving function policy: %v", err)
+	}
+	return filepath.Join(dir, ".kpt", "function-policy.yaml"), nil
+}
+
+// Check returns an error if the policy doesn't allow running the images.
+func (p *Policy) Check(images ...string) error {
+	if p == nil {
+		return nil
+	}
+	for _, image := range images {
+		name := fnimage.Name(image)
+		if matchAny(p.Deny, name) {
+			return errors.Errorf("function image %s is denied by the function policy %s", image, p.path)
+		}
+		if len(p.Allow) > 0 && !matchAny(p.Allow, name) {
+			return errors.Errorf("function image %s is not allowed by the function policy %s", image, p.path)
+		}
+		if len(p.allowlist) > 0 && !matchAny(p.allowlist, name) {
+			return errors.Errorf("function image %s is not allowed by --fn-allowlist", image)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the image name matches the pattern.
+func Match(pattern, name string) (bool, error) {
+	if prefix := strings.TrimSuffix(pattern, "/**"); prefix != pattern {
+		if _, err := path.Match(prefix, ""); err != nil {
+			return false, errors.Errorf("invalid pattern %s: %v", pattern, err)
+		}
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if match, _ := path.Match(prefix, dir); match {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	match, err := path.Match(pattern, name)
+	if err != nil {
+		return false, errors.Errorf("invalid pattern %s: %v", pattern, err)
+	}
+	return match, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match, _ := Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnpolicy_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/GoogleContainerTools/kpt/internal/util/fnpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"gcr.io/kpt-functions/set-namespace", "gcr.io/kpt-functions/set-namespace", true},
+		{"gcr.io/kpt-functions/*", "gcr.io/kpt-functions/set-namespace", true},
+		{"gcr.io/kpt-functions/*", "gcr.io/kpt-functions/sub/fn", false},
+		{"gcr.io/my-org/**", "gcr.io/my-org/team/fn", true},
+		{"gcr.io/my-org/**", "gcr.io/my-org/fn", true},
+		{"gcr.io/my-org/**", "gcr.io/other/fn", false},
+		{"*.gcr.io/**", "eu.gcr.io/my-org/fn", true},
+	}
+	for _, test := range tests {
+		match, err := Match(test.pattern, test.name)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, match, "%s %s", test.pattern, test.name)
+	}
+
+	_, err := Match("gcr.io/[", "gcr.io/fn")
+	assert.EqualError(t, err, "invalid pattern gcr.io/[: syntax error in pattern")
+}
+
+func TestPolicy_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-fnpolicy-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "function-policy.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`apiVersion: kpt.dev/v1alpha1
+kind: FunctionPolicy
+allow:
+- gcr.io/kpt-functions/*
+- gcr.io/my-org/**
+deny:
+- gcr.io/kpt-functions/helm-template
+`), 0600))
+	old := os.Getenv(PolicyFileEnv)
+	os.Setenv(PolicyFileEnv, path)
+	defer os.Setenv(PolicyFileEnv, old)
+
+	p, err := DefaultPolicy(nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, p.Check("gcr.io/kpt-functions/set-namespace:v0.1", "gcr.io/my-org/team/fn@sha256:abc"))
+	assert.EqualError(t, p.Check("gcr.io/kpt-functions/helm-template:v0.1"),
+		"function image gcr.io/kpt-functions/helm-template:v0.1 is denied by the function policy "+path)
+	assert.EqualError(t, p.Check("docker.io/evil/fn"),
+		"function image docker.io/evil/fn is not allowed by the function policy "+path)
+
+	// the allowlist restricts the images allowed by the policy
+	p, err = DefaultPolicy([]string{"gcr.io/my-org/**"})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Check("gcr.io/my-org/fn:v1"))
+	assert.EqualError(t, p.Check("gcr.io/kpt-functions/set-namespace:v0.1"),
+		"function image gcr.io/kpt-functions/set-namespace:v0.1 is not allowed by --fn-allowlist")
+}
+
+func TestDefaultPolicy_none(t *testing.T) {
+	old := os.Getenv(PolicyFileEnv)
+	os.Setenv(PolicyFileEnv, filepath.Join(os.TempDir(), "kpt-missing-function-policy.yaml"))
+	defer os.Setenv(PolicyFileEnv, old)
+
+	p, err := DefaultPolicy(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+	assert.NoError(t, p.Check("docker.io/any/fn"))
+
+	p, err = DefaultPolicy([]string{"gcr.io/kpt-functions/*"})
+	assert.NoError(t, err)
+	assert.EqualError(t, p.Check("docker.io/any/fn"),
+		"function image docker.io/any/fn is not allowed by --fn-allowlist")
+}
@@ -0,0 +1,216 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fnrunner delegates running function containers to a remote function
+// runner service, such as a runner in a cluster, so the function images are
+// never pulled or run locally.
+//
+// The runner protocol is gRPC: kpt calls the Run method of the
+// kpt.fnrunner.v1.FunctionRunner service of fnrunnerpb/fnrunner.proto.
+package fnrunner
+
+//go:generate protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. fnrunnerpb/fnrunner.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnrunner/fnrunnerpb"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// AddressEnv is the name of the environment variable that sets the address
+// of the runner.  The --runner-address flag takes precedence over it.
+const AddressEnv = "KPT_FN_RUNNER_ADDRESS"
+
+// TokenEnv is the name of the environment variable with the bearer token
+// sent to the runner, if it requires authentication.
+const TokenEnv = "KPT_FN_RUNNER_TOKEN"
+
+// Filter runs a function container with the runner.
+type Filter struct {
+	// Address is the address of the runner, e.g. https://runner.example.com
+	Address string
+
+	// Image is the image of the function container
+	Image string
+
+	// DialOptions are appended to the options of the connection to the
+	// runner, e.g. to trust other certificates than the system roots.
+	DialOptions []grpc.DialOption
+
+	runtimeutil.FunctionFilter
+}
+
+// Filter runs the function on the nodes.
+func (f *Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	f.FunctionFilter.Run = f.Run
+	return f.FunctionFilter.Filter(nodes)
+}
+
+// Run sends the ResourceList read from reader to the runner, and writes the
+// ResourceList returned by the function to writer.
+func (f *Filter) Run(reader io.Reader, writer io.Writer) error {
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	conn, err := f.dial()
+	if err != nil {
+		return errors.Errorf("unable to reach function runner %s: %v", f.Address, err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if token := os.Getenv(TokenEnv); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	r, err := fnrunnerpb.NewFunctionRunnerClient(conn).Run(ctx,
+		&fnrunnerpb.RunRequest{Image: f.Image, ResourceList: string(b)})
+	if err != nil {
+		return errors.Errorf("function %s failed on runner %s: %v", f.Image, f.Address, err)
+	}
+	if r.Stderr != "" {
+		fmt.Fprint(os.Stderr, r.Stderr)
+	}
+	if r.Error != "" {
+		return errors.Errorf("function %s failed on runner %s: %s", f.Image, f.Address, r.Error)
+	}
+	_, err = io.WriteString(writer, r.ResourceList)
+	return errors.Wrap(err)
+}
+
+// dial connects to the runner, with TLS unless its address is http.
+func (f *Filter) dial() (*grpc.ClientConn, error) {
+	target, secure, err := Target(f.Address)
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if secure {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))}
+	}
+	return grpc.Dial(target, append(opts, f.DialOptions...)...)
+}
+
+// Target returns the gRPC target of the runner at address, and whether the
+// connection uses TLS.  Addresses without a scheme use https, and addresses
+// without a port use the default port of their scheme.
+func Target(address string) (string, bool, error) {
+	secure := true
+	switch {
+	case strings.HasPrefix(address, "https://"):
+		address = strings.TrimPrefix(address, "https://")
+	case strings.HasPrefix(address, "http://"):
+		address, secure = strings.TrimPrefix(address, "http://"), false
+	case strings.Contains(address, "://"):
+		return "", false, errors.Errorf(
+			"unsupported scheme of function runner %s, must be http or https", address)
+	}
+	host := strings.TrimSuffix(address, "/")
+	if strings.Contains(host, "/") {
+		return "", false, errors.Errorf("function runner address %s must not have a path", address)
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "443"
+		if !secure {
+			port = "80"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	return host, secure, nil
+}
+
+// Run runs the container functions declared in the package at dir with the
+// runner at address, with the same scoping rules as when running them
+// locally.  Functions which are not run in containers are not run.
+func Run(dir, address string) error {
+	rw := &kio.LocalPackageReadWriter{PackagePath: dir}
+	nodes, err := rw.Read()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	var filters []kio.Filter
+	for _, n := range nodes {
+		spec := runtimeutil.GetFunctionSpec(n)
+		if spec == nil || spec.Container.Image == "" {
+			continue
+		}
+		filters = append(filters, &Filter{
+			Address:        address,
+			Image:          spec.Container.Image,
+			FunctionFilter: runtimeutil.FunctionFilter{FunctionConfig: n},
+		})
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return kio.Pipeline{
+		Inputs:  []kio.Reader{&kio.PackageBuffer{Nodes: nodes}},
+		Filters: filters,
+		Outputs: []kio.Writer{rw},
+	}.Execute()
+}
+
+// Command runs a function container with the runner on a package, or on the
+// resources read from Input.
+type Command struct {
+	// Address is the address of the runner
+	Address string
+
+	// Image is the image of the function container
+	Image string
+
+	// Dir is the directory of the package.  If empty, the resources are
+	// read from Input and written to Output.
+	Dir string
+
+	// Data is the data of the ConfigMap passed to the function as its
+	// function config
+	Data map[string]string
+
+	// DryRun writes the resources of the package to Output instead of
+	// writing them to the package
+	DryRun bool
+
+	Input  io.Reader
+	Output io.Writer
+}
+
+// Run runs the function.
+func (c Command) Run() error {
+	config, err := functions.FunctionConfig(c.Data)
+	if err != nil {
+		return err
+	}
+	f := &Filter{
+		Address:        c.Address,
+		Image:          c.Image,
+		FunctionFilter: runtimeutil.FunctionFilter{FunctionConfig: config, GlobalScope: true},
+	}
+	return functions.RunImperative(f, c.Dir, c.DryRun, c.Input, c.Output)
+}
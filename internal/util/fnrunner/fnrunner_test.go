@@ -0,0 +1,186 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fnrunner_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/testenv"
+	. "github.com/GoogleContainerTools/kpt/internal/util/fnrunner"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnrunner/fnrunnerpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+`
+
+// runner is a fake function runner, which scales the replicas of the
+// resources to 3 and records the images it ran and the authorization
+// metadata of the calls.
+type runner struct {
+	fnrunnerpb.UnimplementedFunctionRunnerServer
+	images         []string
+	authorizations []string
+}
+
+func (r *runner) Run(ctx context.Context, req *fnrunnerpb.RunRequest) (*fnrunnerpb.RunResponse, error) {
+	r.images = append(r.images, req.Image)
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.authorizations = append(r.authorizations, md.Get("authorization")...)
+	switch req.Image {
+	case "gcr.io/example/fail":
+		return &fnrunnerpb.RunResponse{Error: "exit status 1", Stderr: "failed\n"}, nil
+	case "gcr.io/example/unavailable":
+		return nil, status.Error(codes.Unavailable, "no nodes")
+	}
+	return &fnrunnerpb.RunResponse{
+		ResourceList: strings.Replace(req.ResourceList, "replicas: 1", "replicas: 3", -1),
+	}, nil
+}
+
+// newServer serves the runner without TLS, and returns its address and a
+// function stopping it.
+func newServer(t *testing.T, r *runner) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	s := grpc.NewServer()
+	fnrunnerpb.RegisterFunctionRunnerServer(s, r)
+	go func() { _ = s.Serve(l) }()
+	return "http://" + l.Addr().String(), s.Stop
+}
+
+func TestTarget(t *testing.T) {
+	for address, expected := range map[string]struct {
+		target string
+		secure bool
+	}{
+		"runner.example.com":              {target: "runner.example.com:443", secure: true},
+		"https://runner.example.com:8443": {target: "runner.example.com:8443", secure: true},
+		"http://localhost:9445/":          {target: "localhost:9445"},
+		"http://localhost":                {target: "localhost:80"},
+		"http://[::1]":                    {target: "[::1]:80"},
+	} {
+		target, secure, err := Target(address)
+		assert.NoError(t, err, address)
+		assert.Equal(t, expected.target, target, address)
+		assert.Equal(t, expected.secure, secure, address)
+	}
+
+	_, _, err := Target("unix:///var/run/runner.sock")
+	assert.Error(t, err)
+	_, _, err = Target("https://runner.example.com/runner")
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	r := &runner{}
+	address, stop := newServer(t, r)
+	defer stop()
+	dir, err := ioutil.TempDir("", "kpt-fnrunner-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(deployment), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fn.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/example/scale
+`), 0600))
+
+	if !assert.NoError(t, Run(dir, address)) {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"gcr.io/example/scale"}, r.images)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "deployment.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), string(b))
+}
+
+func TestCommand_Run(t *testing.T) {
+	r := &runner{}
+	address, stop := newServer(t, r)
+	defer stop()
+	testenv.Setenv(t, TokenEnv, "secret")
+
+	out := &bytes.Buffer{}
+	err := Command{Address: address, Image: "gcr.io/example/scale", Data: map[string]string{"a": "b"},
+		Input: strings.NewReader(deployment), Output: out}.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), out.String())
+	assert.Equal(t, []string{"Bearer secret"}, r.authorizations)
+
+	err = Command{Address: address, Image: "gcr.io/example/fail",
+		Input: strings.NewReader(deployment), Output: out}.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "function gcr.io/example/fail failed on runner "+address+": exit status 1")
+	}
+
+	err = Command{Address: address + "/missing", Image: "gcr.io/example/scale",
+		Input: strings.NewReader(deployment), Output: out}.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "must not have a path")
+	}
+
+	err = Command{Address: address, Image: "gcr.io/example/unavailable",
+		Input: strings.NewReader(deployment), Output: out}.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rpc error: code = Unavailable desc = no nodes")
+	}
+}
+
+func TestFilter_Run_tls(t *testing.T) {
+	gs := grpc.NewServer()
+	fnrunnerpb.RegisterFunctionRunnerServer(gs, &runner{})
+	s := httptest.NewUnstartedServer(gs)
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+
+	out := &bytes.Buffer{}
+	f := &Filter{Address: s.URL, Image: "gcr.io/example/scale", DialOptions: []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots})),
+	}}
+	assert.NoError(t, f.Run(strings.NewReader(deployment), out))
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), out.String())
+}
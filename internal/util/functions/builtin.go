@@ -0,0 +1,239 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/search"
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/go-openapi/spec"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fieldmeta"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/setters2"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Names of the builtin functions.
+const (
+	ApplySetters        = "apply-setters"
+	SetNamespace        = "set-namespace"
+	SetLabels           = "set-labels"
+	SearchReplace       = "search-replace"
+	EnsureNameSubstring = "ensure-name-substring"
+	ValidateSchemas     = "validate-schemas"
+)
+
+// builtin is a function implemented by kpt.
+type builtin struct {
+	// fields are the fields of its config, or nil if it accepts any field
+	fields []string
+
+	// filter returns the filter running the function on the package at
+	// pkgPath with the config
+	filter func(pkgPath string, config map[string]string) (kio.Filter, error)
+}
+
+var builtins = map[string]builtin{
+	ApplySetters: {filter: applySetters},
+	SetNamespace: {fields: []string{"namespace"}, filter: setNamespace},
+	SetLabels:    {filter: setLabels},
+	SearchReplace: {
+		fields: []string{"by-value", "by-value-regex", "by-path", "put-literal", "put-pattern"},
+		filter: searchReplace,
+	},
+	EnsureNameSubstring: {fields: []string{"prepend", "append"}, filter: ensureNameSubstring},
+	ValidateSchemas:     {fields: []string{"crd-path", "strict"}, filter: validateSchemas},
+}
+
+// Builtins returns the sorted names of the builtin functions.
+func Builtins() []string {
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builtinFilter returns the filter running the builtin function on the
+// package at pkgPath.  The config of builtin functions is a map of strings,
+// or a ConfigMap whose data is the map.
+func builtinFilter(pkgPath string, f kptfile.Function) (kio.Filter, error) {
+	b, found := builtins[f.Name]
+	if !found {
+		return nil, errors.Errorf("unknown builtin function %q, must be one of: %s",
+			f.Name, strings.Join(Builtins(), ", "))
+	}
+	config := map[string]string{}
+	if f.Config.Kind != 0 {
+		n := yaml.NewRNode(&f.Config)
+		if data := n.Field("data"); data != nil {
+			n = data.Value
+		}
+		if err := n.YNode().Decode(&config); err != nil {
+			return nil, errors.Errorf("invalid config of builtin function %s: %v", f.Name, err)
+		}
+	}
+	if b.fields != nil {
+		for key := range config {
+			if !contains(b.fields, key) {
+				return nil, errors.Errorf("unknown field %s in the config of builtin function %s, must be one of: %s",
+					key, f.Name, strings.Join(b.fields, ", "))
+			}
+		}
+	}
+	return b.filter(pkgPath, config)
+}
+
+// applySetters sets the fields referencing the setters of the package, with
+// the values of the config overriding the values in the Kptfile.  The values
+// must match the constraints of the setter definitions.  The list and map
+// fields referencing list and map setters are replaced by their values.
+func applySetters(pkgPath string, values map[string]string) (kio.Filter, error) {
+	if err := setters.CheckValues(pkgPath, values); err != nil {
+		return nil, err
+	}
+	collections, err := setters.CollectionValues(pkgPath, values)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := openapi.SchemaFromFile(filepath.Join(pkgPath, kptfile.KptFileName))
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		schema = &spec.Schema{}
+	}
+	if schema.Definitions == nil {
+		schema.Definitions = spec.Definitions{}
+	}
+	for name, value := range values {
+		if _, found := collections[name]; found {
+			continue
+		}
+		key := fieldmeta.SetterDefinitionPrefix + name
+		def := schema.Definitions[key]
+		def.AddExtension(setters2.K8sCliExtensionKey, map[string]interface{}{
+			"setter": map[string]interface{}{"name": name, "value": value},
+		})
+		schema.Definitions[key] = def
+	}
+	set := kio.FilterAll(&setters2.Set{SetAll: true, SettersSchema: schema})
+	return kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+		nodes, err := set.Filter(nodes)
+		if err != nil {
+			return nil, err
+		}
+		return setters.SetCollections(collections).Filter(nodes)
+	}), nil
+}
+
+// setNamespace sets the namespace of the namespaced resources.
+func setNamespace(_ string, config map[string]string) (kio.Filter, error) {
+	namespace := config["namespace"]
+	if namespace == "" {
+		return nil, errors.Errorf("builtin function %s must have a namespace", SetNamespace)
+	}
+	return kio.FilterAll(yaml.FilterFunc(func(n *yaml.RNode) (*yaml.RNode, error) {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if namespaced, known := openapi.IsNamespaceScoped(meta.TypeMeta); known && !namespaced {
+			return n, nil
+		}
+		return n, n.PipeE(yaml.LookupCreate(yaml.MappingNode, "metadata"),
+			yaml.SetField("namespace", yaml.NewScalarRNode(namespace)))
+	})), nil
+}
+
+// setLabels sets the labels of the config on the resources.
+func setLabels(_ string, labels map[string]string) (kio.Filter, error) {
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return kio.FilterAll(yaml.FilterFunc(func(n *yaml.RNode) (*yaml.RNode, error) {
+		for _, k := range keys {
+			if err := n.PipeE(yaml.SetLabel(k, labels[k])); err != nil {
+				return nil, errors.Wrap(err)
+			}
+		}
+		return n, nil
+	})), nil
+}
+
+// searchReplace searches and replaces fields like kpt cfg search.
+func searchReplace(pkgPath string, config map[string]string) (kio.Filter, error) {
+	sr := &search.SearchReplace{
+		ByValue:      config["by-value"],
+		ByValueRegex: config["by-value-regex"],
+		ByPath:       config["by-path"],
+		PutLiteral:   config["put-literal"],
+		PutPattern:   config["put-pattern"],
+		PackagePath:  pkgPath,
+	}
+	if sr.ByValue == "" && sr.ByValueRegex == "" && sr.ByPath == "" {
+		return nil, errors.Errorf("builtin function %s must have one of by-value, by-value-regex or by-path",
+			SearchReplace)
+	}
+	if sr.ByValue != "" && sr.ByValueRegex != "" {
+		return nil, errors.Errorf("builtin function %s must not have both by-value and by-value-regex",
+			SearchReplace)
+	}
+	return sr.FilterAll()
+}
+
+// ensureNameSubstring prepends and appends the substrings of the config to
+// the names of the resources which don't already have them.
+func ensureNameSubstring(_ string, config map[string]string) (kio.Filter, error) {
+	prefix, suffix := config["prepend"], config["append"]
+	if prefix == "" && suffix == "" {
+		return nil, errors.Errorf("builtin function %s must have prepend or append", EnsureNameSubstring)
+	}
+	return kio.FilterAll(yaml.FilterFunc(func(n *yaml.RNode) (*yaml.RNode, error) {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		name := meta.Name
+		if !strings.HasPrefix(name, prefix) {
+			name = prefix + name
+		}
+		if !strings.HasSuffix(name, suffix) {
+			name += suffix
+		}
+		if name == meta.Name {
+			return n, nil
+		}
+		return n, n.PipeE(yaml.Lookup("metadata"), yaml.SetField("name", yaml.NewScalarRNode(name)))
+	})), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestRunFunctions_builtin(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+`), 0600))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # {"$kpt-set":"replicas"}
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.18
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+`), 0600))
+
+	var dep kptfile.Dependency
+	testutil.AssertNoError(t, yaml.Unmarshal([]byte(`
+functions:
+- runtime: builtin
+  name: apply-setters
+  config:
+    replicas: "5"
+- runtime: builtin
+  name: set-namespace
+  config:
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: namespace
+    data:
+      namespace: apps
+- runtime: builtin
+  name: set-labels
+  config:
+    app: web
+- runtime: builtin
+  name: search-replace
+  config:
+    by-value: nginx:1.18
+    put-literal: nginx:1.19
+- runtime: builtin
+  name: ensure-name-substring
+  config:
+    prepend: prod-
+`), &dep))
+	if !assert.NoError(t, functions.RunFunctions(d, dep.Functions, functions.RunOptions{})) {
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(filepath.Join(d, "deploy.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: prod-web
+  namespace: apps
+  labels:
+    app: web
+spec:
+  replicas: 5 # {"$kpt-set":"replicas"}
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod-apps
+  labels:
+    app: web
+`, string(actual))
+}
+
+func TestRunFunctions_builtinErrors(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+
+	for config, expected := range map[string]string{
+		"name: kubeval\n": `unknown builtin function "kubeval", must be one of: ` +
+			"apply-setters, ensure-name-substring, search-replace, set-labels, set-namespace, validate-schemas",
+		"name: set-namespace\nconfig:\n  namespce: apps\n": "unknown field namespce in the config of " +
+			"builtin function set-namespace, must be one of: namespace",
+		"name: set-namespace\n":  "builtin function set-namespace must have a namespace",
+		"name: search-replace\n": "builtin function search-replace must have one of by-value, by-value-regex or by-path",
+	} {
+		var f kptfile.Function
+		testutil.AssertNoError(t, yaml.Unmarshal([]byte("runtime: builtin\n"+config), &f))
+		err := functions.RunFunctions(d, []kptfile.Function{f}, functions.RunOptions{})
+		assert.EqualError(t, err, expected, config)
+	}
+}
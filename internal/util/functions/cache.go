@@ -0,0 +1,200 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// CacheHomeEnv is the name of the environment variable that controls the
+// directory of the cache of function outputs.  Defaults to
+// UserHomeDir/.kpt/fn-cache if unspecified.
+const CacheHomeEnv = "KPT_FN_CACHE_HOME"
+
+// CacheHome returns the directory of the cache of function outputs.
+func CacheHome() (string, error) {
+	if dir := os.Getenv(CacheHomeEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Errorf("trouble resolving function cache directory: %v", err)
+	}
+	return filepath.Join(dir, ".kpt", "fn-cache"), nil
+}
+
+// CachedFilter runs a function, caching the resources it outputs by the hash
+// of its input resources and its Key.  The function is only run if its output
+// for the same input isn't cached yet.  The output of functions reporting
+// results isn't cached, so their results are reported each time.
+type CachedFilter struct {
+	// Function is the function whose output is cached
+	Function kio.Filter
+
+	// Key identifies the function and its function config.  The output of
+	// functions with an empty key isn't cached.
+	Key string
+
+	// Dir is the directory of the cache
+	Dir string
+}
+
+// Filter returns the cached output of the function for the nodes, or runs
+// the function and caches its output.
+func (f CachedFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if f.Key == "" {
+		return f.Function.Filter(nodes)
+	}
+	in, err := writeNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(f.Key+"\n"), in...))
+	path := filepath.Join(f.Dir, hex.EncodeToString(sum[:])+".yaml")
+	if b, err := ioutil.ReadFile(path); err == nil {
+		out, err := (&kio.ByteReader{Reader: bytes.NewReader(b), OmitReaderAnnotations: true}).Read()
+		if err == nil {
+			// record the use of the output, so it isn't pruned
+			now := time.Now()
+			_ = os.Chtimes(path, now, now)
+			return out, nil
+		}
+		// outputs which can't be read are replaced
+	}
+
+	out, err := f.Function.Filter(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := f.Function.(interface{ uncacheable() bool }); ok && u.uncacheable() {
+		return out, nil
+	}
+	b, err := writeNodes(out)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return nil, errors.Errorf("trouble creating function cache directory: %v", err)
+	}
+	// write the output to a temporary file so functions run at the same
+	// time never read a partially written output
+	tmp, err := ioutil.TempFile(f.Dir, "tmp-")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	return out, errors.Wrap(err)
+}
+
+// PruneCache removes the cached function outputs which were not used since
+// the time.  Returns the number of outputs removed and the bytes freed.
+func PruneCache(since time.Time) (int, int64, error) {
+	dir, err := CacheHome()
+	if err != nil {
+		return 0, 0, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, errors.Wrap(err)
+	}
+	var removed int
+	var freed int64
+	for _, info := range infos {
+		if info.IsDir() || !info.ModTime().Before(since) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+			return removed, freed, errors.Wrap(err)
+		}
+		removed++
+		freed += info.Size()
+	}
+	return removed, freed, nil
+}
+
+func writeNodes(nodes []*yaml.RNode) ([]byte, error) {
+	var b bytes.Buffer
+	err := kio.ByteWriter{Writer: &b, KeepReaderAnnotations: true}.Write(nodes)
+	return b.Bytes(), errors.Wrap(err)
+}
+
+// cacheKey returns the key of the output of the function run on the package
+// at pkgPath: the id of its image, or the content of its program or binary,
+// its function config, and the network access, limits and timeout of its
+// container.  Returns an empty key for functions which can't
+// be identified, such as container functions whose image isn't pulled yet.
+func cacheKey(pkgPath string, f kptfile.Function, binary string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "runtime: %s\n", f.Runtime)
+	switch f.Runtime {
+	case kptfile.StarlarkRuntime:
+		program := []byte(f.Source)
+		if f.Path != "" {
+			var err error
+			if program, err = ioutil.ReadFile(filepath.Join(pkgPath, filepath.FromSlash(f.Path))); err != nil {
+				return ""
+			}
+		}
+		h.Write(program)
+	case kptfile.ExecRuntime:
+		b, err := ioutil.ReadFile(binary)
+		if err != nil {
+			return ""
+		}
+		h.Write(b)
+		fmt.Fprintf(h, "\nargs: %q\n", f.Exec.Args)
+	default:
+		id, err := fnimage.ID(f.Image)
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "image: %s\n", id)
+		fmt.Fprintf(h, "network: %t\nhosts: %q\ntimeout: %s\n", f.Network, f.Hosts, f.Timeout)
+		if f.Limits != nil {
+			fmt.Fprintf(h, "limits: %s %s\n", f.Limits.CPU, f.Limits.Memory)
+		}
+	}
+	if f.Config.Kind != 0 {
+		config, err := yaml.NewRNode(&f.Config).String()
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "config: %s\n", config)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestCachedFilter(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-cache")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+
+	runs := 0
+	f := functions.CachedFilter{
+		Function: kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			runs++
+			for _, n := range nodes {
+				if err := n.PipeE(yaml.SetLabel("app", "cached")); err != nil {
+					return nil, err
+				}
+			}
+			return nodes, nil
+		}),
+		Key: "label",
+		Dir: d,
+	}
+	input := func(name string) []*yaml.RNode {
+		return []*yaml.RNode{yaml.MustParse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n")}
+	}
+
+	for i := 0; i < 2; i++ {
+		out, err := f.Filter(input("a"))
+		testutil.AssertNoError(t, err)
+		assert.Equal(t, 1, runs)
+		s, err := out[0].String()
+		testutil.AssertNoError(t, err)
+		assert.Contains(t, s, "app: cached")
+	}
+
+	// other inputs and keys are run again
+	_, err = f.Filter(input("b"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, 2, runs)
+	f.Key = "other"
+	_, err = f.Filter(input("b"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, 3, runs)
+
+	// functions without a key aren't cached
+	f.Key = ""
+	_, err = f.Filter(input("b"))
+	testutil.AssertNoError(t, err)
+	_, err = f.Filter(input("b"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, 5, runs)
+}
+
+func TestRunFunctions_cache(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	cache := filepath.Join(d, "cache")
+	defer os.Setenv(functions.CacheHomeEnv, os.Getenv(functions.CacheHomeEnv))
+	testutil.AssertNoError(t, os.Setenv(functions.CacheHomeEnv, cache))
+
+	dep := filepath.Join(d, "dep")
+	log := filepath.Join(d, "log")
+	testutil.AssertNoError(t, os.MkdirAll(filepath.Join(d, "bin"), 0700))
+	testutil.AssertNoError(t, os.MkdirAll(dep, 0700))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "bin", "set-replicas"),
+		[]byte("#!/bin/sh\necho run >> "+log+"\nsed \"s/replicas: 3/replicas: $1/\"\n"), 0700))
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+spec:
+  replicas: 3
+`
+	policy := functions.ExecPolicy{Allow: true, Allowlist: []string{"bin/set-replicas"}, Dir: d}
+	run := func(replicas string) int {
+		testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(dep, "deploy.yaml"), []byte(deployment), 0600))
+		fns := []kptfile.Function{{Exec: &kptfile.ExecFunction{Path: "bin/set-replicas", Args: []string{replicas}}}}
+		testutil.AssertNoError(t, functions.RunFunctions(dep, fns, functions.RunOptions{ExecPolicy: policy, Cache: true}))
+		actual, err := ioutil.ReadFile(filepath.Join(dep, "deploy.yaml"))
+		testutil.AssertNoError(t, err)
+		assert.Contains(t, string(actual), "replicas: "+replicas)
+		b, _ := ioutil.ReadFile(log)
+		return strings.Count(string(b), "run")
+	}
+
+	assert.Equal(t, 1, run("5"))
+	// the unchanged package is rendered from the cache
+	assert.Equal(t, 1, run("5"))
+	// the function is run again when its arguments change
+	assert.Equal(t, 2, run("7"))
+}
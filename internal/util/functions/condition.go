@@ -0,0 +1,98 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ConditionFilter runs a function only if its condition is met.  Otherwise
+// the resources are passed through unchanged.
+type ConditionFilter struct {
+	// Function is the function run if the condition is met
+	Function kio.Filter
+
+	// When is the condition of the function
+	When kptfile.Condition
+
+	// Setters are the values of the setters of the package by name
+	Setters map[string]string
+
+	// Name is the name of the function reported when it's skipped
+	Name string
+
+	// StdErr is where the skipped functions are reported, if set
+	StdErr io.Writer
+}
+
+// Filter runs the function on the nodes if the condition is met.
+func (f ConditionFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	met, err := Met(f.When, f.Setters, nodes)
+	if err != nil {
+		return nil, errors.WrapPrefixf(err, "condition of function %s", f.Name)
+	}
+	if !met {
+		if f.StdErr != nil {
+			fmt.Fprintf(f.StdErr, "skipped function %s, its condition isn't met\n", f.Name)
+		}
+		return nodes, nil
+	}
+	return f.Function.Filter(nodes)
+}
+
+// Met returns true if the condition is met by the resources and the values
+// of the setters of the package.
+func Met(when kptfile.Condition, setters map[string]string, nodes []*yaml.RNode) (bool, error) {
+	if when.Setter != nil {
+		value, found := setters[when.Setter.Name]
+		if !found {
+			return false, errors.Errorf("setter %s is not defined by the package", when.Setter.Name)
+		}
+		if value != when.Setter.Value {
+			return false, nil
+		}
+	}
+	if len(when.Exists) > 0 {
+		exists, err := anySelected(when.Exists, nodes)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+	if len(when.NotExists) > 0 {
+		exists, err := anySelected(when.NotExists, nodes)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// anySelected returns true if any of the resources matches any of the
+// selectors.
+func anySelected(selectors []kptfile.Selector, nodes []*yaml.RNode) (bool, error) {
+	for _, n := range nodes {
+		match, err := Selects(selectors, nil, n)
+		if err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
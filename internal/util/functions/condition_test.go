@@ -0,0 +1,114 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestMet(t *testing.T) {
+	var nodes []*yaml.RNode
+	for _, r := range selectorResources {
+		nodes = append(nodes, yaml.MustParse(r))
+	}
+	setters := map[string]string{"env": "prod"}
+
+	var tests = []struct {
+		name     string
+		when     kptfile.Condition
+		expected bool
+	}{
+		{
+			name:     "none",
+			expected: true,
+		},
+		{
+			name:     "setter",
+			when:     kptfile.Condition{Setter: &kptfile.SetterCondition{Name: "env", Value: "prod"}},
+			expected: true,
+		},
+		{
+			name: "other setter value",
+			when: kptfile.Condition{Setter: &kptfile.SetterCondition{Name: "env", Value: "dev"}},
+		},
+		{
+			name:     "exists",
+			when:     kptfile.Condition{Exists: []kptfile.Selector{{Kind: "Service"}}},
+			expected: true,
+		},
+		{
+			name: "doesn't exist",
+			when: kptfile.Condition{Exists: []kptfile.Selector{{Kind: "Ingress"}}},
+		},
+		{
+			name:     "not exists",
+			when:     kptfile.Condition{NotExists: []kptfile.Selector{{Kind: "Ingress"}}},
+			expected: true,
+		},
+		{
+			name: "exists and not exists",
+			when: kptfile.Condition{
+				Exists:    []kptfile.Selector{{Kind: "Deployment"}},
+				NotExists: []kptfile.Selector{{Namespace: "data"}},
+			},
+		},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			met, err := functions.Met(test.when, setters, nodes)
+			testutil.AssertNoError(t, err)
+			assert.Equal(t, test.expected, met)
+		})
+	}
+
+	_, err := functions.Met(kptfile.Condition{Setter: &kptfile.SetterCondition{Name: "region"}}, setters, nodes)
+	assert.EqualError(t, err, "setter region is not defined by the package")
+}
+
+func TestConditionFilter(t *testing.T) {
+	nodes := []*yaml.RNode{yaml.MustParse(selectorResources[0])}
+	var ran bool
+	stderr := &bytes.Buffer{}
+	f := functions.ConditionFilter{
+		Function: kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			ran = true
+			return nodes[:0], nil
+		}),
+		When:    kptfile.Condition{NotExists: []kptfile.Selector{{Kind: "Deployment"}}},
+		Name:    "gcr.io/kpt-functions/scale",
+		Setters: map[string]string{},
+		StdErr:  stderr,
+	}
+	out, err := f.Filter(nodes)
+	testutil.AssertNoError(t, err)
+	assert.False(t, ran)
+	assert.Len(t, out, 1)
+	assert.Equal(t, "skipped function gcr.io/kpt-functions/scale, its condition isn't met\n", stderr.String())
+
+	f.When = kptfile.Condition{Exists: []kptfile.Selector{{Kind: "Deployment"}}}
+	out, err = f.Filter(nodes)
+	testutil.AssertNoError(t, err)
+	assert.True(t, ran)
+	assert.Empty(t, out)
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	goexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// oomExitCode is the exit code of containers killed by the kernel, e.g. for
+// exceeding their memory limit.
+const oomExitCode = 137
+
+// ContainerFilter runs a container function with resource limits and a
+// timeout, enforced by the container runtime.  Like the containers of
+// functions run by kpt fn run, the container runs as nobody, and without
+// network access unless Network is set.
+type ContainerFilter struct {
+	// Image is the image of the function
+	Image string
+
+	// Network allows the container to access the network
+	Network bool
+
+	// Hosts are the only hostnames the container may resolve with network
+	// access, if set.  They are resolved each time the container runs, and
+	// pinned in the hosts file of the container, which has no DNS server.
+	// Connections to IP addresses aren't restricted.
+	Hosts []string
+
+	// CPU is the number of CPUs the container may use
+	CPU string
+
+	// Memory is the memory the container may use
+	Memory string
+
+	// Timeout is the duration after which the container is killed
+	Timeout time.Duration
+
+	runtimeutil.FunctionFilter
+}
+
+// Filter runs the function on the nodes.
+func (f *ContainerFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	f.FunctionFilter.Run = f.Run
+	return f.FunctionFilter.Filter(nodes)
+}
+
+// Args returns the arguments of the container runtime running the container
+// with the name, pinning the host:ip entries of PinnedHosts.
+func (f *ContainerFilter) Args(name string, pinned []string) []string {
+	args := []string{"run", "--rm", "-i", "--name", name,
+		"--user", "nobody", "--security-opt=no-new-privileges"}
+	if !f.Network {
+		args = append(args, "--network", "none")
+	} else if len(f.Hosts) > 0 {
+		args = append(args, "--dns", "127.0.0.1")
+		for _, h := range pinned {
+			args = append(args, "--add-host", h)
+		}
+	}
+	if f.CPU != "" {
+		args = append(args, "--cpus", f.CPU)
+	}
+	if f.Memory != "" {
+		// the container may not swap beyond its memory limit
+		args = append(args, "--memory", f.Memory, "--memory-swap", f.Memory)
+	}
+	return append(args, f.Image)
+}
+
+// PinnedHosts resolves the hosts, returning the host:ip entries of the hosts
+// file of containers which may only resolve them.  IP addresses need no
+// entry.
+func PinnedHosts(hosts []string) ([]string, error) {
+	var entries []string
+	for _, h := range hosts {
+		if net.ParseIP(h) != nil {
+			continue
+		}
+		ips, err := net.LookupHost(h)
+		if err != nil {
+			return nil, errors.Errorf("failed to resolve host %s: %v", h, err)
+		}
+		for _, ip := range ips {
+			entries = append(entries, h+":"+ip)
+		}
+	}
+	return entries, nil
+}
+
+// Run runs the container with the ResourceList read from reader and written
+// to writer.  Containers exceeding the timeout are killed.
+func (f *ContainerFilter) Run(reader io.Reader, writer io.Writer) error {
+	ctx := context.Background()
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	var pinned []string
+	if f.Network {
+		// the hosts are resolved on every run, so their entries are current
+		var err error
+		if pinned, err = PinnedHosts(f.Hosts); err != nil {
+			return errors.WrapPrefixf(err, "function %s", f.Image)
+		}
+	}
+	name := fmt.Sprintf("kpt-fn-%d-%d", os.Getpid(), time.Now().UnixNano())
+	var stderr bytes.Buffer
+	cmd := goexec.CommandContext(ctx, fnimage.Runtime, f.Args(name, pinned)...)
+	cmd.Stdin = reader
+	cmd.Stdout = writer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		// killing the runtime client doesn't stop the container
+		_ = goexec.Command(fnimage.Runtime, "kill", name).Run()
+		return errors.Errorf("function %s was killed after exceeding its timeout of %s", f.Image, f.Timeout)
+	}
+	if exit, ok := err.(*goexec.ExitError); ok && exit.ExitCode() == oomExitCode {
+		msg := fmt.Sprintf("function %s was killed", f.Image)
+		if f.Memory != "" {
+			msg += fmt.Sprintf(", it may have exceeded its memory limit of %s", f.Memory)
+		}
+		return errors.Errorf("%s", msg)
+	}
+	if err != nil {
+		return errors.Errorf("function %s failed: %v: %s", f.Image, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
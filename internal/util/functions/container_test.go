@@ -0,0 +1,118 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerFilter_Args(t *testing.T) {
+	f := &functions.ContainerFilter{Image: "gcr.io/example/fn:v1"}
+	assert.Equal(t, []string{"run", "--rm", "-i", "--name", "fn", "--user", "nobody",
+		"--security-opt=no-new-privileges", "--network", "none", "gcr.io/example/fn:v1"}, f.Args("fn", nil))
+
+	f = &functions.ContainerFilter{Image: "gcr.io/example/fn:v1", Network: true, CPU: "0.5", Memory: "256m"}
+	assert.Equal(t, []string{"run", "--rm", "-i", "--name", "fn", "--user", "nobody",
+		"--security-opt=no-new-privileges", "--cpus", "0.5", "--memory", "256m", "--memory-swap", "256m",
+		"gcr.io/example/fn:v1"}, f.Args("fn", nil))
+
+	f = &functions.ContainerFilter{Image: "gcr.io/example/fn:v1", Network: true,
+		Hosts: []string{"schemas.example.com"}}
+	assert.Equal(t, []string{"run", "--rm", "-i", "--name", "fn", "--user", "nobody",
+		"--security-opt=no-new-privileges", "--dns", "127.0.0.1", "--add-host", "schemas.example.com:203.0.113.7",
+		"gcr.io/example/fn:v1"}, f.Args("fn", []string{"schemas.example.com:203.0.113.7"}))
+}
+
+func TestPinnedHosts(t *testing.T) {
+	hosts, err := functions.PinnedHosts([]string{"localhost", "203.0.113.7"})
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, hosts, "localhost:127.0.0.1")
+	for _, h := range hosts {
+		assert.True(t, strings.HasPrefix(h, "localhost:"), h)
+	}
+}
+
+// setupContainerRuntime sets a fake container runtime running the script
+// instead of containers, and logging the containers killed.
+func setupContainerRuntime(t *testing.T, script string) (string, func()) {
+	d, err := ioutil.TempDir("", "kpt-fn-runtime")
+	testutil.AssertNoError(t, err)
+	log := filepath.Join(d, "log")
+	runtime := filepath.Join(d, "docker")
+	testutil.AssertNoError(t, ioutil.WriteFile(runtime, []byte("#!/bin/sh\n"+
+		"if [ \"$1\" = kill ]; then echo \"kill $2\" >> "+log+"; exit 0; fi\n"+script), 0700))
+	old := fnimage.Runtime
+	fnimage.Runtime = runtime
+	return log, func() {
+		fnimage.Runtime = old
+		os.RemoveAll(d)
+	}
+}
+
+func TestContainerFilter_Run(t *testing.T) {
+	_, clean := setupContainerRuntime(t, "cat\n")
+	defer clean()
+
+	var out bytes.Buffer
+	f := &functions.ContainerFilter{Image: "gcr.io/example/fn:v1"}
+	testutil.AssertNoError(t, f.Run(strings.NewReader("kind: ResourceList\n"), &out))
+	assert.Equal(t, "kind: ResourceList\n", out.String())
+}
+
+// TestContainerFilter_Run_hosts verifies the hosts are resolved when the
+// container runs.
+func TestContainerFilter_Run_hosts(t *testing.T) {
+	log, clean := setupContainerRuntime(t, "echo \"$@\" >> \"$(dirname \"$0\")/log\"\ncat\n")
+	defer clean()
+
+	f := &functions.ContainerFilter{Image: "gcr.io/example/fn:v1", Network: true, Hosts: []string{"localhost"}}
+	testutil.AssertNoError(t, f.Run(strings.NewReader("kind: ResourceList\n"), ioutil.Discard))
+	args, err := ioutil.ReadFile(log)
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(args), "--dns 127.0.0.1 --add-host localhost:127.0.0.1")
+}
+
+func TestContainerFilter_Run_timeout(t *testing.T) {
+	log, clean := setupContainerRuntime(t, "exec sleep 5\n")
+	defer clean()
+
+	f := &functions.ContainerFilter{Image: "gcr.io/example/fn:v1", Timeout: 100 * time.Millisecond}
+	err := f.Run(strings.NewReader(""), ioutil.Discard)
+	assert.EqualError(t, err, "function gcr.io/example/fn:v1 was killed after exceeding its timeout of 100ms")
+	killed, err := ioutil.ReadFile(log)
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(killed), "kill kpt-fn-")
+}
+
+func TestContainerFilter_Run_killed(t *testing.T) {
+	_, clean := setupContainerRuntime(t, "exit 137\n")
+	defer clean()
+
+	f := &functions.ContainerFilter{Image: "gcr.io/example/fn:v1", Memory: "64m"}
+	err := f.Run(strings.NewReader(""), ioutil.Discard)
+	assert.EqualError(t, err,
+		"function gcr.io/example/fn:v1 was killed, it may have exceeded its memory limit of 64m")
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Environment returns the environment of the name declared in the Kptfile
+// of the package at path, or nil if the package has no Kptfile or doesn't
+// declare it.
+func Environment(path, name string) (*kptfile.Environment, error) {
+	if _, err := os.Stat(filepath.Join(path, kptfile.KptFileName)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	k, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return k.GetEnvironment(name), nil
+}
+
+// SetterFunction returns the apply-setters builtin function setting the
+// setters to the values, e.g. the setters of an environment.
+func SetterFunction(values map[string]string) (kptfile.Function, error) {
+	f := kptfile.Function{Runtime: kptfile.BuiltinRuntime, Name: ApplySetters}
+	if err := f.Config.Encode(values); err != nil {
+		return f, errors.Wrap(err)
+	}
+	return f, nil
+}
+
+// EnvironmentFunctions returns the functions hydrating a package for the
+// environment: the apply-setters builtin function setting the setters of
+// the environment, followed by the functions.  The functions of the
+// environment run after fns.
+func EnvironmentFunctions(env kptfile.Environment, fns []kptfile.Function) ([]kptfile.Function, error) {
+	var result []kptfile.Function
+	if len(env.Setters) > 0 {
+		f, err := SetterFunction(env.Setters)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	result = append(result, fns...)
+	return append(result, env.Functions...), nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentFunctions(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "1"
+environments:
+- name: dev
+- name: prod
+  setters:
+    replicas: "5"
+  functions:
+  - runtime: builtin
+    name: set-labels
+    config:
+      env: prod
+`), 0600))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1 # {"$kpt-set":"replicas"}
+`), 0600))
+
+	env, err := functions.Environment(d, "staging")
+	testutil.AssertNoError(t, err)
+	assert.Nil(t, env)
+	env, err = functions.Environment(d, "prod")
+	testutil.AssertNoError(t, err)
+	if !assert.NotNil(t, env) {
+		t.FailNow()
+	}
+
+	f := kptfile.Function{Runtime: kptfile.BuiltinRuntime, Name: functions.SetNamespace}
+	testutil.AssertNoError(t, f.Config.Encode(map[string]string{"namespace": "apps"}))
+	fns, err := functions.EnvironmentFunctions(*env, []kptfile.Function{f})
+	testutil.AssertNoError(t, err)
+	var names []string
+	for _, fn := range fns {
+		names = append(names, fn.Name)
+	}
+	assert.Equal(t, []string{functions.ApplySetters, functions.SetNamespace, functions.SetLabels}, names)
+
+	if !assert.NoError(t, functions.RunFunctions(d, fns, functions.RunOptions{})) {
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(filepath.Join(d, "deploy.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+  labels:
+    env: prod
+spec:
+  replicas: 5 # {"$kpt-set":"replicas"}
+`, string(actual))
+
+	// the environment doesn't change the values of the Kptfile
+	b, err := ioutil.ReadFile(filepath.Join(d, kptfile.KptFileName))
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(b), `value: "1"`)
+
+	dev, err := functions.Environment(d, "dev")
+	testutil.AssertNoError(t, err)
+	fns, err = functions.EnvironmentFunctions(*dev, []kptfile.Function{f})
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, []kptfile.Function{f}, fns)
+}
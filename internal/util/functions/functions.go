@@ -15,10 +15,21 @@
 package functions
 
 import (
+	"io"
+	goexec "os/exec"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/fnpolicy"
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
 	"sigs.k8s.io/kustomize/kyaml/fn/runtime/container"
 	"sigs.k8s.io/kustomize/kyaml/fn/runtime/exec"
 	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
@@ -28,30 +39,265 @@ import (
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
-func RunFunctions(path string, functions []kptfile.Function) error {
+// ExecPolicy decides which exec functions may run local binaries.
+type ExecPolicy struct {
+	// Allow allows running exec functions at all, e.g. with --allow-exec
+	Allow bool
+
+	// Allowlist is the execAllowlist of the Kptfile declaring the functions
+	Allowlist []string
+
+	// Dir is the directory of the package declaring the functions, which
+	// the paths of binaries are relative to
+	Dir string
+}
+
+// Binary returns the path of the binary of the exec function, or an error if
+// the policy doesn't allow running it.
+func (p ExecPolicy) Binary(f kptfile.ExecFunction) (string, error) {
+	if f.Path == "" {
+		return "", errors.Errorf("exec function must have a path")
+	}
+	if !p.Allow {
+		return "", errors.Errorf("exec function %s is only run with --allow-exec", f.Path)
+	}
+	allowed := false
+	for _, pattern := range p.Allowlist {
+		if match, _ := path.Match(pattern, filepath.ToSlash(f.Path)); match {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", errors.Errorf("exec function %s is not in the execAllowlist of the Kptfile", f.Path)
+	}
+	if !strings.ContainsAny(f.Path, `/\`) {
+		binary, err := goexec.LookPath(f.Path)
+		return binary, errors.Wrap(err)
+	}
+	if filepath.IsAbs(f.Path) {
+		return f.Path, nil
+	}
+	return filepath.Join(p.Dir, filepath.FromSlash(f.Path)), nil
+}
+
+// RunOptions are the options the functions of a dependency are run with.
+type RunOptions struct {
+	// ExecPolicy decides which exec functions may run local binaries
+	ExecPolicy ExecPolicy
+
+	// Cache caches the output of each function, so functions are only run
+	// again if their input, image, program or function config changed
+	Cache bool
+
+	// NoFailFast runs all the functions even if some fail, and fails after
+	// reporting the results of all of them
+	NoFailFast bool
+
+	// StdErr is where the results reported by the functions are written
+	StdErr io.Writer
+
+	// Trace records the functions run with their timings, if set
+	Trace *Trace
+}
+
+// RunFunctions runs the functions of a dependency on the package at path.
+// The package is only written if all the functions succeed, and none of them
+// reports results of error severity.
+func RunFunctions(path string, functions []kptfile.Function, opts RunOptions) error {
+	fnPolicy, err := fnpolicy.DefaultPolicy(nil)
+	if err != nil {
+		return err
+	}
+	for _, f := range functions {
+		if f.Image != "" && (f.Runtime == "" || f.Runtime == kptfile.ContainerRuntime) && f.Exec == nil {
+			if err := fnPolicy.Check(f.Image); err != nil {
+				return err
+			}
+		}
+	}
+
+	// verify the function images if the signature policy requires it
+	policy, err := signature.DefaultPolicy()
+	if err != nil {
+		return err
+	}
+	if policy != nil && policy.FunctionImages {
+		for _, f := range functions {
+			if f.Runtime == kptfile.StarlarkRuntime || f.Runtime == kptfile.ExecRuntime ||
+				f.Runtime == kptfile.BuiltinRuntime || f.Exec != nil {
+				continue
+			}
+			if err := policy.VerifyImage(f.Image); err != nil {
+				return err
+			}
+		}
+	}
+
 	rw := &kio.LocalPackageReadWriter{
 		PackagePath:        path,
 		IncludeSubpackages: true,
 	}
 
+	cacheDir := ""
+	if opts.Cache {
+		if cacheDir, err = CacheHome(); err != nil {
+			return err
+		}
+	}
+
+	// the setters are read before the functions change the package
+	var setterValues map[string]string
+	for _, f := range functions {
+		if f.When != nil && f.When.Setter != nil {
+			if setterValues, err = setters.Values(path); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
 	var fltrs []kio.Filter
+	var failures []error
 	for i := range functions {
 		f := functions[i]
-		var e exec.Filter
-		e.FunctionConfig = yaml.NewRNode(&f.Config)
-		fltrs = append(fltrs, &container.Filter{
-			ContainerSpec: runtimeutil.ContainerSpec{
-				Image: f.Image,
-			},
-			Exec: e,
-		})
+		if f.Runtime == "" && f.Exec != nil {
+			f.Runtime = kptfile.ExecRuntime
+		}
+		var fltr kio.Filter
+		var binary string
+		switch f.Runtime {
+		case kptfile.StarlarkRuntime:
+			var config *yaml.RNode
+			if f.Config.Kind != 0 {
+				config = yaml.NewRNode(&f.Config)
+			}
+			if fltr, err = starlarkFilter(path, f.Name, f.Path, f.Source, config); err != nil {
+				return err
+			}
+		case kptfile.ExecRuntime:
+			if f.Exec == nil {
+				return errors.Errorf("exec function must have an exec field")
+			}
+			if binary, err = opts.ExecPolicy.Binary(*f.Exec); err != nil {
+				return err
+			}
+			e := &exec.Filter{Path: binary, Args: f.Exec.Args}
+			if f.Config.Kind != 0 {
+				e.FunctionConfig = yaml.NewRNode(&f.Config)
+			}
+			fltr = e
+		case kptfile.BuiltinRuntime:
+			if fltr, err = builtinFilter(path, f); err != nil {
+				return err
+			}
+		case "", kptfile.ContainerRuntime:
+			if f.Limits != nil || f.Timeout != "" || len(f.Hosts) > 0 {
+				if fltr, err = containerFilter(f); err != nil {
+					return err
+				}
+				break
+			}
+			var e exec.Filter
+			e.FunctionConfig = yaml.NewRNode(&f.Config)
+			fltr = &container.Filter{
+				ContainerSpec: runtimeutil.ContainerSpec{
+					Image:   f.Image,
+					Network: f.Network,
+				},
+				Exec: e,
+			}
+		default:
+			return errors.Errorf("unsupported function runtime %q, must be one of: %s, %s, %s, %s",
+				f.Runtime, kptfile.ContainerRuntime, kptfile.StarlarkRuntime, kptfile.ExecRuntime,
+				kptfile.BuiltinRuntime)
+		}
+		// images are pulled before the config schemas are read from them
+		var pull time.Duration
+		if opts.Trace != nil && (f.Runtime == "" || f.Runtime == kptfile.ContainerRuntime) {
+			start := time.Now()
+			if err := fnimage.EnsureCached(f.Image); err != nil {
+				return err
+			}
+			pull = time.Since(start)
+		}
+		// the config is validated before any function runs
+		schema, err := ConfigSchema(path, f, binary)
+		if err != nil {
+			return err
+		}
+		if schema != nil {
+			if err := ValidateConfig(f, schema); err != nil {
+				return err
+			}
+		}
+		fltr = &resultFilter{function: fltr, name: functionName(f), stdErr: opts.StdErr}
+		if f.Generator {
+			fltr = GeneratorFilter{Function: fltr, Name: functionName(f)}
+		}
+		// builtin functions are cheaper to run than to cache
+		if opts.Cache && f.Runtime != kptfile.BuiltinRuntime {
+			fltr = CachedFilter{Function: fltr, Key: cacheKey(path, f, binary), Dir: cacheDir}
+		}
+		if len(f.Selectors) > 0 || len(f.Exclude) > 0 {
+			fltr = SelectFilter{Function: fltr, Selectors: f.Selectors, Exclude: f.Exclude}
+		}
+		if f.When != nil {
+			fltr = ConditionFilter{Function: fltr, When: *f.When, Setters: setterValues,
+				Name: functionName(f), StdErr: opts.StdErr}
+		}
+		if opts.Trace != nil {
+			fltr = &traceFilter{function: fltr, trace: opts.Trace,
+				step: Step{Package: path, Function: functionName(f), Pull: pull}}
+		}
+		if opts.NoFailFast {
+			fltr = &collectFilter{function: fltr, errs: &failures}
+		}
+		fltrs = append(fltrs, fltr)
 	}
 	if len(fltrs) == 0 {
 		return nil
 	}
 
-	return kio.Pipeline{Inputs: []kio.Reader{rw}, Filters: fltrs, Outputs: []kio.Writer{rw}}.
-		Execute()
+	// the resources created by generators are generated again
+	out := &kio.PackageBuffer{}
+	err = kio.Pipeline{Inputs: []kio.Reader{rw},
+		Filters: append([]kio.Filter{kio.FilterFunc(PruneGenerated)}, fltrs...),
+		Outputs: []kio.Writer{out}}.Execute()
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		msgs := make([]string, len(failures))
+		for i := range failures {
+			msgs[i] = failures[i].Error()
+		}
+		return errors.Errorf("%d of %d functions failed:\n%s", len(failures), len(fltrs),
+			strings.Join(msgs, "\n"))
+	}
+	return errors.Wrap(rw.Write(out.Nodes))
+}
+
+// containerFilter returns the filter running the container function with
+// its limits, timeout and pinned hosts.
+func containerFilter(f kptfile.Function) (*ContainerFilter, error) {
+	fltr := &ContainerFilter{Image: f.Image, Network: f.Network, Hosts: f.Hosts}
+	fltr.FunctionConfig = yaml.NewRNode(&f.Config)
+	if len(f.Hosts) > 0 && !f.Network {
+		return nil, errors.Errorf("function %s must set network to resolve hosts", f.Image)
+	}
+	if f.Limits != nil {
+		fltr.CPU, fltr.Memory = f.Limits.CPU, f.Limits.Memory
+	}
+	if f.Timeout != "" {
+		timeout, err := time.ParseDuration(f.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, errors.Errorf("invalid timeout %q of function %s, must be a duration such as 30s",
+				f.Timeout, f.Image)
+		}
+		fltr.Timeout = timeout
+	}
+	return fltr, nil
 }
 
 // ReconcileFunctions runs functions specified by the Kptfile
@@ -76,10 +322,11 @@ func ReconcileFunctions(path string) error {
 	if len(k.Functions.StarlarkFunctions) > 0 {
 		var fltrs []kio.Filter
 		for _, fn := range k.Functions.StarlarkFunctions {
-			fltrs = append(fltrs, &starlark.Filter{
-				Name: fn.Name,
-				Path: filepath.Join(path, fn.Path),
-			})
+			fltr, err := starlarkFilter(path, fn.Name, fn.Path, fn.Source, nil)
+			if err != nil {
+				return err
+			}
+			fltrs = append(fltrs, fltr)
 		}
 		rw := &kio.LocalPackageReadWriter{PackagePath: path}
 		err = kio.Pipeline{
@@ -94,3 +341,56 @@ func ReconcileFunctions(path string) error {
 
 	return nil
 }
+
+// starlarkFilter returns the filter running the starlark script at path
+// relative to the package at pkgPath, or the inline program source.
+func starlarkFilter(pkgPath, name, path, source string, config *yaml.RNode) (kio.Filter, error) {
+	if (path == "") == (source == "") {
+		return nil, errors.Errorf("starlark function %s must have exactly one of path or source", name)
+	}
+	fltr := &starlark.Filter{Name: name, Program: source}
+	fltr.FunctionConfig = config
+	if path != "" {
+		fltr.Path = filepath.Join(pkgPath, filepath.FromSlash(path))
+	}
+	return fltr, nil
+}
+
+// FunctionConfig returns the ConfigMap passed as function config to the
+// functions run imperatively, with the data.
+func FunctionConfig(data map[string]string) (*yaml.RNode, error) {
+	n, err := yaml.Parse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: function-input\n")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		err := n.PipeE(yaml.LookupCreate(yaml.MappingNode, "data"),
+			yaml.SetField(k, yaml.NewScalarRNode(data[k])))
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+	}
+	return n, nil
+}
+
+// RunImperative runs the function filter on the package at dir, or on the
+// resources read from input if dir is empty.  The resources are written to
+// output if dir is empty or with dryRun, and to the package otherwise.
+func RunImperative(filter kio.Filter, dir string, dryRun bool, input io.Reader, output io.Writer) error {
+	var in kio.Reader = &kio.ByteReader{Reader: input}
+	var out kio.Writer = kio.ByteWriter{Writer: output}
+	if dir != "" {
+		rw := &kio.LocalPackageReadWriter{PackagePath: dir}
+		in = rw
+		if !dryRun {
+			out = rw
+		}
+	}
+	return kio.Pipeline{Inputs: []kio.Reader{in}, Filters: []kio.Filter{filter},
+		Outputs: []kio.Writer{out}}.Execute()
+}
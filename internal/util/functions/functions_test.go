@@ -24,7 +24,9 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/internal/testutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 var tests = []testCase{
@@ -143,6 +145,64 @@ spec:
 		},
 	},
 
+	{
+		name: "starlarkFunctions-inline",
+		inputs: map[string]string{
+			"Kptfile": `
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: my-pkg
+functions:
+  starlarkFunctions:
+  - name: func
+    source: |
+      for resource in ctx.resource_list["items"]:
+        resource["metadata"]["annotations"]["foo"] = "bar"
+`,
+
+			"deploy.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+spec:
+  replicas: 3
+`,
+		},
+		outputs: map[string]string{
+			"deploy.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+  annotations:
+    foo: bar
+spec:
+  replicas: 3
+`,
+		},
+	},
+
+	{
+		name: "starlarkFunctions-path-and-source",
+		inputs: map[string]string{
+			"Kptfile": `
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: my-pkg
+functions:
+  starlarkFunctions:
+  - name: func
+    path: reconcile.star
+    source: |
+      print("hello")
+`,
+		},
+		err: "starlark function func must have exactly one of path or source",
+	},
+
 	// Test 2
 	{
 		name: "autoRunStarlark",
@@ -376,3 +436,109 @@ func TestReconcileFunctions(t *testing.T) {
 		}
 	}
 }
+
+func TestRunFunctions_starlark(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	for filename, value := range map[string]string{
+		"deploy.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+spec:
+  replicas: 3
+`,
+		filepath.Join("functions", "replicas.star"): `
+for resource in ctx.resource_list["items"]:
+  resource["spec"]["replicas"] = int(ctx.resource_list["functionConfig"]["data"]["replicas"])
+`,
+	} {
+		abs := filepath.Join(d, filename)
+		testutil.AssertNoError(t, os.MkdirAll(filepath.Dir(abs), 0700))
+		testutil.AssertNoError(t, ioutil.WriteFile(abs, []byte(value), 0600))
+	}
+
+	var dep kptfile.Dependency
+	testutil.AssertNoError(t, yaml.Unmarshal([]byte(`
+functions:
+- runtime: starlark
+  name: replicas
+  path: functions/replicas.star
+  config:
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: replicas
+    data:
+      replicas: "5"
+- runtime: starlark
+  name: annotate
+  source: |
+    for resource in ctx.resource_list["items"]:
+      resource["metadata"]["annotations"]["foo"] = "bar"
+`), &dep))
+	if !assert.NoError(t, functions.RunFunctions(d, dep.Functions, functions.RunOptions{})) {
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(filepath.Join(d, "deploy.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+  annotations:
+    foo: bar
+spec:
+  replicas: 5
+`, string(actual))
+
+	err = functions.RunFunctions(d, []kptfile.Function{{Runtime: "wasm"}}, functions.RunOptions{})
+	assert.EqualError(t, err, `unsupported function runtime "wasm", must be one of: container, starlark, exec, builtin`)
+}
+
+func TestExecPolicy_Binary(t *testing.T) {
+	policy := functions.ExecPolicy{Allow: true, Allowlist: []string{"bin/*", "kustomize"}, Dir: "pkg"}
+	binary, err := policy.Binary(kptfile.ExecFunction{Path: "bin/set-replicas"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("pkg", "bin", "set-replicas"), binary)
+
+	_, err = policy.Binary(kptfile.ExecFunction{Path: "tools/set-replicas"})
+	assert.EqualError(t, err, "exec function tools/set-replicas is not in the execAllowlist of the Kptfile")
+
+	policy.Allow = false
+	_, err = policy.Binary(kptfile.ExecFunction{Path: "bin/set-replicas"})
+	assert.EqualError(t, err, "exec function bin/set-replicas is only run with --allow-exec")
+}
+
+func TestRunFunctions_exec(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	dep := filepath.Join(d, "dep")
+	testutil.AssertNoError(t, os.MkdirAll(filepath.Join(d, "bin"), 0700))
+	testutil.AssertNoError(t, os.MkdirAll(dep, 0700))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "bin", "set-replicas"),
+		[]byte("#!/bin/sh\nsed \"s/replicas: 3/replicas: $1/\"\n"), 0700))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(dep, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+spec:
+  replicas: 3
+`), 0600))
+
+	fns := []kptfile.Function{{Exec: &kptfile.ExecFunction{Path: "bin/set-replicas", Args: []string{"5"}}}}
+	err = functions.RunFunctions(dep, fns, functions.RunOptions{
+		ExecPolicy: functions.ExecPolicy{Allowlist: []string{"bin/set-replicas"}, Dir: d}})
+	assert.EqualError(t, err, "exec function bin/set-replicas is only run with --allow-exec")
+
+	err = functions.RunFunctions(dep, fns,
+		functions.RunOptions{ExecPolicy: functions.ExecPolicy{Allow: true, Allowlist: []string{"bin/set-replicas"}, Dir: d}})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(filepath.Join(dep, "deploy.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(actual), "replicas: 5")
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// GeneratedByAnnotation is the annotation of the resources created by a
+// generator function, whose value is the name of the function.
+const GeneratedByAnnotation = "kpt.dev/generated-by"
+
+// GeneratorFilter runs a generator function, annotating the resources it
+// creates with the function.
+type GeneratorFilter struct {
+	// Function is the generator function
+	Function kio.Filter
+
+	// Name is the name of the function the resources are annotated with
+	Name string
+}
+
+// Filter runs the function on the nodes, and annotates the nodes it output
+// which weren't input.  Generated nodes without a path are written to a file
+// named after their kind and name.
+func (f GeneratorFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	input := map[string]bool{}
+	for _, n := range nodes {
+		id, err := resourceID(n)
+		if err != nil {
+			return nil, err
+		}
+		input[id] = true
+	}
+	out, err := f.Function.Filter(nodes)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range out {
+		id, err := resourceID(n)
+		if err != nil {
+			return nil, err
+		}
+		if input[id] {
+			continue
+		}
+		if err := n.PipeE(yaml.SetAnnotation(GeneratedByAnnotation, f.Name)); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if meta.Annotations[kioutil.PathAnnotation] == "" {
+			file := strings.ToLower(fmt.Sprintf("%s_%s.yaml", meta.Kind, meta.Name))
+			if err := n.PipeE(yaml.SetAnnotation(kioutil.PathAnnotation, file)); err != nil {
+				return nil, errors.Wrap(err)
+			}
+		}
+	}
+	return out, nil
+}
+
+// PruneGenerated removes the resources created by generator functions, so
+// the functions generate them again and the ones they no longer generate are
+// deleted.
+func PruneGenerated(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	var kept []*yaml.RNode
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if meta.Annotations[GeneratedByAnnotation] == "" {
+			kept = append(kept, n)
+		}
+	}
+	return kept, nil
+}
+
+// resourceID returns the apiVersion, kind, namespace and name of the
+// resource.
+func resourceID(n *yaml.RNode) (string, error) {
+	meta, err := n.GetMeta()
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	return strings.Join([]string{meta.APIVersion, meta.Kind, meta.Namespace, meta.Name}, "/"), nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestGeneratorFilter(t *testing.T) {
+	nodes := []*yaml.RNode{yaml.MustParse(selectorResources[0])}
+	f := functions.GeneratorFilter{
+		Function: kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			return append(nodes, yaml.MustParse(
+				"apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: apps\n")), nil
+		}),
+		Name: "gcr.io/example/generate-service",
+	}
+	out, err := f.Filter(nodes)
+	testutil.AssertNoError(t, err)
+	if !assert.Len(t, out, 2) {
+		t.FailNow()
+	}
+	meta, err := out[0].GetMeta()
+	testutil.AssertNoError(t, err)
+	assert.NotContains(t, meta.Annotations, functions.GeneratedByAnnotation)
+	meta, err = out[1].GetMeta()
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, "gcr.io/example/generate-service", meta.Annotations[functions.GeneratedByAnnotation])
+	assert.Equal(t, "service_web.yaml", meta.Annotations[kioutil.PathAnnotation])
+
+	pruned, err := functions.PruneGenerated(out)
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, out[:1], pruned)
+}
+
+func TestRunFunctions_generator(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-generator")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"),
+		[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"), 0600))
+	// previously generated by a generator which isn't run anymore
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "stale.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: stale
+  annotations:
+    kpt.dev/generated-by: gcr.io/example/generate-config
+`), 0600))
+
+	err = functions.RunFunctions(d, []kptfile.Function{{
+		Runtime:   kptfile.StarlarkRuntime,
+		Name:      "generate-service",
+		Generator: true,
+		Source: `
+service = {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}}
+ctx.resource_list["items"].append(service)
+`,
+	}}, functions.RunOptions{})
+	testutil.AssertNoError(t, err)
+
+	_, err = os.Stat(filepath.Join(d, "stale.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	b, err := ioutil.ReadFile(filepath.Join(d, "service_web.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(b), "kpt.dev/generated-by: generate-service")
+}
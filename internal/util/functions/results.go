@@ -0,0 +1,204 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/container"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/exec"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/starlark"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// The severities of the results reported by functions.  Results without a
+// severity are errors.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Result is a result reported by a function in the results field of the
+// ResourceList it outputs, such as a validation error.
+type Result struct {
+	Message  string `yaml:"message,omitempty" json:"message,omitempty"`
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"`
+
+	// ResourceRef identifies the resource the result is about
+	ResourceRef struct {
+		APIVersion string `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
+		Kind       string `yaml:"kind,omitempty" json:"kind,omitempty"`
+		Name       string `yaml:"name,omitempty" json:"name,omitempty"`
+		Namespace  string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	} `yaml:"resourceRef,omitempty" json:"resourceRef"`
+
+	// File is the file the result is about
+	File struct {
+		Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	} `yaml:"file,omitempty" json:"file"`
+}
+
+// String returns the result as reported to the user, e.g.
+// "[error] Deployment/web (deploy.yaml): replicas must be at least 2".
+func (r Result) String() string {
+	severity := r.Severity
+	if severity == "" {
+		severity = SeverityError
+	}
+	var about []string
+	if r.ResourceRef.Kind != "" || r.ResourceRef.Name != "" {
+		about = append(about, r.ResourceRef.Kind+"/"+r.ResourceRef.Name)
+	}
+	if r.File.Path != "" {
+		about = append(about, "("+r.File.Path+")")
+	}
+	if len(about) == 0 {
+		return fmt.Sprintf("[%s] %s", severity, r.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", severity, strings.Join(about, " "), r.Message)
+}
+
+// resultGroup is a group of results, or a single result.
+type resultGroup struct {
+	Result `yaml:",inline"`
+	Items  []Result `yaml:"items,omitempty"`
+}
+
+// ParseResults parses the results field of a ResourceList, either a list of
+// results, or a list or a single group of results with items.
+func ParseResults(b []byte) ([]Result, error) {
+	var groups []resultGroup
+	if err := yaml.Unmarshal(b, &groups); err != nil {
+		var group resultGroup
+		if err := yaml.Unmarshal(b, &group); err != nil {
+			return nil, errors.Errorf("invalid function results: %v", err)
+		}
+		groups = []resultGroup{group}
+	}
+	var results []Result
+	for _, g := range groups {
+		if g.Message != "" {
+			results = append(results, g.Result)
+		}
+		results = append(results, g.Items...)
+	}
+	return results, nil
+}
+
+// resultFilter runs a function, and writes the results it reports.  It fails
+// if the function reports results of error severity.
+type resultFilter struct {
+	function kio.Filter
+	name     string
+	stdErr   io.Writer
+
+	// reported is true if the function reported results in its last run
+	reported bool
+}
+
+func (f *resultFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	tmp, err := ioutil.TempFile("", "kpt-fn-results-")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	switch fn := f.function.(type) {
+	case *starlark.Filter:
+		fn.ResultsFile = tmp.Name()
+	case *exec.Filter:
+		fn.ResultsFile = tmp.Name()
+	case *container.Filter:
+		fn.Exec.ResultsFile = tmp.Name()
+	case *ContainerFilter:
+		fn.ResultsFile = tmp.Name()
+	}
+
+	out, runErr := f.function.Filter(nodes)
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var results []Result
+	if len(strings.TrimSpace(string(b))) > 0 {
+		if results, err = ParseResults(b); err != nil {
+			return nil, errors.Errorf("function %s: %v", f.name, err)
+		}
+	}
+	f.reported = len(results) > 0
+	failed := 0
+	for _, r := range results {
+		if r.Severity == "" || r.Severity == SeverityError {
+			failed++
+		}
+		if f.stdErr != nil {
+			fmt.Fprintf(f.stdErr, "%s: %s\n", f.name, r)
+		}
+	}
+	switch {
+	case runErr != nil:
+		return nil, runErr
+	case failed > 0:
+		return nil, errors.Errorf("function %s reported %d results of error severity", f.name, failed)
+	}
+	return out, nil
+}
+
+// uncacheable returns true if the function reported results, whose output is
+// not cached so the results are reported each time it is run.
+func (f *resultFilter) uncacheable() bool {
+	return f.reported
+}
+
+// collectFilter records the failure of a function instead of failing, and
+// passes the resources through unchanged, so the other functions still run.
+type collectFilter struct {
+	function kio.Filter
+	errs     *[]error
+}
+
+func (f *collectFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	out, err := f.function.Filter(nodes)
+	if err != nil {
+		*f.errs = append(*f.errs, err)
+		return nodes, nil
+	}
+	return out, nil
+}
+
+// functionName returns the name of the function reported with its results.
+func functionName(f kptfile.Function) string {
+	switch {
+	case f.Exec != nil:
+		return f.Exec.Path
+	case f.Runtime == kptfile.StarlarkRuntime && f.Name != "":
+		return f.Name
+	case f.Runtime == kptfile.StarlarkRuntime && f.Path != "":
+		return f.Path
+	case f.Runtime == kptfile.StarlarkRuntime:
+		return "starlark"
+	case f.Runtime == kptfile.BuiltinRuntime:
+		return f.Name
+	}
+	return f.Image
+}
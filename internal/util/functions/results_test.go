@@ -0,0 +1,131 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResults(t *testing.T) {
+	results, err := functions.ParseResults([]byte(`
+- message: replicas must be at least 2
+  severity: error
+  resourceRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+  file:
+    path: deploy.yaml
+- name: lint
+  items:
+  - message: missing labels
+    severity: warning
+  - message: checked 3 resources
+    severity: info
+`))
+	testutil.AssertNoError(t, err)
+	var actual []string
+	for _, r := range results {
+		actual = append(actual, r.String())
+	}
+	assert.Equal(t, []string{
+		"[error] Deployment/web (deploy.yaml): replicas must be at least 2",
+		"[warning] missing labels",
+		"[info] checked 3 resources",
+	}, actual)
+
+	results, err = functions.ParseResults([]byte(`
+name: lint
+items:
+- message: no severity
+`))
+	testutil.AssertNoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "[error] no severity", results[0].String())
+	}
+}
+
+func TestRunFunctions_results(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	dep := filepath.Join(d, "dep")
+	log := filepath.Join(d, "log")
+	testutil.AssertNoError(t, os.MkdirAll(filepath.Join(d, "bin"), 0700))
+	testutil.AssertNoError(t, os.MkdirAll(dep, 0700))
+	scripts := map[string]string{
+		"warn":         "cat\nprintf 'results:\\n- message: replicas is low\\n  severity: warning\\n'\n",
+		"fail":         "cat\nprintf 'results:\\n- message: replicas must be at least 5\\n  severity: error\\n'\n",
+		"set-replicas": "echo set-replicas >> " + log + "\nsed \"s/replicas: 3/replicas: 5/\"\n",
+	}
+	for name, script := range scripts {
+		testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "bin", name), []byte("#!/bin/sh\n"+script), 0700))
+	}
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+spec:
+  replicas: 3
+`
+	run := func(noFailFast bool, names ...string) (string, string, error) {
+		testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(dep, "deploy.yaml"), []byte(deployment), 0600))
+		os.Remove(log)
+		var fns []kptfile.Function
+		for _, name := range names {
+			fns = append(fns, kptfile.Function{Exec: &kptfile.ExecFunction{Path: "bin/" + name}})
+		}
+		var stdErr bytes.Buffer
+		err := functions.RunFunctions(dep, fns, functions.RunOptions{
+			ExecPolicy: functions.ExecPolicy{Allow: true, Allowlist: []string{"bin/*"}, Dir: d},
+			NoFailFast: noFailFast,
+			StdErr:     &stdErr,
+		})
+		actual, rerr := ioutil.ReadFile(filepath.Join(dep, "deploy.yaml"))
+		testutil.AssertNoError(t, rerr)
+		ran, _ := ioutil.ReadFile(log)
+		assert.Equal(t, err == nil, strings.Contains(string(actual), "replicas: 5"),
+			"the package is only written if all the functions succeed")
+		return stdErr.String(), string(ran), err
+	}
+
+	// warnings are reported without failing
+	stdErr, ran, err := run(false, "warn", "set-replicas")
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, "bin/warn: [warning] replicas is low\n", stdErr)
+	assert.Equal(t, "set-replicas\n", ran)
+
+	// the functions after the first failing one aren't run
+	stdErr, ran, err = run(false, "fail", "warn", "set-replicas")
+	assert.EqualError(t, err, "function bin/fail reported 1 results of error severity")
+	assert.Equal(t, "bin/fail: [error] replicas must be at least 5\n", stdErr)
+	assert.Empty(t, ran)
+
+	// without fail fast all the functions are run before failing
+	stdErr, ran, err = run(true, "fail", "warn", "set-replicas")
+	assert.EqualError(t, err, "1 of 3 functions failed:\nfunction bin/fail reported 1 results of error severity")
+	assert.Equal(t, "bin/fail: [error] replicas must be at least 5\nbin/warn: [warning] replicas is low\n", stdErr)
+	assert.Equal(t, "set-replicas\n", ran)
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ConfigSchemaLabel is the label of the function images publishing the
+// OpenAPI schema of their functionConfig, as JSON or YAML.
+const ConfigSchemaLabel = "dev.kpt.fn.config-schema"
+
+// ConfigSchemaSuffix is the suffix of the sidecar files of exec functions and
+// starlark scripts publishing the OpenAPI schema of their functionConfig,
+// e.g. my-fn.schema.json for my-fn.  Unlike .yaml files, the sidecar files
+// within packages are not read as resources.
+const ConfigSchemaSuffix = ".schema.json"
+
+// ConfigSchema returns the OpenAPI schema published by the function for its
+// functionConfig, or nil if it doesn't publish one.  binary is the path of
+// the binary of exec functions.
+func ConfigSchema(pkgPath string, f kptfile.Function, binary string) (*spec.Schema, error) {
+	var b []byte
+	switch f.Runtime {
+	case kptfile.BuiltinRuntime:
+		// builtin functions validate their config themselves
+		return nil, nil
+	case kptfile.ExecRuntime, kptfile.StarlarkRuntime:
+		path := binary
+		if f.Runtime == kptfile.StarlarkRuntime {
+			if f.Path == "" {
+				// inline programs have no sidecar file
+				return nil, nil
+			}
+			path = filepath.Join(pkgPath, filepath.FromSlash(f.Path))
+		}
+		var err error
+		b, err = ioutil.ReadFile(path + ConfigSchemaSuffix)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+	default:
+		label, err := fnimage.Label(f.Image, ConfigSchemaLabel)
+		if err != nil {
+			return nil, err
+		}
+		b = []byte(label)
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return nil, nil
+	}
+	j, err := toJSON(b)
+	if err != nil {
+		return nil, errors.Errorf("invalid config schema of function %s: %v", functionName(f), err)
+	}
+	schema := &spec.Schema{}
+	if err := schema.UnmarshalJSON(j); err != nil {
+		return nil, errors.Errorf("invalid config schema of function %s: %v", functionName(f), err)
+	}
+	return schema, nil
+}
+
+// ValidateConfig validates the functionConfig of the function against the
+// schema.  A function without functionConfig is validated as if its
+// functionConfig was empty.
+func ValidateConfig(f kptfile.Function, schema *spec.Schema) error {
+	data := []byte("{}")
+	if f.Config.Kind != 0 {
+		s, err := yaml.NewRNode(&f.Config).String()
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if data, err = toJSON([]byte(s)); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	var config interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return errors.Wrap(err)
+	}
+	result := validate.NewSchemaValidator(schema, nil, "", strfmt.Default).Validate(config)
+	if result.IsValid() {
+		return nil
+	}
+	var msgs []string
+	for _, err := range result.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return errors.Errorf("invalid config of function %s:\n  - %s", functionName(f), strings.Join(msgs, "\n  - "))
+}
+
+// toJSON converts YAML, or JSON, to JSON.
+func toJSON(b []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
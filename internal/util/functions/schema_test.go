@@ -0,0 +1,115 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const replicasSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {"replicas": {"type": "integer"}}
+}
+`
+
+// execFunction returns an exec function with the config, whose binary
+// publishes replicasSchema.
+func execFunction(t *testing.T, dir, config string) kptfile.Function {
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "fn"), []byte("#!/bin/sh\ncat\n"), 0700))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "fn"+functions.ConfigSchemaSuffix),
+		[]byte(replicasSchema), 0600))
+	f := kptfile.Function{Runtime: kptfile.ExecRuntime, Exec: &kptfile.ExecFunction{Path: "./fn"}}
+	if config != "" {
+		n, err := yaml.Parse(config)
+		testutil.AssertNoError(t, err)
+		f.Config = *n.YNode()
+	}
+	return f
+}
+
+func TestConfigSchema(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-schema")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	f := execFunction(t, d, "")
+
+	schema, err := functions.ConfigSchema(d, f, filepath.Join(d, "fn"))
+	testutil.AssertNoError(t, err)
+	if assert.NotNil(t, schema) {
+		assert.Contains(t, schema.Properties, "replicas")
+	}
+
+	// functions without a sidecar file don't publish a schema
+	schema, err = functions.ConfigSchema(d, f, filepath.Join(d, "other"))
+	testutil.AssertNoError(t, err)
+	assert.Nil(t, schema)
+}
+
+func TestValidateConfig(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-schema")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+
+	for config, invalid := range map[string]string{
+		"replicas: 3\n":    "",
+		"":                 "",
+		"replcas: 3\n":     "replcas",
+		"replicas: many\n": "replicas",
+	} {
+		f := execFunction(t, d, config)
+		schema, err := functions.ConfigSchema(d, f, filepath.Join(d, "fn"))
+		testutil.AssertNoError(t, err)
+		err = functions.ValidateConfig(f, schema)
+		if invalid == "" {
+			assert.NoError(t, err, config)
+			continue
+		}
+		if assert.Error(t, err, config) {
+			assert.Contains(t, err.Error(), "invalid config of function ./fn:\n  - ")
+			assert.Contains(t, err.Error(), invalid)
+		}
+	}
+}
+
+func TestRunFunctions_invalidConfig(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-schema")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"),
+		[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"), 0600))
+	f := execFunction(t, d, "replcas: 3\n")
+	// the function isn't run with an invalid config
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "fn"),
+		[]byte("#!/bin/sh\ntouch "+filepath.Join(d, "ran")+"\ncat\n"), 0700))
+
+	err = functions.RunFunctions(d, []kptfile.Function{f}, functions.RunOptions{
+		ExecPolicy: functions.ExecPolicy{Allow: true, Allowlist: []string{"./fn"}, Dir: d},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "replcas")
+	}
+	_, err = os.Stat(filepath.Join(d, "ran"))
+	assert.True(t, os.IsNotExist(err))
+}
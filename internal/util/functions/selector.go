@@ -0,0 +1,116 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"path"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// SelectFilter runs a function only on the resources matching any of its
+// Selectors and none of its Exclude selectors.  The other resources are
+// passed through unchanged, so the function can neither see nor change them.
+type SelectFilter struct {
+	// Function is the function run on the selected resources
+	Function kio.Filter
+
+	// Selectors select the resources the function is run on.  All the
+	// resources are selected if empty.
+	Selectors []kptfile.Selector
+
+	// Exclude excludes resources from the selected resources
+	Exclude []kptfile.Selector
+}
+
+// Filter runs the function on the selected nodes.
+func (f SelectFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	var selected, unselected []*yaml.RNode
+	for _, n := range nodes {
+		match, err := Selects(f.Selectors, f.Exclude, n)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			selected = append(selected, n)
+		} else {
+			unselected = append(unselected, n)
+		}
+	}
+	out, err := f.Function.Filter(selected)
+	if err != nil {
+		return nil, err
+	}
+	return append(unselected, out...), nil
+}
+
+// Selects returns true if the resource matches any of the selectors, or
+// there are none, and doesn't match any of the exclude selectors.
+func Selects(selectors, exclude []kptfile.Selector, n *yaml.RNode) (bool, error) {
+	for _, s := range exclude {
+		match, err := Matches(s, n)
+		if err != nil || match {
+			return false, err
+		}
+	}
+	if len(selectors) == 0 {
+		return true, nil
+	}
+	for _, s := range selectors {
+		match, err := Matches(s, n)
+		if err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
+
+// Matches returns true if the resource matches all the fields of the
+// selector which are set.
+func Matches(s kptfile.Selector, n *yaml.RNode) (bool, error) {
+	meta, err := n.GetMeta()
+	if err != nil {
+		return false, errors.Wrap(err)
+	}
+	switch {
+	case s.APIVersion != "" && s.APIVersion != meta.APIVersion,
+		s.Kind != "" && s.Kind != meta.Kind,
+		s.Name != "" && s.Name != meta.Name,
+		s.Namespace != "" && s.Namespace != meta.Namespace:
+		return false, nil
+	}
+	for k, v := range s.Labels {
+		if value, found := meta.Labels[k]; !found || value != v {
+			return false, nil
+		}
+	}
+	if s.Path == "" {
+		return true, nil
+	}
+	p, _, err := kioutil.GetFileAnnotations(n)
+	if err != nil {
+		return false, errors.Wrap(err)
+	}
+	match, err := path.Match(s.Path, filepath.ToSlash(p))
+	if err != nil {
+		return false, errors.Errorf("invalid selector path %s: %v", s.Path, err)
+	}
+	return match, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var selectorResources = []string{`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+  labels:
+    tier: frontend
+  annotations:
+    config.kubernetes.io/path: apps/web.yaml
+`, `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: apps
+  annotations:
+    config.kubernetes.io/path: apps/web.yaml
+`, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: db
+  namespace: data
+  labels:
+    tier: backend
+  annotations:
+    config.kubernetes.io/path: data/db.yaml
+`}
+
+func TestSelects(t *testing.T) {
+	var tests = []struct {
+		name      string
+		selectors []kptfile.Selector
+		exclude   []kptfile.Selector
+		expected  []bool
+	}{
+		{
+			name:     "all",
+			expected: []bool{true, true, true},
+		},
+		{
+			name:      "kind",
+			selectors: []kptfile.Selector{{APIVersion: "apps/v1", Kind: "Deployment"}},
+			expected:  []bool{true, false, true},
+		},
+		{
+			name:      "name and namespace",
+			selectors: []kptfile.Selector{{Name: "web", Namespace: "apps"}},
+			expected:  []bool{true, true, false},
+		},
+		{
+			name:      "labels",
+			selectors: []kptfile.Selector{{Labels: map[string]string{"tier": "backend"}}},
+			expected:  []bool{false, false, true},
+		},
+		{
+			name:      "path",
+			selectors: []kptfile.Selector{{Path: "apps/*"}},
+			expected:  []bool{true, true, false},
+		},
+		{
+			name:      "any selector",
+			selectors: []kptfile.Selector{{Kind: "Service"}, {Name: "db"}},
+			expected:  []bool{false, true, true},
+		},
+		{
+			name:     "exclude",
+			exclude:  []kptfile.Selector{{Kind: "Service"}},
+			expected: []bool{true, false, true},
+		},
+		{
+			name:      "selectors and exclude",
+			selectors: []kptfile.Selector{{Kind: "Deployment"}},
+			exclude:   []kptfile.Selector{{Namespace: "data"}},
+			expected:  []bool{true, false, false},
+		},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			for j, r := range selectorResources {
+				match, err := functions.Selects(test.selectors, test.exclude, yaml.MustParse(r))
+				testutil.AssertNoError(t, err)
+				assert.Equal(t, test.expected[j], match, r)
+			}
+		})
+	}
+}
+
+func TestMatches_invalidPath(t *testing.T) {
+	_, err := functions.Matches(kptfile.Selector{Path: "["}, yaml.MustParse(selectorResources[0]))
+	assert.EqualError(t, err, "invalid selector path [: syntax error in pattern")
+}
+
+func TestSelectFilter(t *testing.T) {
+	var nodes []*yaml.RNode
+	for _, r := range selectorResources {
+		nodes = append(nodes, yaml.MustParse(r))
+	}
+	var seen []string
+	f := functions.SelectFilter{
+		Function: kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			for _, n := range nodes {
+				meta, err := n.GetMeta()
+				if err != nil {
+					return nil, err
+				}
+				seen = append(seen, meta.Kind+"/"+meta.Name)
+			}
+			return nodes[:0], nil
+		}),
+		Selectors: []kptfile.Selector{{Kind: "Deployment"}},
+	}
+	out, err := f.Filter(nodes)
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, []string{"Deployment/web", "Deployment/db"}, seen)
+	if assert.Len(t, out, 1) {
+		meta, err := out[0].GetMeta()
+		testutil.AssertNoError(t, err)
+		assert.Equal(t, "Service", meta.Kind)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ResultsAnnotation is the annotation the results reported by functions
+// about a resource are injected as, a JSON list of the results.
+const ResultsAnnotation = "kpt.dev/fn-results"
+
+// ResultsFile is a file of the results reported by a function, written to
+// the results directory by kpt fn run.
+type ResultsFile struct {
+	// Name is the name of the file without its extension, e.g. results-0
+	Name string
+
+	Results []Result
+}
+
+// ReadResultsDir reads the results-N.yaml files of dir, in the order the
+// functions were run.
+func ReadResultsDir(dir string) ([]ResultsFile, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "results-*.yaml"))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	index := func(path string) int {
+		var n int
+		_, _ = fmt.Sscanf(filepath.Base(path), "results-%d.yaml", &n)
+		return n
+	}
+	sort.Slice(matches, func(i, j int) bool { return index(matches[i]) < index(matches[j]) })
+
+	var files []ResultsFile
+	for _, path := range matches {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		file := ResultsFile{Name: strings.TrimSuffix(filepath.Base(path), ".yaml")}
+		if len(strings.TrimSpace(string(b))) > 0 {
+			if file.Results, err = ParseResults(b); err != nil {
+				return nil, errors.Errorf("%s: %v", path, err)
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// WriteResultsJSON replaces the results-N.yaml files of dir with
+// results-N.json files.
+func WriteResultsJSON(dir string, files []ResultsFile) error {
+	for _, file := range files {
+		results := file.Results
+		if results == nil {
+			results = []Result{}
+		}
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		path := filepath.Join(dir, file.Name)
+		if err := ioutil.WriteFile(path+".json", append(b, '\n'), 0600); err != nil {
+			return errors.Wrap(err)
+		}
+		if err := os.Remove(path + ".yaml"); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// resultEvent is a result streamed as a JSON event.
+type resultEvent struct {
+	// Results is the name of the results file of the function
+	Results string `json:"results"`
+
+	Result
+}
+
+// StreamResults writes the results to w as newline-delimited JSON events,
+// one for each result.
+func StreamResults(w io.Writer, files []ResultsFile) error {
+	e := json.NewEncoder(w)
+	for _, file := range files {
+		for _, r := range file.Results {
+			if err := e.Encode(resultEvent{Results: file.Name, Result: r}); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+// AnnotateResults injects the results as the ResultsAnnotation of the
+// resources of the package at pkgPath they are about, replacing the results
+// injected by previous runs.  Results which aren't about a resource or a
+// file are not injected.
+func AnnotateResults(pkgPath string, results []Result) error {
+	rw := &kio.LocalPackageReadWriter{PackagePath: pkgPath}
+	return kio.Pipeline{
+		Inputs: []kio.Reader{rw},
+		Filters: []kio.Filter{kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+			for _, n := range nodes {
+				var about []Result
+				for _, r := range results {
+					match, err := resultAbout(r, n)
+					if err != nil {
+						return nil, err
+					}
+					if match {
+						about = append(about, r)
+					}
+				}
+				if len(about) == 0 {
+					if err := n.PipeE(yaml.ClearAnnotation(ResultsAnnotation)); err != nil {
+						return nil, errors.Wrap(err)
+					}
+					continue
+				}
+				b, err := json.Marshal(about)
+				if err != nil {
+					return nil, errors.Wrap(err)
+				}
+				if err := n.PipeE(yaml.SetAnnotation(ResultsAnnotation, string(b))); err != nil {
+					return nil, errors.Wrap(err)
+				}
+			}
+			return nodes, nil
+		})},
+		Outputs: []kio.Writer{rw},
+	}.Execute()
+}
+
+// resultAbout returns true if the result is about the resource.
+func resultAbout(r Result, n *yaml.RNode) (bool, error) {
+	ref := r.ResourceRef
+	if ref.Kind == "" && ref.Name == "" && r.File.Path == "" {
+		return false, nil
+	}
+	return Matches(kptfile.Selector{APIVersion: ref.APIVersion, Kind: ref.Kind,
+		Name: ref.Name, Namespace: ref.Namespace, Path: r.File.Path}, n)
+}
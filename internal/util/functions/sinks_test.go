@@ -0,0 +1,135 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupResultsDir returns a results directory with the results of 11
+// functions, of which only two reported results.
+func setupResultsDir(t *testing.T) string {
+	d, err := ioutil.TempDir("", "kpt-fn-results")
+	testutil.AssertNoError(t, err)
+	for i := 0; i < 11; i++ {
+		testutil.AssertNoError(t, ioutil.WriteFile(
+			filepath.Join(d, fmt.Sprintf("results-%d.yaml", i)), nil, 0600))
+	}
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "results-1.yaml"), []byte(`
+- message: replicas must be at least 2
+  resourceRef:
+    kind: Deployment
+    name: web
+`), 0600))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "results-10.yaml"), []byte(`
+- message: missing labels
+  severity: warning
+  file:
+    path: service.yaml
+`), 0600))
+	return d
+}
+
+func TestReadResultsDir(t *testing.T) {
+	d := setupResultsDir(t)
+	defer os.RemoveAll(d)
+
+	files, err := functions.ReadResultsDir(d)
+	testutil.AssertNoError(t, err)
+	if !assert.Len(t, files, 11) {
+		t.FailNow()
+	}
+	// results-10 was written after results-2
+	assert.Equal(t, "results-2", files[2].Name)
+	assert.Equal(t, "results-10", files[10].Name)
+	assert.Len(t, files[0].Results, 0)
+	if assert.Len(t, files[1].Results, 1) {
+		assert.Equal(t, "[error] Deployment/web: replicas must be at least 2", files[1].Results[0].String())
+	}
+}
+
+func TestStreamResults(t *testing.T) {
+	d := setupResultsDir(t)
+	defer os.RemoveAll(d)
+	files, err := functions.ReadResultsDir(d)
+	testutil.AssertNoError(t, err)
+
+	var out bytes.Buffer
+	testutil.AssertNoError(t, functions.StreamResults(&out, files))
+	assert.Equal(t,
+		`{"results":"results-1","message":"replicas must be at least 2","resourceRef":{"kind":"Deployment","name":"web"},"file":{}}
+{"results":"results-10","message":"missing labels","severity":"warning","resourceRef":{},"file":{"path":"service.yaml"}}
+`, out.String())
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	d := setupResultsDir(t)
+	defer os.RemoveAll(d)
+	files, err := functions.ReadResultsDir(d)
+	testutil.AssertNoError(t, err)
+
+	testutil.AssertNoError(t, functions.WriteResultsJSON(d, files))
+	_, err = os.Stat(filepath.Join(d, "results-1.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	b, err := ioutil.ReadFile(filepath.Join(d, "results-1.json"))
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(b), `"message": "replicas must be at least 2"`)
+	b, err = ioutil.ReadFile(filepath.Join(d, "results-0.json"))
+	testutil.AssertNoError(t, err)
+	assert.Equal(t, "[]\n", string(b))
+}
+
+func TestAnnotateResults(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-fn-results")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`), 0600))
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "service.yaml"), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    kpt.dev/fn-results: '[{"message":"stale"}]'
+`), 0600))
+
+	var r functions.Result
+	r.Message = "replicas must be at least 2"
+	r.ResourceRef.Kind = "Deployment"
+	r.ResourceRef.Name = "web"
+	testutil.AssertNoError(t, functions.AnnotateResults(d, []functions.Result{r, {Message: "not about a resource"}}))
+
+	b, err := ioutil.ReadFile(filepath.Join(d, "deploy.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.Contains(t, string(b), "kpt.dev/fn-results:")
+	assert.Contains(t, string(b), `{"message":"replicas must be at least 2","resourceRef":{"kind":"Deployment","name":"web"},"file":{}}`)
+	assert.NotContains(t, string(b), "not about a resource")
+	// the results of previous runs are replaced
+	b, err = ioutil.ReadFile(filepath.Join(d, "service.yaml"))
+	testutil.AssertNoError(t, err)
+	assert.NotContains(t, string(b), "kpt.dev/fn-results")
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Trace records the functions run on packages with their timings, to find
+// the slow functions of pipelines.  A Trace may be shared by the
+// dependencies synced in parallel.
+type Trace struct {
+	mu sync.Mutex
+
+	// Steps are the functions run, in the order they finished
+	Steps []Step `json:"steps"`
+}
+
+// Step is a function run on a package.  Durations are in nanoseconds in the
+// JSON trace.
+type Step struct {
+	// Package is the path of the package the function was run on
+	Package string `json:"package"`
+
+	Function string `json:"function"`
+
+	Start time.Time `json:"start"`
+
+	// Duration is how long the function ran, excluding pulling its image
+	Duration time.Duration `json:"duration"`
+
+	// Pull is how long pulling the image of the function took, or 0 if it
+	// was in the local cache
+	Pull time.Duration `json:"pull"`
+
+	// Input and Output are the numbers of resources input to the function
+	// and output by it
+	Input  int `json:"input"`
+	Output int `json:"output"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func (t *Trace) add(s Step) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Steps = append(t.Steps, s)
+}
+
+// WriteJSON writes the trace as JSON.
+func (t *Trace) WriteJSON(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.Wrap(e.Encode(t))
+}
+
+// WriteTimings writes a table of the timings of the steps.
+func (t *Trace) WriteTimings(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFUNCTION\tPULL\tRUN\tRESOURCES\t")
+	var total time.Duration
+	for _, s := range t.Steps {
+		resources := fmt.Sprintf("%d -> %d", s.Input, s.Output)
+		if s.Error != "" {
+			resources = "failed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", s.Package, s.Function,
+			s.Pull.Round(time.Millisecond), s.Duration.Round(time.Millisecond), resources)
+		total += s.Pull + s.Duration
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t%s\t\t\n", total.Round(time.Millisecond))
+	return errors.Wrap(tw.Flush())
+}
+
+// traceFilter records the step of running the function in the trace.
+type traceFilter struct {
+	function kio.Filter
+	step     Step
+	trace    *Trace
+}
+
+func (f *traceFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	step := f.step
+	step.Start = time.Now()
+	step.Input = len(nodes)
+	out, err := f.function.Filter(nodes)
+	step.Duration = time.Since(step.Start)
+	step.Output = len(out)
+	if err != nil {
+		step.Error = err.Error()
+	}
+	f.trace.add(step)
+	return out, err
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestRunFunctions_trace(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	testutil.AssertNoError(t, err)
+	defer os.RemoveAll(d)
+	testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"),
+		[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"), 0600))
+
+	trace := &functions.Trace{}
+	err = functions.RunFunctions(d, []kptfile.Function{
+		{Runtime: kptfile.BuiltinRuntime, Name: functions.SetNamespace,
+			Config: *yaml.MustParse("namespace: apps\n").YNode()},
+		{Runtime: kptfile.StarlarkRuntime, Name: "drop", Source: `ctx.resource_list["items"] = []`},
+	}, functions.RunOptions{Trace: trace})
+	testutil.AssertNoError(t, err)
+
+	if assert.Len(t, trace.Steps, 2) {
+		assert.Equal(t, functions.Step{Package: d, Function: "set-namespace", Input: 1, Output: 1},
+			withoutTimes(trace.Steps[0]))
+		assert.Equal(t, functions.Step{Package: d, Function: "drop", Input: 1, Output: 0},
+			withoutTimes(trace.Steps[1]))
+	}
+
+	b := &bytes.Buffer{}
+	testutil.AssertNoError(t, trace.WriteTimings(b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Regexp(t, `^PACKAGE +FUNCTION +PULL +RUN +RESOURCES$`, lines[0])
+		assert.Regexp(t, `^`+regexp.QuoteMeta(d)+` +set-namespace +0s +\S+ +1 -> 1$`, lines[1])
+		assert.Regexp(t, `^`+regexp.QuoteMeta(d)+` +drop +0s +\S+ +1 -> 0$`, lines[2])
+		assert.Regexp(t, `^TOTAL +\S+$`, lines[3])
+	}
+
+	b.Reset()
+	testutil.AssertNoError(t, trace.WriteJSON(b))
+	var decoded functions.Trace
+	testutil.AssertNoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, trace.Steps[1].Duration, decoded.Steps[1].Duration)
+}
+
+func withoutTimes(s functions.Step) functions.Step {
+	s.Start, s.Duration = time.Time{}, 0
+	return s
+}
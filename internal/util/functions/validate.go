@@ -0,0 +1,141 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	kptopenapi "github.com/GoogleContainerTools/kpt/internal/util/openapi"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// looseDefinitions are the definitions of the Kubernetes OpenAPI schema
+// which are declared as strings, but are commonly written as numbers.
+var looseDefinitions = []string{
+	"io.k8s.apimachinery.pkg.util.intstr.IntOrString",
+	"io.k8s.apimachinery.pkg.api.resource.Quantity",
+}
+
+// SchemaValidator validates resources against the OpenAPI schemas of their
+// types.  The schemas are the ones of kyaml, which are read from the cluster
+// or from a file with --k8s-schema-source.
+type SchemaValidator struct {
+	// Strict fails the validation of resources whose type has no schema,
+	// instead of skipping them
+	Strict bool
+}
+
+// Filter returns the nodes if they are all valid, or an error listing the
+// invalid fields of the resources.
+func (v SchemaValidator) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	root := rootSchema()
+	var msgs []string
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		id := meta.Kind + " " + meta.Name
+		if meta.Namespace != "" {
+			id = meta.Kind + " " + meta.Namespace + "/" + meta.Name
+		}
+		s := openapi.SchemaForResourceType(meta.TypeMeta)
+		if s == nil {
+			if v.Strict {
+				msgs = append(msgs, fmt.Sprintf("%s: no schema found for %s", id, meta.APIVersion))
+			}
+			continue
+		}
+		str, err := n.String()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		b, err := toJSON([]byte(str))
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		var obj interface{}
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		result := validate.NewSchemaValidator(s.Schema, root, "", strfmt.Default).Validate(obj)
+		for _, err := range result.Errors {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", id, err))
+		}
+	}
+	if len(msgs) > 0 {
+		return nil, errors.Errorf("resources do not match their schemas:\n  - %s",
+			strings.Join(msgs, "\n  - "))
+	}
+	return nodes, nil
+}
+
+// rootSchema returns a copy of the kyaml schema the references of the
+// resource schemas are resolved in, with the loose definitions accepting
+// numbers too.
+func rootSchema() *spec.Schema {
+	root := *openapi.Schema()
+	root.Definitions = spec.Definitions{}
+	for name, def := range openapi.Schema().Definitions {
+		root.Definitions[name] = def
+	}
+	for _, name := range looseDefinitions {
+		if def, found := root.Definitions[name]; found {
+			def.Type = spec.StringOrArray{"string", "integer", "number"}
+			def.Format = ""
+			root.Definitions[name] = def
+		}
+	}
+	return &root
+}
+
+// validateSchemas validates the resources against the schemas of their
+// types, including the CustomResourceDefinitions of the package and of the
+// files and directories of crd-path, separated by commas.
+func validateSchemas(pkgPath string, config map[string]string) (kio.Filter, error) {
+	dirs := []string{pkgPath}
+	for _, p := range strings.Split(config["crd-path"], ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		p = filepath.Join(pkgPath, filepath.FromSlash(p))
+		if _, err := os.Stat(p); err != nil {
+			return nil, errors.Errorf("invalid crd-path of builtin function %s: %v", ValidateSchemas, err)
+		}
+		dirs = append(dirs, p)
+	}
+	if err := kptopenapi.AddCRDSchemas(dirs...); err != nil {
+		return nil, err
+	}
+	v := SchemaValidator{}
+	if s := config["strict"]; s != "" {
+		var err error
+		if v.Strict, err = strconv.ParseBool(s); err != nil {
+			return nil, errors.Errorf("invalid strict of builtin function %s: %v", ValidateSchemas, err)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package functions_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+const validateCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: databases.example.com
+spec:
+  group: example.com
+  names:
+    kind: Database
+    plural: databases
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+`
+
+func TestRunFunctions_validateSchemas(t *testing.T) {
+	var tests = []struct {
+		name      string
+		resources string
+		config    map[string]string
+		expected  string
+	}{
+		{
+			name: "valid",
+			resources: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+        resources:
+          limits:
+            cpu: 1
+---
+apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: db
+spec:
+  size: 10
+`,
+		},
+		{
+			name: "invalid field",
+			resources: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  replicas: three
+`,
+			expected: "Deployment apps/web: spec.replicas in body must be of type integer",
+		},
+		{
+			name: "invalid custom resource",
+			resources: `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: db
+spec:
+  size: large
+`,
+			expected: "Database db: spec.size in body must be of type integer",
+		},
+		{
+			name: "unknown type",
+			resources: `apiVersion: example.com/v1
+kind: Cache
+metadata:
+  name: cache
+`,
+		},
+		{
+			name: "unknown type strict",
+			resources: `apiVersion: example.com/v1
+kind: Cache
+metadata:
+  name: cache
+`,
+			config:   map[string]string{"strict": "true"},
+			expected: "Cache cache: no schema found for example.com/v1",
+		},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "kpt")
+			testutil.AssertNoError(t, err)
+			defer os.RemoveAll(d)
+			testutil.AssertNoError(t, os.MkdirAll(filepath.Join(d, "crds"), 0700))
+			testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "crds", "crd.yaml"),
+				[]byte(validateCRD), 0600))
+			testutil.AssertNoError(t, ioutil.WriteFile(filepath.Join(d, "resources.yaml"),
+				[]byte(test.resources), 0600))
+
+			f := kptfile.Function{Runtime: kptfile.BuiltinRuntime, Name: functions.ValidateSchemas}
+			config := map[string]string{"crd-path": "crds"}
+			for k, v := range test.config {
+				config[k] = v
+			}
+			testutil.AssertNoError(t, f.Config.Encode(config))
+
+			err = functions.RunFunctions(d, []kptfile.Function{f}, functions.RunOptions{})
+			if test.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+		})
+	}
+}
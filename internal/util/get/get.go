@@ -27,18 +27,36 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	"github.com/GoogleContainerTools/kpt/internal/util/helm"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/semver"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"sigs.k8s.io/kustomize/kyaml/copyutil"
 	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/filters"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
-// Command fetches a package from a git repository and copies it to a local directory.
+// Command fetches a package from a git repository or an OCI registry and copies it
+// to a local directory.
 type Command struct {
 	// Git contains information about the git repo to fetch
 	kptfile.Git
 
+	// OCI contains information about the OCI image to pull.  Takes precedence over Git
+	// if its image is set.
+	OCI kptfile.OCI
+
+	// Helm contains information about the Helm chart to inflate.  Takes precedence
+	// over Git if its chart is set.
+	Helm kptfile.Helm
+
+	// Values are the values files used to inflate the Helm chart
+	Values []string
+
 	// Destination is the output directory to clone the package to.  Defaults to the name of the package --
 	// either the base repo name, or the base subdirectory name.
 	Destination string
@@ -48,6 +66,22 @@ type Command struct {
 
 	// Remove directory before copying to it.
 	Clean bool
+
+	// Frozen replaces the package at the destination only if it resolves to the
+	// same commits and digests as recorded in the LockFile of the destination.
+	Frozen bool
+
+	// Depth is the number of commits fetched from the git repo.  The full
+	// history is fetched if 0.
+	Depth int
+
+	// Sparse fetches only the files of the package directory from the git repo.
+	Sparse bool
+
+	// SignaturePolicy, if set, is the policy the package must be signed
+	// according to.  The signature is verified before the package is copied
+	// to the destination.
+	SignaturePolicy *signature.Policy
 }
 
 // Run runs the Command.
@@ -55,18 +89,42 @@ func (c Command) Run() error {
 	if err := (&c).DefaultValues(); err != nil {
 		return err
 	}
+	if c.Frozen {
+		return c.runFrozen()
+	}
 
 	if _, err := os.Stat(c.Destination); !c.Clean && !os.IsNotExist(err) {
 		return errors.Errorf("destination directory %s already exists", c.Destination)
 	}
 
+	if c.OCI.Image != "" {
+		return c.pullOCI()
+	}
+	if c.Helm.Chart != "" {
+		if c.SignaturePolicy != nil {
+			return errors.Errorf("verifying the signature of helm charts is not supported")
+		}
+		return c.inflateHelm()
+	}
+
 	// normalize path to a filepath
 	if !strings.HasSuffix(c.Directory, "file://") {
 		c.Directory = filepath.Join(path.Split(c.Directory))
 	}
 
+	// resolve a version constraint to the latest matching tag -- the Kptfile
+	// keeps the constraint so the package can be updated within it
+	ref := c.Ref
+	if semver.IsConstraint(c.Ref) {
+		var err error
+		if ref, err = semver.ResolveGit(c.Repo, c.Directory, c.Ref); err != nil {
+			return err
+		}
+	}
+
 	// define where we are going to clone the package from
-	r := &git.RepoSpec{OrgRepo: c.Repo, Path: c.Directory, Ref: c.Ref}
+	r := &git.RepoSpec{OrgRepo: c.Repo, Path: c.Directory, Ref: ref, Depth: c.Depth, Sparse: c.Sparse,
+		Submodules: c.Submodules}
 
 	defaultRef, err := gitutil.DefaultRef(c.Repo)
 	if err != nil {
@@ -81,6 +139,12 @@ func (c Command) Run() error {
 	}
 	defer os.RemoveAll(r.Dir)
 
+	if c.SignaturePolicy != nil {
+		if err := c.SignaturePolicy.VerifyPackage(r.AbsPath()); err != nil {
+			return err
+		}
+	}
+
 	// delete the existing package if it exists
 	if c.Clean {
 		err = os.RemoveAll(c.Destination)
@@ -96,13 +160,149 @@ func (c Command) Run() error {
 			r.Path, r.OrgRepo, r.Ref)
 	}
 
+	// find the git commit sha that we cloned the package at so we can write it to the KptFile
+	cmd := exec.Command("git", "rev-parse", "--verify", "HEAD")
+	cmd.Dir = r.AbsPath()
+	cmd.Env = os.Environ()
+	cmd.Stderr = os.Stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	upstream := kptfile.Upstream{Type: kptfile.GitOrigin, Git: c.Git}
+	upstream.Git.Commit = strings.TrimSpace(string(b))
+
 	// create or update the KptFile with the values from git
-	if err = (&c).upsertKptfile(r); err != nil {
+	if err = (&c).upsertKptfile(upstream); err != nil {
+		return errors.Wrap(err)
+	}
+	if ref != c.Ref {
+		return kptfileutil.LockResolved(c.Destination, ref)
+	}
+	return nil
+}
+
+// runFrozen fetches the package to a temporary directory, and replaces the
+// destination with it only if the package and its subpackages resolve to the
+// upstreams recorded in the LockFile of the destination.
+func (c Command) runFrozen() error {
+	expected, err := kptfileutil.ReadLockFile(c.Destination)
+	if err != nil {
+		return errors.Errorf("--frozen requires a %s in %s: %v", kptfile.LockFileName, c.Destination, err)
+	}
+	dir, err := ioutil.TempDir("", "kpt-get-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	staged := c
+	staged.Frozen = false
+	staged.Clean = false
+	staged.Destination = filepath.Join(dir, filepath.Base(c.Destination))
+	if err := staged.Run(); err != nil {
+		return err
+	}
+	actual, err := kptfileutil.Lock(staged.Destination)
+	if err != nil {
+		return err
+	}
+	if diffs := kptfileutil.DiffLock(expected, actual); len(diffs) > 0 {
+		return errors.Errorf("package resolves differently than %s:\n  %s",
+			kptfile.LockFileName, strings.Join(diffs, "\n  "))
+	}
+
+	if err := os.RemoveAll(c.Destination); err != nil {
 		return errors.Wrap(err)
 	}
+	if err := copyutil.CopyDir(staged.Destination, c.Destination); err != nil {
+		return errors.Wrap(err)
+	}
+	return kptfileutil.WriteLockFile(c.Destination, expected)
+}
+
+// pullOCI pulls the package from the OCI registry and copies it to the destination.
+func (c Command) pullOCI() error {
+	tag := c.OCI.Ref
+	if semver.IsConstraint(c.OCI.Ref) {
+		var err error
+		if tag, err = semver.ResolveOCI(c.OCI.Image, c.OCI.Ref); err != nil {
+			return err
+		}
+	}
+	ref, err := oci.NewReference(c.OCI.Image, tag)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "kpt-get-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	digest, err := oci.Pull(ref, dir)
+	if err != nil {
+		return errors.Errorf("failed to pull oci image: %v", err)
+	}
+	if c.SignaturePolicy != nil {
+		ref.Ref = digest
+		if err := c.SignaturePolicy.VerifyImage(ref.String()); err != nil {
+			return err
+		}
+	}
+
+	// delete the existing package if it exists
+	if c.Clean {
+		if err := os.RemoveAll(c.Destination); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	if err := copyutil.CopyDir(dir, c.Destination); err != nil {
+		return errors.Wrap(err)
+	}
+
+	// record the digest so updates start from the exact manifest that was pulled
+	upstream := kptfile.Upstream{Type: kptfile.OCIOrigin, OCI: c.OCI}
+	upstream.OCI.Digest = digest
+	if err := (&c).upsertKptfile(upstream); err != nil {
+		return errors.Wrap(err)
+	}
+	if tag != c.OCI.Ref {
+		return kptfileutil.LockResolved(c.Destination, tag)
+	}
 	return nil
 }
 
+// inflateHelm renders the Helm chart and writes the resources to the destination,
+// one file per resource.
+func (c Command) inflateHelm() error {
+	b, err := helm.Template(c.Helm, c.Name, c.Values)
+	if err != nil {
+		return err
+	}
+
+	// delete the existing package if it exists
+	if c.Clean {
+		if err := os.RemoveAll(c.Destination); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	if err := os.MkdirAll(c.Destination, 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	err = kio.Pipeline{
+		Inputs: []kio.Reader{&kio.ByteReader{Reader: bytes.NewReader(b)}},
+		Filters: []kio.Filter{
+			&filters.FileSetter{FilenamePattern: filters.DefaultFilenamePattern},
+			filters.FormatFilter{},
+		},
+		Outputs: []kio.Writer{kio.LocalPackageWriter{PackagePath: c.Destination}},
+	}.Execute()
+	if err != nil {
+		return errors.Errorf("failed to write helm chart resources: %v", err)
+	}
+	return errors.Wrap((&c).upsertKptfile(kptfile.Upstream{Type: kptfile.HelmOrigin, Helm: c.Helm}))
+}
+
 // Cloner is a function that can clone a git repo.
 type Cloner func(repoSpec *git.RepoSpec) error
 
@@ -137,12 +337,42 @@ func ClonerUsingGitExec(repoSpec *git.RepoSpec, defaultRef string) error {
 	return nil
 }
 
+// sparseCheckout configures the repo to only check out the files of the
+// package directory if the repoSpec is sparse.  The .gitmodules file is
+// checked out as well if the submodules are initialized.
+func sparseCheckout(repoSpec *git.RepoSpec) error {
+	dir := strings.Trim(filepath.ToSlash(filepath.Clean(repoSpec.Path)), "/")
+	if !repoSpec.Sparse || dir == "" || dir == "." {
+		return nil
+	}
+	cmd := exec.Command("git", "config", "core.sparseCheckout", "true")
+	cmd.Dir = repoSpec.Dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.WrapPrefixf(err, "trouble configuring sparse checkout: %s", out)
+	}
+	info := filepath.Join(repoSpec.Dir, ".git", "info")
+	if err := os.MkdirAll(info, 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	patterns := "/" + dir + "/\n"
+	if repoSpec.Submodules {
+		patterns += "/.gitmodules\n"
+	}
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(info, "sparse-checkout"), []byte(patterns), 0600))
+}
+
 func clonerUsingGitExec(repoSpec *git.RepoSpec) error {
 	gitProgram, err := exec.LookPath("git")
 	if err != nil {
 		return errors.WrapPrefixf(err, "no 'git' program on path")
 	}
 
+	env, err := gitutil.AuthEnv(repoSpec.CloneSpec())
+	if err != nil {
+		return err
+	}
+	env = append(os.Environ(), env...)
+
 	repoSpec.Dir, err = ioutil.TempDir("", "kpt-get-")
 	if err != nil {
 		return err
@@ -177,71 +407,136 @@ func clonerUsingGitExec(repoSpec *git.RepoSpec) error {
 		}
 	}
 
-	err = func() error {
-		cmd = exec.Command(gitProgram, "fetch", "origin", "--depth=1", repoSpec.Ref)
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		cmd.Dir = repoSpec.Dir
-		err = cmd.Run()
-		if err != nil {
-			return errors.WrapPrefixf(err, "trouble fetching %s, "+
-				"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials", repoSpec.Ref)
-		}
-		cmd = exec.Command(gitProgram, "reset", "--hard", "FETCH_HEAD")
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		cmd.Dir = repoSpec.Dir
-		err = cmd.Run()
-		if err != nil {
-			return errors.WrapPrefixf(
-				err, "trouble hard resetting empty repository to %s", repoSpec.Ref)
+	if err := sparseCheckout(repoSpec); err != nil {
+		return err
+	}
+
+	// fetch the ref into the cache of the repo so only the changes since it
+	// was last fetched are fetched.  Partial clones are not cached.
+	fetchDir := repoSpec.Dir
+	if !repoSpec.Sparse {
+		if fetchDir, err = gitutil.CachedRepo(repoSpec.CloneSpec()); err != nil {
+			return err
 		}
-		return nil
-	}()
+	}
+	commit, err := fetchRef(gitProgram, fetchDir, env, repoSpec)
 	if err != nil {
-		cmd = exec.Command(gitProgram, "fetch", "origin")
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		cmd.Dir = repoSpec.Dir
-		if err = cmd.Run(); err != nil {
-			return errors.WrapPrefixf(err, "trouble fetching origin, "+
-				"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials")
+		return err
+	}
+	if fetchDir != repoSpec.Dir {
+		if err := gitutil.KeepFetched(fetchDir, repoSpec.Ref, commit); err != nil {
+			return err
 		}
-		cmd = exec.Command(gitProgram, "reset", "--hard", repoSpec.Ref)
-		cmd.Stdout = &out
-		cmd.Stderr = &out
-		cmd.Dir = repoSpec.Dir
-		if err = cmd.Run(); err != nil {
-			return errors.WrapPrefixf(
-				err, "trouble hard resetting empty repository to %s, "+
-					"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials", repoSpec.Ref)
+		if err := gitutil.UseCachedObjects(repoSpec.Dir, fetchDir); err != nil {
+			return err
 		}
 	}
 
-	cmd = exec.Command(gitProgram, "submodule", "update", "--init", "--recursive")
+	cmd = exec.Command(gitProgram, "reset", "--hard", commit)
 	cmd.Stdout = &out
+	cmd.Stderr = &out
 	cmd.Dir = repoSpec.Dir
-	err = cmd.Run()
-	if err != nil {
-		return errors.WrapPrefixf(err, "trouble fetching submodules for %s, "+
-			"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials", repoSpec.Ref)
+	if err = cmd.Run(); err != nil {
+		return errors.WrapPrefixf(
+			err, "trouble hard resetting empty repository to %s", repoSpec.Ref)
 	}
 
+	return updateSubmodules(gitProgram, env, repoSpec)
+}
+
+// updateSubmodules initializes the submodules of the package directory,
+// recursively, if the repoSpec initializes submodules.
+func updateSubmodules(gitProgram string, env []string, repoSpec *git.RepoSpec) error {
+	if !repoSpec.Submodules {
+		return nil
+	}
+	args := []string{"submodule", "update", "--init", "--recursive"}
+	if dir := strings.Trim(filepath.ToSlash(filepath.Clean(repoSpec.Path)), "/"); dir != "" && dir != "." {
+		args = append(args, "--", dir)
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(gitProgram, args...)
+	cmd.Env = env
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.Dir = repoSpec.Dir
+	if err := cmd.Run(); err != nil {
+		return errors.WrapPrefixf(err, "trouble fetching submodules for %s: %s, "+
+			"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials",
+			repoSpec.Ref, strings.TrimSpace(out.String()))
+	}
 	return nil
 }
 
+// fetchRef fetches the ref of the repoSpec into the git repo at dir, and
+// returns the commit it resolves to.
+func fetchRef(gitProgram, dir string, env []string, repoSpec *git.RepoSpec) (string, error) {
+	fetchArgs := []string{"fetch", "origin"}
+	if repoSpec.Sparse {
+		fetchArgs = append(fetchArgs, "--filter=blob:none")
+	}
+	var out bytes.Buffer
+	run := func(args ...string) error {
+		out.Reset()
+		cmd := exec.Command(gitProgram, args...)
+		cmd.Env = env
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		cmd.Dir = dir
+		return cmd.Run()
+	}
+
+	args := append([]string{}, fetchArgs...)
+	if repoSpec.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", repoSpec.Depth))
+	} else if _, err := os.Stat(filepath.Join(dir, "shallow")); err == nil {
+		// deepen the history fetched to the cache
+		args = append(args, "--unshallow")
+	}
+	rev := "FETCH_HEAD"
+	if err := run(append(args, repoSpec.Ref)...); err != nil {
+		// fallback on fetching the origin -- some versions of git have an
+		// issue with fetching a commit by sha
+		if err = run(fetchArgs...); err != nil {
+			return "", errors.WrapPrefixf(err, "trouble fetching origin, "+
+				"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials")
+		}
+		rev = repoSpec.Ref
+	}
+	if err := run("rev-parse", "--verify", rev+"^{commit}"); err != nil {
+		return "", errors.WrapPrefixf(err, "trouble resolving %s, "+
+			"please run 'git clone <REPO>; stat <DIR/SUBDIR>' to verify credentials", repoSpec.Ref)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
 // DefaultValues sets values to the default values if they were unspecified
 func (c *Command) DefaultValues() error {
-	if len(c.Repo) == 0 {
+	if len(c.Helm.Chart) > 0 {
+		if len(c.Helm.Repo) == 0 {
+			return errors.Errorf("must specify chart repo")
+		}
+		if len(c.Helm.Version) == 0 {
+			return errors.Errorf("must specify chart version")
+		}
+	} else if len(c.Values) > 0 {
+		return errors.Errorf("values may only be specified for helm charts")
+	}
+	if len(c.OCI.Image) > 0 {
+		if len(c.OCI.Ref) == 0 {
+			c.OCI.Ref = oci.DefaultTag
+		}
+	} else if len(c.Helm.Chart) == 0 && len(c.Repo) == 0 {
 		return errors.Errorf("must specify repo")
 	}
-	if len(c.Ref) == 0 {
+	isGit := len(c.OCI.Image) == 0 && len(c.Helm.Chart) == 0
+	if isGit && len(c.Ref) == 0 {
 		return errors.Errorf("must specify ref")
 	}
 	if len(c.Destination) == 0 {
 		return errors.Errorf("must specify destination")
 	}
-	if len(c.Directory) == 0 {
+	if isGit && len(c.Directory) == 0 {
 		return errors.Errorf("must specify remote subdirectory")
 	}
 
@@ -254,8 +549,8 @@ func (c *Command) DefaultValues() error {
 }
 
 // upsertKptfile populates the KptFile values, merging any cloned KptFile and the
-// cloneFrom values.
-func (c *Command) upsertKptfile(spec *git.RepoSpec) error {
+// upstream values.
+func (c *Command) upsertKptfile(upstream kptfile.Upstream) error {
 	// read KptFile cloned with the package if it exists
 	kpgfile, err := kptfileutil.ReadFile(c.Destination)
 	if err != nil {
@@ -275,22 +570,7 @@ func (c *Command) upsertKptfile(spec *git.RepoSpec) error {
 		}
 	}
 
-	// find the git commit sha that we cloned the package at so we can write it to the KptFile
-	cmd := exec.Command("git", "rev-parse", "--verify", "HEAD")
-	cmd.Dir = spec.AbsPath()
-	cmd.Env = os.Environ()
-	cmd.Stderr = os.Stderr
-	b, err := cmd.Output()
-	if err != nil {
-		return err
-	}
-	commit := strings.TrimSpace(string(b))
-
-	// populate the cloneFrom values so we know where the package came from
-	kpgfile.Upstream = kptfile.Upstream{
-		Type: kptfile.GitOrigin,
-		Git:  c.Git,
-	}
-	kpgfile.Upstream.Git.Commit = commit
+	// populate the upstream values so we know where the package came from
+	kpgfile.Upstream = upstream
 	return kptfileutil.WriteFile(c.Destination, kpgfile)
 }
@@ -16,12 +16,18 @@ package get_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/testutil"
 	. "github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/helm"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
@@ -85,6 +91,83 @@ func TestCommand_Run(t *testing.T) {
 	})
 }
 
+// TestCommand_Run_frozen verifies that a locked package is only replaced if it
+// resolves to the locked commit.
+func TestCommand_Run_frozen(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	get := Command{Git: kptfile.Git{
+		Repo:      "file://" + g.RepoDirectory,
+		Ref:       "master",
+		Directory: "/",
+	},
+		Destination: filepath.Base(g.RepoDirectory)}
+	if !assert.NoError(t, get.Run()) {
+		t.FailNow()
+	}
+	r := filepath.Join(w.WorkspaceDirectory, g.RepoName)
+	lock, err := kptfileutil.Lock(r)
+	assert.NoError(t, err)
+	assert.NoError(t, kptfileutil.WriteLockFile(r, lock))
+
+	// the package is fetched again while the branch is unchanged
+	get.Frozen = true
+	assert.NoError(t, get.Run())
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1), r)
+
+	// the package is left unchanged once the branch moves
+	if !assert.NoError(t, g.ReplaceData(testutil.Dataset2)) {
+		t.FailNow()
+	}
+	testutil.Commit(t, g, "new data")
+	err = get.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "package resolves differently than Kptfile.lock")
+	}
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1), r)
+	read, err := kptfileutil.ReadLockFile(r)
+	assert.NoError(t, err)
+	assert.Equal(t, lock, read)
+}
+
+// TestCommand_Run_constraint verifies that a version constraint is resolved to
+// the latest matching tag, which is recorded in the lock file.
+func TestCommand_Run_constraint(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	testutil.Tag(t, g, "v1.0.0")
+	commit, err := g.GetCommit()
+	assert.NoError(t, err)
+	if !assert.NoError(t, g.ReplaceData(testutil.Dataset2)) {
+		t.FailNow()
+	}
+	testutil.CommitTag(t, g, "v2.0.0")
+
+	err = Command{Git: kptfile.Git{
+		Repo:      "file://" + g.RepoDirectory,
+		Ref:       "^1",
+		Directory: "/",
+	},
+		Destination: filepath.Base(g.RepoDirectory)}.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	r := filepath.Join(w.WorkspaceDirectory, g.RepoName)
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1), r)
+	kf, err := kptfileutil.ReadFile(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "^1", kf.Upstream.Git.Ref)
+	assert.Equal(t, commit, kf.Upstream.Git.Commit)
+	lock, err := kptfileutil.ReadLockFile(r)
+	assert.NoError(t, err)
+	if assert.Len(t, lock.Packages, 1) {
+		assert.Equal(t, "v1.0.0", lock.Packages[0].Git.Ref)
+		assert.Equal(t, commit, lock.Packages[0].Git.Commit)
+	}
+}
+
 // TestCommand_Run_subdir verifies that Command will clone a subdirectory of a repo.
 //
 // - destination dir should match the name of the subdirectory
@@ -131,6 +214,145 @@ func TestCommand_Run_subdir(t *testing.T) {
 	})
 }
 
+// TestCommand_Run_sparse verifies Command fetches a subdirectory using a sparse
+// checkout and the full history.
+func TestCommand_Run_sparse(t *testing.T) {
+	subdir := "java"
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	err := Command{Git: kptfile.Git{
+		Repo: g.RepoDirectory, Ref: "refs/heads/master", Directory: subdir},
+		Destination: filepath.Base(subdir),
+		Sparse:      true,
+	}.Run()
+	assert.NoError(t, err)
+
+	// verify the cloned contents matches the repository
+	r := filepath.Join(w.WorkspaceDirectory, subdir)
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1, subdir), r)
+
+	commit, err := g.GetCommit()
+	assert.NoError(t, err)
+	kf, err := kptfileutil.ReadFile(r)
+	assert.NoError(t, err)
+	assert.Equal(t, commit, kf.Upstream.Git.Commit)
+}
+
+// TestCommand_Run_submodules verifies Command initializes the submodules of
+// the package directory only if submodules are enabled.
+func TestCommand_Run_submodules(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	// git does not clone submodules from local paths by default
+	for k, v := range map[string]string{
+		"GIT_CONFIG_COUNT":   "1",
+		"GIT_CONFIG_KEY_0":   "protocol.file.allow",
+		"GIT_CONFIG_VALUE_0": "always",
+	} {
+		assert.NoError(t, os.Setenv(k, v))
+		defer os.Unsetenv(k)
+	}
+
+	// add a repo as a submodule of the java package
+	sub, err := ioutil.TempDir("", "kpt-submodule-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(sub)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(sub, "lib.yaml"), []byte("kind: ConfigMap\n"), 0600))
+	r := gitutil.NewLocalGitRunner(sub)
+	for _, args := range [][]string{{"init"}, {"add", "."}, {"commit", "-m", "lib"}} {
+		if !assert.NoError(t, r.Run(args...)) {
+			t.FailNow()
+		}
+	}
+	if !assert.NoError(t, gitutil.NewLocalGitRunner(g.RepoDirectory).Run(
+		"submodule", "add", sub, filepath.Join("java", "lib"))) {
+		t.FailNow()
+	}
+	testutil.Commit(t, g, "add submodule")
+
+	for _, sparse := range []bool{false, true} {
+		dest := fmt.Sprintf("java-%v", sparse)
+		err = Command{Git: kptfile.Git{
+			Repo: g.RepoDirectory, Ref: "master", Directory: "java", Submodules: true},
+			Destination: dest,
+			Sparse:      sparse,
+		}.Run()
+		assert.NoError(t, err)
+		b, err := ioutil.ReadFile(filepath.Join(w.WorkspaceDirectory, dest, "lib", "lib.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, "kind: ConfigMap\n", string(b))
+
+		kf, err := kptfileutil.ReadFile(filepath.Join(w.WorkspaceDirectory, dest))
+		assert.NoError(t, err)
+		assert.True(t, kf.Upstream.Git.Submodules)
+	}
+
+	err = Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: "java"},
+		Destination: "java"}.Run()
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.WorkspaceDirectory, "java", "lib", "lib.yaml"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestCommand_Run_cache verifies packages sharing an upstream share the cache
+// of the fetched repo.
+func TestCommand_Run_cache(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	for _, subdir := range []string{"java", "mysql"} {
+		err := Command{Git: kptfile.Git{
+			Repo: g.RepoDirectory, Ref: "master", Directory: subdir},
+			Destination: subdir,
+		}.Run()
+		assert.NoError(t, err)
+		g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1, subdir),
+			filepath.Join(w.WorkspaceDirectory, subdir))
+	}
+
+	entries, err := gitutil.ListCache()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Contains(t, entries[0].URI, g.RepoName)
+	}
+}
+
+func TestCommand_Run_verifySignature(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	var verified []string
+	cosign := signature.Cosign
+	signature.Cosign = func(args ...string) ([]byte, error) {
+		verified = append(verified, args[0])
+		return nil, nil
+	}
+	defer func() { signature.Cosign = cosign }()
+	policy := &signature.Policy{Keys: []string{"/cosign.pub"}}
+
+	// the package is not signed, so it is not fetched
+	err := Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: "java"},
+		Destination: "java", SignaturePolicy: policy}.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "is not signed: no Kptfile.sig")
+	}
+	assert.NoDirExists(t, filepath.Join(w.WorkspaceDirectory, "java"))
+
+	err = ioutil.WriteFile(filepath.Join(g.RepoDirectory, "java", signature.File), []byte("{}"), 0600)
+	assert.NoError(t, err)
+	assert.NoError(t, gitutil.NewLocalGitRunner(g.RepoDirectory).Run("add", "."))
+	testutil.Commit(t, g, "sign java")
+	err = Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: "java"},
+		Destination: "java", SignaturePolicy: policy}.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verify-blob"}, verified)
+	assert.FileExists(t, filepath.Join(w.WorkspaceDirectory, "java", signature.File))
+}
+
 // TestCommand_Run_destination verifies Command clones the repo to a destination with a specific name rather
 // than using the name of the source repo.
 func TestCommand_Run_destination(t *testing.T) {
@@ -659,4 +881,63 @@ func TestCommand_DefaultValues_AtVersion(t *testing.T) {
 
 	c = Command{Git: kptfile.Git{Repo: "foo", Directory: "/", Ref: "r"}}
 	assert.EqualError(t, c.DefaultValues(), "must specify destination")
+
+	// OCI packages need neither a repo nor a subdirectory, and default to the latest tag
+	c = Command{OCI: kptfile.OCI{Image: "ghcr.io/org/pkg"}, Destination: "/"}
+	assert.NoError(t, c.DefaultValues())
+	assert.Equal(t, "latest", c.OCI.Ref)
+
+	// Helm charts must specify a version
+	c = Command{Helm: kptfile.Helm{Repo: "https://charts.example.com", Chart: "app"}, Destination: "/"}
+	assert.EqualError(t, c.DefaultValues(), "must specify chart version")
+	c.Helm.Version = "1.0.0"
+	assert.NoError(t, c.DefaultValues())
+
+	c = Command{Git: kptfile.Git{Repo: "foo", Directory: "/", Ref: "r"}, Destination: "/", Values: []string{"v.yaml"}}
+	assert.EqualError(t, c.DefaultValues(), "values may only be specified for helm charts")
+}
+
+// TestCommand_Run_helm verifies that the rendered chart is written one resource
+// per file, with a Kptfile recording the chart.
+func TestCommand_Run_helm(t *testing.T) {
+	defer func(f func(kptfile.Helm, string, []string) ([]byte, error)) { helm.Template = f }(helm.Template)
+	helm.Template = func(chart kptfile.Helm, releaseName string, values []string) ([]byte, error) {
+		assert.Equal(t, "my-app", releaseName)
+		assert.Equal(t, []string{"values.yaml"}, values)
+		return []byte(`---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: my-app
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+`), nil
+	}
+
+	dir, err := ioutil.TempDir("", "kpt-get-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "my-app")
+
+	chart := kptfile.Helm{Repo: "https://charts.example.com", Chart: "app", Version: "1.0.0"}
+	err = Command{Helm: chart, Values: []string{"values.yaml"}, Destination: dest}.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, name := range []string{"my-app_service.yaml", "my-app_deployment.yaml"} {
+		_, err := os.Stat(filepath.Join(dest, name))
+		assert.NoError(t, err, name)
+	}
+	kf, err := kptfileutil.ReadFile(dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-app", kf.Name)
+	assert.Equal(t, kptfile.Upstream{Type: kptfile.HelmOrigin, Helm: chart}, kf.Upstream)
 }
@@ -18,8 +18,14 @@ package git
 import (
 	"path/filepath"
 	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
 )
 
+// DefaultDepth is the number of commits kpt pkg get and kpt pkg update fetch
+// unless --depth is specified.
+const DefaultDepth = 1
+
 // RepoSpec specifies a git repository and a branch and path therein.
 type RepoSpec struct {
 	// Host, e.g. github.com
@@ -41,6 +47,26 @@ type RepoSpec struct {
 
 	// e.g. .git or empty in case of _git is present
 	GitSuffix string
+
+	// Depth is the number of commits to fetch.  The full history is fetched
+	// if 0.
+	Depth int
+
+	// Sparse fetches only the files of Path, using a partial clone without
+	// blobs and a sparse checkout of Path.
+	Sparse bool
+
+	// Submodules initializes the submodules of Path, recursively.
+	Submodules bool
+}
+
+// ValidateDepth returns an error if the --depth flag, the number of commits
+// to fetch, is negative.
+func ValidateDepth(depth int) error {
+	if depth < 0 {
+		return errors.Errorf("--depth must not be negative")
+	}
+	return nil
 }
 
 // AbsPath is the absolute path to the subdirectory
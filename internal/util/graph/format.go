@@ -0,0 +1,147 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Formats are the formats the graph can be written in.
+var Formats = []string{"dot", "json", "mermaid"}
+
+// Write writes the graph to w in the format.
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "json":
+		return g.WriteJSON(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	}
+	return errors.Errorf("unsupported output format %q, must be one of: %s",
+		format, strings.Join(Formats, ", "))
+}
+
+// WriteJSON writes the graph as JSON.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.Wrap(e.Encode(g))
+}
+
+// WriteDOT writes the graph in the DOT language of graphviz.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	b := &strings.Builder{}
+	b.WriteString("digraph packages {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		shape := "box"
+		if n.Kind == UpstreamNode {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(b, "  %q [label=%q, shape=%s];\n", n.ID, n.label(), shape)
+	}
+	for _, e := range g.Edges {
+		style := ""
+		if e.Kind != SubpackageEdge && e.Kind != UpstreamEdge {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(b, "  %q -> %q [label=%q%s];\n", e.From, e.To, e.label(), style)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return errors.Wrap(err)
+}
+
+// WriteMermaid writes the graph as a mermaid flowchart.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	// mermaid ids are restricted, so nodes are numbered
+	ids := map[string]string{}
+	b := &strings.Builder{}
+	b.WriteString("graph LR\n")
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		left, right := "[", "]"
+		if n.Kind == UpstreamNode {
+			left, right = "([", "])"
+		}
+		fmt.Fprintf(b, "  %s%s\"%s\"%s\n", ids[n.ID], left, mermaidEscape(n.label()), right)
+	}
+	for _, e := range g.Edges {
+		from, found := ids[e.From]
+		if !found {
+			// the base of a variant may be outside of the workspace
+			from = fmt.Sprintf("n%d", len(ids))
+			ids[e.From] = from
+			fmt.Fprintf(b, "  %s[\"%s\"]\n", from, mermaidEscape(e.From))
+		}
+		arrow := "-->"
+		if e.Kind != SubpackageEdge && e.Kind != UpstreamEdge {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(b, "  %s %s|\"%s\"| %s\n", from, arrow, mermaidEscape(e.label()), ids[e.To])
+	}
+	_, err := io.WriteString(w, b.String())
+	return errors.Wrap(err)
+}
+
+// label returns the label of the node, which is the package path and name,
+// or the upstream uri.
+func (n Node) label() string {
+	if n.Kind == PackageNode && n.Name != "" && n.Name != lastElem(n.ID) {
+		return fmt.Sprintf("%s (%s)", n.ID, n.Name)
+	}
+	return n.ID
+}
+
+// label returns the label of the edge, which is its kind followed by the
+// version information of upstream and dependency edges.
+func (e Edge) label() string {
+	parts := []string{string(e.Kind)}
+	if e.Ref != "" {
+		parts = append(parts, "@"+e.Ref)
+	}
+	if e.Version != "" {
+		parts = append(parts, shortVersion(e.Version))
+	}
+	if e.Status != "" {
+		parts = append(parts, string(e.Status))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shortVersion abbreviates commits and digests the way git does.
+func shortVersion(v string) string {
+	if i := strings.Index(v, ":"); i >= 0 && len(v) > i+13 {
+		return v[:i+13]
+	}
+	if len(v) > 7 {
+		return v[:7]
+	}
+	return v
+}
+
+func lastElem(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,299 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graph builds the graph of the packages of a workspace, their
+// subpackages, variants and upstreams.
+package graph
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/semver"
+	"github.com/GoogleContainerTools/kpt/internal/util/variant"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/pathutil"
+)
+
+// NodeKind is the kind of a node of the graph.
+type NodeKind string
+
+const (
+	// PackageNode is a local package of the workspace.
+	PackageNode NodeKind = "package"
+	// UpstreamNode is a git repo directory or OCI image packages are fetched from.
+	UpstreamNode NodeKind = "upstream"
+)
+
+// EdgeKind is the kind of an edge of the graph.
+type EdgeKind string
+
+const (
+	// SubpackageEdge is from a package to a package nested within it.
+	SubpackageEdge EdgeKind = "subpackage"
+	// UpstreamEdge is from a package to the upstream it was fetched from.
+	UpstreamEdge EdgeKind = "upstream"
+	// DependencyEdge is from a package to the upstream of a dependency
+	// declared in its Kptfile.
+	DependencyEdge EdgeKind = "dependency"
+	// VariantEdge is from a base package to a variant generated from it.
+	VariantEdge EdgeKind = "variant"
+)
+
+// Status is the status of a package relative to its upstream.
+type Status string
+
+const (
+	// UpToDate is the status of packages fetched at the commit their ref
+	// currently resolves to.
+	UpToDate Status = "up-to-date"
+	// Behind is the status of packages whose ref resolves to a commit other
+	// than the one they were fetched at.
+	Behind Status = "behind"
+	// Pinned is the status of packages fetched at a commit or digest, which
+	// never changes.
+	Pinned Status = "pinned"
+	// Unknown is the status of packages whose upstream could not be resolved.
+	Unknown Status = "unknown"
+)
+
+// Node is a package or an upstream.
+type Node struct {
+	// ID identifies the node.  The ID of a package is its slash separated
+	// path relative to the workspace, the ID of an upstream is its uri.
+	ID string `json:"id"`
+
+	// Kind is the kind of the node
+	Kind NodeKind `json:"kind"`
+
+	// Name is the name of the package from its Kptfile
+	Name string `json:"name,omitempty"`
+}
+
+// Edge is a relationship between two nodes.
+type Edge struct {
+	// From is the ID of the package the edge starts from
+	From string `json:"from"`
+
+	// To is the ID of the node the edge points to
+	To string `json:"to"`
+
+	// Kind is the kind of the edge
+	Kind EdgeKind `json:"kind"`
+
+	// Ref is the git ref, semver constraint or OCI tag the package is
+	// fetched from.  Only set for upstream and dependency edges.
+	Ref string `json:"ref,omitempty"`
+
+	// Version is the commit or digest the package was fetched at.
+	// Only set for upstream edges.
+	Version string `json:"version,omitempty"`
+
+	// Status is the status of the package relative to the upstream.
+	// Only set for upstream edges, if resolved with ResolveStatus.
+	Status Status `json:"status,omitempty"`
+}
+
+// Graph is the graph of the packages of a workspace.
+type Graph struct {
+	// Nodes are the packages and upstreams, packages first
+	Nodes []Node `json:"nodes"`
+
+	// Edges are the relationships between the nodes
+	Edges []Edge `json:"edges"`
+}
+
+// Build returns the graph of the packages in the dir.
+func Build(dir string) (*Graph, error) {
+	dirs, err := pathutil.DirsWithFile(dir, kptfile.KptFileName, true)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	sort.Strings(dirs)
+
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	upstreams := map[string]bool{}
+	var packages []string
+	for _, d := range dirs {
+		if strings.Contains(filepath.ToSlash(d)+"/", ".git/") {
+			continue
+		}
+		kf, err := kptfileutil.ReadFile(d)
+		if err != nil {
+			return nil, err
+		}
+		id, err := packageID(dir, d)
+		if err != nil {
+			return nil, err
+		}
+		g.Nodes = append(g.Nodes, Node{ID: id, Kind: PackageNode, Name: kf.Name})
+
+		if parent := nearestParent(id, packages); parent != "" {
+			g.Edges = append(g.Edges, Edge{From: parent, To: id, Kind: SubpackageEdge})
+		}
+		packages = append(packages, id)
+
+		if base := kf.Annotations[variant.BaseAnnotation]; base != "" {
+			g.Edges = append(g.Edges, Edge{From: path.Join(id, base), To: id, Kind: VariantEdge})
+		}
+
+		if e, found := upstreamEdge(id, kf.Upstream, UpstreamEdge); found {
+			g.Edges = append(g.Edges, e)
+			upstreams[e.To] = true
+		}
+		for _, dep := range kf.Dependencies {
+			if e, found := upstreamEdge(id, dep.Upstream, DependencyEdge); found {
+				g.Edges = append(g.Edges, e)
+				upstreams[e.To] = true
+			}
+		}
+	}
+
+	var ids []string
+	for id := range upstreams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g.Nodes = append(g.Nodes, Node{ID: id, Kind: UpstreamNode})
+	}
+	return g, nil
+}
+
+// packageID returns the slash separated path of the package relative to the
+// workspace.
+func packageID(dir, pkgDir string) (string, error) {
+	rel, err := filepath.Rel(dir, pkgDir)
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// nearestParent returns the package which most closely contains the package,
+// or an empty string if it isn't a subpackage.
+func nearestParent(id string, packages []string) string {
+	var parent string
+	for _, p := range packages {
+		if p == id || (p != "." && !strings.HasPrefix(id, p+"/")) {
+			continue
+		}
+		if len(p) > len(parent) || parent == "" {
+			parent = p
+		}
+	}
+	return parent
+}
+
+// upstreamEdge returns the edge from the package to the git repo directory or
+// OCI image of the upstream.
+func upstreamEdge(from string, u kptfile.Upstream, kind EdgeKind) (Edge, bool) {
+	switch {
+	case u.Type == kptfile.GitOrigin || (u.Type == "" && u.Git.Repo != ""):
+		to := u.Git.Repo
+		if d := strings.Trim(u.Git.Directory, "/"); d != "" {
+			to += "/" + d
+		}
+		e := Edge{From: from, To: to, Kind: kind, Ref: u.Git.Ref}
+		if kind == UpstreamEdge {
+			e.Version = u.Git.Commit
+		}
+		return e, true
+	case u.Type == kptfile.OCIOrigin:
+		e := Edge{From: from, To: "oci://" + u.OCI.Image, Kind: kind, Ref: u.OCI.Ref}
+		if kind == UpstreamEdge {
+			e.Version = u.OCI.Digest
+		}
+		return e, true
+	}
+	return Edge{}, false
+}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ResolveStatus sets the status of the upstream edges of the packages fetched
+// from git, by resolving their refs against the upstream repos.  Packages
+// pulled from OCI registries are pinned if pulled by digest, and unknown
+// otherwise.
+func (g *Graph) ResolveStatus(dir string) error {
+	resolved := map[string]string{}
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		if e.Kind != UpstreamEdge {
+			continue
+		}
+		kf, err := kptfileutil.ReadFile(filepath.Join(dir, filepath.FromSlash(e.From)))
+		if err != nil {
+			return err
+		}
+		if kf.Upstream.Type == kptfile.OCIOrigin {
+			e.Status = Unknown
+			if strings.HasPrefix(kf.Upstream.OCI.Ref, "sha256:") {
+				e.Status = Pinned
+			}
+			continue
+		}
+
+		git := kf.Upstream.Git
+		if commitPattern.MatchString(git.Ref) {
+			e.Status = Pinned
+			continue
+		}
+		key := git.Repo + "\n" + git.Directory + "\n" + git.Ref
+		commit, found := resolved[key]
+		if !found {
+			commit, err = ResolveGit(git)
+			if err != nil {
+				return err
+			}
+			resolved[key] = commit
+		}
+		switch {
+		case commit == "":
+			e.Status = Unknown
+		case commit == git.Commit:
+			e.Status = UpToDate
+		default:
+			e.Status = Behind
+		}
+	}
+	return nil
+}
+
+// ResolveGit returns the commit the ref of the package currently resolves
+// to.  Semver constraints are resolved to the latest matching tag.
+func ResolveGit(git kptfile.Git) (string, error) {
+	ref := git.Ref
+	if semver.IsConstraint(ref) {
+		tag, err := semver.ResolveGit(git.Repo, git.Directory, ref)
+		if err != nil {
+			return "", err
+		}
+		// tags prefixed with the directory take precedence
+		if d := strings.Trim(git.Directory, "/"); d != "" {
+			commit, err := gitutil.ResolveRef(git.Repo, d+"/"+tag)
+			if err != nil || commit != "" {
+				return commit, err
+			}
+		}
+		ref = tag
+	}
+	return gitutil.ResolveRef(git.Repo, ref)
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	. "github.com/GoogleContainerTools/kpt/internal/util/graph"
+	"github.com/GoogleContainerTools/kpt/internal/util/variant"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupWorkspace fetches the java and mysql packages of the repo into a
+// workspace, with the wordpress package as a subpackage of java.
+func setupWorkspace(t *testing.T, g *testutil.TestGitRepo) {
+	for pkg, dest := range map[string]string{
+		"java":      filepath.Join("apps", "java"),
+		"mysql":     filepath.Join("apps", "mysql"),
+		"wordpress": filepath.Join("apps", "java", "wordpress"),
+	} {
+		err := get.Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: pkg},
+			Destination: dest}.Run()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	setupWorkspace(t, g)
+
+	// the mysql package is a variant of the java package
+	kf, err := kptfileutil.ReadFile(filepath.Join(w.WorkspaceDirectory, "apps", "mysql"))
+	assert.NoError(t, err)
+	kf.Annotations = map[string]string{variant.BaseAnnotation: "../java"}
+	assert.NoError(t, kptfileutil.WriteFile(filepath.Join(w.WorkspaceDirectory, "apps", "mysql"), kf))
+
+	graph, err := Build("apps")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []Node{
+		{ID: "java", Kind: PackageNode, Name: "java"},
+		{ID: "java/wordpress", Kind: PackageNode, Name: "wordpress"},
+		{ID: "mysql", Kind: PackageNode, Name: "mysql"},
+		{ID: g.RepoDirectory + "/java", Kind: UpstreamNode},
+		{ID: g.RepoDirectory + "/mysql", Kind: UpstreamNode},
+		{ID: g.RepoDirectory + "/wordpress", Kind: UpstreamNode},
+	}, graph.Nodes)
+	commit, err := g.GetCommit()
+	assert.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{From: "java", To: g.RepoDirectory + "/java", Kind: UpstreamEdge, Ref: "master", Version: commit},
+		{From: "java", To: "java/wordpress", Kind: SubpackageEdge},
+		{From: "java/wordpress", To: g.RepoDirectory + "/wordpress", Kind: UpstreamEdge, Ref: "master", Version: commit},
+		{From: "java", To: "mysql", Kind: VariantEdge},
+		{From: "mysql", To: g.RepoDirectory + "/mysql", Kind: UpstreamEdge, Ref: "master", Version: commit},
+	}, graph.Edges)
+}
+
+func TestGraph_ResolveStatus(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	setupWorkspace(t, g)
+
+	// the mysql package is pinned to the commit it was fetched at
+	kf, err := kptfileutil.ReadFile(filepath.Join(w.WorkspaceDirectory, "apps", "mysql"))
+	assert.NoError(t, err)
+	kf.Upstream.Git.Ref = kf.Upstream.Git.Commit
+	assert.NoError(t, kptfileutil.WriteFile(filepath.Join(w.WorkspaceDirectory, "apps", "mysql"), kf))
+
+	testutil.CopyData(t, g, filepath.Join(testutil.Dataset2, "java"), "java")
+	testutil.Commit(t, g, "update java")
+
+	graph, err := Build("apps")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, graph.ResolveStatus("apps")) {
+		t.FailNow()
+	}
+	status := map[string]Status{}
+	for _, e := range graph.Edges {
+		if e.Kind == UpstreamEdge {
+			status[e.From] = e.Status
+		}
+	}
+	// the master branch moved, so packages fetched from it are behind even
+	// if their directory did not change
+	assert.Equal(t, map[string]Status{
+		"java":           Behind,
+		"java/wordpress": Behind,
+		"mysql":          Pinned,
+	}, status)
+}
+
+func TestGraph_Write(t *testing.T) {
+	graph := &Graph{
+		Nodes: []Node{
+			{ID: "app", Kind: PackageNode, Name: "app"},
+			{ID: "app/db", Kind: PackageNode, Name: "mysql"},
+			{ID: "https://github.com/example/pkgs/app", Kind: UpstreamNode},
+		},
+		Edges: []Edge{
+			{From: "app", To: "https://github.com/example/pkgs/app", Kind: UpstreamEdge,
+				Ref: "v1", Version: "7d4c0e3dbb27d14d8ed5f7fda3cab9a5f6e20c4a", Status: UpToDate},
+			{From: "app", To: "app/db", Kind: SubpackageEdge},
+		},
+	}
+
+	tests := map[string]string{
+		"dot": `digraph packages {
+  rankdir=LR;
+  "app" [label="app", shape=box];
+  "app/db" [label="app/db (mysql)", shape=box];
+  "https://github.com/example/pkgs/app" [label="https://github.com/example/pkgs/app", shape=ellipse];
+  "app" -> "https://github.com/example/pkgs/app" [label="upstream @v1 7d4c0e3 up-to-date"];
+  "app" -> "app/db" [label="subpackage"];
+}
+`,
+		"mermaid": `graph LR
+  n0["app"]
+  n1["app/db (mysql)"]
+  n2(["https://github.com/example/pkgs/app"])
+  n0 -->|"upstream @v1 7d4c0e3 up-to-date"| n2
+  n0 -->|"subpackage"| n1
+`,
+		"json": `{
+  "nodes": [
+    {
+      "id": "app",
+      "kind": "package",
+      "name": "app"
+    },
+    {
+      "id": "app/db",
+      "kind": "package",
+      "name": "mysql"
+    },
+    {
+      "id": "https://github.com/example/pkgs/app",
+      "kind": "upstream"
+    }
+  ],
+  "edges": [
+    {
+      "from": "app",
+      "to": "https://github.com/example/pkgs/app",
+      "kind": "upstream",
+      "ref": "v1",
+      "version": "7d4c0e3dbb27d14d8ed5f7fda3cab9a5f6e20c4a",
+      "status": "up-to-date"
+    },
+    {
+      "from": "app",
+      "to": "app/db",
+      "kind": "subpackage"
+    }
+  ]
+}
+`,
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			assert.NoError(t, graph.Write(b, format))
+			assert.Equal(t, expected, b.String())
+		})
+	}
+
+	err := graph.Write(&bytes.Buffer{}, "yaml")
+	assert.EqualError(t, err, `unsupported output format "yaml", must be one of: dot, json, mermaid`)
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package helm inflates Helm charts into packages using the helm program.
+package helm
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Scheme is the prefix of arguments referring to charts in a Helm repository.
+const Scheme = "helm://"
+
+// IsReference returns true if the argument refers to a chart in a Helm
+// repository.
+func IsReference(arg string) bool {
+	return strings.HasPrefix(arg, Scheme)
+}
+
+// ParseReference parses an argument of the form helm://HOST/PATH/CHART into
+// the https url of the chart repository and the name of the chart.
+func ParseReference(arg string) (kptfile.Helm, error) {
+	u, err := url.Parse("https://" + strings.TrimPrefix(arg, Scheme))
+	if err != nil || u.Host == "" {
+		return kptfile.Helm{}, errors.Errorf("invalid helm chart %s: must be helm://HOST/PATH/CHART", arg)
+	}
+	repoPath, chart := path.Split(strings.Trim(u.Path, "/"))
+	if chart == "" {
+		return kptfile.Helm{}, errors.Errorf("invalid helm chart %s: must be helm://HOST/PATH/CHART", arg)
+	}
+	u.Path = strings.TrimSuffix("/"+repoPath, "/")
+	return kptfile.Helm{Repo: u.String(), Chart: chart}, nil
+}
+
+// Template renders the chart at its version with the values files using
+// helm template, and returns the rendered resources.
+// Making it a var so that it can be overridden for local testing
+var Template = func(chart kptfile.Helm, releaseName string, values []string) ([]byte, error) {
+	helmProgram, err := exec.LookPath("helm")
+	if err != nil {
+		return nil, errors.Errorf("no 'helm' program on path: %v", err)
+	}
+	args := []string{"template", releaseName, chart.Chart,
+		"--repo", chart.Repo, "--version", chart.Version, "--include-crds"}
+	for _, v := range values {
+		args = append(args, "--values", v)
+	}
+	cmd := exec.Command(helmProgram, args...)
+	cmd.Env = os.Environ()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("failed to inflate helm chart %s %s from %s: %v: %s",
+			chart.Chart, chart.Version, chart.Repo, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
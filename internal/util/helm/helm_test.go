@@ -0,0 +1,45 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	for _, test := range []struct {
+		arg      string
+		expected kptfile.Helm
+		err      bool
+	}{
+		{arg: "helm://charts.bitnami.com/bitnami/nginx",
+			expected: kptfile.Helm{Repo: "https://charts.bitnami.com/bitnami", Chart: "nginx"}},
+		{arg: "helm://charts.example.com:8443/app/",
+			expected: kptfile.Helm{Repo: "https://charts.example.com:8443", Chart: "app"}},
+		{arg: "helm://charts.example.com", err: true},
+		{arg: "helm:///app", err: true},
+	} {
+		chart, err := ParseReference(test.arg)
+		if test.err {
+			assert.Error(t, err, test.arg)
+			continue
+		}
+		assert.NoError(t, err, test.arg)
+		assert.Equal(t, test.expected, chart, test.arg)
+	}
+}
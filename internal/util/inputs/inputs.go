@@ -0,0 +1,215 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inputs lists the input parameters declared in the Kptfile of a
+// package, and checks their values before the package is rendered.
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Formats are the formats the inputs can be written in.
+var Formats = []string{"text", "json"}
+
+// Types are the types of inputs.
+var Types = []string{kptfile.InputString, kptfile.InputInt, kptfile.InputBool, kptfile.InputEnum}
+
+// Value is an input of a package with its value.
+type Value struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Enum        []string `json:"enum,omitempty"`
+	Required    bool     `json:"required"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+
+	// Value is the value of the setter of the input if it's set, else the
+	// default of the input, else the value of the setter in the package
+	Value string `json:"value"`
+
+	// Set is true if the setter of the input is set or the input has a
+	// default
+	Set bool `json:"set"`
+}
+
+// List returns the inputs of the package at pkgPath with their values, in
+// the order they are declared.  Returns nil if the package has no Kptfile.
+func List(pkgPath string) ([]Value, error) {
+	if _, err := os.Stat(filepath.Join(pkgPath, kptfile.KptFileName)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	kf, err := kptfileutil.ReadFile(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(kf.Inputs) == 0 {
+		return nil, nil
+	}
+	setterValues, err := setters.Values(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	var values []Value
+	for _, in := range kf.Inputs {
+		v := Value{
+			Name:        in.Name,
+			Type:        in.Type,
+			Enum:        in.Enum,
+			Required:    in.Required,
+			Default:     in.Default,
+			Description: in.Description,
+		}
+		if v.Type == "" {
+			v.Type = kptfile.InputString
+		}
+		setterValue, found := setterValues[in.Name]
+		switch {
+		case found && setters.IsSet(pkgPath, in.Name):
+			v.Value, v.Set = setterValue, true
+		case in.Default != "":
+			v.Value, v.Set = in.Default, true
+		default:
+			v.Value = setterValue
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Check returns an error listing the required inputs of the package at
+// pkgPath which aren't set, and the inputs whose value doesn't match their
+// type.
+func Check(pkgPath string) error {
+	values, err := List(pkgPath)
+	if err != nil {
+		return err
+	}
+	var msgs []string
+	for _, v := range values {
+		if v.Required && !v.Set {
+			msgs = append(msgs, fmt.Sprintf("input %s is required, set it with: kpt cfg set %s %s VALUE",
+				v.Name, pkgPath, v.Name))
+			continue
+		}
+		if err := v.check(); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.Errorf("package %s has invalid inputs:\n  - %s", pkgPath, strings.Join(msgs, "\n  - "))
+	}
+	return nil
+}
+
+// CheckType returns an error if the type of the input is unknown, or if
+// it's an enum without values.
+func (v Value) CheckType() error {
+	for _, t := range Types {
+		if v.Type != t {
+			continue
+		}
+		if v.Type == kptfile.InputEnum && len(v.Enum) == 0 {
+			return errors.Errorf("input %s of type enum must declare its enum values", v.Name)
+		}
+		return nil
+	}
+	return errors.Errorf("input %s has unknown type %q, must be one of: %s",
+		v.Name, v.Type, strings.Join(Types, ", "))
+}
+
+// check returns an error if the type of the input is invalid, or if its
+// value doesn't match its type.  Empty values aren't checked.
+func (v Value) check() error {
+	if err := v.CheckType(); err != nil || v.Value == "" {
+		return err
+	}
+	switch v.Type {
+	case kptfile.InputInt:
+		if _, err := strconv.Atoi(v.Value); err != nil {
+			return errors.Errorf("input %s must be an int, got %q", v.Name, v.Value)
+		}
+	case kptfile.InputBool:
+		if _, err := strconv.ParseBool(v.Value); err != nil {
+			return errors.Errorf("input %s must be a bool, got %q", v.Name, v.Value)
+		}
+	case kptfile.InputEnum:
+		for _, e := range v.Enum {
+			if v.Value == e {
+				return nil
+			}
+		}
+		return errors.Errorf("input %s must be one of: %s, got %q", v.Name, strings.Join(v.Enum, ", "), v.Value)
+	}
+	return nil
+}
+
+// Write writes the inputs to w in the format.
+func Write(w io.Writer, values []Value, format string) error {
+	switch format {
+	case "text":
+		return writeText(w, values)
+	case "json":
+		if values == nil {
+			values = []Value{}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return errors.Wrap(e.Encode(values))
+	}
+	return errors.Errorf("unsupported output format %q, must be one of: %s",
+		format, strings.Join(Formats, ", "))
+}
+
+// writeText writes a table of the inputs.
+func writeText(w io.Writer, values []Value) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tREQUIRED\tDEFAULT\tVALUE\tDESCRIPTION")
+	for _, v := range values {
+		t := v.Type
+		if len(v.Enum) > 0 {
+			t += "(" + strings.Join(v.Enum, "|") + ")"
+		}
+		required := "no"
+		if v.Required {
+			required = "yes"
+		}
+		value := v.Value
+		if !v.Set && v.Required {
+			value = "<unset>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", v.Name, t, required,
+			orDash(v.Default), orDash(value), orDash(v.Description))
+	}
+	return errors.Wrap(tw.Flush())
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inputs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+const inputsKptfile = `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+          isSet: true
+    io.k8s.cli.setters.tier:
+      x-k8s-cli:
+        setter:
+          name: tier
+          value: gold
+    io.k8s.cli.setters.domain:
+      x-k8s-cli:
+        setter:
+          name: domain
+          value: ""
+inputs:
+- name: replicas
+  type: int
+  required: true
+  description: number of replicas
+- name: tier
+  type: enum
+  enum: [bronze, silver]
+  default: bronze
+- name: domain
+  required: true
+- name: debug
+  type: bool
+`
+
+func TestList(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile(kptfile.KptFileName, inputsKptfile))
+
+	values, err := List(d)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{
+		{Name: "replicas", Type: "int", Required: true, Description: "number of replicas", Value: "3", Set: true},
+		{Name: "tier", Type: "enum", Enum: []string{"bronze", "silver"}, Default: "bronze", Value: "bronze", Set: true},
+		{Name: "domain", Type: "string", Required: true},
+		{Name: "debug", Type: "bool"},
+	}, values)
+
+	var b bytes.Buffer
+	assert.NoError(t, Write(&b, values, "text"))
+	assert.Equal(t, `NAME      TYPE                 REQUIRED  DEFAULT  VALUE    DESCRIPTION
+replicas  int                  yes       -        3        number of replicas
+tier      enum(bronze|silver)  no        bronze   bronze   -
+domain    string               yes       -        <unset>  -
+debug     bool                 no        -        -        -
+`, b.String())
+}
+
+func TestCheck(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile(kptfile.KptFileName, inputsKptfile))
+
+	assert.EqualError(t, Check(d), "package "+d+" has invalid inputs:\n"+
+		"  - input domain is required, set it with: kpt cfg set "+d+" domain VALUE")
+}
+
+func TestCheck_types(t *testing.T) {
+	var tests = []struct {
+		value    Value
+		expected string
+	}{
+		{value: Value{Name: "a", Type: "string", Value: "x"}},
+		{value: Value{Name: "a", Type: "int", Value: "3"}},
+		{value: Value{Name: "a", Type: "int", Value: "three"}, expected: `input a must be an int, got "three"`},
+		{value: Value{Name: "a", Type: "bool", Value: "true"}},
+		{value: Value{Name: "a", Type: "bool", Value: "yes"}, expected: `input a must be a bool, got "yes"`},
+		{value: Value{Name: "a", Type: "enum", Enum: []string{"x", "y"}, Value: "y"}},
+		{value: Value{Name: "a", Type: "enum", Enum: []string{"x", "y"}, Value: "z"},
+			expected: `input a must be one of: x, y, got "z"`},
+		{value: Value{Name: "a", Type: "enum"}, expected: "input a of type enum must declare its enum values"},
+		{value: Value{Name: "a", Type: "float", Value: "1.5"},
+			expected: `input a has unknown type "float", must be one of: string, int, bool, enum`},
+	}
+	for _, test := range tests {
+		err := test.value.check()
+		if test.expected == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestList_noKptfile(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage(""))
+
+	values, err := List(d)
+	assert.NoError(t, err)
+	assert.Nil(t, values)
+	assert.NoError(t, Check(d))
+}
@@ -0,0 +1,472 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint checks a package for mistakes which would only surface when
+// it is synced, rendered or applied: an invalid Kptfile, invalid function
+// images, unused setters, missing function files and duplicate resources.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/fnimage"
+	"github.com/GoogleContainerTools/kpt/internal/util/inputs"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/setters2"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Formats are the formats the findings can be written in.
+var Formats = []string{"text", "json"}
+
+// Severities of findings.  Packages with errors fail kpt pkg lint.
+const (
+	Error   = "error"
+	Warning = "warning"
+)
+
+// Checks of the linter.
+const (
+	CheckKptfile   = "kptfile"
+	CheckImage     = "image"
+	CheckSetter    = "setter"
+	CheckFile      = "file"
+	CheckDuplicate = "duplicate"
+)
+
+// setterPrefix prefixes the names of the setter definitions of the Kptfile.
+const setterPrefix = "io.k8s.cli.setters."
+
+var (
+	// repositoryPattern matches the repositories of image references
+	repositoryPattern = regexp.MustCompile(
+		`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+
+	// tagPattern matches the tags of image references
+	tagPattern = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+	// setterRefPattern matches the setter references of field comments,
+	// e.g. {"$kpt-set":"replicas"}
+	setterRefPattern = regexp.MustCompile(`"\$(?:kpt-set|openapi)"\s*:\s*"([^"]+)"`)
+
+	// definitionRefPattern matches the references to setter definitions of
+	// field comments and substitutions
+	definitionRefPattern = regexp.MustCompile(regexp.QuoteMeta("#/definitions/"+setterPrefix) + `([\w.-]+)`)
+
+	// substitutionPattern matches the setters of substitution patterns
+	substitutionPattern = regexp.MustCompile(`\$\{([\w.-]+)\}`)
+)
+
+// Finding is a mistake found in a package.
+type Finding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+
+	// File is the slash separated path of the file relative to the package
+	File string `json:"file,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// Package returns the findings of the package at dir, excluding its
+// subpackages.
+func Package(dir string) ([]Finding, error) {
+	l := &linter{dir: dir}
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir}).Read()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	if kf, ok := l.kptfile(); ok {
+		l.kptfileFunctions(kf)
+		if err := l.setters(kf, nodes); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.resourceFunctions(nodes); err != nil {
+		return nil, err
+	}
+	if err := l.duplicates(nodes); err != nil {
+		return nil, err
+	}
+	return l.findings, nil
+}
+
+// Errors returns the number of findings of the Error severity.
+func Errors(findings []Finding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == Error {
+			n++
+		}
+	}
+	return n
+}
+
+type linter struct {
+	dir      string
+	findings []Finding
+}
+
+func (l *linter) add(severity, check, file, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity, Check: check, File: file, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// kptfile validates the Kptfile against its schema, returning false if it
+// can't be read.
+func (l *linter) kptfile() (kptfile.KptFile, bool) {
+	if _, err := os.Stat(filepath.Join(l.dir, kptfile.KptFileName)); os.IsNotExist(err) {
+		l.add(Error, CheckKptfile, "", "package has no %s", kptfile.KptFileName)
+		return kptfile.KptFile{}, false
+	}
+	kf, err := kptfileutil.ReadFileStrict(l.dir)
+	if err != nil {
+		l.add(Error, CheckKptfile, kptfile.KptFileName, "%v", err)
+		return kptfile.KptFile{}, false
+	}
+	if kf.APIVersion != kptfile.KptFileAPIVersion || kf.Kind != kptfile.KptFileName {
+		l.add(Error, CheckKptfile, kptfile.KptFileName, "apiVersion and kind must be %s %s, got %s %s",
+			kptfile.KptFileAPIVersion, kptfile.KptFileName, kf.APIVersion, kf.Kind)
+	}
+	if kf.Name == "" {
+		l.add(Warning, CheckKptfile, kptfile.KptFileName, "metadata.name isn't set")
+	}
+	names := map[string]bool{}
+	for i, d := range kf.Dependencies {
+		switch {
+		case d.Name == "":
+			l.add(Error, CheckKptfile, kptfile.KptFileName, "dependencies[%d] has no name", i)
+		case names[d.Name]:
+			l.add(Error, CheckKptfile, kptfile.KptFileName, "dependency %s is declared more than once", d.Name)
+		}
+		names[d.Name] = true
+		if d.EnsureNotExists && d.Git.Repo != "" {
+			l.add(Error, CheckKptfile, kptfile.KptFileName,
+				"dependency %s sets the mutually exclusive fields ensureNotExists and git", d.Name)
+		}
+	}
+	for _, in := range kf.Inputs {
+		if in.Name == "" {
+			l.add(Error, CheckKptfile, kptfile.KptFileName, "inputs must have a name")
+			continue
+		}
+		v := inputs.Value{Name: in.Name, Type: in.Type, Enum: in.Enum}
+		if v.Type == "" {
+			v.Type = kptfile.InputString
+		}
+		if err := v.CheckType(); err != nil {
+			l.add(Error, CheckKptfile, kptfile.KptFileName, "%v", err)
+		}
+	}
+	return kf, true
+}
+
+// kptfileFunctions checks the functions of the dependencies and pipelines
+// of the Kptfile.
+func (l *linter) kptfileFunctions(kf kptfile.KptFile) {
+	for _, d := range kf.Dependencies {
+		for i, f := range d.Functions {
+			l.function(fmt.Sprintf("dependency %s function %d", d.Name, i), f, filepath.Join(l.dir, d.Name))
+		}
+	}
+	for _, p := range kf.Functions.Pipelines {
+		for i, f := range p.Functions {
+			// the package pipelines are run on is only known when they
+			// are imported
+			l.function(fmt.Sprintf("pipeline %s function %d", p.Name, i), f, "")
+		}
+	}
+	for _, e := range kf.Environments {
+		for i, f := range e.Functions {
+			l.function(fmt.Sprintf("environment %s function %d", e.Name, i), f, l.dir)
+		}
+	}
+}
+
+// function checks a function of the Kptfile, run on the package at pkgPath
+// if known.
+func (l *linter) function(name string, f kptfile.Function, pkgPath string) {
+	file := kptfile.KptFileName
+	switch {
+	case f.Import != nil:
+		if f.Import.Git == nil && f.Import.Path != "" {
+			p := filepath.Join(l.dir, filepath.FromSlash(f.Import.Path), kptfile.KptFileName)
+			if _, err := os.Stat(p); err != nil {
+				l.add(Error, CheckFile, file, "%s imports pipeline %s from %s, which has no %s",
+					name, f.Import.Pipeline, f.Import.Path, kptfile.KptFileName)
+			}
+		}
+	case f.Runtime == kptfile.ExecRuntime || f.Exec != nil:
+		if f.Exec == nil || f.Exec.Path == "" {
+			l.add(Error, CheckKptfile, file, "%s has no exec path", name)
+			return
+		}
+		if strings.ContainsAny(f.Exec.Path, `/\`) && !filepath.IsAbs(f.Exec.Path) {
+			l.checkFile(name, file, filepath.Join(l.dir, filepath.FromSlash(f.Exec.Path)))
+		}
+	case f.Runtime == kptfile.StarlarkRuntime:
+		if f.Path == "" && f.Source == "" {
+			l.add(Error, CheckKptfile, file, "%s has no starlark path or source", name)
+			return
+		}
+		if f.Path != "" && pkgPath != "" {
+			// dependencies which aren't synced yet can't be checked
+			if _, err := os.Stat(pkgPath); err == nil {
+				l.checkFile(name, file, filepath.Join(pkgPath, filepath.FromSlash(f.Path)))
+			}
+		}
+	case f.Runtime == kptfile.BuiltinRuntime:
+		if f.Name == "" {
+			l.add(Error, CheckKptfile, file, "%s has no builtin name", name)
+		}
+	case f.Runtime == "" || f.Runtime == kptfile.ContainerRuntime:
+		l.image(name, file, f.Image)
+	default:
+		l.add(Error, CheckKptfile, file, "%s has unknown runtime %q", name, f.Runtime)
+	}
+}
+
+// image checks the image reference of a container function.
+func (l *linter) image(name, file, image string) {
+	if image == "" {
+		l.add(Error, CheckImage, file, "%s has no image", name)
+		return
+	}
+	ref, err := fnimage.Reference(image)
+	if err != nil {
+		l.add(Error, CheckImage, file, "%s has invalid image %s: %v", name, image, err)
+		return
+	}
+	if !repositoryPattern.MatchString(ref.Repository) {
+		l.add(Error, CheckImage, file, "%s has invalid image %s: invalid repository %s", name, image, ref.Repository)
+		return
+	}
+	if !oci.IsDigest(ref.Ref) && !tagPattern.MatchString(ref.Ref) {
+		l.add(Error, CheckImage, file, "%s has invalid image %s: invalid tag %s", name, image, ref.Ref)
+		return
+	}
+	if fnimage.Name(image) == image || ref.Ref == oci.DefaultTag {
+		l.add(Warning, CheckImage, file, "%s image %s isn't pinned to a version tag or digest", name, image)
+	}
+}
+
+func (l *linter) checkFile(name, file, path string) {
+	if _, err := os.Stat(path); err != nil {
+		rel, relErr := filepath.Rel(l.dir, path)
+		if relErr != nil {
+			rel = path
+		}
+		l.add(Error, CheckFile, file, "%s references missing file %s", name, filepath.ToSlash(rel))
+	}
+}
+
+// setters reports the setters of the Kptfile whose values don't match the
+// constraints of their definitions, and the setters which no field of the
+// resources, substitution or function condition references.
+func (l *linter) setters(kf kptfile.KptFile, nodes []*yaml.RNode) error {
+	schema, err := openapi.SchemaFromFile(filepath.Join(l.dir, kptfile.KptFileName))
+	if err != nil || schema == nil {
+		return err
+	}
+	invalid, err := setters.InvalidValues(l.dir, nil)
+	if err != nil {
+		return err
+	}
+	for _, msg := range invalid {
+		l.add(Error, CheckSetter, kptfile.KptFileName, "setter %s", msg)
+	}
+	used := map[string]bool{}
+	addRefs := func(text string) {
+		for _, m := range setterRefPattern.FindAllStringSubmatch(text, -1) {
+			used[m[1]] = true
+			for _, s := range substitutionPattern.FindAllStringSubmatch(m[1], -1) {
+				used[s[1]] = true
+			}
+		}
+		for _, m := range definitionRefPattern.FindAllStringSubmatch(text, -1) {
+			used[m[1]] = true
+		}
+	}
+	for _, n := range nodes {
+		s, err := n.String()
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		addRefs(s)
+	}
+	b, err := yaml.Marshal(kf.OpenAPI)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	addRefs(string(b))
+	for _, d := range kf.Dependencies {
+		for _, f := range d.Functions {
+			if f.When != nil && f.When.Setter != nil {
+				used[f.When.Setter.Name] = true
+			}
+		}
+	}
+	for _, p := range kf.Functions.Pipelines {
+		for _, f := range p.Functions {
+			if f.When != nil && f.When.Setter != nil {
+				used[f.When.Setter.Name] = true
+			}
+		}
+	}
+
+	var unused []string
+	for key := range schema.Definitions {
+		if !strings.HasPrefix(key, setterPrefix) {
+			continue
+		}
+		def := schema.Definitions[key]
+		ext, err := setters2.GetExtFromSchema(&def)
+		if err != nil {
+			return err
+		}
+		if ext == nil || ext.Setter == nil {
+			continue
+		}
+		if !used[ext.Setter.Name] && !used[strings.TrimPrefix(key, setterPrefix)] {
+			unused = append(unused, ext.Setter.Name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		l.add(Warning, CheckSetter, kptfile.KptFileName, "setter %s isn't referenced by any resource", name)
+	}
+	return nil
+}
+
+// resourceFunctions checks the images and starlark scripts of the
+// functions declared by resources of the package.
+func (l *linter) resourceFunctions(nodes []*yaml.RNode) error {
+	for _, n := range nodes {
+		spec := runtimeutil.GetFunctionSpec(n)
+		if spec == nil {
+			continue
+		}
+		file, _, err := kioutil.GetFileAnnotations(n)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		meta, err := n.GetMeta()
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		name := fmt.Sprintf("function %s %s", meta.Kind, meta.Name)
+		switch {
+		case spec.Container.Image != "":
+			l.image(name, file, spec.Container.Image)
+		case spec.Starlark.Path != "":
+			// starlark paths are relative to the function config
+			l.checkFile(name, file, filepath.Join(l.dir, filepath.Dir(filepath.FromSlash(file)),
+				filepath.FromSlash(spec.Starlark.Path)))
+		}
+	}
+	return nil
+}
+
+// duplicates reports the resources declared more than once in the package.
+func (l *linter) duplicates(nodes []*yaml.RNode) error {
+	files := map[string][]string{}
+	var ids []string
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if meta.Kind == "" || meta.Name == "" {
+			continue
+		}
+		group := ""
+		if i := strings.Index(meta.APIVersion, "/"); i >= 0 {
+			group = meta.APIVersion[:i]
+		}
+		id := meta.Kind + "." + group + " " + meta.Name
+		if group == "" {
+			id = meta.Kind + " " + meta.Name
+		}
+		if meta.Namespace != "" {
+			id = strings.Replace(id, " ", " "+meta.Namespace+"/", 1)
+		}
+		file, _, err := kioutil.GetFileAnnotations(n)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if _, found := files[id]; !found {
+			ids = append(ids, id)
+		}
+		files[id] = append(files[id], file)
+	}
+	for _, id := range ids {
+		if len(files[id]) > 1 {
+			l.add(Error, CheckDuplicate, files[id][0], "%s is declared more than once, in %s",
+				id, strings.Join(files[id], ", "))
+		}
+	}
+	return nil
+}
+
+// Write writes the findings to w in the format.
+func Write(w io.Writer, findings []Finding, format string) error {
+	switch format {
+	case "text":
+		return writeText(w, findings)
+	case "json":
+		if findings == nil {
+			findings = []Finding{}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return errors.Wrap(e.Encode(findings))
+	}
+	return errors.Errorf("unsupported output format %q, must be one of: %s",
+		format, strings.Join(Formats, ", "))
+}
+
+// writeText writes a table of the findings.
+func writeText(w io.Writer, findings []Finding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "no findings")
+		return errors.Wrap(err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tCHECK\tFILE\tMESSAGE")
+	for _, f := range findings {
+		file := f.File
+		if file == "" {
+			file = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, file, f.Message)
+	}
+	return errors.Wrap(tw.Flush())
+}
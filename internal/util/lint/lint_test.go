@@ -0,0 +1,167 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackage(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+    io.k8s.cli.setters.tier:
+      type: string
+      enum: [bronze, silver]
+      x-k8s-cli:
+        setter:
+          name: tier
+          value: gold
+    io.k8s.cli.setters.tag:
+      x-k8s-cli:
+        setter:
+          name: tag
+          value: "1.19"
+    io.k8s.cli.substitutions.image:
+      x-k8s-cli:
+        substitution:
+          name: image
+          pattern: nginx:TAG
+          values:
+          - marker: TAG
+            ref: '#/definitions/io.k8s.cli.setters.tag'
+dependencies:
+- name: db
+  git:
+    repo: https://github.com/example/db
+    directory: /
+    ref: v1
+  functions:
+  - image: gcr.io/kpt-fn/set-namespace:v0.1
+  - image: gcr.io/Example/Fn:v1
+  - image: gcr.io/kpt-fn/set-labels
+  - runtime: starlark
+    path: missing.star
+  - exec:
+      path: ./bin/missing
+inputs:
+- name: size
+  type: float
+`,
+		"db/Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: db
+`,
+		"deploy.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # {"$kpt-set":"replicas"}
+`,
+		"copy.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+		"fn/config.yaml": `apiVersion: example.com/v1
+kind: Labels
+metadata:
+  name: labels
+  annotations:
+    config.kubernetes.io/function: |
+      starlark: {path: labels.star, name: labels}
+`,
+	}))
+
+	findings, err := Package(d)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Severity: Error, Check: CheckKptfile, File: "Kptfile",
+			Message: `input size has unknown type "float", must be one of: string, int, bool, enum`},
+		{Severity: Error, Check: CheckImage, File: "Kptfile",
+			Message: "dependency db function 1 has invalid image gcr.io/Example/Fn:v1: invalid repository Example/Fn"},
+		{Severity: Warning, Check: CheckImage, File: "Kptfile",
+			Message: "dependency db function 2 image gcr.io/kpt-fn/set-labels isn't pinned to a version tag or digest"},
+		{Severity: Error, Check: CheckFile, File: "Kptfile",
+			Message: "dependency db function 3 references missing file db/missing.star"},
+		{Severity: Error, Check: CheckFile, File: "Kptfile",
+			Message: "dependency db function 4 references missing file bin/missing"},
+		{Severity: Error, Check: CheckSetter, File: "Kptfile",
+			Message: `setter tier in body should be one of [bronze silver], got "gold"`},
+		{Severity: Warning, Check: CheckSetter, File: "Kptfile",
+			Message: "setter tier isn't referenced by any resource"},
+		{Severity: Error, Check: CheckFile, File: "fn/config.yaml",
+			Message: "function Labels labels references missing file fn/labels.star"},
+		{Severity: Error, Check: CheckDuplicate, File: "copy.yaml",
+			Message: "Deployment.apps web is declared more than once, in copy.yaml, deploy.yaml"},
+	}, findings)
+	assert.Equal(t, 6, Errors(findings))
+}
+
+func TestPackage_invalidKptfile(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+dependency:
+- name: db
+`,
+	}))
+
+	findings, err := Package(d)
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, Error, findings[0].Severity)
+		assert.Equal(t, CheckKptfile, findings[0].Check)
+		assert.Contains(t, findings[0].Message, "field dependency not found")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Write(&b, nil, "text"))
+	assert.Equal(t, "no findings\n", b.String())
+
+	b.Reset()
+	assert.NoError(t, Write(&b, []Finding{
+		{Severity: Warning, Check: CheckSetter, File: "Kptfile", Message: "setter tier isn't referenced by any resource"},
+		{Severity: Error, Check: CheckKptfile, Message: "package has no Kptfile"},
+	}, "text"))
+	assert.Equal(t, `SEVERITY  CHECK    FILE     MESSAGE
+warning   setter   Kptfile  setter tier isn't referenced by any resource
+error     kptfile  -        package has no Kptfile
+`, b.String())
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// indexMediaTypes are the media types of multi-platform images, which
+// function images usually are.
+var indexMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// Digest returns the digest of the manifest ref points to with the
+// credentials of the docker config.
+func Digest(ref Reference) (string, error) {
+	c := &Client{Credentials: DockerConfigCredentials}
+	return c.Digest(ref)
+}
+
+// Digest returns the digest of the manifest, or the index of a multi-platform
+// image, ref points to, without downloading it.
+func (c *Client) Digest(ref Reference) (string, error) {
+	if IsDigest(ref.Ref) {
+		return ref.Ref, nil
+	}
+	c.actions, c.authorization = "pull", ""
+	accept := append(append([]string{}, indexMediaTypes...), manifestMediaTypes...)
+	header := http.Header{"Accept": {strings.Join(accept, ",")}}
+	resp, err := c.send(ref, http.MethodHead, "/manifests/"+ref.Ref, header, nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if !IsDigest(digest) {
+		return "", fmt.Errorf("registry %s returned no digest for %s", ref.Registry, ref)
+	}
+	return digest, nil
+}
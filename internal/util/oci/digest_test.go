@@ -0,0 +1,50 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Digest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/org/fn/manifests/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	actual, err := (&Client{}).Digest(Reference{Registry: registry, Repository: "org/fn", Ref: "v1"})
+	assert.NoError(t, err)
+	assert.Equal(t, digest, actual)
+
+	// digests are not resolved
+	actual, err = (&Client{}).Digest(Reference{Registry: registry, Repository: "org/missing", Ref: digest})
+	assert.NoError(t, err)
+	assert.Equal(t, digest, actual)
+
+	_, err = (&Client{}).Digest(Reference{Registry: registry, Repository: "org/fn", Ref: "v2"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,533 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci contains functions for pulling packages stored as OCI
+// artifacts from container registries.
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Scheme is the prefix of the package arguments stored in OCI registries.
+	Scheme = "oci://"
+
+	// DefaultTag is the tag pulled if a reference has no tag or digest.
+	DefaultTag = "latest"
+)
+
+// manifestMediaTypes are the manifest media types accepted from registries.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference identifies a package in an OCI registry.
+type Reference struct {
+	// Registry is the host of the registry, e.g. ghcr.io or localhost:5000
+	Registry string
+
+	// Repository is the repository of the package in the registry, e.g. org/pkg
+	Repository string
+
+	// Ref is the tag or the digest of the package
+	Ref string
+}
+
+// IsReference returns true if the package argument s is stored in an
+// OCI registry.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, Scheme)
+}
+
+// ParseReference parses a reference of the form
+// [oci://]REGISTRY/REPOSITORY[:TAG|@DIGEST]. The tag defaults to latest.
+func ParseReference(s string) (Reference, error) {
+	r := Reference{}
+	image := strings.TrimPrefix(s, Scheme)
+	if i := strings.Index(image, "@"); i >= 0 {
+		r.Ref = image[i+1:]
+		image = image[:i]
+		if !IsDigest(r.Ref) {
+			return Reference{}, fmt.Errorf("invalid digest %q in %s; must be sha256:<hex>", r.Ref, s)
+		}
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		r.Ref = image[i+1:]
+		image = image[:i]
+	}
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Reference{}, fmt.Errorf(
+			"invalid OCI reference %s; must be oci://REGISTRY/REPOSITORY[:TAG|@DIGEST]", s)
+	}
+	r.Registry, r.Repository = parts[0], parts[1]
+	if r.Ref == "" {
+		r.Ref = DefaultTag
+	}
+	return r, nil
+}
+
+// NewReference returns the reference to the passed tag or digest of the
+// image REGISTRY/REPOSITORY.
+func NewReference(image, ref string) (Reference, error) {
+	r, err := ParseReference(image)
+	if err != nil {
+		return Reference{}, err
+	}
+	if ref != "" {
+		r.Ref = ref
+	}
+	return r, nil
+}
+
+// IsDigest returns true if ref is a sha256 digest rather than a tag.
+func IsDigest(ref string) bool {
+	hash := strings.TrimPrefix(ref, "sha256:")
+	if hash == ref || len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Image returns the image of the reference, without the tag or digest.
+func (r Reference) Image() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the reference as REGISTRY/REPOSITORY:TAG or
+// REGISTRY/REPOSITORY@DIGEST.
+func (r Reference) String() string {
+	if IsDigest(r.Ref) {
+		return r.Image() + "@" + r.Ref
+	}
+	return r.Image() + ":" + r.Ref
+}
+
+// Pull pulls the package at ref into dir with the credentials of the
+// docker config. Returns the digest of the pulled manifest.
+func Pull(ref Reference, dir string) (string, error) {
+	c := &Client{Credentials: DockerConfigCredentials}
+	return c.Pull(ref, dir)
+}
+
+// Client pulls and pushes packages with the registry HTTP API.
+type Client struct {
+	// HTTPClient is the HTTP client used to reach registries. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Credentials returns the username and password for a registry, or
+	// false to access it anonymously.
+	Credentials func(registry string) (string, string, bool)
+
+	// Annotations are the annotations of the manifests pushed, e.g. the
+	// metadata of the package.
+	Annotations map[string]string
+
+	// actions are the actions on the repository requested from the
+	// registry, e.g. pull or pull,push.
+	actions string
+
+	// authorization is the Authorization header granted by the registry
+	// for the repository being accessed.
+	authorization string
+}
+
+// manifest is the subset of an OCI image manifest read and written by
+// the Client.
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+
+	// Annotations describe the package, e.g. for registries to index it
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// descriptor describes a blob of a manifest.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Pull pulls the package at ref, and extracts the layers of its manifest
+// into dir in order. Layers must be tar archives, optionally gzipped.
+// Returns the digest of the manifest, which is verified against ref if
+// it is a digest.
+func (c *Client) Pull(ref Reference, dir string) (string, error) {
+	c.actions, c.authorization = "pull", ""
+	header := http.Header{"Accept": {strings.Join(manifestMediaTypes, ",")}}
+	resp, err := c.send(ref, http.MethodGet, "/manifests/"+ref.Ref, header, nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	digest := digestOf(body)
+	if IsDigest(ref.Ref) && digest != ref.Ref {
+		return "", fmt.Errorf("manifest of %s has digest %s", ref, digest)
+	}
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return "", fmt.Errorf("unable to parse manifest of %s: %v", ref, err)
+	}
+	if len(m.Layers) == 0 {
+		return "", fmt.Errorf("manifest of %s has no layers", ref)
+	}
+	for _, layer := range m.Layers {
+		if err := c.extractLayer(ref, layer, dir); err != nil {
+			return "", err
+		}
+	}
+	return digest, nil
+}
+
+// extractLayer downloads the layer blob and extracts it into dir,
+// verifying its digest.
+func (c *Client) extractLayer(ref Reference, layer descriptor, dir string) error {
+	resp, err := c.send(ref, http.MethodGet, "/blobs/"+layer.Digest, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	blob := io.TeeReader(resp.Body, h)
+	r := blob
+	if strings.HasSuffix(layer.MediaType, "gzip") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("unable to read layer %s of %s: %v", layer.Digest, ref, err)
+		}
+		r = gz
+	}
+	if err := untar(r, dir); err != nil {
+		return fmt.Errorf("unable to extract layer %s of %s: %v", layer.Digest, ref, err)
+	}
+	// read the rest of the blob so the digest covers all of it
+	if _, err := io.Copy(ioutil.Discard, blob); err != nil {
+		return err
+	}
+	if digest := "sha256:" + hex.EncodeToString(h.Sum(nil)); digest != layer.Digest {
+		return fmt.Errorf("layer %s of %s has digest %s", layer.Digest, ref, digest)
+	}
+	return nil
+}
+
+// send sends a request for the path of the repository of ref, or for the
+// absolute URL u, authenticating with the registry if it requests it.
+// Returns an error if the response status is not one of expected.
+func (c *Client) send(ref Reference, method, u string, header http.Header, body []byte,
+	expected ...int) (*http.Response, error) {
+	if !strings.Contains(u, "://") {
+		u = fmt.Sprintf("%s://%s/v2/%s%s", scheme(ref.Registry), ref.Registry, ref.Repository, u)
+	}
+	resp, err := c.do(method, u, header, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if c.authorization, err = c.authorize(ref, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(method, u, header, body); err != nil {
+			return nil, err
+		}
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("unable to %s %s: %s", strings.ToLower(method), u, resp.Status)
+}
+
+// do sends a request with the authorization granted by the registry.
+func (c *Client) do(method, u string, header http.Header, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	return c.httpClient().Do(req)
+}
+
+// challengeParam matches the parameters of a WWW-Authenticate challenge.
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize returns the Authorization header answering the challenge of
+// the registry: the credentials for Basic challenges, or a token from
+// the realm of Bearer challenges.
+func (c *Client) authorize(ref Reference, challenge string) (string, error) {
+	user, password, hasCredentials := "", "", false
+	if c.Credentials != nil {
+		user, password, hasCredentials = c.Credentials(ref.Registry)
+	}
+	switch {
+	case strings.HasPrefix(challenge, "Basic"):
+		if !hasCredentials {
+			return "", fmt.Errorf("registry %s requires credentials; run docker login %s",
+				ref.Registry, ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password)), nil
+	case strings.HasPrefix(challenge, "Bearer"):
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge from registry %s: %q",
+			ref.Registry, challenge)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid authentication realm from registry %s: %q", ref.Registry, challenge)
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:%s", ref.Repository, c.actions))
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCredentials {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to authenticate with registry %s: %s", ref.Registry, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to authenticate with registry %s: %v", ref.Registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// scheme returns http for registries on the loopback interface, which
+// are usually local test registries, and https for all others.
+func scheme(registry string) string {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return "http"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+	return "https"
+}
+
+// digestOf returns the sha256 digest of b.
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// untar extracts the directories and regular files of the tar archive
+// into dir. Other entries, such as links, are skipped.
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %s in archive", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode)&0755|0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dockerHubServer is the key of the credentials of Docker Hub in the
+// docker config, whichever of its registry names a reference uses.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// dockerHubRegistries are the registry names of Docker Hub.
+var dockerHubRegistries = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// DockerConfigCredentials returns the credentials of the registry stored
+// by docker login in the docker config file, if any.  Like docker, the
+// credential helper of the registry in credHelpers takes precedence over
+// the credsStore helper, which takes precedence over the auths.
+func DockerConfigCredentials(registry string) (string, string, bool) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", "", false
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+		CredsStore  string            `json:"credsStore"`
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return "", "", false
+	}
+	keys := []string{registry, "https://" + registry, "http://" + registry}
+	if dockerHubRegistries[registry] {
+		keys = []string{dockerHubServer, "docker.io", "index.docker.io"}
+	}
+
+	for _, key := range keys {
+		if helper := config.CredHelpers[key]; helper != "" {
+			return CredentialHelper(helper, key)
+		}
+	}
+	if config.CredsStore != "" {
+		for _, key := range keys {
+			if user, password, found := CredentialHelper(config.CredsStore, key); found {
+				return user, password, true
+			}
+		}
+	}
+	for _, key := range keys {
+		auth, found := config.Auths[key]
+		if !found || auth.Auth == "" && auth.Username == "" {
+			continue
+		}
+		if auth.Username != "" {
+			return auth.Username, auth.Password, true
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", false
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+// CredentialHelper returns the credentials of the server stored by the
+// docker credential helper, by running docker-credential-<helper> get.
+// Making it a var so that it can be overridden for testing.
+var CredentialHelper = func(helper, server string) (string, string, bool) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	out, err := cmd.Output()
+	if err != nil {
+		// helpers exit with an error if they have no credentials of the server
+		return "", "", false
+	}
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil || creds.Secret == "" {
+		return "", "", false
+	}
+	return creds.Username, creds.Secret, true
+}
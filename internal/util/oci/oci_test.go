@@ -0,0 +1,226 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	for _, test := range []struct {
+		arg      string
+		expected Reference
+		err      bool
+	}{
+		{arg: "oci://ghcr.io/org/pkg:v1.2.3",
+			expected: Reference{Registry: "ghcr.io", Repository: "org/pkg", Ref: "v1.2.3"}},
+		{arg: "oci://ghcr.io/org/pkg",
+			expected: Reference{Registry: "ghcr.io", Repository: "org/pkg", Ref: "latest"}},
+		{arg: "oci://localhost:5000/pkg@" + digest,
+			expected: Reference{Registry: "localhost:5000", Repository: "pkg", Ref: digest}},
+		{arg: "oci://localhost:5000/pkg",
+			expected: Reference{Registry: "localhost:5000", Repository: "pkg", Ref: "latest"}},
+		{arg: "oci://ghcr.io/org/pkg@v1", err: true},
+		{arg: "oci://pkg:v1", err: true},
+	} {
+		r, err := ParseReference(test.arg)
+		if test.err {
+			assert.Error(t, err, test.arg)
+			continue
+		}
+		assert.NoError(t, err, test.arg)
+		assert.Equal(t, test.expected, r, test.arg)
+	}
+
+	r, err := NewReference("ghcr.io/org/pkg", digest)
+	assert.NoError(t, err)
+	assert.Equal(t, "ghcr.io/org/pkg@"+digest, r.String())
+	assert.Equal(t, "ghcr.io/org/pkg", r.Image())
+}
+
+// newRegistry returns a registry serving a package with a single gzipped
+// layer holding the passed files at repository pkg and tag v1. If token
+// is set, the registry requires it as a Bearer token.
+func newRegistry(t *testing.T, files map[string]string, token string) (*httptest.Server, string) {
+	var layer bytes.Buffer
+	gz := gzip.NewWriter(&layer)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)),
+			Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	layerDigest := digestOf(layer.Bytes())
+	m, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     manifestMediaTypes[0],
+		"layers": []map[string]interface{}{{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"digest":    layerDigest,
+			"size":      layer.Len(),
+		}},
+	})
+	assert.NoError(t, err)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:pkg:pull", r.URL.Query().Get("scope"))
+			fmt.Fprintf(w, `{"token": %q}`, token)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/pkg/manifests/v1", "/v2/pkg/manifests/" + digestOf(m):
+			_, _ = w.Write(m)
+		case "/v2/pkg/blobs/" + layerDigest:
+			_, _ = w.Write(layer.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, digestOf(m)
+}
+
+func TestClient_Pull(t *testing.T) {
+	server, digest := newRegistry(t, map[string]string{
+		"Kptfile":     "kind: Kptfile\n",
+		"app/cm.yaml": "kind: ConfigMap\n",
+	}, "secret")
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	for _, ref := range []string{"v1", digest} {
+		dir, err := ioutil.TempDir("", "kpt-oci-test")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer os.RemoveAll(dir)
+
+		c := &Client{}
+		pulled, err := c.Pull(Reference{Registry: registry, Repository: "pkg", Ref: ref}, dir)
+		if !assert.NoError(t, err, ref) {
+			continue
+		}
+		assert.Equal(t, digest, pulled)
+		b, err := ioutil.ReadFile(filepath.Join(dir, "app", "cm.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, "kind: ConfigMap\n", string(b))
+	}
+
+	_, err := (&Client{}).Pull(Reference{Registry: registry, Repository: "pkg", Ref: "v2"}, os.TempDir())
+	assert.Error(t, err)
+	_, err = (&Client{}).Pull(Reference{Registry: registry, Repository: "pkg",
+		Ref: "sha256:" + strings.Repeat("b", 64)}, os.TempDir())
+	assert.Error(t, err)
+}
+
+func TestUntar_InvalidPath(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Typeflag: tar.TypeReg}))
+	assert.NoError(t, tw.Close())
+	assert.Error(t, untar(&archive, os.TempDir()))
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-oci-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.json"),
+		[]byte(`{"auths": {"https://ghcr.io": {"auth": "dXNlcjpwYXNz"}}}`), 0600))
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	user, password, found := DockerConfigCredentials("ghcr.io")
+	assert.True(t, found)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", password)
+	_, _, found = DockerConfigCredentials("quay.io")
+	assert.False(t, found)
+}
+
+func TestDockerConfigCredentials_helpers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-oci-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "aHViOnB1Yg=="},
+    "gcr.io": {},
+    "quay.io": {"auth": "cXVheTpxdWF5"}
+  },
+  "credsStore": "desktop",
+  "credHelpers": {"gcr.io": "gcloud"}
+}`), 0600))
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	credentialHelper := CredentialHelper
+	defer func() { CredentialHelper = credentialHelper }()
+	CredentialHelper = func(helper, server string) (string, string, bool) {
+		switch helper + " " + server {
+		case "gcloud gcr.io":
+			return "oauth2accesstoken", "gcloud-token", true
+		case "desktop ghcr.io":
+			return "user", "desktop-token", true
+		}
+		return "", "", false
+	}
+
+	for registry, expected := range map[string][2]string{
+		// the helper of the registry takes precedence
+		"gcr.io": {"oauth2accesstoken", "gcloud-token"},
+		// the credentials store
+		"ghcr.io": {"user", "desktop-token"},
+		// the auths if the store has no credentials
+		"quay.io": {"quay", "quay"},
+		// Docker Hub, whichever its registry name
+		"docker.io":            {"hub", "pub"},
+		"registry-1.docker.io": {"hub", "pub"},
+	} {
+		user, password, found := DockerConfigCredentials(registry)
+		assert.True(t, found, registry)
+		assert.Equal(t, expected, [2]string{user, password}, registry)
+	}
+	_, _, found := DockerConfigCredentials("example.com")
+	assert.False(t, found)
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// ConfigMediaType is the media type of the config blob of pushed
+	// packages.
+	ConfigMediaType = "application/vnd.kpt.package.config.v1+json"
+
+	// LayerMediaType is the media type of the layer of pushed packages,
+	// a gzipped tar archive of the package directory.
+	LayerMediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Push pushes the package in dir to ref with the credentials of the
+// docker config, with the annotations in its manifest. Returns the digest
+// of the pushed manifest.
+func Push(dir string, ref Reference, annotations map[string]string) (string, error) {
+	c := &Client{Credentials: DockerConfigCredentials, Annotations: annotations}
+	return c.Push(dir, ref)
+}
+
+// Push pushes the package in dir, including its subpackages, to the tag
+// of ref as an image with a single layer. Returns the digest of the
+// pushed manifest, which is an immutable reference to the package.
+func (c *Client) Push(dir string, ref Reference) (string, error) {
+	if IsDigest(ref.Ref) {
+		return "", fmt.Errorf("unable to push to digest %s; push to a tag", ref.Ref)
+	}
+	c.actions, c.authorization = "pull,push", ""
+	layer, err := tarDir(dir)
+	if err != nil {
+		return "", err
+	}
+	config := []byte("{}")
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaTypes[0],
+		Config:        descriptor{MediaType: ConfigMediaType, Digest: digestOf(config), Size: int64(len(config))},
+		Layers:        []descriptor{{MediaType: LayerMediaType, Digest: digestOf(layer), Size: int64(len(layer))}},
+		Annotations:   c.Annotations,
+	}
+	for _, blob := range [][]byte{config, layer} {
+		if err := c.uploadBlob(ref, blob); err != nil {
+			return "", err
+		}
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": {m.MediaType}}
+	resp, err := c.send(ref, http.MethodPut, "/manifests/"+ref.Ref, header, body, http.StatusCreated, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return digestOf(body), nil
+}
+
+// uploadBlob uploads the blob to the repository of ref, unless the
+// registry already has it.
+func (c *Client) uploadBlob(ref Reference, blob []byte) error {
+	digest := digestOf(blob)
+	resp, err := c.send(ref, http.MethodHead, "/blobs/"+digest, nil, nil, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	resp, err = c.send(ref, http.MethodPost, "/blobs/uploads/", nil, nil, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return fmt.Errorf("invalid upload location from registry %s: %q", ref.Registry, resp.Header.Get("Location"))
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = c.send(ref, http.MethodPut, location.String(), header, blob, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// tarDir returns a gzipped tar archive of the directories and regular
+// files in dir, skipping .git directories. Entries are sorted and have
+// no timestamps, so the archive of the same files always has the same
+// digest.
+func tarDir(dir string) ([]byte, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Name() == ".git" {
+			// submodules and worktrees have a .git file rather than a directory
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path != dir && (info.IsDir() || info.Mode().IsRegular()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		hdr := &tar.Header{Name: filepath.ToSlash(rel), Mode: int64(info.Mode().Perm())}
+		if info.IsDir() {
+			hdr.Name += "/"
+			hdr.Typeflag = tar.TypeDir
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = info.Size()
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRegistry is a registry storing pushed blobs and manifests in
+// memory.
+type memoryRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (m *memoryRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v2/pkg")
+	switch {
+	case r.Method == http.MethodPost && path == "/blobs/uploads/":
+		w.Header().Set("Location", "/v2/pkg/blobs/uploads/1?state=a")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && path == "/blobs/uploads/1":
+		body, _ := ioutil.ReadAll(r.Body)
+		if digestOf(body) != r.URL.Query().Get("digest") || r.URL.Query().Get("state") != "a" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m.blobs[digestOf(body)] = body
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/blobs/"):
+		blob, found := m.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(blob)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		body, _ := ioutil.ReadAll(r.Body)
+		m.manifests[strings.TrimPrefix(path, "/manifests/")] = body
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/manifests/"):
+		body, found := m.manifests[strings.TrimPrefix(path, "/manifests/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient_Push(t *testing.T) {
+	registry := &memoryRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	ref := Reference{Registry: strings.TrimPrefix(server.URL, "http://"), Repository: "pkg", Ref: "v1"}
+
+	src, err := ioutil.TempDir("", "kpt-oci-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(src)
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub", ".git"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "Kptfile"), []byte("kind: Kptfile\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "sub", "cm.yaml"), []byte("kind: ConfigMap\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "sub", ".git", "HEAD"), []byte("ref\n"), 0600))
+
+	c := &Client{Annotations: map[string]string{"org.opencontainers.image.licenses": "Apache-2.0"}}
+	digest, err := c.Push(src, ref)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, registry.blobs, 2)
+	assert.Contains(t, string(registry.manifests["v1"]),
+		`"annotations":{"org.opencontainers.image.licenses":"Apache-2.0"}`)
+
+	// pushing the same files again results in the same digest
+	again, err := c.Push(src, ref)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, again)
+
+	dest, err := ioutil.TempDir("", "kpt-oci-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dest)
+	ref.Ref = digest
+	registry.manifests[digest] = registry.manifests["v1"]
+	pulled, err := c.Pull(ref, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, pulled)
+	b, err := ioutil.ReadFile(filepath.Join(dest, "sub", "cm.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kind: ConfigMap\n", string(b))
+	_, err = os.Stat(filepath.Join(dest, "sub", ".git"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = c.Push(src, ref)
+	assert.Error(t, err)
+}
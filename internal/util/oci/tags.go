@@ -0,0 +1,64 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Tags lists the tags of the repository of ref with the credentials of the
+// docker config.
+func Tags(ref Reference) ([]string, error) {
+	c := &Client{Credentials: DockerConfigCredentials}
+	return c.Tags(ref)
+}
+
+// nextLink matches the url of the next page in a Link header.
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Tags lists the tags of the repository of ref, following pagination.
+func (c *Client) Tags(ref Reference) ([]string, error) {
+	c.actions, c.authorization = "pull", ""
+	var tags []string
+	u := "/tags/list"
+	for u != "" {
+		resp, err := c.send(ref, http.MethodGet, u, nil, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tags list from registry %s: %v", ref.Registry, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		u = ""
+		if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			next, err := resp.Request.URL.Parse(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid next link from registry %s: %v", ref.Registry, err)
+			}
+			u = next.String()
+		}
+	}
+	return tags, nil
+}
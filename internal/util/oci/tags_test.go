@@ -0,0 +1,49 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Tags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/org/pkg/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/org/pkg/tags/list?n=2&last=v1.1.0>; rel="next"`)
+			fmt.Fprint(w, `{"name": "org/pkg", "tags": ["v1.0.0", "v1.1.0"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"name": "org/pkg", "tags": ["v2.0.0"]}`)
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	tags, err := (&Client{}).Tags(Reference{Registry: registry, Repository: "org/pkg", Ref: DefaultTag})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0", "v2.0.0"}, tags)
+
+	_, err = (&Client{}).Tags(Reference{Registry: registry, Repository: "org/missing", Ref: DefaultTag})
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	crdKind = "CustomResourceDefinition"
+
+	listTypeExtension      = "x-kubernetes-list-type"
+	listMapKeysExtension   = "x-kubernetes-list-map-keys"
+	mergeKeyExtension      = "x-kubernetes-patch-merge-key"
+	patchStrategyExtension = "x-kubernetes-patch-strategy"
+	gvkExtension           = "x-kubernetes-group-version-kind"
+)
+
+// AddCRDSchemas adds the schemas of the CustomResourceDefinitions found in
+// the package directories to the kyaml openAPI schema, so the list merge keys
+// of the custom resources are known when merging them.  Directories which
+// do not exist are skipped.
+func AddCRDSchemas(dirs ...string) error {
+	var crds []*yaml.RNode
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		nodes, err := (&kio.LocalPackageReader{PackagePath: dir}).Read()
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			meta, err := node.GetMeta()
+			if err != nil || meta.Kind != crdKind ||
+				!strings.HasPrefix(meta.APIVersion, "apiextensions.k8s.io/") {
+				continue
+			}
+			crds = append(crds, node)
+		}
+	}
+	if len(crds) == 0 {
+		return nil
+	}
+
+	s, err := CRDSchema(crds)
+	if err != nil {
+		return err
+	}
+	// initialize the configured schema before adding to it
+	openapi.Schema()
+	return openapi.AddSchema(s)
+}
+
+// CRDSchema returns a swagger document containing a definition for each
+// version of the CustomResourceDefinitions.  List map keys of the structural
+// schemas are translated to the patch merge keys used by the merge.
+func CRDSchema(crds []*yaml.RNode) ([]byte, error) {
+	definitions := map[string]interface{}{}
+	for _, crd := range crds {
+		var c struct {
+			Spec struct {
+				Group string `yaml:"group"`
+				Names struct {
+					Kind string `yaml:"kind"`
+				} `yaml:"names"`
+				Validation *struct {
+					OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+				} `yaml:"validation"`
+				Versions []struct {
+					Name   string `yaml:"name"`
+					Schema *struct {
+						OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+					} `yaml:"schema"`
+				} `yaml:"versions"`
+				Version string `yaml:"version"`
+			} `yaml:"spec"`
+		}
+		if err := crd.YNode().Decode(&c); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if c.Spec.Group == "" || c.Spec.Names.Kind == "" {
+			return nil, errors.Errorf("invalid %s %s: missing group or kind",
+				crdKind, crd.GetName())
+		}
+
+		versions := map[string]map[string]interface{}{}
+		for _, v := range c.Spec.Versions {
+			versions[v.Name] = nil
+			if v.Schema != nil {
+				versions[v.Name] = v.Schema.OpenAPIV3Schema
+			}
+		}
+		if c.Spec.Version != "" {
+			if _, found := versions[c.Spec.Version]; !found {
+				versions[c.Spec.Version] = nil
+			}
+		}
+		for name, schema := range versions {
+			// apiextensions.k8s.io/v1beta1 allows a schema for all versions
+			if schema == nil && c.Spec.Validation != nil {
+				schema = c.Spec.Validation.OpenAPIV3Schema
+			}
+			if schema == nil {
+				continue
+			}
+			d := toMergeSchema(schema).(map[string]interface{})
+			d[gvkExtension] = []interface{}{map[string]interface{}{
+				"group":   c.Spec.Group,
+				"version": name,
+				"kind":    c.Spec.Names.Kind,
+			}}
+			definitions[fmt.Sprintf("%s.%s.%s", c.Spec.Group, name, c.Spec.Names.Kind)] = d
+		}
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"swagger":     "2.0",
+		"info":        map[string]interface{}{"title": "CustomResourceDefinitions", "version": "v1"},
+		"paths":       map[string]interface{}{},
+		"definitions": definitions,
+	})
+	return b, errors.Wrap(err)
+}
+
+// toMergeSchema returns a copy of the structural schema v with map lists
+// annotated with the merge key of their elements.
+func toMergeSchema(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range v {
+			m[k] = toMergeSchema(val)
+		}
+		// kyaml only supports merging lists on a single key
+		keys, _ := v[listMapKeysExtension].([]interface{})
+		if v[listTypeExtension] == "map" && len(keys) == 1 {
+			if _, found := v[mergeKeyExtension]; !found {
+				m[mergeKeyExtension] = keys[0]
+				m[patchStrategyExtension] = "merge"
+			}
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i := range v {
+			l[i] = toMergeSchema(v[i])
+		}
+		return l
+	default:
+		return v
+	}
+}
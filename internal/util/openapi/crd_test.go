@@ -0,0 +1,130 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              ports:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys:
+                - port
+                items:
+                  type: object
+                  properties:
+                    port:
+                      type: integer
+              hosts:
+                type: array
+                x-kubernetes-list-type: atomic
+                items:
+                  type: string
+`
+
+func TestCRDSchema(t *testing.T) {
+	b, err := CRDSchema([]*yaml.RNode{yaml.MustParse(crd)})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var s struct {
+		Definitions map[string]struct {
+			GVK        []map[string]string `json:"x-kubernetes-group-version-kind"`
+			Properties struct {
+				Spec struct {
+					Properties map[string]map[string]interface{} `json:"properties"`
+				} `json:"spec"`
+			} `json:"properties"`
+		} `json:"definitions"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &s))
+
+	d, found := s.Definitions["example.com.v1.Widget"]
+	if !assert.True(t, found) {
+		t.FailNow()
+	}
+	assert.Equal(t, []map[string]string{{"group": "example.com", "version": "v1", "kind": "Widget"}}, d.GVK)
+	ports := d.Properties.Spec.Properties["ports"]
+	assert.Equal(t, "port", ports["x-kubernetes-patch-merge-key"])
+	assert.Equal(t, "merge", ports["x-kubernetes-patch-strategy"])
+	hosts := d.Properties.Spec.Properties["hosts"]
+	assert.NotContains(t, hosts, "x-kubernetes-patch-merge-key")
+}
+
+func TestCRDSchema_missingGroup(t *testing.T) {
+	_, err := CRDSchema([]*yaml.RNode{yaml.MustParse(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  names:
+    kind: Widget
+`)})
+	assert.EqualError(t, err, "invalid CustomResourceDefinition widgets.example.com: missing group or kind")
+}
+
+func TestAddCRDSchemas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-crd-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "crd.yaml"), []byte(crd), 0600))
+
+	openapi.ResetOpenAPI()
+	defer openapi.ResetOpenAPI()
+	assert.NoError(t, AddCRDSchemas(dir, filepath.Join(dir, "missing")))
+
+	s := openapi.SchemaForResourceType(yaml.TypeMeta{APIVersion: "example.com/v1", Kind: "Widget"})
+	if !assert.NotNil(t, s) {
+		t.FailNow()
+	}
+	strategy, key := s.Field("spec").Field("ports").PatchStrategyAndKey()
+	assert.Equal(t, "merge", strategy)
+	assert.Equal(t, "port", key)
+
+	// the built-in schema is kept
+	assert.NotNil(t, openapi.SchemaForResourceType(yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}))
+}
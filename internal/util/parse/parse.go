@@ -21,15 +21,53 @@ import (
 	"strings"
 
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/helm"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"sigs.k8s.io/kustomize/kyaml/errors"
 )
 
 type Target struct {
 	kptfile.Git
+	OCI         kptfile.OCI
+	Helm        kptfile.Helm
 	Destination string
 }
 
+// OCIParseArgs parses the arguments of a package stored in an OCI registry,
+// of the form oci://REGISTRY/REPOSITORY[:TAG|@DIGEST].
+func OCIParseArgs(args []string) (Target, error) {
+	g := Target{}
+	ref, err := oci.ParseReference(args[0])
+	if err != nil {
+		return g, err
+	}
+	destination, err := getDest(args[1], ref.Image(), "/")
+	if err != nil {
+		return g, err
+	}
+	g.OCI = kptfile.OCI{Image: ref.Image(), Ref: ref.Ref}
+	g.Destination = filepath.Clean(destination)
+	return g, nil
+}
+
+// HelmParseArgs parses the arguments of a Helm chart, of the form
+// helm://HOST/PATH/CHART.
+func HelmParseArgs(args []string) (Target, error) {
+	g := Target{}
+	chart, err := helm.ParseReference(args[0])
+	if err != nil {
+		return g, err
+	}
+	destination, err := getDest(args[1], chart.Chart, "/")
+	if err != nil {
+		return g, err
+	}
+	g.Helm = chart
+	g.Destination = filepath.Clean(destination)
+	return g, nil
+}
+
 func GitParseArgs(args []string) (Target, error) {
 	g := Target{}
 	if args[0] == "-" {
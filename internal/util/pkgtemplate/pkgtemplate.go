@@ -0,0 +1,258 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pkgtemplate scaffolds new packages from built-in templates, or from
+// template packages in git repos.
+package pkgtemplate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	"github.com/GoogleContainerTools/kpt/internal/util/man"
+	"github.com/GoogleContainerTools/kpt/internal/util/parse"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fieldmeta"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Blank is the template of an empty package, with only a Kptfile and a
+// README.
+const Blank = "blank"
+
+// Templates are the names of the built-in templates.
+var Templates = []string{Blank, "deployment", "namespace", "crd-operator"}
+
+// Values are the values a package is scaffolded with.
+type Values struct {
+	// Name is the name of the package
+	Name string
+
+	// Description is the short description of the package.  Template
+	// packages keep their description if empty.
+	Description string
+
+	// Tags are the tags of the package
+	Tags []string
+
+	// URL is the url of the page with information about the package
+	URL string
+}
+
+// Scaffold writes the files of the template to the package at dir, except
+// the files which already exist, and returns the paths of the files written.
+// The template is the name of a built-in template, or a template package in
+// a git repo of the form REPO_URI[.git]/PKG_PATH[@VERSION].
+func Scaffold(dir, name string, values Values) ([]string, error) {
+	if t, found := builtins[name]; found {
+		return t.write(dir, values)
+	}
+	if !strings.Contains(name, "/") {
+		return nil, errors.Errorf("unknown template %q, must be one of: %s, or a git package",
+			name, strings.Join(Templates, ", "))
+	}
+	return scaffoldGit(dir, name, values)
+}
+
+// builtin is a built-in template.
+type builtin struct {
+	// files are the files of the template by slash separated path, which
+	// are rendered as text templates of the Values
+	files map[string]string
+
+	// setters are the names and values of the setters declared in the
+	// Kptfile.  The values are rendered as text templates of the Values.
+	setters [][2]string
+}
+
+// write writes the Kptfile, the README and the files of the template.
+func (b builtin) write(dir string, values Values) ([]string, error) {
+	var written []string
+	kf, err := b.kptfile(values)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, kptfile.KptFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := writeKptfile(path, kf); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	readme, err := render(manTemplate, values)
+	if err != nil {
+		return nil, err
+	}
+	// the README uses ' instead of ` as golang doesn't allow using ` in a
+	// raw string literal
+	files := map[string]string{man.ManFilename: strings.ReplaceAll(readme, "'", "`")}
+	for name, content := range b.files {
+		if files[name], err = render(content, values); err != nil {
+			return nil, err
+		}
+	}
+	names := []string{man.ManFilename}
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(files[name]), 0600); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// kptfile returns the Kptfile of the template, declaring its setters.
+func (b builtin) kptfile(values Values) (kptfile.KptFile, error) {
+	kf := kptfile.KptFile{ResourceMeta: kptfile.TypeMeta}
+	kf.Name = values.Name
+	kf.PackageMeta = kptfile.PackageMeta{
+		ShortDescription: values.Description,
+		URL:              values.URL,
+		Tags:             values.Tags,
+	}
+	if len(b.setters) == 0 {
+		return kf, nil
+	}
+	definitions := map[string]interface{}{}
+	for _, s := range b.setters {
+		value, err := render(s[1], values)
+		if err != nil {
+			return kf, err
+		}
+		definitions[fieldmeta.SetterDefinitionPrefix+s[0]] = map[string]interface{}{
+			"x-k8s-cli": map[string]interface{}{
+				"setter": map[string]interface{}{"name": s[0], "value": value},
+			},
+		}
+	}
+	kf.OpenAPI = map[string]interface{}{"definitions": definitions}
+	return kf, nil
+}
+
+func writeKptfile(path string, kf kptfile.KptFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer f.Close()
+	e := yaml.NewEncoder(f)
+	defer e.Close()
+	return errors.Wrap(e.Encode(kf))
+}
+
+func render(text string, values Values) (string, error) {
+	t, err := template.New("").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	b := &bytes.Buffer{}
+	if err := t.Execute(b, values); err != nil {
+		return "", errors.Wrap(err)
+	}
+	return b.String(), nil
+}
+
+// scaffoldGit fetches the template package from git, and copies its files to
+// the package at dir.  The Kptfile of the template is written without its
+// upstream, with the name and metadata of the values.
+func scaffoldGit(dir, name string, values Values) ([]string, error) {
+	tmp, err := ioutil.TempDir("", "kpt-init-")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer os.RemoveAll(tmp)
+	t, err := parse.GitParseArgs([]string{name, tmp})
+	if err != nil {
+		return nil, err
+	}
+	src := filepath.Join(tmp, "template")
+	if err := (get.Command{Git: t.Git, Destination: src, Depth: git.DefaultDepth}).Run(); err != nil {
+		return nil, errors.Errorf("failed to fetch template %s: %v", name, err)
+	}
+
+	var written []string
+	kf, err := kptfileutil.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	kf.Upstream = kptfile.Upstream{}
+	kf.Name = values.Name
+	if values.Description != "" {
+		kf.PackageMeta.ShortDescription = values.Description
+	}
+	if values.URL != "" {
+		kf.PackageMeta.URL = values.URL
+	}
+	if len(values.Tags) > 0 {
+		kf.PackageMeta.Tags = values.Tags
+	}
+	if _, err := os.Stat(filepath.Join(dir, kptfile.KptFileName)); os.IsNotExist(err) {
+		if err := kptfileutil.WriteFile(dir, kf); err != nil {
+			return nil, err
+		}
+		written = append(written, filepath.Join(dir, kptfile.KptFileName))
+	}
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dest, 0700)
+		}
+		if rel == kptfile.KptFileName {
+			return nil
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dest, b, info.Mode().Perm()); err != nil {
+			return err
+		}
+		written = append(written, dest)
+		return nil
+	})
+	return written, errors.Wrap(err)
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkgtemplate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	. "github.com/GoogleContainerTools/kpt/internal/util/pkgtemplate"
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/setters2/settersutil"
+)
+
+func TestScaffold(t *testing.T) {
+	tests := map[string]struct {
+		files   []string
+		setters map[string]string
+	}{
+		"blank": {
+			files: []string{"Kptfile", "README.md"},
+		},
+		"deployment": {
+			files:   []string{"Kptfile", "README.md", "deployment.yaml", "functions.yaml", "service.yaml"},
+			setters: map[string]string{"name": "my-app", "image": "nginx:1.19", "replicas": "1", "port": "80"},
+		},
+		"namespace": {
+			files: []string{"Kptfile", "README.md", "functions.yaml", "namespace.yaml",
+				"resourcequota.yaml", "rolebinding.yaml"},
+			setters: map[string]string{"namespace": "my-app", "cpu-quota": "4", "memory-quota": "8Gi",
+				"admin-group": "my-app-admins@example.com"},
+		},
+		"crd-operator": {
+			files: []string{"Kptfile", "README.md", "crd.yaml", "deployment.yaml", "functions.yaml",
+				"namespace.yaml", "rbac.yaml"},
+			setters: map[string]string{"name": "my-app", "namespace": "my-app-system",
+				"image": "gcr.io/example/my-app:v0.1.0"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			openapi.ResetOpenAPI()
+			defer openapi.ResetOpenAPI()
+			d, err := ioutil.TempDir("", "kpt-template-test")
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			defer os.RemoveAll(d)
+			dir := filepath.Join(d, "my-app")
+			assert.NoError(t, os.Mkdir(dir, 0700))
+
+			written, err := Scaffold(dir, name, Values{Name: "my-app", Description: "my app"})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			var files []string
+			for _, path := range written {
+				files = append(files, filepath.Base(path))
+			}
+			assert.Equal(t, test.files, files)
+
+			kf, err := kptfileutil.ReadFile(dir)
+			assert.NoError(t, err)
+			assert.Equal(t, "my-app", kf.Name)
+			assert.Equal(t, "my app", kf.PackageMeta.ShortDescription)
+			values, err := setters.Values(dir)
+			assert.NoError(t, err)
+			if test.setters == nil {
+				assert.Empty(t, values)
+				return
+			}
+			assert.Equal(t, test.setters, values)
+
+			// every setter is referenced by the resources
+			for setter, value := range test.setters {
+				openapi.ResetOpenAPI()
+				fs := &settersutil.FieldSetter{
+					Name:            setter,
+					Value:           value,
+					OpenAPIPath:     filepath.Join(dir, kptfile.KptFileName),
+					OpenAPIFileName: kptfile.KptFileName,
+					ResourcesPath:   dir,
+				}
+				count, err := fs.Set()
+				assert.NoError(t, err)
+				assert.NotZero(t, count, setter)
+			}
+
+			// existing files are not overwritten
+			written, err = Scaffold(dir, name, Values{Name: "my-app", Description: "my app"})
+			assert.NoError(t, err)
+			assert.Empty(t, written)
+		})
+	}
+}
+
+func TestScaffold_unknown(t *testing.T) {
+	_, err := Scaffold(".", "statefulset", Values{Name: "my-app"})
+	assert.EqualError(t, err,
+		`unknown template "statefulset", must be one of: blank, deployment, namespace, crd-operator, or a git package`)
+}
+
+func TestScaffold_git(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	dir := filepath.Join(w.WorkspaceDirectory, "my-app")
+	assert.NoError(t, os.Mkdir(dir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# my app\n"), 0600))
+
+	written, err := Scaffold(dir, "file://"+g.RepoDirectory+".git/mysql@master",
+		Values{Name: "my-app", Tags: []string{"app.kpt.dev/mysql"}})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Contains(t, written, filepath.Join(dir, kptfile.KptFileName))
+	assert.NotContains(t, written, filepath.Join(dir, "README.md"))
+
+	// the files of the template are copied, except the existing files
+	for _, name := range []string{"mysql-configmap.resource.yaml", "mysql-service.resource.yaml",
+		"mysql-statefulset.resource.yaml"} {
+		assert.Contains(t, written, filepath.Join(dir, name))
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# my app\n", string(b))
+
+	// the Kptfile does not record the template as its upstream
+	kf, err := kptfileutil.ReadFile(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-app", kf.Name)
+	assert.Equal(t, kptfile.Upstream{}, kf.Upstream)
+	assert.Equal(t, []string{"app.kpt.dev/mysql"}, kf.PackageMeta.Tags)
+}
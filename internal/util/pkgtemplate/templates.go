@@ -0,0 +1,269 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkgtemplate
+
+// builtins are the built-in templates by name.
+var builtins = map[string]builtin{
+	Blank: {},
+	"deployment": {
+		files: map[string]string{
+			"deployment.yaml": deploymentTemplate,
+			"service.yaml":    serviceTemplate,
+			"functions.yaml":  functionsTemplate,
+		},
+		setters: [][2]string{
+			{"name", "{{.Name}}"},
+			{"image", "nginx:1.19"},
+			{"replicas", "1"},
+			{"port", "80"},
+		},
+	},
+	"namespace": {
+		files: map[string]string{
+			"namespace.yaml":     namespaceTemplate,
+			"resourcequota.yaml": resourceQuotaTemplate,
+			"rolebinding.yaml":   roleBindingTemplate,
+			"functions.yaml":     functionsTemplate,
+		},
+		setters: [][2]string{
+			{"namespace", "{{.Name}}"},
+			{"cpu-quota", "4"},
+			{"memory-quota", "8Gi"},
+			{"admin-group", "{{.Name}}-admins@example.com"},
+		},
+	},
+	"crd-operator": {
+		files: map[string]string{
+			"crd.yaml":        crdTemplate,
+			"namespace.yaml":  operatorNamespaceTemplate,
+			"rbac.yaml":       operatorRBACTemplate,
+			"deployment.yaml": operatorDeploymentTemplate,
+			"functions.yaml":  functionsTemplate,
+		},
+		setters: [][2]string{
+			{"name", "{{.Name}}"},
+			{"namespace", "{{.Name}}-system"},
+			{"image", "gcr.io/example/{{.Name}}:v0.1.0"},
+		},
+	},
+}
+
+// functionsTemplate declares the functions run on the package by
+// kpt fn run.  It is local configuration, so it is not applied.
+var functionsTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: validate
+  annotations:
+    config.k8s.io/function: |
+      container:
+        image: gcr.io/kpt-functions/kubeval
+        network: true
+    config.kubernetes.io/local-config: "true"
+`
+
+var deploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+  labels:
+    app: {{.Name}} # {"$openapi":"name"}
+spec:
+  replicas: 1 # {"$openapi":"replicas"}
+  selector:
+    matchLabels:
+      app: {{.Name}} # {"$openapi":"name"}
+  template:
+    metadata:
+      labels:
+        app: {{.Name}} # {"$openapi":"name"}
+    spec:
+      containers:
+      - name: {{.Name}} # {"$openapi":"name"}
+        image: nginx:1.19 # {"$openapi":"image"}
+        ports:
+        - containerPort: 80 # {"$openapi":"port"}
+`
+
+var serviceTemplate = `apiVersion: v1
+kind: Service
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+  labels:
+    app: {{.Name}} # {"$openapi":"name"}
+spec:
+  selector:
+    app: {{.Name}} # {"$openapi":"name"}
+  ports:
+  - port: 80
+    targetPort: 80 # {"$openapi":"port"}
+`
+
+var namespaceTemplate = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{.Name}} # {"$openapi":"namespace"}
+`
+
+var resourceQuotaTemplate = `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: default
+  namespace: {{.Name}} # {"$openapi":"namespace"}
+spec:
+  hard:
+    requests.cpu: "4" # {"$openapi":"cpu-quota"}
+    requests.memory: 8Gi # {"$openapi":"memory-quota"}
+`
+
+var roleBindingTemplate = `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: admins
+  namespace: {{.Name}} # {"$openapi":"namespace"}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: admin
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: {{.Name}}-admins@example.com # {"$openapi":"admin-group"}
+`
+
+var crdTemplate = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: apps.example.com
+spec:
+  group: example.com
+  names:
+    kind: App
+    listKind: AppList
+    plural: apps
+    singular: app
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+          status:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+    subresources:
+      status: {}
+`
+
+var operatorNamespaceTemplate = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{.Name}}-system # {"$openapi":"namespace"}
+`
+
+var operatorRBACTemplate = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+  namespace: {{.Name}}-system # {"$openapi":"namespace"}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+rules:
+- apiGroups: ["example.com"]
+  resources: ["apps", "apps/status"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: [""]
+  resources: ["events"]
+  verbs: ["create", "patch"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: {{.Name}} # {"$openapi":"name"}
+subjects:
+- kind: ServiceAccount
+  name: {{.Name}} # {"$openapi":"name"}
+  namespace: {{.Name}}-system # {"$openapi":"namespace"}
+`
+
+var operatorDeploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}} # {"$openapi":"name"}
+  namespace: {{.Name}}-system # {"$openapi":"namespace"}
+  labels:
+    app: {{.Name}} # {"$openapi":"name"}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: {{.Name}} # {"$openapi":"name"}
+  template:
+    metadata:
+      labels:
+        app: {{.Name}} # {"$openapi":"name"}
+    spec:
+      serviceAccountName: {{.Name}} # {"$openapi":"name"}
+      containers:
+      - name: operator
+        image: gcr.io/example/{{.Name}}:v0.1.0 # {"$openapi":"image"}
+`
+
+// manTemplate is the content for the automatically generated README.md file.
+// It uses ' instead of ` since golang doesn't allow using ` in a raw string
+// literal. We do a replace on the content before printing.
+var manTemplate = `# {{.Name}}
+
+## Description
+{{.Description}}
+
+## Usage
+
+### Fetch the package
+'kpt pkg get REPO_URI[.git]/PKG_PATH[@VERSION] {{.Name}}'
+Details: https://googlecontainertools.github.io/kpt/reference/pkg/get/
+
+### View package content
+'kpt cfg tree {{.Name}}'
+Details: https://googlecontainertools.github.io/kpt/reference/cfg/tree/
+
+### List setters
+'kpt cfg list-setters {{.Name}}'
+Details: https://googlecontainertools.github.io/kpt/reference/cfg/list-setters/
+
+### Set a value
+'kpt cfg set {{.Name}} NAME VALUE'
+Details: https://googlecontainertools.github.io/kpt/reference/cfg/set/
+
+### Apply the package
+'''
+kpt live init {{.Name}}
+kpt live apply {{.Name}} --reconcile-timeout=2m --output=table
+'''
+Details: https://googlecontainertools.github.io/kpt/reference/live/
+`
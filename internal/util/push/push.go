@@ -0,0 +1,253 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package push contains libraries for publishing packages.
+package push
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Command publishes a local package, including its subpackages, to a tag of a
+// git repository or an OCI registry, and records the immutable reference of the
+// published package in the local Kptfile.
+type Command struct {
+	// Path is the filepath to the local package
+	Path string
+
+	// Git contains the repo, subdirectory and tag to push the package to
+	Git kptfile.Git
+
+	// OCI contains the image and tag to push the package to.  Takes precedence
+	// over Git if its image is set.
+	OCI kptfile.OCI
+}
+
+// Run runs the Command.  Returns the published reference.
+func (c Command) Run() (kptfile.Upstream, error) {
+	kf, err := kptfileutil.ReadFile(c.Path)
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+
+	dir, err := stage(c.Path)
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	var published kptfile.Upstream
+	if c.OCI.Image != "" {
+		published, err = c.pushOCI(dir)
+	} else {
+		published, err = c.pushGit(dir)
+	}
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+
+	kf.Published = published
+	return published, kptfileutil.WriteFile(c.Path, kf)
+}
+
+// stage copies the package to a temporary directory, without the published
+// reference of its Kptfile, which only describes the local package.
+func stage(pkgPath string) (string, error) {
+	dir, err := ioutil.TempDir("", "kpt-push-")
+	if err != nil {
+		return "", err
+	}
+	if err := copyutil.CopyDir(pkgPath, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", errors.Wrap(err)
+	}
+	kf, err := kptfileutil.ReadFile(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	kf.Published = kptfile.Upstream{}
+	if err := kptfileutil.WriteFile(dir, kf); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// pushOCI pushes the package in dir to the OCI registry.
+func (c Command) pushOCI(dir string) (kptfile.Upstream, error) {
+	ref, err := oci.NewReference(c.OCI.Image, c.OCI.Ref)
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+	kf, err := kptfileutil.ReadFile(dir)
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+	digest, err := oci.Push(dir, ref, Annotations(kf))
+	if err != nil {
+		return kptfile.Upstream{}, errors.Errorf("failed to push oci image: %v", err)
+	}
+	return kptfile.Upstream{
+		Type: kptfile.OCIOrigin,
+		OCI:  kptfile.OCI{Image: ref.Image(), Ref: ref.Ref, Digest: digest},
+	}, nil
+}
+
+// Annotations returns the OCI manifest annotations describing the package,
+// from the metadata of its Kptfile.  The first documentation URL is the
+// documentation of the image, and the keywords are joined with commas.
+func Annotations(kf kptfile.KptFile) map[string]string {
+	meta := kf.PackageMeta
+	var maintainers []string
+	for _, m := range meta.Maintainers {
+		maintainers = append(maintainers, m.String())
+	}
+	var documentation string
+	if len(meta.Documentation) > 0 {
+		documentation = meta.Documentation[0]
+	}
+	annotations := map[string]string{}
+	for key, value := range map[string]string{
+		"org.opencontainers.image.title":         kf.Name,
+		"org.opencontainers.image.description":   meta.ShortDescription,
+		"org.opencontainers.image.version":       meta.Version,
+		"org.opencontainers.image.licenses":      meta.License,
+		"org.opencontainers.image.authors":       strings.Join(maintainers, ", "),
+		"org.opencontainers.image.url":           meta.URL,
+		"org.opencontainers.image.documentation": documentation,
+		"dev.kpt.package.keywords":               strings.Join(meta.Keywords, ","),
+	} {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
+
+// pushGit commits the package in dir to the subdirectory of the repo, on top
+// of its default branch, and pushes the commit as a new tag.  The default
+// branch itself is not changed.  Tags of packages in subdirectories are
+// prefixed with the subdirectory, the same way kpt pkg get looks them up.
+func (c Command) pushGit(dir string) (kptfile.Upstream, error) {
+	if c.Git.Repo == "" {
+		return kptfile.Upstream{}, errors.Errorf("must specify repo")
+	}
+	if c.Git.Ref == "" {
+		return kptfile.Upstream{}, errors.Errorf("must specify tag")
+	}
+	repoDir, err := ioutil.TempDir("", "kpt-push-")
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+	defer os.RemoveAll(repoDir)
+
+	g := gitutil.NewLocalGitRunner(repoDir)
+	if g.Env, err = gitutil.AuthEnv(c.Git.Repo); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	run := func(args ...string) error {
+		if err := g.Run(args...); err != nil {
+			return errors.Errorf("trouble running git %s: %v: %s",
+				args[0], err, strings.TrimSpace(g.Stderr.String()))
+		}
+		return nil
+	}
+	if err := run("init"); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	if err := run("remote", "add", "origin", c.Git.Repo); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	defaultRef, err := gitutil.DefaultRef(c.Git.Repo)
+	if err != nil {
+		return kptfile.Upstream{}, err
+	}
+	// an empty repo has no default branch yet -- the tag is a root commit
+	if g.Run("fetch", "origin", "--depth=1", defaultRef) == nil {
+		if err := run("reset", "--hard", "FETCH_HEAD"); err != nil {
+			return kptfile.Upstream{}, err
+		}
+	}
+
+	directory := strings.Trim(path.Clean("/"+c.Git.Directory), "/")
+	if err := replaceDir(repoDir, directory, dir); err != nil {
+		return kptfile.Upstream{}, err
+	}
+
+	tag := c.Git.Ref
+	if directory != "" {
+		tag = path.Join(directory, c.Git.Ref)
+	}
+	if err := run("add", "-A"); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	if err := run("commit", "--allow-empty", "-m", fmt.Sprintf("Publish package %s", tag)); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	if err := run("tag", tag); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	if err := run("push", "origin", "refs/tags/"+tag); err != nil {
+		return kptfile.Upstream{}, err
+	}
+	if err := run("rev-parse", "--verify", "HEAD"); err != nil {
+		return kptfile.Upstream{}, err
+	}
+
+	return kptfile.Upstream{
+		Type: kptfile.GitOrigin,
+		Git: kptfile.Git{
+			Repo:      c.Git.Repo,
+			Directory: "/" + directory,
+			Ref:       c.Git.Ref,
+			Commit:    strings.TrimSpace(g.Stdout.String()),
+		},
+	}, nil
+}
+
+// replaceDir replaces the contents of the subdirectory of the repo with the
+// package in pkgDir, keeping the .git directory of the repo.
+func replaceDir(repoDir, directory, pkgDir string) error {
+	target := filepath.Join(repoDir, filepath.FromSlash(directory))
+	if directory == "" {
+		entries, err := ioutil.ReadDir(repoDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Name() == ".git" {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(repoDir, e.Name())); err != nil {
+				return err
+			}
+		}
+	} else if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return errors.Wrap(copyutil.CopyDir(pkgDir, target))
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	. "github.com/GoogleContainerTools/kpt/internal/util/push"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommand_Run_git verifies that the package is pushed as a new tag
+// prefixed with the subdirectory, and that the commit of the tag is
+// recorded in the local Kptfile.
+func TestCommand_Run_git(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+
+	pkg := filepath.Join(w.WorkspaceDirectory, "app")
+	if !assert.NoError(t, os.MkdirAll(pkg, 0700)) {
+		t.FailNow()
+	}
+	kf := kptfile.KptFile{ResourceMeta: kptfile.TypeMeta}
+	kf.Name = "app"
+	assert.NoError(t, kptfileutil.WriteFile(pkg, kf))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pkg, "cm.yaml"), []byte("kind: ConfigMap\n"), 0600))
+
+	published, err := Command{
+		Path: pkg,
+		Git:  kptfile.Git{Repo: g.RepoDirectory, Directory: "/app", Ref: "v1"},
+	}.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	gr := gitutil.NewLocalGitRunner(g.RepoDirectory)
+	assert.NoError(t, gr.Run("rev-parse", "app/v1"))
+	assert.Equal(t, kptfile.Upstream{
+		Type: kptfile.GitOrigin,
+		Git: kptfile.Git{Repo: g.RepoDirectory, Directory: "/app", Ref: "v1",
+			Commit: strings.TrimSpace(gr.Stdout.String())},
+	}, published)
+
+	// the tag contains the package, without the published reference
+	gr = gitutil.NewLocalGitRunner(g.RepoDirectory)
+	assert.NoError(t, gr.Run("show", "app/v1:app/cm.yaml"))
+	assert.Equal(t, "kind: ConfigMap\n", gr.Stdout.String())
+	gr = gitutil.NewLocalGitRunner(g.RepoDirectory)
+	assert.NoError(t, gr.Run("show", "app/v1:app/Kptfile"))
+	assert.NotContains(t, gr.Stdout.String(), "published")
+
+	// the rest of the repo and the default branch are unchanged
+	gr = gitutil.NewLocalGitRunner(g.RepoDirectory)
+	assert.NoError(t, gr.Run("ls-tree", "--name-only", "app/v1"))
+	assert.Contains(t, gr.Stdout.String(), "java")
+	gr = gitutil.NewLocalGitRunner(g.RepoDirectory)
+	assert.NoError(t, gr.Run("ls-tree", "--name-only", "master"))
+	assert.NotContains(t, gr.Stdout.String(), "app")
+
+	kf, err = kptfileutil.ReadFile(pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, published, kf.Published)
+}
+
+func TestCommand_Run_noKptfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-push-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = Command{Path: dir, Git: kptfile.Git{Repo: "file:///nowhere", Ref: "v1"}}.Run()
+	assert.Error(t, err)
+}
+
+func TestAnnotations(t *testing.T) {
+	kf := kptfile.KptFile{ResourceMeta: kptfile.TypeMeta}
+	kf.Name = "app"
+	kf.PackageMeta = kptfile.PackageMeta{
+		ShortDescription: "a web app",
+		License:          "Apache-2.0",
+		Maintainers: []kptfile.Maintainer{
+			{Name: "Jane Doe", Email: "jane@example.com"},
+			{Name: "Platform Team"},
+		},
+		Keywords:      []string{"web", "nginx"},
+		Documentation: []string{"https://example.com/app", "https://example.com/app/faq"},
+	}
+	assert.Equal(t, map[string]string{
+		"org.opencontainers.image.title":         "app",
+		"org.opencontainers.image.description":   "a web app",
+		"org.opencontainers.image.licenses":      "Apache-2.0",
+		"org.opencontainers.image.authors":       "Jane Doe <jane@example.com>, Platform Team",
+		"org.opencontainers.image.documentation": "https://example.com/app",
+		"dev.kpt.package.keywords":               "web,nginx",
+	}, Annotations(kf))
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package readiness records the conditions of the packages declaring
+// readiness gates in their Kptfile, and checks if the packages are ready.
+package readiness
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Record sets the conditions in the status of the Kptfile of the package at
+// pkgPath.  Packages without readiness gates are left unchanged.
+func Record(pkgPath string, conditions ...kptfile.StatusCondition) error {
+	kf, found, err := readKptfile(pkgPath)
+	if err != nil || !found || len(kf.ReadinessGates) == 0 {
+		return err
+	}
+	for _, c := range conditions {
+		kf.SetCondition(c)
+	}
+	return kptfileutil.WriteFile(pkgPath, kf)
+}
+
+// Check returns an error listing the readiness gates of the package at
+// pkgPath which aren't met, except the gates of the ignored condition types.
+// The SettersResolved condition is evaluated rather than read from the
+// status, as the setters may have been set since it was recorded.
+func Check(pkgPath string, ignore ...string) error {
+	kf, found, err := readKptfile(pkgPath)
+	if err != nil || !found || len(kf.ReadinessGates) == 0 {
+		return err
+	}
+	kf.SetCondition(SettersResolved(pkgPath))
+	var unmet []string
+	for _, g := range kf.UnmetReadinessGates() {
+		if contains(ignore, g.ConditionType) {
+			continue
+		}
+		msg := g.ConditionType
+		if c := kf.GetCondition(g.ConditionType); c != nil && c.Message != "" {
+			msg += ": " + c.Message
+		}
+		unmet = append(unmet, msg)
+	}
+	if len(unmet) > 0 {
+		return errors.Errorf("package %s is not ready:\n  - %s", pkgPath, strings.Join(unmet, "\n  - "))
+	}
+	return nil
+}
+
+// SettersResolved returns the SettersResolved condition of the package at
+// pkgPath.
+func SettersResolved(pkgPath string) kptfile.StatusCondition {
+	c := kptfile.StatusCondition{Type: kptfile.ConditionSettersResolved, Status: kptfile.ConditionTrue}
+	if err := setters.CheckForRequiredSetters(pkgPath); err != nil {
+		c.Status, c.Reason, c.Message = kptfile.ConditionFalse, "RequiredSettersNotSet", err.Error()
+	}
+	return c
+}
+
+// Validated returns the Validated condition of a package whose functions
+// returned err.
+func Validated(err error) kptfile.StatusCondition {
+	c := kptfile.StatusCondition{Type: kptfile.ConditionValidated, Status: kptfile.ConditionTrue}
+	if err != nil {
+		c.Status, c.Reason, c.Message = kptfile.ConditionFalse, "FunctionFailed", err.Error()
+	}
+	return c
+}
+
+// readKptfile reads the Kptfile of the package, returning false if the
+// package has no Kptfile.
+func readKptfile(pkgPath string) (kptfile.KptFile, bool, error) {
+	if _, err := os.Stat(filepath.Join(pkgPath, kptfile.KptFileName)); os.IsNotExist(err) {
+		return kptfile.KptFile{}, false, nil
+	}
+	kf, err := kptfileutil.ReadFile(pkgPath)
+	return kf, err == nil, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package readiness
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const gatedKptfile = `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+          required: true
+readinessGates:
+- conditionType: SettersResolved
+- conditionType: Validated
+- conditionType: Reconciled
+`
+
+func TestRecord(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile(kptfile.KptFileName, gatedKptfile))
+
+	assert.NoError(t, Record(d, SettersResolved(d), Validated(fmt.Errorf("kubeval failed"))))
+	kf, err := kptfileutil.ReadFile(d)
+	assert.NoError(t, err)
+	if assert.NotNil(t, kf.Status) && assert.Len(t, kf.Status.Conditions, 2) {
+		assert.Equal(t, kptfile.ConditionSettersResolved, kf.Status.Conditions[0].Type)
+		assert.Equal(t, kptfile.ConditionFalse, kf.Status.Conditions[0].Status)
+		assert.Equal(t, "RequiredSettersNotSet", kf.Status.Conditions[0].Reason)
+		assert.Equal(t, kptfile.StatusCondition{Type: kptfile.ConditionValidated,
+			Status: kptfile.ConditionFalse, Reason: "FunctionFailed", Message: "kubeval failed"},
+			kf.Status.Conditions[1])
+	}
+
+	err = Check(d, kptfile.ConditionReconciled)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "package "+d+" is not ready:\n  - SettersResolved: ")
+		assert.Contains(t, err.Error(), "\n  - Validated: kubeval failed")
+		assert.NotContains(t, err.Error(), "Reconciled")
+	}
+}
+
+func TestRecord_noReadinessGates(t *testing.T) {
+	content := `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+`
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile(kptfile.KptFileName, content))
+
+	assert.NoError(t, Record(d, Validated(nil)))
+	b, err := ioutil.ReadFile(filepath.Join(d, kptfile.KptFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(b))
+	assert.NoError(t, Check(d))
+}
+
+func TestCheck(t *testing.T) {
+	d := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFile(kptfile.KptFileName, `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+readinessGates:
+- conditionType: SettersResolved
+- conditionType: Validated
+status:
+  conditions:
+  - type: Validated
+    status: "True"
+`))
+
+	assert.NoError(t, Check(d))
+}
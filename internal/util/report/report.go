@@ -0,0 +1,226 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report writes the results of kpt commands in the formats
+// understood by CI systems: JUnit XML test reports, and SARIF logs.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// The report formats.
+const (
+	FormatJUnit = "junit"
+	FormatSARIF = "sarif"
+)
+
+// IsFormat returns true if the passed output format is a report format.
+func IsFormat(format string) bool {
+	return format == FormatJUnit || format == FormatSARIF
+}
+
+// Result is the outcome of checking a resource, such as its apply or its
+// status.
+type Result struct {
+	// Category is what was checked, e.g. "apply" or "status". It is the
+	// test suite of JUnit reports, and the rule of SARIF logs.
+	Category string
+	// Resource identifies the checked resource.
+	Resource string
+	// File is the path of the file declaring the resource, if known.
+	File string
+	// Failed is true if the check failed.
+	Failed bool
+	// Message describes the outcome of the check.
+	Message string
+}
+
+// Write writes the results to w in the passed report format. The tool is
+// the name of the command which produced the results.
+func Write(w io.Writer, format, tool string, results []Result) error {
+	switch format {
+	case FormatJUnit:
+		return WriteJUnit(w, tool, results)
+	case FormatSARIF:
+		return WriteSARIF(w, tool, results)
+	default:
+		return fmt.Errorf("unknown report format %q; must be junit or sarif", format)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report. Every category is
+// a test suite, in the order in which the categories first appear, and
+// every result is a test case of its suite.
+func WriteJUnit(w io.Writer, tool string, results []Result) error {
+	report := junitTestSuites{Name: tool}
+	index := map[string]int{}
+	for _, r := range results {
+		i, found := index[r.Category]
+		if !found {
+			i = len(report.Suites)
+			index[r.Category] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.Category})
+		}
+		tc := junitTestCase{Name: r.Resource, ClassName: r.Category, File: r.File}
+		suite := &report.Suites[i]
+		suite.Tests++
+		report.Tests++
+		if r.Failed {
+			tc.Failure = &junitFailure{Message: r.Message, Text: r.Message}
+			suite.Failures++
+			report.Failures++
+		} else {
+			tc.SystemOut = r.Message
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	kptURI       = "https://googlecontainertools.github.io/kpt/"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// WriteSARIF writes the failed results as a SARIF log with a single run.
+// Every category is a rule, and every failure an error of its rule,
+// located in the file declaring the resource if known.
+func WriteSARIF(w io.Writer, tool string, results []Result) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: tool, InformationURI: kptURI}},
+		Results: []sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, r := range results {
+		if !rules[r.Category] {
+			rules[r.Category] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: r.Category})
+		}
+		if !r.Failed {
+			continue
+		}
+		location := sarifLocation{
+			LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: r.Resource}},
+		}
+		if r.File != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: r.File},
+			}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    r.Category,
+			Level:     "error",
+			Message:   sarifMessage{Text: fmt.Sprintf("%s: %s", r.Resource, r.Message)},
+			Locations: []sarifLocation{location},
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var results = []Result{
+	{Category: "apply", Resource: "ConfigMap/test/a", Message: "applied"},
+	{Category: "apply", Resource: "ConfigMap/test/b", Failed: true, Message: "conflict <x>"},
+	{Category: "status", Resource: "ConfigMap/test/a", File: "cm.yaml", Failed: true, Message: "InProgress"},
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, FormatJUnit, "kpt live apply", results))
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="kpt live apply" tests="3" failures="2">
+  <testsuite name="apply" tests="2" failures="1">
+    <testcase name="ConfigMap/test/a" classname="apply">
+      <system-out>applied</system-out>
+    </testcase>
+    <testcase name="ConfigMap/test/b" classname="apply">
+      <failure message="conflict &lt;x&gt;">conflict &lt;x&gt;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="status" tests="1" failures="1">
+    <testcase name="ConfigMap/test/a" classname="status" file="cm.yaml">
+      <failure message="InProgress">InProgress</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, FormatSARIF, "kpt live apply", results))
+	var log sarifLog
+	if !assert.NoError(t, json.Unmarshal(out.Bytes(), &log)) {
+		t.FailNow()
+	}
+	assert.Equal(t, sarifVersion, log.Version)
+	if !assert.Len(t, log.Runs, 1) {
+		t.FailNow()
+	}
+	run := log.Runs[0]
+	assert.Equal(t, "kpt live apply", run.Tool.Driver.Name)
+	assert.Equal(t, []sarifRule{{ID: "apply"}, {ID: "status"}}, run.Tool.Driver.Rules)
+	if !assert.Len(t, run.Results, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, "apply", run.Results[0].RuleID)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "ConfigMap/test/b: conflict <x>", run.Results[0].Message.Text)
+	assert.Nil(t, run.Results[0].Locations[0].PhysicalLocation)
+	if assert.NotNil(t, run.Results[1].Locations[0].PhysicalLocation) {
+		assert.Equal(t, "cm.yaml", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	assert.Error(t, Write(&bytes.Buffer{}, "yaml", "kpt", results))
+	assert.True(t, IsFormat(FormatSARIF))
+	assert.False(t, IsFormat("table"))
+}
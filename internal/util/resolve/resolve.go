@@ -0,0 +1,287 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resolve contains libraries for resolving the merge conflicts left
+// in a package by an update.
+package resolve
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Choice is the side of a conflict to keep.
+type Choice string
+
+const (
+	// Local keeps the local changes
+	Local Choice = "local"
+	// Upstream keeps the upstream changes
+	Upstream Choice = "upstream"
+	// Both keeps the local changes followed by the upstream changes
+	Both Choice = "both"
+	// Skip leaves the conflict markers in the file
+	Skip Choice = "skip"
+)
+
+var Choices = []string{string(Local), string(Upstream), string(Both)}
+
+const (
+	startMarker = "<<<<<<<"
+	baseMarker  = "|||||||"
+	sepMarker   = "======="
+	endMarker   = ">>>>>>>"
+)
+
+// Command resolves the conflicts in the files of a package.
+type Command struct {
+	// Path is the path to the local package
+	Path string
+
+	// Keep resolves all conflicts by keeping this side.  If empty, the user
+	// is asked for each conflict.
+	Keep Choice
+
+	// Input is where the answers of the user are read from
+	Input io.Reader
+
+	// Output is where the conflicts and prompts are written
+	Output io.Writer
+}
+
+// Run resolves the conflicts, and returns an error if any remain.
+func (c Command) Run() error {
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	if c.Input == nil {
+		c.Input = os.Stdin
+	}
+	switch c.Keep {
+	case "", Local, Upstream, Both:
+	default:
+		return fmt.Errorf("invalid choice %q: must be one of %s", c.Keep, strings.Join(Choices, ", "))
+	}
+
+	files, err := Files(c.Path)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(c.Output, "no conflicts in %s\n", c.Path)
+		return nil
+	}
+
+	input := bufio.NewReader(c.Input)
+	remaining := 0
+	for _, f := range files {
+		path := filepath.Join(c.Path, f)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		segments, hunks := Parse(string(b))
+		n := 0
+		for i := range segments {
+			if segments[i].Hunk == nil {
+				continue
+			}
+			n++
+			choice := c.Keep
+			if choice == "" {
+				fmt.Fprintf(c.Output, "%s: conflict %d of %d\n%s", path, n, hunks, segments[i].Text)
+				if choice, err = ask(input, c.Output); err != nil {
+					return err
+				}
+			}
+			if choice != Skip {
+				segments[i] = Segment{Text: segments[i].Hunk.Resolve(choice)}
+			} else {
+				remaining++
+			}
+		}
+
+		var sb strings.Builder
+		for _, s := range segments {
+			sb.WriteString(s.Text)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(sb.String()), info.Mode()); err != nil {
+			return err
+		}
+	}
+
+	if remaining > 0 {
+		return fmt.Errorf("%d conflicts remaining in %s", remaining, c.Path)
+	}
+	fmt.Fprintf(c.Output, "resolved conflicts in %d files\n", len(files))
+	return nil
+}
+
+// ask prompts the user for the side of a conflict to keep.
+func ask(input *bufio.Reader, output io.Writer) (Choice, error) {
+	for {
+		fmt.Fprint(output, "Keep [l]ocal, [u]pstream, [b]oth or [s]kip? ")
+		line, err := input.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "l", "local":
+			return Local, nil
+		case "u", "upstream":
+			return Upstream, nil
+		case "b", "both":
+			return Both, nil
+		case "s", "skip":
+			return Skip, nil
+		}
+		if err == io.EOF {
+			return "", fmt.Errorf("no choice for conflict")
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// Files returns the files of the package containing conflict markers,
+// relative to the package.
+func Files(path string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if _, hunks := Parse(string(b)); hunks == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
+
+// Segment is a part of a file, which is either unchanged text or a conflict.
+type Segment struct {
+	// Text is the text of the segment, including the conflict markers
+	Text string
+
+	// Hunk is set if the segment is a conflict
+	Hunk *Hunk
+}
+
+// Hunk contains the sides of a conflict.
+type Hunk struct {
+	Local    string
+	Upstream string
+}
+
+// Resolve returns the text of the hunk keeping the chosen side.
+func (h *Hunk) Resolve(choice Choice) string {
+	switch choice {
+	case Local:
+		return h.Local
+	case Upstream:
+		return h.Upstream
+	default:
+		return h.Local + h.Upstream
+	}
+}
+
+// Parse splits the text into segments of unchanged text and conflicts, and
+// returns the number of conflicts.  Incomplete conflict markers are treated
+// as text.
+func Parse(text string) ([]Segment, int) {
+	lines := strings.SplitAfter(text, "\n")
+	var segments []Segment
+	var plain strings.Builder
+	hunks := 0
+	for i := 0; i < len(lines); i++ {
+		if !isMarker(lines[i], startMarker) {
+			plain.WriteString(lines[i])
+			continue
+		}
+		hunk, end, ok := parseHunk(lines, i)
+		if !ok {
+			plain.WriteString(lines[i])
+			continue
+		}
+		if plain.Len() > 0 {
+			segments = append(segments, Segment{Text: plain.String()})
+			plain.Reset()
+		}
+		segments = append(segments, Segment{Text: strings.Join(lines[i:end+1], ""), Hunk: hunk})
+		hunks++
+		i = end
+	}
+	if plain.Len() > 0 {
+		segments = append(segments, Segment{Text: plain.String()})
+	}
+	return segments, hunks
+}
+
+// parseHunk parses the conflict starting at lines[start], and returns the
+// index of its end marker.
+func parseHunk(lines []string, start int) (*Hunk, int, bool) {
+	var local, upstream strings.Builder
+	side := &local
+	base := false
+	sep := false
+	for i := start + 1; i < len(lines); i++ {
+		switch {
+		case isMarker(lines[i], baseMarker) && !sep:
+			base = true
+		case isMarker(lines[i], sepMarker) && !sep:
+			sep = true
+			side = &upstream
+		case isMarker(lines[i], endMarker) && sep:
+			return &Hunk{Local: local.String(), Upstream: upstream.String()}, i, true
+		case isMarker(lines[i], startMarker):
+			return nil, 0, false
+		case base && !sep:
+			// the original text is not kept
+		default:
+			side.WriteString(lines[i])
+		}
+	}
+	return nil, 0, false
+}
+
+func isMarker(line, marker string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	if marker == sepMarker {
+		return line == marker
+	}
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
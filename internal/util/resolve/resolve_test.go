@@ -0,0 +1,145 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	. "github.com/GoogleContainerTools/kpt/internal/util/resolve"
+	"github.com/stretchr/testify/assert"
+)
+
+const conflicted = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+<<<<<<< local
+  replicas: 5
+=======
+  replicas: 3
+>>>>>>> upstream
+  template:
+    spec:
+      containers:
+      - name: app
+<<<<<<< local
+        image: app:local
+||||||| original
+        image: app:v1
+=======
+        image: app:v2
+>>>>>>> upstream
+`
+
+func setupPackage(t *testing.T) string {
+	return pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").
+		WithFile("deploy.yaml", conflicted).
+		WithFile("service.yaml", "kind: Service\n"))
+}
+
+func TestParse(t *testing.T) {
+	segments, hunks := Parse(conflicted)
+	assert.Equal(t, 2, hunks)
+	if !assert.Len(t, segments, 4) {
+		t.FailNow()
+	}
+	assert.Nil(t, segments[0].Hunk)
+	assert.Equal(t, &Hunk{Local: "  replicas: 5\n", Upstream: "  replicas: 3\n"}, segments[1].Hunk)
+	assert.Equal(t, &Hunk{Local: "        image: app:local\n", Upstream: "        image: app:v2\n"}, segments[3].Hunk)
+
+	// the segments contain the whole text
+	var sb strings.Builder
+	for _, s := range segments {
+		sb.WriteString(s.Text)
+	}
+	assert.Equal(t, conflicted, sb.String())
+}
+
+func TestParse_incomplete(t *testing.T) {
+	text := "a: 1\n<<<<<<< local\nb: 1\n=======\n"
+	segments, hunks := Parse(text)
+	assert.Equal(t, 0, hunks)
+	assert.Equal(t, []Segment{{Text: text}}, segments)
+}
+
+func TestCommand_Run_keep(t *testing.T) {
+	dir := setupPackage(t)
+
+	files, err := Files(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deploy.yaml"}, files)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, Command{Path: dir, Keep: Upstream, Output: out}.Run())
+	assert.Equal(t, "resolved conflicts in 1 files\n", out.String())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`, string(b))
+}
+
+func TestCommand_Run_interactive(t *testing.T) {
+	dir := setupPackage(t)
+
+	// an invalid answer is asked again
+	out := &bytes.Buffer{}
+	err := Command{Path: dir, Input: strings.NewReader("x\nl\ns\n"), Output: out}.Run()
+	assert.EqualError(t, err, "1 conflicts remaining in "+dir)
+	assert.Contains(t, out.String(), filepath.Join(dir, "deploy.yaml")+": conflict 2 of 2\n")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "spec:\n  replicas: 5\n  template:")
+	assert.Contains(t, string(b), "<<<<<<< local\n        image: app:local\n")
+
+	out.Reset()
+	assert.NoError(t, Command{Path: dir, Input: strings.NewReader("both\n"), Output: out}.Run())
+	b, err = ioutil.ReadFile(filepath.Join(dir, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "      - name: app\n        image: app:local\n        image: app:v2\n")
+
+	out.Reset()
+	assert.NoError(t, Command{Path: dir, Output: out}.Run())
+	assert.Equal(t, "no conflicts in "+dir+"\n", out.String())
+}
+
+func TestCommand_Run_noAnswer(t *testing.T) {
+	dir := setupPackage(t)
+
+	err := Command{Path: dir, Input: strings.NewReader(""), Output: &bytes.Buffer{}}.Run()
+	assert.EqualError(t, err, "no choice for conflict")
+}
+
+func TestCommand_Run_invalidKeep(t *testing.T) {
+	err := Command{Path: ".", Keep: "ours"}.Run()
+	assert.EqualError(t, err, `invalid choice "ours": must be one of local, upstream, both`)
+}
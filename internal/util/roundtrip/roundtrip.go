@@ -0,0 +1,259 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package roundtrip preserves the formatting of the resources of a package
+// which functions read and wrote without changing them.
+package roundtrip
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Snapshot is the content of the files of a package before functions were
+// run on it.
+type Snapshot struct {
+	dir   string
+	files map[string][]byte
+}
+
+// Take returns a snapshot of the YAML files and the Kptfiles of the package
+// at dir.  A directory which doesn't exist has no files.
+func Take(dir string) (*Snapshot, error) {
+	s := &Snapshot{dir: dir, files: map[string][]byte{}}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return s, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isResourceFile(info.Name()) {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		s.files[rel] = b
+		return nil
+	})
+	return s, errors.Wrap(err)
+}
+
+// Restore writes back the original text of the resources whose content,
+// including their comments, is unchanged since the snapshot, so only the
+// resources changed by the functions are formatted by kpt.  The files whose
+// resources are all unchanged are restored byte for byte.
+func (s *Snapshot) Restore() error {
+	return s.each(func(file string, restored []byte, _ []string) error {
+		return errors.Wrap(ioutil.WriteFile(filepath.Join(s.dir, file), restored, 0600))
+	})
+}
+
+// Verify returns an error listing the resources whose formatting changed
+// since the snapshot although their content didn't.
+func (s *Snapshot) Verify() error {
+	var churn []string
+	err := s.each(func(file string, _ []byte, resources []string) error {
+		for _, r := range resources {
+			churn = append(churn, fmt.Sprintf("%s (%s)", file, r))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(churn) > 0 {
+		return errors.Errorf("formatting of unchanged resources changed:\n  - %s",
+			strings.Join(churn, "\n  - "))
+	}
+	return nil
+}
+
+// each calls fn with each file of the snapshot which was reformatted, its
+// content with the original text of its reformatted resources, and the
+// reformatted resources.
+func (s *Snapshot) each(fn func(file string, restored []byte, resources []string) error) error {
+	var files []string
+	for file := range s.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		b, err := ioutil.ReadFile(filepath.Join(s.dir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		restored, resources, err := restore(s.files[file], b)
+		if err != nil {
+			return errors.WrapPrefixf(err, "%s", file)
+		}
+		if len(resources) == 0 {
+			continue
+		}
+		if err := fn(file, restored, resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// document is a YAML document of a file.
+type document struct {
+	// text is the text of the document, without its separator
+	text string
+
+	// content is the document formatted by kyaml, which is equal for
+	// documents differing only by their formatting
+	content string
+
+	// name is the kind and name of the resource of the document
+	name string
+}
+
+// restore returns the current content of a file with the documents whose
+// content is unchanged replaced by their original text, and the names of
+// the replaced documents.
+func restore(original, current []byte) ([]byte, []string, error) {
+	if string(original) == string(current) {
+		return current, nil, nil
+	}
+	oldDocs, err := documents(original)
+	if err != nil {
+		return nil, nil, err
+	}
+	newDocs, err := documents(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := make([]bool, len(oldDocs))
+	// match returns the first unused original document with the text, or
+	// the content if byText is false
+	match := func(d document, byText bool) int {
+		for i, old := range oldDocs {
+			if used[i] {
+				continue
+			}
+			if (byText && old.text == d.text) || (!byText && old.content == d.content) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// the documents written unchanged are matched first, so a reformatted
+	// document isn't matched with the original of a document written as is
+	unchanged := make([]bool, len(newDocs))
+	for i, d := range newDocs {
+		if j := match(d, true); j >= 0 {
+			used[j], unchanged[i] = true, true
+		}
+	}
+	var names []string
+	inOrder := len(oldDocs) == len(newDocs)
+	texts := make([]string, len(newDocs))
+	for i, d := range newDocs {
+		texts[i] = d.text
+		if unchanged[i] {
+			inOrder = inOrder && oldDocs[i].text == d.text
+			continue
+		}
+		j := match(d, false)
+		if j < 0 {
+			inOrder = false
+			continue
+		}
+		used[j] = true
+		inOrder = inOrder && i == j
+		texts[i] = oldDocs[j].text
+		if i < len(newDocs)-1 && !strings.HasSuffix(texts[i], "\n") {
+			texts[i] += "\n"
+		}
+		names = append(names, d.name)
+	}
+	if len(names) == 0 {
+		return current, nil, nil
+	}
+	if inOrder {
+		// the separators and the end of the file are restored too
+		return original, names, nil
+	}
+	return []byte(strings.Join(texts, "---\n")), names, nil
+}
+
+// documents splits the YAML documents of a file.
+func documents(b []byte) ([]document, error) {
+	var docs []document
+	var text strings.Builder
+	add := func() error {
+		d := document{text: text.String(), name: fmt.Sprintf("document %d", len(docs)+1)}
+		text.Reset()
+		if strings.TrimSpace(d.text) != "" {
+			n, err := yaml.Parse(d.text)
+			if err != nil {
+				return errors.Wrap(err)
+			}
+			if d.content, err = n.String(); err != nil {
+				return errors.Wrap(err)
+			}
+			if meta, err := n.GetMeta(); err == nil && meta.Kind != "" {
+				d.name = meta.Kind + " " + meta.Name
+			}
+		}
+		docs = append(docs, d)
+		return nil
+	}
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if strings.TrimRight(line, " \t\r\n") == "---" {
+			if err := add(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		text.WriteString(line)
+	}
+	if err := add(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// isResourceFile returns true for the files kpt reads resources from.
+func isResourceFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".yaml" || ext == ".yml" || name == kptfile.KptFileName
+}
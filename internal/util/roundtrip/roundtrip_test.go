@@ -0,0 +1,166 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtrip
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const original = `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: web
+spec:
+    replicas: 3 # scaled by hand
+    template:
+        metadata:
+            labels: &labels
+                app: web
+        spec:
+            containers:
+                -   name: web
+                    image: nginx:1.18
+---
+apiVersion: v1
+kind: Service
+metadata:
+    name: web
+spec:
+    selector:
+        app: web
+`
+
+// reformatted is the original formatted by kyaml.
+const reformatted = `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # scaled by hand
+  template:
+    metadata:
+      labels: &labels
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.18
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+`
+
+// changed is the original formatted by kyaml, with the image of the
+// deployment changed.
+const changed = `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # scaled by hand
+  template:
+    metadata:
+      labels: &labels
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+`
+
+func setup(t *testing.T, current string) (string, *Snapshot) {
+	d, err := ioutil.TempDir("", "kpt-roundtrip")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "app.yaml"), []byte(original), 0600))
+	s, err := Take(d)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "app.yaml"), []byte(current), 0600))
+	return d, s
+}
+
+func TestRestore(t *testing.T) {
+	d, s := setup(t, reformatted)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, s.Restore())
+	b, err := ioutil.ReadFile(filepath.Join(d, "app.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, original, string(b))
+}
+
+func TestRestore_changed(t *testing.T) {
+	d, s := setup(t, changed)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, s.Restore())
+	b, err := ioutil.ReadFile(filepath.Join(d, "app.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # scaled by hand
+  template:
+    metadata:
+      labels: &labels
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+---
+apiVersion: v1
+kind: Service
+metadata:
+    name: web
+spec:
+    selector:
+        app: web
+`, string(b))
+}
+
+func TestVerify(t *testing.T) {
+	d, s := setup(t, changed)
+	defer os.RemoveAll(d)
+	assert.EqualError(t, s.Verify(), "formatting of unchanged resources changed:\n  - app.yaml (Service web)")
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "app.yaml"), []byte(original), 0600))
+	assert.NoError(t, s.Verify())
+}
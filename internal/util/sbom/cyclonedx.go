@@ -0,0 +1,153 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+)
+
+// cdxDocument is a CycloneDX 1.4 document in its JSON format.
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component,omitempty"`
+}
+
+type cdxTool struct {
+	Name string `json:"name"`
+}
+
+type cdxComponent struct {
+	BOMRef             string           `json:"bom-ref"`
+	Type               string           `json:"type"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	PURL               string           `json:"purl,omitempty"`
+	Licenses           []cdxLicense     `json:"licenses,omitempty"`
+	ExternalReferences []cdxExternalRef `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty    `json:"properties,omitempty"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseName `json:"license"`
+}
+
+type cdxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cdxExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDX returns the CycloneDX document of the inventory.  Packages
+// depend on their subpackages, their dependencies and the function images
+// they reference.
+func (inv *Inventory) cycloneDX(created time.Time) cdxDocument {
+	h := inv.hash()
+	doc := cdxDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		// the serial number is a name-based uuid derived from the contents,
+		// so the same packages generate the same document
+		SerialNumber: fmt.Sprintf("urn:uuid:%s-%s-5%s-8%s-%s", h[0:8], h[8:12], h[13:16], h[17:20], h[20:32]),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Name: "kpt"}},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	dependsOn := map[string][]string{}
+	for _, p := range inv.Packages {
+		c := cdxComponent{
+			BOMRef:  "package:" + p.Path,
+			Type:    "application",
+			Name:    p.Name,
+			Version: p.version(),
+		}
+		if p.License != "" {
+			c.Licenses = []cdxLicense{{License: cdxLicenseName{Name: p.License}}}
+		}
+		if u := sourceURL(p.Upstream); u != "" {
+			c.ExternalReferences = []cdxExternalRef{{Type: "distribution", URL: u}}
+		}
+		c.Properties = append(c.Properties, cdxProperty{Name: "kpt:path", Value: p.Path})
+		if p.Upstream.Type == kptfile.OCIOrigin && p.Upstream.OCI.Digest != "" {
+			c.Properties = append(c.Properties, cdxProperty{Name: "kpt:upstream.digest", Value: p.Upstream.OCI.Digest})
+		} else if p.Upstream.Git.Commit != "" {
+			c.Properties = append(c.Properties, cdxProperty{Name: "kpt:upstream.commit", Value: p.Upstream.Git.Commit})
+		}
+
+		if p.Path == "." {
+			root := c
+			doc.Metadata.Component = &root
+		} else {
+			doc.Components = append(doc.Components, c)
+		}
+		if p.Parent != "" {
+			dependsOn[p.Parent] = append(dependsOn[p.Parent], c.BOMRef)
+		}
+	}
+
+	for _, f := range inv.Functions {
+		c := cdxComponent{
+			BOMRef:  "image:" + f.Image,
+			Type:    "container",
+			Name:    f.name(),
+			Version: f.version(),
+			PURL:    f.purl(),
+		}
+		doc.Components = append(doc.Components, c)
+		for _, p := range f.Packages {
+			dependsOn[p] = append(dependsOn[p], c.BOMRef)
+		}
+	}
+
+	for _, p := range inv.Packages {
+		deps := dependsOn[p.Path]
+		if deps == nil {
+			deps = []string{}
+		}
+		doc.Dependencies = append(doc.Dependencies, cdxDependency{Ref: "package:" + p.Path, DependsOn: deps})
+	}
+	return doc
+}
@@ -0,0 +1,348 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sbom generates software bills of materials of packages, listing
+// their upstream sources, subpackages and the function images they use.
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/pathutil"
+)
+
+// Formats are the formats an SBOM can be written in.
+var Formats = []string{"spdx", "cyclonedx"}
+
+// Package is a package, subpackage or Kptfile dependency of the SBOM.
+type Package struct {
+	// Path is the slash separated path of the package relative to the
+	// directory the SBOM is generated for
+	Path string
+
+	// Name is the name of the package
+	Name string
+
+	// Version is the version of the package from its package metadata
+	Version string
+
+	// License is the license of the package from its package metadata
+	License string
+
+	// Upstream is where the package was fetched from
+	Upstream kptfile.Upstream
+
+	// Parent is the path of the package containing the package, or empty
+	Parent string
+
+	// Dependency is set if the package is a dependency declared in the
+	// Kptfile of its parent, rather than a subpackage
+	Dependency bool
+}
+
+// Function is a function image referenced by the packages.
+type Function struct {
+	// Image is the image, including its tag or digest
+	Image string
+
+	// Digest is the digest of the image, if it is referenced by digest or
+	// resolved with ResolveDigests
+	Digest string
+
+	// Packages are the paths of the packages referencing the image
+	Packages []string
+}
+
+// Inventory is the contents of an SBOM.
+type Inventory struct {
+	// Name is the name of the SBOM, which is the name of the package it is
+	// generated for
+	Name string
+
+	// Packages are the packages, sorted by path
+	Packages []Package
+
+	// Functions are the function images, sorted by image
+	Functions []Function
+}
+
+// Read returns the inventory of the package, or workspace of packages, at
+// dir.  Function images are read from the function annotations of the
+// resources, and the functions of the Kptfile dependencies.
+func Read(dir string) (*Inventory, error) {
+	dirs, err := pathutil.DirsWithFile(dir, kptfile.KptFileName, true)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	sort.Strings(dirs)
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	inv := &Inventory{Name: filepath.Base(abs)}
+	functions := map[string]map[string]bool{}
+	addFunction := func(image, pkg string) {
+		if image == "" {
+			return
+		}
+		if functions[image] == nil {
+			functions[image] = map[string]bool{}
+		}
+		functions[image][pkg] = true
+	}
+
+	paths := map[string]bool{}
+	for _, d := range dirs {
+		if strings.Contains(filepath.ToSlash(d)+"/", ".git/") {
+			continue
+		}
+		kf, err := kptfileutil.ReadFile(d)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, d)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		p := Package{
+			Path:     filepath.ToSlash(rel),
+			Name:     kf.Name,
+			Version:  kf.PackageMeta.Version,
+			License:  kf.PackageMeta.License,
+			Upstream: kf.Upstream,
+			Parent:   parentOf(filepath.ToSlash(rel), paths),
+		}
+		if p.Path == "." {
+			inv.Name = kf.Name
+		}
+		inv.Packages = append(inv.Packages, p)
+		paths[p.Path] = true
+
+		images, err := functionImages(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			addFunction(image, p.Path)
+		}
+		for _, dep := range kf.Dependencies {
+			for _, f := range dep.Functions {
+				addFunction(f.Image, p.Path)
+			}
+		}
+	}
+
+	// dependencies which have not been synced are not on disk, so are added
+	// from the Kptfile of the package declaring them
+	for _, p := range append([]Package{}, inv.Packages...) {
+		kf, err := kptfileutil.ReadFile(filepath.Join(dir, filepath.FromSlash(p.Path)))
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range kf.Dependencies {
+			if dep.EnsureNotExists {
+				continue
+			}
+			depPath := path.Join(p.Path, dep.Name)
+			if paths[depPath] {
+				continue
+			}
+			paths[depPath] = true
+			inv.Packages = append(inv.Packages, Package{
+				Path: depPath, Name: dep.Name, Upstream: dep.Upstream, Parent: p.Path, Dependency: true,
+			})
+		}
+	}
+	sort.SliceStable(inv.Packages, func(i, j int) bool { return inv.Packages[i].Path < inv.Packages[j].Path })
+
+	for image, pkgs := range functions {
+		f := Function{Image: image}
+		if i := strings.Index(image, "@"); i >= 0 && oci.IsDigest(image[i+1:]) {
+			f.Digest = image[i+1:]
+		}
+		for pkg := range pkgs {
+			f.Packages = append(f.Packages, pkg)
+		}
+		sort.Strings(f.Packages)
+		inv.Functions = append(inv.Functions, f)
+	}
+	sort.Slice(inv.Functions, func(i, j int) bool { return inv.Functions[i].Image < inv.Functions[j].Image })
+	return inv, nil
+}
+
+// parentOf returns the path of the package which most closely contains the
+// package, or an empty string.
+func parentOf(p string, paths map[string]bool) string {
+	if p == "." {
+		return ""
+	}
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		if paths[dir] {
+			return dir
+		}
+		if dir == "." {
+			return ""
+		}
+	}
+}
+
+// functionImages returns the images of the functions declared by the
+// resources of the package, excluding its subpackages.
+func functionImages(dir string) ([]string, error) {
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir, OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var images []string
+	for _, n := range nodes {
+		spec := runtimeutil.GetFunctionSpec(n)
+		if spec != nil && spec.Container.Image != "" {
+			images = append(images, spec.Container.Image)
+		}
+	}
+	return images, nil
+}
+
+// ResolveDigests resolves the digests of the function images referenced by
+// tag with the registries of the images.
+func (inv *Inventory) ResolveDigests() error {
+	for i := range inv.Functions {
+		f := &inv.Functions[i]
+		if f.Digest != "" {
+			continue
+		}
+		ref, err := oci.ParseReference(f.Image)
+		if err != nil {
+			return errors.Errorf("unable to resolve the digest of function image %s: %v", f.Image, err)
+		}
+		if f.Digest, err = oci.Digest(ref); err != nil {
+			return errors.Errorf("unable to resolve the digest of function image %s: %v", f.Image, err)
+		}
+	}
+	return nil
+}
+
+// Write writes the SBOM of the inventory to w in the format, created at the
+// time.
+func (inv *Inventory) Write(w io.Writer, format string, created time.Time) error {
+	var doc interface{}
+	switch format {
+	case "spdx":
+		doc = inv.spdx(created)
+	case "cyclonedx":
+		doc = inv.cycloneDX(created)
+	default:
+		return errors.Errorf("unsupported format %q, must be one of: %s", format, strings.Join(Formats, ", "))
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.Wrap(e.Encode(doc))
+}
+
+// hash returns a digest of the inventory, identifying the documents
+// generated from it.
+func (inv *Inventory) hash() string {
+	b, _ := json.Marshal(inv)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sourceURL returns the url the package was fetched from, or an empty string
+// for local packages.
+func sourceURL(u kptfile.Upstream) string {
+	switch {
+	case u.Type == kptfile.OCIOrigin:
+		s := "oci://" + u.OCI.Image
+		if u.OCI.Digest != "" {
+			return s + "@" + u.OCI.Digest
+		}
+		return s + ":" + u.OCI.Ref
+	case u.Type == kptfile.HelmOrigin:
+		return strings.TrimSuffix(u.Helm.Repo, "/") + "/" + u.Helm.Chart
+	case u.Git.Repo != "":
+		s := "git+" + u.Git.Repo
+		if v := u.Git.Commit; v != "" {
+			s += "@" + v
+		} else if u.Git.Ref != "" {
+			s += "@" + u.Git.Ref
+		}
+		if d := strings.Trim(u.Git.Directory, "/"); d != "" {
+			s += "#" + d
+		}
+		return s
+	}
+	return ""
+}
+
+// version returns the version of the package: the version of its package
+// metadata, or the ref of its upstream.
+func (p Package) version() string {
+	switch {
+	case p.Version != "":
+		return p.Version
+	case p.Upstream.Type == kptfile.OCIOrigin:
+		return p.Upstream.OCI.Ref
+	case p.Upstream.Type == kptfile.HelmOrigin:
+		return p.Upstream.Helm.Version
+	}
+	return p.Upstream.Git.Ref
+}
+
+// purl returns the package url of the function image.
+func (f Function) purl() string {
+	ref, err := oci.ParseReference(f.Image)
+	if err != nil || f.Digest == "" {
+		return ""
+	}
+	name := ref.Repository[strings.LastIndex(ref.Repository, "/")+1:]
+	s := "pkg:oci/" + name + "@" + strings.Replace(f.Digest, ":", "%3A", 1) +
+		"?repository_url=" + ref.Image()
+	if !oci.IsDigest(ref.Ref) {
+		s += "&tag=" + ref.Ref
+	}
+	return s
+}
+
+// version returns the digest of the function image if known, or its tag.
+func (f Function) version() string {
+	if f.Digest != "" {
+		return f.Digest
+	}
+	if ref, err := oci.ParseReference(f.Image); err == nil {
+		return ref.Ref
+	}
+	return ""
+}
+
+// name returns the image of the function without its tag or digest.
+func (f Function) name() string {
+	if ref, err := oci.ParseReference(f.Image); err == nil {
+		return ref.Image()
+	}
+	return f.Image
+}
@@ -0,0 +1,228 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	. "github.com/GoogleContainerTools/kpt/internal/util/sbom"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+const digest = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+// setupPackages writes an app package fetched from git, with a db subpackage
+// pulled from an OCI registry, and a function resource.
+func setupPackages(t *testing.T) string {
+	files := map[string]string{
+		"Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+upstream:
+  type: git
+  git:
+    commit: 7d4c0e3dbb27d14d8ed5f7fda3cab9a5f6e20c4a
+    repo: https://github.com/example/pkgs
+    directory: /app
+    ref: v1.0.0
+packageMetadata:
+  license: Apache-2.0
+dependencies:
+- name: cache
+  git:
+    repo: https://github.com/example/pkgs
+    directory: /redis
+    ref: v2
+  functions:
+  - image: gcr.io/kpt-fn/set-namespace:v0.1
+`,
+		"fn.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: labels
+  annotations:
+    config.kubernetes.io/function: |
+      container:
+        image: gcr.io/kpt-fn/set-labels@` + digest + `
+`,
+		"db/Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: db
+upstream:
+  type: oci
+  oci:
+    image: ghcr.io/example/mysql
+    ref: v8
+    digest: ` + digest + `
+`,
+	}
+	return pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("app").WithFiles(files))
+}
+
+func TestRead(t *testing.T) {
+	dir := setupPackages(t)
+
+	inv, err := Read(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "app", inv.Name)
+	if assert.Len(t, inv.Packages, 3) {
+		assert.Equal(t, ".", inv.Packages[0].Path)
+		assert.Equal(t, "Apache-2.0", inv.Packages[0].License)
+		assert.Equal(t, "", inv.Packages[0].Parent)
+
+		assert.Equal(t, "cache", inv.Packages[1].Path)
+		assert.Equal(t, ".", inv.Packages[1].Parent)
+		assert.True(t, inv.Packages[1].Dependency)
+		assert.Equal(t, "/redis", inv.Packages[1].Upstream.Git.Directory)
+
+		assert.Equal(t, "db", inv.Packages[2].Path)
+		assert.Equal(t, ".", inv.Packages[2].Parent)
+		assert.Equal(t, kptfile.OCIOrigin, inv.Packages[2].Upstream.Type)
+	}
+	assert.Equal(t, []Function{
+		{Image: "gcr.io/kpt-fn/set-labels@" + digest, Digest: digest, Packages: []string{"."}},
+		{Image: "gcr.io/kpt-fn/set-namespace:v0.1", Packages: []string{"."}},
+	}, inv.Functions)
+}
+
+func TestInventory_Write_spdx(t *testing.T) {
+	dir := setupPackages(t)
+	inv, err := Read(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	b := &bytes.Buffer{}
+	assert.NoError(t, inv.Write(b, "spdx", time.Unix(1600000000, 0)))
+	var doc struct {
+		SPDXVersion  string `json:"spdxVersion"`
+		CreationInfo struct {
+			Created string `json:"created"`
+		} `json:"creationInfo"`
+		Packages []struct {
+			SPDXID           string `json:"SPDXID"`
+			VersionInfo      string `json:"versionInfo"`
+			DownloadLocation string `json:"downloadLocation"`
+			LicenseDeclared  string `json:"licenseDeclared"`
+			ExternalRefs     []struct {
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []struct {
+			SPDXElementID      string `json:"spdxElementId"`
+			RelationshipType   string `json:"relationshipType"`
+			RelatedSPDXElement string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}
+	if !assert.NoError(t, json.Unmarshal(b.Bytes(), &doc)) {
+		t.FailNow()
+	}
+	assert.Equal(t, "SPDX-2.2", doc.SPDXVersion)
+	assert.Equal(t, "2020-09-13T12:26:40Z", doc.CreationInfo.Created)
+	if assert.Len(t, doc.Packages, 5) {
+		assert.Equal(t, "SPDXRef-Package-.", doc.Packages[0].SPDXID)
+		assert.Equal(t, "v1.0.0", doc.Packages[0].VersionInfo)
+		assert.Equal(t, "git+https://github.com/example/pkgs@7d4c0e3dbb27d14d8ed5f7fda3cab9a5f6e20c4a#app",
+			doc.Packages[0].DownloadLocation)
+		assert.Equal(t, "Apache-2.0", doc.Packages[0].LicenseDeclared)
+		assert.Equal(t, "git+https://github.com/example/pkgs@v2#redis", doc.Packages[1].DownloadLocation)
+		assert.Equal(t, "oci://ghcr.io/example/mysql@"+digest, doc.Packages[2].DownloadLocation)
+		assert.Equal(t, "NOASSERTION", doc.Packages[2].LicenseDeclared)
+		assert.Equal(t, digest, doc.Packages[3].VersionInfo)
+		if assert.Len(t, doc.Packages[3].ExternalRefs, 1) {
+			assert.Equal(t, "pkg:oci/set-labels@sha256%3A9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"+
+				"?repository_url=gcr.io/kpt-fn/set-labels", doc.Packages[3].ExternalRefs[0].ReferenceLocator)
+		}
+		assert.Equal(t, "v0.1", doc.Packages[4].VersionInfo)
+		assert.Empty(t, doc.Packages[4].ExternalRefs)
+	}
+	var relationships []string
+	for _, r := range doc.Relationships {
+		relationships = append(relationships, r.SPDXElementID+" "+r.RelationshipType+" "+r.RelatedSPDXElement)
+	}
+	assert.Equal(t, []string{
+		"SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-.",
+		"SPDXRef-Package-. DEPENDS_ON SPDXRef-Package-cache",
+		"SPDXRef-Package-. CONTAINS SPDXRef-Package-db",
+		"SPDXRef-Image-gcr.io-kpt-fn-set-labels-sha256-9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" +
+			" BUILD_TOOL_OF SPDXRef-Package-.",
+		"SPDXRef-Image-gcr.io-kpt-fn-set-namespace-v0.1 BUILD_TOOL_OF SPDXRef-Package-.",
+	}, relationships)
+}
+
+func TestInventory_Write_cycloneDX(t *testing.T) {
+	dir := setupPackages(t)
+	inv, err := Read(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	b := &bytes.Buffer{}
+	assert.NoError(t, inv.Write(b, "cyclonedx", time.Unix(1600000000, 0)))
+	var doc struct {
+		BOMFormat    string `json:"bomFormat"`
+		SerialNumber string `json:"serialNumber"`
+		Metadata     struct {
+			Timestamp string `json:"timestamp"`
+			Component struct {
+				BOMRef string `json:"bom-ref"`
+				Name   string `json:"name"`
+			} `json:"component"`
+		} `json:"metadata"`
+		Components []struct {
+			BOMRef  string `json:"bom-ref"`
+			Type    string `json:"type"`
+			Version string `json:"version"`
+		} `json:"components"`
+		Dependencies []struct {
+			Ref       string   `json:"ref"`
+			DependsOn []string `json:"dependsOn"`
+		} `json:"dependencies"`
+	}
+	if !assert.NoError(t, json.Unmarshal(b.Bytes(), &doc)) {
+		t.FailNow()
+	}
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	assert.Regexp(t, "^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-8[0-9a-f]{3}-[0-9a-f]{12}$", doc.SerialNumber)
+	assert.Equal(t, "2020-09-13T12:26:40Z", doc.Metadata.Timestamp)
+	assert.Equal(t, "package:.", doc.Metadata.Component.BOMRef)
+	assert.Equal(t, "app", doc.Metadata.Component.Name)
+	if assert.Len(t, doc.Components, 4) {
+		assert.Equal(t, "package:cache", doc.Components[0].BOMRef)
+		assert.Equal(t, "package:db", doc.Components[1].BOMRef)
+		assert.Equal(t, "v8", doc.Components[1].Version)
+		assert.Equal(t, "container", doc.Components[2].Type)
+		assert.Equal(t, digest, doc.Components[2].Version)
+	}
+	if assert.Len(t, doc.Dependencies, 3) {
+		assert.Equal(t, "package:.", doc.Dependencies[0].Ref)
+		assert.Equal(t, []string{"package:cache", "package:db",
+			"image:gcr.io/kpt-fn/set-labels@" + digest, "image:gcr.io/kpt-fn/set-namespace:v0.1"},
+			doc.Dependencies[0].DependsOn)
+		assert.Empty(t, doc.Dependencies[1].DependsOn)
+	}
+
+	err = inv.Write(b, "swid", time.Now())
+	assert.EqualError(t, err, `unsupported format "swid", must be one of: spdx, cyclonedx`)
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"regexp"
+	"time"
+)
+
+// noAssertion is the SPDX value of unknown fields.
+const noAssertion = "NOASSERTION"
+
+// spdxDocument is an SPDX 2.2 document in its JSON format.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// invalidIDChars matches the characters SPDX identifiers may not contain.
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+func spdxID(kind, name string) string {
+	return "SPDXRef-" + kind + "-" + invalidIDChars.ReplaceAllString(name, "-")
+}
+
+// spdx returns the SPDX document of the inventory.
+func (inv *Inventory) spdx(created time.Time) spdxDocument {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.2",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              inv.Name,
+		DocumentNamespace: "https://kpt.dev/spdx/" + invalidIDChars.ReplaceAllString(inv.Name, "-") + "-" + inv.hash(),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: kpt"},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	for _, p := range inv.Packages {
+		id := spdxID("Package", p.Path)
+		sp := spdxPackage{
+			SPDXID:           id,
+			Name:             p.Name,
+			VersionInfo:      p.version(),
+			DownloadLocation: sourceURL(p.Upstream),
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  p.License,
+			CopyrightText:    noAssertion,
+			Comment:          "kpt package " + p.Path,
+		}
+		if sp.DownloadLocation == "" {
+			sp.DownloadLocation = "NONE"
+		}
+		if sp.LicenseDeclared == "" {
+			sp.LicenseDeclared = noAssertion
+		}
+		doc.Packages = append(doc.Packages, sp)
+
+		switch {
+		case p.Parent == "":
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: id})
+		case p.Dependency:
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID: spdxID("Package", p.Parent), RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id})
+		default:
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID: spdxID("Package", p.Parent), RelationshipType: "CONTAINS", RelatedSPDXElement: id})
+		}
+	}
+
+	for _, f := range inv.Functions {
+		id := spdxID("Image", f.Image)
+		sp := spdxPackage{
+			SPDXID:           id,
+			Name:             f.name(),
+			VersionInfo:      f.version(),
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			CopyrightText:    noAssertion,
+			Comment:          "kpt function image " + f.Image,
+		}
+		if purl := f.purl(); purl != "" {
+			sp.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE_MANAGER", ReferenceType: "purl", ReferenceLocator: purl}}
+		}
+		doc.Packages = append(doc.Packages, sp)
+		for _, p := range f.Packages {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID: id, RelationshipType: "BUILD_TOOL_OF", RelatedSPDXElement: spdxID("Package", p)})
+		}
+	}
+	return doc
+}
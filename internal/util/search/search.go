@@ -83,19 +83,28 @@ func (sr *SearchReplace) Perform(resourcesPath string) error {
 		PackageFileName: kptfile.KptFileName,
 	}
 
+	fltr, err := sr.FilterAll()
+	if err != nil {
+		return err
+	}
+	return kio.Pipeline{
+		Inputs:  []kio.Reader{inout},
+		Filters: []kio.Filter{fltr},
+		Outputs: []kio.Writer{inout},
+	}.Execute()
+}
+
+// FilterAll returns the filter performing the search and replace operation
+// on each node.
+func (sr *SearchReplace) FilterAll() (kio.Filter, error) {
 	if sr.ByValueRegex != "" {
 		re, err := regexp.Compile(sr.ByValueRegex)
 		if err != nil {
-			return errors.Wrap(err)
+			return nil, errors.Wrap(err)
 		}
 		sr.regex = re
 	}
-
-	return kio.Pipeline{
-		Inputs:  []kio.Reader{inout},
-		Filters: []kio.Filter{kio.FilterAll(sr)},
-		Outputs: []kio.Writer{inout},
-	}.Execute()
+	return kio.FilterAll(sr), nil
 }
 
 // Filter parses input node and performs search and replace operation on the node
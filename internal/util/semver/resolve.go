@@ -0,0 +1,83 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+)
+
+// ResolveGit returns the latest version of the package in the directory of the
+// repo matching the constraint.  Tags prefixed with the directory, which version
+// the package independently of the rest of the repo, take precedence over the
+// tags of the repo.  The returned version does not include the directory prefix.
+func ResolveGit(repo, directory, constraint string) (string, error) {
+	tags, err := GitTags(repo, directory)
+	if err != nil {
+		return "", err
+	}
+	return latest(constraint, tags, repo)
+}
+
+// GitTags returns the tags versioning the package in the directory of the
+// repo: the tags prefixed with the directory, without the prefix, or the tags
+// of the repo if there are none.
+func GitTags(repo, directory string) ([]string, error) {
+	tags, err := gitutil.ListTags(repo)
+	if err != nil {
+		return nil, err
+	}
+	var pkgTags, repoTags []string
+	prefix := strings.Trim(directory, "/") + "/"
+	for _, tag := range tags {
+		switch {
+		case prefix != "/" && strings.HasPrefix(tag, prefix):
+			pkgTags = append(pkgTags, strings.TrimPrefix(tag, prefix))
+		case !strings.Contains(tag, "/"):
+			repoTags = append(repoTags, tag)
+		}
+	}
+	if len(pkgTags) > 0 {
+		return pkgTags, nil
+	}
+	return repoTags, nil
+}
+
+// ResolveOCI returns the latest tag of the image matching the constraint.
+func ResolveOCI(image, constraint string) (string, error) {
+	ref, err := oci.NewReference(image, oci.DefaultTag)
+	if err != nil {
+		return "", err
+	}
+	tags, err := oci.Tags(ref)
+	if err != nil {
+		return "", err
+	}
+	return latest(constraint, tags, image)
+}
+
+func latest(constraint string, tags []string, source string) (string, error) {
+	version, found, err := Latest(constraint, tags)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no version of %s matches %s", source, constraint)
+	}
+	return version, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveGit(t *testing.T) {
+	g, _, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v2.0.0", "java/v1.5.0", "java/v1.6.0-rc.1"} {
+		testutil.Tag(t, g, tag)
+	}
+	repo := "file://" + g.RepoDirectory
+
+	version, err := semver.ResolveGit(repo, "/", "^1")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", version)
+
+	// tags of the subdirectory take precedence
+	version, err = semver.ResolveGit(repo, "/java", "^1")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.5.0", version)
+
+	version, err = semver.ResolveGit(repo, "/mysql", ">=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2.0.0", version)
+
+	_, err = semver.ResolveGit(repo, "/", "^3")
+	assert.EqualError(t, err, "no version of "+repo+" matches ^3")
+}
@@ -0,0 +1,307 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package semver resolves semantic version constraints of upstream refs to
+// concrete versions.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, e.g. v1.2.3 or 1.2.3-rc.1.
+type Version struct {
+	Major, Minor, Patch int
+	PreRelease          string
+}
+
+// ParseVersion parses a semantic version with an optional v prefix.  Build
+// metadata is ignored.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.PreRelease = s[i+1:]
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid version %q: must be MAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*p = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than o.
+// Pre-releases are less than the release of the same version.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case o.PreRelease == "":
+		return -1
+	case v.PreRelease < o.PreRelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// Constraint is a set of version ranges, any of which a version must match.
+type Constraint struct {
+	// ranges contains the alternatives separated by ||, each of which
+	// is a set of comparisons that must all match
+	ranges [][]comparison
+}
+
+type comparison struct {
+	op      string
+	version Version
+}
+
+// IsConstraint returns true if the ref is a version constraint rather than
+// a concrete ref, i.e. uses a range operator or a wildcard.
+func IsConstraint(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if strings.ContainsAny(ref, "^~<>=|* ") {
+		return true
+	}
+	// partial versions with wildcards, e.g. 1.x
+	_, n, err := parsePartial(ref)
+	return err == nil && n < len(strings.Split(ref, "."))
+}
+
+// ParseConstraint parses a constraint such as ^1.2, ~1.2.3, >=1.0.0 <2.0.0,
+// 1.x or ^1.0 || ^2.0.  Partial versions match any value of their missing
+// parts.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(s, "||") {
+		var r []comparison
+		for _, term := range strings.Fields(alt) {
+			cmps, err := parseTerm(term)
+			if err != nil {
+				return c, fmt.Errorf("invalid constraint %q: %v", s, err)
+			}
+			r = append(r, cmps...)
+		}
+		if len(r) == 0 {
+			return c, fmt.Errorf("invalid constraint %q: empty range", s)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// parseTerm parses a single term of a range into the comparisons it implies.
+func parseTerm(term string) ([]comparison, error) {
+	op := ""
+	for _, o := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	v, n, err := parsePartial(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+
+	// upper returns the exclusive upper bound incrementing the part at i
+	upper := func(i int) Version {
+		switch i {
+		case 0:
+			return Version{Major: v.Major + 1}
+		case 1:
+			return Version{Major: v.Major, Minor: v.Minor + 1}
+		default:
+			return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+		}
+	}
+	if n == 0 {
+		// wildcard -- matches everything
+		return []comparison{{op: ">=", version: Version{}}}, nil
+	}
+	switch op {
+	case "^":
+		// allow changes that do not modify the left-most non-zero part
+		i := 0
+		switch {
+		case v.Major == 0 && n > 1 && (v.Minor > 0 || n == 2):
+			i = 1
+		case v.Major == 0 && n > 1:
+			i = 2
+		}
+		return []comparison{{op: ">=", version: v}, {op: "<", version: upper(i)}}, nil
+	case "~":
+		i := 1
+		if n == 1 {
+			i = 0
+		}
+		return []comparison{{op: ">=", version: v}, {op: "<", version: upper(i)}}, nil
+	case ">", "<=":
+		if n < 3 {
+			// >1.2 is >=1.3.0, <=1.2 is <1.3.0
+			o := map[string]string{">": ">=", "<=": "<"}[op]
+			return []comparison{{op: o, version: upper(n - 1)}}, nil
+		}
+		return []comparison{{op: op, version: v}}, nil
+	case ">=", "<":
+		return []comparison{{op: op, version: v}}, nil
+	default:
+		if n < 3 {
+			return []comparison{{op: ">=", version: v}, {op: "<", version: upper(n - 1)}}, nil
+		}
+		return []comparison{{op: "=", version: v}}, nil
+	}
+}
+
+// parsePartial parses a version which may omit trailing parts or use x or *
+// wildcards, returning the number of parts specified.
+func parsePartial(s string) (Version, int, error) {
+	var v Version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.PreRelease = s[i+1:]
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || s == "" {
+		return v, 0, fmt.Errorf("invalid version %q", s)
+	}
+	n := 0
+	for i, p := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if i >= len(parts) || parts[i] == "x" || parts[i] == "X" || parts[i] == "*" {
+			break
+		}
+		num, err := strconv.Atoi(parts[i])
+		if err != nil || num < 0 {
+			return v, 0, fmt.Errorf("invalid version %q", s)
+		}
+		*p = num
+		n++
+	}
+	return v, n, nil
+}
+
+// Matches returns true if the version satisfies the constraint.  Pre-releases
+// only match comparisons against a pre-release of the same version.
+func (c Constraint) Matches(v Version) bool {
+	for _, r := range c.ranges {
+		if matchesRange(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesRange(r []comparison, v Version) bool {
+	allowPre := v.PreRelease == ""
+	for _, cmp := range r {
+		d := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = d >= 0
+		case ">":
+			ok = d > 0
+		case "<=":
+			ok = d <= 0
+		case "<":
+			ok = d < 0
+		default:
+			ok = d == 0
+		}
+		if !ok {
+			return false
+		}
+		if cmp.version.PreRelease != "" && cmp.version.Major == v.Major &&
+			cmp.version.Minor == v.Minor && cmp.version.Patch == v.Patch {
+			allowPre = true
+		}
+	}
+	return allowPre
+}
+
+// Latest returns the greatest of the tags matching the constraint, and false
+// if none match.  Tags which are not semantic versions are ignored.
+func Latest(constraint string, tags []string) (string, bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", false, err
+	}
+	var latest string
+	var latestVersion Version
+	for _, tag := range tags {
+		v, err := ParseVersion(tag)
+		if err != nil || !c.Matches(v) {
+			continue
+		}
+		if latest == "" || v.Compare(latestVersion) > 0 {
+			latest, latestVersion = tag, v
+		}
+	}
+	return latest, latest != "", nil
+}
+
+// Newer returns the tags which are greater versions than version, sorted from
+// the least to the greatest.  Pre-releases are only included if version is a
+// pre-release.  Tags which are not semantic versions are ignored.
+func Newer(version string, tags []string) ([]string, error) {
+	current, err := ParseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	var newer []string
+	versions := map[string]Version{}
+	for _, tag := range tags {
+		v, err := ParseVersion(tag)
+		if err != nil || v.Compare(current) <= 0 {
+			continue
+		}
+		if v.PreRelease != "" && current.PreRelease == "" {
+			continue
+		}
+		newer = append(newer, tag)
+		versions[tag] = v
+	}
+	sort.SliceStable(newer, func(i, j int) bool {
+		return versions[newer[i]].Compare(versions[newer[j]]) < 0
+	})
+	return newer, nil
+}
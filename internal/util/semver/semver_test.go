@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsConstraint(t *testing.T) {
+	for ref, expected := range map[string]bool{
+		"^1.2":          true,
+		"~1.2.3":        true,
+		">=1.0.0 <2":    true,
+		"1.x":           true,
+		"v1.2.X":        true,
+		"^1 || ^2":      true,
+		"v1.2.3":        false,
+		"master":        false,
+		"refs/heads/x":  false,
+		"":              false,
+		"release-1.x.y": false,
+	} {
+		assert.Equal(t, expected, IsConstraint(ref), ref)
+	}
+}
+
+func TestConstraint_Matches(t *testing.T) {
+	for _, test := range []struct {
+		constraint string
+		matches    []string
+		misses     []string
+	}{
+		{constraint: "^1.2", matches: []string{"1.2.0", "v1.9.9"}, misses: []string{"1.1.9", "2.0.0", "1.3.0-rc.1"}},
+		{constraint: "^0.2.3", matches: []string{"0.2.3", "0.2.9"}, misses: []string{"0.3.0", "0.2.2"}},
+		{constraint: "^0.0.3", matches: []string{"0.0.3"}, misses: []string{"0.0.4"}},
+		{constraint: "~1.2.3", matches: []string{"1.2.3", "1.2.8"}, misses: []string{"1.3.0"}},
+		{constraint: "~1", matches: []string{"1.0.0", "1.5.0"}, misses: []string{"2.0.0"}},
+		{constraint: ">=1.0.0 <2", matches: []string{"1.0.0", "1.99.0"}, misses: []string{"0.9.0", "2.0.0"}},
+		{constraint: ">1.2", matches: []string{"1.3.0"}, misses: []string{"1.2.9"}},
+		{constraint: "<=1.2", matches: []string{"1.2.9"}, misses: []string{"1.3.0"}},
+		{constraint: "1.x", matches: []string{"1.0.0", "1.4.2"}, misses: []string{"2.0.0"}},
+		{constraint: "*", matches: []string{"0.0.1", "9.0.0"}, misses: []string{"1.0.0-alpha"}},
+		{constraint: "^1.0 || ^3.0", matches: []string{"1.1.0", "3.2.0"}, misses: []string{"2.0.0"}},
+		{constraint: "1.2.3", matches: []string{"v1.2.3"}, misses: []string{"1.2.4"}},
+		{constraint: ">=1.3.0-rc.1", matches: []string{"1.3.0-rc.2", "1.3.0", "1.4.0"}, misses: []string{"1.4.0-rc.1"}},
+	} {
+		c, err := ParseConstraint(test.constraint)
+		if !assert.NoError(t, err, test.constraint) {
+			continue
+		}
+		for _, s := range test.matches {
+			v, err := ParseVersion(s)
+			assert.NoError(t, err)
+			assert.True(t, c.Matches(v), "%s should match %s", test.constraint, s)
+		}
+		for _, s := range test.misses {
+			v, err := ParseVersion(s)
+			assert.NoError(t, err)
+			assert.False(t, c.Matches(v), "%s should not match %s", test.constraint, s)
+		}
+	}
+
+	for _, invalid := range []string{"^", "^1.a", ">=1.2.3.4", "^1 ||"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.10.0", "v1.9.0", "v2.0.0", "v1.11.0-rc.1", "latest", "release"}
+	latest, found, err := Latest("^1.0", tags)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1.10.0", latest)
+
+	_, found, err = Latest("^3", tags)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestNewer(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.10.0", "v1.9.0", "v2.0.0", "v1.11.0-rc.1", "latest", "release"}
+	newer, err := Newer("v1.9.0", tags)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.10.0", "v2.0.0"}, newer)
+
+	// pre-releases are newer versions of pre-releases
+	newer, err = Newer("v1.11.0-alpha.1", tags)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.11.0-rc.1", "v2.0.0"}, newer)
+
+	newer, err = Newer("v2.0.0", tags)
+	assert.NoError(t, err)
+	assert.Empty(t, newer)
+
+	_, err = Newer("master", tags)
+	assert.Error(t, err)
+}
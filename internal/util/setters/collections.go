@@ -0,0 +1,265 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package setters
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/go-openapi/spec"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/fieldmeta"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/setters2"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var (
+	// refPattern matches the setter references of field comments, e.g.
+	// {"$kpt-set":"cidrs"}
+	refPattern = regexp.MustCompile(`"\$(?:kpt-set|openapi)"\s*:\s*"([^"]+)"`)
+
+	// definitionRefPattern matches the references to setter definitions of
+	// field comments, e.g. {"$ref":"#/definitions/io.k8s.cli.setters.cidrs"}
+	definitionRefPattern = regexp.MustCompile(`"\$ref"\s*:\s*"#/definitions/` +
+		regexp.QuoteMeta(fieldmeta.SetterDefinitionPrefix) + `([^"]+)"`)
+)
+
+// IsList returns true if the setter definition is of a list setter: its
+// type is array, or it has list values.
+func IsList(def spec.Schema, setter *setters2.SetterDefinition) bool {
+	return def.Type.Contains("array") || (setter != nil && len(setter.ListValues) > 0)
+}
+
+// IsMap returns true if the setter definition is of a map setter, whose
+// type is object.
+func IsMap(def spec.Schema) bool {
+	return def.Type.Contains("object")
+}
+
+// ParseList parses the value of a list setter: a YAML sequence such as
+// [a, b], or a list of values separated by commas.
+func ParseList(value string) (*yaml.RNode, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "[") || strings.HasPrefix(strings.TrimSpace(value), "- ") {
+		n, err := yaml.Parse(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid list %q: %v", value, err)
+		}
+		if n.YNode().Kind != yaml.SequenceNode {
+			return nil, errors.Errorf("invalid list %q, must be a YAML sequence such as [a, b]", value)
+		}
+		return format(n), nil
+	}
+	n := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			n.Content = append(n.Content, yaml.NewScalarRNode(v).YNode())
+		}
+	}
+	return yaml.NewRNode(n), nil
+}
+
+// ParseMap parses the value of a map setter, a YAML mapping such as
+// {a: b, c: d}.  The fields of the mapping are sorted by key.
+func ParseMap(value string) (*yaml.RNode, error) {
+	if strings.TrimSpace(value) == "" {
+		return yaml.NewRNode(&yaml.Node{Kind: yaml.MappingNode}), nil
+	}
+	n, err := yaml.Parse(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid map %q: %v", value, err)
+	}
+	if n.YNode().Kind != yaml.MappingNode {
+		return nil, errors.Errorf("invalid map %q, must be a YAML mapping such as {a: b}", value)
+	}
+	return format(n), nil
+}
+
+// format formats the node deterministically: in block style, with the
+// fields of mappings sorted by key.
+func format(n *yaml.RNode) *yaml.RNode {
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			node.Style = 0
+			var pairs [][2]*yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+			}
+			sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0].Value < pairs[j][0].Value })
+			node.Content = nil
+			for _, p := range pairs {
+				walk(p[1])
+				node.Content = append(node.Content, p[0], p[1])
+			}
+		case yaml.SequenceNode:
+			node.Style = 0
+			for _, c := range node.Content {
+				walk(c)
+			}
+		}
+	}
+	walk(n.YNode())
+	return n
+}
+
+// CollectionValues returns the values of the list and map setters of the
+// package at path, with the values overriding the values in the Kptfile.
+func CollectionValues(path string, values map[string]string) (map[string]*yaml.RNode, error) {
+	kptFilePath := filepath.Join(path, kptfile.KptFileName)
+	if _, err := os.Stat(kptFilePath); err != nil {
+		return nil, nil
+	}
+	settersSchema, err := openapi.SchemaFromFile(kptFilePath)
+	if err != nil || settersSchema == nil {
+		return nil, err
+	}
+	result := map[string]*yaml.RNode{}
+	for key := range settersSchema.Definitions {
+		if !strings.HasPrefix(key, fieldmeta.SetterDefinitionPrefix) {
+			continue
+		}
+		def := settersSchema.Definitions[key]
+		cliExt, err := setters2.GetExtFromSchema(&def)
+		if err != nil {
+			return nil, err
+		}
+		if cliExt == nil || cliExt.Setter == nil {
+			continue
+		}
+		name := cliExt.Setter.Name
+		value, found := values[name]
+		n, err := collectionValue(def, cliExt.Setter, value, found)
+		if err != nil {
+			return nil, errors.Errorf("setter %s: %v", name, err)
+		}
+		if n != nil {
+			result[name] = n
+		}
+	}
+	return result, nil
+}
+
+// collectionValue returns the value of a list or map setter, the value if
+// found, else the value of the Kptfile.  Returns nil for the other setters,
+// and for the setters without a value.
+func collectionValue(def spec.Schema, setter *setters2.SetterDefinition, value string, found bool) (*yaml.RNode, error) {
+	switch {
+	case IsList(def, setter):
+		if !found {
+			if len(setter.ListValues) == 0 {
+				return nil, nil
+			}
+			value = "[" + strings.Join(quoteAll(setter.ListValues), ", ") + "]"
+		}
+		return ParseList(value)
+	case IsMap(def):
+		if !found {
+			if setter.Value == "" {
+				return nil, nil
+			}
+			value = setter.Value
+		}
+		return ParseMap(value)
+	}
+	return nil, nil
+}
+
+// quoteAll returns the values as double quoted YAML strings.
+func quoteAll(values []string) []string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, `"`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)+`"`)
+	}
+	return quoted
+}
+
+// SetCollectionFields sets the list and map fields of the package at path
+// referencing the list and map setters of its Kptfile to their values, e.g.
+// after kpt cfg set.
+func SetCollectionFields(path string) error {
+	values, err := CollectionValues(path, nil)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	rw := &kio.LocalPackageReadWriter{PackagePath: path}
+	return errors.WithStack(kio.Pipeline{
+		Inputs:  []kio.Reader{rw},
+		Filters: []kio.Filter{SetCollections(values)},
+		Outputs: []kio.Writer{rw},
+	}.Execute())
+}
+
+// SetCollections returns a filter setting the list and map fields whose
+// comment references a setter of the values, e.g. the field
+// cidrs: # {"$kpt-set":"cidrs"}.  The fields are replaced by the values, in
+// block style with the fields of maps sorted by key, so setting them again
+// doesn't change them.
+func SetCollections(values map[string]*yaml.RNode) kio.Filter {
+	return kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+		if len(values) == 0 {
+			return nodes, nil
+		}
+		for _, n := range nodes {
+			setCollections(n.YNode(), values)
+		}
+		return nodes, nil
+	})
+}
+
+// setCollections sets the fields of the node referencing the setters.
+func setCollections(node *yaml.Node, values map[string]*yaml.RNode) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range node.Content {
+			setCollections(c, values)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			name := setterRef(key.LineComment)
+			if name == "" {
+				name = setterRef(value.LineComment)
+			}
+			v, found := values[name]
+			isNull := value.Kind == yaml.ScalarNode && value.Tag == "!!null"
+			if !found || (value.Kind != yaml.SequenceNode && value.Kind != yaml.MappingNode && !isNull) {
+				setCollections(value, values)
+				continue
+			}
+			set := v.Copy().YNode()
+			set.HeadComment, set.LineComment, set.FootComment =
+				value.HeadComment, value.LineComment, value.FootComment
+			*value = *set
+		}
+	}
+}
+
+// setterRef returns the name of the setter referenced by the comment.
+func setterRef(comment string) string {
+	if m := refPattern.FindStringSubmatch(comment); m != nil {
+		return m[1]
+	}
+	if m := definitionRefPattern.FindStringSubmatch(comment); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package setters
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestParseList(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected string
+	}{
+		{value: "[10.0.0.0/8, 192.168.0.0/16]", expected: "- 10.0.0.0/8\n- 192.168.0.0/16\n"},
+		{value: "--verbose, --port=80", expected: "- --verbose\n- --port=80\n"},
+		{value: "- a\n- b\n", expected: "- a\n- b\n"},
+		{value: "", expected: "[]\n"},
+	}
+	for _, test := range tests {
+		n, err := ParseList(test.value)
+		if !assert.NoError(t, err, test.value) {
+			continue
+		}
+		assert.Equal(t, test.expected, n.MustString(), test.value)
+	}
+
+	_, err := ParseList("[a, b")
+	assert.Error(t, err)
+}
+
+func TestParseMap(t *testing.T) {
+	n, err := ParseMap("{team: web, app: nginx, limits: {memory: 1Gi, cpu: 1}}")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "app: nginx\nlimits:\n  cpu: 1\n  memory: 1Gi\nteam: web\n", n.MustString())
+	}
+
+	_, err = ParseMap("[a, b]")
+	assert.EqualError(t, err, `invalid map "[a, b]", must be a YAML mapping such as {a: b}`)
+}
+
+func TestSetCollections(t *testing.T) {
+	n, err := yaml.Parse(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  labels: # {"$kpt-set":"labels"}
+    old: label
+spec:
+  cidrs: # {"$kpt-set":"cidrs"}
+  - 10.0.0.0/8
+  args: [] # {"$ref":"#/definitions/io.k8s.cli.setters.args"}
+  other:
+  - unchanged
+`)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	labels, err := ParseMap("{team: web, app: nginx}")
+	assert.NoError(t, err)
+	cidrs, err := ParseList("[10.0.0.0/8, 192.168.0.0/16]")
+	assert.NoError(t, err)
+	args, err := ParseList("--verbose")
+	assert.NoError(t, err)
+	values := map[string]*yaml.RNode{"labels": labels, "cidrs": cidrs, "args": args}
+
+	for i := 0; i < 2; i++ {
+		_, err = SetCollections(values).Filter([]*yaml.RNode{n})
+		assert.NoError(t, err)
+	}
+
+	for path, expected := range map[string]string{
+		"metadata.labels": "app: nginx\nteam: web\n",
+		"spec.cidrs":      "- 10.0.0.0/8\n- 192.168.0.0/16\n",
+		"spec.args":       "- --verbose\n",
+		"spec.other":      "- unchanged\n",
+	} {
+		field, err := n.Pipe(yaml.Lookup(strings.Split(path, ".")...))
+		if assert.NoError(t, err) && assert.NotNil(t, field, path) {
+			field.YNode().LineComment = ""
+			assert.Equal(t, expected, field.MustString(), path)
+		}
+	}
+}
+
+func TestCollectionValues(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(`apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.cidrs:
+      type: array
+      items:
+        type: string
+        pattern: ^[0-9./]+$
+      x-k8s-cli:
+        setter:
+          name: cidrs
+          listValues:
+          - 10.0.0.0/8
+    io.k8s.cli.setters.labels:
+      type: object
+      x-k8s-cli:
+        setter:
+          name: labels
+          value: '{team: web}'
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+`), 0600))
+
+	values, err := CollectionValues(d, nil)
+	if assert.NoError(t, err) {
+		assert.Len(t, values, 2)
+		assert.Equal(t, "- 10.0.0.0/8\n", values["cidrs"].MustString())
+		assert.Equal(t, "team: web\n", values["labels"].MustString())
+	}
+
+	values, err = CollectionValues(d, map[string]string{"cidrs": "10.0.0.0/8, 192.168.0.0/16", "labels": "{b: 2, a: 1}"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "- 10.0.0.0/8\n- 192.168.0.0/16\n", values["cidrs"].MustString())
+		assert.Equal(t, "a: 1\nb: 2\n", values["labels"].MustString())
+	}
+
+	assert.NoError(t, CheckValues(d, nil))
+	err = CheckValues(d, map[string]string{"cidrs": "[10.0.0.0/8, any]"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "cidrs.1 in body should match '^[0-9./]+$'")
+	}
+	assert.EqualError(t, CheckValue(d, "labels", "[a]"), "invalid setter values in package "+d+
+		":\n  - labels: invalid map \"[a]\", must be a YAML mapping such as {a: b}")
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package setters
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/fieldmeta"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/setters2"
+)
+
+// CheckValues returns an error listing the setters of the package at path
+// whose values don't match the constraints of their definitions in the
+// Kptfile: their type, and the minimum, maximum, enum, pattern, minLength
+// and maxLength of their values.  The values override the values of the
+// setters in the Kptfile, e.g. the values set by apply-setters.  Setters
+// without a value aren't checked.
+func CheckValues(path string, values map[string]string) error {
+	return check(path, values, true)
+}
+
+// CheckValue returns an error if the value doesn't match the constraints of
+// the definition of the setter of the package at path, e.g. before setting
+// it with kpt cfg set.
+func CheckValue(path, name, value string) error {
+	return check(path, map[string]string{name: value}, false)
+}
+
+// InvalidValues returns why the values of the setters of the package at
+// path don't match the constraints of their definitions, as CheckValues
+// does, with a message per constraint.
+func InvalidValues(path string, values map[string]string) ([]string, error) {
+	return invalidValues(path, values, true)
+}
+
+// check returns an error listing the invalid values of the setters.
+func check(path string, values map[string]string, all bool) error {
+	msgs, err := invalidValues(path, values, all)
+	if err != nil {
+		return err
+	}
+	if len(msgs) > 0 {
+		return errors.Errorf("invalid setter values in package %s:\n  - %s", path, strings.Join(msgs, "\n  - "))
+	}
+	return nil
+}
+
+// invalidValues checks the values of the setters, and the values of the
+// other setters in the Kptfile if all is true.
+func invalidValues(path string, values map[string]string, all bool) ([]string, error) {
+	kptFilePath := filepath.Join(path, kptfile.KptFileName)
+	if _, err := os.Stat(kptFilePath); err != nil {
+		// packages without a Kptfile have no setters
+		return nil, nil
+	}
+	settersSchema, err := openapi.SchemaFromFile(kptFilePath)
+	if err != nil || settersSchema == nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range settersSchema.Definitions {
+		if strings.HasPrefix(key, fieldmeta.SetterDefinitionPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var msgs []string
+	for _, key := range keys {
+		def := settersSchema.Definitions[key]
+		cliExt, err := setters2.GetExtFromSchema(&def)
+		if err != nil {
+			return nil, err
+		}
+		if cliExt == nil || cliExt.Setter == nil {
+			continue
+		}
+		name := cliExt.Setter.Name
+		value, found := values[name]
+		if !found && !all {
+			continue
+		}
+		if IsList(def, cliExt.Setter) || IsMap(def) {
+			n, err := collectionValue(def, cliExt.Setter, value, found)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if n == nil {
+				continue
+			}
+			b, err := n.MarshalJSON()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			msgs = append(msgs, validateValue(name, string(b), b, def)...)
+			continue
+		}
+		if !found {
+			value = cliExt.Setter.Value
+		}
+		if !found && value == "" {
+			continue
+		}
+		msgs = append(msgs, checkValue(name, value, def)...)
+	}
+	return msgs, nil
+}
+
+// checkValue returns the constraints of the setter definition the value
+// doesn't match.
+func checkValue(name, value string, def spec.Schema) []string {
+	var typed interface{} = value
+	switch {
+	case def.Type.Contains("integer"):
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("%s must be an integer, got %q", name, value)}
+		}
+		typed = i
+	case def.Type.Contains("number"):
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("%s must be a number, got %q", name, value)}
+		}
+		typed = f
+	case def.Type.Contains("boolean"):
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return []string{fmt.Sprintf("%s must be a boolean, got %q", name, value)}
+		}
+		typed = b
+	}
+	b, err := json.Marshal(typed)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return validateValue(name, value, b, def)
+}
+
+// validateValue returns the constraints of the setter definition the JSON
+// value doesn't match.
+func validateValue(name, value string, b []byte, def spec.Schema) []string {
+	// the value is decoded as the schema is, so numbers are compared to
+	// the numbers of the enum as float64
+	var obj interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return []string{err.Error()}
+	}
+	s := def
+	s.Extensions = nil
+	var msgs []string
+	result := validate.NewSchemaValidator(&s, nil, name, strfmt.Default).Validate(obj)
+	for _, err := range result.Errors {
+		msgs = append(msgs, fmt.Sprintf("%v, got %q", err, value))
+	}
+	return msgs
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package setters
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+const constraintsKptfile = `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      type: integer
+      minimum: 1
+      maximum: 10
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+    io.k8s.cli.setters.tier:
+      type: string
+      enum: [dev, prod]
+      x-k8s-cli:
+        setter:
+          name: tier
+          value: dev
+    io.k8s.cli.setters.name:
+      type: string
+      pattern: ^[a-z][a-z0-9-]*$
+      x-k8s-cli:
+        setter:
+          name: name
+          value: web
+    io.k8s.cli.setters.debug:
+      type: boolean
+      x-k8s-cli:
+        setter:
+          name: debug
+          value: "false"
+    io.k8s.cli.setters.image:
+      x-k8s-cli:
+        setter:
+          name: image
+`
+
+func TestCheckValues(t *testing.T) {
+	var tests = []struct {
+		name     string
+		values   map[string]string
+		expected []string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:   "valid overrides",
+			values: map[string]string{"replicas": "10", "tier": "prod", "debug": "true", "image": "nginx"},
+		},
+		{
+			name:     "not an integer",
+			values:   map[string]string{"replicas": "banana"},
+			expected: []string{`replicas must be an integer, got "banana"`},
+		},
+		{
+			name:     "out of range",
+			values:   map[string]string{"replicas": "12"},
+			expected: []string{"replicas in body should be less than or equal to 10", `got "12"`},
+		},
+		{
+			name:     "not in enum",
+			values:   map[string]string{"tier": "staging"},
+			expected: []string{"tier in body should be one of [dev prod]", `got "staging"`},
+		},
+		{
+			name:     "not matching pattern",
+			values:   map[string]string{"name": "Web"},
+			expected: []string{"name in body should match '^[a-z][a-z0-9-]*$'", `got "Web"`},
+		},
+		{
+			name:     "not a boolean",
+			values:   map[string]string{"debug": "maybe"},
+			expected: []string{`debug must be a boolean, got "maybe"`},
+		},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "kpt")
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			defer os.RemoveAll(d)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(constraintsKptfile), 0600))
+
+			err = CheckValues(d, test.values)
+			if len(test.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "invalid setter values in package "+d)
+				for _, e := range test.expected {
+					assert.Contains(t, err.Error(), e)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, kptfile.KptFileName), []byte(constraintsKptfile), 0600))
+
+	assert.NoError(t, CheckValue(d, "replicas", "5"))
+	assert.NoError(t, CheckValue(d, "unknown", "banana"))
+	assert.EqualError(t, CheckValue(d, "replicas", "banana"),
+		"invalid setter values in package "+d+":\n  - replicas must be an integer, got \"banana\"")
+}
@@ -303,6 +303,12 @@ func (a AutoSet) setInheritedSettersForPkg(pkgPath, parentKptfilePath, setterRef
 	return nil
 }
 
+// IsSet returns true if the setter of the package at path was set with
+// kpt cfg set or by an auto-setter.
+func IsSet(path, setterName string) bool {
+	return isSet(setterName, filepath.Join(path, kptfile.KptFileName))
+}
+
 // isSet checks the openAPI file and returns true iff the openAPI definition
 // for for given setter has isSet flag set to true
 func isSet(setterName, openAPIFile string) bool {
@@ -365,6 +371,36 @@ func DefExists(resourcePath, setterName string) bool {
 	return setter != nil
 }
 
+// Values returns the values of the setters defined in the Kptfile of the
+// package by setter name.  The values of array setters are joined with
+// commas.  Returns nil if the package has no setters.
+func Values(path string) (map[string]string, error) {
+	settersSchema, err := openapi.SchemaFromFile(filepath.Join(path, kptfile.KptFileName))
+	if err != nil || settersSchema == nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for key := range settersSchema.Definitions {
+		if !strings.HasPrefix(key, fieldmeta.SetterDefinitionPrefix) {
+			continue
+		}
+		sch := settersSchema.Definitions[key]
+		cliExt, err := setters2.GetExtFromSchema(&sch)
+		if err != nil {
+			return nil, err
+		}
+		if cliExt == nil || cliExt.Setter == nil {
+			continue
+		}
+		value := cliExt.Setter.Value
+		if value == "" && len(cliExt.Setter.ListValues) > 0 {
+			value = strings.Join(cliExt.Setter.ListValues, ",")
+		}
+		values[cliExt.Setter.Name] = value
+	}
+	return values, nil
+}
+
 // CheckForRequiredSetters takes the package path, checks if there is a KrmFile
 // and checks if all the required setters are set
 func CheckForRequiredSetters(path string) error {
@@ -88,6 +88,42 @@ openAPI:
 	}
 }
 
+func TestValues(t *testing.T) {
+	openapi.ResetOpenAPI()
+	defer openapi.ResetOpenAPI()
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "Kptfile"), []byte(`
+apiVersion: v1alpha1
+kind: Kptfile
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+    io.k8s.cli.setters.zones:
+      x-k8s-cli:
+        setter:
+          name: zones
+          listValues:
+          - us-east1-b
+          - us-east1-c
+    io.k8s.cli.substitutions.image:
+      x-k8s-cli:
+        substitution:
+          name: image
+          pattern: ${image-name}:${image-tag}
+`), 0600)
+	assert.NoError(t, err)
+
+	values, err := Values(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"replicas": "3", "zones": "us-east1-b,us-east1-c"}, values)
+}
+
 func TestSetV2AutoSetter(t *testing.T) {
 	var tests = []struct {
 		name            string
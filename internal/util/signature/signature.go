@@ -0,0 +1,299 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signature signs packages and verifies their provenance using the
+// cosign program of sigstore.
+package signature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// File is the name of the file of a package containing its signature, as a
+// cosign bundle.  Signature files are excluded from the signed contents.
+const File = "Kptfile.sig"
+
+// PolicyFileEnv is the name of the environment variable that controls the
+// location of the signature policy.  Defaults to
+// UserHomeDir/.kpt/signature-policy.yaml if unspecified.
+const PolicyFileEnv = "KPT_SIGNATURE_POLICY"
+
+// Policy declares the keys and identities packages must be signed with.
+type Policy struct {
+	yaml.ResourceMeta `yaml:",inline"`
+
+	// Keys are the paths to the public keys of key-based signatures.  Paths
+	// are relative to the policy file, and may be any key reference cosign
+	// supports, e.g. a KMS uri.
+	Keys []string `yaml:"keys,omitempty"`
+
+	// Identities are the identities of keyless signatures.
+	Identities []Identity `yaml:"identities,omitempty"`
+
+	// FunctionImages requires the images of the functions kpt runs to be
+	// signed by the keys or identities of the policy.
+	FunctionImages bool `yaml:"functionImages,omitempty"`
+
+	// path is the path of the policy file
+	path string
+}
+
+// Identity is the identity of a keyless signature, which is recorded in the
+// certificate issued to the signer.
+type Identity struct {
+	// Issuer is the OIDC issuer of the identity,
+	// e.g. https://token.actions.githubusercontent.com
+	Issuer string `yaml:"issuer"`
+
+	// Subject is the identity, e.g. an email address or the uri of a CI
+	// workflow.
+	Subject string `yaml:"subject,omitempty"`
+
+	// SubjectRegExp is a regular expression matching the identity.  Used
+	// instead of Subject if set.
+	SubjectRegExp string `yaml:"subjectRegExp,omitempty"`
+}
+
+// ReadPolicy reads the signature policy at path, or at the default location
+// if path is empty.
+func ReadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		var err error
+		if path, err = policyPath(); err != nil {
+			return nil, err
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("unable to read signature policy %s: %v", path, err)
+	}
+	p := &Policy{path: path}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, errors.Errorf("unable to parse signature policy %s: %v", path, err)
+	}
+	if len(p.Keys) == 0 && len(p.Identities) == 0 {
+		return nil, errors.Errorf("signature policy %s has no keys or identities", path)
+	}
+	for _, id := range p.Identities {
+		if id.Issuer == "" || (id.Subject == "" && id.SubjectRegExp == "") {
+			return nil, errors.Errorf(
+				"signature policy %s: identities require an issuer and a subject or subjectRegExp", path)
+		}
+	}
+	return p, nil
+}
+
+// DefaultPolicy returns the signature policy at the default location, or
+// nil if there is no policy.
+func DefaultPolicy() (*Policy, error) {
+	path, err := policyPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return ReadPolicy(path)
+}
+
+func policyPath() (string, error) {
+	if path := os.Getenv(PolicyFileEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Errorf("trouble resolving signature policy: %v", err)
+	}
+	return filepath.Join(dir, ".kpt", "signature-policy.yaml"), nil
+}
+
+// Cosign runs the cosign program with the args, and returns its output.
+// Making it a var so that it can be overridden for local testing
+var Cosign = func(args ...string) ([]byte, error) {
+	cosignProgram, err := exec.LookPath("cosign")
+	if err != nil {
+		return nil, errors.Errorf("no 'cosign' program on path: %v", err)
+	}
+	cmd := exec.Command(cosignProgram, args...)
+	cmd.Env = os.Environ()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// SignPackage signs the contents of the package at dir with the key, or
+// keyless if key is empty, and writes the signature to the File of the
+// package.
+func SignPackage(dir, key string) error {
+	manifest, clean, err := writeManifest(dir)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	args := []string{"sign-blob", "--yes", "--bundle", filepath.Join(dir, File)}
+	if key != "" {
+		args = append(args, "--key", key)
+	}
+	if _, err := Cosign(append(args, manifest)...); err != nil {
+		return errors.Errorf("failed to sign package %s: %v", dir, err)
+	}
+	return nil
+}
+
+// SignImage signs the image, which should be referenced by digest, with the
+// key, or keyless if key is empty.
+func SignImage(image, key string) error {
+	args := []string{"sign", "--yes"}
+	if key != "" {
+		args = append(args, "--key", key)
+	}
+	if _, err := Cosign(append(args, image)...); err != nil {
+		return errors.Errorf("failed to sign image %s: %v", image, err)
+	}
+	return nil
+}
+
+// VerifyPackage verifies the contents of the package at dir match the
+// signature of its File, and that it is signed by a key or identity of the
+// policy.
+func (p *Policy) VerifyPackage(dir string) error {
+	bundle := filepath.Join(dir, File)
+	if _, err := os.Stat(bundle); err != nil {
+		return errors.Errorf("package %s is not signed: no %s", dir, File)
+	}
+	manifest, clean, err := writeManifest(dir)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	return p.verify(dir, []string{"verify-blob", "--bundle", bundle}, manifest)
+}
+
+// VerifyImage verifies the image, which should be referenced by digest, is
+// signed by a key or identity of the policy.
+func (p *Policy) VerifyImage(image string) error {
+	return p.verify(image, []string{"verify"}, image)
+}
+
+// verify runs the cosign verify command for each key and identity of the
+// policy, until one succeeds.
+func (p *Policy) verify(name string, command []string, target string) error {
+	var failures []string
+	for _, key := range p.Keys {
+		if !strings.Contains(key, "://") && !filepath.IsAbs(key) {
+			key = filepath.Join(filepath.Dir(p.path), key)
+		}
+		args := append(append([]string{}, command...), "--key", key, target)
+		_, err := Cosign(args...)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("key %s: %v", key, err))
+	}
+	for _, id := range p.Identities {
+		args := append(append([]string{}, command...), "--certificate-oidc-issuer", id.Issuer)
+		subject := id.Subject
+		if id.SubjectRegExp != "" {
+			args = append(args, "--certificate-identity-regexp", id.SubjectRegExp)
+			subject = id.SubjectRegExp
+		} else {
+			args = append(args, "--certificate-identity", id.Subject)
+		}
+		_, err := Cosign(append(args, target)...)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("identity %s of %s: %v", subject, id.Issuer, err))
+	}
+	return errors.Errorf("failed to verify the signature of %s:\n  %s", name, strings.Join(failures, "\n  "))
+}
+
+// Manifest returns the sha256 digest and slash separated path of each file
+// of the package at dir, one file per line sorted by path, in the format of
+// sha256sum.  The manifest is what is signed, so the signature covers the
+// contents of the package independently of how it is stored or fetched.
+func Manifest(dir string) ([]byte, error) {
+	var lines []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// submodules and worktrees have a .git file rather than a directory
+		if info.Name() == File || info.Name() == ".git" || !info.Mode().IsRegular() {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		lines = append(lines, hex.EncodeToString(sum[:])+"  "+filepath.ToSlash(rel)+"\n")
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][66:] < lines[j][66:] })
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// writeManifest writes the manifest of the package to a temporary file, and
+// returns its path and a function removing it.
+func writeManifest(dir string) (string, func(), error) {
+	manifest, err := Manifest(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := ioutil.TempFile("", "kpt-manifest-")
+	if err != nil {
+		return "", nil, errors.Wrap(err)
+	}
+	clean := func() { os.Remove(f.Name()) }
+	if _, err := f.Write(manifest); err != nil {
+		f.Close()
+		clean()
+		return "", nil, errors.Wrap(err)
+	}
+	if err := f.Close(); err != nil {
+		clean()
+		return "", nil, errors.Wrap(err)
+	}
+	return f.Name(), clean, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// fakeCosign replaces the cosign program, recording the commands it is run
+// with.  Commands containing the rejected argument fail.
+func fakeCosign(t *testing.T, rejected string) (*[]string, func()) {
+	var commands []string
+	cosign := Cosign
+	Cosign = func(args ...string) ([]byte, error) {
+		command := strings.Join(args, " ")
+		// the manifest is a temporary file
+		if strings.HasPrefix(args[0], "sign-blob") || strings.HasPrefix(args[0], "verify-blob") {
+			command = strings.TrimSuffix(command, args[len(args)-1]) + "MANIFEST"
+		}
+		commands = append(commands, command)
+		if rejected != "" && strings.Contains(command, rejected) {
+			return nil, errors.Errorf("no matching signatures")
+		}
+		return nil, nil
+	}
+	return &commands, func() { Cosign = cosign }
+}
+
+func TestManifest(t *testing.T) {
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"Kptfile":          "kind: Kptfile\n",
+		"Kptfile.sig":      "{}",
+		"sub/Kptfile.sig":  "{}",
+		"sub/deploy.yaml":  "kind: Deployment\n",
+		".git/HEAD":        "ref: refs/heads/master\n",
+		"service.yaml":     "kind: Service\n",
+		"sub/lib/.git":     "gitdir: ../../.git/modules/lib\n",
+		"sub/service.yaml": "kind: Service\n",
+	}))
+
+	manifest, err := Manifest(dir)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"c6bc1ce286a4c2dd427642a48518dfab1a024151e987c3a9fd1c095372536044  Kptfile\n"+
+			"0986089a4d6c36657cb202f9580c17af4d96f818afca398410926f5b9703104d  service.yaml\n"+
+			"2e15259aa2d978f7affbf2000945c972ebf0beed0e2dcb7b0c490ee33871f120  sub/deploy.yaml\n"+
+			"0986089a4d6c36657cb202f9580c17af4d96f818afca398410926f5b9703104d  sub/service.yaml\n",
+		string(manifest))
+}
+
+func TestSignPackage(t *testing.T) {
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{"Kptfile": "kind: Kptfile\n"}))
+	commands, restore := fakeCosign(t, "")
+	defer restore()
+
+	assert.NoError(t, SignPackage(dir, "cosign.key"))
+	assert.NoError(t, SignPackage(dir, ""))
+	assert.Equal(t, []string{
+		"sign-blob --yes --bundle " + filepath.Join(dir, File) + " --key cosign.key MANIFEST",
+		"sign-blob --yes --bundle " + filepath.Join(dir, File) + " MANIFEST",
+	}, *commands)
+}
+
+func TestReadPolicy(t *testing.T) {
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"policy.yaml": `apiVersion: kpt.dev/v1alpha1
+kind: SignaturePolicy
+keys:
+- cosign.pub
+identities:
+- issuer: https://accounts.google.com
+  subject: release@example.com
+functionImages: true
+`,
+		"empty.yaml": "kind: SignaturePolicy\n",
+		"invalid.yaml": `kind: SignaturePolicy
+identities:
+- issuer: https://accounts.google.com
+`,
+	}))
+
+	p, err := ReadPolicy(filepath.Join(dir, "policy.yaml"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"cosign.pub"}, p.Keys)
+	assert.Equal(t, []Identity{{Issuer: "https://accounts.google.com", Subject: "release@example.com"}}, p.Identities)
+	assert.True(t, p.FunctionImages)
+
+	_, err = ReadPolicy(filepath.Join(dir, "empty.yaml"))
+	assert.EqualError(t, err, "signature policy "+filepath.Join(dir, "empty.yaml")+" has no keys or identities")
+	_, err = ReadPolicy(filepath.Join(dir, "invalid.yaml"))
+	assert.EqualError(t, err, "signature policy "+filepath.Join(dir, "invalid.yaml")+
+		": identities require an issuer and a subject or subjectRegExp")
+
+	// the default policy is optional
+	os.Setenv(PolicyFileEnv, filepath.Join(dir, "missing.yaml"))
+	defer os.Unsetenv(PolicyFileEnv)
+	p, err = DefaultPolicy()
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestPolicy_VerifyPackage(t *testing.T) {
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{"Kptfile": "kind: Kptfile\n", File: "{}"}))
+	p := &Policy{
+		Keys:       []string{"cosign.pub"},
+		Identities: []Identity{{Issuer: "https://accounts.google.com", SubjectRegExp: "@example.com$"}},
+		path:       filepath.Join("policies", "policy.yaml"),
+	}
+
+	// the key does not verify the signature, the identity does
+	commands, restore := fakeCosign(t, "--key")
+	defer restore()
+	assert.NoError(t, p.VerifyPackage(dir))
+	bundle := filepath.Join(dir, File)
+	assert.Equal(t, []string{
+		"verify-blob --bundle " + bundle + " --key " + filepath.Join("policies", "cosign.pub") + " MANIFEST",
+		"verify-blob --bundle " + bundle + " --certificate-oidc-issuer https://accounts.google.com" +
+			" --certificate-identity-regexp @example.com$ MANIFEST",
+	}, *commands)
+
+	// neither does
+	restore()
+	_, restore = fakeCosign(t, "verify-blob")
+	err := p.VerifyPackage(dir)
+	assert.EqualError(t, err, "failed to verify the signature of "+dir+":\n"+
+		"  key "+filepath.Join("policies", "cosign.pub")+": no matching signatures\n"+
+		"  identity @example.com$ of https://accounts.google.com: no matching signatures")
+
+	// packages without a signature are not verified
+	assert.NoError(t, os.Remove(bundle))
+	assert.EqualError(t, p.VerifyPackage(dir), "package "+dir+" is not signed: no "+File)
+}
+
+func TestPolicy_VerifyImage(t *testing.T) {
+	p := &Policy{Keys: []string{"https://kms.example.com/key"}}
+	commands, restore := fakeCosign(t, "")
+	defer restore()
+	assert.NoError(t, p.VerifyImage("ghcr.io/org/pkg@sha256:3c5f"))
+	assert.Equal(t, []string{"verify --key https://kms.example.com/key ghcr.io/org/pkg@sha256:3c5f"}, *commands)
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package status reports how far the packages of a workspace are behind their
+// upstreams, and whether updating them would conflict with their local
+// changes.
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/graph"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/semver"
+	"github.com/GoogleContainerTools/kpt/internal/util/update"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// Formats are the formats the status can be written in.
+var Formats = []string{"text", "json"}
+
+// Unknown is the number of commits behind of packages whose upstream history
+// could not be fetched, or which are pulled from OCI registries.
+const Unknown = -1
+
+// Package is the status of a package relative to its upstream.
+type Package struct {
+	// Path is the slash separated path of the package relative to the
+	// workspace
+	Path string `json:"path"`
+
+	// Upstream is the git repo directory or OCI image the package is
+	// fetched from
+	Upstream string `json:"upstream"`
+
+	// Ref is the git ref, semver constraint or OCI tag of the upstream
+	Ref string `json:"ref"`
+
+	// Version is the commit or digest the package was fetched at
+	Version string `json:"version"`
+
+	// Latest is the commit or digest the ref currently resolves to
+	Latest string `json:"latest,omitempty"`
+
+	// Behind is true if the ref resolves to another version than the
+	// package was fetched at
+	Behind bool `json:"behind"`
+
+	// CommitsBehind is the number of commits changing the package directory
+	// since the package was fetched, or Unknown
+	CommitsBehind int `json:"commitsBehind"`
+
+	// NewerTags are the tags of greater semantic versions than the version
+	// of the package, if it is fetched at a version
+	NewerTags []string `json:"newerTags,omitempty"`
+
+	// LocalChanges are the files modified locally since the package was
+	// fetched
+	LocalChanges []string `json:"localChanges,omitempty"`
+
+	// Conflicts are the files which updating the package would conflict in
+	Conflicts []string `json:"conflicts,omitempty"`
+
+	// Errors are the errors checking the status of the package.  The
+	// fields they prevented from being checked are left empty.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Workspace returns the status of each package with an upstream under dir,
+// parents first.  Packages nested within a package fetched from the same
+// repo are skipped, as they are updated with the package.
+func Workspace(dir string) ([]Package, error) {
+	paths, err := update.WorkspacePackages(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no packages with an upstream found in %s", dir)
+	}
+	var packages []Package
+	for _, path := range paths {
+		p, err := Check(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		p.Path = filepath.ToSlash(rel)
+		packages = append(packages, p)
+	}
+	return packages, nil
+}
+
+// Check returns the status of the package at path.  Failures to reach the
+// upstream are recorded in the Errors of the status, so the status of the
+// other packages of a workspace can still be reported.
+func Check(path string) (Package, error) {
+	kf, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return Package{}, err
+	}
+	p := Package{Path: filepath.ToSlash(path), CommitsBehind: Unknown}
+	var current string
+	var tags []string
+	switch kf.Upstream.Type {
+	case kptfile.OCIOrigin:
+		current, tags = p.checkOCI(kf.Upstream.OCI)
+	case kptfile.GitOrigin:
+		current, tags = p.checkGit(kf.Upstream.Git)
+	default:
+		return p, errors.Errorf("package %s has no git or oci upstream", path)
+	}
+
+	if semver.IsConstraint(p.Ref) {
+		current = ""
+		if lock, err := kptfileutil.ReadLockFile(path); err == nil {
+			// the lock file records the version the constraint resolved to
+			for _, l := range lock.Packages {
+				switch {
+				case l.Path != ".":
+				case kf.Upstream.Type == kptfile.OCIOrigin:
+					current = l.OCI.Ref
+				default:
+					current = strings.TrimPrefix(l.Git.Ref, strings.Trim(l.Git.Directory, "/")+"/")
+				}
+			}
+		}
+	}
+	if current != "" && tags != nil {
+		// refs which are not versions have no newer tags
+		p.NewerTags, _ = semver.Newer(current, tags)
+	}
+
+	if p.LocalChanges, err = update.LocalChanges(path); err != nil {
+		p.addError("local changes", err)
+	}
+	// packages without local changes update without conflicts
+	if p.Behind && len(p.LocalChanges) > 0 {
+		if p.Conflicts, err = (update.Command{Path: path}).Conflicts(); err != nil {
+			p.addError("conflicts", err)
+		}
+	}
+	return p, nil
+}
+
+// checkGit sets the upstream fields of a package fetched from git, and
+// returns its version without the directory prefix and the tags versioning
+// the package.
+func (p *Package) checkGit(git kptfile.Git) (string, []string) {
+	p.Upstream = git.Repo
+	if d := strings.Trim(git.Directory, "/"); d != "" {
+		p.Upstream += "/" + d
+	}
+	p.Ref, p.Version = git.Ref, git.Commit
+
+	var err error
+	if p.Latest, err = graph.ResolveGit(git); err != nil {
+		p.addError("ref", err)
+	} else if p.Latest == "" && git.Ref == git.Commit {
+		// packages fetched at a commit are never behind
+		p.Latest = git.Ref
+	}
+	switch {
+	case p.Latest == "":
+	case p.Latest == p.Version:
+		p.CommitsBehind = 0
+	default:
+		p.Behind = true
+		if p.CommitsBehind, err = gitutil.CountCommits(git.Repo, git.Directory, p.Version, p.Latest); err != nil {
+			p.CommitsBehind = Unknown
+			p.addError("commits", err)
+		}
+	}
+
+	tags, err := semver.GitTags(git.Repo, git.Directory)
+	if err != nil {
+		p.addError("tags", err)
+	}
+	return strings.TrimPrefix(git.Ref, strings.Trim(git.Directory, "/")+"/"), tags
+}
+
+// checkOCI sets the upstream fields of a package pulled from an OCI
+// registry, and returns its tag and the tags of the image.
+func (p *Package) checkOCI(o kptfile.OCI) (string, []string) {
+	p.Upstream, p.Ref, p.Version = o.Image, o.Ref, o.Digest
+	ref, err := oci.NewReference(o.Image, o.Ref)
+	if err != nil {
+		p.addError("ref", err)
+		return "", nil
+	}
+	if p.Latest, err = oci.Digest(ref); err != nil {
+		p.addError("ref", err)
+	}
+	p.Behind = p.Latest != "" && p.Latest != p.Version
+	if p.Latest != "" && !p.Behind {
+		p.CommitsBehind = 0
+	}
+
+	tags, err := oci.Tags(ref)
+	if err != nil {
+		p.addError("tags", err)
+	}
+	return o.Ref, tags
+}
+
+func (p *Package) addError(check string, err error) {
+	p.Errors = append(p.Errors, fmt.Sprintf("%s: %v", check,
+		strings.ReplaceAll(err.Error(), "\n", " ")))
+}
+
+// Write writes the status of the packages to w in the format.
+func Write(w io.Writer, packages []Package, format string) error {
+	switch format {
+	case "text":
+		return writeText(w, packages)
+	case "json":
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return errors.Wrap(e.Encode(packages))
+	}
+	return errors.Errorf("unsupported output format %q, must be one of: %s",
+		format, strings.Join(Formats, ", "))
+}
+
+// writeText writes a table of the packages, followed by the errors checking
+// their status.
+func writeText(w io.Writer, packages []Package) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tREF\tBEHIND\tCOMMITS\tNEWER TAGS\tLOCAL CHANGES\tCONFLICTS\t")
+	for _, p := range packages {
+		behind := "no"
+		switch {
+		case p.Latest == "":
+			behind = "unknown"
+		case p.Behind:
+			behind = "yes"
+		}
+		commits := "-"
+		if p.CommitsBehind != Unknown {
+			commits = fmt.Sprint(p.CommitsBehind)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", p.Path, p.Ref, behind, commits,
+			list(p.NewerTags), list(p.LocalChanges), list(p.Conflicts))
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err)
+	}
+	for _, p := range packages {
+		for _, e := range p.Errors {
+			fmt.Fprintf(w, "%s: %s\n", p.Path, e)
+		}
+	}
+	return nil
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	. "github.com/GoogleContainerTools/kpt/internal/util/status"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	testutil.Tag(t, g, "v1.0.0")
+	fetched, err := g.GetCommit()
+	assert.NoError(t, err)
+	for pkg, ref := range map[string]string{"java": "master", "mysql": "master", "wordpress": "v1.0.0"} {
+		err := get.Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: ref, Directory: pkg},
+			Destination: filepath.Join("apps", pkg)}.Run()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	// the mysql image is changed both locally and upstream, and the java
+	// port only locally
+	testutil.CopyData(t, g, filepath.Join(testutil.Dataset2, "mysql"), "mysql")
+	testutil.Commit(t, g, "update mysql")
+	testutil.Tag(t, g, "v1.1.0")
+	latest, err := g.GetCommit()
+	assert.NoError(t, err)
+	testutil.Replace(t, filepath.Join(w.WorkspaceDirectory, "apps", "mysql", "mysql-statefulset.resource.yaml"),
+		"mysql:5.7", "mysql:5.6")
+	testutil.Replace(t, filepath.Join(w.WorkspaceDirectory, "apps", "java", "java-service.resource.yaml"),
+		"8080", "8081")
+
+	packages, err := Workspace("apps")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []Package{
+		{
+			Path:          "java",
+			Upstream:      g.RepoDirectory + "/java",
+			Ref:           "master",
+			Version:       fetched,
+			Latest:        latest,
+			Behind:        true,
+			CommitsBehind: 0,
+			LocalChanges:  []string{"java-service.resource.yaml"},
+		},
+		{
+			Path:          "mysql",
+			Upstream:      g.RepoDirectory + "/mysql",
+			Ref:           "master",
+			Version:       fetched,
+			Latest:        latest,
+			Behind:        true,
+			CommitsBehind: 1,
+			LocalChanges:  []string{"mysql-statefulset.resource.yaml"},
+			Conflicts:     []string{"mysql-statefulset.resource.yaml"},
+		},
+		{
+			Path:          "wordpress",
+			Upstream:      g.RepoDirectory + "/wordpress",
+			Ref:           "v1.0.0",
+			Version:       fetched,
+			Latest:        fetched,
+			CommitsBehind: 0,
+			NewerTags:     []string{"v1.1.0"},
+		},
+	}, packages)
+
+	b := &bytes.Buffer{}
+	assert.NoError(t, Write(b, packages, "text"))
+	assert.Equal(t, strings.Join([]string{
+		"PACKAGE    REF     BEHIND  COMMITS  NEWER TAGS  LOCAL CHANGES                    CONFLICTS                        ",
+		"java       master  yes     0        -           java-service.resource.yaml       -                                ",
+		"mysql      master  yes     1        -           mysql-statefulset.resource.yaml  mysql-statefulset.resource.yaml  ",
+		"wordpress  v1.0.0  no      0        v1.1.0      -                                -                                ",
+		"",
+	}, "\n"), b.String())
+}
+
+func TestWrite_json(t *testing.T) {
+	b := &bytes.Buffer{}
+	assert.NoError(t, Write(b, []Package{{Path: "app", Upstream: "oci://example.com/app",
+		Ref: "v1", Version: "sha256:abc", CommitsBehind: Unknown,
+		Errors: []string{"ref: unauthorized"}}}, "json"))
+	assert.Equal(t, `[
+  {
+    "path": "app",
+    "upstream": "oci://example.com/app",
+    "ref": "v1",
+    "version": "sha256:abc",
+    "behind": false,
+    "commitsBehind": -1,
+    "errors": [
+      "ref: unauthorized"
+    ]
+  }
+]
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, Write(b, []Package{{Path: "app", Ref: "v1", CommitsBehind: Unknown,
+		Errors: []string{"ref: unauthorized"}}}, "text"))
+	assert.Contains(t, b.String(), "app      v1   unknown  -")
+	assert.Contains(t, b.String(), "app: ref: unauthorized\n")
+
+	assert.EqualError(t, Write(b, nil, "yaml"),
+		`unsupported output format "yaml", must be one of: text, json`)
+}
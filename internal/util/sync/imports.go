@@ -0,0 +1,148 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// ResolveImports returns the functions with the functions importing a
+// pipeline replaced by the functions of the pipeline, recursively.  The
+// paths of local packages are relative to dir, the package importing them.
+func ResolveImports(dir string, fns []kptfile.Function) ([]kptfile.Function, error) {
+	r := importResolver{importing: map[string]bool{}}
+	defer r.clean()
+	return r.resolve(dir, fns)
+}
+
+// importResolver resolves the imports of pipelines, fetching the packages
+// in git repos declaring them once.
+type importResolver struct {
+	// importing are the pipelines being imported, to detect cycles
+	importing map[string]bool
+
+	// fetched are the directories git packages were fetched to, by location
+	fetched map[string]string
+}
+
+func (r *importResolver) resolve(dir string, fns []kptfile.Function) ([]kptfile.Function, error) {
+	var resolved []kptfile.Function
+	for _, f := range fns {
+		if f.Import == nil {
+			resolved = append(resolved, f)
+			continue
+		}
+		imp, when := *f.Import, f.When
+		f.Import, f.When = nil, nil
+		if f.Image != "" || f.Exec != nil || f.Runtime != "" || f.Name != "" || f.Path != "" ||
+			f.Source != "" || f.Config.Kind != 0 || f.Generator {
+			return nil, errors.Errorf("function importing pipeline %s must only set import and when", imp.Pipeline)
+		}
+		if imp.Pipeline == "" {
+			return nil, errors.Errorf("imports must name a pipeline")
+		}
+
+		pkg, location, err := r.pkg(dir, imp)
+		if err != nil {
+			return nil, err
+		}
+		key := location + "#" + imp.Pipeline
+		if r.importing[key] {
+			return nil, errors.Errorf("pipeline %s of %s imports itself", imp.Pipeline, location)
+		}
+		k, err := kptfileutil.ReadFile(pkg)
+		if err != nil {
+			return nil, errors.WrapPrefixf(err, "failed to read the Kptfile of %s", location)
+		}
+		var pipeline *kptfile.Pipeline
+		for i := range k.Functions.Pipelines {
+			if k.Functions.Pipelines[i].Name == imp.Pipeline {
+				pipeline = &k.Functions.Pipelines[i]
+			}
+		}
+		if pipeline == nil {
+			return nil, errors.Errorf("pipeline %s not found in %s", imp.Pipeline, location)
+		}
+
+		r.importing[key] = true
+		imported, err := r.resolve(pkg, pipeline.Functions)
+		delete(r.importing, key)
+		if err != nil {
+			return nil, err
+		}
+		// the condition of the import is the condition of its functions
+		for i := range imported {
+			if when == nil {
+				break
+			}
+			if imported[i].When != nil {
+				return nil, errors.Errorf("functions of pipeline %s imported with a condition must not have conditions",
+					imp.Pipeline)
+			}
+			imported[i].When = when
+		}
+		resolved = append(resolved, imported...)
+	}
+	return resolved, nil
+}
+
+// pkg returns the directory of the package declaring the imported pipeline,
+// and its location as reported to the user.
+func (r *importResolver) pkg(dir string, imp kptfile.PipelineImport) (string, string, error) {
+	if imp.Git == nil {
+		pkg := filepath.Join(dir, filepath.FromSlash(imp.Path))
+		abs, err := filepath.Abs(pkg)
+		if err != nil {
+			return "", "", errors.Wrap(err)
+		}
+		return pkg, abs, nil
+	}
+	if imp.Path != "" {
+		return "", "", errors.Errorf("import of pipeline %s must not set both path and git", imp.Pipeline)
+	}
+	location := fmt.Sprintf("%s/%s@%s", imp.Git.Repo, imp.Git.Directory, imp.Git.Ref)
+	if pkg, found := r.fetched[location]; found {
+		return pkg, location, nil
+	}
+	tmp, err := ioutil.TempDir("", "kpt-pipeline-")
+	if err != nil {
+		return "", "", errors.Wrap(err)
+	}
+	if r.fetched == nil {
+		r.fetched = map[string]string{}
+	}
+	pkg := filepath.Join(tmp, "pkg")
+	r.fetched[location] = pkg
+	if err := (get.Command{Git: *imp.Git, Destination: pkg, Depth: git.DefaultDepth}).Run(); err != nil {
+		return "", "", errors.WrapPrefixf(err, "failed to fetch pipeline %s from %s", imp.Pipeline, location)
+	}
+	return pkg, location, nil
+}
+
+// clean removes the packages fetched from git repos.
+func (r *importResolver) clean() {
+	for _, pkg := range r.fetched {
+		os.RemoveAll(filepath.Dir(pkg))
+	}
+}
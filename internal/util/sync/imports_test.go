@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupPipelines returns a directory with an app package, and a policies
+// package declaring pipelines.
+func setupPipelines(t *testing.T) string {
+	d, err := ioutil.TempDir("", "kpt-pipelines")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(d, "app"), 0700))
+	assert.NoError(t, os.MkdirAll(filepath.Join(d, "policies"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "policies", kptfile.KptFileName), []byte(`
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: policies
+functions:
+  pipelines:
+  - name: validate
+    functions:
+    - image: gcr.io/kpt-functions/kubeval
+    - import:
+        pipeline: lint
+  - name: lint
+    functions:
+    - image: gcr.io/kpt-functions/lint
+  - name: cycle
+    functions:
+    - import:
+        pipeline: cycle
+`), 0600))
+	return d
+}
+
+func TestResolveImports(t *testing.T) {
+	d := setupPipelines(t)
+	defer os.RemoveAll(d)
+
+	fns, err := ResolveImports(filepath.Join(d, "app"), []kptfile.Function{
+		{Image: "gcr.io/kpt-functions/set-namespace"},
+		{Import: &kptfile.PipelineImport{Pipeline: "validate", Path: "../policies"}},
+	})
+	assert.NoError(t, err)
+	var images []string
+	for _, f := range fns {
+		images = append(images, f.Image)
+	}
+	assert.Equal(t, []string{
+		"gcr.io/kpt-functions/set-namespace",
+		"gcr.io/kpt-functions/kubeval",
+		"gcr.io/kpt-functions/lint",
+	}, images)
+}
+
+func TestResolveImports_errors(t *testing.T) {
+	d := setupPipelines(t)
+	defer os.RemoveAll(d)
+	app := filepath.Join(d, "app")
+	policies, err := filepath.Abs(filepath.Join(d, "policies"))
+	assert.NoError(t, err)
+
+	_, err = ResolveImports(app, []kptfile.Function{
+		{Import: &kptfile.PipelineImport{Pipeline: "missing", Path: "../policies"}}})
+	assert.EqualError(t, err, "pipeline missing not found in "+policies)
+
+	_, err = ResolveImports(app, []kptfile.Function{
+		{Import: &kptfile.PipelineImport{Pipeline: "cycle", Path: "../policies"}}})
+	assert.EqualError(t, err, "pipeline cycle of "+policies+" imports itself")
+
+	_, err = ResolveImports(app, []kptfile.Function{
+		{Image: "gcr.io/kpt-functions/lint", Import: &kptfile.PipelineImport{Pipeline: "lint", Path: "../policies"}}})
+	assert.EqualError(t, err, "function importing pipeline lint must only set import and when")
+}
+
+func TestResolveImports_when(t *testing.T) {
+	d := setupPipelines(t)
+	defer os.RemoveAll(d)
+
+	when := &kptfile.Condition{Setter: &kptfile.SetterCondition{Name: "env", Value: "prod"}}
+	fns, err := ResolveImports(filepath.Join(d, "app"), []kptfile.Function{
+		{Import: &kptfile.PipelineImport{Pipeline: "validate", Path: "../policies"}, When: when},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, fns, 2) {
+		assert.Equal(t, when, fns[0].When)
+		assert.Equal(t, when, fns[1].When)
+	}
+}
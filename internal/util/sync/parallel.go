@@ -0,0 +1,123 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+)
+
+// syncDependencies syncs the dependencies of the Kptfile, with up to
+// c.Parallel dependencies at a time.  The output of the dependencies synced
+// at the same time is written a line at a time.
+func (c Command) syncDependencies(k *kptfile.KptFile) error {
+	if c.Parallel > 1 {
+		mu := &gosync.Mutex{}
+		c.StdOut = &lockedWriter{w: c.StdOut, mu: mu}
+		c.StdErr = &lockedWriter{w: c.StdErr, mu: mu}
+	}
+	names := make([]string, len(k.Dependencies))
+	for i := range k.Dependencies {
+		names[i] = k.Dependencies[i].Name
+	}
+	return runParallel(names, c.Parallel, func(i int) error {
+		return c.syncDependency(k, k.Dependencies[i])
+	})
+}
+
+// runParallel calls run with the index of each of the dependencies with the
+// names, up to n at a time.  A dependency nested within another one, or the
+// other way around, is only run after the one declared before it succeeded.
+// No dependency is started after one failed, and the error of the first
+// failed dependency is returned.
+func runParallel(names []string, n int, run func(int) error) error {
+	if n <= 1 {
+		for i := range names {
+			if err := run(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, len(names))
+	done := make([]chan struct{}, len(names))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, n)
+	var mu gosync.Mutex
+	failed := false
+	var wg gosync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			for j := 0; j < i; j++ {
+				if !nested(names[i], names[j]) {
+					continue
+				}
+				<-done[j]
+			}
+			// slots are only taken once the dependencies run before are done,
+			// so waiting dependencies never hold a slot
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			mu.Lock()
+			skip := failed
+			mu.Unlock()
+			if skip {
+				return
+			}
+			if err := run(i); err != nil {
+				mu.Lock()
+				errs[i], failed = err, true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nested returns true if the dependency directory a is b, or either is
+// nested within the other.
+func nested(a, b string) bool {
+	a, b = filepath.ToSlash(filepath.Clean(a)), filepath.ToSlash(filepath.Clean(b))
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// lockedWriter serializes the writes of the dependencies synced at the same
+// time to the same writer.
+type lockedWriter struct {
+	w  io.Writer
+	mu *gosync.Mutex
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"fmt"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunParallel_serial(t *testing.T) {
+	var order []string
+	names := []string{"a", "b", "c"}
+	err := runParallel(names, 1, func(i int) error {
+		order = append(order, names[i])
+		if names[i] == "b" {
+			return fmt.Errorf("b failed")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "b failed")
+	assert.Equal(t, []string{"a", "b"}, order)
+}
+
+func TestRunParallel_concurrent(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	var mu gosync.Mutex
+	running, max := 0, 0
+	err := runParallel(names, 2, func(i int) error {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, max)
+}
+
+func TestRunParallel_nested(t *testing.T) {
+	names := []string{"a", "b", "a/c", "a/c/d"}
+	var mu gosync.Mutex
+	finished := map[string]bool{}
+	var early []string
+	err := runParallel(names, 4, func(i int) error {
+		mu.Lock()
+		for _, parent := range names[:i] {
+			if nested(names[i], parent) && !finished[parent] {
+				early = append(early, names[i])
+			}
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		finished[names[i]] = true
+		mu.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, early)
+	assert.Len(t, finished, 4)
+}
+
+func TestRunParallel_failed(t *testing.T) {
+	names := []string{"a", "b", "a/c"}
+	var mu gosync.Mutex
+	var run []string
+	err := runParallel(names, 2, func(i int) error {
+		mu.Lock()
+		run = append(run, names[i])
+		mu.Unlock()
+		if names[i] == "a" {
+			return fmt.Errorf("a failed")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "a failed")
+	assert.NotContains(t, run, "a/c")
+}
+
+func TestNested(t *testing.T) {
+	assert.True(t, nested("a", "a"))
+	assert.True(t, nested("a/b", "a"))
+	assert.True(t, nested("a", "a/b/"))
+	assert.False(t, nested("ab", "a"))
+	assert.False(t, nested("a", "b"))
+}
@@ -24,6 +24,9 @@ import (
 
 	"github.com/GoogleContainerTools/kpt/internal/util/functions"
 	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	"github.com/GoogleContainerTools/kpt/internal/util/inputs"
+	"github.com/GoogleContainerTools/kpt/internal/util/readiness"
 	"github.com/GoogleContainerTools/kpt/internal/util/setters"
 	"github.com/GoogleContainerTools/kpt/internal/util/update"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
@@ -39,8 +42,32 @@ type Command struct {
 
 	Verbose bool
 	DryRun  bool
-	StdOut  io.Writer
-	StdErr  io.Writer
+
+	// AllowExec allows the exec functions of the dependencies to run the
+	// binaries in the execAllowlist of the Kptfile
+	AllowExec bool
+
+	// Parallel is the number of dependencies synced at a time.  Dependencies
+	// nested within another dependency are synced after it.  Defaults to 1.
+	Parallel int
+
+	// FnCache caches the output of the functions of the dependencies, so
+	// they aren't run again for the same input.  See functions.PruneCache.
+	FnCache bool
+
+	// FailFast overrides the functions.failFast field of the Kptfile, if set
+	FailFast *bool
+
+	// Environment hydrates the dependencies declaring the environment in
+	// their Kptfile for it, with its setter values and functions, if set
+	Environment string
+
+	// Trace records the functions run on the dependencies with their
+	// timings, if set
+	Trace *functions.Trace
+
+	StdOut io.Writer
+	StdErr io.Writer
 }
 
 // Run syncs all dependencies declared in the Kptfile, fetching them
@@ -81,27 +108,80 @@ func (c Command) Run() error {
 		}
 	}
 
-	for i := range k.Dependencies {
-		dep := k.Dependencies[i]
-		if err := c.sync(dep); err != nil {
+	return c.syncDependencies(k)
+}
+
+// syncDependency syncs the dependency, then sets its setters, checks its
+// inputs and runs its functions.  The SettersResolved and Validated
+// conditions are recorded in the Kptfile of dependencies declaring readiness
+// gates.
+func (c Command) syncDependency(k *kptfile.KptFile, dep kptfile.Dependency) error {
+	if err := c.sync(dep); err != nil {
+		return err
+	}
+	path := filepath.Join(c.Dir, dep.Name)
+	if dep.AutoSet {
+		a := setters.AutoSet{
+			Writer:      c.StdOut,
+			PackagePath: path,
+		}
+		if err := a.PerformAutoSetters(); err != nil {
 			return err
 		}
-		path := filepath.Join(c.Dir, dep.Name)
-		if dep.AutoSet {
-			a := setters.AutoSet{
-				Writer:      c.StdOut,
-				PackagePath: path,
-			}
-			if err := a.PerformAutoSetters(); err != nil {
-				return err
-			}
+	}
+	if !dep.EnsureNotExists {
+		if err := inputs.Check(path); err != nil {
+			return err
 		}
-		if err := functions.RunFunctions(path, dep.Functions); err != nil {
+		if err := setters.CheckValues(path, nil); err != nil {
 			return err
 		}
 	}
+	failFast := k.Functions.FailFast
+	if c.FailFast != nil {
+		failFast = c.FailFast
+	}
+	fns, err := ResolveImports(c.Dir, dep.Functions)
+	if err != nil {
+		return err
+	}
+	if fns, err = c.environmentFunctions(path, fns); err != nil {
+		return err
+	}
+	err = functions.RunFunctions(path, fns, functions.RunOptions{
+		ExecPolicy: functions.ExecPolicy{Allow: c.AllowExec, Allowlist: k.Functions.ExecAllowlist, Dir: c.Dir},
+		Cache:      c.FnCache,
+		NoFailFast: failFast != nil && !*failFast,
+		StdErr:     c.StdErr,
+		Trace:      c.Trace,
+	})
+	if c.DryRun || dep.EnsureNotExists {
+		return err
+	}
+	recordErr := readiness.Record(path, readiness.SettersResolved(path), readiness.Validated(err))
+	if err != nil {
+		return err
+	}
+	return recordErr
+}
 
-	return nil
+// environmentFunctions returns the functions of the dependency at path with
+// the setters and the functions of --environment, if the dependency declares
+// it.
+func (c Command) environmentFunctions(path string, fns []kptfile.Function) ([]kptfile.Function, error) {
+	if c.Environment == "" {
+		return fns, nil
+	}
+	env, err := functions.Environment(path, c.Environment)
+	if err != nil || env == nil {
+		return fns, err
+	}
+	e := *env
+	// the imports of the environment are relative to the dependency
+	if e.Functions, err = ResolveImports(path, e.Functions); err != nil {
+		return nil, err
+	}
+	return functions.EnvironmentFunctions(e, fns)
 }
 
 func (c Command) sync(dependency kptfile.Dependency) error {
@@ -165,6 +245,7 @@ func (c Command) get(dependency kptfile.Dependency) error {
 		Git:         dependency.Git,
 		Destination: path,
 		Name:        dependency.Name,
+		Depth:       git.DefaultDepth,
 	}.Run()
 }
 
@@ -188,6 +269,7 @@ func (c Command) update(dependency kptfile.Dependency, k *kptfile.KptFile) error
 		Strategy: update.StrategyType(dependency.Strategy),
 		Verbose:  c.Verbose,
 		AutoSet:  dependency.AutoSet,
+		Depth:    git.DefaultDepth,
 	}.Run()
 }
 
@@ -0,0 +1,324 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tree builds the tree of a package, its subpackages and their
+// resources.
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Formats are the formats the tree can be written in.
+var Formats = []string{"text", "json", "yaml"}
+
+// Package is a package of the tree.
+type Package struct {
+	// Name is the name of the package from its Kptfile, or the name of the
+	// directory if it has no Kptfile
+	Name string `json:"name" yaml:"name"`
+
+	// Path is the slash separated path of the package relative to the root
+	// of the tree
+	Path string `json:"path" yaml:"path"`
+
+	// Upstream is the upstream the package was fetched from, and its ref
+	Upstream string `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+
+	// Setters are the values of the setters of the package by setter name
+	Setters map[string]string `json:"setters,omitempty" yaml:"setters,omitempty"`
+
+	// Metadata is the metadata of the package from its Kptfile
+	Metadata *Metadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	// ResourceCount is the number of resources of the package, excluding
+	// its subpackages
+	ResourceCount int `json:"resourceCount" yaml:"resourceCount"`
+
+	// Resources are the resources of the package, sorted by file
+	Resources []Resource `json:"resources,omitempty" yaml:"resources,omitempty"`
+
+	// Packages are the subpackages of the package, sorted by path
+	Packages []*Package `json:"packages,omitempty" yaml:"packages,omitempty"`
+}
+
+// Metadata is the metadata of a package.
+type Metadata struct {
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+	License     string `json:"license,omitempty" yaml:"license,omitempty"`
+
+	// Maintainers are the maintainers of the package as "Name <email>"
+	Maintainers []string `json:"maintainers,omitempty" yaml:"maintainers,omitempty"`
+
+	Keywords []string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+
+	// Documentation are the URLs of the documentation of the package
+	Documentation []string `json:"documentation,omitempty" yaml:"documentation,omitempty"`
+}
+
+// Resource is a resource of a package.
+type Resource struct {
+	// File is the slash separated path of the file of the resource,
+	// relative to its package
+	File string `json:"file" yaml:"file"`
+
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+	Namespace  string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name       string `json:"name" yaml:"name"`
+}
+
+// Build returns the tree of the package at dir.  If kinds are specified,
+// only the resources of the kinds are included.  Kinds are matched case
+// insensitively.
+func Build(dir string, kinds []string) (*Package, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, errors.Wrap(err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	root, err := readPackage(dir, ".", filepath.Base(abs), kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := map[string]*Package{".": root}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == dir {
+			return nil
+		}
+		if info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, kptfile.KptFileName)); err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		p, err := readPackage(path, filepath.ToSlash(rel), info.Name(), kinds)
+		if err != nil {
+			return err
+		}
+		// walk visits parents before their children
+		parent := filepath.Dir(rel)
+		for packages[filepath.ToSlash(parent)] == nil {
+			parent = filepath.Dir(parent)
+		}
+		packages[filepath.ToSlash(parent)].Packages = append(packages[filepath.ToSlash(parent)].Packages, p)
+		packages[p.Path] = p
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return root, nil
+}
+
+// readPackage reads the Kptfile and resources of the package at dir,
+// excluding its subpackages.
+func readPackage(dir, path, name string, kinds []string) (*Package, error) {
+	p := &Package{Name: name, Path: path}
+	if _, err := os.Stat(filepath.Join(dir, kptfile.KptFileName)); err == nil {
+		kf, err := kptfileutil.ReadFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		if kf.Name != "" {
+			p.Name = kf.Name
+		}
+		p.Upstream = upstream(kf.Upstream)
+		if p.Setters, err = setters.Values(dir); err != nil {
+			return nil, err
+		}
+		if len(p.Setters) == 0 {
+			p.Setters = nil
+		}
+		p.Metadata = metadata(kf.PackageMeta)
+	}
+
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir}).Read()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if meta.Kind == kptfile.TypeMeta.Kind || !matchesKind(meta.Kind, kinds) {
+			continue
+		}
+		file, _, err := kioutil.GetFileAnnotations(n)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		p.Resources = append(p.Resources, Resource{
+			File:       filepath.ToSlash(file),
+			APIVersion: meta.APIVersion,
+			Kind:       meta.Kind,
+			Namespace:  meta.Namespace,
+			Name:       meta.Name,
+		})
+	}
+	sort.SliceStable(p.Resources, func(i, j int) bool { return p.Resources[i].File < p.Resources[j].File })
+	p.ResourceCount = len(p.Resources)
+	return p, nil
+}
+
+// metadata returns the metadata of the package, or nil if it has none.
+func metadata(meta kptfile.PackageMeta) *Metadata {
+	m := &Metadata{
+		Description:   meta.ShortDescription,
+		Version:       meta.Version,
+		License:       meta.License,
+		Keywords:      meta.Keywords,
+		Documentation: meta.Documentation,
+	}
+	for _, maintainer := range meta.Maintainers {
+		m.Maintainers = append(m.Maintainers, maintainer.String())
+	}
+	if m.Description == "" && m.Version == "" && m.License == "" && len(m.Maintainers) == 0 &&
+		len(m.Keywords) == 0 && len(m.Documentation) == 0 {
+		return nil
+	}
+	return m
+}
+
+func matchesKind(kind string, kinds []string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// upstream returns the upstream of the package as uri@ref, or an empty
+// string for local packages.
+func upstream(u kptfile.Upstream) string {
+	switch {
+	case u.Type == kptfile.OCIOrigin:
+		return "oci://" + u.OCI.Image + ":" + u.OCI.Ref
+	case u.Type == kptfile.HelmOrigin:
+		return "helm://" + strings.TrimPrefix(u.Helm.Repo, "https://") + "/" + u.Helm.Chart + "@" + u.Helm.Version
+	case u.Git.Repo != "":
+		s := u.Git.Repo
+		if d := strings.Trim(u.Git.Directory, "/"); d != "" {
+			s += "/" + d
+		}
+		return s + "@" + u.Git.Ref
+	}
+	return ""
+}
+
+// Write writes the tree to w in the format.
+func (p *Package) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		b := &strings.Builder{}
+		p.writeText(b, "", "")
+		_, err := io.WriteString(w, b.String())
+		return errors.Wrap(err)
+	case "json":
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return errors.Wrap(e.Encode(p))
+	case "yaml":
+		b, err := yaml.Marshal(p)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		_, err = w.Write(b)
+		return errors.Wrap(err)
+	}
+	return errors.Errorf("unsupported output format %q, must be one of: %s",
+		format, strings.Join(Formats, ", "))
+}
+
+// writeText writes the package as a line annotated with its upstream,
+// resource count, setters, license and keywords, followed by its resources
+// and subpackages.
+func (p *Package) writeText(b *strings.Builder, prefix, childPrefix string) {
+	annotations := []string{fmt.Sprintf("%d resources", p.ResourceCount)}
+	if p.Upstream != "" {
+		annotations = append([]string{"upstream " + p.Upstream}, annotations...)
+	}
+	if len(p.Setters) > 0 {
+		var names []string
+		for name := range p.Setters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var values []string
+		for _, name := range names {
+			values = append(values, name+"="+p.Setters[name])
+		}
+		annotations = append(annotations, "setters "+strings.Join(values, " "))
+	}
+	if p.Metadata != nil && p.Metadata.License != "" {
+		annotations = append(annotations, "license "+p.Metadata.License)
+	}
+	if p.Metadata != nil && len(p.Metadata.Keywords) > 0 {
+		annotations = append(annotations, "keywords "+strings.Join(p.Metadata.Keywords, ","))
+	}
+	fmt.Fprintf(b, "%sPackage %q (%s)\n", prefix, p.Path, strings.Join(annotations, ", "))
+
+	count := len(p.Resources) + len(p.Packages)
+	for i, r := range p.Resources {
+		branch, _ := branches(i == count-1)
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + name
+		}
+		fmt.Fprintf(b, "%s%s[%s]  %s %s\n", childPrefix, branch, r.File, r.Kind, name)
+	}
+	for i, sub := range p.Packages {
+		branch, indent := branches(len(p.Resources)+i == count-1)
+		sub.writeText(b, childPrefix+branch, childPrefix+indent)
+	}
+}
+
+// branches returns the branch drawn before a child, and the indent of the
+// children of the child.
+func branches(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
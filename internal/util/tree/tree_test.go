@@ -0,0 +1,241 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tree_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	. "github.com/GoogleContainerTools/kpt/internal/util/tree"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+)
+
+// setupPackage writes an app package with a db subpackage to a temp dir.
+func setupPackage(t *testing.T) string {
+	files := map[string]string{
+		"Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: app
+upstream:
+  type: git
+  git:
+    repo: https://github.com/example/pkgs
+    directory: /app
+    ref: v1
+openAPI:
+  definitions:
+    io.k8s.cli.setters.replicas:
+      x-k8s-cli:
+        setter:
+          name: replicas
+          value: "3"
+`,
+		"deploy.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: prod
+`,
+		"svc.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: prod
+`,
+		"db/Kptfile": `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: mysql
+packageMetadata:
+  license: Apache-2.0
+  maintainers:
+  - name: Jane Doe
+    email: jane@example.com
+  keywords: [database, mysql]
+  documentation: [https://example.com/mysql]
+`,
+		"db/statefulset.yaml": `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: mysql
+`,
+	}
+	return pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("app").WithFiles(files))
+}
+
+func TestBuild(t *testing.T) {
+	openapi.ResetOpenAPI()
+	defer openapi.ResetOpenAPI()
+	dir := setupPackage(t)
+
+	tree, err := Build(dir, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, &Package{
+		Name:          "app",
+		Path:          ".",
+		Upstream:      "https://github.com/example/pkgs/app@v1",
+		Setters:       map[string]string{"replicas": "3"},
+		ResourceCount: 2,
+		Resources: []Resource{
+			{File: "deploy.yaml", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "app"},
+			{File: "svc.yaml", APIVersion: "v1", Kind: "Service", Namespace: "prod", Name: "app"},
+		},
+		Packages: []*Package{{
+			Name: "mysql",
+			Path: "db",
+			Metadata: &Metadata{
+				License:       "Apache-2.0",
+				Maintainers:   []string{"Jane Doe <jane@example.com>"},
+				Keywords:      []string{"database", "mysql"},
+				Documentation: []string{"https://example.com/mysql"},
+			},
+			ResourceCount: 1,
+			Resources: []Resource{
+				{File: "statefulset.yaml", APIVersion: "apps/v1", Kind: "StatefulSet", Name: "mysql"},
+			},
+		}},
+	}, tree)
+}
+
+func TestBuild_kinds(t *testing.T) {
+	openapi.ResetOpenAPI()
+	defer openapi.ResetOpenAPI()
+	dir := setupPackage(t)
+
+	tree, err := Build(dir, []string{"service", "StatefulSet"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []Resource{
+		{File: "svc.yaml", APIVersion: "v1", Kind: "Service", Namespace: "prod", Name: "app"},
+	}, tree.Resources)
+	assert.Equal(t, 1, tree.ResourceCount)
+	assert.Equal(t, 1, tree.Packages[0].ResourceCount)
+}
+
+func TestPackage_Write(t *testing.T) {
+	tree := &Package{
+		Name:          "app",
+		Path:          ".",
+		Upstream:      "https://github.com/example/pkgs/app@v1",
+		Setters:       map[string]string{"replicas": "3", "env": "prod"},
+		ResourceCount: 1,
+		Resources: []Resource{
+			{File: "svc.yaml", APIVersion: "v1", Kind: "Service", Namespace: "prod", Name: "app"},
+		},
+		Packages: []*Package{{
+			Name:          "mysql",
+			Path:          "db",
+			ResourceCount: 1,
+			Resources: []Resource{
+				{File: "statefulset.yaml", APIVersion: "apps/v1", Kind: "StatefulSet", Name: "mysql"},
+			},
+		}},
+	}
+
+	tests := map[string]string{
+		"text": `Package "." (upstream https://github.com/example/pkgs/app@v1, 1 resources, setters env=prod replicas=3)
+├── [svc.yaml]  Service prod/app
+└── Package "db" (1 resources)
+    └── [statefulset.yaml]  StatefulSet mysql
+`,
+		"yaml": `name: app
+path: .
+upstream: https://github.com/example/pkgs/app@v1
+setters:
+  env: prod
+  replicas: "3"
+resourceCount: 1
+resources:
+- file: svc.yaml
+  apiVersion: v1
+  kind: Service
+  namespace: prod
+  name: app
+packages:
+- name: mysql
+  path: db
+  resourceCount: 1
+  resources:
+  - file: statefulset.yaml
+    apiVersion: apps/v1
+    kind: StatefulSet
+    name: mysql
+`,
+		"json": `{
+  "name": "app",
+  "path": ".",
+  "upstream": "https://github.com/example/pkgs/app@v1",
+  "setters": {
+    "env": "prod",
+    "replicas": "3"
+  },
+  "resourceCount": 1,
+  "resources": [
+    {
+      "file": "svc.yaml",
+      "apiVersion": "v1",
+      "kind": "Service",
+      "namespace": "prod",
+      "name": "app"
+    }
+  ],
+  "packages": [
+    {
+      "name": "mysql",
+      "path": "db",
+      "resourceCount": 1,
+      "resources": [
+        {
+          "file": "statefulset.yaml",
+          "apiVersion": "apps/v1",
+          "kind": "StatefulSet",
+          "name": "mysql"
+        }
+      ]
+    }
+  ]
+}
+`,
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			assert.NoError(t, tree.Write(b, format))
+			assert.Equal(t, expected, b.String())
+		})
+	}
+
+	err := tree.Write(&bytes.Buffer{}, "dot")
+	assert.EqualError(t, err, `unsupported output format "dot", must be one of: text, json, yaml`)
+}
+
+func TestPackage_Write_metadata(t *testing.T) {
+	tree := &Package{
+		Name:          "mysql",
+		Path:          ".",
+		Metadata:      &Metadata{License: "Apache-2.0", Keywords: []string{"database", "mysql"}},
+		ResourceCount: 0,
+	}
+	var b bytes.Buffer
+	assert.NoError(t, tree.Write(&b, "text"))
+	assert.Equal(t, `Package "." (0 resources, license Apache-2.0, keywords database,mysql)
+`, b.String())
+}
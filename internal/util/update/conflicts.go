@@ -0,0 +1,242 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// LocalLabel labels the local changes in conflict markers
+	LocalLabel = "local"
+	// OriginalLabel labels the original upstream in conflict markers
+	OriginalLabel = "original"
+	// UpstreamLabel labels the upstream changes in conflict markers
+	UpstreamLabel = "upstream"
+)
+
+// ConflictError is returned when both the local package and upstream changed
+// the same fields of resources.  The update is applied, with git-style
+// conflict markers written into the conflicting files.
+type ConflictError struct {
+	// Files contains the conflicting files, relative to the package
+	Files []string
+
+	// Fields contains the conflicting fields of the resources in each file
+	Fields map[string][]string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("merge conflicts in %s: resolve them with 'kpt pkg resolve'",
+		strings.Join(e.Files, ", "))
+}
+
+// markConflicts writes conflict markers into the files of the merged package
+// where the local and updated packages changed the same field of a resource
+// differently, and returns the conflicts with the files relative to the
+// packages.  The markers are written using git merge-file on the local,
+// original and updated file.
+func markConflicts(localPath, originalPath, updatedPath, mergedPath string) (ConflictError, error) {
+	conflicts := ConflictError{Fields: map[string][]string{}}
+	err := filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if info.Name() == kptfile.KptFileName || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		rel, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+		original, updated, merged := filepath.Join(originalPath, rel),
+			filepath.Join(updatedPath, rel), filepath.Join(mergedPath, rel)
+		for _, p := range []string{original, updated, merged} {
+			if _, err := os.Stat(p); err != nil {
+				// not changed on both sides
+				return nil
+			}
+		}
+
+		fields, err := conflictingFields(path, original, updated)
+		if err != nil || len(fields) == 0 {
+			return err
+		}
+		marked, err := mergeFile(path, original, updated)
+		if err != nil || marked == nil {
+			return err
+		}
+		conflicts.Files = append(conflicts.Files, filepath.ToSlash(rel))
+		conflicts.Fields[filepath.ToSlash(rel)] = fields
+		return ioutil.WriteFile(merged, marked, info.Mode())
+	})
+	sort.Strings(conflicts.Files)
+	return conflicts, err
+}
+
+// mergeFile returns the 3-way merge of the files with conflict markers, or
+// nil if they merge without conflicts.
+func mergeFile(local, original, updated string) ([]byte, error) {
+	merged, conflicts, err := mergeFiles(local, original, updated)
+	if err != nil || !conflicts {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// mergeFiles returns the 3-way merge of the files, and whether it has
+// conflict markers.
+func mergeFiles(local, original, updated string) ([]byte, bool, error) {
+	cmd := exec.Command("git", "merge-file", "-p",
+		"-L", LocalLabel, "-L", OriginalLabel, "-L", UpstreamLabel,
+		local, original, updated)
+	cmd.Env = os.Environ()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), false, nil
+	}
+	// git merge-file exits with the number of conflicts, or a negative
+	// value on errors
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 &&
+		exitErr.ExitCode() < 128 {
+		return stdout.Bytes(), true, nil
+	}
+	return nil, false, errors.Errorf("failed to merge %s: %v: %s", local, err, stderr.String())
+}
+
+// conflictingFields returns the fields of the resources in the files which
+// were changed from the original to different values locally and upstream,
+// as "<resource>: <field>".
+func conflictingFields(local, original, updated string) ([]string, error) {
+	var resources []map[string]map[string]string
+	for _, path := range []string{local, original, updated} {
+		r, err := readFields(path)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+	var conflicts []string
+	l, o, u := resources[0], resources[1], resources[2]
+	for id, originalFields := range o {
+		localFields, found := l[id]
+		if !found {
+			continue
+		}
+		updatedFields, found := u[id]
+		if !found {
+			continue
+		}
+		fields := map[string]bool{}
+		for _, r := range []map[string]string{localFields, originalFields, updatedFields} {
+			for f := range r {
+				fields[f] = true
+			}
+		}
+		for f := range fields {
+			lv, lFound := localFields[f]
+			ov, oFound := originalFields[f]
+			uv, uFound := updatedFields[f]
+			localChanged := lFound != oFound || lv != ov
+			updatedChanged := uFound != oFound || uv != ov
+			if localChanged && updatedChanged && (lFound != uFound || lv != uv) {
+				conflicts = append(conflicts, id+": "+strings.TrimPrefix(f, "."))
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// readFields returns the scalar fields of each resource in the file by the
+// resource id, e.g. "apps/v1 Deployment ns/app".  Files which are not valid
+// resources have no fields.
+func readFields(path string) (map[string]map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	nodes, err := (&kio.ByteReader{Reader: bytes.NewReader(b), OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return nil, nil
+	}
+	resources := map[string]map[string]string{}
+	for _, node := range nodes {
+		meta, err := node.GetMeta()
+		if err != nil {
+			continue
+		}
+		id := meta.APIVersion + " " + meta.Kind + " " + meta.Name
+		if meta.Namespace != "" {
+			id = meta.APIVersion + " " + meta.Kind + " " + meta.Namespace + "/" + meta.Name
+		}
+		fields := map[string]string{}
+		flatten("", node.YNode(), fields)
+		resources[id] = fields
+	}
+	return resources, nil
+}
+
+// flatten adds the scalar fields of the node to fields by their path.  List
+// elements with a name are identified by it rather than their index, so
+// reordering the elements does not conflict.
+func flatten(path string, node *yaml.Node, fields map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			flatten(path, n, fields)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			flatten(path+"."+node.Content[i].Value, node.Content[i+1], fields)
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			key := fmt.Sprintf("[%d]", i)
+			if name := yaml.NewRNode(n).Field("name"); name != nil && name.Value.YNode().Kind == yaml.ScalarNode {
+				key = fmt.Sprintf("[name=%s]", name.Value.YNode().Value)
+			}
+			flatten(path+key, n, fields)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			flatten(path, node.Alias, fields)
+		}
+	default:
+		fields[path] = node.Value
+	}
+}
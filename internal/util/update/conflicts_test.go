@@ -0,0 +1,179 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const conflictOriginal = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+`
+
+func writeConflictPackages(t *testing.T, local, updated, merged string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-conflicts-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for pkg, content := range map[string]string{
+		"local": local, "original": conflictOriginal, "updated": updated, "merged": merged} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, pkg), 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, pkg, "deploy.yaml"), []byte(content), 0600))
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestMarkConflicts(t *testing.T) {
+	local := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:local
+`
+	updated := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`
+	dir, clean := writeConflictPackages(t, local, updated, updated)
+	defer clean()
+
+	conflicts, err := markConflicts(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"), filepath.Join(dir, "merged"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deploy.yaml"}, conflicts.Files)
+	assert.Equal(t, map[string][]string{"deploy.yaml": {
+		"apps/v1 Deployment app: spec.template.spec.containers[name=app].image"}}, conflicts.Fields)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "merged", "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+<<<<<<< local
+        image: app:local
+=======
+        image: app:v2
+>>>>>>> upstream
+`, string(b))
+}
+
+func TestMarkConflicts_noConflict(t *testing.T) {
+	// adjacent fields changed on each side conflict for git, but not for the
+	// resource merge
+	local := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+`
+	updated := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`
+	merged := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`
+	dir, clean := writeConflictPackages(t, local, updated, merged)
+	defer clean()
+
+	conflicts, err := markConflicts(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"), filepath.Join(dir, "merged"))
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts.Files)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "merged", "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, merged, string(b))
+}
+
+func TestConflictingFields_sameChange(t *testing.T) {
+	changed := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`
+	dir, clean := writeConflictPackages(t, changed, changed, changed)
+	defer clean()
+
+	fields, err := conflictingFields(filepath.Join(dir, "local", "deploy.yaml"),
+		filepath.Join(dir, "original", "deploy.yaml"), filepath.Join(dir, "updated", "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/diff"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// conflictStatus is the status of the files which would conflict.
+const conflictStatus = "conflict"
+
+// summarizeDryRun writes the files of the package at pkgPath which the update
+// of its copy at updatedPath added, modified or deleted, and the conflicting
+// fields.  Returns an error if the update failed or would conflict, so
+// updates can be gated on it.
+func summarizeDryRun(w io.Writer, pkgPath, updatedPath string, updateErr error) error {
+	conflicts := &ConflictError{}
+	if updateErr != nil {
+		c, ok := updateErr.(*ConflictError)
+		if !ok {
+			return updateErr
+		}
+		conflicts = c
+	}
+
+	files, err := diff.DiffPackages(pkgPath, updatedPath)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(w, "dry-run: updating %s would not change any files\n", pkgPath)
+		return nil
+	}
+	fmt.Fprintf(w, "dry-run: updating %s would change %d files:\n", pkgPath, len(files))
+	for _, f := range files {
+		fields, conflict := conflicts.Fields[f.Path]
+		status := string(f.Status)
+		if conflict {
+			status = conflictStatus
+		}
+		fmt.Fprintf(w, "  %-9s %s\n", status, f.Path)
+		for _, field := range fields {
+			fmt.Fprintf(w, "      %s\n", field)
+		}
+	}
+
+	if len(conflicts.Files) > 0 {
+		return errors.Errorf("updating %s would conflict in %s", pkgPath,
+			strings.Join(conflicts.Files, ", "))
+	}
+	return nil
+}
+
+// Conflicts returns the files of the package which updating it would
+// conflict in, by updating a copy of it.  Unlike a dry-run, the package does
+// not need to be committed to git.
+func (u Command) Conflicts() ([]string, error) {
+	abs, err := filepath.Abs(u.Path)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	dir, err := ioutil.TempDir("", "kpt-conflicts-")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+	pkgPath := filepath.Join(dir, filepath.Base(abs))
+	if err := copyutil.CopyDir(u.Path, pkgPath); err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	u.Path, u.FullPackagePath = pkgPath, pkgPath
+	u.DryRun, u.checked = false, true
+	u.Output = ioutil.Discard
+	err = u.Run()
+	if c, ok := err.(*ConflictError); ok {
+		return c.Files, nil
+	}
+	return nil, err
+}
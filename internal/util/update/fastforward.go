@@ -17,11 +17,13 @@ package update
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/get"
 	"github.com/GoogleContainerTools/kpt/internal/util/git"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"sigs.k8s.io/kustomize/kyaml/copyutil"
 	"sigs.k8s.io/kustomize/kyaml/errors"
 	"sigs.k8s.io/kustomize/kyaml/sets"
@@ -40,24 +42,40 @@ var kptfileSet = func() sets.String {
 }()
 
 func (u FastForwardUpdater) Update(options UpdateOptions) error {
+	if isOCI(options) {
+		original, _, err := pullOCI(options.KptFile.Upstream.OCI.Image, options.KptFile.Upstream.OCI.Digest)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(original)
+		if err := errorIfDiffers(original, options.PackagePath, options.KptFile.PreserveLocal); err != nil {
+			return err
+		}
+		return get.Command{Destination: options.PackagePath, Clean: true, OCI: toOCI(options)}.Run()
+	}
+
 	g := options.KptFile.Upstream.Git
 	g.Ref = options.ToRef
 	g.Repo = options.ToRepo
-	if err := errorIfChanged(g, options.PackagePath); err != nil {
+	if err := errorIfChanged(g, options); err != nil {
 		return err
 	}
 
 	// refetch the package
-	return get.Command{Destination: options.PackagePath, Clean: true, Git: g}.Run()
+	return get.Command{Destination: options.PackagePath, Clean: true, Git: g,
+		Depth: options.Depth, Sparse: options.Sparse}.Run()
 }
 
-// errorIfChanged returns an error if the package at pkgPath has changed from the upstream
+// errorIfChanged returns an error if the package has changed from the upstream
 // source referenced by g.
-func errorIfChanged(g kptfile.Git, pkgPath string) error {
+func errorIfChanged(g kptfile.Git, options UpdateOptions) error {
 	original := &git.RepoSpec{
-		OrgRepo: g.Repo,
-		Path:    g.Directory,
-		Ref:     g.Commit,
+		OrgRepo:    g.Repo,
+		Path:       g.Directory,
+		Ref:        g.Commit,
+		Depth:      options.Depth,
+		Sparse:     options.Sparse,
+		Submodules: g.Submodules,
 	}
 	defaultRef, err := gitutil.DefaultRef(g.Repo)
 	if err != nil {
@@ -68,20 +86,81 @@ func errorIfChanged(g kptfile.Git, pkgPath string) error {
 		return errors.Errorf("failed cloning git repo: %v", err)
 	}
 	defer os.RemoveAll(original.Dir)
-	diff, err := copyutil.Diff(original.AbsPath(), pkgPath)
+	return errorIfDiffers(original.AbsPath(), options.PackagePath, options.KptFile.PreserveLocal)
+}
+
+// errorIfDiffers returns an error if the package at pkgPath differs from the
+// original package at originalPath, ignoring the preserved local files.
+func errorIfDiffers(originalPath, pkgPath string, preserved []string) error {
+	changed, err := changedFiles(originalPath, pkgPath, preserved)
 	if err != nil {
-		return errors.Errorf("failed to compare local package to original source: %v", err)
+		return err
 	}
-
-	diff = diff.Difference(kptfileSet)
-	if diff.Len() > 0 {
+	if len(changed) > 0 {
 		return DiffError(fmt.Sprintf(
 			"local package files have been modified: %v.\n  use a different update --strategy.",
-			diff.List()))
+			changed))
 	}
 	return nil
 }
 
+// changedFiles returns the sorted files of the package at pkgPath which differ
+// from the original package at originalPath, ignoring the Kptfile and the
+// preserved local files.
+func changedFiles(originalPath, pkgPath string, preserved []string) ([]string, error) {
+	diff, err := copyutil.Diff(originalPath, pkgPath)
+	if err != nil {
+		return nil, errors.Errorf("failed to compare local package to original source: %v", err)
+	}
+
+	diff = diff.Difference(kptfileSet)
+	for _, f := range diff.List() {
+		if isPreserved(preserved, filepath.ToSlash(f)) {
+			delete(diff, f)
+		}
+	}
+	return diff.List(), nil
+}
+
+// LocalChanges returns the files of the package at path which were modified
+// locally since it was fetched, by fetching the commit or digest it was
+// fetched at.  The Kptfile and the preserved local files are ignored.
+func LocalChanges(path string) ([]string, error) {
+	kf, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		original, _, err := pullOCI(kf.Upstream.OCI.Image, kf.Upstream.OCI.Digest)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(original)
+		return changedFiles(original, path, kf.PreserveLocal)
+	}
+	if kf.Upstream.Type != kptfile.GitOrigin {
+		return nil, errors.Errorf("package %s has no upstream", path)
+	}
+
+	g := kf.Upstream.Git
+	original := &git.RepoSpec{
+		OrgRepo:    g.Repo,
+		Path:       g.Directory,
+		Ref:        g.Commit,
+		Depth:      git.DefaultDepth,
+		Submodules: g.Submodules,
+	}
+	defaultRef, err := gitutil.DefaultRef(g.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := get.ClonerUsingGitExec(original, defaultRef); err != nil {
+		return nil, errors.Errorf("failed cloning git repo: %v", err)
+	}
+	defer os.RemoveAll(original.Dir)
+	return changedFiles(original.AbsPath(), path, kf.PreserveLocal)
+}
+
 // DiffError is returned if the local package and upstream package contents do not match.
 type DiffError string
 
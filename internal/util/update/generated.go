@@ -0,0 +1,59 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+)
+
+// generatedFiles returns the files of the package at pkgPath whose resources
+// were all created by generator functions, as patterns matching them.
+func generatedFiles(pkgPath string) ([]string, error) {
+	nodes, err := (&kio.LocalPackageReader{PackagePath: pkgPath}).Read()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	generated := map[string]bool{}
+	for _, n := range nodes {
+		meta, err := n.GetMeta()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		file := path.Clean(filepath.ToSlash(meta.Annotations[kioutil.PathAnnotation]))
+		isGenerated := meta.Annotations[functions.GeneratedByAnnotation] != ""
+		if all, found := generated[file]; !found || all {
+			generated[file] = isGenerated
+		}
+	}
+	var patterns []string
+	for file, all := range generated {
+		if all {
+			patterns = append(patterns, patternMeta.ReplaceAllString(file, `\$0`))
+		}
+	}
+	sort.Strings(patterns)
+	return patterns, nil
+}
+
+// patternMeta matches the characters with a special meaning in patterns.
+var patternMeta = regexp.MustCompile(`[*?[\\]`)
@@ -0,0 +1,54 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-generated")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	generated := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    kpt.dev/generated-by: gcr.io/example/generate-service
+`
+	for file, content := range map[string]string{
+		"deploy.yaml":          "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"service_web.yaml":     generated,
+		"[generated]/web.yaml": generated,
+		"mixed.yaml":           generated + "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(d, file)), 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(d, file), []byte(content), 0600))
+	}
+
+	patterns, err := generatedFiles(d)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`\[generated]/web.yaml`, "service_web.yaml"}, patterns)
+	assert.True(t, isPreserved(patterns, "[generated]/web.yaml"))
+	assert.False(t, isPreserved(patterns, "mixed.yaml"))
+}
@@ -48,6 +48,14 @@ type GitPatchUpdater struct {
 }
 
 func (u GitPatchUpdater) Update(options UpdateOptions) error {
+	if isOCI(options) {
+		return errors.Errorf("update strategy %s is not supported for packages pulled from an oci registry",
+			AlphaGitPatch)
+	}
+	if options.KptFile.Upstream.Git.Submodules {
+		return errors.Errorf("update strategy %s is not supported for packages with git submodules",
+			AlphaGitPatch)
+	}
 	u.UpdateOptions = options
 	u.packageRef = path.Join(strings.TrimLeft(u.KptFile.Upstream.Git.Directory, "/"),
 		u.ToRef)
@@ -257,8 +265,15 @@ func (u *GitPatchUpdater) commitTargetFiles() error {
 
 // formatPatch generates a patch for the most recent commit and records it on p.patch.
 func (u *GitPatchUpdater) formatPatch() error {
-	if err := u.gitRunner.Run(
-		"format-patch", "--stdout", "-n", "HEAD^", "--relative", "./"); err != nil {
+	args := []string{"format-patch", "--stdout", "-n", "HEAD^", "--relative", "./"}
+	if len(u.KptFile.PreserveLocal) > 0 {
+		// exclude the preserved local files from the patch
+		args = []string{"format-patch", "--stdout", "-n", "HEAD^", "--relative", "--", "."}
+		for _, p := range u.KptFile.PreserveLocal {
+			args = append(args, ":(exclude,glob)"+p)
+		}
+	}
+	if err := u.gitRunner.Run(args...); err != nil {
 		return errors.Errorf("update failed: unable to create patch: %v: %s %s",
 			err, u.gitRunner.Stderr.String(), u.gitRunner.Stdout.String())
 	}
@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// isOCI returns true if the package was pulled from an OCI registry.
+func isOCI(options UpdateOptions) bool {
+	return options.KptFile.Upstream.Type == kptfile.OCIOrigin
+}
+
+// toOCI returns the OCI upstream the package is updated to.
+func toOCI(options UpdateOptions) kptfile.OCI {
+	o := options.KptFile.Upstream.OCI
+	o.Image = options.ToRepo
+	o.Ref = options.ToRef
+	o.Digest = ""
+	return o
+}
+
+// pullOCI pulls the package at ref of the image to a new temporary directory,
+// which the caller must remove.  Returns the directory and the digest of the
+// pulled package.
+func pullOCI(image, ref string) (string, string, error) {
+	r, err := oci.NewReference(image, ref)
+	if err != nil {
+		return "", "", err
+	}
+	dir, err := ioutil.TempDir("", "kpt-update-")
+	if err != nil {
+		return "", "", err
+	}
+	digest, err := oci.Pull(r, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", errors.Errorf("failed to pull oci image %s: %v", r, err)
+	}
+	return dir, digest, nil
+}
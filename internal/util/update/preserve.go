@@ -0,0 +1,193 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/sets"
+)
+
+// validatePreserved returns an error if a preserveLocal pattern is malformed.
+func validatePreserved(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil || path.IsAbs(p) || p == "" {
+			return errors.Errorf("invalid preserveLocal path %q", p)
+		}
+	}
+	return nil
+}
+
+// isPreserved returns true if the slash separated path relative to the
+// package matches one of the preserveLocal patterns, or is in a directory
+// matching one of them.
+func isPreserved(patterns []string, rel string) bool {
+	elems := strings.Split(path.Clean(rel), "/")
+	for _, p := range patterns {
+		pattern := strings.Split(path.Clean(p), "/")
+		for i := len(elems); i > 0; i-- {
+			if matchElems(pattern, elems[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchElems matches the elements of a path against the elements of a
+// pattern, where a ** element matches any number of path elements.
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+		return false
+	}
+	return matchElems(pattern[1:], elems[1:])
+}
+
+// preservedFiles is a copy of the preserved files of a local package, which
+// are restored after the package is updated.
+type preservedFiles struct {
+	// pkgPath is the path to the local package
+	pkgPath string
+
+	// patterns are the preserveLocal patterns of the local package
+	patterns []string
+
+	// dir is the directory the preserved files are copied to
+	dir string
+
+	// files are the preserved files, relative to the package
+	files sets.String
+}
+
+// savePreserved copies the files of the package at pkgPath matching the
+// preserveLocal patterns.
+func savePreserved(pkgPath string, patterns []string) (*preservedFiles, error) {
+	p := &preservedFiles{pkgPath: pkgPath, patterns: patterns, files: sets.String{}}
+	if len(patterns) == 0 {
+		return p, nil
+	}
+	dir, err := ioutil.TempDir("", "kpt-preserve-")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	p.dir = dir
+	files, err := p.list()
+	if err != nil {
+		p.clean()
+		return nil, err
+	}
+	for _, f := range files {
+		if err := copyFile(filepath.Join(pkgPath, f), filepath.Join(dir, f)); err != nil {
+			p.clean()
+			return nil, err
+		}
+		p.files.Insert(f)
+	}
+	return p, nil
+}
+
+// list returns the files of the package matching the preserveLocal patterns.
+func (p *preservedFiles) list() ([]string, error) {
+	var files []string
+	err := filepath.Walk(p.pkgPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		rel, err := filepath.Rel(p.pkgPath, path)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// the Kptfile declaring the patterns is always updated
+		if rel == kptfile.KptFileName {
+			return nil
+		}
+		if isPreserved(p.patterns, filepath.ToSlash(rel)) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// restore restores the preserved files of the package, deleting the
+// preserved paths added by the update.
+func (p *preservedFiles) restore() error {
+	defer p.clean()
+	if len(p.patterns) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(p.pkgPath); err != nil {
+		return errors.Wrap(err)
+	}
+
+	files, err := p.list()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !p.files.Has(f) {
+			if err := os.Remove(filepath.Join(p.pkgPath, f)); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+	}
+	for _, f := range p.files.List() {
+		if err := copyFile(filepath.Join(p.dir, f), filepath.Join(p.pkgPath, f)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *preservedFiles) clean() {
+	if p.dir != "" {
+		os.RemoveAll(p.dir)
+	}
+}
+
+// copyFile copies the file at src to dst, creating the directory of dst.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	return errors.Wrap(copyutil.SyncFile(src, dst))
+}
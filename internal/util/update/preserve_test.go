@@ -0,0 +1,47 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPreserved(t *testing.T) {
+	patterns := []string{"local/**", "README.local.md", "config/*.env", "**/site.yaml", "overrides"}
+	for path, expected := range map[string]bool{
+		"local/a.yaml":           true,
+		"local/a/b/c.yaml":       true,
+		"README.local.md":        true,
+		"docs/README.local.md":   false,
+		"config/prod.env":        true,
+		"config/prod/values.env": false,
+		"site.yaml":              true,
+		"a/b/site.yaml":          true,
+		"overrides/a.yaml":       true,
+		"overrides.yaml":         false,
+		"deployment.yaml":        false,
+	} {
+		assert.Equal(t, expected, isPreserved(patterns, path), path)
+	}
+	assert.False(t, isPreserved(nil, "local/a.yaml"))
+}
+
+func TestValidatePreserved(t *testing.T) {
+	assert.NoError(t, validatePreserved([]string{"local/**", "*.local.md"}))
+	assert.EqualError(t, validatePreserved([]string{"local/[a"}), `invalid preserveLocal path "local/[a"`)
+	assert.EqualError(t, validatePreserved([]string{"/etc/passwd"}), `invalid preserveLocal path "/etc/passwd"`)
+}
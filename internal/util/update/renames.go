@@ -0,0 +1,375 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// renameThreshold is the similarity above which a resource or file deleted
+// upstream and a resource or file added upstream are the same one renamed.
+const renameThreshold = 0.6
+
+// resourceID identifies a resource of a package by the file it is in and
+// its kind, namespace and name.
+type resourceID struct {
+	path, apiVersion, kind, namespace, name string
+}
+
+func (id resourceID) String() string {
+	if id.kind == "" {
+		return id.path
+	}
+	name := id.name
+	if id.namespace != "" {
+		name = id.namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s", id.path, id.kind, name)
+}
+
+// rename is a resource or file which was moved or renamed upstream.
+type rename struct {
+	from, to resourceID
+}
+
+// followRenames finds the resources and files which were moved or renamed
+// between the original and updated packages, and moves and renames them in
+// the original and local packages, so the local changes are merged into the
+// renamed resources rather than dropped with the deleted ones.
+//
+// Resources keeping their identity are moved if they are in another file
+// upstream.  Resources deleted upstream are renamed to the resource of the
+// same kind added upstream with the most similar content.  Files which are
+// not resources are renamed to the most similar file added upstream.
+func followRenames(localPath, originalPath, updatedPath string) ([]rename, error) {
+	original, err := readResources(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := readResources(updatedPath)
+	if err != nil {
+		return nil, err
+	}
+	renames := findRenames(original, updated)
+	if len(renames) > 0 {
+		if err := applyRenames(originalPath, renames); err != nil {
+			return nil, err
+		}
+		if err := applyRenames(localPath, renames); err != nil {
+			return nil, err
+		}
+	}
+
+	fileRenames, err := followFileRenames(localPath, originalPath, updatedPath)
+	if err != nil {
+		return nil, err
+	}
+	return append(renames, fileRenames...), nil
+}
+
+// resource is a resource read from a package.
+type resource struct {
+	id      resourceID
+	content []string
+}
+
+// readResources reads the resources of the package at dir.
+func readResources(dir string) ([]resource, error) {
+	nodes, err := (&kio.LocalPackageReader{PackagePath: dir}).Read()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	var resources []resource
+	for _, n := range nodes {
+		id, err := identify(n)
+		if err != nil {
+			return nil, err
+		}
+		// compare the content of resources without their identity
+		c := n.Copy()
+		for _, f := range [][]yaml.Filter{
+			{yaml.ClearAnnotation(kioutil.PathAnnotation)},
+			{yaml.ClearAnnotation(kioutil.IndexAnnotation)},
+			{yaml.Clear(yaml.APIVersionField)},
+			{yaml.Clear(yaml.KindField)},
+			{yaml.Lookup(yaml.MetadataField), yaml.Clear(yaml.NameField)},
+			{yaml.Lookup(yaml.MetadataField), yaml.Clear(yaml.NamespaceField)},
+		} {
+			if err := c.PipeE(f...); err != nil {
+				return nil, errors.Wrap(err)
+			}
+		}
+		s, err := c.String()
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		resources = append(resources, resource{id: id, content: strings.Split(strings.TrimSpace(s), "\n")})
+	}
+	return resources, nil
+}
+
+func identify(n *yaml.RNode) (resourceID, error) {
+	meta, err := n.GetMeta()
+	if err != nil {
+		return resourceID{}, errors.Wrap(err)
+	}
+	path, _, err := kioutil.GetFileAnnotations(n)
+	if err != nil {
+		return resourceID{}, errors.Wrap(err)
+	}
+	return resourceID{path: filepath.ToSlash(path), apiVersion: meta.APIVersion, kind: meta.Kind,
+		namespace: meta.Namespace, name: meta.Name}, nil
+}
+
+// setMeta returns a filter setting the metadata field of a resource.
+func setMeta(field, value string) yaml.Filter {
+	return yaml.FilterFunc(func(n *yaml.RNode) (*yaml.RNode, error) {
+		return n.Pipe(yaml.LookupCreate(yaml.MappingNode, yaml.MetadataField),
+			yaml.SetField(field, yaml.NewScalarRNode(value)))
+	})
+}
+
+// findRenames returns the resources of the original package which were moved
+// to another file or renamed in the updated package.  Resources which exist
+// more than once in a package are not moved, as they are ambiguous.
+func findRenames(original, updated []resource) []rename {
+	identity := func(id resourceID) resourceID {
+		id.path = ""
+		return id
+	}
+	originalIDs, updatedIDs := map[resourceID][]resource{}, map[resourceID][]resource{}
+	for _, r := range original {
+		originalIDs[identity(r.id)] = append(originalIDs[identity(r.id)], r)
+	}
+	for _, r := range updated {
+		updatedIDs[identity(r.id)] = append(updatedIDs[identity(r.id)], r)
+	}
+
+	var renames []rename
+	var deleted, added []resource
+	for _, r := range original {
+		u, found := updatedIDs[identity(r.id)]
+		switch {
+		case !found:
+			deleted = append(deleted, r)
+		case len(u) == 1 && len(originalIDs[identity(r.id)]) == 1 && u[0].id.path != r.id.path:
+			renames = append(renames, rename{from: r.id, to: u[0].id})
+		}
+	}
+	for _, r := range updated {
+		if _, found := originalIDs[identity(r.id)]; !found {
+			added = append(added, r)
+		}
+	}
+
+	// pair the deleted and added resources, most similar first
+	type pair struct {
+		from, to   resource
+		similarity float64
+	}
+	var pairs []pair
+	for _, d := range deleted {
+		for _, a := range added {
+			if d.id.apiVersion != a.id.apiVersion || d.id.kind != a.id.kind {
+				continue
+			}
+			if s := similarity(d.content, a.content); s >= renameThreshold {
+				pairs = append(pairs, pair{from: d, to: a, similarity: s})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].similarity > pairs[j].similarity })
+	paired := map[resourceID]bool{}
+	for _, p := range pairs {
+		if paired[p.from.id] || paired[p.to.id] {
+			continue
+		}
+		paired[p.from.id], paired[p.to.id] = true, true
+		renames = append(renames, rename{from: p.from.id, to: p.to.id})
+	}
+	return renames
+}
+
+// similarity returns the Sørensen–Dice coefficient of the lines of a and b,
+// from 0 if they have no line in common to 1 if they have the same lines.
+func similarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	lines := map[string]int{}
+	for _, l := range a {
+		lines[l]++
+	}
+	common := 0
+	for _, l := range b {
+		if lines[l] > 0 {
+			lines[l]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// applyRenames moves and renames the resources of the package at dir.
+// Resources which already exist with the new identity are not renamed.
+func applyRenames(dir string, renames []rename) error {
+	rw := &kio.LocalPackageReadWriter{PackagePath: dir}
+	nodes, err := rw.Read()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	ids := map[resourceID]bool{}
+	for _, n := range nodes {
+		id, err := identify(n)
+		if err != nil {
+			return err
+		}
+		ids[id] = true
+	}
+
+	changed := false
+	for _, n := range nodes {
+		id, err := identify(n)
+		if err != nil {
+			return err
+		}
+		for _, r := range renames {
+			if r.from != id || ids[r.to] {
+				continue
+			}
+			err := n.PipeE(yaml.SetAnnotation(kioutil.PathAnnotation, r.to.path))
+			if err == nil && r.to.name != id.name {
+				err = n.PipeE(setMeta(yaml.NameField, r.to.name))
+			}
+			if err == nil && r.to.namespace != id.namespace {
+				err = n.PipeE(setMeta(yaml.NamespaceField, r.to.namespace))
+			}
+			if err != nil {
+				return errors.Wrap(err)
+			}
+			ids[r.to], changed = true, true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return errors.Wrap(rw.Write(nodes))
+}
+
+// followFileRenames renames the files which are not resources and were
+// renamed upstream in the original and local packages, merging the local
+// changes into the renamed file.  Files whose local changes conflict with
+// the upstream changes are not renamed, so they are kept as modified.
+func followFileRenames(localPath, originalPath, updatedPath string) ([]rename, error) {
+	_, originalFiles, err := getSubDirsAndNonKrmFiles(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	_, updatedFiles, err := getSubDirsAndNonKrmFiles(updatedPath)
+	if err != nil {
+		return nil, err
+	}
+	deleted := originalFiles.Difference(updatedFiles).List()
+	added := updatedFiles.Difference(originalFiles).List()
+
+	read := func(path string) ([]string, error) {
+		b, err := ioutil.ReadFile(path)
+		return strings.Split(string(b), "\n"), errors.Wrap(err)
+	}
+	var renames []rename
+	renamed := map[string]bool{}
+	for _, d := range deleted {
+		local := filepath.Join(localPath, d)
+		if !fileExists(local) {
+			continue
+		}
+		same, err := compareFiles(filepath.Join(originalPath, d), local)
+		if err != nil || same {
+			// unmodified files are deleted and added without loss
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		dc, err := read(filepath.Join(originalPath, d))
+		if err != nil {
+			return nil, err
+		}
+		best, bestSimilarity := "", renameThreshold
+		for _, a := range added {
+			if renamed[a] || fileExists(filepath.Join(localPath, a)) {
+				continue
+			}
+			ac, err := read(filepath.Join(updatedPath, a))
+			if err != nil {
+				return nil, err
+			}
+			if s := similarity(dc, ac); s >= bestSimilarity {
+				best, bestSimilarity = a, s
+			}
+		}
+		if best == "" {
+			continue
+		}
+		merged, conflicts, err := mergeFiles(local, filepath.Join(originalPath, d),
+			filepath.Join(updatedPath, best))
+		if err != nil {
+			return nil, err
+		}
+		if conflicts {
+			continue
+		}
+		// the local file is replaced by the merge of its changes into the
+		// renamed file, which is kept as it differs from the original
+		to := filepath.Join(localPath, best)
+		if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if err := ioutil.WriteFile(to, merged, info.Mode()); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if err := os.Remove(local); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		to = filepath.Join(originalPath, best)
+		if err := os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		if err := os.Rename(filepath.Join(originalPath, d), to); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		renamed[best] = true
+		renames = append(renames, rename{
+			from: resourceID{path: strings.TrimPrefix(filepath.ToSlash(d), "/")},
+			to:   resourceID{path: strings.TrimPrefix(filepath.ToSlash(best), "/")},
+		})
+	}
+	return renames, nil
+}
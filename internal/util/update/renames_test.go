@@ -0,0 +1,198 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeRenamePackages writes the files of the local, original and updated
+// packages, by package and slash separated path.
+func writeRenamePackages(t *testing.T, files map[string]map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-renames-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for pkg, contents := range files {
+		for path, content := range contents {
+			path = filepath.Join(dir, pkg, filepath.FromSlash(path))
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+			assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func assertFiles(t *testing.T, dir string, expected map[string]string) {
+	actual := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		actual[filepath.ToSlash(rel)] = string(b)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+const renameDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+`
+
+func renameService(name, port string) string {
+	return `apiVersion: v1
+kind: Service
+metadata:
+  name: ` + name + `
+spec:
+  ports:
+  - name: http
+    port: ` + port + `
+    protocol: TCP
+  selector:
+    app: app
+`
+}
+
+func TestFollowRenames_moved(t *testing.T) {
+	// the service is moved to its own file upstream, and its port changed
+	// locally
+	dir, clean := writeRenamePackages(t, map[string]map[string]string{
+		"local":    {"app.yaml": renameDeployment + "---\n" + renameService("app", "8080")},
+		"original": {"app.yaml": renameDeployment + "---\n" + renameService("app", "80")},
+		"updated":  {"app.yaml": renameDeployment, "service.yaml": renameService("app", "80")},
+	})
+	defer clean()
+
+	renames, err := followRenames(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"))
+	assert.NoError(t, err)
+	assert.Equal(t, []rename{{
+		from: resourceID{path: "app.yaml", apiVersion: "v1", kind: "Service", name: "app"},
+		to:   resourceID{path: "service.yaml", apiVersion: "v1", kind: "Service", name: "app"},
+	}}, renames)
+	assert.Equal(t, "app.yaml Service app", renames[0].from.String())
+
+	assertFiles(t, filepath.Join(dir, "local"), map[string]string{
+		"app.yaml":     renameDeployment,
+		"service.yaml": renameService("app", "8080"),
+	})
+	assertFiles(t, filepath.Join(dir, "original"), map[string]string{
+		"app.yaml":     renameDeployment,
+		"service.yaml": renameService("app", "80"),
+	})
+}
+
+func TestFollowRenames_renamed(t *testing.T) {
+	// the service is renamed upstream, and its port changed locally
+	dir, clean := writeRenamePackages(t, map[string]map[string]string{
+		"local":    {"service.yaml": renameService("app", "8080")},
+		"original": {"service.yaml": renameService("app", "80")},
+		"updated":  {"app-service.yaml": renameService("app-http", "80")},
+	})
+	defer clean()
+
+	renames, err := followRenames(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"))
+	assert.NoError(t, err)
+	assert.Equal(t, []rename{{
+		from: resourceID{path: "service.yaml", apiVersion: "v1", kind: "Service", name: "app"},
+		to:   resourceID{path: "app-service.yaml", apiVersion: "v1", kind: "Service", name: "app-http"},
+	}}, renames)
+	assertFiles(t, filepath.Join(dir, "local"), map[string]string{
+		"app-service.yaml": renameService("app-http", "8080"),
+	})
+}
+
+func TestFollowRenames_unrelated(t *testing.T) {
+	// a resource deleted upstream is not renamed to a dissimilar resource
+	// added upstream
+	dir, clean := writeRenamePackages(t, map[string]map[string]string{
+		"local":    {"service.yaml": renameService("app", "8080")},
+		"original": {"service.yaml": renameService("app", "80")},
+		"updated": {"service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: db
+spec:
+  clusterIP: None
+  type: ClusterIP
+`},
+	})
+	defer clean()
+
+	renames, err := followRenames(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"))
+	assert.NoError(t, err)
+	assert.Empty(t, renames)
+	assertFiles(t, filepath.Join(dir, "local"), map[string]string{
+		"service.yaml": renameService("app", "8080"),
+	})
+}
+
+func TestFollowRenames_files(t *testing.T) {
+	readme := strings.Join([]string{"# app", "", "Deploys the app.", "", "## Usage", "", "kpt pkg get", ""}, "\n")
+	dir, clean := writeRenamePackages(t, map[string]map[string]string{
+		"local": {
+			"README.md": strings.Replace(readme, "# app", "# my app", 1),
+			"NOTES.txt": "unchanged\n",
+		},
+		"original": {"README.md": readme, "NOTES.txt": "unchanged\n"},
+		"updated": {
+			"docs/README.md": strings.Replace(readme, "kpt pkg get", "kpt pkg get app", 1),
+			"docs/NOTES.txt": "unchanged\n",
+		},
+	})
+	defer clean()
+
+	renames, err := followRenames(filepath.Join(dir, "local"), filepath.Join(dir, "original"),
+		filepath.Join(dir, "updated"))
+	assert.NoError(t, err)
+	// unmodified files are replaced by the update
+	assert.Equal(t, []rename{{from: resourceID{path: "README.md"}, to: resourceID{path: "docs/README.md"}}},
+		renames)
+	assertFiles(t, filepath.Join(dir, "local"), map[string]string{
+		"docs/README.md": strings.Replace(strings.Replace(readme, "# app", "# my app", 1),
+			"kpt pkg get", "kpt pkg get app", 1),
+		"NOTES.txt": "unchanged\n",
+	})
+	assertFiles(t, filepath.Join(dir, "original"), map[string]string{
+		"docs/README.md": readme,
+		"NOTES.txt":      "unchanged\n",
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity([]string{"a", "b"}, []string{"b", "a"}))
+	assert.Equal(t, 0.5, similarity([]string{"a", "b"}, []string{"a", "c"}))
+	assert.Equal(t, 0.0, similarity([]string{"a"}, []string{"b"}))
+	assert.Equal(t, 1.0, similarity(nil, nil))
+}
@@ -23,7 +23,11 @@ import "github.com/GoogleContainerTools/kpt/internal/util/get"
 type ReplaceUpdater struct{}
 
 func (u ReplaceUpdater) Update(options UpdateOptions) error {
+	if isOCI(options) {
+		return get.Command{Destination: options.PackagePath, Clean: true, OCI: toOCI(options)}.Run()
+	}
 	options.KptFile.Upstream.Git.Ref = options.ToRef
 	options.KptFile.Upstream.Git.Repo = options.ToRepo
-	return get.Command{Destination: options.PackagePath, Clean: true, Git: options.KptFile.Upstream.Git}.Run()
+	return get.Command{Destination: options.PackagePath, Clean: true, Git: options.KptFile.Upstream.Git,
+		Depth: options.Depth, Sparse: options.Sparse}.Run()
 }
@@ -16,6 +16,7 @@ package update
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -25,6 +26,7 @@ import (
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/get"
 	"github.com/GoogleContainerTools/kpt/internal/util/git"
+	kptopenapi "github.com/GoogleContainerTools/kpt/internal/util/openapi"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"sigs.k8s.io/kustomize/kyaml/copyutil"
@@ -41,6 +43,10 @@ import (
 type ResourceMergeUpdater struct{}
 
 func (u ResourceMergeUpdater) Update(options UpdateOptions) error {
+	if isOCI(options) {
+		return u.updateFromOCI(options)
+	}
+
 	g := options.KptFile.Upstream.Git
 	g.Ref = options.ToRef
 	g.Repo = options.ToRepo
@@ -51,20 +57,67 @@ func (u ResourceMergeUpdater) Update(options UpdateOptions) error {
 	}
 
 	// get the original repo
-	original := &git.RepoSpec{OrgRepo: g.Repo, Path: g.Directory, Ref: g.Commit}
+	original := &git.RepoSpec{OrgRepo: g.Repo, Path: g.Directory, Ref: g.Commit,
+		Depth: options.Depth, Sparse: options.Sparse, Submodules: g.Submodules}
 	if err := get.ClonerUsingGitExec(original, defaultRef); err != nil {
 		return errors.Errorf("failed to clone git repo: original source: %v", err)
 	}
 	defer os.RemoveAll(original.AbsPath())
 
 	// get the updated repo
-	updated := &git.RepoSpec{OrgRepo: options.ToRepo, Path: g.Directory, Ref: options.ToRef}
+	updated := &git.RepoSpec{OrgRepo: options.ToRepo, Path: g.Directory, Ref: options.ToRef,
+		Depth: options.Depth, Sparse: options.Sparse, Submodules: g.Submodules}
 	if err := get.ClonerUsingGitExec(updated, defaultRef); err != nil {
 		return errors.Errorf("failed to clone git repo: updated source: %v", err)
 	}
 	defer os.RemoveAll(updated.AbsPath())
 
-	kf, err := u.updatedKptfile(updated.AbsPath(), original.AbsPath(), options)
+	cmd := exec.Command("git", "rev-parse", "--verify", "HEAD")
+	cmd.Dir = updated.AbsPath()
+	cmd.Env = os.Environ()
+	cmd.Stderr = os.Stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	// local package controls the upstream field
+	upstream := options.KptFile.Upstream
+	upstream.Git.Commit = strings.TrimSpace(string(b))
+	upstream.Git.Ref = options.ToRef
+	upstream.Git.Repo = options.ToRepo
+
+	return u.merge(updated.AbsPath(), original.AbsPath(), upstream, options)
+}
+
+// updateFromOCI merges the package pulled from an OCI registry at the digest
+// recorded in the Kptfile and at the updated ref.
+func (u ResourceMergeUpdater) updateFromOCI(options UpdateOptions) error {
+	original, _, err := pullOCI(options.KptFile.Upstream.OCI.Image, options.KptFile.Upstream.OCI.Digest)
+	if err != nil {
+		return errors.Errorf("original source: %v", err)
+	}
+	defer os.RemoveAll(original)
+
+	to := toOCI(options)
+	updated, digest, err := pullOCI(to.Image, to.Ref)
+	if err != nil {
+		return errors.Errorf("updated source: %v", err)
+	}
+	defer os.RemoveAll(updated)
+
+	// local package controls the upstream field
+	upstream := options.KptFile.Upstream
+	upstream.OCI = to
+	upstream.OCI.Digest = digest
+
+	return u.merge(updated, original, upstream, options)
+}
+
+// merge performs a 3-way merge of the original and updated packages into the
+// local package, and records the upstream in its Kptfile.
+func (u ResourceMergeUpdater) merge(updatedPath, originalPath string, upstream kptfile.Upstream,
+	options UpdateOptions) error {
+	kf, err := u.updatedKptfile(updatedPath, originalPath, upstream, options)
 	if err != nil {
 		return err
 	}
@@ -75,22 +128,48 @@ func (u ResourceMergeUpdater) Update(options UpdateOptions) error {
 
 	err = settersutil.SetAllSetterDefinitions(
 		filepath.Join(options.PackagePath, kptfile.KptFileName),
-		original.AbsPath(),
-		updated.AbsPath(),
+		originalPath,
+		updatedPath,
 		options.PackagePath,
 	)
 	if err != nil {
 		return err
 	}
 
-	if err := MergeSubPackages(options.PackagePath, updated.AbsPath(), original.AbsPath()); err != nil {
+	if err := MergeSubPackages(options.PackagePath, updatedPath, originalPath); err != nil {
+		return err
+	}
+
+	// use the schemas of the CRDs in the packages to find the merge keys of
+	// custom resources lists
+	if err := kptopenapi.AddCRDSchemas(originalPath, updatedPath, options.PackagePath); err != nil {
 		return err
 	}
 
+	// move the local changes of the resources and files renamed upstream to
+	// the renamed ones, rather than dropping them with the deleted ones
+	renames, err := followRenames(options.PackagePath, originalPath, updatedPath)
+	if err != nil {
+		return err
+	}
+	for _, r := range renames {
+		fmt.Fprintf(options.Output, "RENAMED: %s to %s upstream\n", r.from, r.to)
+	}
+
+	// keep the local package to find the conflicts after merging
+	localPath, err := ioutil.TempDir("", "kpt-")
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer os.RemoveAll(localPath)
+	if err := copyutil.CopyDir(options.PackagePath, localPath); err != nil {
+		return errors.Wrap(err)
+	}
+
 	// merge the Resources: original + updated + dest => dest
 	err = filters.Merge3{
-		OriginalPath: original.AbsPath(),
-		UpdatedPath:  updated.AbsPath(),
+		OriginalPath: originalPath,
+		UpdatedPath:  updatedPath,
 		DestPath:     options.PackagePath,
 		// TODO: Write a test to ensure this is set
 		MergeOnPath: true,
@@ -99,21 +178,27 @@ func (u ResourceMergeUpdater) Update(options UpdateOptions) error {
 		return err
 	}
 
-	return ReplaceNonKRMFiles(updated.AbsPath(), original.AbsPath(), options.PackagePath)
-}
+	if err := ReplaceNonKRMFiles(updatedPath, originalPath, options.PackagePath); err != nil {
+		return err
+	}
 
-// updatedKptfile returns a Kptfile to replace the existing local Kptfile as part of the update
-func (u ResourceMergeUpdater) updatedKptfile(updatedPath, originalPath string, options UpdateOptions) (
-	kptfile.KptFile, error) {
-	cmd := exec.Command("git", "rev-parse", "--verify", "HEAD")
-	cmd.Dir = updatedPath
-	cmd.Env = os.Environ()
-	cmd.Stderr = os.Stderr
-	b, err := cmd.Output()
+	conflicts, err := markConflicts(localPath, originalPath, updatedPath, options.PackagePath)
 	if err != nil {
-		return kptfile.KptFile{}, err
+		return err
+	}
+	if len(conflicts.Files) > 0 {
+		for _, f := range conflicts.Files {
+			fmt.Fprintf(options.Output, "CONFLICT: merge conflict in %s\n",
+				filepath.Join(options.PackagePath, f))
+		}
+		return &conflicts
 	}
-	commit := strings.TrimSpace(string(b))
+	return nil
+}
+
+// updatedKptfile returns a Kptfile to replace the existing local Kptfile as part of the update
+func (u ResourceMergeUpdater) updatedKptfile(updatedPath, originalPath string, upstream kptfile.Upstream,
+	options UpdateOptions) (kptfile.KptFile, error) {
 	updatedKf, err := kptfileutil.ReadFile(updatedPath)
 	if err != nil {
 		updatedKf, err = kptfileutil.ReadFile(options.PackagePath)
@@ -130,11 +215,7 @@ func (u ResourceMergeUpdater) updatedKptfile(updatedPath, originalPath string, o
 		}
 	}
 
-	// local package controls the upstream field
-	updatedKf.Upstream = options.KptFile.Upstream
-	updatedKf.Upstream.Git.Commit = commit
-	updatedKf.Upstream.Git.Ref = options.ToRef
-	updatedKf.Upstream.Git.Repo = options.ToRepo
+	updatedKf.Upstream = upstream
 
 	// keep the local OpenAPI values
 	err = updatedKf.MergeOpenAPI(options.KptFile, originalKf)
@@ -16,14 +16,25 @@
 package update
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	"github.com/GoogleContainerTools/kpt/internal/util/oci"
+	"github.com/GoogleContainerTools/kpt/internal/util/semver"
 	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
+	"github.com/GoogleContainerTools/kpt/internal/util/variant"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
 	"sigs.k8s.io/kustomize/kyaml/errors"
 )
 
@@ -34,7 +45,8 @@ type UpdateOptions struct {
 	// ToRef is the ref to update to
 	ToRef string
 
-	// ToRepo is the repo to use for updating
+	// ToRepo is the repo to use for updating, or the image for packages pulled
+	// from an OCI registry
 	ToRepo string
 
 	// PackagePath is the relative path to the local package
@@ -58,6 +70,13 @@ type UpdateOptions struct {
 
 	// Perform setters automatically based on environment
 	AutoSet bool
+
+	// Depth is the number of commits fetched from git repos.  The full
+	// history is fetched if 0.
+	Depth int
+
+	// Sparse fetches only the files of the package directory from git repos.
+	Sparse bool
 }
 
 // Updater updates a local package
@@ -113,13 +132,15 @@ type Command struct {
 	// Ref is the ref to update to
 	Ref string
 
-	// Repo is the repo to update to
+	// Repo is the repo to update to, or the image for packages pulled from an
+	// OCI registry
 	Repo string
 
 	// Strategy is the update strategy to use
 	Strategy StrategyType
 
-	// DryRun if set will print the patch instead of applying it
+	// DryRun if set will print a summary of the changes to the package
+	// instead of updating it, or the patch for AlphaGitPatch
 	DryRun bool
 
 	// Verbose if set will print verbose information about the commands being run
@@ -134,6 +155,25 @@ type Command struct {
 
 	// Perform setters automatically based on environment
 	AutoSet bool
+
+	// Depth is the number of commits fetched from git repos.  The full
+	// history is fetched if 0.
+	Depth int
+
+	// Sparse fetches only the files of the package directory from git repos.
+	Sparse bool
+
+	// Submodules initializes the git submodules of the package directory,
+	// and records it in the Kptfile so later updates initialize them as well.
+	Submodules bool
+
+	// SignaturePolicy, if set, is the policy the updated upstream must be
+	// signed according to.  The signature is verified before updating.
+	SignaturePolicy *signature.Policy
+
+	// checked is set if the packages were checked into git by a
+	// WorkspaceCommand before they were updated
+	checked bool
 }
 
 // Run runs the Command.
@@ -142,28 +182,69 @@ func (u Command) Run() error {
 		u.Output = os.Stdout
 	}
 
-	kptfile, err := kptfileutil.ReadFileStrict(u.Path)
+	kf, err := kptfileutil.ReadFileStrict(u.Path)
 	if err != nil {
 		return errors.Errorf("unable to read package Kptfile: %v", err)
 	}
 
+	if kf.Upstream.Type == kptfile.HelmOrigin {
+		return errors.Errorf("updating packages inflated from helm chart %s is not supported",
+			kf.Upstream.Helm.Chart)
+	}
+
 	// default arguments
-	if u.Repo == "" {
-		u.Repo = kptfile.Upstream.Git.Repo
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		if u.Repo == "" {
+			u.Repo = kf.Upstream.OCI.Image
+		}
+		if u.Ref == "" {
+			u.Ref = kf.Upstream.OCI.Ref
+		}
+	} else {
+		if u.Repo == "" {
+			u.Repo = kf.Upstream.Git.Repo
+		}
+		if u.Ref == "" {
+			u.Ref = kf.Upstream.Git.Ref
+		}
+		if u.Submodules {
+			kf.Upstream.Git.Submodules = true
+		}
 	}
-	if u.Ref == "" {
-		u.Ref = kptfile.Upstream.Git.Ref
+
+	// resolve a version constraint to the latest matching tag -- the Kptfile
+	// keeps the constraint, and the lock file records the concrete version
+	constraint := ""
+	if semver.IsConstraint(u.Ref) {
+		constraint = u.Ref
+		if kf.Upstream.Type == kptfile.OCIOrigin {
+			u.Ref, err = semver.ResolveOCI(u.Repo, constraint)
+		} else {
+			u.Ref, err = semver.ResolveGit(u.Repo, kf.Upstream.Git.Directory, constraint)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(u.Output, "resolved %s to %s\n", constraint, u.Ref)
 	}
 
 	// require package is checked into git before trying to update it
-	g := gitutil.NewLocalGitRunner("./")
-	if err := g.Run("status", "-s", u.Path); err != nil {
-		return errors.Errorf(
-			"kpt packages must be checked into a git repo before they are updated: %v", err)
+	if !u.checked {
+		if err := requireCommitted(u.Path); err != nil {
+			return err
+		}
 	}
-	if strings.TrimSpace(g.Stdout.String()) != "" {
-		return errors.Errorf("must commit package %s to git before attempting to update",
-			u.Path)
+
+	// verify the commit or digest the ref resolves to, and update to it, so
+	// the upstream can't change between verifying and updating
+	toRef := u.Ref
+	if u.SignaturePolicy != nil {
+		if toRef, err = u.resolveUpstream(kf); err != nil {
+			return err
+		}
+		if err := u.verifyUpstream(kf, toRef); err != nil {
+			return err
+		}
 	}
 
 	// update
@@ -171,27 +252,231 @@ func (u Command) Run() error {
 	if !found {
 		return errors.Errorf("unrecognized update strategy %s", u.Strategy)
 	}
+	if err := validatePreserved(kf.PreserveLocal); err != nil {
+		return err
+	}
+
+	// a dry-run updates a copy of the package, so the changes can be
+	// summarized without modifying it -- except for alpha-git-patch, which
+	// prints the patch instead
+	pkgPath, absPkgPath, output := u.Path, u.FullPackagePath, u.Output
+	summarize := u.DryRun && u.Strategy != AlphaGitPatch
+	if summarize {
+		abs, err := filepath.Abs(u.Path)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		dir, err := ioutil.TempDir("", "kpt-dry-run-")
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		defer os.RemoveAll(dir)
+		pkgPath = filepath.Join(dir, filepath.Base(abs))
+		absPkgPath = pkgPath
+		output = ioutil.Discard
+		if err := copyutil.CopyDir(u.Path, pkgPath); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+
+	// the generated files are preserved like the preserveLocal files, since
+	// their generators generate them again rather than merging them
+	generated, err := generatedFiles(pkgPath)
+	if err != nil {
+		return err
+	}
+	local := kf
+	local.PreserveLocal = append(generated, kf.PreserveLocal...)
+	preserved, err := savePreserved(pkgPath, local.PreserveLocal)
+	if err != nil {
+		return err
+	}
 	err = updater().Update(UpdateOptions{
-		KptFile:        kptfile,
-		ToRef:          u.Ref,
+		KptFile:        local,
+		ToRef:          toRef,
 		ToRepo:         u.Repo,
-		PackagePath:    u.Path,
-		AbsPackagePath: u.FullPackagePath,
+		PackagePath:    pkgPath,
+		AbsPackagePath: absPkgPath,
 		DryRun:         u.DryRun,
 		Verbose:        u.Verbose,
 		SimpleMessage:  u.SimpleMessage,
-		Output:         u.Output,
+		Output:         output,
 		AutoSet:        u.AutoSet,
+		Depth:          u.Depth,
+		Sparse:         u.Sparse,
 	})
+	// restore the site-local and generated files the update may have
+	// modified or deleted
+	if restoreErr := preserved.restore(); err == nil {
+		err = restoreErr
+	}
+	if _, conflicts := err.(*ConflictError); err == nil || conflicts {
+		if err := keepLocalFields(pkgPath, kf); err != nil {
+			return err
+		}
+		if toRef != u.Ref {
+			if err := keepRef(pkgPath, u.Ref); err != nil {
+				return err
+			}
+		}
+	}
+	if summarize {
+		return summarizeDryRun(u.Output, u.Path, pkgPath, err)
+	}
 
-	if err != nil {
+	// the package is updated with conflict markers, which the user resolves
+	conflictErr, conflicts := err.(*ConflictError)
+	if err != nil && !conflicts {
 		return err
 	}
 
+	if constraint != "" && !u.DryRun {
+		if err := keepConstraint(u.Path, constraint, u.Ref); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(filepath.Join(u.Path, kptfile.LockFileName)); err == nil && !u.DryRun {
+		// refresh the lock file of the package with the updated upstreams
+		lock, err := kptfileutil.Lock(u.Path)
+		if err != nil {
+			return err
+		}
+		if err := kptfileutil.WriteLockFile(u.Path, lock); err != nil {
+			return err
+		}
+	}
+
+	if conflicts {
+		// the conflicting files are not valid resources until resolved
+		return conflictErr
+	}
+
 	// perform auto-setters after the package is updated
 	a := setters.AutoSet{
 		Writer:      u.Output,
 		PackagePath: u.Path,
 	}
-	return a.PerformAutoSetters()
+	if err := a.PerformAutoSetters(); err != nil {
+		return err
+	}
+
+	// regenerate the variants of the updated base package
+	return variant.Command{Path: u.Path, Output: u.Output}.Run()
+}
+
+// resolveUpstream returns the commit, or the digest for OCI packages, the
+// ref of the upstream points to.
+func (u Command) resolveUpstream(kf kptfile.KptFile) (string, error) {
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		if oci.IsDigest(u.Ref) {
+			return u.Ref, nil
+		}
+		ref, err := oci.NewReference(u.Repo, u.Ref)
+		if err != nil {
+			return "", err
+		}
+		return oci.Digest(ref)
+	}
+	commit, err := gitutil.ResolveRef(u.Repo, u.Ref)
+	if err != nil {
+		return "", err
+	}
+	if commit == "" {
+		// the ref may be a commit already
+		if !commitRegexp.MatchString(u.Ref) {
+			return "", errors.Errorf("unable to resolve %s of %s to a commit", u.Ref, u.Repo)
+		}
+		commit = u.Ref
+	}
+	return commit, nil
+}
+
+// commitRegexp matches full git commit hashes.
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// verifyUpstream verifies the signature of the upstream at the commit or
+// digest ref, by fetching it to a temporary directory.  Fetches are cached,
+// so the updater does not fetch the upstream again.
+func (u Command) verifyUpstream(kf kptfile.KptFile, ref string) error {
+	dir, err := ioutil.TempDir("", "kpt-verify-")
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+	g := get.Command{
+		Destination:     filepath.Join(dir, filepath.Base(u.Path)),
+		Depth:           u.Depth,
+		Sparse:          u.Sparse,
+		SignaturePolicy: u.SignaturePolicy,
+	}
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		g.OCI = kptfile.OCI{Image: u.Repo, Ref: ref}
+	} else {
+		g.Git = kptfile.Git{Repo: u.Repo, Directory: kf.Upstream.Git.Directory, Ref: ref,
+			Submodules: kf.Upstream.Git.Submodules}
+	}
+	return g.Run()
+}
+
+// keepRef restores the ref of the upstream in the Kptfile of the package
+// updated to the commit or digest the ref resolved to.
+func keepRef(path, ref string) error {
+	kf, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		kf.Upstream.OCI.Ref = ref
+	} else {
+		kf.Upstream.Git.Ref = ref
+	}
+	return kptfileutil.WriteFile(path, kf)
+}
+
+// keepLocalFields keeps the fields of the local Kptfile which declare how
+// the package is used locally, as the updated Kptfile may not declare them.
+func keepLocalFields(path string, local kptfile.KptFile) error {
+	kf, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(kf.PreserveLocal, local.PreserveLocal) &&
+		reflect.DeepEqual(kf.Variants, local.Variants) {
+		return nil
+	}
+	kf.PreserveLocal = local.PreserveLocal
+	kf.Variants = local.Variants
+	return kptfileutil.WriteFile(path, kf)
+}
+
+// requireCommitted returns an error if the changes to the files at path are
+// not committed to git.
+func requireCommitted(path string) error {
+	g := gitutil.NewLocalGitRunner("./")
+	if err := g.Run("status", "-s", path); err != nil {
+		return errors.Errorf(
+			"kpt packages must be checked into a git repo before they are updated: %v", err)
+	}
+	if strings.TrimSpace(g.Stdout.String()) != "" {
+		return errors.Errorf("must commit package %s to git before attempting to update",
+			path)
+	}
+	return nil
+}
+
+// keepConstraint restores the version constraint of the updated package in
+// its Kptfile, and records the version it resolved to in the lock file.
+func keepConstraint(path, constraint, version string) error {
+	kf, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		kf.Upstream.OCI.Ref = constraint
+	} else {
+		kf.Upstream.Git.Ref = constraint
+	}
+	if err := kptfileutil.WriteFile(path, kf); err != nil {
+		return err
+	}
+	return kptfileutil.LockResolved(path, version)
 }
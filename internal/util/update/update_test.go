@@ -24,6 +24,7 @@ import (
 	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/testutil"
 	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	"github.com/GoogleContainerTools/kpt/internal/util/signature"
 	. "github.com/GoogleContainerTools/kpt/internal/util/update"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
 	"github.com/stretchr/testify/assert"
@@ -355,6 +356,71 @@ func TestCommand_Run_localPackageChanges(t *testing.T) {
 	}
 }
 
+// TestCommand_Run_preserveLocal verifies the files matching the preserveLocal
+// paths of the Kptfile are never modified or deleted by an update.
+func TestCommand_Run_preserveLocal(t *testing.T) {
+	for i := range updateStrategies {
+		strategy := updateStrategies[i]
+		t.Run(string(strategy), func(t *testing.T) {
+			g := &testutil.TestSetupManager{
+				T: t,
+				// Update upstream to Dataset2
+				UpstreamChanges: []testutil.Content{{Data: testutil.Dataset2}},
+			}
+			defer g.Clean()
+			if !g.Init(testutil.Dataset1) {
+				t.FailNow()
+			}
+
+			// preserve the upstream mysql files and a site-local file
+			pkg := g.LocalWorkspace.FullPackagePath()
+			kf, err := kptfileutil.ReadFile(pkg)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			kf.PreserveLocal = []string{"mysql", "local/**"}
+			if !assert.NoError(t, kptfileutil.WriteFile(pkg, kf)) {
+				t.FailNow()
+			}
+			site := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: site\n"
+			assert.NoError(t, os.MkdirAll(filepath.Join(pkg, "local"), 0700))
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(pkg, "local", "site.yaml"), []byte(site), 0600))
+			localGit := gitutil.NewLocalGitRunner(g.LocalWorkspace.WorkspaceDirectory)
+			assert.NoError(t, localGit.Run("add", "."))
+			assert.NoError(t, localGit.Run("commit", "-m", "add local files"))
+
+			err = Command{
+				Path:            g.UpstreamRepo.RepoName,
+				FullPackagePath: toAbsPath(t, g.UpstreamRepo.RepoName),
+				Strategy:        strategy,
+				SimpleMessage:   true,
+			}.Run()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+
+			// expect Dataset2 with the local mysql files and site-local file
+			expected, err := ioutil.TempDir("", "kpt-test")
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			defer os.RemoveAll(expected)
+			assert.NoError(t, copyutil.CopyDir(
+				filepath.Join(g.UpstreamRepo.DatasetDirectory, testutil.Dataset2), expected))
+			assert.NoError(t, copyutil.CopyDir(
+				filepath.Join(g.UpstreamRepo.DatasetDirectory, testutil.Dataset1, "mysql"),
+				filepath.Join(expected, "mysql")))
+			assert.NoError(t, os.MkdirAll(filepath.Join(expected, "local"), 0700))
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(expected, "local", "site.yaml"), []byte(site), 0600))
+			g.AssertLocalDataEquals(expected)
+
+			kf, err = kptfileutil.ReadFile(pkg)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"mysql", "local/**"}, kf.PreserveLocal)
+		})
+	}
+}
+
 // TestCommand_Run_toBranchRef verifies the package contents are set to the contents of the branch
 // it was updated to.
 func TestCommand_Run_toBranchRef(t *testing.T) {
@@ -404,6 +470,48 @@ func TestCommand_Run_toBranchRef(t *testing.T) {
 	}
 }
 
+// TestCommand_Run_verifySignature verifies the package is updated to the
+// commit whose signature was verified, even if the branch moves on during
+// the verification.
+func TestCommand_Run_verifySignature(t *testing.T) {
+	g := &testutil.TestSetupManager{
+		T:               t,
+		UpstreamChanges: []testutil.Content{{Data: testutil.Dataset2, Branch: "master"}},
+	}
+	defer g.Clean()
+	if !g.Init(testutil.Dataset1) {
+		return
+	}
+	upstream := g.UpstreamRepo
+	assert.NoError(t, ioutil.WriteFile(
+		filepath.Join(upstream.RepoDirectory, signature.File), []byte("{}"), 0600))
+	assert.NoError(t, gitutil.NewLocalGitRunner(upstream.RepoDirectory).Run("add", "."))
+	testutil.Commit(t, upstream, "sign")
+	verified, err := upstream.GetCommit()
+	assert.NoError(t, err)
+
+	// push an unsigned commit to the branch while the signature is verified
+	cosign := signature.Cosign
+	signature.Cosign = func(args ...string) ([]byte, error) {
+		assert.NoError(t, ioutil.WriteFile(
+			filepath.Join(upstream.RepoDirectory, "unverified.yaml"), []byte("{}"), 0600))
+		assert.NoError(t, gitutil.NewLocalGitRunner(upstream.RepoDirectory).Run("add", "."))
+		testutil.Commit(t, upstream, "unverified")
+		return nil, nil
+	}
+	defer func() { signature.Cosign = cosign }()
+
+	assert.NoError(t, Command{
+		Path:            upstream.RepoName,
+		FullPackagePath: toAbsPath(t, upstream.RepoName),
+		Strategy:        FastForward,
+		SignaturePolicy: &signature.Policy{Keys: []string{"/cosign.pub"}},
+	}.Run())
+	assert.FileExists(t, filepath.Join(upstream.RepoName, signature.File))
+	assert.NoFileExists(t, filepath.Join(upstream.RepoName, "unverified.yaml"))
+	g.AssertKptfile(upstream.RepoName, verified, "master")
+}
+
 // TestCommand_Run_toTagRef verifies the package contents are set to the contents of the tag
 // it was updated to.
 func TestCommand_Run_toTagRef(t *testing.T) {
@@ -615,6 +723,84 @@ func TestCommand_Run_emitPatch(t *testing.T) {
 `)
 }
 
+// TestCommand_Run_dryRun verifies a dry-run prints a summary of the changes
+// without updating the package.
+func TestCommand_Run_dryRun(t *testing.T) {
+	for _, strategy := range []StrategyType{FastForward, ForceDeleteReplace, KResourceMerge} {
+		t.Run(string(strategy), func(t *testing.T) {
+			g := &testutil.TestSetupManager{
+				T:               t,
+				UpstreamChanges: []testutil.Content{{Data: testutil.Dataset2}},
+			}
+			defer g.Clean()
+			if !g.Init(testutil.Dataset1) {
+				t.FailNow()
+			}
+
+			b := &bytes.Buffer{}
+			err := Command{
+				Path:            g.UpstreamRepo.RepoName,
+				FullPackagePath: toAbsPath(t, g.UpstreamRepo.RepoName),
+				Strategy:        strategy,
+				DryRun:          true,
+				Output:          b,
+			}.Run()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Contains(t, b.String(), "dry-run: updating "+g.UpstreamRepo.RepoName+" would change ")
+			assert.Contains(t, b.String(), "  modified  Kptfile\n")
+			assert.Contains(t, b.String(), "  modified  mysql/mysql-statefulset.resource.yaml\n")
+
+			// expect the package to be unchanged
+			g.AssertLocalDataEquals(testutil.Dataset1)
+			commit, err := g.UpstreamRepo.GetCommit()
+			assert.NoError(t, err)
+			kf, err := kptfileutil.ReadFile(g.UpstreamRepo.RepoName)
+			assert.NoError(t, err)
+			assert.NotEqual(t, commit, kf.Upstream.Git.Commit)
+		})
+	}
+}
+
+// TestCommand_Run_dryRunConflict verifies a dry-run prints the conflicting
+// fields and fails if the update would conflict.
+func TestCommand_Run_dryRunConflict(t *testing.T) {
+	g := &testutil.TestSetupManager{
+		T:               t,
+		UpstreamChanges: []testutil.Content{{Data: testutil.Dataset2}},
+	}
+	defer g.Clean()
+	if !g.Init(testutil.Dataset1) {
+		t.FailNow()
+	}
+	statefulset := filepath.Join(g.UpstreamRepo.RepoName, "mysql", "mysql-statefulset.resource.yaml")
+	testutil.Replace(t, statefulset, "image: mysql:5.7", "image: mysql:5.6")
+	localGit := gitutil.NewLocalGitRunner(g.LocalWorkspace.WorkspaceDirectory)
+	assert.NoError(t, localGit.Run("commit", "-am", "change image"))
+	local, err := ioutil.ReadFile(statefulset)
+	assert.NoError(t, err)
+
+	b := &bytes.Buffer{}
+	err = Command{
+		Path:            g.UpstreamRepo.RepoName,
+		FullPackagePath: toAbsPath(t, g.UpstreamRepo.RepoName),
+		Strategy:        KResourceMerge,
+		DryRun:          true,
+		Output:          b,
+	}.Run()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "would conflict in mysql/mysql-statefulset.resource.yaml")
+	}
+	assert.Contains(t, b.String(), "  conflict  mysql/mysql-statefulset.resource.yaml\n"+
+		"      apps/v1 StatefulSet mysql: spec.template.spec.containers[name=mysql].image\n")
+
+	// expect the package to be unchanged
+	b2, err := ioutil.ReadFile(statefulset)
+	assert.NoError(t, err)
+	assert.Equal(t, string(local), string(b2))
+}
+
 // TestCommand_Run_failInvalidPath verifies Run fails if the path is invalid
 func TestCommand_Run_failInvalidPath(t *testing.T) {
 	for i := range updateStrategies {
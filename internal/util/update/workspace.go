@@ -0,0 +1,214 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/pathutil"
+)
+
+// PackageStatus is the result of updating a package of a workspace.
+type PackageStatus string
+
+const (
+	// PackageUpdated is the status of packages updated to a new upstream
+	// version
+	PackageUpdated PackageStatus = "updated"
+	// PackageUpToDate is the status of packages already at the upstream
+	// version
+	PackageUpToDate PackageStatus = "up-to-date"
+	// PackageConflict is the status of packages updated with conflicts
+	PackageConflict PackageStatus = "conflict"
+	// PackageFailed is the status of packages which failed to update
+	PackageFailed PackageStatus = "failed"
+	// PackageDryRun is the status of packages whose changes were printed
+	PackageDryRun PackageStatus = "dry-run"
+)
+
+// PackageResult is the result of updating a package of a workspace.
+type PackageResult struct {
+	// Path is the path to the package
+	Path string
+
+	// Status is the result of the update
+	Status PackageStatus
+
+	// Err is the error updating the package, if any
+	Err error
+}
+
+// WorkspaceCommand updates every package under a directory against its own
+// upstream, and prints an aggregated report.
+type WorkspaceCommand struct {
+	// Command contains the options of each update.  Its Path, FullPackagePath,
+	// Ref and Repo are set for each package.
+	Command
+
+	// Dir is the directory containing the packages
+	Dir string
+}
+
+// Run updates the packages, and returns an error if any failed to update.
+func (w WorkspaceCommand) Run() ([]PackageResult, error) {
+	if w.Output == nil {
+		w.Output = os.Stdout
+	}
+	if w.Ref != "" || w.Repo != "" {
+		return nil, errors.Errorf("a version or repo can not be specified when updating a workspace")
+	}
+
+	// require the workspace is checked into git once, as updating a package
+	// leaves the packages nested within it modified
+	if err := requireCommitted(w.Dir); err != nil {
+		return nil, err
+	}
+
+	paths, err := WorkspacePackages(w.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no packages with an upstream found in %s", w.Dir)
+	}
+
+	var results []PackageResult
+	failed := 0
+	for _, path := range paths {
+		fmt.Fprintf(w.Output, "updating package %s\n", path)
+		result := w.update(path)
+		if result.Status == PackageFailed || result.Status == PackageConflict {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	fmt.Fprintln(w.Output)
+	tw := tabwriter.NewWriter(w.Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tSTATUS\t")
+	for _, r := range results {
+		status := string(r.Status)
+		if r.Err != nil {
+			status += ": " + strings.ReplaceAll(r.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t\n", r.Path, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return results, err
+	}
+
+	if failed > 0 {
+		return results, errors.Errorf("failed to update %d of %d packages", failed, len(results))
+	}
+	return results, nil
+}
+
+// update updates the package at path.
+func (w WorkspaceCommand) update(path string) PackageResult {
+	result := PackageResult{Path: path}
+	before, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		result.Status, result.Err = PackageFailed, err
+		return result
+	}
+
+	u := w.Command
+	u.Path = path
+	u.FullPackagePath, err = filepath.Abs(path)
+	if err != nil {
+		result.Status, result.Err = PackageFailed, errors.Wrap(err)
+		return result
+	}
+	u.checked = true
+	err = u.Run()
+	switch err.(type) {
+	case nil:
+	case *ConflictError:
+		result.Status, result.Err = PackageConflict, err
+		return result
+	default:
+		result.Status, result.Err = PackageFailed, err
+		return result
+	}
+	if u.DryRun {
+		result.Status = PackageDryRun
+		return result
+	}
+
+	after, err := kptfileutil.ReadFile(path)
+	if err != nil {
+		result.Status, result.Err = PackageFailed, err
+		return result
+	}
+	result.Status = PackageUpToDate
+	if before.Upstream.Git.Commit != after.Upstream.Git.Commit ||
+		before.Upstream.OCI.Digest != after.Upstream.OCI.Digest {
+		result.Status = PackageUpdated
+	}
+	return result
+}
+
+// WorkspacePackages returns the packages with a git or oci upstream under
+// dir, parents first.  Packages nested within a package fetched from the
+// same repo are skipped, as they are updated with the package.
+func WorkspacePackages(dir string) ([]string, error) {
+	dirs, err := pathutil.DirsWithFile(dir, kptfile.KptFileName, true)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	var paths []string
+	upstreams := map[string]kptfile.Upstream{}
+	for _, d := range dirs {
+		if strings.Contains(filepath.ToSlash(d)+"/", ".git/") {
+			continue
+		}
+		kf, err := kptfileutil.ReadFile(d)
+		if err != nil {
+			return nil, err
+		}
+		if kf.Upstream.Type != kptfile.GitOrigin && kf.Upstream.Type != kptfile.OCIOrigin {
+			continue
+		}
+		if updatedWithParent(d, kf.Upstream, upstreams) {
+			continue
+		}
+		upstreams[d] = kf.Upstream
+		paths = append(paths, d)
+	}
+	return paths, nil
+}
+
+// updatedWithParent returns true if the package at path is nested within a
+// package of the upstreams fetched from the same repo or image.
+func updatedWithParent(path string, upstream kptfile.Upstream, upstreams map[string]kptfile.Upstream) bool {
+	for parent, u := range upstreams {
+		rel, err := filepath.Rel(parent, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if u.Type == upstream.Type && u.Git.Repo == upstream.Git.Repo && u.OCI.Image == upstream.OCI.Image {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/get"
+	. "github.com/GoogleContainerTools/kpt/internal/util/update"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupWorkspace fetches the java and mysql packages of the repo into a
+// workspace, and commits them.
+func setupWorkspace(t *testing.T, g *testutil.TestGitRepo, w *testutil.TestWorkspace) {
+	for _, pkg := range []string{"java", "mysql"} {
+		err := get.Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: pkg},
+			Destination: filepath.Join("apps", pkg)}.Run()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	gr := gitutil.NewLocalGitRunner(w.WorkspaceDirectory)
+	assert.NoError(t, gr.Run("add", "."))
+	assert.NoError(t, gr.Run("commit", "-m", "add packages"))
+}
+
+func TestWorkspaceCommand_Run(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	setupWorkspace(t, g, w)
+
+	// only the mysql package changes upstream
+	testutil.CopyData(t, g, filepath.Join(testutil.Dataset2, "mysql"), "mysql")
+	testutil.Commit(t, g, "update mysql")
+
+	b := &bytes.Buffer{}
+	results, err := WorkspaceCommand{
+		Command: Command{Strategy: KResourceMerge, Output: b},
+		Dir:     ".",
+	}.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []PackageResult{
+		{Path: filepath.Join("apps", "java"), Status: PackageUpdated},
+		{Path: filepath.Join("apps", "mysql"), Status: PackageUpdated},
+	}, results)
+	assert.Contains(t, b.String(), "PACKAGE     STATUS   \napps/java   updated  \napps/mysql  updated  \n")
+
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset1, "java"),
+		filepath.Join(w.WorkspaceDirectory, "apps", "java"))
+	g.AssertEqual(t, filepath.Join(g.DatasetDirectory, testutil.Dataset2, "mysql"),
+		filepath.Join(w.WorkspaceDirectory, "apps", "mysql"))
+}
+
+func TestWorkspaceCommand_Run_failures(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	setupWorkspace(t, g, w)
+
+	// the java package is modified locally, so it can't be fast-forwarded
+	testutil.Replace(t, filepath.Join(w.WorkspaceDirectory, "apps", "java", "java-service.resource.yaml"),
+		"8080", "8081")
+	gr := gitutil.NewLocalGitRunner(w.WorkspaceDirectory)
+	assert.NoError(t, gr.Run("commit", "-am", "change port"))
+
+	b := &bytes.Buffer{}
+	results, err := WorkspaceCommand{
+		Command: Command{Strategy: FastForward, Output: b},
+		Dir:     "apps",
+	}.Run()
+	assert.EqualError(t, err, "failed to update 1 of 2 packages")
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, PackageFailed, results[0].Status)
+		assert.Equal(t, PackageUpToDate, results[1].Status)
+	}
+	assert.Contains(t, b.String(), "apps/java   failed: local package files have been modified")
+}
+
+func TestWorkspacePackages(t *testing.T) {
+	g, w, clean := testutil.SetupDefaultRepoAndWorkspace(t, testutil.Dataset1)
+	defer clean()
+	setupWorkspace(t, g, w)
+
+	// a package nested in a package from the same repo is updated with it
+	err := get.Command{Git: kptfile.Git{Repo: g.RepoDirectory, Ref: "master", Directory: "wordpress"},
+		Destination: filepath.Join("apps", "java", "wordpress")}.Run()
+	assert.NoError(t, err)
+
+	paths, err := WorkspacePackages(".")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("apps", "java"), filepath.Join("apps", "mysql")}, paths)
+}
+
+func TestWorkspaceCommand_Run_version(t *testing.T) {
+	_, err := WorkspaceCommand{Command: Command{Ref: "v1"}, Dir: "."}.Run()
+	assert.EqualError(t, err, "a version or repo can not be specified when updating a workspace")
+}
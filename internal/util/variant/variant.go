@@ -0,0 +1,212 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package variant generates the variants of a base package declared in its
+// Kptfile.
+package variant
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/setters"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/setters2/settersutil"
+)
+
+// BaseAnnotation is the annotation of the Kptfile of a variant recording the
+// path to its base package, relative to the variant.
+const BaseAnnotation = "kpt.dev/variant-base"
+
+// Command generates the variants of a base package.
+type Command struct {
+	// Path is the path to the base package
+	Path string
+
+	// Names are the names of the variants to generate.  Defaults to all of
+	// the variants of the base package.
+	Names []string
+
+	// Output is where the generated variants are written
+	Output io.Writer
+}
+
+// Run generates the variants, replacing any previously generated variant,
+// so variants are regenerated deterministically after the base is updated.
+func (c Command) Run() error {
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	kf, err := kptfileutil.ReadFile(c.Path)
+	if err != nil {
+		return errors.Errorf("unable to read package Kptfile: %v", err)
+	}
+	if err := Validate(c.Path, kf.Variants); err != nil {
+		return err
+	}
+
+	found := map[string]bool{}
+	for _, v := range kf.Variants {
+		found[v.Name] = true
+	}
+	for _, name := range c.Names {
+		if !found[name] {
+			return errors.Errorf("package %s has no variant %q", c.Path, name)
+		}
+	}
+
+	for _, v := range kf.Variants {
+		if len(c.Names) > 0 && !contains(c.Names, v.Name) {
+			continue
+		}
+		dir := Directory(c.Path, v)
+		if err := generate(c.Path, dir, v); err != nil {
+			return errors.WrapPrefixf(err, "failed to generate variant %s", v.Name)
+		}
+		fmt.Fprintf(c.Output, "generated variant %s in %s\n", v.Name, dir)
+	}
+	return nil
+}
+
+// Directory returns the directory the variant of the base package is
+// generated to.
+func Directory(base string, v kptfile.Variant) string {
+	dir := v.Directory
+	if dir == "" {
+		abs, err := filepath.Abs(base)
+		if err != nil {
+			abs = base
+		}
+		dir = filepath.Base(abs) + "-" + v.Name
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(base)), filepath.FromSlash(dir))
+}
+
+// Validate returns an error if the variants of the base package are not well
+// formed.
+func Validate(base string, variants []kptfile.Variant) error {
+	names := map[string]bool{}
+	dirs := map[string]string{}
+	for _, v := range variants {
+		if v.Name == "" {
+			return errors.Errorf("one or more variants missing 'name'")
+		}
+		if names[v.Name] {
+			return errors.Errorf("variant %q is declared more than once", v.Name)
+		}
+		names[v.Name] = true
+
+		dir := Directory(base, v)
+		rel, err := filepath.Rel(base, dir)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if rel == "." || !strings.HasPrefix(rel, "..") {
+			return errors.Errorf("variant %q directory must be outside of the base package", v.Name)
+		}
+		if other, found := dirs[dir]; found {
+			return errors.Errorf("variants %q and %q have the same directory", other, v.Name)
+		}
+		dirs[dir] = v.Name
+
+		for name := range v.Setters {
+			if !setters.DefExists(base, name) {
+				return errors.Errorf("variant %q sets setter %q which is not defined by the package",
+					v.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// generate copies the base package to dir with the setter values of the
+// variant.  The variant is staged in a temporary directory so a failure does
+// not leave a partial variant behind.
+func generate(base, dir string, v kptfile.Variant) error {
+	tmp, err := ioutil.TempDir("", "kpt-variant-")
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer os.RemoveAll(tmp)
+	staged := filepath.Join(tmp, v.Name)
+	if err := copyutil.CopyDir(base, staged); err != nil {
+		return errors.Wrap(err)
+	}
+
+	kf, err := kptfileutil.ReadFile(staged)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, base)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	// variants are generated from the base rather than fetched
+	kf.Name = v.Name
+	kf.Variants = nil
+	kf.Upstream = kptfile.Upstream{}
+	if kf.Annotations == nil {
+		kf.Annotations = map[string]string{}
+	}
+	kf.Annotations[BaseAnnotation] = filepath.ToSlash(rel)
+	if err := kptfileutil.WriteFile(staged, kf); err != nil {
+		return err
+	}
+
+	// set the setters in a stable order
+	var names []string
+	for name := range v.Setters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fs := &settersutil.FieldSetter{
+			Name:            name,
+			Value:           v.Setters[name],
+			SetBy:           "kpt",
+			OpenAPIPath:     filepath.Join(staged, kptfile.KptFileName),
+			OpenAPIFileName: kptfile.KptFileName,
+			ResourcesPath:   staged,
+			IsSet:           true,
+		}
+		if _, err := fs.Set(); err != nil {
+			return errors.WrapPrefixf(err, "failed to set %q", name)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrap(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return errors.Wrap(err)
+	}
+	return errors.Wrap(copyutil.CopyDir(staged, dir))
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package variant_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil"
+	. "github.com/GoogleContainerTools/kpt/internal/util/variant"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/copyutil"
+)
+
+// setupBase copies the helloworld-set package with the variants to a
+// temporary directory.
+func setupBase(t *testing.T, variants ...kptfile.Variant) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-variant-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ds, err := testutil.GetTestDataPath()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	base := filepath.Join(dir, "app")
+	if !assert.NoError(t, copyutil.CopyDir(filepath.Join(ds, testutil.HelloWorldSet), base)) {
+		t.FailNow()
+	}
+	kf, err := kptfileutil.ReadFile(base)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	kf.Variants = variants
+	if !assert.NoError(t, kptfileutil.WriteFile(base, kf)) {
+		t.FailNow()
+	}
+	return base, func() { os.RemoveAll(dir) }
+}
+
+func TestCommand_Run(t *testing.T) {
+	base, clean := setupBase(t,
+		kptfile.Variant{Name: "team-a", Setters: map[string]string{"replicas": "3"}},
+		kptfile.Variant{Name: "team-b", Directory: "teams/b", Setters: map[string]string{"replicas": "7"}},
+	)
+	defer clean()
+
+	b := &bytes.Buffer{}
+	err := Command{Path: base, Output: b}.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	teamA := filepath.Join(filepath.Dir(base), "app-team-a")
+	teamB := filepath.Join(filepath.Dir(base), "teams", "b")
+	assert.Equal(t, "generated variant team-a in "+teamA+"\ngenerated variant team-b in "+teamB+"\n", b.String())
+
+	for dir, replicas := range map[string]string{teamA: "3", teamB: "7"} {
+		deploy, err := ioutil.ReadFile(filepath.Join(dir, "deploy.yaml"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(deploy), "replicas: "+replicas+" #")
+
+		kf, err := kptfileutil.ReadFile(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, kf.Variants)
+		assert.Equal(t, kptfile.Upstream{}, kf.Upstream)
+		assert.NotEmpty(t, kf.Annotations[BaseAnnotation])
+	}
+	kf, err := kptfileutil.ReadFile(teamA)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", kf.Name)
+	assert.Equal(t, "../app", kf.Annotations[BaseAnnotation])
+
+	// the base package is unchanged
+	deploy, err := ioutil.ReadFile(filepath.Join(base, "deploy.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(deploy), "replicas: 5 #")
+
+	// regenerating a variant replaces local changes to it
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(teamA, "local.yaml"), []byte("a: b\n"), 0600))
+	err = Command{Path: base, Names: []string{"team-a"}, Output: b}.Run()
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(teamA, "local.yaml"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCommand_Run_unknownName(t *testing.T) {
+	base, clean := setupBase(t, kptfile.Variant{Name: "team-a"})
+	defer clean()
+
+	err := Command{Path: base, Names: []string{"team-c"}, Output: &bytes.Buffer{}}.Run()
+	assert.EqualError(t, err, `package `+base+` has no variant "team-c"`)
+}
+
+func TestValidate(t *testing.T) {
+	base, clean := setupBase(t)
+	defer clean()
+
+	for _, test := range []struct {
+		variants []kptfile.Variant
+		err      string
+	}{
+		{[]kptfile.Variant{{}}, "one or more variants missing 'name'"},
+		{[]kptfile.Variant{{Name: "a"}, {Name: "a"}}, `variant "a" is declared more than once`},
+		{[]kptfile.Variant{{Name: "a", Directory: "app/a"}},
+			`variant "a" directory must be outside of the base package`},
+		{[]kptfile.Variant{{Name: "a", Directory: "x"}, {Name: "b", Directory: "x"}},
+			`variants "a" and "b" have the same directory`},
+		{[]kptfile.Variant{{Name: "a", Setters: map[string]string{"namespace": "a"}}},
+			`variant "a" sets setter "namespace" which is not defined by the package`},
+		{[]kptfile.Variant{{Name: "a", Setters: map[string]string{"replicas": "1"}}}, ""},
+	} {
+		err := Validate(base, test.variants)
+		if test.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wasm runs functions compiled to WebAssembly with a WASI runtime,
+// without a container daemon.
+package wasm
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/functions"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/fn/runtime/runtimeutil"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// RuntimeEnv is the name of the environment variable that sets the WASI
+// runtime functions are run with.  Defaults to the first of Runtimes found
+// on the PATH.
+const RuntimeEnv = "KPT_WASM_RUNTIME"
+
+// Runtimes are the WASI runtimes functions are run with, in order of
+// preference.
+var Runtimes = []string{"wasmtime", "wasmedge", "wasmer"}
+
+// functionAnnotations are the annotations declaring functions, newest first.
+var functionAnnotations = []string{"config.kubernetes.io/function", "config.k8s.io/function"}
+
+// Filter runs a function compiled to WebAssembly.  The module reads the
+// ResourceList from stdin and writes it to stdout.  It is not granted access
+// to the filesystem, the network or the environment.
+type Filter struct {
+	// Path is the path to the .wasm module of the function
+	Path string
+
+	// Runtime is the WASI runtime executable.  Defaults to the runtime of
+	// RuntimeEnv, or the first of Runtimes found on the PATH.
+	Runtime string
+
+	runtimeutil.FunctionFilter
+}
+
+// Filter runs the function on the nodes.
+func (f *Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	f.FunctionFilter.Run = f.Run
+	return f.FunctionFilter.Filter(nodes)
+}
+
+// Run runs the module with the runtime, with the ResourceList read from
+// reader and written to writer.
+func (f *Filter) Run(reader io.Reader, writer io.Writer) error {
+	runtime := f.Runtime
+	if runtime == "" {
+		var err error
+		if runtime, err = FindRuntime(); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(f.Path); err != nil {
+		return errors.Errorf("unable to read WebAssembly function %s: %v", f.Path, err)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(runtime, RuntimeArgs(runtime, f.Path)...)
+	cmd.Stdin = reader
+	cmd.Stdout = writer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if err := cmd.Run(); err != nil {
+		return errors.Errorf("WebAssembly function %s failed: %v: %s", f.Path, err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// FindRuntime returns the WASI runtime of RuntimeEnv, or the first of
+// Runtimes found on the PATH.
+func FindRuntime() (string, error) {
+	if runtime := os.Getenv(RuntimeEnv); runtime != "" {
+		return runtime, nil
+	}
+	for _, name := range Runtimes {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.Errorf("no WebAssembly runtime found: install one of %s, or set %s",
+		strings.Join(Runtimes, ", "), RuntimeEnv)
+}
+
+// RuntimeArgs returns the arguments of the runtime to run the module.  No
+// directories or environment variables are passed to the module.
+func RuntimeArgs(runtime, module string) []string {
+	switch strings.TrimSuffix(filepath.Base(runtime), ".exe") {
+	case "wasmtime", "wasmer":
+		return []string{"run", module}
+	default:
+		return []string{module}
+	}
+}
+
+// Spec is the WebAssembly runtime of a function declared by the
+// config.kubernetes.io/function annotation of its function config.
+type Spec struct {
+	// Path is the slash separated path of the .wasm module, relative to
+	// the package the function is declared in
+	Path string `yaml:"path,omitempty"`
+}
+
+// GetSpec returns the WebAssembly runtime of the function declared by the
+// function config, or nil if it doesn't declare a WebAssembly function.
+func GetSpec(n *yaml.RNode) (*Spec, error) {
+	meta, err := n.GetMeta()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, key := range functionAnnotations {
+		value, found := meta.Annotations[key]
+		if !found {
+			continue
+		}
+		var fn struct {
+			Wasm *Spec `yaml:"wasm,omitempty"`
+		}
+		if err := yaml.Unmarshal([]byte(value), &fn); err != nil {
+			return nil, errors.Errorf("invalid %s annotation of %s: %v", key, meta.Name, err)
+		}
+		if fn.Wasm == nil || fn.Wasm.Path == "" {
+			return nil, nil
+		}
+		return fn.Wasm, nil
+	}
+	return nil, nil
+}
+
+// Run runs the WebAssembly functions declared in the package at dir on its
+// resources, in the order they are read.  Like container functions, a
+// function is only run on the resources in the directory of its function
+// config and the directories within it.
+func Run(dir string) error {
+	rw := &kio.LocalPackageReadWriter{PackagePath: dir}
+	nodes, err := rw.Read()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	var filters []kio.Filter
+	for _, n := range nodes {
+		spec, err := GetSpec(n)
+		if err != nil {
+			return err
+		}
+		if spec == nil {
+			continue
+		}
+		path, err := ModulePath(dir, spec.Path)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, &Filter{
+			Path:           path,
+			FunctionFilter: runtimeutil.FunctionFilter{FunctionConfig: n},
+		})
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return kio.Pipeline{
+		Inputs:  []kio.Reader{&kio.PackageBuffer{Nodes: nodes}},
+		Filters: filters,
+		Outputs: []kio.Writer{rw},
+	}.Execute()
+}
+
+// ModulePath returns the path of the module at the slash separated path
+// relative to the package at dir.  Modules must be within the package.
+func ModulePath(dir, path string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("WebAssembly function %s must be within the package", path)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// Command runs a WebAssembly function on a package, or on the resources read
+// from Input.
+type Command struct {
+	// Path is the path to the .wasm module of the function
+	Path string
+
+	// Dir is the directory of the package.  If empty, the resources are
+	// read from Input and written to Output.
+	Dir string
+
+	// Data is the data of the ConfigMap passed to the function as its
+	// function config
+	Data map[string]string
+
+	// DryRun writes the resources of the package to Output instead of
+	// writing them to the package
+	DryRun bool
+
+	Input  io.Reader
+	Output io.Writer
+}
+
+// Run runs the function.
+func (c Command) Run() error {
+	config, err := functions.FunctionConfig(c.Data)
+	if err != nil {
+		return err
+	}
+	f := &Filter{
+		Path:           c.Path,
+		FunctionFilter: runtimeutil.FunctionFilter{FunctionConfig: config, GlobalScope: true},
+	}
+	return functions.RunImperative(f, c.Dir, c.DryRun, c.Input, c.Output)
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/internal/testutil/pkgbuilder"
+	. "github.com/GoogleContainerTools/kpt/internal/util/wasm"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+`
+
+// setupRuntime sets a fake runtime, which scales the replicas of the
+// resources to 3 and records the module it was run with.
+func setupRuntime(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "kpt-wasm-")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	runtime := filepath.Join(dir, "runtime")
+	script := "#!/bin/sh\necho \"$1\" > " + filepath.Join(dir, "module") +
+		"\nsed 's/replicas: 1/replicas: 3/'\n"
+	if !assert.NoError(t, ioutil.WriteFile(runtime, []byte(script), 0700)) {
+		t.FailNow()
+	}
+	old, found := os.LookupEnv(RuntimeEnv)
+	os.Setenv(RuntimeEnv, runtime)
+	return dir, func() {
+		if found {
+			os.Setenv(RuntimeEnv, old)
+		} else {
+			os.Unsetenv(RuntimeEnv)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRuntimeArgs(t *testing.T) {
+	assert.Equal(t, []string{"run", "fn.wasm"}, RuntimeArgs("/usr/bin/wasmtime", "fn.wasm"))
+	assert.Equal(t, []string{"run", "fn.wasm"}, RuntimeArgs("wasmer", "fn.wasm"))
+	assert.Equal(t, []string{"fn.wasm"}, RuntimeArgs("wasmedge", "fn.wasm"))
+}
+
+func TestFindRuntime(t *testing.T) {
+	_, clean := setupRuntime(t)
+	defer clean()
+	runtime, err := FindRuntime()
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getenv(RuntimeEnv), runtime)
+}
+
+func TestGetSpec(t *testing.T) {
+	tests := map[string]struct {
+		annotations string
+		expected    *Spec
+		err         string
+	}{
+		"wasm": {
+			annotations: "config.kubernetes.io/function: |\n      wasm:\n        path: fn.wasm\n",
+			expected:    &Spec{Path: "fn.wasm"},
+		},
+		"legacy annotation": {
+			annotations: "config.k8s.io/function: |\n      wasm:\n        path: fn.wasm\n",
+			expected:    &Spec{Path: "fn.wasm"},
+		},
+		"container": {
+			annotations: "config.kubernetes.io/function: |\n      container:\n        image: fn\n",
+		},
+		"no function": {
+			annotations: "a: b\n",
+		},
+		"invalid": {
+			annotations: "config.kubernetes.io/function: 'wasm: ['\n",
+			err:         "invalid config.kubernetes.io/function annotation of fn",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			n := yaml.MustParse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: fn\n  annotations:\n    " +
+				test.annotations)
+			spec, err := GetSpec(n)
+			if test.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, spec)
+		})
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	path, err := ModulePath("pkg", "functions/fn.wasm")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("pkg", "functions", "fn.wasm"), path)
+
+	for _, p := range []string{"../fn.wasm", "functions/../../fn.wasm", "/fn.wasm"} {
+		_, err := ModulePath("pkg", p)
+		assert.EqualError(t, err, "WebAssembly function "+p+" must be within the package")
+	}
+}
+
+func TestRun(t *testing.T) {
+	runtimeDir, clean := setupRuntime(t)
+	defer clean()
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"deployment.yaml": deployment,
+		"fn.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      wasm:
+        path: functions/fn.wasm
+`,
+		"functions/fn.wasm": "",
+	}))
+
+	if !assert.NoError(t, Run(dir)) {
+		t.FailNow()
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "deployment.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), string(b))
+	b, err = ioutil.ReadFile(filepath.Join(runtimeDir, "module"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "functions", "fn.wasm")+"\n", string(b))
+}
+
+func TestRun_missingModule(t *testing.T) {
+	_, clean := setupRuntime(t)
+	defer clean()
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{
+		"deployment.yaml": deployment,
+		"fn.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fn
+  annotations:
+    config.kubernetes.io/function: |
+      wasm:
+        path: fn.wasm
+`,
+	}))
+
+	err := Run(dir)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unable to read WebAssembly function")
+	}
+}
+
+func TestCommand_Run(t *testing.T) {
+	_, clean := setupRuntime(t)
+	defer clean()
+	dir := pkgbuilder.ExpandPkg(t, pkgbuilder.NewPackage("").WithFiles(map[string]string{"deployment.yaml": deployment, "fn.wasm": ""}))
+
+	// dry-run writes the resources to the output
+	out := &bytes.Buffer{}
+	err := Command{Path: filepath.Join(dir, "fn.wasm"), Dir: dir, DryRun: true, Output: out}.Run()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "replicas: 3")
+	b, err := ioutil.ReadFile(filepath.Join(dir, "deployment.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, deployment, string(b))
+
+	err = Command{Path: filepath.Join(dir, "fn.wasm"), Dir: dir, Data: map[string]string{"a": "b"}}.Run()
+	assert.NoError(t, err)
+	b, err = ioutil.ReadFile(filepath.Join(dir, "deployment.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), string(b))
+
+	// without a package, the resources are read from the input
+	out.Reset()
+	err = Command{Path: filepath.Join(dir, "fn.wasm"), Input: strings.NewReader(deployment), Output: out}.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(deployment, "replicas: 1", "replicas: 3", 1), out.String())
+}
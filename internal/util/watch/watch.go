@@ -0,0 +1,124 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watch runs commands on a package again whenever its files change.
+package watch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"sigs.k8s.io/kustomize/kyaml/errors"
+)
+
+// DefaultInterval is the interval the files of a package are checked for
+// changes at.
+const DefaultInterval = 500 * time.Millisecond
+
+// Command runs OnChange on a package, and again whenever its files change.
+// The changes OnChange makes to the package don't trigger another run.
+type Command struct {
+	// Dir is the directory of the package
+	Dir string
+
+	// Interval is the interval the files are checked for changes at.
+	// Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// OnChange is run when the files change.  Its errors are written to Out
+	// rather than stopping the watch.
+	OnChange func() error
+
+	// Stop stops watching when closed.  The package is watched until the
+	// process exits if nil.
+	Stop <-chan struct{}
+
+	Out io.Writer
+}
+
+// Run runs OnChange, then watches the package until stopped.
+func (c Command) Run() error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	last, err := c.run()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.Out, "watching %s for changes\n", c.Dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Stop:
+			return nil
+		case <-ticker.C:
+		}
+		files, err := Snapshot(c.Dir)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(files, last) {
+			continue
+		}
+		fmt.Fprintf(c.Out, "%s changed, running again\n", c.Dir)
+		if last, err = c.run(); err != nil {
+			return err
+		}
+	}
+}
+
+// run runs OnChange, and returns the snapshot of the package after it.
+func (c Command) run() (map[string]File, error) {
+	if err := c.OnChange(); err != nil {
+		fmt.Fprintf(c.Out, "error: %v\n", err)
+	}
+	return Snapshot(c.Dir)
+}
+
+// File is the state of a file, which changes when the file is written.
+type File struct {
+	ModTime time.Time
+	Size    int64
+	Mode    os.FileMode
+}
+
+// Snapshot returns the state of the files of the package at dir by path,
+// except the files of git repos.
+func Snapshot(dir string) (map[string]File, error) {
+	files := map[string]File{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files deleted while walking the package are skipped
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		files[path] = File{ModTime: info.ModTime(), Size: info.Size(), Mode: info.Mode()}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return files, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/internal/util/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-watch")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	assert.NoError(t, os.MkdirAll(filepath.Join(d, ".git"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, ".git", "HEAD"), []byte("ref"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "deploy.yaml"), []byte("kind: Deployment\n"), 0600))
+
+	files, err := watch.Snapshot(d)
+	assert.NoError(t, err)
+	assert.Contains(t, files, filepath.Join(d, "deploy.yaml"))
+	assert.NotContains(t, files, filepath.Join(d, ".git", "HEAD"))
+}
+
+func TestCommand_Run(t *testing.T) {
+	d, err := ioutil.TempDir("", "kpt-watch")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "deploy.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("replicas: 1\n"), 0600))
+
+	var mu sync.Mutex
+	runs := 0
+	ran := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- watch.Command{
+			Dir:      d,
+			Interval: 10 * time.Millisecond,
+			OnChange: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				runs++
+				// the changes of the runs don't trigger another run
+				err := ioutil.WriteFile(filepath.Join(d, "out.yaml"), []byte(fmt.Sprint(runs)), 0600)
+				ran <- struct{}{}
+				if runs == 2 {
+					return fmt.Errorf("run failed")
+				}
+				return err
+			},
+			Stop: stop,
+			Out:  &out,
+		}.Run()
+	}()
+
+	<-ran
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("replicas: 22\n"), 0600))
+	<-ran
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	assert.NoError(t, <-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, fmt.Sprintf("watching %s for changes\n%s changed, running again\nerror: run failed\n", d, d),
+		out.String())
+}
@@ -16,6 +16,7 @@
 //go:generate $GOBIN/mdtogo site/content/en/reference/pkg internal/docs/generated/pkgdocs --license=none --recursive=true --strategy=cmdDocs
 //go:generate $GOBIN/mdtogo site/content/en/reference/cfg internal/docs/generated/cfgdocs --license=none --recursive=true --strategy=cmdDocs
 //go:generate $GOBIN/mdtogo site/content/en/reference/fn internal/docs/generated/fndocs --license=none --recursive=true --strategy=cmdDocs
+//go:generate $GOBIN/mdtogo site/content/en/reference/cache internal/docs/generated/cachedocs --license=none --recursive=true --strategy=cmdDocs
 //go:generate $GOBIN/mdtogo site/content/en/reference internal/docs/generated/overview --license=none --strategy=cmdDocs
 //go:generate $GOBIN/mdtogo site/content/en/guides/consumer internal/guides/generated/consumer --license=none --recursive=true --strategy=guide
 //go:generate $GOBIN/mdtogo site/content/en/guides/ecosystem internal/guides/generated/ecosystem --license=none --recursive=true --strategy=guide
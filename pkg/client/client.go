@@ -6,86 +6,504 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util"
 	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
+// DefaultFieldManager is the field manager used for server-side apply
+// when none is specified.
+const DefaultFieldManager = "kpt"
+
+// DefaultQPS and DefaultBurst are the rate limits suggested for callers
+// issuing many requests, such as inventory migrations. Clients returned
+// by NewClient are not throttled unless WithRateLimit is called.
+const (
+	DefaultQPS   float32 = 20
+	DefaultBurst int     = 40
+)
+
+// DefaultPropagationPolicy is the propagation policy used by Delete when
+// neither the options nor the client specify one. Dependents are deleted
+// by the garbage collector in the background, the same as kubectl.
+const DefaultPropagationPolicy = metav1.DeletePropagationBackground
+
+// MutateFunc mutates the passed object in place. It returns true if
+// the object was changed and needs to be written back.
+type MutateFunc func(obj *unstructured.Unstructured) (bool, error)
+
+// Client reads and writes objects in the API server.
+type Client interface {
+	// Get fetches the object identified by meta.
+	Get(ctx context.Context, meta object.ObjMetadata) (*unstructured.Unstructured, error)
+	// Update replaces the object identified by meta with obj.
+	Update(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.UpdateOptions) error
+	// UpdateWithRetry fetches, mutates and updates the object, retrying on conflict.
+	UpdateWithRetry(ctx context.Context, meta object.ObjMetadata, mutate MutateFunc, options *metav1.UpdateOptions) error
+	// Patch patches the object identified by meta with data.
+	Patch(ctx context.Context, meta object.ObjMetadata, patchType types.PatchType, data []byte, options *metav1.PatchOptions) error
+	// Apply applies obj using server-side apply.
+	Apply(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.PatchOptions) error
+	// DryRunApply returns obj as a server-side apply would store it,
+	// without storing it.
+	DryRunApply(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.PatchOptions) (*unstructured.Unstructured, error)
+	// Delete deletes the object identified by meta.
+	Delete(ctx context.Context, meta object.ObjMetadata, options *metav1.DeleteOptions) error
+	// ListByInventory returns the objects of the passed kinds owned by the inventory.
+	ListByInventory(ctx context.Context, inventoryID string, gks []schema.GroupKind) ([]*unstructured.Unstructured, error)
+}
+
+var _ Client = &client{}
+
 // client is the client to update object in the API server.
 type client struct {
-	client     dynamic.Interface
-	restMapper meta.RESTMapper
+	client       dynamic.Interface
+	restMapper   *mappingCache
+	fieldManager string
+	force        bool
+	retryBackoff wait.Backoff
+	workers      int
+	keys         OwningInventoryKeys
+	dryRun       bool
+	propagation  metav1.DeletionPropagation
+	gracePeriod  *int64
+	watch        bool
+	progress     ProgressFunc
+	limiter      flowcontrol.RateLimiter
 }
 
 func NewClient(d dynamic.Interface, mapper meta.RESTMapper) *client {
 	return &client{
-		client:     d,
-		restMapper: mapper,
+		client:       d,
+		restMapper:   newMappingCache(mapper),
+		fieldManager: DefaultFieldManager,
+		retryBackoff: retry.DefaultBackoff,
+		workers:      DefaultMigrationWorkers,
+		keys:         DefaultOwningInventoryKeys,
+		propagation:  DefaultPropagationPolicy,
 	}
 }
 
+// WithRetry sets the backoff used by UpdateWithRetry when the
+// API server returns a conflict error.
+func (uc *client) WithRetry(backoff wait.Backoff) *client {
+	uc.retryBackoff = backoff
+	return uc
+}
+
+// WithFieldManager sets the field manager used for server-side apply.
+func (uc *client) WithFieldManager(fieldManager string) *client {
+	uc.fieldManager = fieldManager
+	return uc
+}
+
+// WithForceConflicts makes server-side apply take the ownership of the
+// fields managed by other field managers, instead of failing with a
+// conflict, unless the options of the apply set Force.
+func (uc *client) WithForceConflicts(force bool) *client {
+	uc.force = force
+	return uc
+}
+
 // Update updates an object using dynamic client
 func (uc *client) Update(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.UpdateOptions) error {
-	r, err := uc.resourceInterface(meta)
+	_, err := uc.update(ctx, meta, obj, options)
+	return err
+}
+
+// update updates an object and returns the object stored in the API server.
+func (uc *client) update(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	r, err := uc.resourceInterface(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	opts := metav1.UpdateOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if uc.dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return r.Update(ctx, obj, opts)
+}
+
+// WithDryRun enables server-side dry-run for all write operations, so the
+// API server validates the requests without persisting any changes.
+func (uc *client) WithDryRun(dryRun bool) *client {
+	uc.dryRun = dryRun
+	return uc
+}
+
+// WithPropagationPolicy sets the propagation policy used by Delete
+// when the options don't specify one.
+func (uc *client) WithPropagationPolicy(policy metav1.DeletionPropagation) *client {
+	uc.propagation = policy
+	return uc
+}
+
+// WithGracePeriod sets the grace period in seconds used by Delete when
+// the options don't specify one. A negative value resets it to the
+// default grace period of the object.
+func (uc *client) WithGracePeriod(seconds int64) *client {
+	if seconds < 0 {
+		uc.gracePeriod = nil
+		return uc
+	}
+	uc.gracePeriod = &seconds
+	return uc
+}
+
+// WithWorkers sets the number of objects processed concurrently
+// by MigrateAnnotations.
+func (uc *client) WithWorkers(workers int) *client {
+	uc.workers = workers
+	return uc
+}
+
+// WithOwningInventoryKeys sets the annotation and label keys used by
+// MigrateAnnotations to record the owning inventory.
+func (uc *client) WithOwningInventoryKeys(keys OwningInventoryKeys) *client {
+	uc.keys = keys
+	return uc
+}
+
+// WithWatch makes MigrateAnnotations list and watch the objects to migrate
+// instead of fetching them one at a time. The objects are listed once per
+// kind and namespace, and the watch keeps them up to date while the
+// migration is running.
+func (uc *client) WithWatch(watch bool) *client {
+	uc.watch = watch
+	return uc
+}
+
+// WithProgress sets the function called by MigrateAnnotations after
+// each object is migrated.
+func (uc *client) WithProgress(progress ProgressFunc) *client {
+	uc.progress = progress
+	return uc
+}
+
+// WithRateLimit throttles the client to qps requests per second, allowing
+// bursts of up to burst requests, so large migrations don't trip the API
+// priority and fairness limits of busy clusters. The limit applies to
+// every operation of the client, including each retry. A qps of zero or
+// less disables client-side throttling.
+func (uc *client) WithRateLimit(qps float32, burst int) *client {
+	if qps <= 0 {
+		uc.limiter = nil
+		return uc
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	uc.limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	return uc
+}
+
+// throttle blocks until the rate limiter allows another request, or
+// returns an error if the context is done first.
+func (uc *client) throttle(ctx context.Context) error {
+	if uc.limiter == nil {
+		return nil
+	}
+	return uc.limiter.Wait(ctx)
+}
+
+// UpdateWithRetry fetches the object, mutates it and updates it in the
+// API server. If the update fails with a conflict, the latest version
+// of the object is fetched again and the mutation is re-applied until
+// the retry backoff is exhausted. The update is skipped if the mutation
+// reports that the object did not change.
+func (uc *client) UpdateWithRetry(ctx context.Context, meta object.ObjMetadata, mutate MutateFunc, options *metav1.UpdateOptions) error {
+	return retry.RetryOnConflict(uc.retryBackoff, func() error {
+		obj, err := uc.Get(ctx, meta)
+		if err != nil {
+			return err
+		}
+		changed, err := mutate(obj)
+		if err != nil || !changed {
+			return err
+		}
+		return uc.Update(ctx, meta, obj, options)
+	})
+}
+
+// Apply applies an object using server-side apply. Only the fields set in
+// obj are owned by the field manager, so fields managed by other
+// controllers are left untouched. The field manager in the options takes
+// precedence over the one configured on the client.
+func (uc *client) Apply(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.PatchOptions) error {
+	r, err := uc.resourceInterface(ctx, meta)
+	if err != nil {
+		return err
+	}
+	opts := uc.applyOptions(options)
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = r.Patch(ctx, meta.Name, types.ApplyPatchType, data, opts)
+	return err
+}
+
+// DryRunApply server-side applies an object with a dry-run, and returns
+// the object the API server would store: the live object with the fields
+// of obj merged, and the fields defaulted by the server set.
+func (uc *client) DryRunApply(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.PatchOptions) (*unstructured.Unstructured, error) {
+	r, err := uc.resourceInterface(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	opts := uc.applyOptions(options)
+	opts.DryRun = []string{metav1.DryRunAll}
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return r.Patch(ctx, meta.Name, types.ApplyPatchType, data, opts)
+}
+
+// applyOptions returns the passed options of a server-side apply, with
+// the field manager, force and dry-run settings of the client for the
+// options which are not set.
+func (uc *client) applyOptions(options *metav1.PatchOptions) metav1.PatchOptions {
+	opts := metav1.PatchOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.FieldManager == "" {
+		opts.FieldManager = uc.fieldManager
+	}
+	if opts.Force == nil && uc.force {
+		force := true
+		opts.Force = &force
+	}
+	if uc.dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
+// Patch patches an object using dynamic client. The patchType determines
+// how data is interpreted, e.g. as a JSON patch or a strategic merge patch.
+func (uc *client) Patch(ctx context.Context, meta object.ObjMetadata, patchType types.PatchType, data []byte, options *metav1.PatchOptions) error {
+	r, err := uc.resourceInterface(ctx, meta)
 	if err != nil {
 		return err
 	}
-	if options == nil {
-		options = &metav1.UpdateOptions{}
+	opts := metav1.PatchOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if uc.dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
 	}
-	_, err = r.Update(ctx, obj, *options)
+	_, err = r.Patch(ctx, meta.Name, patchType, data, opts)
 	return err
 }
 
+// Delete deletes an object using dynamic client. The propagation policy
+// and grace period configured on the client are used unless the options
+// specify them.
+func (uc *client) Delete(ctx context.Context, meta object.ObjMetadata, options *metav1.DeleteOptions) error {
+	r, err := uc.resourceInterface(ctx, meta)
+	if err != nil {
+		return err
+	}
+	return r.Delete(ctx, meta.Name, uc.deleteOptions(options))
+}
+
+// deleteOptions returns a copy of the passed options with the client's
+// propagation policy, grace period and dry-run settings filled in.
+func (uc *client) deleteOptions(options *metav1.DeleteOptions) metav1.DeleteOptions {
+	opts := metav1.DeleteOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.PropagationPolicy == nil && uc.propagation != "" {
+		propagation := uc.propagation
+		opts.PropagationPolicy = &propagation
+	}
+	if opts.GracePeriodSeconds == nil && uc.gracePeriod != nil {
+		gracePeriod := *uc.gracePeriod
+		opts.GracePeriodSeconds = &gracePeriod
+	}
+	if uc.dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
 // Get fetches the requested object into the input obj using dynamic client
 func (uc *client) Get(ctx context.Context, meta object.ObjMetadata) (*unstructured.Unstructured, error) {
-	r, err := uc.resourceInterface(meta)
+	r, err := uc.resourceInterface(ctx, meta)
 	if err != nil {
 		return nil, err
 	}
 	return r.Get(ctx, meta.Name, metav1.GetOptions{})
 }
 
-func (uc *client) resourceInterface(meta object.ObjMetadata) (dynamic.ResourceInterface, error) {
-	mapping, err := uc.restMapper.RESTMapping(meta.GroupKind)
+// ListByInventory returns all the objects of the passed kinds, in all
+// namespaces, which are owned by the inventory with the passed ID. If an
+// owning inventory label key is configured, it is used as a label selector
+// so the filtering happens in the API server.
+func (uc *client) ListByInventory(ctx context.Context, inventoryID string, gks []schema.GroupKind) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, gk := range gks {
+		mapping, err := uc.restMapper.RESTMapping(gk)
+		if err != nil {
+			return nil, err
+		}
+		if err := uc.throttle(ctx); err != nil {
+			return nil, err
+		}
+		opts := metav1.ListOptions{}
+		if uc.keys.Label != "" {
+			opts.LabelSelector = fmt.Sprintf("%s=%s", uc.keys.Label, inventoryID)
+		}
+		list, err := uc.client.Resource(mapping.Resource).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if id, found := OwningInventory(obj, uc.keys); found && id == inventoryID {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs, nil
+}
+
+// resourceInterface returns the dynamic client for the passed object. The
+// client is only namespaced if the object kind is namespace scoped, so
+// cluster-scoped objects such as ClusterRoles can be accessed as well.
+// It blocks until the client's rate limiter allows another request.
+func (uc *client) resourceInterface(ctx context.Context, objMeta object.ObjMetadata) (dynamic.ResourceInterface, error) {
+	if err := uc.throttle(ctx); err != nil {
+		return nil, err
+	}
+	mapping, err := uc.restMapper.RESTMapping(objMeta.GroupKind)
 	if err != nil {
 		return nil, err
 	}
-	namespacedClient := uc.client.Resource(mapping.Resource).Namespace(meta.Namespace)
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return uc.client.Resource(mapping.Resource), nil
+	}
+	namespacedClient := uc.client.Resource(mapping.Resource).Namespace(objMeta.Namespace)
 	return namespacedClient, nil
 }
 
+// OwningInventoryAnnotation is the default annotation key recording the
+// ID of the inventory owning an object.
+const OwningInventoryAnnotation = "config.k8s.io/owning-inventory"
+
+// OwningInventoryKeys identifies where the owning inventory ID is
+// recorded on an object.
+type OwningInventoryKeys struct {
+	// Annotation is the annotation key storing the owning inventory ID.
+	// The annotation is neither read nor written if it is empty.
+	Annotation string
+	// Label is an optional label key storing the owning inventory ID.
+	// If both keys are set, the annotation determines the current owner.
+	Label string
+}
+
+// DefaultOwningInventoryKeys records the owning inventory only in the
+// "config.k8s.io/owning-inventory" annotation.
+var DefaultOwningInventoryKeys = OwningInventoryKeys{
+	Annotation: OwningInventoryAnnotation,
+}
+
 // UpdateAnnotation updates the object owning inventory annotation
 // to the new ID when the owning inventory annotation is either empty or the old ID.
 // It returns if the annotation is updated.
 func UpdateAnnotation(obj *unstructured.Unstructured, oldID, newID string) (bool, error) {
-	key := "config.k8s.io/owning-inventory"
+	return UpdateOwningInventory(obj, oldID, newID, DefaultOwningInventoryKeys)
+}
+
+// UpdateOwningInventory updates the owning inventory annotation and/or
+// label identified by keys to the new ID when the current owning inventory
+// is either empty or the old ID. The owning inventory is removed if the new
+// ID is empty. It returns if the object is updated.
+func UpdateOwningInventory(obj *unstructured.Unstructured, oldID, newID string, keys OwningInventoryKeys) (bool, error) {
+	if keys.Annotation == "" && keys.Label == "" {
+		return false, fmt.Errorf("owning inventory annotation and label keys are both empty")
+	}
 	annotations := obj.GetAnnotations()
 	if annotations == nil {
 		annotations = make(map[string]string)
 	}
-	val, found := annotations[key]
-	if !found || val == oldID {
-		annotations[key] = newID
-		// Since the annotation is updated, we also need to update the
-		// last applied configuration annotation.
-		u := getOriginalObj(obj)
-		if u != nil {
-			u.SetAnnotations(annotations)
-			err := util.CreateOrUpdateAnnotation(false, u, scheme.DefaultJSONEncoder())
-			obj.SetAnnotations(u.GetAnnotations())
-			return true, err
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	val, found := OwningInventory(obj, keys)
+	if found && val != oldID || !found && newID == "" {
+		return false, nil
+	}
+	if keys.Annotation != "" {
+		setOwningInventory(annotations, keys.Annotation, newID)
+	}
+	if keys.Label != "" {
+		setOwningInventory(labels, keys.Label, newID)
+		obj.SetLabels(labels)
+	}
+	// Since the owning inventory is updated, we also need to update the
+	// last applied configuration annotation.
+	u := getOriginalObj(obj)
+	if u != nil {
+		u.SetAnnotations(annotations)
+		if keys.Label != "" {
+			originalLabels := u.GetLabels()
+			if originalLabels == nil {
+				originalLabels = make(map[string]string)
+			}
+			setOwningInventory(originalLabels, keys.Label, newID)
+			u.SetLabels(originalLabels)
 		}
-		obj.SetAnnotations(annotations)
-		return true, nil
+		err := util.CreateOrUpdateAnnotation(false, u, scheme.DefaultJSONEncoder())
+		obj.SetAnnotations(u.GetAnnotations())
+		return true, err
+	}
+	obj.SetAnnotations(annotations)
+	return true, nil
+}
+
+// OwningInventory returns the owning inventory ID recorded on the object
+// under the passed keys, and whether it was found. The annotation takes
+// precedence over the label when both keys are set.
+func OwningInventory(obj *unstructured.Unstructured, keys OwningInventoryKeys) (string, bool) {
+	if keys.Annotation != "" {
+		val, found := obj.GetAnnotations()[keys.Annotation]
+		return val, found
+	}
+	if keys.Label != "" {
+		val, found := obj.GetLabels()[keys.Label]
+		return val, found
+	}
+	return "", false
+}
+
+// setOwningInventory sets key to id, or deletes key if id is empty.
+func setOwningInventory(m map[string]string, key, id string) {
+	if id == "" {
+		delete(m, key)
+		return
 	}
-	return false, nil
+	m[key] = id
 }
 
 func getOriginalObj(obj *unstructured.Unstructured) *unstructured.Unstructured {
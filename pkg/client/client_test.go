@@ -4,9 +4,22 @@
 package client
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
 func TestUpdateAnnotation(t *testing.T) {
@@ -71,3 +84,439 @@ func TestUpdateAnnotation(t *testing.T) {
 		}
 	}
 }
+
+func TestApply(t *testing.T) {
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "deployment",
+				"namespace": "test",
+			},
+		},
+	}
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	var patchType types.PatchType
+	var patch []byte
+	d.PrependReactor("patch", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(clienttesting.PatchAction)
+		patchType = patchAction.GetPatchType()
+		patch = patchAction.GetPatch()
+		return true, deployment, nil
+	})
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	c := NewClient(d, mapper)
+	objMeta, err := object.CreateObjMetadata("test", "deployment", schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := c.Apply(context.TODO(), objMeta, deployment, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if patchType != types.ApplyPatchType {
+		t.Errorf("expected patch type %s, got %s", types.ApplyPatchType, patchType)
+	}
+	expected, err := deployment.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(patch) != string(expected) {
+		t.Errorf("expected patch %s, got %s", expected, patch)
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	c := NewClient(nil, nil)
+	opts := c.applyOptions(nil)
+	if opts.FieldManager != DefaultFieldManager || opts.Force != nil {
+		t.Errorf("unexpected default apply options %+v", opts)
+	}
+
+	c = c.WithFieldManager("team-a").WithForceConflicts(true)
+	opts = c.applyOptions(nil)
+	if opts.FieldManager != "team-a" || opts.Force == nil || !*opts.Force {
+		t.Errorf("expected field manager team-a with force, got %+v", opts)
+	}
+
+	force := false
+	opts = c.applyOptions(&metav1.PatchOptions{FieldManager: "team-b", Force: &force})
+	if opts.FieldManager != "team-b" || *opts.Force {
+		t.Errorf("expected the options to take precedence, got %+v", opts)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "deployment",
+				"namespace": "test",
+			},
+		},
+	}
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment.DeepCopy())
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	c := NewClient(d, mapper)
+	objMeta, err := object.CreateObjMetadata("test", "deployment", schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data := []byte(`{"metadata":{"annotations":{"config.k8s.io/owning-inventory":"new"}}}`)
+	if err := c.Patch(context.TODO(), objMeta, types.MergePatchType, data, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	obj, err := c.Get(context.TODO(), objMeta)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if obj.GetAnnotations()["config.k8s.io/owning-inventory"] != "new" {
+		t.Errorf("the owning-inventory annotation is not correctly patched")
+	}
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "deployment",
+				"namespace": "test",
+			},
+		},
+	}
+	testcases := map[string]struct {
+		conflicts     int
+		expectedCalls int
+		isError       bool
+	}{
+		"no conflict updates once": {
+			conflicts:     0,
+			expectedCalls: 1,
+			isError:       false,
+		},
+		"conflict is retried": {
+			conflicts:     2,
+			expectedCalls: 3,
+			isError:       false,
+		},
+		"conflicts exhaust the retries": {
+			conflicts:     5,
+			expectedCalls: 3,
+			isError:       true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment.DeepCopy())
+			calls := 0
+			d.PrependReactor("update", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls <= tc.conflicts {
+					return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"},
+						"deployment", fmt.Errorf("conflict"))
+				}
+				return false, nil, nil
+			})
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+			mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+			c := NewClient(d, mapper).WithRetry(wait.Backoff{Steps: 3})
+			objMeta, err := object.CreateObjMetadata("test", "deployment", schema.GroupKind{Group: "apps", Kind: "Deployment"})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			err = c.UpdateWithRetry(context.TODO(), objMeta, func(obj *unstructured.Unstructured) (bool, error) {
+				return UpdateAnnotation(obj, "old", "new")
+			}, nil)
+			if tc.isError {
+				if !apierrors.IsConflict(err) {
+					t.Errorf("expected conflict error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d update calls, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestGet_ClusterScoped(t *testing.T) {
+	clusterRole := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "ClusterRole",
+			"metadata": map[string]interface{}{
+				"name": "cluster-role",
+			},
+		},
+	}
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), clusterRole)
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "rbac.authorization.k8s.io", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+
+	c := NewClient(d, mapper)
+	// The inventory may record a namespace for cluster-scoped objects;
+	// it must be ignored when accessing the object.
+	objMeta, err := object.CreateObjMetadata("test", "cluster-role",
+		schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	obj, err := c.Get(context.TODO(), objMeta)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if obj.GetName() != "cluster-role" {
+		t.Errorf("expected cluster-role, got %s", obj.GetName())
+	}
+}
+
+func TestUpdateOwningInventory(t *testing.T) {
+	testcases := map[string]struct {
+		keys                OwningInventoryKeys
+		annotations         map[string]string
+		labels              map[string]string
+		shouldUpdate        bool
+		expectedAnnotations map[string]string
+		expectedLabels      map[string]string
+		isError             bool
+	}{
+		"custom annotation key is updated": {
+			keys: OwningInventoryKeys{Annotation: "example.com/owner"},
+			annotations: map[string]string{
+				"example.com/owner": "old",
+			},
+			shouldUpdate: true,
+			expectedAnnotations: map[string]string{
+				"example.com/owner": "new",
+			},
+		},
+		"annotation and label are updated": {
+			keys: OwningInventoryKeys{
+				Annotation: OwningInventoryAnnotation,
+				Label:      "example.com/owner",
+			},
+			shouldUpdate: true,
+			expectedAnnotations: map[string]string{
+				OwningInventoryAnnotation: "new",
+			},
+			expectedLabels: map[string]string{
+				"example.com/owner": "new",
+			},
+		},
+		"label only mode reads the label": {
+			keys: OwningInventoryKeys{Label: "example.com/owner"},
+			labels: map[string]string{
+				"example.com/owner": "random",
+			},
+			shouldUpdate: false,
+			expectedLabels: map[string]string{
+				"example.com/owner": "random",
+			},
+		},
+		"empty keys is an error": {
+			keys:    OwningInventoryKeys{},
+			isError: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata": map[string]interface{}{
+						"name":      "deployment",
+						"namespace": "test",
+					},
+				},
+			}
+			obj.SetAnnotations(tc.annotations)
+			obj.SetLabels(tc.labels)
+			updated, err := UpdateOwningInventory(obj, "old", "new", tc.keys)
+			if tc.isError {
+				if err == nil {
+					t.Errorf("expected error but received none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if updated != tc.shouldUpdate {
+				t.Errorf("expected updated %t, got %t", tc.shouldUpdate, updated)
+			}
+			for k, v := range tc.expectedAnnotations {
+				if obj.GetAnnotations()[k] != v {
+					t.Errorf("expected annotation %s=%s, got %s", k, v, obj.GetAnnotations()[k])
+				}
+			}
+			for k, v := range tc.expectedLabels {
+				if obj.GetLabels()[k] != v {
+					t.Errorf("expected label %s=%s, got %s", k, v, obj.GetLabels()[k])
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate_DryRun(t *testing.T) {
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "deployment",
+				"namespace": "test",
+			},
+		},
+	}
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment.DeepCopy())
+	updated := false
+	d.PrependReactor("update", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		updated = true
+		return true, deployment, nil
+	})
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	c := NewClient(d, mapper).WithDryRun(true)
+	objMeta, err := object.CreateObjMetadata("test", "deployment", schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	options := &metav1.UpdateOptions{}
+	if err := c.Update(context.TODO(), objMeta, deployment, options); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !updated {
+		t.Errorf("expected the dry-run update to be sent to the server")
+	}
+	if len(options.DryRun) != 0 {
+		t.Errorf("the caller's options should not be modified")
+	}
+}
+
+func TestDeleteOptions(t *testing.T) {
+	foreground := metav1.DeletePropagationForeground
+	orphan := metav1.DeletePropagationOrphan
+	zero := int64(0)
+	testcases := map[string]struct {
+		client              *client
+		options             *metav1.DeleteOptions
+		expectedPropagation metav1.DeletionPropagation
+		expectedGracePeriod *int64
+	}{
+		"default propagation is background": {
+			client:              NewClient(nil, nil),
+			options:             nil,
+			expectedPropagation: metav1.DeletePropagationBackground,
+		},
+		"client propagation policy and grace period are used": {
+			client:              NewClient(nil, nil).WithPropagationPolicy(foreground).WithGracePeriod(0),
+			options:             &metav1.DeleteOptions{},
+			expectedPropagation: metav1.DeletePropagationForeground,
+			expectedGracePeriod: &zero,
+		},
+		"options take precedence over the client": {
+			client:              NewClient(nil, nil).WithPropagationPolicy(foreground),
+			options:             &metav1.DeleteOptions{PropagationPolicy: &orphan},
+			expectedPropagation: metav1.DeletePropagationOrphan,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			opts := tc.client.deleteOptions(tc.options)
+			if opts.PropagationPolicy == nil || *opts.PropagationPolicy != tc.expectedPropagation {
+				t.Errorf("expected propagation policy %s, got %v", tc.expectedPropagation, opts.PropagationPolicy)
+			}
+			if tc.expectedGracePeriod == nil {
+				if opts.GracePeriodSeconds != nil {
+					t.Errorf("expected no grace period, got %d", *opts.GracePeriodSeconds)
+				}
+			} else if opts.GracePeriodSeconds == nil || *opts.GracePeriodSeconds != *tc.expectedGracePeriod {
+				t.Errorf("expected grace period %d, got %v", *tc.expectedGracePeriod, opts.GracePeriodSeconds)
+			}
+		})
+	}
+}
+
+func TestListByInventory(t *testing.T) {
+	owned := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "owned",
+				"namespace": "test",
+				"annotations": map[string]interface{}{
+					"config.k8s.io/owning-inventory": "inv",
+				},
+			},
+		},
+	}
+	otherNamespace := owned.DeepCopy()
+	otherNamespace.SetName("other-namespace")
+	otherNamespace.SetNamespace("other")
+	unowned := owned.DeepCopy()
+	unowned.SetName("unowned")
+	unowned.SetAnnotations(map[string]string{"config.k8s.io/owning-inventory": "random"})
+
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), owned, otherNamespace, unowned)
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	c := NewClient(d, mapper)
+	objs, err := c.ListByInventory(context.TODO(), "inv", []schema.GroupKind{{Group: "apps", Kind: "Deployment"}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	names := map[string]bool{}
+	for _, obj := range objs {
+		names[obj.GetName()] = true
+	}
+	if len(names) != 2 || !names["owned"] || !names["other-namespace"] {
+		t.Errorf("expected owned and other-namespace, got %v", names)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "deployment",
+				"namespace": "test",
+			},
+		},
+	}
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	// One request every 1000 seconds; only the first request fits in the burst.
+	c := NewClient(d, mapper).WithRateLimit(0.001, 1)
+	objMeta, err := object.CreateObjMetadata("test", "deployment", schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Get(ctx, objMeta); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := c.Get(ctx, objMeta); err == nil {
+		t.Errorf("expected the second request to be throttled")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// FakeClient is an in-memory implementation of the Client interface
+// for testing code which reads and writes objects through the Client.
+type FakeClient struct {
+	mu      sync.Mutex
+	objects map[object.ObjMetadata]*unstructured.Unstructured
+	dryRun  bool
+}
+
+var _ Client = &FakeClient{}
+
+// NewFakeClient returns a FakeClient storing the passed objects.
+func NewFakeClient(objs ...*unstructured.Unstructured) *FakeClient {
+	f := &FakeClient{
+		objects: make(map[object.ObjMetadata]*unstructured.Unstructured),
+	}
+	for _, obj := range objs {
+		f.objects[fakeObjMetadata(obj)] = obj.DeepCopy()
+	}
+	return f
+}
+
+// WithDryRun makes the FakeClient validate writes without storing them,
+// as the API server does for dry-run requests.
+func (f *FakeClient) WithDryRun(dryRun bool) *FakeClient {
+	f.dryRun = dryRun
+	return f
+}
+
+// Objects returns copies of all the objects stored in the FakeClient.
+func (f *FakeClient) Objects() []*unstructured.Unstructured {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var objs []*unstructured.Unstructured
+	for _, obj := range f.objects {
+		objs = append(objs, obj.DeepCopy())
+	}
+	return objs
+}
+
+func (f *FakeClient) Get(_ context.Context, meta object.ObjMetadata) (*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, found := f.objects[meta]
+	if !found {
+		return nil, notFound(meta)
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (f *FakeClient) Update(_ context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, _ *metav1.UpdateOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, found := f.objects[meta]; !found {
+		return notFound(meta)
+	}
+	if !f.dryRun {
+		f.objects[meta] = obj.DeepCopy()
+	}
+	return nil
+}
+
+func (f *FakeClient) UpdateWithRetry(ctx context.Context, meta object.ObjMetadata, mutate MutateFunc, options *metav1.UpdateOptions) error {
+	obj, err := f.Get(ctx, meta)
+	if err != nil {
+		return err
+	}
+	changed, err := mutate(obj)
+	if err != nil || !changed {
+		return err
+	}
+	return f.Update(ctx, meta, obj, options)
+}
+
+// Patch only supports merge and strategic merge patches. Strategic merge
+// patches are applied as plain JSON merge patches.
+func (f *FakeClient) Patch(_ context.Context, meta object.ObjMetadata, patchType types.PatchType, data []byte, _ *metav1.PatchOptions) error {
+	if patchType != types.MergePatchType && patchType != types.StrategicMergePatchType {
+		return fmt.Errorf("patch type %s is not supported by the fake client", patchType)
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, found := f.objects[meta]
+	if !found {
+		return notFound(meta)
+	}
+	obj = obj.DeepCopy()
+	obj.Object = mergePatch(obj.Object, patch)
+	if !f.dryRun {
+		f.objects[meta] = obj
+	}
+	return nil
+}
+
+// Apply creates the object if it doesn't exist; otherwise the fields
+// set in obj are merged into the stored object.
+func (f *FakeClient) Apply(_ context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, _ *metav1.PatchOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dryRun {
+		return nil
+	}
+	f.objects[meta] = f.applied(meta, obj)
+	return nil
+}
+
+// DryRunApply returns the object Apply would store, without storing it.
+func (f *FakeClient) DryRunApply(_ context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, _ *metav1.PatchOptions) (*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.applied(meta, obj), nil
+}
+
+// applied returns a copy of the stored object with the fields set in obj
+// merged, or of obj if no object is stored.
+func (f *FakeClient) applied(meta object.ObjMetadata, obj *unstructured.Unstructured) *unstructured.Unstructured {
+	existing, found := f.objects[meta]
+	if !found {
+		return obj.DeepCopy()
+	}
+	existing = existing.DeepCopy()
+	existing.Object = mergePatch(existing.Object, obj.DeepCopy().Object)
+	return existing
+}
+
+func (f *FakeClient) Delete(_ context.Context, meta object.ObjMetadata, _ *metav1.DeleteOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, found := f.objects[meta]; !found {
+		return notFound(meta)
+	}
+	if !f.dryRun {
+		delete(f.objects, meta)
+	}
+	return nil
+}
+
+// ListByInventory returns the stored objects of the passed kinds owned
+// by the inventory, using the default owning inventory keys.
+func (f *FakeClient) ListByInventory(_ context.Context, inventoryID string, gks []schema.GroupKind) ([]*unstructured.Unstructured, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var objs []*unstructured.Unstructured
+	for _, gk := range gks {
+		for meta, obj := range f.objects {
+			if meta.GroupKind != gk {
+				continue
+			}
+			if id, found := OwningInventory(obj, DefaultOwningInventoryKeys); found && id == inventoryID {
+				objs = append(objs, obj.DeepCopy())
+			}
+		}
+	}
+	return objs, nil
+}
+
+// mergePatch applies the JSON merge patch to the passed object,
+// where null values in the patch remove the field.
+func mergePatch(obj, patch map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		obj = make(map[string]interface{})
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(obj, k)
+			continue
+		}
+		patchMap, isMap := v.(map[string]interface{})
+		objMap, objIsMap := obj[k].(map[string]interface{})
+		if isMap && objIsMap {
+			obj[k] = mergePatch(objMap, patchMap)
+			continue
+		}
+		obj[k] = v
+	}
+	return obj
+}
+
+func fakeObjMetadata(obj *unstructured.Unstructured) object.ObjMetadata {
+	return object.ObjMetadata{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		GroupKind: obj.GroupVersionKind().GroupKind(),
+	}
+}
+
+func notFound(meta object.ObjMetadata) error {
+	return apierrors.NewNotFound(schema.GroupResource{
+		Group:    meta.GroupKind.Group,
+		Resource: meta.GroupKind.Kind,
+	}, meta.Name)
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestFakeClient(t *testing.T) {
+	deployment := newDeployment("deployment", map[string]string{"config.k8s.io/owning-inventory": "old"})
+	objMeta := object.ObjMetadata{
+		Namespace: "test",
+		Name:      "deployment",
+		GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+	}
+	var c Client = NewFakeClient(deployment)
+
+	err := c.UpdateWithRetry(context.TODO(), objMeta, func(obj *unstructured.Unstructured) (bool, error) {
+		return UpdateAnnotation(obj, "old", "new")
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	obj, err := c.Get(context.TODO(), objMeta)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if obj.GetAnnotations()["config.k8s.io/owning-inventory"] != "new" {
+		t.Errorf("the owning-inventory annotation is not correctly updated")
+	}
+
+	data := []byte(`{"metadata":{"annotations":{"config.k8s.io/owning-inventory":null}}}`)
+	if err := c.Patch(context.TODO(), objMeta, types.MergePatchType, data, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	obj, err = c.Get(context.TODO(), objMeta)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, found := obj.GetAnnotations()["config.k8s.io/owning-inventory"]; found {
+		t.Errorf("the owning-inventory annotation is not removed")
+	}
+
+	if err := c.Delete(context.TODO(), objMeta, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := c.Get(context.TODO(), objMeta); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
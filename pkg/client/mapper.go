@@ -0,0 +1,70 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog"
+)
+
+// resetter is implemented by RESTMappers which can reset their
+// cached discovery information, e.g. the DeferredDiscoveryRESTMapper.
+type resetter interface {
+	Reset()
+}
+
+// mappingCache caches the RESTMappings returned by the wrapped RESTMapper
+// so repeated operations on the same kind don't trigger discovery. If a
+// kind is not found, the wrapped RESTMapper is reset (when possible) and
+// the lookup is retried once, so newly installed CRDs are picked up.
+type mappingCache struct {
+	mapper   meta.RESTMapper
+	mu       sync.RWMutex
+	mappings map[schema.GroupKind]*meta.RESTMapping
+}
+
+func newMappingCache(mapper meta.RESTMapper) *mappingCache {
+	return &mappingCache{
+		mapper:   mapper,
+		mappings: make(map[schema.GroupKind]*meta.RESTMapping),
+	}
+}
+
+// RESTMapping returns the cached RESTMapping for the passed GroupKind,
+// querying the wrapped RESTMapper on a cache miss.
+func (c *mappingCache) RESTMapping(gk schema.GroupKind) (*meta.RESTMapping, error) {
+	c.mu.RLock()
+	mapping, found := c.mappings[gk]
+	c.mu.RUnlock()
+	if found {
+		return mapping, nil
+	}
+	mapping, err := c.mapper.RESTMapping(gk)
+	if meta.IsNoMatchError(err) {
+		klog.V(4).Infof("no mapping found for %s; resetting RESTMapper", gk)
+		c.Reset()
+		mapping, err = c.mapper.RESTMapping(gk)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.mappings[gk] = mapping
+	c.mu.Unlock()
+	return mapping, nil
+}
+
+// Reset clears the cached mappings and resets the wrapped RESTMapper
+// if it supports it.
+func (c *mappingCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mappings = make(map[schema.GroupKind]*meta.RESTMapping)
+	if resettable, ok := c.mapper.(resetter); ok {
+		resettable.Reset()
+	}
+}
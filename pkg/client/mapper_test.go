@@ -0,0 +1,71 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeRESTMapper counts the RESTMapping calls and only knows the
+// CRD kind after it has been reset.
+type fakeRESTMapper struct {
+	meta.RESTMapper
+	calls  int
+	resets int
+}
+
+func (f *fakeRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	f.calls++
+	if gk.Kind == "Custom" && f.resets == 0 {
+		return nil, &meta.NoKindMatchError{GroupKind: gk}
+	}
+	return &meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Group: gk.Group, Version: "v1", Resource: "customs"},
+		GroupVersionKind: gk.WithVersion("v1"),
+		Scope:            meta.RESTScopeNamespace,
+	}, nil
+}
+
+func (f *fakeRESTMapper) Reset() {
+	f.resets++
+}
+
+func TestMappingCache(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	f := &fakeRESTMapper{}
+	c := newMappingCache(f)
+	for i := 0; i < 3; i++ {
+		if _, err := c.RESTMapping(gk); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if f.calls != 1 {
+		t.Errorf("expected 1 RESTMapping call, got %d", f.calls)
+	}
+	if f.resets != 0 {
+		t.Errorf("expected no reset, got %d", f.resets)
+	}
+}
+
+func TestMappingCache_ResetOnNoMatch(t *testing.T) {
+	gk := schema.GroupKind{Group: "example.com", Kind: "Custom"}
+	f := &fakeRESTMapper{}
+	c := newMappingCache(f)
+	mapping, err := c.RESTMapping(gk)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if mapping.GroupVersionKind.Kind != "Custom" {
+		t.Errorf("unexpected mapping %v", mapping.GroupVersionKind)
+	}
+	if f.resets != 1 {
+		t.Errorf("expected 1 reset, got %d", f.resets)
+	}
+	if f.calls != 2 {
+		t.Errorf("expected 2 RESTMapping calls, got %d", f.calls)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// DefaultMigrationWorkers is the default number of objects migrated
+// concurrently by MigrateAnnotations.
+const DefaultMigrationWorkers = 10
+
+// MigrationStatus describes the outcome of migrating the owning-inventory
+// annotation of a single object.
+type MigrationStatus string
+
+const (
+	// MigrationUpdated means the annotation was rewritten to the new ID.
+	MigrationUpdated MigrationStatus = "Updated"
+	// MigrationUnchanged means the object is owned by another inventory
+	// or already carries the new ID.
+	MigrationUnchanged MigrationStatus = "Unchanged"
+	// MigrationNotFound means the object no longer exists in the cluster.
+	MigrationNotFound MigrationStatus = "NotFound"
+	// MigrationFailed means the object could not be fetched or updated.
+	MigrationFailed MigrationStatus = "Failed"
+)
+
+// MigrationResult is the result of migrating a single object.
+type MigrationResult struct {
+	Object object.ObjMetadata
+	Status MigrationStatus
+	Error  error
+	// UID and ResourceVersion of the object as last observed
+	// during the migration; empty if the object was not found.
+	UID             types.UID
+	ResourceVersion string
+	// Unowned is true if the object had no owning inventory before it
+	// was updated, so rolling back the migration removes it.
+	Unowned bool
+}
+
+// MigrationEvent reports the progress of MigrateAnnotations.
+type MigrationEvent struct {
+	// Result is the result of the object which was just migrated.
+	Result MigrationResult
+	// Completed is the number of objects migrated so far.
+	Completed int
+	// Total is the number of objects to migrate.
+	Total int
+}
+
+// ProgressFunc is called with a MigrationEvent after each object is
+// migrated. Calls are serialized, so it does not need to be thread-safe.
+type ProgressFunc func(event MigrationEvent)
+
+// MigrateAnnotations rewrites the owning-inventory annotation (and/or label,
+// see WithOwningInventoryKeys) from oldID to newID on all the passed objects.
+// The annotation of the objects owned by oldID is removed if newID is empty.
+// The objects are fetched, mutated and updated concurrently by a bounded
+// pool of workers, retrying on conflict. The returned results are in the
+// same order as objs.
+func (uc *client) MigrateAnnotations(ctx context.Context, oldID, newID string, objs []object.ObjMetadata) []MigrationResult {
+	results := make([]MigrationResult, len(objs))
+	workers := uc.workers
+	if workers <= 0 {
+		workers = DefaultMigrationWorkers
+	}
+	var cache *objectCache
+	if uc.watch {
+		var err error
+		cache, err = uc.watchObjects(ctx, objs)
+		if err != nil {
+			klog.V(4).Infof("unable to watch objects, fetching them instead: %s", err)
+		} else {
+			defer cache.stop()
+		}
+	}
+	var mu sync.Mutex
+	completed := 0
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = uc.migrateAnnotation(ctx, cache, oldID, newID, objs[index])
+				if uc.progress != nil {
+					mu.Lock()
+					completed++
+					uc.progress(MigrationEvent{
+						Result:    results[index],
+						Completed: completed,
+						Total:     len(objs),
+					})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range objs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// migrateAnnotation migrates the owning-inventory annotation of a
+// single object and returns the result. Objects applied with server-side
+// apply don't have a last-applied-configuration annotation to rewrite,
+// so the ownership of the annotation is transferred to the client's
+// field manager with a server-side apply instead. The object is read
+// from the cache if one is passed, and re-read from the API server
+// after a conflict.
+func (uc *client) migrateAnnotation(ctx context.Context, cache *objectCache, oldID, newID string, meta object.ObjMetadata) MigrationResult {
+	obj, err := uc.cachedGet(ctx, cache, meta)
+	if err != nil {
+		return migrationResult(meta, nil, false, err)
+	}
+	if IsServerSideApplied(obj) {
+		return uc.applyAnnotation(ctx, obj, oldID, newID, meta)
+	}
+	changed, unowned := false, false
+	err = retry.RetryOnConflict(uc.retryBackoff, func() error {
+		var err error
+		if obj == nil {
+			if obj, err = uc.Get(ctx, meta); err != nil {
+				return err
+			}
+		}
+		_, owned := OwningInventory(obj, uc.keys)
+		unowned = !owned
+		changed, err = UpdateOwningInventory(obj, oldID, newID, uc.keys)
+		if err != nil || !changed {
+			return err
+		}
+		updated, err := uc.update(ctx, meta, obj, &metav1.UpdateOptions{})
+		if err != nil {
+			if apierrors.IsConflict(err) {
+				// re-read the object before retrying
+				obj = nil
+			}
+			return err
+		}
+		obj = updated
+		return nil
+	})
+	result := migrationResult(meta, obj, changed, err)
+	result.Unowned = unowned && result.Status == MigrationUpdated
+	return result
+}
+
+// cachedGet returns the object from the cache if it is there,
+// otherwise it is fetched from the API server.
+func (uc *client) cachedGet(ctx context.Context, cache *objectCache, meta object.ObjMetadata) (*unstructured.Unstructured, error) {
+	if cache != nil {
+		if obj, found := cache.get(meta); found {
+			return obj, nil
+		}
+	}
+	return uc.Get(ctx, meta)
+}
+
+// applyAnnotation sets the owning-inventory annotation of the passed
+// server-side applied object using server-side apply.
+func (uc *client) applyAnnotation(ctx context.Context, obj *unstructured.Unstructured, oldID, newID string, meta object.ObjMetadata) MigrationResult {
+	changed, err := UpdateOwningInventory(obj.DeepCopy(), oldID, newID, uc.keys)
+	if err != nil || !changed {
+		return migrationResult(meta, obj, changed, err)
+	}
+	_, owned := OwningInventory(obj, uc.keys)
+	force := true
+	err = uc.Apply(ctx, meta, OwningInventoryApplyObject(obj, newID, uc.keys), &metav1.PatchOptions{Force: &force})
+	result := migrationResult(meta, obj, true, err)
+	result.Unowned = !owned && result.Status == MigrationUpdated
+	return result
+}
+
+func migrationResult(meta object.ObjMetadata, obj *unstructured.Unstructured, changed bool, err error) MigrationResult {
+	result := MigrationResult{Object: meta}
+	if obj != nil {
+		result.UID = obj.GetUID()
+		result.ResourceVersion = obj.GetResourceVersion()
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		result.Status = MigrationNotFound
+	case err != nil:
+		result.Status = MigrationFailed
+		result.Error = err
+	case changed:
+		result.Status = MigrationUpdated
+	default:
+		result.Status = MigrationUnchanged
+	}
+	return result
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func newDeployment(name string, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "test",
+			},
+		},
+	}
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func newTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	return mapper
+}
+
+func TestMigrateAnnotations(t *testing.T) {
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDeployment("owned", map[string]string{"config.k8s.io/owning-inventory": "old"}),
+		newDeployment("unowned", nil),
+		newDeployment("other", map[string]string{"config.k8s.io/owning-inventory": "random"}),
+	)
+	var objs []object.ObjMetadata
+	for _, name := range []string{"owned", "unowned", "other", "missing"} {
+		objMeta, err := object.CreateObjMetadata("test", name, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		objs = append(objs, objMeta)
+	}
+	expected := []MigrationStatus{
+		MigrationUpdated,
+		MigrationUpdated,
+		MigrationUnchanged,
+		MigrationNotFound,
+	}
+
+	c := NewClient(d, newTestMapper()).WithWorkers(2)
+	results := c.MigrateAnnotations(context.TODO(), "old", "new", objs)
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if result.Object != objs[i] {
+			t.Errorf("expected result for %s, got %s", objs[i], result.Object)
+		}
+		if result.Status != expected[i] {
+			t.Errorf("expected status %s for %s, got %s (%v)", expected[i], objs[i].Name, result.Status, result.Error)
+		}
+	}
+	obj, err := c.Get(context.TODO(), objs[0])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if obj.GetAnnotations()["config.k8s.io/owning-inventory"] != "new" {
+		t.Errorf("the owning-inventory annotation is not correctly updated")
+	}
+}
+
+func TestMigrateAnnotations_Rollback(t *testing.T) {
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDeployment("owned", map[string]string{"config.k8s.io/owning-inventory": "old"}),
+		newDeployment("unowned", nil),
+	)
+	var objs []object.ObjMetadata
+	for _, name := range []string{"owned", "unowned"} {
+		objMeta, err := object.CreateObjMetadata("test", name, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		objs = append(objs, objMeta)
+	}
+
+	c := NewClient(d, newTestMapper())
+	results := c.MigrateAnnotations(context.TODO(), "old", "new", objs)
+	if results[0].Unowned || !results[1].Unowned {
+		t.Fatalf("expected only the unowned object to be unowned, got %v and %v", results[0].Unowned, results[1].Unowned)
+	}
+
+	// An empty ID removes the annotation written by the migration.
+	results = c.MigrateAnnotations(context.TODO(), "new", "", objs[1:])
+	if results[0].Status != MigrationUpdated {
+		t.Fatalf("expected status %s, got %s (%v)", MigrationUpdated, results[0].Status, results[0].Error)
+	}
+	obj, err := c.Get(context.TODO(), objs[1])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, found := obj.GetAnnotations()["config.k8s.io/owning-inventory"]; found {
+		t.Errorf("expected the owning-inventory annotation to be removed")
+	}
+}
+
+func TestMigrateAnnotations_WatchAndProgress(t *testing.T) {
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDeployment("first", map[string]string{"config.k8s.io/owning-inventory": "old"}),
+		newDeployment("second", nil),
+	)
+	var objs []object.ObjMetadata
+	for _, name := range []string{"first", "second"} {
+		objMeta, err := object.CreateObjMetadata("test", name, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		objs = append(objs, objMeta)
+	}
+	var events []MigrationEvent
+	c := NewClient(d, newTestMapper()).WithWatch(true).WithProgress(func(event MigrationEvent) {
+		events = append(events, event)
+	})
+	results := c.MigrateAnnotations(context.TODO(), "old", "new", objs)
+	for _, result := range results {
+		if result.Status != MigrationUpdated {
+			t.Errorf("expected status %s for %s, got %s (%v)", MigrationUpdated, result.Object.Name, result.Status, result.Error)
+		}
+	}
+	if len(events) != len(objs) {
+		t.Fatalf("expected %d progress events, got %d", len(objs), len(events))
+	}
+	for i, event := range events {
+		if event.Completed != i+1 || event.Total != len(objs) {
+			t.Errorf("unexpected progress %d/%d", event.Completed, event.Total)
+		}
+	}
+	// The objects are listed and watched instead of fetched one at a time.
+	for _, action := range d.Actions() {
+		if action.GetVerb() == "get" {
+			t.Errorf("unexpected get of %s", action.GetResource())
+		}
+	}
+}
+
+func TestMigrateAnnotations_UpdateErrors(t *testing.T) {
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDeployment("conflict", map[string]string{"config.k8s.io/owning-inventory": "old"}),
+		newDeployment("forbidden", map[string]string{"config.k8s.io/owning-inventory": "old"}),
+	)
+	conflicts := 0
+	d.PrependReactor("update", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		obj := action.(clienttesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+		gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+		switch {
+		case obj.GetName() == "forbidden":
+			return true, nil, apierrors.NewForbidden(gr, obj.GetName(), errors.New("denied"))
+		case conflicts == 0:
+			conflicts++
+			return true, nil, apierrors.NewConflict(gr, obj.GetName(), errors.New("modified"))
+		}
+		return false, nil, nil
+	})
+	var objs []object.ObjMetadata
+	for _, name := range []string{"conflict", "forbidden"} {
+		objMeta, err := object.CreateObjMetadata("test", name, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		objs = append(objs, objMeta)
+	}
+
+	c := NewClient(d, newTestMapper()).WithWorkers(1)
+	results := c.MigrateAnnotations(context.TODO(), "old", "new", objs)
+	if results[0].Status != MigrationUpdated {
+		t.Errorf("expected the conflict to be retried, got %s (%v)", results[0].Status, results[0].Error)
+	}
+	if results[1].Status != MigrationFailed || !apierrors.IsForbidden(results[1].Error) {
+		t.Errorf("expected the forbidden update to fail, got %s (%v)", results[1].Status, results[1].Error)
+	}
+	gets := 0
+	for _, action := range d.Actions() {
+		if action.GetVerb() == "get" {
+			gets++
+		}
+	}
+	// Each object is fetched once, and the conflicting object once more
+	// before the update is retried.
+	if gets != 3 {
+		t.Errorf("expected 3 gets, got %d", gets)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// IsServerSideApplied returns true if the object was applied using
+// server-side apply, i.e. it has no last-applied-configuration annotation
+// and at least one of its field managers used the Apply operation.
+func IsServerSideApplied(obj *unstructured.Unstructured) bool {
+	if _, found := obj.GetAnnotations()[v1.LastAppliedConfigAnnotation]; found {
+		return false
+	}
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// OwningInventoryApplyObject returns the minimal object which sets the
+// owning inventory annotation and/or label of the passed object to the
+// new ID. Server-side applying it with the kpt field manager (and force)
+// transfers the ownership of these fields to kpt, leaving the ownership
+// of all other fields untouched. If the new ID is empty, applying it removes
+// the annotation and/or label owned by kpt.
+func OwningInventoryApplyObject(obj *unstructured.Unstructured, newID string, keys OwningInventoryKeys) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(obj.GetAPIVersion())
+	u.SetKind(obj.GetKind())
+	u.SetNamespace(obj.GetNamespace())
+	u.SetName(obj.GetName())
+	if newID == "" {
+		return u
+	}
+	if keys.Annotation != "" {
+		u.SetAnnotations(map[string]string{keys.Annotation: newID})
+	}
+	if keys.Label != "" {
+		u.SetLabels(map[string]string{keys.Label: newID})
+	}
+	return u
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIsServerSideApplied(t *testing.T) {
+	testcases := map[string]struct {
+		annotations   map[string]string
+		managedFields []metav1.ManagedFieldsEntry
+		expected      bool
+	}{
+		"no managed fields": {
+			expected: false,
+		},
+		"client-side applied": {
+			annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate},
+			},
+			expected: false,
+		},
+		"server-side applied": {
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply},
+			},
+			expected: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			obj := newDeployment("deployment", tc.annotations)
+			obj.SetManagedFields(tc.managedFields)
+			if actual := IsServerSideApplied(obj); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOwningInventoryApplyObject(t *testing.T) {
+	obj := newDeployment("deployment", map[string]string{
+		"config.k8s.io/owning-inventory": "old",
+		"other":                          "value",
+	})
+	obj.SetLabels(map[string]string{"app": "test"})
+	_ = unstructured.SetNestedField(obj.Object, int64(3), "spec", "replicas")
+
+	u := OwningInventoryApplyObject(obj, "new", DefaultOwningInventoryKeys)
+	if u.GetName() != "deployment" || u.GetNamespace() != "test" || u.GetKind() != "Deployment" {
+		t.Errorf("unexpected object identity %s/%s %s", u.GetNamespace(), u.GetName(), u.GetKind())
+	}
+	expected := map[string]string{"config.k8s.io/owning-inventory": "new"}
+	if len(u.GetAnnotations()) != 1 || u.GetAnnotations()["config.k8s.io/owning-inventory"] != "new" {
+		t.Errorf("expected annotations %v, got %v", expected, u.GetAnnotations())
+	}
+	if len(u.GetLabels()) != 0 {
+		t.Errorf("expected no labels, got %v", u.GetLabels())
+	}
+	if _, found := u.Object["spec"]; found {
+		t.Errorf("apply object should only contain the owning inventory fields")
+	}
+}
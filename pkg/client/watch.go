@@ -0,0 +1,113 @@
+// Copyright 2020 Google LLC.
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// objectCache holds the latest observed version of a set of objects.
+// It is filled by listing the objects once per kind and namespace, and
+// kept up to date by watching them from the listed resource version.
+type objectCache struct {
+	mu       sync.RWMutex
+	objs     map[object.ObjMetadata]*unstructured.Unstructured
+	watchers []watch.Interface
+}
+
+// watchObjects lists and watches the passed objects, returning the cache
+// holding them. The watches are stopped by calling stop on the cache.
+func (uc *client) watchObjects(ctx context.Context, objMetas []object.ObjMetadata) (*objectCache, error) {
+	cache := &objectCache{
+		objs: make(map[object.ObjMetadata]*unstructured.Unstructured),
+	}
+	wanted := make(map[object.ObjMetadata]bool, len(objMetas))
+	// Group the objects by kind and namespace, so each group is
+	// listed and watched with a single request.
+	groups := make(map[object.ObjMetadata]bool)
+	for _, objMeta := range objMetas {
+		wanted[objMeta] = true
+		groups[object.ObjMetadata{Namespace: objMeta.Namespace, GroupKind: objMeta.GroupKind}] = true
+	}
+	for group := range groups {
+		r, err := uc.resourceInterface(ctx, group)
+		if err != nil {
+			cache.stop()
+			return nil, err
+		}
+		list, err := r.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			cache.stop()
+			return nil, err
+		}
+		for i := range list.Items {
+			cache.store(wanted, &list.Items[i])
+		}
+		w, err := r.Watch(ctx, metav1.ListOptions{ResourceVersion: list.GetResourceVersion()})
+		if err != nil {
+			cache.stop()
+			return nil, err
+		}
+		cache.watchers = append(cache.watchers, w)
+		go cache.consume(wanted, w)
+	}
+	return cache, nil
+}
+
+// consume updates the cache with the events of the passed watch until
+// the watch is stopped.
+func (c *objectCache) consume(wanted map[object.ObjMetadata]bool, w watch.Interface) {
+	for event := range w.ResultChan() {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			klog.V(6).Infof("observed %s/%s at resource version %s",
+				obj.GetNamespace(), obj.GetName(), obj.GetResourceVersion())
+			c.store(wanted, obj)
+		case watch.Deleted:
+			c.mu.Lock()
+			delete(c.objs, object.UnstructuredToObjMeta(obj))
+			c.mu.Unlock()
+		}
+	}
+}
+
+// store adds the object to the cache if it is one of the wanted objects.
+func (c *objectCache) store(wanted map[object.ObjMetadata]bool, obj *unstructured.Unstructured) {
+	objMeta := object.UnstructuredToObjMeta(obj)
+	if !wanted[objMeta] {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objs[objMeta] = obj.DeepCopy()
+}
+
+// get returns a copy of the cached object, and whether it was found.
+func (c *objectCache) get(objMeta object.ObjMetadata) (*unstructured.Unstructured, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	obj, found := c.objs[objMeta]
+	if !found {
+		return nil, false
+	}
+	return obj.DeepCopy(), true
+}
+
+// stop stops all the watches of the cache.
+func (c *objectCache) stop() {
+	for _, w := range c.watchers {
+		w.Stop()
+	}
+}
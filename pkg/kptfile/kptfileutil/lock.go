@@ -0,0 +1,181 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kptfileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"sigs.k8s.io/kustomize/kyaml/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// Lock returns the LockFile of the package in dir, recording the upstream of
+// the package and of each subpackage fetched from git, an OCI registry or a
+// Helm chart.  Upstreams must be resolved to a commit or digest.
+func Lock(dir string) (kptfile.LockFile, error) {
+	lock := kptfile.LockFile{ResourceMeta: kptfile.LockTypeMeta}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || info.Name() != kptfile.KptFileName {
+			return nil
+		}
+		pkgPath := filepath.Dir(path)
+		kf, err := ReadFileStrict(pkgPath)
+		if err != nil {
+			return err
+		}
+		switch kf.Upstream.Type {
+		case kptfile.GitOrigin, kptfile.OCIOrigin, kptfile.HelmOrigin:
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(dir, pkgPath)
+		if err != nil {
+			return err
+		}
+		lock.Packages = append(lock.Packages,
+			kptfile.LockedPackage{Path: filepath.ToSlash(rel), Upstream: kf.Upstream})
+		return nil
+	})
+	if err != nil {
+		return kptfile.LockFile{}, errors.Wrap(err)
+	}
+	sort.Slice(lock.Packages, func(i, j int) bool {
+		return lock.Packages[i].Path < lock.Packages[j].Path
+	})
+
+	// keep the concrete versions that version constraints were resolved to
+	// by earlier locks, as long as the packages are unchanged
+	if previous, err := ReadLockFile(dir); err == nil {
+		locked := map[string]kptfile.Upstream{}
+		for _, p := range previous.Packages {
+			locked[p.Path] = p.Upstream
+		}
+		for i, p := range lock.Packages {
+			l, found := locked[p.Path]
+			if found && withRef(l, ref(p.Upstream)) == p.Upstream {
+				lock.Packages[i].Upstream = l
+			}
+		}
+	}
+	return lock, nil
+}
+
+// LockResolved writes the LockFile of the package in dir, recording version as
+// the concrete ref that the version constraint of the package resolved to.
+func LockResolved(dir, version string) error {
+	lock, err := Lock(dir)
+	if err != nil {
+		return err
+	}
+	for i, p := range lock.Packages {
+		if p.Path == "." {
+			lock.Packages[i].Upstream = withRef(p.Upstream, version)
+		}
+	}
+	return WriteLockFile(dir, lock)
+}
+
+// ref returns the ref of the upstream.
+func ref(u kptfile.Upstream) string {
+	if u.Type == kptfile.OCIOrigin {
+		return u.OCI.Ref
+	}
+	return u.Git.Ref
+}
+
+// withRef returns the upstream with its ref replaced.
+func withRef(u kptfile.Upstream, ref string) kptfile.Upstream {
+	if u.Type == kptfile.OCIOrigin {
+		u.OCI.Ref = ref
+	} else if u.Type == kptfile.GitOrigin {
+		u.Git.Ref = ref
+	}
+	return u
+}
+
+// ReadLockFile reads the LockFile in the given directory
+func ReadLockFile(dir string) (kptfile.LockFile, error) {
+	lock := kptfile.LockFile{}
+	b, err := ioutil.ReadFile(filepath.Join(dir, kptfile.LockFileName))
+	if err != nil {
+		return lock, errors.Errorf("unable to read %s: %v", kptfile.LockFileName, err)
+	}
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return lock, errors.Errorf("unable to parse %s: %v", kptfile.LockFileName, err)
+	}
+	return lock, nil
+}
+
+// WriteLockFile writes the LockFile to the given directory
+func WriteLockFile(dir string, lock kptfile.LockFile) error {
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, kptfile.LockFileName), b, 0600)
+}
+
+// DiffLock returns a description of each package whose upstream differs
+// between the expected and actual LockFiles.  Returns nil if they match.
+func DiffLock(expected, actual kptfile.LockFile) []string {
+	actualPkgs := map[string]kptfile.Upstream{}
+	for _, p := range actual.Packages {
+		actualPkgs[p.Path] = p.Upstream
+	}
+	var diffs []string
+	for _, p := range expected.Packages {
+		a, found := actualPkgs[p.Path]
+		delete(actualPkgs, p.Path)
+		switch {
+		case !found:
+			diffs = append(diffs, fmt.Sprintf("%s: missing", p.Path))
+		case a != p.Upstream:
+			diffs = append(diffs, fmt.Sprintf("%s: expected %s, resolved %s",
+				p.Path, describe(p.Upstream), describe(a)))
+		}
+	}
+	var extra []string
+	for path := range actualPkgs {
+		extra = append(extra, path)
+	}
+	sort.Strings(extra)
+	for _, path := range extra {
+		diffs = append(diffs, fmt.Sprintf("%s: not in %s", path, kptfile.LockFileName))
+	}
+	return diffs
+}
+
+// describe returns a short description of the resolved upstream.
+func describe(u kptfile.Upstream) string {
+	switch u.Type {
+	case kptfile.OCIOrigin:
+		return fmt.Sprintf("%s@%s", u.OCI.Image, u.OCI.Digest)
+	case kptfile.HelmOrigin:
+		return fmt.Sprintf("%s/%s %s", u.Helm.Repo, u.Helm.Chart, u.Helm.Version)
+	default:
+		return fmt.Sprintf("%s%s@%s", u.Git.Repo, u.Git.Directory, u.Git.Commit)
+	}
+}
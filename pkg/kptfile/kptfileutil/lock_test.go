@@ -0,0 +1,89 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kptfileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kpt-lock-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	root := kptfile.Upstream{Type: kptfile.GitOrigin, Git: kptfile.Git{
+		Repo: "https://github.com/org/repo", Directory: "/app", Ref: "v1", Commit: "abc"}}
+	sub := kptfile.Upstream{Type: kptfile.OCIOrigin, OCI: kptfile.OCI{
+		Image: "ghcr.io/org/sub", Ref: "v2", Digest: "sha256:def"}}
+	for path, upstream := range map[string]kptfile.Upstream{
+		"":          root,
+		"b/sub":     sub,
+		"a/local":   {}, // packages without upstream are not locked
+		".git/skip": root,
+	} {
+		pkg := filepath.Join(dir, path)
+		assert.NoError(t, os.MkdirAll(pkg, 0700))
+		kf := kptfile.KptFile{ResourceMeta: kptfile.TypeMeta, Upstream: upstream}
+		kf.Name = filepath.Base(pkg)
+		assert.NoError(t, WriteFile(pkg, kf))
+	}
+
+	lock, err := Lock(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	expected := kptfile.LockFile{ResourceMeta: kptfile.LockTypeMeta, Packages: []kptfile.LockedPackage{
+		{Path: ".", Upstream: root},
+		{Path: "b/sub", Upstream: sub},
+	}}
+	assert.Equal(t, expected, lock)
+
+	// the lock file round trips
+	assert.NoError(t, WriteLockFile(dir, lock))
+	read, err := ReadLockFile(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, read)
+}
+
+func TestDiffLock(t *testing.T) {
+	git := func(commit string) kptfile.Upstream {
+		return kptfile.Upstream{Type: kptfile.GitOrigin, Git: kptfile.Git{
+			Repo: "https://github.com/org/repo", Directory: "/app", Ref: "main", Commit: commit}}
+	}
+	expected := kptfile.LockFile{Packages: []kptfile.LockedPackage{
+		{Path: ".", Upstream: git("abc")},
+		{Path: "sub", Upstream: git("abc")},
+	}}
+
+	assert.Empty(t, DiffLock(expected, expected))
+
+	actual := kptfile.LockFile{Packages: []kptfile.LockedPackage{
+		{Path: ".", Upstream: git("def")},
+		{Path: "other", Upstream: git("abc")},
+	}}
+	assert.Equal(t, []string{
+		".: expected https://github.com/org/repo/app@abc, resolved https://github.com/org/repo/app@def",
+		"sub: missing",
+		"other: not in Kptfile.lock",
+	}, DiffLock(expected, actual))
+}
@@ -99,6 +99,30 @@ func ReadFileStrict(pkgPath string) (kptfile.KptFile, error) {
 			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.git.directory", pkgPath)
 		}
 	}
+	if kf.Upstream.Type == kptfile.OCIOrigin {
+		oci := kf.Upstream.OCI
+		if oci.Image == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.oci.image", pkgPath)
+		}
+		if oci.Digest == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.oci.digest", pkgPath)
+		}
+		if oci.Ref == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.oci.ref", pkgPath)
+		}
+	}
+	if kf.Upstream.Type == kptfile.HelmOrigin {
+		helm := kf.Upstream.Helm
+		if helm.Repo == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.helm.repo", pkgPath)
+		}
+		if helm.Chart == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.helm.chart", pkgPath)
+		}
+		if helm.Version == "" {
+			return kptfile.KptFile{}, errors.Errorf("%s Kptfile missing upstream.helm.version", pkgPath)
+		}
+	}
 	if kf.Upstream.Type == kptfile.StdinOrigin {
 		stdin := kf.Upstream.Stdin
 		if stdin.FilenamePattern == "" {
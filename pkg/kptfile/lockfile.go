@@ -0,0 +1,53 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kptfile
+
+import (
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// LockFileName is the name of the LockFile
+	LockFileName = "Kptfile.lock"
+	// LockFileKind is the kind of LockFile instances
+	LockFileKind = "KptfileLock"
+)
+
+// LockTypeMeta is the TypeMeta for LockFile instances.
+var LockTypeMeta = yaml.ResourceMeta{
+	TypeMeta: yaml.TypeMeta{
+		APIVersion: KptFileAPIVersion,
+		Kind:       LockFileKind,
+	},
+}
+
+// LockFile records the exact upstream commits and digests of a package and
+// all of its subpackages, so the same package can be fetched reproducibly.
+type LockFile struct {
+	yaml.ResourceMeta `yaml:",inline"`
+
+	// Packages contains the resolved upstream of each package, sorted by path
+	Packages []LockedPackage `yaml:"packages,omitempty"`
+}
+
+// LockedPackage is the resolved upstream of a package or subpackage.
+type LockedPackage struct {
+	// Path is the slash separated path of the package relative to the
+	// LockFile.  The package containing the LockFile is ".".
+	Path string `yaml:"path"`
+
+	// Upstream is the upstream of the package, as recorded in its Kptfile
+	Upstream `yaml:",inline"`
+}
@@ -42,6 +42,10 @@ type KptFile struct {
 	// CloneFrom records where the package was originally cloned from
 	Upstream Upstream `yaml:"upstream,omitempty"`
 
+	// Published records where the package was last published to with
+	// kpt pkg push, as an immutable reference for downstream consumers
+	Published Upstream `yaml:"published,omitempty"`
+
 	// PackageMeta contains information about the package
 	PackageMeta PackageMeta `yaml:"packageMetadata,omitempty"`
 
@@ -58,6 +62,197 @@ type KptFile struct {
 
 	// Parameters for inventory object.
 	Inventory *Inventory `yaml:"inventory,omitempty"`
+
+	// StatusReaders declares how to compute the status of resources
+	// kpt can not compute the status of, such as custom resources.
+	StatusReaders []StatusReader `yaml:"statusReaders,omitempty"`
+
+	// PruneAllowlist lists the high-risk kinds which kpt live apply
+	// prunes without --force or confirmation.
+	PruneAllowlist []ResourceKind `yaml:"pruneAllowlist,omitempty"`
+
+	// PreserveLocal lists the paths of the site-local files of the package,
+	// which kpt pkg update never modifies or deletes.  Paths are relative to
+	// the package and may contain the wildcards of path.Match, and ** to
+	// match any number of directories, e.g. local/** or README.local.md.
+	PreserveLocal []string `yaml:"preserveLocal,omitempty"`
+
+	// Variants declares the named variants of the package generated by
+	// kpt pkg variant, e.g. a variant per team, namespace or cluster.
+	Variants []Variant `yaml:"variants,omitempty"`
+
+	// ReadinessGates are the conditions which must be true for the package
+	// to be ready, e.g. to be applied by kpt live apply.
+	ReadinessGates []ReadinessGate `yaml:"readinessGates,omitempty"`
+
+	// Inputs declares the input parameters of the package.  The value of an
+	// input is the value of the setter of the same name.
+	Inputs []Input `yaml:"inputs,omitempty"`
+
+	// Environments declares the environments the package is hydrated for,
+	// e.g. dev and prod, selected with kpt fn run --environment and
+	// kpt pkg sync --environment.
+	Environments []Environment `yaml:"environments,omitempty"`
+
+	// Status records the conditions of the package observed by kpt.
+	Status *Status `yaml:"status,omitempty"`
+}
+
+// Input is an input parameter of the package.
+type Input struct {
+	// Name is the name of the input, and of the setter holding its value.
+	Name string `yaml:"name,omitempty"`
+
+	// Type is the type of the values of the input.  One of: string, int,
+	// bool, enum.  Defaults to string.
+	Type string `yaml:"type,omitempty"`
+
+	// Enum are the allowed values of enum inputs.
+	Enum []string `yaml:"enum,omitempty"`
+
+	// Required inputs must be set with kpt cfg set unless they have a
+	// default.
+	Required bool `yaml:"required,omitempty"`
+
+	// Default is the value of the input if its setter isn't set.
+	Default string `yaml:"default,omitempty"`
+
+	// Description describes the input to the users of the package.
+	Description string `yaml:"description,omitempty"`
+}
+
+// Types of inputs.
+const (
+	InputString = "string"
+	InputInt    = "int"
+	InputBool   = "bool"
+	InputEnum   = "enum"
+)
+
+// Variant is a named copy of a base package with its own setter values.
+type Variant struct {
+	// Name is the name of the variant, and of its package.
+	Name string `yaml:"name,omitempty"`
+
+	// Directory is the directory the variant is generated to, relative to
+	// the directory containing the base package.  Defaults to
+	// <base package name>-<variant name>.
+	Directory string `yaml:"directory,omitempty"`
+
+	// Setters are the values of the setters of the variant by setter name.
+	Setters map[string]string `yaml:"setters,omitempty"`
+}
+
+// Environment is a named environment the package is hydrated for, with its
+// own setter values and functions.
+type Environment struct {
+	// Name is the name of the environment.
+	Name string `yaml:"name,omitempty"`
+
+	// Setters are the values of the setters in the environment by setter
+	// name, overriding the values of the Kptfile.
+	Setters map[string]string `yaml:"setters,omitempty"`
+
+	// Functions are run in the environment after the functions of the
+	// package.
+	Functions []Function `yaml:"functions,omitempty"`
+}
+
+// GetEnvironment returns the environment of the name, or nil if the
+// Kptfile doesn't declare it.
+func (kf KptFile) GetEnvironment(name string) *Environment {
+	for i := range kf.Environments {
+		if kf.Environments[i].Name == name {
+			return &kf.Environments[i]
+		}
+	}
+	return nil
+}
+
+// ReadinessGate is a condition which must be true for the package to be
+// ready.
+type ReadinessGate struct {
+	// ConditionType is the type of the condition, e.g. SettersResolved.
+	ConditionType string `yaml:"conditionType,omitempty"`
+}
+
+// Status is the status of a package.
+type Status struct {
+	Conditions []StatusCondition `yaml:"conditions,omitempty"`
+}
+
+// Types of the conditions kpt records in the status of packages declaring
+// readiness gates.
+const (
+	// ConditionSettersResolved is true if all the required setters of the
+	// package are set.
+	ConditionSettersResolved = "SettersResolved"
+
+	// ConditionValidated is true if the functions of the package ran
+	// without errors when it was last synced.
+	ConditionValidated = "Validated"
+
+	// ConditionReconciled is true if the resources of the package were
+	// reconciled when it was last applied by kpt live apply.
+	ConditionReconciled = "Reconciled"
+)
+
+// ConditionStatus is the status of a condition.
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// StatusCondition is a condition of a package.
+type StatusCondition struct {
+	Type   string          `yaml:"type,omitempty"`
+	Status ConditionStatus `yaml:"status,omitempty"`
+	// Reason is a CamelCase reason for the status of the condition.
+	Reason string `yaml:"reason,omitempty"`
+	// Message is a human readable description of the status.
+	Message string `yaml:"message,omitempty"`
+}
+
+// GetCondition returns the condition of the type from the status of the
+// package, or nil if the package has no such condition.
+func (kf KptFile) GetCondition(conditionType string) *StatusCondition {
+	if kf.Status == nil {
+		return nil
+	}
+	for i := range kf.Status.Conditions {
+		if kf.Status.Conditions[i].Type == conditionType {
+			return &kf.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition sets the condition in the status of the package, replacing
+// the condition of the same type.
+func (kf *KptFile) SetCondition(c StatusCondition) {
+	if kf.Status == nil {
+		kf.Status = &Status{}
+	}
+	if existing := kf.GetCondition(c.Type); existing != nil {
+		*existing = c
+		return
+	}
+	kf.Status.Conditions = append(kf.Status.Conditions, c)
+}
+
+// UnmetReadinessGates returns the readiness gates of the package whose
+// condition isn't true.
+func (kf KptFile) UnmetReadinessGates() []ReadinessGate {
+	var unmet []ReadinessGate
+	for _, g := range kf.ReadinessGates {
+		if c := kf.GetCondition(g.ConditionType); c == nil || c.Status != ConditionTrue {
+			unmet = append(unmet, g)
+		}
+	}
+	return unmet
 }
 
 // Inventory encapsulates the parameters for the inventory object. All of the
@@ -69,6 +264,32 @@ type Inventory struct {
 	InventoryID string            `yaml:"inventoryID,omitempty"`
 	Labels      map[string]string `yaml:"labels,omitempty"`
 	Annotations map[string]string `yaml:"annotations,omitempty"`
+	// Type is the kind of object storing the inventory in the cluster,
+	// either "resourcegroup" (the default) or "secret".
+	Type string `yaml:"type,omitempty"`
+}
+
+// StatusReader declares the readiness of the resources of a kind with
+// CEL expressions evaluated against the resource.
+type StatusReader struct {
+	// Group is the API group of the resources. Empty for the core group.
+	Group string `yaml:"group,omitempty"`
+	// Kind is the kind of the resources.
+	Kind string `yaml:"kind,omitempty"`
+	// Ready is the expression which is true once a resource is ready,
+	// e.g. status.phase == "Ready".
+	Ready string `yaml:"ready,omitempty"`
+	// Failed is the optional expression which is true if a resource
+	// failed, and will never become ready.
+	Failed string `yaml:"failed,omitempty"`
+}
+
+// ResourceKind identifies a kind of resources.
+type ResourceKind struct {
+	// Group is the API group of the resources. Empty for the core group.
+	Group string `yaml:"group,omitempty"`
+	// Kind is the kind of the resources.
+	Kind string `yaml:"kind,omitempty"`
 }
 
 type Functions struct {
@@ -77,6 +298,62 @@ type Functions struct {
 
 	// StarlarkFunctions is a list of starlark functions to run
 	StarlarkFunctions []StarlarkFunction `yaml:"starlarkFunctions,omitempty"`
+
+	// ExecAllowlist lists the binaries the exec functions declared in the
+	// Kptfile may run, as declared by their path.  Entries may contain the
+	// wildcards of path.Match.  Exec functions are also only run with
+	// --allow-exec.
+	ExecAllowlist []string `yaml:"execAllowlist,omitempty"`
+
+	// ImageDigests records the digests the images of the container
+	// functions of the package resolved to when they were last run by
+	// kpt fn run, to detect when the tags of the images change.
+	ImageDigests []ImageDigest `yaml:"imageDigests,omitempty"`
+
+	// FailFast stops running the functions of a dependency at the first
+	// function failing, or reporting results of error severity.  If false,
+	// all the functions are run and their results reported before failing.
+	// Defaults to true.
+	FailFast *bool `yaml:"failFast,omitempty"`
+
+	// Pipelines are named lists of functions, which the functions of the
+	// dependencies of this and other packages may import.
+	Pipelines []Pipeline `yaml:"pipelines,omitempty"`
+}
+
+// Pipeline is a named list of functions.
+type Pipeline struct {
+	// Name is the name the pipeline is imported by
+	Name string `yaml:"name,omitempty"`
+
+	Functions []Function `yaml:"functions,omitempty"`
+}
+
+// PipelineImport imports the functions of a pipeline declared in the
+// Kptfile of a package, either local or in a git repo, in place of a
+// function.
+type PipelineImport struct {
+	// Pipeline is the name of the pipeline
+	Pipeline string `yaml:"pipeline,omitempty"`
+
+	// Path is the path of the local package declaring the pipeline,
+	// relative to the package importing it.  Defaults to the package
+	// importing it.
+	Path string `yaml:"path,omitempty"`
+
+	// Git is the location of the package declaring the pipeline, if in a
+	// git repo
+	Git *Git `yaml:"git,omitempty"`
+}
+
+// ImageDigest is the digest an image resolved to.
+type ImageDigest struct {
+	// Image is the image as referenced by the function, e.g.
+	// gcr.io/example/fn:v1
+	Image string `yaml:"image,omitempty"`
+
+	// Digest is the digest the image resolved to, e.g. sha256:...
+	Digest string `yaml:"digest,omitempty"`
 }
 
 type StarlarkFunction struct {
@@ -84,6 +361,32 @@ type StarlarkFunction struct {
 	Name string `yaml:"name,omitempty"`
 	// Path is the path to the *.star script to run
 	Path string `yaml:"path,omitempty"`
+	// Source is the inline starlark program to run, instead of a script
+	Source string `yaml:"source,omitempty"`
+}
+
+// FunctionLimits are the resource limits of a function container.
+type FunctionLimits struct {
+	// CPU is the number of CPUs the container may use, e.g. 0.5
+	CPU string `yaml:"cpu,omitempty"`
+
+	// Memory is the memory the container may use, e.g. 512m or 1g
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// Selector matches the resources matching all of its fields which are set.
+type Selector struct {
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Kind       string `yaml:"kind,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	Namespace  string `yaml:"namespace,omitempty"`
+
+	// Labels are the labels of the resources
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Path is the slash separated path of the file of the resources,
+	// relative to the package.  It may contain the wildcards of path.Match.
+	Path string `yaml:"path,omitempty"`
 }
 
 // MergeOpenAPI adds the OpenAPI definitions from localKf to updatedKf.
@@ -297,6 +600,33 @@ type PackageMeta struct {
 
 	// ShortDescription contains a short description of the package.
 	ShortDescription string `yaml:"shortDescription,omitempty"`
+
+	// Maintainers are the people maintaining the package.
+	Maintainers []Maintainer `yaml:"maintainers,omitempty"`
+
+	// Keywords describe the package for search, e.g. in registries the
+	// package is published to.
+	Keywords []string `yaml:"keywords,omitempty"`
+
+	// Documentation are the URLs of the documentation of the package.
+	Documentation []string `yaml:"documentation,omitempty"`
+}
+
+// Maintainer is a maintainer of a package.
+type Maintainer struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+// String returns the maintainer as "Name <email>".
+func (m Maintainer) String() string {
+	switch {
+	case m.Email == "":
+		return m.Name
+	case m.Name == "":
+		return "<" + m.Email + ">"
+	}
+	return m.Name + " <" + m.Email + ">"
 }
 
 // OriginType defines the type of origin for a package
@@ -306,6 +636,10 @@ const (
 	// GitOrigin specifies a package as having been cloned from a git repository
 	GitOrigin   OriginType = "git"
 	StdinOrigin OriginType = "stdin"
+	// OCIOrigin specifies a package as having been pulled from an OCI registry
+	OCIOrigin OriginType = "oci"
+	// HelmOrigin specifies a package as having been inflated from a Helm chart
+	HelmOrigin OriginType = "helm"
 )
 
 // Upstream defines where a package was cloned from
@@ -317,6 +651,12 @@ type Upstream struct {
 	Git Git `yaml:"git,omitempty"`
 
 	Stdin Stdin `yaml:"stdin,omitempty"`
+
+	// OCI contains information on the origin of packages pulled from an OCI registry.
+	OCI OCI `yaml:"oci,omitempty"`
+
+	// Helm contains information on the origin of packages inflated from a Helm chart.
+	Helm Helm `yaml:"helm,omitempty"`
 }
 
 type Stdin struct {
@@ -338,9 +678,135 @@ type Git struct {
 
 	// Ref is the git ref the package was cloned from
 	Ref string `yaml:"ref,omitempty"`
+
+	// Submodules initializes the git submodules of the package directory when
+	// the package is fetched, so their contents are part of the package
+	Submodules bool `yaml:"submodules,omitempty"`
 }
 
+// OCI contains information on the origin of packages pulled from an OCI registry.
+type OCI struct {
+	// Image is the repository of the package in the registry.  e.g. ghcr.io/org/pkg
+	Image string `yaml:"image,omitempty"`
+
+	// Ref is the tag or digest the package was pulled from
+	Ref string `yaml:"ref,omitempty"`
+
+	// Digest is the digest of the manifest that the package was pulled at
+	Digest string `yaml:"digest,omitempty"`
+}
+
+// Helm contains information on the origin of packages inflated from a Helm chart.
+type Helm struct {
+	// Repo is the url of the chart repository.  e.g. https://charts.bitnami.com/bitnami
+	Repo string `yaml:"repo,omitempty"`
+
+	// Chart is the name of the chart in the repository
+	Chart string `yaml:"chart,omitempty"`
+
+	// Version is the version of the chart the package was inflated from
+	Version string `yaml:"version,omitempty"`
+}
+
+// Runtimes of functions.
+const (
+	ContainerRuntime = "container"
+	StarlarkRuntime  = "starlark"
+	ExecRuntime      = "exec"
+	BuiltinRuntime   = "builtin"
+)
+
 type Function struct {
 	Config yaml.Node `yaml:"config,omitempty"`
 	Image  string    `yaml:"image,omitempty"`
+
+	// Runtime is the runtime of the function, container, starlark, exec or
+	// builtin.  Defaults to exec if Exec is set, and to container otherwise.
+	Runtime string `yaml:"runtime,omitempty"`
+
+	// Exec is the local binary run by exec functions.  Exec functions are
+	// only run if allowed, see Functions.ExecAllowlist.
+	Exec *ExecFunction `yaml:"exec,omitempty"`
+
+	// Name is the name that will be given to the starlark program, or the
+	// name of the builtin function to run
+	Name string `yaml:"name,omitempty"`
+
+	// Path is the path to the *.star script to run, relative to the
+	// package the function is run on
+	Path string `yaml:"path,omitempty"`
+
+	// Source is the inline starlark program to run, instead of a script
+	Source string `yaml:"source,omitempty"`
+
+	// Selectors select the resources the function is run on.  A resource
+	// is selected if it matches any of the selectors.  Defaults to all the
+	// resources of the package.
+	Selectors []Selector `yaml:"selectors,omitempty"`
+
+	// Exclude excludes the resources matching any of the selectors from
+	// the resources the function is run on.
+	Exclude []Selector `yaml:"exclude,omitempty"`
+
+	// Network allows the container of the function to access the network
+	Network bool `yaml:"network,omitempty"`
+
+	// Hosts are the only hostnames the container of the function may
+	// resolve with network access, e.g. the host of a schema it fetches.
+	// They are pinned in the hosts file of the container, which has no DNS
+	// server.  Connections to IP addresses aren't restricted.  Defaults to
+	// resolving any hostname.
+	Hosts []string `yaml:"hosts,omitempty"`
+
+	// Limits are the resource limits of the container of the function,
+	// enforced by the container runtime
+	Limits *FunctionLimits `yaml:"limits,omitempty"`
+
+	// Timeout is the duration after which the container of the function is
+	// killed, e.g. 30s or 5m
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// Import imports the functions of a pipeline in place of this function.
+	// The other fields of the function must not be set.
+	Import *PipelineImport `yaml:"import,omitempty"`
+
+	// When is the condition the function is run on.  The function is
+	// skipped, passing the resources through unchanged, if the condition
+	// isn't met.  Defaults to always running the function.
+	When *Condition `yaml:"when,omitempty"`
+
+	// Generator marks the function as a generator creating resources, e.g.
+	// from templates.  The resources it creates are annotated with the
+	// function, and are pruned and generated again each time the functions
+	// are run.
+	Generator bool `yaml:"generator,omitempty"`
+}
+
+// Condition is met if all of its fields which are set are met.
+type Condition struct {
+	// Setter is met if the setter of the package has the value
+	Setter *SetterCondition `yaml:"setter,omitempty"`
+
+	// Exists is met if any of the resources output by the functions before
+	// it matches any of the selectors
+	Exists []Selector `yaml:"exists,omitempty"`
+
+	// NotExists is met if none of the resources output by the functions
+	// before it matches any of the selectors
+	NotExists []Selector `yaml:"notExists,omitempty"`
+}
+
+// SetterCondition is met if the setter has the value.
+type SetterCondition struct {
+	Name  string `yaml:"name,omitempty"`
+	Value string `yaml:"value,omitempty"`
+}
+
+type ExecFunction struct {
+	// Path is the path to the binary relative to the package declaring the
+	// function, or the name of a binary on the PATH
+	Path string `yaml:"path,omitempty"`
+
+	// Args are the arguments the binary is run with
+	Args []string `yaml:"args,omitempty"`
 }
@@ -537,3 +537,23 @@ openAPI:
 		})
 	}
 }
+
+func TestKptFile_UnmetReadinessGates(t *testing.T) {
+	kf := KptFile{ReadinessGates: []ReadinessGate{
+		{ConditionType: ConditionSettersResolved},
+		{ConditionType: ConditionValidated},
+		{ConditionType: ConditionReconciled},
+	}}
+	kf.SetCondition(StatusCondition{Type: ConditionSettersResolved, Status: ConditionTrue})
+	kf.SetCondition(StatusCondition{Type: ConditionValidated, Status: ConditionTrue})
+	kf.SetCondition(StatusCondition{Type: ConditionValidated, Status: ConditionFalse, Reason: "FunctionFailed"})
+
+	assert.Equal(t, []StatusCondition{
+		{Type: ConditionSettersResolved, Status: ConditionTrue},
+		{Type: ConditionValidated, Status: ConditionFalse, Reason: "FunctionFailed"},
+	}, kf.Status.Conditions)
+	assert.Equal(t, []ReadinessGate{
+		{ConditionType: ConditionValidated},
+		{ConditionType: ConditionReconciled},
+	}, kf.UnmetReadinessGates())
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// ActuationRecord records an apply in progress. It is stored before any
+// object is applied, and deleted once the inventory is up to date, so a
+// record found by the next apply means the previous one was interrupted.
+type ActuationRecord struct {
+	// Started is when the apply started.
+	Started time.Time `json:"started"`
+	// Objects are the objects being applied.
+	Objects []object.ObjMetadata `json:"objects"`
+}
+
+// ActuationStore is implemented by the InventoryClients which record
+// the applies in progress, so interrupted applies can be resumed.
+type ActuationStore interface {
+	// LoadActuation returns the actuation record of the inventory, or
+	// nil if there is none.
+	LoadActuation(ctx context.Context, inv inventory.InventoryInfo) (*ActuationRecord, error)
+	// SaveActuation stores the actuation record of the inventory,
+	// replacing the existing one.
+	SaveActuation(ctx context.Context, inv inventory.InventoryInfo, rec ActuationRecord) error
+	// DeleteActuation deletes the actuation record of the inventory. A
+	// missing record is not an error.
+	DeleteActuation(ctx context.Context, inv inventory.InventoryInfo) error
+}
+
+// BeginActuation records that the passed objects are about to be applied
+// to the inventory, if the InventoryClient is an ActuationStore. If the
+// record of an interrupted apply exists, its objects are first merged
+// into the inventory, since they may have been applied without being
+// stored. They are then applied again or pruned, instead of being
+// orphaned. Returns the record of the interrupted apply, or nil.
+func BeginActuation(ctx context.Context, invClient InventoryClient, inv inventory.InventoryInfo,
+	objs []object.ObjMetadata) (*ActuationRecord, error) {
+	store, ok := invClient.(ActuationStore)
+	if !ok {
+		return nil, nil
+	}
+	interrupted, err := store.LoadActuation(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	if interrupted != nil {
+		stored, err := invClient.Load(ctx, inv)
+		if err != nil {
+			return nil, err
+		}
+		merged := map[object.ObjMetadata]bool{}
+		for _, obj := range interrupted.Objects {
+			merged[obj] = true
+		}
+		if err := invClient.Store(ctx, inv, union(stored, merged)); err != nil {
+			return nil, err
+		}
+	}
+	rec := ActuationRecord{Started: time.Now().UTC(), Objects: objs}
+	if err := store.SaveActuation(ctx, inv, rec); err != nil {
+		return nil, err
+	}
+	return interrupted, nil
+}
+
+// EndActuation deletes the actuation record of the inventory, if the
+// InventoryClient is an ActuationStore. It must only be called once the
+// inventory stores all the applied objects.
+func EndActuation(ctx context.Context, invClient InventoryClient, inv inventory.InventoryInfo) error {
+	store, ok := invClient.(ActuationStore)
+	if !ok {
+		return nil
+	}
+	return store.DeleteActuation(ctx, inv)
+}
+
+const (
+	// ActuationInventoryLabel is the label of the actuation record
+	// Secret set to the ID of the inventory.
+	ActuationInventoryLabel = "kpt.dev/actuation-of"
+	// actuationSecretType is the type of the actuation record Secrets.
+	actuationSecretType corev1.SecretType = "kpt.dev/actuation"
+	// actuationDataKey is the key of the actuation record Secret data
+	// holding the JSON encoded record.
+	actuationDataKey = "actuation"
+)
+
+// actuationSecretName returns the name of the Secret storing the
+// actuation record of the inventory.
+func actuationSecretName(inv inventory.InventoryInfo) string {
+	return fmt.Sprintf("%s-actuation", inv.Name())
+}
+
+// loadSecretActuation returns the actuation record of the inventory
+// stored in a Secret in the namespace of the inventory, or nil.
+func loadSecretActuation(ctx context.Context, cs kubernetes.Interface,
+	inv inventory.InventoryInfo) (*ActuationRecord, error) {
+	secret, err := cs.CoreV1().Secrets(inv.Namespace()).Get(ctx, actuationSecretName(inv), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.Labels[ActuationInventoryLabel] != inv.ID() {
+		return nil, nil
+	}
+	var rec ActuationRecord
+	if err := json.Unmarshal(secret.Data[actuationDataKey], &rec); err != nil {
+		return nil, fmt.Errorf("unable to read actuation record Secret %s: %v", secret.Name, err)
+	}
+	return &rec, nil
+}
+
+// saveSecretActuation stores the actuation record of the inventory in a
+// Secret in the namespace of the inventory.
+func saveSecretActuation(ctx context.Context, cs kubernetes.Interface,
+	inv inventory.InventoryInfo, rec ActuationRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      actuationSecretName(inv),
+			Namespace: inv.Namespace(),
+			Labels:    map[string]string{ActuationInventoryLabel: inv.ID()},
+		},
+		Type: actuationSecretType,
+		Data: map[string][]byte{actuationDataKey: data},
+	}
+	secrets := cs.CoreV1().Secrets(inv.Namespace())
+	existing, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteSecretActuation deletes the Secret storing the actuation record
+// of the inventory. A missing Secret is not an error.
+func deleteSecretActuation(ctx context.Context, cs kubernetes.Interface, inv inventory.InventoryInfo) error {
+	err := cs.CoreV1().Secrets(inv.Namespace()).Delete(ctx, actuationSecretName(inv), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestBeginActuation(t *testing.T) {
+	ctx := context.Background()
+	inv := WrapInventoryInfoObj(inventoryObj)
+	a := object.UnstructuredToObjMeta(newPlanObj("a", "a"))
+	b := object.UnstructuredToObjMeta(newPlanObj("b", "a"))
+	invClient := NewMemoryInventoryClient()
+	if !assert.NoError(t, invClient.Store(ctx, inv, []object.ObjMetadata{a})) {
+		t.FailNow()
+	}
+
+	interrupted, err := BeginActuation(ctx, invClient, inv, []object.ObjMetadata{a, b})
+	assert.NoError(t, err)
+	assert.Nil(t, interrupted)
+
+	// The apply is interrupted before b is stored, so the next apply
+	// merges b into the inventory.
+	interrupted, err = BeginActuation(ctx, invClient, inv, []object.ObjMetadata{a})
+	assert.NoError(t, err)
+	if assert.NotNil(t, interrupted) {
+		assert.Equal(t, []object.ObjMetadata{a, b}, interrupted.Objects)
+	}
+	stored, err := invClient.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Equal(t, []object.ObjMetadata{a, b}, stored)
+
+	assert.NoError(t, EndActuation(ctx, invClient, inv))
+	rec, err := invClient.LoadActuation(ctx, inv)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+}
+
+func TestSecretActuation(t *testing.T) {
+	ctx := context.Background()
+	inv := WrapInventoryInfoObj(inventoryObj)
+	cs := fake.NewSimpleClientset()
+
+	rec, err := loadSecretActuation(ctx, cs, inv)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	a := object.UnstructuredToObjMeta(newPlanObj("a", "a"))
+	started := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, objs := range [][]object.ObjMetadata{{a}, nil} {
+		expected := ActuationRecord{Started: started, Objects: objs}
+		if !assert.NoError(t, saveSecretActuation(ctx, cs, inv, expected)) {
+			t.FailNow()
+		}
+		rec, err = loadSecretActuation(ctx, cs, inv)
+		assert.NoError(t, err)
+		if assert.NotNil(t, rec) {
+			assert.Equal(t, expected, *rec)
+		}
+	}
+
+	assert.NoError(t, deleteSecretActuation(ctx, cs, inv))
+	assert.NoError(t, deleteSecretActuation(ctx, cs, inv))
+	rec, err = loadSecretActuation(ctx, cs, inv)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+}
+
+func TestParallelApplier_Resume(t *testing.T) {
+	ctx := context.Background()
+	inv := WrapInventoryInfoObj(inventoryObj)
+	kept := newPlanObj("kept", "a")
+	orphan := newPlanObj("orphan", "a")
+	c := client.NewFakeClient(orphan)
+	invClient := NewMemoryInventoryClient()
+	// The interrupted apply applied orphan without storing it, and the
+	// package no longer contains it.
+	err := invClient.SaveActuation(ctx, inv, ActuationRecord{
+		Objects: []object.ObjMetadata{object.UnstructuredToObjMeta(orphan)},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var resumed []ActuationRecord
+	a := &ParallelApplier{
+		Client:          c,
+		InventoryClient: invClient,
+		Resumed:         func(rec ActuationRecord) { resumed = append(resumed, rec) },
+	}
+	results, err := a.Run(ctx, inv, []*unstructured.Unstructured{inventoryObj, kept})
+	assert.NoError(t, err)
+	assert.Len(t, resumed, 1)
+	assert.Equal(t, []ActuationResult{
+		{Object: object.UnstructuredToObjMeta(kept), Operation: OperationApplied},
+		{Object: object.UnstructuredToObjMeta(orphan), Operation: OperationPruned},
+	}, results)
+
+	stored, err := invClient.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Equal(t, []object.ObjMetadata{object.UnstructuredToObjMeta(kept)}, stored)
+	rec, err := invClient.LoadActuation(ctx, inv)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+}
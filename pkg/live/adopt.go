@@ -0,0 +1,263 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// AdoptStatus is the outcome of adopting a single object.
+type AdoptStatus string
+
+const (
+	// Adopted means the owning inventory of the object was set to the
+	// adopting inventory.
+	Adopted AdoptStatus = "Adopted"
+	// AlreadyOwned means the object was already owned by the adopting
+	// inventory.
+	AlreadyOwned AdoptStatus = "AlreadyOwned"
+	// NotFound means the object does not exist in the cluster yet, and
+	// will be created by apply.
+	NotFound AdoptStatus = "NotFound"
+	// OwnedByOther means the object is owned by another inventory, and
+	// was not adopted.
+	OwnedByOther AdoptStatus = "OwnedByOther"
+)
+
+// AdoptResult is the outcome of adopting a single object. Owner is the
+// inventory owning the object before it was adopted, if any.
+type AdoptResult struct {
+	Object object.ObjMetadata
+	Status AdoptStatus
+	Owner  string
+}
+
+// AdoptError is returned when objects are owned by other inventories.
+type AdoptError struct {
+	Results []AdoptResult
+}
+
+func (e AdoptError) Error() string {
+	var objs []string
+	for _, r := range e.Results {
+		objs = append(objs, fmt.Sprintf("%s %s/%s (owned by %q)", r.Object.GroupKind.Kind,
+			r.Object.Namespace, r.Object.Name, r.Owner))
+	}
+	return fmt.Sprintf("objects are owned by other inventories: %s", strings.Join(objs, ", "))
+}
+
+// Adopt sets the owning inventory annotation of the passed objects which
+// exist in the cluster, but do not have the annotation, to the ID of the
+// passed inventory. Objects owned by another inventory are adopted only
+// if force is true. Inventory objects are skipped. Returns the outcome
+// for every object, and an AdoptError if objects were owned by other
+// inventories and not adopted.
+func Adopt(ctx context.Context, c client.Client, inv inventory.InventoryInfo,
+	objs []*unstructured.Unstructured, force bool) ([]AdoptResult, error) {
+	var results []AdoptResult
+	var conflicts []AdoptResult
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) {
+			continue
+		}
+		result := AdoptResult{Object: object.UnstructuredToObjMeta(obj)}
+		err := c.UpdateWithRetry(ctx, result.Object, func(liveObj *unstructured.Unstructured) (bool, error) {
+			owner, found := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys)
+			result.Owner = owner
+			switch {
+			case found && owner == inv.ID():
+				result.Status = AlreadyOwned
+				return false, nil
+			case found && owner != "" && !force:
+				result.Status = OwnedByOther
+				return false, nil
+			}
+			result.Status = Adopted
+			return client.UpdateAnnotation(liveObj, owner, inv.ID())
+		}, nil)
+		switch {
+		case apierrors.IsNotFound(err):
+			result.Status = NotFound
+		case err != nil:
+			return results, err
+		}
+		if result.Status == OwnedByOther {
+			conflicts = append(conflicts, result)
+		}
+		results = append(results, result)
+	}
+	if len(conflicts) > 0 {
+		return results, AdoptError{Results: conflicts}
+	}
+	return results, nil
+}
+
+// AdoptedObjs returns the objects which were adopted or already owned
+// by the inventory, which are the ones to store in the inventory.
+func AdoptedObjs(results []AdoptResult) []object.ObjMetadata {
+	var objs []object.ObjMetadata
+	for _, r := range results {
+		if r.Status == Adopted || r.Status == AlreadyOwned {
+			objs = append(objs, r.Object)
+		}
+	}
+	return objs
+}
+
+// OwnershipPolicy determines what apply does with objects of the package
+// which are owned by another inventory.
+type OwnershipPolicy string
+
+const (
+	// OwnershipFail fails the apply, listing the conflicting objects.
+	OwnershipFail OwnershipPolicy = "fail"
+	// OwnershipSkip leaves the conflicting objects untouched, and
+	// applies the other objects.
+	OwnershipSkip OwnershipPolicy = "skip"
+	// OwnershipAdoptIfAbandoned adopts the conflicting objects whose
+	// owning inventory no longer exists in the cluster, and fails for
+	// the others.
+	OwnershipAdoptIfAbandoned OwnershipPolicy = "adopt-if-abandoned"
+	// OwnershipForceAdopt adopts all conflicting objects.
+	OwnershipForceAdopt OwnershipPolicy = "force-adopt"
+)
+
+// OwnershipPolicies are the supported ownership policies.
+var OwnershipPolicies = []OwnershipPolicy{OwnershipFail, OwnershipSkip,
+	OwnershipAdoptIfAbandoned, OwnershipForceAdopt}
+
+// ParseOwnershipPolicy returns the ownership policy with the passed
+// name, or an error if it is not supported.
+func ParseOwnershipPolicy(name string) (OwnershipPolicy, error) {
+	var names []string
+	for _, policy := range OwnershipPolicies {
+		if string(policy) == name {
+			return policy, nil
+		}
+		names = append(names, string(policy))
+	}
+	return "", fmt.Errorf("unknown ownership policy %q; must be one of %s",
+		name, strings.Join(names, ", "))
+}
+
+// InventoryExistsFunc returns true if an inventory object with the
+// passed ID exists in the cluster.
+type InventoryExistsFunc func(ctx context.Context, id string) (bool, error)
+
+// inventoryGVRs are the resources inventory objects are stored in.
+var inventoryGVRs = []schema.GroupVersionResource{
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "secrets"},
+	{Group: ResourceGroupGVK.Group, Version: ResourceGroupGVK.Version, Resource: "resourcegroups"},
+}
+
+// NewInventoryExistsFunc returns an InventoryExistsFunc which looks up
+// ConfigMap, Secret and ResourceGroup inventory objects by their
+// inventory ID label, in all namespaces.
+func NewInventoryExistsFunc(d dynamic.Interface) InventoryExistsFunc {
+	return func(ctx context.Context, id string) (bool, error) {
+		opts := metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", common.InventoryLabel, id),
+			Limit:         1,
+		}
+		for _, gvr := range inventoryGVRs {
+			list, err := d.Resource(gvr).List(ctx, opts)
+			if apierrors.IsNotFound(err) {
+				// The ResourceGroup CRD is not installed.
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			if len(list.Items) > 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// ResolveOwnership finds the passed objects which exist in the cluster
+// and are owned by another inventory, and applies the ownership policy
+// to them. Conflicting objects are adopted, or reported as OwnedByOther
+// if they are not. Returns the outcome for every conflicting object, and
+// an AdoptError if the policy requires the apply to fail. The exists
+// func is only used by the adopt-if-abandoned policy.
+func ResolveOwnership(ctx context.Context, c client.Client, inv inventory.InventoryInfo,
+	objs []*unstructured.Unstructured, policy OwnershipPolicy, exists InventoryExistsFunc) ([]AdoptResult, error) {
+	var results []AdoptResult
+	var conflicts []AdoptResult
+	abandoned := map[string]bool{}
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) {
+			continue
+		}
+		objMeta := object.UnstructuredToObjMeta(obj)
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+		owner, found := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys)
+		if !found || owner == "" || owner == inv.ID() {
+			continue
+		}
+		result := AdoptResult{Object: objMeta, Status: OwnedByOther, Owner: owner}
+		adopt := policy == OwnershipForceAdopt
+		if policy == OwnershipAdoptIfAbandoned {
+			isAbandoned, checked := abandoned[owner]
+			if !checked {
+				ownerExists, err := exists(ctx, owner)
+				if err != nil {
+					return results, err
+				}
+				isAbandoned = !ownerExists
+				abandoned[owner] = isAbandoned
+			}
+			adopt = isAbandoned
+		}
+		if adopt {
+			err := c.UpdateWithRetry(ctx, objMeta, func(liveObj *unstructured.Unstructured) (bool, error) {
+				current, _ := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys)
+				return client.UpdateAnnotation(liveObj, current, inv.ID())
+			}, nil)
+			if err != nil {
+				return results, err
+			}
+			result.Status = Adopted
+		} else if policy != OwnershipSkip {
+			conflicts = append(conflicts, result)
+		}
+		results = append(results, result)
+	}
+	if len(conflicts) > 0 {
+		return results, AdoptError{Results: conflicts}
+	}
+	return results, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// withOwner returns a copy of the object owned by the inventory.
+func withOwner(obj *unstructured.Unstructured, owner string) *unstructured.Unstructured {
+	owned := obj.DeepCopy()
+	owned.SetAnnotations(map[string]string{client.OwningInventoryAnnotation: owner})
+	return owned
+}
+
+func TestAdopt(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	unowned := newPlanObj("unowned", "a")
+	owned := newPlanObj("owned", "a")
+	other := newPlanObj("other", "a")
+	missing := newPlanObj("missing", "a")
+	objs := []*unstructured.Unstructured{inventoryObj, unowned, owned, other, missing}
+
+	testCases := map[string]struct {
+		force    bool
+		expected []AdoptStatus
+		owners   map[string]string
+		isError  bool
+	}{
+		"Objects owned by other inventories are not adopted": {
+			force:    false,
+			expected: []AdoptStatus{Adopted, AlreadyOwned, OwnedByOther, NotFound},
+			owners:   map[string]string{"unowned": inv.ID(), "owned": inv.ID(), "other": "other-inv"},
+			isError:  true,
+		},
+		"Objects owned by other inventories are adopted with force": {
+			force:    true,
+			expected: []AdoptStatus{Adopted, AlreadyOwned, Adopted, NotFound},
+			owners:   map[string]string{"unowned": inv.ID(), "owned": inv.ID(), "other": inv.ID()},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			c := client.NewFakeClient(unowned, withOwner(owned, inv.ID()), withOwner(other, "other-inv"))
+			results, err := Adopt(context.Background(), c, inv, objs, tc.force)
+			if tc.isError {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), "other-inv")
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			var actual []AdoptStatus
+			for _, r := range results {
+				actual = append(actual, r.Status)
+			}
+			assert.Equal(t, tc.expected, actual)
+
+			for name, owner := range tc.owners {
+				obj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(newPlanObj(name, "a")))
+				assert.NoError(t, err)
+				actual, _ := client.OwningInventory(obj, client.DefaultOwningInventoryKeys)
+				assert.Equal(t, owner, actual, name)
+			}
+		})
+	}
+}
+
+func TestAdoptedObjs(t *testing.T) {
+	results := []AdoptResult{
+		{Object: testDeployment, Status: Adopted},
+		{Object: testPod, Status: NotFound},
+		{Object: testService, Status: AlreadyOwned},
+	}
+	assert.Equal(t, []object.ObjMetadata{testDeployment, testService}, AdoptedObjs(results))
+}
+
+func TestParseOwnershipPolicy(t *testing.T) {
+	for _, policy := range OwnershipPolicies {
+		actual, err := ParseOwnershipPolicy(string(policy))
+		assert.NoError(t, err)
+		assert.Equal(t, policy, actual)
+	}
+	_, err := ParseOwnershipPolicy("adopt")
+	assert.Error(t, err)
+}
+
+func TestResolveOwnership(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	unowned := newPlanObj("unowned", "a")
+	owned := newPlanObj("owned", "a")
+	abandoned := newPlanObj("abandoned", "a")
+	other := newPlanObj("other", "a")
+	objs := []*unstructured.Unstructured{inventoryObj, unowned, owned, abandoned, other,
+		newPlanObj("missing", "a")}
+	exists := func(_ context.Context, id string) (bool, error) {
+		return id == "other-inv", nil
+	}
+
+	testCases := map[string]struct {
+		policy   OwnershipPolicy
+		expected map[string]AdoptStatus
+		owners   map[string]string
+		isError  bool
+	}{
+		"fail": {
+			policy:   OwnershipFail,
+			expected: map[string]AdoptStatus{"abandoned": OwnedByOther, "other": OwnedByOther},
+			owners:   map[string]string{"abandoned": "abandoned-inv", "other": "other-inv"},
+			isError:  true,
+		},
+		"skip": {
+			policy:   OwnershipSkip,
+			expected: map[string]AdoptStatus{"abandoned": OwnedByOther, "other": OwnedByOther},
+			owners:   map[string]string{"abandoned": "abandoned-inv", "other": "other-inv"},
+		},
+		"adopt-if-abandoned": {
+			policy:   OwnershipAdoptIfAbandoned,
+			expected: map[string]AdoptStatus{"abandoned": Adopted, "other": OwnedByOther},
+			owners:   map[string]string{"abandoned": inv.ID(), "other": "other-inv"},
+			isError:  true,
+		},
+		"force-adopt": {
+			policy:   OwnershipForceAdopt,
+			expected: map[string]AdoptStatus{"abandoned": Adopted, "other": Adopted},
+			owners:   map[string]string{"abandoned": inv.ID(), "other": inv.ID()},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			c := client.NewFakeClient(unowned, withOwner(owned, inv.ID()),
+				withOwner(abandoned, "abandoned-inv"), withOwner(other, "other-inv"))
+			results, err := ResolveOwnership(context.Background(), c, inv, objs, tc.policy, exists)
+			if tc.isError {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), "other-inv")
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			actual := map[string]AdoptStatus{}
+			for _, r := range results {
+				actual[r.Object.Name] = r.Status
+			}
+			assert.Equal(t, tc.expected, actual)
+
+			for name, owner := range tc.owners {
+				obj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(newPlanObj(name, "a")))
+				assert.NoError(t, err)
+				actual, _ := client.OwningInventory(obj, client.DefaultOwningInventoryKeys)
+				assert.Equal(t, owner, actual, name)
+			}
+			// Unowned objects are left to apply.
+			obj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(unowned))
+			assert.NoError(t, err)
+			_, found := client.OwningInventory(obj, client.DefaultOwningInventoryKeys)
+			assert.False(t, found)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ApplyTimeMutationAnnotation lists the fields of an object which are
+// set from fields of other live objects, e.g. their status, just before
+// the object is applied. The value is a YAML list of FieldSubstitutions.
+const ApplyTimeMutationAnnotation = "config.kubernetes.io/apply-time-mutation"
+
+// Names of the apply-time setters resolved from the cluster context.
+const (
+	// ClusterNameSetter is the name of the cluster of the context.
+	ClusterNameSetter = "cluster-name"
+	// ContextSetter is the name of the context.
+	ContextSetter = "context"
+)
+
+// SourceReference identifies the live object a field is read from. The
+// namespace defaults to the namespace of the mutated object.
+type SourceReference struct {
+	Group     string `yaml:"group,omitempty"`
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// FieldSubstitution sets the field at TargetPath of an object to the
+// field at SourcePath of the live source object. If Token is set, only
+// the token is replaced in the string at TargetPath. Paths are field
+// names separated by dots, with list indexes in brackets, e.g.
+// "status.loadBalancer.ingress[0].ip".
+type FieldSubstitution struct {
+	SourceRef  SourceReference `yaml:"sourceRef"`
+	SourcePath string          `yaml:"sourcePath"`
+	TargetPath string          `yaml:"targetPath"`
+	Token      string          `yaml:"token,omitempty"`
+}
+
+// ApplyTimeSubstitution resolves apply-time setters and mutations of the
+// objects of a package.
+type ApplyTimeSubstitution struct {
+	// Setters maps setter names to their values. Every "${name}" in a
+	// string field of an object is replaced with the value of the setter.
+	Setters map[string]string
+	// Client reads the source objects of apply-time mutations.
+	Client client.Client
+}
+
+// ClusterSetters returns the apply-time setters for the passed context of
+// the kubeconfig, or its current context if empty.
+func ClusterSetters(config clientcmdapi.Config, contextName string) map[string]string {
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	setters := map[string]string{ContextSetter: contextName}
+	if c, found := config.Contexts[contextName]; found {
+		setters[ClusterNameSetter] = c.Cluster
+	}
+	return setters
+}
+
+// Substitute returns copies of the passed objects with the setters
+// replaced and the mutations applied. Inventory objects are returned
+// unchanged. Returns an error if a mutation is invalid, or its source
+// object or field does not exist.
+func (s *ApplyTimeSubstitution) Substitute(ctx context.Context,
+	objs []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	var substituted []*unstructured.Unstructured
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) {
+			substituted = append(substituted, obj)
+			continue
+		}
+		obj = obj.DeepCopy()
+		if len(s.Setters) > 0 {
+			obj.Object = replaceSetters(obj.Object, s.Setters).(map[string]interface{})
+		}
+		if err := s.mutate(ctx, obj); err != nil {
+			return nil, fmt.Errorf("apply-time mutation of %s/%s failed: %v",
+				obj.GetNamespace(), obj.GetName(), err)
+		}
+		substituted = append(substituted, obj)
+	}
+	return substituted, nil
+}
+
+// mutate applies the apply-time mutations of the object.
+func (s *ApplyTimeSubstitution) mutate(ctx context.Context, obj *unstructured.Unstructured) error {
+	subs, err := ReadFieldSubstitutions(obj)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	if s.Client == nil {
+		return fmt.Errorf("no client to read source objects")
+	}
+	for _, sub := range subs {
+		namespace := sub.SourceRef.Namespace
+		if namespace == "" {
+			namespace = obj.GetNamespace()
+		}
+		id, err := object.CreateObjMetadata(namespace, sub.SourceRef.Name,
+			schema.GroupKind{Group: sub.SourceRef.Group, Kind: sub.SourceRef.Kind})
+		if err != nil {
+			return err
+		}
+		source, err := s.Client.Get(ctx, id)
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("source %s %s/%s not found", id.GroupKind, namespace, id.Name)
+		}
+		if err != nil {
+			return err
+		}
+		value, found, err := getFieldPath(source.Object, sub.SourcePath)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("field %s of source %s %s/%s not found", sub.SourcePath, id.GroupKind,
+				namespace, id.Name)
+		}
+		if sub.Token != "" {
+			target, found, err := getFieldPath(obj.Object, sub.TargetPath)
+			if err != nil {
+				return err
+			}
+			str, ok := target.(string)
+			if !found || !ok {
+				return fmt.Errorf("field %s must be a string containing %s", sub.TargetPath, sub.Token)
+			}
+			value = strings.Replace(str, sub.Token, fmt.Sprint(value), -1)
+		}
+		if err := setFieldPath(obj.Object, sub.TargetPath, runtime.DeepCopyJSONValue(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFieldSubstitutions returns the apply-time mutations of the object,
+// or an error if the annotation is invalid.
+func ReadFieldSubstitutions(obj *unstructured.Unstructured) ([]FieldSubstitution, error) {
+	value, found := obj.GetAnnotations()[ApplyTimeMutationAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var subs []FieldSubstitution
+	if err := yaml.Unmarshal([]byte(value), &subs); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", ApplyTimeMutationAnnotation, err)
+	}
+	for _, sub := range subs {
+		if sub.SourceRef.Kind == "" || sub.SourceRef.Name == "" || sub.SourcePath == "" || sub.TargetPath == "" {
+			return nil, fmt.Errorf("invalid %s annotation: sourceRef kind and name, sourcePath "+
+				"and targetPath are required", ApplyTimeMutationAnnotation)
+		}
+	}
+	return subs, nil
+}
+
+// replaceSetters replaces the setters in all strings of the value.
+func replaceSetters(value interface{}, setters map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		for name, setter := range setters {
+			v = strings.Replace(v, "${"+name+"}", setter, -1)
+		}
+		return v
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = replaceSetters(field, setters)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceSetters(item, setters)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// parseFieldPath splits the path into field names and list indexes.
+func parseFieldPath(path string) ([]interface{}, error) {
+	var elements []interface{}
+	for _, field := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		name := field
+		var indexes []interface{}
+		if i := strings.Index(field, "["); i >= 0 {
+			name = field[:i]
+			for _, index := range strings.Split(strings.TrimSuffix(field[i+1:], "]"), "][") {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid field path %q", path)
+				}
+				indexes = append(indexes, n)
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid field path %q", path)
+		}
+		elements = append(elements, name)
+		elements = append(elements, indexes...)
+	}
+	return elements, nil
+}
+
+// getFieldPath returns the value at the path in the object, and whether
+// it was found.
+func getFieldPath(obj map[string]interface{}, path string) (interface{}, bool, error) {
+	elements, err := parseFieldPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var current interface{} = obj
+	for _, element := range elements {
+		switch e := element.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			if current, ok = m[e]; !ok {
+				return nil, false, nil
+			}
+		case int:
+			l, ok := current.([]interface{})
+			if !ok || e >= len(l) {
+				return nil, false, nil
+			}
+			current = l[e]
+		}
+	}
+	return current, true, nil
+}
+
+// setFieldPath sets the value at the path in the object. Missing fields
+// are created, but lists must already have the indexed item.
+func setFieldPath(obj map[string]interface{}, path string, value interface{}) error {
+	elements, err := parseFieldPath(path)
+	if err != nil {
+		return err
+	}
+	var current interface{} = obj
+	for i, element := range elements {
+		last := i == len(elements)-1
+		switch e := element.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("field path %s does not match the object", path)
+			}
+			if last {
+				m[e] = value
+				return nil
+			}
+			if _, found := m[e]; !found {
+				if _, isIndex := elements[i+1].(int); isIndex {
+					return fmt.Errorf("list of field path %s not found", path)
+				}
+				m[e] = map[string]interface{}{}
+			}
+			current = m[e]
+		case int:
+			l, ok := current.([]interface{})
+			if !ok || e >= len(l) {
+				return fmt.Errorf("list item of field path %s not found", path)
+			}
+			if last {
+				l[e] = value
+				return nil
+			}
+			current = l[e]
+		}
+	}
+	return nil
+}
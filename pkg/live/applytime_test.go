@@ -0,0 +1,152 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestClusterSetters(t *testing.T) {
+	config := clientcmdapi.Config{
+		CurrentContext: "dev",
+		Contexts: map[string]*clientcmdapi.Context{
+			"dev":  {Cluster: "dev-cluster"},
+			"prod": {Cluster: "prod-cluster"},
+		},
+	}
+	assert.Equal(t, map[string]string{ContextSetter: "dev", ClusterNameSetter: "dev-cluster"},
+		ClusterSetters(config, ""))
+	assert.Equal(t, map[string]string{ContextSetter: "prod", ClusterNameSetter: "prod-cluster"},
+		ClusterSetters(config, "prod"))
+}
+
+// withMutation returns the object with the apply-time mutation
+// annotation set to the passed value.
+func withMutation(obj *unstructured.Unstructured, mutation string) *unstructured.Unstructured {
+	obj.SetAnnotations(map[string]string{ApplyTimeMutationAnnotation: mutation})
+	return obj
+}
+
+func TestApplyTimeSubstitution(t *testing.T) {
+	db := newDatabase("db", "Ready")
+	db.Object["status"].(map[string]interface{})["endpoints"] = []interface{}{
+		map[string]interface{}{"host": "10.0.0.1", "port": int64(5432)},
+	}
+	c := client.NewFakeClient(db)
+
+	testCases := map[string]struct {
+		obj      *unstructured.Unstructured
+		path     string
+		expected interface{}
+		isError  bool
+	}{
+		"Setters are replaced in all strings": {
+			obj:      newPlanObj("app", "deployed to ${cluster-name} with ${unknown}"),
+			path:     "data.key",
+			expected: "deployed to dev-cluster with ${unknown}",
+		},
+		"Field is set from the source": {
+			obj: withMutation(newPlanObj("app", ""), `
+- sourceRef:
+    group: example.com
+    kind: Database
+    name: db
+  sourcePath: status.endpoints[0].port
+  targetPath: data.port
+`),
+			path:     "data.port",
+			expected: int64(5432),
+		},
+		"Token is replaced in the target string": {
+			obj: withMutation(newPlanObj("app", "postgres://${db-host}:5432"), `
+- sourceRef:
+    group: example.com
+    kind: Database
+    name: db
+  sourcePath: $.status.endpoints[0].host
+  targetPath: data.key
+  token: ${db-host}
+`),
+			path:     "data.key",
+			expected: "postgres://10.0.0.1:5432",
+		},
+		"Missing source is an error": {
+			obj: withMutation(newPlanObj("app", ""), `
+- sourceRef:
+    group: example.com
+    kind: Database
+    name: missing
+  sourcePath: status.phase
+  targetPath: data.key
+`),
+			isError: true,
+		},
+		"Missing source field is an error": {
+			obj: withMutation(newPlanObj("app", ""), `
+- sourceRef:
+    group: example.com
+    kind: Database
+    name: db
+  sourcePath: status.endpoints[1].host
+  targetPath: data.key
+`),
+			isError: true,
+		},
+		"Incomplete mutation is an error": {
+			obj:     withMutation(newPlanObj("app", ""), "- sourcePath: status.phase\n"),
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			s := &ApplyTimeSubstitution{
+				Setters: map[string]string{ClusterNameSetter: "dev-cluster"},
+				Client:  c,
+			}
+			objs, err := s.Substitute(context.Background(), []*unstructured.Unstructured{inventoryObj, tc.obj})
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) || !assert.Len(t, objs, 2) {
+				t.FailNow()
+			}
+			assert.Equal(t, inventoryObj, objs[0])
+			value, found, err := getFieldPath(objs[1].Object, tc.path)
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, tc.expected, value)
+			assert.NotEqual(t, tc.obj, objs[1], "the passed object must not be changed")
+		})
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	elements, err := parseFieldPath("$.spec.containers[0].ports[1][2].name")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"spec", "containers", 0, "ports", 1, 2, "name"}, elements)
+
+	for _, path := range []string{"spec..name", "spec.containers[a]", "[0]"} {
+		_, err := parseFieldPath(path)
+		assert.Error(t, err, path)
+	}
+}
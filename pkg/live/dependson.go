@@ -0,0 +1,190 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// DependsOnAnnotation lists the objects which must be applied before
+// the annotated object, separated by commas. Each object is referenced
+// as "<group>/namespaces/<namespace>/<kind>/<name>", or as
+// "<group>/<kind>/<name>" for cluster-scoped objects. The group is
+// empty for the core group.
+const DependsOnAnnotation = "config.kubernetes.io/depends-on"
+
+// CyclicDependencyError is returned when the depends-on annotations
+// of a set of objects form a cycle.
+type CyclicDependencyError struct {
+	Objs []object.ObjMetadata
+}
+
+func (e CyclicDependencyError) Error() string {
+	var names []string
+	for _, obj := range e.Objs {
+		names = append(names, formatDependency(obj))
+	}
+	return fmt.Sprintf("cyclic dependency between objects: %s", strings.Join(names, ", "))
+}
+
+// ParseDependsOn returns the objects the passed object depends on,
+// as listed in its depends-on annotation.
+func ParseDependsOn(obj *unstructured.Unstructured) ([]object.ObjMetadata, error) {
+	value, found := obj.GetAnnotations()[DependsOnAnnotation]
+	if !found || strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var deps []object.ObjMetadata
+	for _, ref := range strings.Split(value, ",") {
+		dep, err := parseDependency(strings.TrimSpace(ref))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation on %s/%s: %v",
+				DependsOnAnnotation, obj.GetNamespace(), obj.GetName(), err)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseDependency parses a single object reference of the depends-on
+// annotation.
+func parseDependency(ref string) (object.ObjMetadata, error) {
+	parts := strings.Split(ref, "/")
+	switch {
+	case len(parts) == 3:
+		return object.CreateObjMetadata("", parts[2],
+			schema.GroupKind{Group: parts[0], Kind: parts[1]})
+	case len(parts) == 5 && parts[1] == "namespaces":
+		return object.CreateObjMetadata(parts[2], parts[4],
+			schema.GroupKind{Group: parts[0], Kind: parts[3]})
+	default:
+		return object.ObjMetadata{}, fmt.Errorf("expected %q or %q, got %q",
+			"<group>/namespaces/<namespace>/<kind>/<name>", "<group>/<kind>/<name>", ref)
+	}
+}
+
+// formatDependency returns the depends-on reference of the object.
+func formatDependency(obj object.ObjMetadata) string {
+	if obj.Namespace == "" {
+		return fmt.Sprintf("%s/%s/%s", obj.GroupKind.Group, obj.GroupKind.Kind, obj.Name)
+	}
+	return fmt.Sprintf("%s/namespaces/%s/%s/%s", obj.GroupKind.Group,
+		obj.Namespace, obj.GroupKind.Kind, obj.Name)
+}
+
+// SortByDependencies groups the passed objects so every object comes
+// in a later group than the objects it depends on. Objects in the same
+// group do not depend on each other, and keep their relative order.
+// Dependencies on objects outside the passed set are expected to
+// already exist, and do not affect the order. Returns an error if an
+// annotation is invalid, or the dependencies form a cycle.
+func SortByDependencies(objs []*unstructured.Unstructured) ([][]*unstructured.Unstructured, error) {
+	index := map[object.ObjMetadata]int{}
+	for i, obj := range objs {
+		index[object.UnstructuredToObjMeta(obj)] = i
+	}
+	// dependents maps each object to the objects depending on it, and
+	// remaining counts the unapplied dependencies of each object.
+	dependents := make([][]int, len(objs))
+	remaining := make([]int, len(objs))
+	for i, obj := range objs {
+		deps, err := ParseDependsOn(obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			j, found := index[dep]
+			if !found || j == i {
+				continue
+			}
+			dependents[j] = append(dependents[j], i)
+			remaining[i]++
+		}
+	}
+	var groups [][]*unstructured.Unstructured
+	var next []int
+	for i := range objs {
+		if remaining[i] == 0 {
+			next = append(next, i)
+		}
+	}
+	sorted := 0
+	for len(next) > 0 {
+		current := next
+		next = nil
+		var group []*unstructured.Unstructured
+		for _, i := range current {
+			group = append(group, objs[i])
+			for _, d := range dependents[i] {
+				remaining[d]--
+				if remaining[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		sort.Ints(next)
+		groups = append(groups, group)
+		sorted += len(group)
+	}
+	if sorted != len(objs) {
+		var cycle []object.ObjMetadata
+		for i, obj := range objs {
+			if remaining[i] > 0 {
+				cycle = append(cycle, object.UnstructuredToObjMeta(obj))
+			}
+		}
+		return nil, CyclicDependencyError{Objs: cycle}
+	}
+	return groups, nil
+}
+
+// FilterByGroup returns the objects in the dependency groups computed by
+// SortByDependencies up to and including maxGroup, sorted by their
+// dependencies.
+func FilterByGroup(objs []*unstructured.Unstructured, maxGroup int) ([]*unstructured.Unstructured, error) {
+	groups, err := SortByDependencies(objs)
+	if err != nil {
+		return nil, err
+	}
+	if maxGroup+1 < len(groups) {
+		groups = groups[:maxGroup+1]
+	}
+	return flattenGroups(groups), nil
+}
+
+// ReverseGroups returns the passed groups in reverse order, which is
+// the order objects are pruned or deleted in.
+func ReverseGroups(groups [][]*unstructured.Unstructured) [][]*unstructured.Unstructured {
+	reversed := make([][]*unstructured.Unstructured, 0, len(groups))
+	for i := len(groups) - 1; i >= 0; i-- {
+		reversed = append(reversed, groups[i])
+	}
+	return reversed
+}
+
+// flattenGroups concatenates the passed groups.
+func flattenGroups(groups [][]*unstructured.Unstructured) []*unstructured.Unstructured {
+	var objs []*unstructured.Unstructured
+	for _, group := range groups {
+		objs = append(objs, group...)
+	}
+	return objs
+}
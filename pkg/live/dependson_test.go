@@ -0,0 +1,133 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func newDependsOnObj(apiVersion, kind, namespace, name, dependsOn string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+		},
+	}
+	if namespace != "" {
+		obj.SetNamespace(namespace)
+	}
+	if dependsOn != "" {
+		obj.SetAnnotations(map[string]string{DependsOnAnnotation: dependsOn})
+	}
+	return obj
+}
+
+func TestParseDependsOn(t *testing.T) {
+	testCases := map[string]struct {
+		dependsOn string
+		expected  []object.ObjMetadata
+		isError   bool
+	}{
+		"No annotation": {
+			dependsOn: "",
+			expected:  nil,
+		},
+		"Namespaced and cluster-scoped references": {
+			dependsOn: "apps/namespaces/test/Deployment/foo, /Namespace/test",
+			expected: []object.ObjMetadata{
+				{
+					Namespace: "test",
+					Name:      "foo",
+					GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+				},
+				{
+					Name:      "test",
+					GroupKind: schema.GroupKind{Kind: "Namespace"},
+				},
+			},
+		},
+		"Invalid reference is an error": {
+			dependsOn: "apps/Deployment",
+			isError:   true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			obj := newDependsOnObj("v1", "ConfigMap", "test", "cm", tc.dependsOn)
+			deps, err := ParseDependsOn(obj)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, deps)
+		})
+	}
+}
+
+func TestSortByDependencies(t *testing.T) {
+	ns := newDependsOnObj("v1", "Namespace", "", "test", "")
+	crd := newDependsOnObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com", "")
+	cm := newDependsOnObj("v1", "ConfigMap", "test", "cm", "/Namespace/test")
+	foo := newDependsOnObj("example.com/v1", "Foo", "test", "foo",
+		"apiextensions.k8s.io/CustomResourceDefinition/foos.example.com,/namespaces/test/ConfigMap/cm")
+	external := newDependsOnObj("v1", "Secret", "test", "secret", "/Namespace/other")
+
+	groups, err := SortByDependencies([]*unstructured.Unstructured{foo, cm, external, crd, ns})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, [][]*unstructured.Unstructured{
+		{external, crd, ns},
+		{cm},
+		{foo},
+	}, groups)
+	assert.Equal(t, [][]*unstructured.Unstructured{
+		{foo},
+		{cm},
+		{external, crd, ns},
+	}, ReverseGroups(groups))
+	assert.Equal(t, []*unstructured.Unstructured{external, crd, ns, cm, foo}, flattenGroups(groups))
+
+	filtered, err := FilterByGroup([]*unstructured.Unstructured{foo, cm, external, crd, ns}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*unstructured.Unstructured{external, crd, ns, cm}, filtered)
+}
+
+func TestSortByDependencies_Cycle(t *testing.T) {
+	a := newDependsOnObj("v1", "ConfigMap", "test", "a", "/namespaces/test/ConfigMap/b")
+	b := newDependsOnObj("v1", "ConfigMap", "test", "b", "/namespaces/test/ConfigMap/a")
+	c := newDependsOnObj("v1", "ConfigMap", "test", "c", "")
+
+	_, err := SortByDependencies([]*unstructured.Unstructured{a, b, c})
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+	cycleErr, ok := err.(CyclicDependencyError)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, 2, len(cycleErr.Objs))
+	assert.Contains(t, err.Error(), "/namespaces/test/ConfigMap/a")
+}
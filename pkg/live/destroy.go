@@ -0,0 +1,316 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+const (
+	// OperationDeleted means the object was deleted by destroy.
+	OperationDeleted Operation = "Deleted"
+	// OperationDeleteSkipped means the object was kept by destroy,
+	// because of its on-remove annotation, or because it is owned by
+	// another inventory.
+	OperationDeleteSkipped Operation = "DeleteSkipped"
+)
+
+const (
+	// DefaultDeletionTimeout is how long the Destroyer waits for the
+	// deleted objects of a group to disappear if it does not set it.
+	DefaultDeletionTimeout = time.Minute
+	// DefaultDeletionPollInterval is how often the Destroyer checks if
+	// the deleted objects disappeared if it does not set it.
+	DefaultDeletionPollInterval = 2 * time.Second
+)
+
+// finalizersPatch is the merge patch removing the finalizers of an
+// object.
+const finalizersPatch = `{"metadata":{"finalizers":null}}`
+
+// lastDeletedKinds are the kinds deleted after all other objects, in
+// this order, since deleting them deletes or breaks the objects in them.
+var lastDeletedKinds = []string{"Namespace", "CustomResourceDefinition"}
+
+// StuckDeletionError is the error of an object which still exists once
+// the deletion timeout is reached, usually because its finalizers are
+// not removed.
+type StuckDeletionError struct {
+	Finalizers []string
+}
+
+func (e StuckDeletionError) Error() string {
+	if len(e.Finalizers) == 0 {
+		return "deletion is stuck"
+	}
+	return fmt.Sprintf("deletion is stuck waiting for finalizers: %s", strings.Join(e.Finalizers, ", "))
+}
+
+// Destroyer deletes the objects of an inventory in the reverse order of
+// their depends-on annotations, so an object is only deleted once the
+// objects depending on it are gone. Namespaces and CRDs are deleted
+// last. Every group of objects is deleted, then the Destroyer waits for
+// them to disappear while their finalizers run.
+type Destroyer struct {
+	Client          client.Client
+	InventoryClient InventoryClient
+	// Timeout is how long to wait for the deleted objects of every group
+	// to disappear. Objects which still exist are stuck.
+	Timeout time.Duration
+	// PollInterval is how often to check if deleted objects disappeared.
+	PollInterval time.Duration
+	// ForceFinalizerRemoval removes the finalizers of stuck objects, and
+	// waits for them to disappear again.
+	ForceFinalizerRemoval bool
+	// DryRun deletes the objects without waiting for them, and leaves
+	// the inventory untouched. The Client is expected to be a dry-run
+	// client.
+	DryRun bool
+	// Progress is called after every object is deleted, skipped or
+	// failed.
+	Progress func(result ActuationResult)
+}
+
+// Run deletes the objects of the inventory, holding the lock of the
+// inventory, and deletes the inventory once all objects are gone. The
+// objects which depend on an object which failed to be deleted or is
+// stuck are left untouched, and stay in the inventory with the failed
+// ones. Returns the outcome for every object, and an ActuationError if
+// objects failed.
+func (d *Destroyer) Run(ctx context.Context, inv inventory.InventoryInfo) ([]ActuationResult, error) {
+	unlock, err := d.InventoryClient.Lock(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = unlock()
+	}()
+	stored, err := d.InventoryClient.Load(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	var results []ActuationResult
+	var liveObjs []*unstructured.Unstructured
+	for _, obj := range stored {
+		liveObj, err := d.Client.Get(ctx, obj)
+		switch {
+		case apierrors.IsNotFound(err):
+			results = append(results, d.progress(ActuationResult{Object: obj, Operation: OperationDeleted}))
+		case err != nil:
+			return results, err
+		default:
+			liveObjs = append(liveObjs, liveObj)
+		}
+	}
+	groups, err := DeletionOrder(liveObjs)
+	if err != nil {
+		return results, err
+	}
+	for i, group := range groups {
+		groupResults, err := d.deleteGroup(ctx, inv, group)
+		results = append(results, groupResults...)
+		if err != nil {
+			return results, err
+		}
+		failed := failedResults(groupResults)
+		if len(failed) == 0 {
+			continue
+		}
+		if !d.DryRun {
+			// The failed objects, and the objects they depend on, stay
+			// in the inventory so destroy can be run again.
+			var remaining []object.ObjMetadata
+			for _, r := range failed {
+				remaining = append(remaining, r.Object)
+			}
+			for _, later := range groups[i+1:] {
+				for _, obj := range later {
+					remaining = append(remaining, object.UnstructuredToObjMeta(obj))
+				}
+			}
+			if err := d.InventoryClient.Store(ctx, inv, remaining); err != nil {
+				return results, err
+			}
+		}
+		return results, ActuationError{Results: failed}
+	}
+	if d.DryRun {
+		return results, nil
+	}
+	return results, d.InventoryClient.Delete(ctx, inv)
+}
+
+// DeletionOrder groups the passed objects in the order they are deleted
+// in: the reverse of SortByDependencies, followed by the Namespaces and
+// the CRDs.
+func DeletionOrder(objs []*unstructured.Unstructured) ([][]*unstructured.Unstructured, error) {
+	last := map[string][]*unstructured.Unstructured{}
+	var rest []*unstructured.Unstructured
+	for _, obj := range objs {
+		isLast := false
+		for _, kind := range lastDeletedKinds {
+			if obj.GetKind() == kind {
+				last[kind] = append(last[kind], obj)
+				isLast = true
+			}
+		}
+		if !isLast {
+			rest = append(rest, obj)
+		}
+	}
+	groups, err := SortByDependencies(rest)
+	if err != nil {
+		return nil, err
+	}
+	groups = ReverseGroups(groups)
+	for _, kind := range lastDeletedKinds {
+		if len(last[kind]) > 0 {
+			groups = append(groups, last[kind])
+		}
+	}
+	return groups, nil
+}
+
+// deleteGroup deletes the objects of a group, and waits for them to
+// disappear. Stuck objects have their finalizers removed if
+// ForceFinalizerRemoval is set.
+func (d *Destroyer) deleteGroup(ctx context.Context, inv inventory.InventoryInfo,
+	group []*unstructured.Unstructured) ([]ActuationResult, error) {
+	results := map[object.ObjMetadata]*ActuationResult{}
+	var order, deleted []object.ObjMetadata
+	for _, liveObj := range group {
+		objMeta := object.UnstructuredToObjMeta(liveObj)
+		result := &ActuationResult{Object: objMeta, Operation: OperationDeleted}
+		results[objMeta] = result
+		order = append(order, objMeta)
+		if IsKeptOnRemove(liveObj) {
+			result.Operation = OperationDeleteSkipped
+			result.Message = fmt.Sprintf("annotated with %s: %s", OnRemoveAnnotation, OnRemoveKeep)
+			continue
+		}
+		if owner, found := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys); found &&
+			owner != "" && owner != inv.ID() {
+			result.Operation = OperationDeleteSkipped
+			result.Message = fmt.Sprintf("owned by inventory %q", owner)
+			continue
+		}
+		if err := d.Client.Delete(ctx, objMeta, nil); err != nil && !apierrors.IsNotFound(err) {
+			result.Err = err
+			continue
+		}
+		deleted = append(deleted, objMeta)
+	}
+	if !d.DryRun {
+		stuck, err := d.waitForDeletion(ctx, deleted)
+		if err != nil {
+			return nil, err
+		}
+		if len(stuck) > 0 && d.ForceFinalizerRemoval {
+			for obj := range stuck {
+				err := d.Client.Patch(ctx, obj, types.MergePatchType, []byte(finalizersPatch), nil)
+				if err != nil && !apierrors.IsNotFound(err) {
+					results[obj].Err = err
+					delete(stuck, obj)
+				}
+			}
+			var forced []object.ObjMetadata
+			for _, obj := range deleted {
+				if _, found := stuck[obj]; found {
+					forced = append(forced, obj)
+					results[obj].Message = "finalizers removed"
+				}
+			}
+			if stuck, err = d.waitForDeletion(ctx, forced); err != nil {
+				return nil, err
+			}
+		}
+		for obj, liveObj := range stuck {
+			results[obj].Err = StuckDeletionError{Finalizers: liveObj.GetFinalizers()}
+		}
+	}
+	var groupResults []ActuationResult
+	for _, obj := range order {
+		groupResults = append(groupResults, d.progress(*results[obj]))
+	}
+	return groupResults, nil
+}
+
+// waitForDeletion waits for the passed objects to disappear, for at
+// most the timeout of the Destroyer. Returns the live objects which
+// still exist.
+func (d *Destroyer) waitForDeletion(ctx context.Context,
+	objs []object.ObjMetadata) (map[object.ObjMetadata]*unstructured.Unstructured, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDeletionTimeout
+	}
+	interval := d.PollInterval
+	if interval <= 0 {
+		interval = DefaultDeletionPollInterval
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		remaining := map[object.ObjMetadata]*unstructured.Unstructured{}
+		for _, obj := range objs {
+			liveObj, err := d.Client.Get(ctx, obj)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			remaining[obj] = liveObj
+		}
+		if len(remaining) == 0 {
+			return remaining, nil
+		}
+		var pending []object.ObjMetadata
+		for _, obj := range objs {
+			if _, found := remaining[obj]; found {
+				pending = append(pending, obj)
+			}
+		}
+		objs = pending
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return remaining, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// progress calls the Progress function of the Destroyer with the result
+// if it is set, and returns the result.
+func (d *Destroyer) progress(result ActuationResult) ActuationResult {
+	if d.Progress != nil {
+		d.Progress(result)
+	}
+	return result
+}
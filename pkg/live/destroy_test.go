@@ -0,0 +1,183 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// finalizerClient keeps the deleted objects which have finalizers, as
+// the API server does until the finalizers are removed, and records the
+// order in which objects are deleted.
+type finalizerClient struct {
+	*client.FakeClient
+	deleted []string
+}
+
+func (c *finalizerClient) Delete(ctx context.Context, meta object.ObjMetadata, options *metav1.DeleteOptions) error {
+	c.deleted = append(c.deleted, meta.Name)
+	obj, err := c.Get(ctx, meta)
+	if err != nil {
+		return err
+	}
+	if len(obj.GetFinalizers()) == 0 {
+		return c.FakeClient.Delete(ctx, meta, options)
+	}
+	now := metav1.Now()
+	obj.SetDeletionTimestamp(&now)
+	return c.FakeClient.Update(ctx, meta, obj, nil)
+}
+
+func (c *finalizerClient) Patch(ctx context.Context, meta object.ObjMetadata, patchType types.PatchType,
+	data []byte, options *metav1.PatchOptions) error {
+	if err := c.FakeClient.Patch(ctx, meta, patchType, data, options); err != nil {
+		return err
+	}
+	obj, err := c.Get(ctx, meta)
+	if err != nil {
+		return err
+	}
+	if len(obj.GetFinalizers()) == 0 && obj.GetDeletionTimestamp() != nil {
+		return c.FakeClient.Delete(ctx, meta, nil)
+	}
+	return nil
+}
+
+func newDestroyObj(kind, name string) *unstructured.Unstructured {
+	obj := newPlanObj(name, "a")
+	obj.SetKind(kind)
+	if kind == "Namespace" || kind == "CustomResourceDefinition" {
+		obj.SetNamespace("")
+	}
+	return obj
+}
+
+func TestDeletionOrder(t *testing.T) {
+	ns := newDestroyObj("Namespace", "ns")
+	crd := newDestroyObj("CustomResourceDefinition", "crd")
+	a := dependsOn(newPlanObj("a", "a"), "b")
+	b := newPlanObj("b", "a")
+	c := newPlanObj("c", "a")
+	groups, err := DeletionOrder([]*unstructured.Unstructured{crd, ns, b, a, c})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]*unstructured.Unstructured{{a}, {b, c}, {ns}, {crd}}, groups)
+}
+
+func TestDestroyer_Run(t *testing.T) {
+	ctx := context.Background()
+	inv := WrapInventoryInfoObj(inventoryObj)
+	a := dependsOn(newPlanObj("a", "a"), "b")
+	b := newPlanObj("b", "a")
+	kept := newPlanObj("kept", "a")
+	kept.SetAnnotations(map[string]string{OnRemoveAnnotation: OnRemoveKeep})
+	gone := newPlanObj("gone", "a")
+	c := &finalizerClient{FakeClient: client.NewFakeClient(a, b, kept)}
+	invClient := NewMemoryInventoryClient()
+	var stored []object.ObjMetadata
+	for _, obj := range []*unstructured.Unstructured{b, a, kept, gone} {
+		stored = append(stored, object.UnstructuredToObjMeta(obj))
+	}
+	if !assert.NoError(t, invClient.Store(ctx, inv, stored)) {
+		t.FailNow()
+	}
+
+	d := &Destroyer{Client: c, InventoryClient: invClient, PollInterval: time.Millisecond}
+	results, err := d.Run(ctx, inv)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, c.deleted)
+	assert.Equal(t, []ActuationResult{
+		{Object: object.UnstructuredToObjMeta(gone), Operation: OperationDeleted},
+		{Object: object.UnstructuredToObjMeta(a), Operation: OperationDeleted},
+		{Object: object.UnstructuredToObjMeta(b), Operation: OperationDeleted},
+		{Object: object.UnstructuredToObjMeta(kept), Operation: OperationDeleteSkipped,
+			Message: "annotated with " + OnRemoveAnnotation + ": " + OnRemoveKeep},
+	}, results)
+	_, err = c.Get(ctx, object.UnstructuredToObjMeta(kept))
+	assert.NoError(t, err)
+	remaining, err := invClient.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestDestroyer_StuckDeletion(t *testing.T) {
+	dependency := newPlanObj("dependency", "a")
+	a := dependsOn(newPlanObj("a", "a"), "b")
+	b := dependsOn(newPlanObj("b", "a"), "dependency")
+	b.SetFinalizers([]string{"example.com/cleanup"})
+	aMeta := object.UnstructuredToObjMeta(a)
+	bMeta := object.UnstructuredToObjMeta(b)
+
+	testCases := map[string]struct {
+		force             bool
+		expectedDeleted   []string
+		expectedInventory []object.ObjMetadata
+	}{
+		"stuck": {
+			expectedDeleted:   []string{"a", "b"},
+			expectedInventory: []object.ObjMetadata{bMeta, object.UnstructuredToObjMeta(dependency)},
+		},
+		"force finalizer removal": {
+			force:           true,
+			expectedDeleted: []string{"a", "b", "dependency"},
+		},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			inv := WrapInventoryInfoObj(inventoryObj)
+			c := &finalizerClient{FakeClient: client.NewFakeClient(a, b, dependency)}
+			invClient := NewMemoryInventoryClient()
+			stored := []object.ObjMetadata{aMeta, bMeta, object.UnstructuredToObjMeta(dependency)}
+			if !assert.NoError(t, invClient.Store(ctx, inv, stored)) {
+				t.FailNow()
+			}
+			d := &Destroyer{
+				Client:                c,
+				InventoryClient:       invClient,
+				Timeout:               10 * time.Millisecond,
+				PollInterval:          time.Millisecond,
+				ForceFinalizerRemoval: tc.force,
+			}
+			results, err := d.Run(ctx, inv)
+			assert.Equal(t, tc.expectedDeleted, c.deleted)
+			remaining, loadErr := invClient.Load(ctx, inv)
+			assert.NoError(t, loadErr)
+			assert.Equal(t, tc.expectedInventory, remaining)
+			if !tc.force {
+				if assert.IsType(t, ActuationError{}, err) {
+					failed := err.(ActuationError).Results
+					assert.Equal(t, bMeta, failed[0].Object)
+					assert.Equal(t, StuckDeletionError{Finalizers: []string{"example.com/cleanup"}}, failed[0].Err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			for _, r := range results {
+				if r.Object == bMeta {
+					assert.Equal(t, "finalizers removed", r.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/pmezard/go-difflib/difflib"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kubectl/pkg/util"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// lastAppliedAnnotation is the annotation storing the configuration
+// the object was last applied with.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ObjectDiff is the difference between the local config of an object
+// and its live state in the cluster.
+type ObjectDiff struct {
+	// Object identifies the object.
+	Object object.ObjMetadata `json:"object"`
+	// Type is the action apply would take for the object.
+	Type ActionType `json:"type"`
+	// Diff is a unified diff from the live object to the local config, as
+	// a server-side apply would store it. Empty if the object is unchanged.
+	Diff string `json:"diff,omitempty"`
+	// Drifted lists the fields which were last applied by kpt, but
+	// were changed in the cluster since.
+	Drifted []string `json:"drifted,omitempty"`
+}
+
+// ComputeDiff computes a three-way diff of the passed objects between
+// the configuration they were last applied with, their live state and
+// the local config. Existing objects are compared with the result of a
+// server-side dry-run apply of their local config, so fields defaulted
+// by the server or managed by others are not reported as changes. The
+// cluster objects are the objects currently stored in the inventory, and
+// the ones missing from objs are reported as pruned.
+func ComputeDiff(ctx context.Context, c client.Client, objs []*unstructured.Unstructured,
+	clusterObjs []object.ObjMetadata) ([]ObjectDiff, error) {
+	var diffs []ObjectDiff
+	local := map[object.ObjMetadata]bool{}
+	for _, obj := range objs {
+		objMeta := object.UnstructuredToObjMeta(obj)
+		local[objMeta] = true
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			liveObj = nil
+		} else if err != nil {
+			return nil, err
+		}
+		applied := obj
+		if liveObj != nil {
+			force := true
+			applied, err = c.DryRunApply(ctx, objMeta, obj, &metav1.PatchOptions{Force: &force})
+			if err != nil {
+				return nil, fmt.Errorf("dry-run of %s %s/%s failed: %v", obj.GetKind(),
+					obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+		d, err := diffObject(objMeta, liveObj, applied)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d)
+	}
+	for _, objMeta := range clusterObjs {
+		if local[objMeta] {
+			continue
+		}
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		d, err := diffObject(objMeta, liveObj, nil)
+		if err != nil {
+			return nil, err
+		}
+		if IsKeptOnRemove(liveObj) {
+			d.Type = ActionPruneSkip
+			d.Diff = ""
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// diffObject computes the diff of a single object. The live object is
+// nil if it does not exist, and the applied object is nil if it is
+// pruned.
+func diffObject(objMeta object.ObjMetadata, liveObj, applied *unstructured.Unstructured) (ObjectDiff, error) {
+	d := ObjectDiff{Object: objMeta}
+	var from, to string
+	var err error
+	if liveObj != nil {
+		if d.Drifted, err = driftedFields(liveObj); err != nil {
+			return d, err
+		}
+		if from, err = toYAML(StripLiveFields(liveObj)); err != nil {
+			return d, err
+		}
+	}
+	if applied != nil {
+		if to, err = toYAML(StripLiveFields(applied)); err != nil {
+			return d, err
+		}
+	}
+	switch {
+	case liveObj == nil:
+		d.Type = ActionCreate
+	case applied == nil:
+		d.Type = ActionPrune
+	case from == to:
+		d.Type = ActionUnchanged
+		return d, nil
+	default:
+		d.Type = ActionUpdate
+	}
+	name := fmt.Sprintf("%s/%s/%s", objMeta.GroupKind.Kind, objMeta.Namespace, objMeta.Name)
+	d.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: "live/" + name,
+		ToFile:   "local/" + name,
+		Context:  3,
+	})
+	return d, err
+}
+
+// StripLiveFields returns a copy of the object without the fields set
+// by the API server, which are noise when comparing it with the local
+// config: managedFields, status, the last applied configuration and
+// the server-populated metadata.
+func StripLiveFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	stripped := obj.DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation",
+		"creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(stripped.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(stripped.Object, "status")
+	annotations := stripped.GetAnnotations()
+	delete(annotations, lastAppliedAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(stripped.Object, "metadata", "annotations")
+	} else {
+		stripped.SetAnnotations(annotations)
+	}
+	return stripped
+}
+
+// driftedFields returns the paths of the fields in the last applied
+// configuration of the live object whose live value differs, in
+// sorted order.
+func driftedFields(liveObj *unstructured.Unstructured) ([]string, error) {
+	original, err := util.GetOriginalConfiguration(liveObj)
+	if err != nil || len(original) == 0 {
+		return nil, err
+	}
+	var originalMap map[string]interface{}
+	if err := json.Unmarshal(original, &originalMap); err != nil {
+		return nil, err
+	}
+	var drifted []string
+	collectDrift(nil, originalMap, liveObj.Object, &drifted)
+	sort.Strings(drifted)
+	return drifted, nil
+}
+
+// collectDrift appends the path of every leaf of applied whose value in
+// liveValue differs. Maps are compared field by field, while lists and
+// scalars are compared as a whole.
+func collectDrift(path []string, applied, liveValue interface{}, drifted *[]string) {
+	appliedMap, ok := applied.(map[string]interface{})
+	if !ok {
+		if !reflect.DeepEqual(normalizeJSON(applied), normalizeJSON(liveValue)) {
+			*drifted = append(*drifted, strings.Join(path, "."))
+		}
+		return
+	}
+	liveMap, _ := liveValue.(map[string]interface{})
+	for key, value := range appliedMap {
+		collectDrift(append(append([]string{}, path...), key), value, liveMap[key], drifted)
+	}
+}
+
+// normalizeJSON converts the value to the types produced by decoding
+// JSON, so numbers of different Go types compare equal.
+func normalizeJSON(value interface{}) interface{} {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// toYAML serializes the object as YAML with sorted fields.
+func toYAML(obj *unstructured.Unstructured) (string, error) {
+	b, err := yaml.Marshal(obj.Object)
+	return string(b), err
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestStripLiveFields(t *testing.T) {
+	obj := newPlanObj("cm", "a")
+	liveObj := newLiveObj(t, obj, "1")
+	liveObj.SetUID("uid")
+	liveObj.SetGeneration(2)
+	liveObj.Object["metadata"].(map[string]interface{})["managedFields"] = []interface{}{
+		map[string]interface{}{"manager": "kpt"},
+	}
+	liveObj.Object["status"] = map[string]interface{}{"phase": "Active"}
+
+	stripped := StripLiveFields(liveObj)
+	assert.Equal(t, obj, stripped)
+	// The passed object is not modified.
+	assert.Equal(t, "1", liveObj.GetResourceVersion())
+}
+
+func TestComputeDiff(t *testing.T) {
+	created := newPlanObj("created", "a")
+	unchanged := newPlanObj("unchanged", "a")
+	updated := newPlanObj("updated", "b")
+	pruned := newPlanObj("pruned", "a")
+	kept := newPlanObj("kept", "a")
+	kept.SetAnnotations(map[string]string{OnRemoveAnnotation: OnRemoveKeep})
+
+	// The drifted object was last applied with "a", but changed to "c"
+	// in the cluster.
+	drifted := newLiveObj(t, newPlanObj("drifted", "a"), "5")
+	drifted.Object["data"] = map[string]interface{}{"key": "c"}
+
+	// The defaulted object has a field set by the server, which is not
+	// in the local config.
+	defaulted := newLiveObj(t, newPlanObj("defaulted", "a"), "6")
+	defaulted.Object["immutable"] = false
+
+	clusterObjs := []*unstructured.Unstructured{
+		newLiveObj(t, unchanged, "1"),
+		newLiveObj(t, newPlanObj("updated", "a"), "2"),
+		newLiveObj(t, pruned, "3"),
+		newLiveObj(t, kept, "4"),
+		drifted,
+		defaulted,
+	}
+	objs := []*unstructured.Unstructured{created, unchanged, updated, newPlanObj("drifted", "a"),
+		newPlanObj("defaulted", "a")}
+	var invObjs []object.ObjMetadata
+	for _, obj := range clusterObjs {
+		invObjs = append(invObjs, object.UnstructuredToObjMeta(obj))
+	}
+
+	diffs, err := ComputeDiff(context.Background(), client.NewFakeClient(clusterObjs...), objs, invObjs)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	types := map[string]ActionType{}
+	byName := map[string]ObjectDiff{}
+	for _, d := range diffs {
+		types[d.Object.Name] = d.Type
+		byName[d.Object.Name] = d
+	}
+	assert.Equal(t, map[string]ActionType{
+		"created":   ActionCreate,
+		"unchanged": ActionUnchanged,
+		"updated":   ActionUpdate,
+		"drifted":   ActionUpdate,
+		"defaulted": ActionUnchanged,
+		"pruned":    ActionPrune,
+		"kept":      ActionPruneSkip,
+	}, types)
+
+	assert.Empty(t, byName["unchanged"].Diff)
+	assert.Empty(t, byName["kept"].Diff)
+	assert.Empty(t, byName["defaulted"].Diff)
+	assert.Contains(t, byName["created"].Diff, "+  key: a")
+	assert.Contains(t, byName["updated"].Diff, "-  key: a")
+	assert.Contains(t, byName["updated"].Diff, "+  key: b")
+	assert.Contains(t, byName["pruned"].Diff, "-  key: a")
+	assert.NotContains(t, byName["updated"].Diff, "resourceVersion")
+	assert.NotContains(t, byName["updated"].Diff, lastAppliedAnnotation)
+	assert.Empty(t, byName["updated"].Drifted)
+	assert.Equal(t, []string{"data.key"}, byName["drifted"].Drifted)
+}
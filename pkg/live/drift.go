@@ -0,0 +1,121 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// FieldDrift is a field of an applied object whose live value was
+// changed out-of-band since the apply.
+type FieldDrift struct {
+	// Path is the path of the field, with the indexes of list items in
+	// brackets, e.g. spec.template.spec.containers[0].image.
+	Path string `json:"path"`
+	// Expected is the applied value of the field.
+	Expected interface{} `json:"expected"`
+	// Actual is the live value of the field, or nil if it was removed.
+	Actual interface{} `json:"actual"`
+}
+
+// ObjectDrift is the drift of an applied object from its live state.
+type ObjectDrift struct {
+	// Object identifies the object.
+	Object object.ObjMetadata `json:"object"`
+	// Missing is true if the object was deleted from the cluster.
+	Missing bool `json:"missing,omitempty"`
+	// Fields are the drifted fields of the object, sorted by path.
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// Drifted returns true if the object was deleted or changed.
+func (d ObjectDrift) Drifted() bool {
+	return d.Missing || len(d.Fields) > 0
+}
+
+// DetectDrift compares the passed objects, as they were last applied,
+// with their live state. Only the fields set in the applied objects are
+// compared, so fields defaulted or managed by the cluster are not
+// drift. Returns the drift of every object, in the order of objs.
+func DetectDrift(ctx context.Context, c client.Client, objs []*unstructured.Unstructured) ([]ObjectDrift, error) {
+	var drifts []ObjectDrift
+	for _, obj := range objs {
+		objMeta := object.UnstructuredToObjMeta(obj)
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			drifts = append(drifts, ObjectDrift{Object: objMeta, Missing: true})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %s: %v", objMeta, err)
+		}
+		applied := obj.DeepCopy()
+		unstructured.RemoveNestedField(applied.Object, "status")
+		d := ObjectDrift{Object: objMeta}
+		collectFieldDrift(nil, applied.Object, liveObj.Object, &d.Fields)
+		sort.Slice(d.Fields, func(i, j int) bool {
+			return d.Fields[i].Path < d.Fields[j].Path
+		})
+		drifts = append(drifts, d)
+	}
+	return drifts, nil
+}
+
+// collectFieldDrift appends every leaf of applied whose value in
+// liveValue differs. Maps are compared field by field, and lists item by
+// item if they have the same length, so fields the cluster added to
+// list items are not drift. Lists of different lengths and scalars are
+// compared as a whole.
+func collectFieldDrift(path []string, applied, liveValue interface{}, drifts *[]FieldDrift) {
+	switch a := applied.(type) {
+	case map[string]interface{}:
+		liveMap, ok := liveValue.(map[string]interface{})
+		if !ok && liveValue != nil {
+			break
+		}
+		for key, value := range a {
+			collectFieldDrift(append(append([]string{}, path...), key), value, liveMap[key], drifts)
+		}
+		return
+	case []interface{}:
+		liveList, ok := liveValue.([]interface{})
+		if !ok || len(liveList) != len(a) {
+			break
+		}
+		for i, value := range a {
+			indexed := append([]string{}, path...)
+			indexed[len(indexed)-1] = fmt.Sprintf("%s[%d]", indexed[len(indexed)-1], i)
+			collectFieldDrift(indexed, value, liveList[i], drifts)
+		}
+		return
+	}
+	expected, actual := normalizeJSON(applied), normalizeJSON(liveValue)
+	if !reflect.DeepEqual(expected, actual) {
+		*drifts = append(*drifts, FieldDrift{
+			Path:     strings.Join(path, "."),
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+}
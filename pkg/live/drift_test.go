@@ -0,0 +1,81 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDetectDrift(t *testing.T) {
+	inSync := newPlanObj("in-sync", "a")
+	changed := newPlanObj("changed", "a")
+	missing := newPlanObj("missing", "a")
+
+	// Fields set by the cluster are not drift.
+	liveInSync := newLiveObj(t, inSync, "1")
+	liveInSync.SetUID("uid")
+	liveInSync.Object["data"].(map[string]interface{})["added"] = "b"
+	liveChanged := newLiveObj(t, changed, "2")
+	liveChanged.Object["data"] = map[string]interface{}{"key": "c"}
+
+	drifts, err := DetectDrift(context.Background(), client.NewFakeClient(liveInSync, liveChanged),
+		[]*unstructured.Unstructured{inSync, changed, missing})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, drifts, 3) {
+		t.FailNow()
+	}
+	assert.False(t, drifts[0].Drifted())
+	assert.True(t, drifts[1].Drifted())
+	assert.Equal(t, []FieldDrift{{Path: "data.key", Expected: "a", Actual: "c"}}, drifts[1].Fields)
+	assert.True(t, drifts[2].Missing)
+	assert.Equal(t, "missing", drifts[2].Object.Name)
+}
+
+func TestCollectFieldDrift(t *testing.T) {
+	applied := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:v1"},
+			},
+			"args":   []interface{}{"a"},
+			"labels": map[string]interface{}{"app": "a"},
+		},
+	}
+	liveValue := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(5),
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:v2", "imagePullPolicy": "Always"},
+			},
+			"args": []interface{}{"a", "b"},
+		},
+	}
+	var drifts []FieldDrift
+	collectFieldDrift(nil, applied, liveValue, &drifts)
+	assert.ElementsMatch(t, []FieldDrift{
+		{Path: "spec.replicas", Expected: float64(3), Actual: float64(5)},
+		{Path: "spec.containers[0].image", Expected: "app:v1", Actual: "app:v2"},
+		{Path: "spec.args", Expected: []interface{}{"a"}, Actual: []interface{}{"a", "b"}},
+		{Path: "spec.labels.app", Expected: "a", Actual: nil},
+	}, drifts)
+}
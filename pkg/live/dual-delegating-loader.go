@@ -4,6 +4,7 @@
 package live
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -12,14 +13,33 @@ import (
 	"k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
 var _ manifestreader.ManifestLoader = &DualDelegatingManifestReader{}
 
 // DualDelegatingManifestReader read manifests that either uses ConfigMap
-// or the ResourceGroup as the inventory object.
+// or the ResourceGroup (or Secret) as the inventory object.
 type DualDelegatingManifestReader struct {
 	factory util.Factory
+	// InventoryType overrides the type of the inventory object
+	// generated from the Kptfile if set.
+	InventoryType string
+	// MaxWave limits the objects read to the apply waves up to and
+	// including MaxWave if set.
+	MaxWave *int
+	// MaxGroup limits the objects read to the dependency groups up to and
+	// including MaxGroup if set, as computed by SortByDependencies once
+	// the objects are filtered.
+	MaxGroup *int
+	// Exclude lists objects which are not read, e.g. because they are
+	// owned by another inventory.
+	Exclude map[object.ObjMetadata]bool
+	// Filter limits the objects read to the ones it selects if set.
+	Filter *ResourceFilter
+	// Substitution resolves the apply-time setters and mutations of the
+	// objects read if set.
+	Substitution *ApplyTimeSubstitution
 }
 
 var _ manifestreader.ManifestLoader = &DualDelegatingManifestReader{}
@@ -31,7 +51,8 @@ func NewDualDelegatingManifestReader(f util.Factory) *DualDelegatingManifestRead
 // ManifestReader retrieves the ManifestReader from the delegate ResourceGroup
 // Provider, then calls Read() for this ManifestReader to retrieve the objects
 // and to calculate the type of Inventory object is present. Returns a
-// CachedManifestReader with the read objects, or an error.
+// CachedManifestReader with the read objects sorted by their dependencies,
+// or an error.
 func (cp *DualDelegatingManifestReader) ManifestReader(reader io.Reader, args []string) (manifestreader.ManifestReader, error) {
 	r, err := cp.manifestReader(reader, args)
 	if err != nil {
@@ -42,7 +63,51 @@ func (cp *DualDelegatingManifestReader) ManifestReader(reader io.Reader, args []
 		return nil, err
 	}
 	klog.V(4).Infof("ManifestReader read %d objects", len(objs))
-	return &CachedManifestReader{objs: objs}, nil
+	if err := ValidatePrunePolicy(objs); err != nil {
+		return nil, err
+	}
+	if cp.MaxWave != nil {
+		objs, err = FilterByWave(objs, *cp.MaxWave)
+		if err != nil {
+			return nil, err
+		}
+		klog.V(4).Infof("ManifestReader filtered %d objects up to wave %d", len(objs), *cp.MaxWave)
+	}
+	if len(cp.Exclude) > 0 {
+		var included []*unstructured.Unstructured
+		for _, obj := range objs {
+			if !cp.Exclude[object.UnstructuredToObjMeta(obj)] {
+				included = append(included, obj)
+			}
+		}
+		klog.V(4).Infof("ManifestReader excluded %d objects", len(objs)-len(included))
+		objs = included
+	}
+	if cp.Filter != nil {
+		filtered := cp.Filter.Filter(objs)
+		klog.V(4).Infof("ManifestReader filtered out %d objects", len(objs)-len(filtered))
+		objs = filtered
+	}
+	if cp.Substitution != nil {
+		objs, err = cp.Substitution.Substitute(context.Background(), objs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Order the objects so dependencies declared with the depends-on
+	// annotation come first.
+	maxGroup := len(objs)
+	if cp.MaxGroup != nil {
+		maxGroup = *cp.MaxGroup
+	}
+	sorted, err := FilterByGroup(objs, maxGroup)
+	if err != nil {
+		return nil, err
+	}
+	if len(sorted) < len(objs) {
+		klog.V(4).Infof("ManifestReader filtered %d objects up to dependency group %d", len(sorted), maxGroup)
+	}
+	return &CachedManifestReader{objs: sorted}, nil
 }
 
 func (cp *DualDelegatingManifestReader) manifestReader(reader io.Reader, args []string) (manifestreader.ManifestReader, error) {
@@ -53,6 +118,9 @@ func (cp *DualDelegatingManifestReader) manifestReader(reader io.Reader, args []
 	if len(args) > 1 {
 		return nil, fmt.Errorf("expected one directory argument allowed; got (%s)", args)
 	}
+	if err := ValidateInventoryType(cp.InventoryType); err != nil {
+		return nil, err
+	}
 	// Create ReaderOptions for subsequent ManifestReader.
 	namespace, enforceNamespace, err := cp.factory.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
@@ -78,6 +146,7 @@ func (cp *DualDelegatingManifestReader) manifestReader(reader io.Reader, args []
 				Reader:        reader,
 				ReaderOptions: readerOptions,
 			},
+			inventoryType: cp.InventoryType,
 		}
 	} else {
 		rgReader = &ResourceGroupPathManifestReader{
@@ -85,6 +154,7 @@ func (cp *DualDelegatingManifestReader) manifestReader(reader io.Reader, args []
 				Path:          args[0],
 				ReaderOptions: readerOptions,
 			},
+			inventoryType: cp.InventoryType,
 		}
 	}
 	return rgReader, nil
@@ -94,22 +164,32 @@ func (cp *DualDelegatingManifestReader) manifestReader(reader io.Reader, args []
 // It can return a NoInventoryError or MultipleInventoryError.
 func (cp *DualDelegatingManifestReader) InventoryInfo(objs []*unstructured.Unstructured) (inventory.InventoryInfo, []*unstructured.Unstructured, error) {
 	objs, rgInv := findResourceGroupInv(objs)
-	var inv inventory.InventoryInfo
-	// A ResourceGroup inventory object means we need an InventoryFactoryFunc
-	// which works for ResourceGroup (instead of ConfigMap, which is default).
-	if rgInv != nil {
-		inv = &InventoryResourceGroup{inv: rgInv}
-	}
+	objs, secretInv := findSecretInv(objs)
 	objs, cmInv := findConfigMapInv(objs)
-	if rgInv == nil && cmInv == nil {
+	var invObjs []*unstructured.Unstructured
+	for _, invObj := range []*unstructured.Unstructured{rgInv, secretInv, cmInv} {
+		if invObj != nil {
+			invObjs = append(invObjs, invObj)
+		}
+	}
+	if len(invObjs) == 0 {
 		return nil, objs, inventory.NoInventoryObjError{}
 	}
-	if rgInv != nil && cmInv != nil {
+	if len(invObjs) > 1 {
 		return nil, objs, MultipleInventoryObjError{
-			InvObjs: []*unstructured.Unstructured{rgInv, cmInv},
+			InvObjs: invObjs,
 		}
 	}
-	if cmInv != nil {
+	// A ResourceGroup or Secret inventory object means we need an
+	// InventoryFactoryFunc which works for them (instead of ConfigMap,
+	// which is default).
+	var inv inventory.InventoryInfo
+	switch {
+	case rgInv != nil:
+		inv = &InventoryResourceGroup{inv: rgInv}
+	case secretInv != nil:
+		inv = &InventorySecret{inv: secretInv}
+	default:
 		inv = inventory.WrapInventoryInfoObj(cmInv)
 	}
 	return inv, objs, nil
@@ -157,6 +237,21 @@ func findResourceGroupInv(objs []*unstructured.Unstructured) ([]*unstructured.Un
 	return fileteredObjs, inventoryObj
 }
 
+// findSecretInv returns the pointer to the Secret inventory object,
+// or nil if it does not exist.
+func findSecretInv(objs []*unstructured.Unstructured) ([]*unstructured.Unstructured, *unstructured.Unstructured) {
+	var fileteredObjs []*unstructured.Unstructured
+	var inventoryObj *unstructured.Unstructured
+	for _, obj := range objs {
+		if IsSecretInventory(obj) {
+			inventoryObj = obj
+			continue
+		}
+		fileteredObjs = append(fileteredObjs, obj)
+	}
+	return fileteredObjs, inventoryObj
+}
+
 // findConfigMapInv returns the pointer to the ConfigMap inventory object,
 // or nil if it does not exist.
 func findConfigMapInv(objs []*unstructured.Unstructured) ([]*unstructured.Unstructured, *unstructured.Unstructured) {
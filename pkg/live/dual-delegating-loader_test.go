@@ -5,13 +5,16 @@ package live
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
 var configMapInv = `
@@ -145,3 +148,69 @@ func TestDualDelegatingProvider_Read(t *testing.T) {
 		})
 	}
 }
+
+func TestDualDelegatingManifestReader_Exclude(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test-ns")
+	defer tf.Cleanup()
+	dir, err := ioutil.TempDir("", "provider-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for filename, content := range map[string]string{
+		"Kptfile":           kptFile,
+		"pod-a.yaml":        podA,
+		"deployment-a.yaml": deploymentA,
+	} {
+		err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0600)
+		assert.NoError(t, err)
+	}
+
+	loader := NewDualDelegatingManifestReader(tf)
+	loader.Exclude = map[object.ObjMetadata]bool{
+		{Namespace: "test-namespace", Name: "pod-a", GroupKind: schema.GroupKind{Kind: "Pod"}}: true,
+	}
+	mr, err := loader.ManifestReader(nil, []string{dir})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	objs, err := mr.Read()
+	assert.NoError(t, err)
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetName())
+	}
+	assert.NotContains(t, names, "pod-a")
+	assert.Contains(t, names, "test-deployment")
+}
+
+func TestDualDelegatingManifestReader_Filter(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("test-ns")
+	defer tf.Cleanup()
+	dir, err := ioutil.TempDir("", "provider-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for filename, content := range map[string]string{
+		"Kptfile":           kptFile,
+		"pod-a.yaml":        podA,
+		"deployment-a.yaml": deploymentA,
+	} {
+		err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0600)
+		assert.NoError(t, err)
+	}
+
+	loader := NewDualDelegatingManifestReader(tf)
+	loader.Filter, err = NewResourceFilter("", []string{"Deployment"}, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	mr, err := loader.ManifestReader(nil, []string{dir})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	objs, err := mr.Read()
+	assert.NoError(t, err)
+	var kinds []string
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.ElementsMatch(t, []string{"ResourceGroup", "Deployment"}, kinds)
+}
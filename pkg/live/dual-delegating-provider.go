@@ -48,6 +48,8 @@ func inventoryWrapperFunc(obj *unstructured.Unstructured) inventory.Inventory {
 	switch obj.GetKind() {
 	case "ResourceGroup":
 		return &InventoryResourceGroup{inv: obj}
+	case "Secret":
+		return &InventorySecret{inv: obj}
 	case "ConfigMap":
 		return inventory.WrapInventoryObj(obj)
 	default:
@@ -59,6 +61,8 @@ func invToUnstructuredFunc(inv inventory.InventoryInfo) *unstructured.Unstructur
 	switch invInfo := inv.(type) {
 	case *InventoryResourceGroup:
 		return invInfo.inv
+	case *InventorySecret:
+		return invInfo.inv
 	case *inventory.InventoryConfigMap:
 		return invInfo.UnstructuredInventory()
 	default:
@@ -0,0 +1,123 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// ExitCondition is an outcome of an apply which may or may not be an
+// error, depending on the ExitPolicy. Resources which failed to be
+// applied are always an error.
+type ExitCondition string
+
+const (
+	// ExitOnReconcileTimeout is when the applied resources are not
+	// Current by the reconcile timeout.
+	ExitOnReconcileTimeout ExitCondition = "reconcile-timeout"
+	// ExitOnSkipped is when resources were skipped, because they are
+	// owned by another inventory, conflict with another field manager, or
+	// are kept instead of being pruned.
+	ExitOnSkipped ExitCondition = "skipped"
+	// ExitOnPruneFailure is when resources failed to be pruned.
+	ExitOnPruneFailure ExitCondition = "prune-failure"
+)
+
+// DefaultExitConditions are the conditions which are errors if the
+// policy is not set.
+var DefaultExitConditions = []string{string(ExitOnReconcileTimeout), string(ExitOnPruneFailure)}
+
+// ExitPolicy is the set of conditions which are errors.
+type ExitPolicy map[ExitCondition]bool
+
+// ParseExitPolicy returns the ExitPolicy with the passed conditions.
+func ParseExitPolicy(conditions []string) (ExitPolicy, error) {
+	p := ExitPolicy{}
+	for _, c := range conditions {
+		switch ExitCondition(c) {
+		case ExitOnReconcileTimeout, ExitOnSkipped, ExitOnPruneFailure:
+			p[ExitCondition(c)] = true
+		default:
+			return nil, fmt.Errorf("unknown exit condition %q; must be one of %s, %s or %s",
+				c, ExitOnReconcileTimeout, ExitOnSkipped, ExitOnPruneFailure)
+		}
+	}
+	return p, nil
+}
+
+// ReconcileTimeoutError is returned when the applied resources are not
+// Current by the reconcile timeout.
+type ReconcileTimeoutError struct {
+	Objects []object.ObjMetadata
+}
+
+func (e ReconcileTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for %d resources to reconcile", len(e.Objects))
+}
+
+// SkippedError is returned when resources were skipped, and skipped
+// resources are errors by the ExitPolicy.
+type SkippedError struct {
+	Objects []object.ObjMetadata
+}
+
+func (e SkippedError) Error() string {
+	return fmt.Sprintf("%d resources were skipped", len(e.Objects))
+}
+
+// Check returns the error of the apply which returned err and results,
+// and skipped the passed objects before actuation. Reconcile timeouts
+// and prune failures which are not errors by the policy are dropped,
+// and skipped objects become a SkippedError if they are. Returns nil if
+// the apply succeeded by the policy.
+func (p ExitPolicy) Check(err error, results []ActuationResult, skipped []object.ObjMetadata) error {
+	switch e := err.(type) {
+	case ReconcileTimeoutError:
+		if !p[ExitOnReconcileTimeout] {
+			err = nil
+		}
+	case ActuationError:
+		var failed []ActuationResult
+		for _, r := range e.Results {
+			if r.Operation != OperationPruned || p[ExitOnPruneFailure] {
+				failed = append(failed, r)
+			}
+		}
+		if len(failed) == 0 {
+			err = nil
+		} else {
+			err = ActuationError{Results: failed}
+		}
+	}
+	if err != nil || !p[ExitOnSkipped] {
+		return err
+	}
+	objs := append([]object.ObjMetadata{}, skipped...)
+	for _, r := range results {
+		if r.Operation == OperationPruneSkipped || r.Operation == OperationApplySkipped {
+			objs = append(objs, r.Object)
+		}
+	}
+	if len(objs) == 0 {
+		return nil
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].String() < objs[j].String()
+	})
+	return SkippedError{Objects: objs}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestParseExitPolicy(t *testing.T) {
+	p, err := ParseExitPolicy(DefaultExitConditions)
+	assert.NoError(t, err)
+	assert.Equal(t, ExitPolicy{ExitOnReconcileTimeout: true, ExitOnPruneFailure: true}, p)
+
+	_, err = ParseExitPolicy([]string{"apply-failure"})
+	assert.Error(t, err)
+}
+
+func TestExitPolicy_Check(t *testing.T) {
+	a := object.UnstructuredToObjMeta(newPlanObj("a", "a"))
+	b := object.UnstructuredToObjMeta(newPlanObj("b", "a"))
+	applyFailure := ActuationResult{Object: a, Operation: OperationApplied, Err: fmt.Errorf("invalid")}
+	pruneFailure := ActuationResult{Object: b, Operation: OperationPruned, Err: fmt.Errorf("forbidden")}
+	pruneSkipped := ActuationResult{Object: b, Operation: OperationPruneSkipped}
+	timeout := ReconcileTimeoutError{Objects: []object.ObjMetadata{a}}
+
+	testCases := map[string]struct {
+		conditions []string
+		err        error
+		results    []ActuationResult
+		skipped    []object.ObjMetadata
+		expected   error
+	}{
+		"reconcile timeout is an error": {
+			conditions: DefaultExitConditions,
+			err:        timeout,
+			expected:   timeout,
+		},
+		"reconcile timeout is ignored": {
+			conditions: []string{string(ExitOnPruneFailure)},
+			err:        timeout,
+		},
+		"prune failure is ignored": {
+			conditions: []string{string(ExitOnReconcileTimeout)},
+			err:        ActuationError{Results: []ActuationResult{pruneFailure}},
+			results:    []ActuationResult{pruneFailure},
+		},
+		"apply failure is always an error": {
+			err:      ActuationError{Results: []ActuationResult{applyFailure, pruneFailure}},
+			results:  []ActuationResult{applyFailure, pruneFailure},
+			expected: ActuationError{Results: []ActuationResult{applyFailure}},
+		},
+		"skipped is not an error by default": {
+			conditions: DefaultExitConditions,
+			results:    []ActuationResult{pruneSkipped},
+			skipped:    []object.ObjMetadata{a},
+		},
+		"skipped is an error": {
+			conditions: []string{string(ExitOnSkipped)},
+			results:    []ActuationResult{pruneSkipped},
+			skipped:    []object.ObjMetadata{a},
+			expected:   SkippedError{Objects: []object.ObjMetadata{a, b}},
+		},
+		"nothing skipped": {
+			conditions: []string{string(ExitOnSkipped)},
+		},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			p, err := ParseExitPolicy(tc.conditions)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.expected, p.Check(tc.err, tc.results, tc.skipped))
+		})
+	}
+}
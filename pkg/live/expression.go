@@ -0,0 +1,85 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+)
+
+// Expression is a compiled CEL expression evaluated against a resource.
+// The resource is the variable "self", and its top-level fields
+// apiVersion, kind, metadata, spec, status and data are variables too.
+type Expression struct {
+	source  string
+	program cel.Program
+}
+
+// newExpressionEnv returns the CEL environment declaring the variables
+// of the expressions. Resources have no schema, so all of them are dyn.
+func newExpressionEnv() (*cel.Env, error) {
+	return cel.NewEnv(cel.Declarations(
+		decls.NewVar("self", decls.Dyn),
+		decls.NewVar("apiVersion", decls.Dyn),
+		decls.NewVar("kind", decls.Dyn),
+		decls.NewVar("metadata", decls.Dyn),
+		decls.NewVar("spec", decls.Dyn),
+		decls.NewVar("status", decls.Dyn),
+		decls.NewVar("data", decls.Dyn),
+	))
+}
+
+// CompileExpression parses and checks the passed expression.
+func CompileExpression(source string) (*Expression, error) {
+	env, err := newExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, err)
+	}
+	return &Expression{source: source, program: program}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// EvalBool evaluates the expression against the passed object, and
+// returns an error if the result is not a bool.
+func (e *Expression) EvalBool(obj map[string]interface{}) (bool, error) {
+	vars := map[string]interface{}{"self": obj}
+	for key, value := range obj {
+		vars[key] = value
+	}
+	value, _, err := e.program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluated to %v, not a bool", e.source, value.Value())
+	}
+	return bool(b), nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var exprTestObj = map[string]interface{}{
+	"kind": "Database",
+	"metadata": map[string]interface{}{
+		"name":       "db",
+		"generation": int64(3),
+	},
+	"status": map[string]interface{}{
+		"phase":              "Ready",
+		"observedGeneration": int64(3),
+		"replicas":           2.0,
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Synced", "status": "False"},
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		},
+	},
+}
+
+func TestExpression_EvalBool(t *testing.T) {
+	testCases := map[string]struct {
+		expr     string
+		expected bool
+		isError  bool
+	}{
+		"string equality":     {expr: `status.phase == "Ready"`, expected: true},
+		"single quotes":       {expr: `status.phase != 'Ready'`, expected: false},
+		"self":                {expr: `self.kind == "Database"`, expected: true},
+		"int and field":       {expr: `status.observedGeneration == metadata.generation`, expected: true},
+		"double":              {expr: `status.replicas >= 2.0`, expected: true},
+		"negative number":     {expr: `metadata.generation > -1`, expected: true},
+		"string comparison":   {expr: `status.phase < "Running"`, expected: true},
+		"index":               {expr: `status.conditions[1].type == "Ready"`, expected: true},
+		"map index":           {expr: `status["phase"] == "Ready"`, expected: true},
+		"and or not":          {expr: `!(status.phase == "Failed") && (false || true)`, expected: true},
+		"in list":             {expr: `status.phase in ["Ready", "Succeeded"]`, expected: true},
+		"in map":              {expr: `"phase" in status`, expected: true},
+		"conditional":         {expr: `has(status.phase) ? status.phase == "Ready" : false`, expected: true},
+		"has missing":         {expr: `has(status.message)`, expected: false},
+		"has present":         {expr: `has(status.conditions)`, expected: true},
+		"size function":       {expr: `size(status.conditions) == 2`, expected: true},
+		"size method":         {expr: `status.phase.size() == 5`, expected: true},
+		"exists":              {expr: `status.conditions.exists(c, c.type == "Ready" && c.status == "True")`, expected: true},
+		"exists none":         {expr: `status.conditions.exists(c, c.type == "Stalled")`, expected: false},
+		"all":                 {expr: `status.conditions.all(c, c.status == "True")`, expected: false},
+		"string functions":    {expr: `metadata.name.startsWith("d") && metadata.name.endsWith("b") && kind.contains("ata")`, expected: true},
+		"or absorbs error":    {expr: `status.message == "x" || true`, expected: true},
+		"and absorbs error":   {expr: `false && status.message == "x"`, expected: false},
+		"missing field":       {expr: `status.message == "x"`, isError: true},
+		"not a bool":          {expr: `status.phase`, isError: true},
+		"invalid comparison":  {expr: `status.phase > 1`, isError: true},
+		"int and double":      {expr: `status.replicas >= 2`, isError: true},
+		"index out of bounds": {expr: `status.conditions[2].type == "x"`, isError: true},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			expr, err := CompileExpression(tc.expr)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			actual, err := expr.EvalBool(exprTestObj)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCompileExpression_Error(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`status.phase ==`,
+		`status.phase = "Ready"`,
+		`"unterminated`,
+		`(status.phase == "Ready"`,
+		`status.phase == "Ready")`,
+		`unknown(status)`,
+		`has(status)`,
+		`status.conditions.exists("c", true)`,
+		`status.phase.startsWith()`,
+		`status.phase $ 1`,
+		`unknownField == "x"`,
+	} {
+		_, err := CompileExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
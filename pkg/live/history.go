@@ -0,0 +1,241 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// DefaultRevisionHistoryLimit is the number of revisions kept for an
+// inventory. Older revisions are deleted when a revision is recorded.
+const DefaultRevisionHistoryLimit = 10
+
+// Revision is a successful apply of a package. It records the applied
+// manifests, so the revision can be applied again by rollback, and the
+// objects stored in the inventory after the apply.
+type Revision struct {
+	Number        int                          `json:"number"`
+	Timestamp     time.Time                    `json:"timestamp"`
+	ManifestsHash string                       `json:"manifestsHash"`
+	Inventory     []object.ObjMetadata         `json:"inventory"`
+	Objects       []*unstructured.Unstructured `json:"objects"`
+}
+
+// RevisionStore is implemented by the InventoryClients which record the
+// apply history of inventories.
+type RevisionStore interface {
+	// ListRevisions returns the revisions of the inventory, sorted by
+	// increasing number.
+	ListRevisions(ctx context.Context, inv inventory.InventoryInfo) ([]Revision, error)
+	// SaveRevision stores a revision of the inventory.
+	SaveRevision(ctx context.Context, inv inventory.InventoryInfo, rev Revision) error
+	// DeleteRevision deletes the revision of the inventory with the
+	// passed number.
+	DeleteRevision(ctx context.Context, inv inventory.InventoryInfo, number int) error
+}
+
+// NewRevision returns an unnumbered revision of the passed objects.
+// Inventory objects are not recorded. The manifests hash is computed
+// from the objects sorted by their metadata, so it does not depend on
+// the order of the files of the package.
+func NewRevision(objs []*unstructured.Unstructured) (Revision, error) {
+	rev := Revision{Timestamp: time.Now().UTC()}
+	for _, obj := range objs {
+		if !inventory.IsInventoryObject(obj) {
+			rev.Objects = append(rev.Objects, obj.DeepCopy())
+		}
+	}
+	sort.SliceStable(rev.Objects, func(i, j int) bool {
+		return object.UnstructuredToObjMeta(rev.Objects[i]).String() <
+			object.UnstructuredToObjMeta(rev.Objects[j]).String()
+	})
+	h := sha256.New()
+	for _, obj := range rev.Objects {
+		rev.Inventory = append(rev.Inventory, object.UnstructuredToObjMeta(obj))
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return Revision{}, err
+		}
+		h.Write(data)
+	}
+	rev.ManifestsHash = hex.EncodeToString(h.Sum(nil))
+	return rev, nil
+}
+
+// RecordRevision stores the passed objects as the next revision of the
+// inventory, and deletes the oldest revisions so at most limit revisions
+// are kept. A limit of 0 or less keeps all revisions. Returns the
+// recorded revision.
+func RecordRevision(ctx context.Context, store RevisionStore, inv inventory.InventoryInfo,
+	objs []*unstructured.Unstructured, limit int) (Revision, error) {
+	rev, err := NewRevision(objs)
+	if err != nil {
+		return Revision{}, err
+	}
+	revisions, err := store.ListRevisions(ctx, inv)
+	if err != nil {
+		return Revision{}, err
+	}
+	rev.Number = 1
+	if len(revisions) > 0 {
+		rev.Number = revisions[len(revisions)-1].Number + 1
+	}
+	if err := store.SaveRevision(ctx, inv, rev); err != nil {
+		return Revision{}, err
+	}
+	revisions = append(revisions, rev)
+	if limit > 0 && len(revisions) > limit {
+		for _, old := range revisions[:len(revisions)-limit] {
+			if err := store.DeleteRevision(ctx, inv, old.Number); err != nil {
+				return rev, err
+			}
+		}
+	}
+	return rev, nil
+}
+
+// FindRevision returns the revision with the passed number. The number
+// 0 selects the revision before the latest one, which is the one a
+// rollback returns to.
+func FindRevision(revisions []Revision, number int) (Revision, error) {
+	if number == 0 {
+		if len(revisions) < 2 {
+			return Revision{}, fmt.Errorf("no previous revision to roll back to")
+		}
+		return revisions[len(revisions)-2], nil
+	}
+	var numbers []string
+	for _, rev := range revisions {
+		if rev.Number == number {
+			return rev, nil
+		}
+		numbers = append(numbers, strconv.Itoa(rev.Number))
+	}
+	return Revision{}, fmt.Errorf("revision %d not found; available revisions: %v", number, numbers)
+}
+
+const (
+	// RevisionInventoryLabel is the label of the revision Secrets set to
+	// the ID of their inventory.
+	RevisionInventoryLabel = "kpt.dev/revision-of"
+	// RevisionNumberLabel is the label of the revision Secrets set to
+	// the number of the revision.
+	RevisionNumberLabel = "kpt.dev/revision"
+	// revisionSecretType is the type of the revision Secrets.
+	revisionSecretType corev1.SecretType = "kpt.dev/revision"
+	// revisionDataKey is the key of the revision Secret data holding the
+	// gzipped JSON revision.
+	revisionDataKey = "revision"
+)
+
+// revisionSecretName returns the name of the Secret storing the revision
+// of the inventory.
+func revisionSecretName(inv inventory.InventoryInfo, number int) string {
+	return fmt.Sprintf("%s-rev-%d", inv.Name(), number)
+}
+
+// listSecretRevisions returns the revisions of the inventory stored in
+// Secrets in the namespace of the inventory.
+func listSecretRevisions(ctx context.Context, cs kubernetes.Interface,
+	inv inventory.InventoryInfo) ([]Revision, error) {
+	list, err := cs.CoreV1().Secrets(inv.Namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", RevisionInventoryLabel, inv.ID()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var revisions []Revision
+	for _, secret := range list.Items {
+		r, err := gzip.NewReader(bytes.NewReader(secret.Data[revisionDataKey]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read revision Secret %s: %v", secret.Name, err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read revision Secret %s: %v", secret.Name, err)
+		}
+		var rev Revision
+		if err := json.Unmarshal(data, &rev); err != nil {
+			return nil, fmt.Errorf("unable to read revision Secret %s: %v", secret.Name, err)
+		}
+		revisions = append(revisions, rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// saveSecretRevision stores the revision of the inventory in a Secret in
+// the namespace of the inventory.
+func saveSecretRevision(ctx context.Context, cs kubernetes.Interface,
+	inv inventory.InventoryInfo, rev Revision) error {
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revisionSecretName(inv, rev.Number),
+			Namespace: inv.Namespace(),
+			Labels: map[string]string{
+				RevisionInventoryLabel: inv.ID(),
+				RevisionNumberLabel:    strconv.Itoa(rev.Number),
+			},
+		},
+		Type: revisionSecretType,
+		Data: map[string][]byte{revisionDataKey: buf.Bytes()},
+	}
+	_, err = cs.CoreV1().Secrets(inv.Namespace()).Create(ctx, secret, metav1.CreateOptions{})
+	return err
+}
+
+// deleteSecretRevision deletes the Secret storing the revision of the
+// inventory. A revision which does not exist is not an error.
+func deleteSecretRevision(ctx context.Context, cs kubernetes.Interface,
+	inv inventory.InventoryInfo, number int) error {
+	err := cs.CoreV1().Secrets(inv.Namespace()).Delete(ctx, revisionSecretName(inv, number),
+		metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestNewRevision(t *testing.T) {
+	a := newPlanObj("a", "1")
+	b := newPlanObj("b", "1")
+
+	rev, err := NewRevision([]*unstructured.Unstructured{inventoryObj, b, a})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []object.ObjMetadata{
+		object.UnstructuredToObjMeta(a),
+		object.UnstructuredToObjMeta(b),
+	}, rev.Inventory)
+	assert.Len(t, rev.Objects, 2)
+
+	reordered, err := NewRevision([]*unstructured.Unstructured{a, b})
+	assert.NoError(t, err)
+	assert.Equal(t, rev.ManifestsHash, reordered.ManifestsHash)
+
+	changed, err := NewRevision([]*unstructured.Unstructured{a, newPlanObj("b", "2")})
+	assert.NoError(t, err)
+	assert.NotEqual(t, rev.ManifestsHash, changed.ManifestsHash)
+}
+
+func TestRecordRevision(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	store := NewMemoryInventoryClient()
+	for i := 0; i < 4; i++ {
+		rev, err := RecordRevision(context.Background(), store, inv,
+			[]*unstructured.Unstructured{newPlanObj("a", string(rune('a'+i)))}, 3)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, i+1, rev.Number)
+	}
+	revisions, err := store.ListRevisions(context.Background(), inv)
+	assert.NoError(t, err)
+	var numbers []int
+	for _, rev := range revisions {
+		numbers = append(numbers, rev.Number)
+	}
+	assert.Equal(t, []int{2, 3, 4}, numbers)
+}
+
+func TestFindRevision(t *testing.T) {
+	revisions := []Revision{{Number: 2}, {Number: 3}, {Number: 4}}
+	testCases := map[string]struct {
+		revisions []Revision
+		number    int
+		expected  int
+		isError   bool
+	}{
+		"Previous revision is found by default": {
+			revisions: revisions,
+			expected:  3,
+		},
+		"Revision is found by number": {
+			revisions: revisions,
+			number:    2,
+			expected:  2,
+		},
+		"Unknown revision is an error": {
+			revisions: revisions,
+			number:    1,
+			isError:   true,
+		},
+		"A single revision has no previous revision": {
+			revisions: revisions[:1],
+			isError:   true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			rev, err := FindRevision(tc.revisions, tc.number)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, rev.Number)
+		})
+	}
+}
+
+func TestSecretRevisions(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	cs := fake.NewSimpleClientset()
+	ctx := context.Background()
+	for i := 1; i <= 2; i++ {
+		rev, err := NewRevision([]*unstructured.Unstructured{newPlanObj("a", string(rune('a'+i)))})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		rev.Number = i
+		assert.NoError(t, saveSecretRevision(ctx, cs, inv, rev))
+	}
+
+	revisions, err := listSecretRevisions(ctx, cs, inv)
+	if !assert.NoError(t, err) || !assert.Len(t, revisions, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, 1, revisions[0].Number)
+	assert.Equal(t, 2, revisions[1].Number)
+	assert.Equal(t, "c", revisions[1].Objects[0].Object["data"].(map[string]interface{})["key"])
+
+	assert.NoError(t, deleteSecretRevision(ctx, cs, inv, 1))
+	assert.NoError(t, deleteSecretRevision(ctx, cs, inv, 1))
+	revisions, err = listSecretRevisions(ctx, cs, inv)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+}
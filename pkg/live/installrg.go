@@ -0,0 +1,188 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+)
+
+// ResourceGroupCRDVersionLabel is the label of the ResourceGroup custom
+// resource definition holding its version. Definitions installed before
+// the label was introduced have version 0.
+const ResourceGroupCRDVersionLabel = "kpt.dev/resourcegroup-crd-version"
+
+// ResourceGroupCRDVersion is the version of the ResourceGroup custom
+// resource definition installed by this kpt. It must be incremented
+// whenever the definition changes.
+const ResourceGroupCRDVersion = 1
+
+// resourceGroupCRDName is the name of the ResourceGroup custom resource
+// definition.
+const resourceGroupCRDName = "resourcegroups.kpt.dev"
+
+// namespaceGroupKind is the GroupKind of namespaces.
+var namespaceGroupKind = schema.GroupKind{Kind: "Namespace"}
+
+// CRDStatus is the state of the ResourceGroup custom resource definition
+// in the cluster, compared with the version of this kpt.
+type CRDStatus string
+
+const (
+	// CRDNotInstalled means the definition is not installed.
+	CRDNotInstalled CRDStatus = "NotInstalled"
+	// CRDCurrent means the installed definition has the version of kpt.
+	CRDCurrent CRDStatus = "Current"
+	// CRDOutdated means the installed definition is older than kpt, and
+	// can be upgraded.
+	CRDOutdated CRDStatus = "Outdated"
+	// CRDNewer means the installed definition was installed by a newer
+	// kpt. It is never downgraded.
+	CRDNewer CRDStatus = "Newer"
+)
+
+// InstallResult is the outcome of InstallResourceGroup.
+type InstallResult struct {
+	// NamespaceCreated is true if the namespace was created.
+	NamespaceCreated bool
+	// Status is the status of the definition before the install.
+	Status CRDStatus
+	// InstalledVersion is the version of the definition before the
+	// install. It is only set if the definition was installed.
+	InstalledVersion int
+	// Upgraded is true if an outdated definition was upgraded.
+	Upgraded bool
+}
+
+// ResourceGroupCRDStatus returns the status and the version of the
+// ResourceGroup custom resource definition installed in the cluster.
+func ResourceGroupCRDStatus(ctx context.Context, d dynamic.Interface, mapper meta.RESTMapper) (CRDStatus, int, error) {
+	mapping, err := mapper.RESTMapping(crdGroupKind)
+	if err != nil {
+		return "", 0, err
+	}
+	crd, err := d.Resource(mapping.Resource).Get(ctx, resourceGroupCRDName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return CRDNotInstalled, 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	version := crdVersion(crd)
+	return compareCRDVersion(version), version, nil
+}
+
+// InstallResourceGroup installs the ResourceGroup custom resource
+// definition if it is not installed, and upgrades it if it is outdated
+// and upgrade is true. A definition installed by a newer kpt is left
+// unchanged. The namespace is created if it is not empty and does not
+// exist.
+func InstallResourceGroup(ctx context.Context, d dynamic.Interface, mapper meta.RESTMapper,
+	namespace string, upgrade bool) (InstallResult, error) {
+	result := InstallResult{}
+	if namespace != "" {
+		created, err := EnsureNamespace(ctx, d, mapper, namespace)
+		if err != nil {
+			return result, err
+		}
+		result.NamespaceCreated = created
+	}
+	mapping, err := mapper.RESTMapping(crdGroupKind)
+	if err != nil {
+		return result, err
+	}
+	crd, err := resourceGroupCRD(mapping.GroupVersionKind.Version)
+	if err != nil {
+		return result, err
+	}
+	client := d.Resource(mapping.Resource)
+	existing, err := client.Get(ctx, resourceGroupCRDName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		result.Status = CRDNotInstalled
+		klog.V(4).Infoln("creating ResourceGroup CRD...")
+		_, err = client.Create(ctx, crd, metav1.CreateOptions{})
+		return result, err
+	}
+	if err != nil {
+		return result, err
+	}
+	result.InstalledVersion = crdVersion(existing)
+	result.Status = compareCRDVersion(result.InstalledVersion)
+	if result.Status != CRDOutdated || !upgrade {
+		return result, nil
+	}
+	klog.V(4).Infof("upgrading ResourceGroup CRD from version %d to %d...",
+		result.InstalledVersion, ResourceGroupCRDVersion)
+	crd.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := client.Update(ctx, crd, metav1.UpdateOptions{}); err != nil {
+		return result, err
+	}
+	result.Upgraded = true
+	return result, nil
+}
+
+// EnsureNamespace creates the namespace if it does not exist. Returns
+// true if the namespace was created.
+func EnsureNamespace(ctx context.Context, d dynamic.Interface, mapper meta.RESTMapper, name string) (bool, error) {
+	mapping, err := mapper.RESTMapping(namespaceGroupKind)
+	if err != nil {
+		return false, err
+	}
+	client := d.Resource(mapping.Resource)
+	_, err = client.Get(ctx, name, metav1.GetOptions{})
+	if err == nil || !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(mapping.GroupVersionKind)
+	ns.SetName(name)
+	klog.V(4).Infof("creating namespace %s...", name)
+	_, err = client.Create(ctx, ns, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// crdVersion returns the version of the ResourceGroup custom resource
+// definition from its label, or 0 if the label is missing or invalid.
+func crdVersion(crd *unstructured.Unstructured) int {
+	version, err := strconv.Atoi(crd.GetLabels()[ResourceGroupCRDVersionLabel])
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// compareCRDVersion returns the status of an installed definition with
+// the passed version.
+func compareCRDVersion(version int) CRDStatus {
+	switch {
+	case version < ResourceGroupCRDVersion:
+		return CRDOutdated
+	case version > ResourceGroupCRDVersion:
+		return CRDNewer
+	default:
+		return CRDCurrent
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+var namespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+func newInstallTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		{Group: "apiextensions.k8s.io", Version: "v1"},
+		{Version: "v1"},
+	})
+	mapper.Add(crdGroupKind.WithVersion("v1"), meta.RESTScopeRoot)
+	mapper.Add(namespaceGroupKind.WithVersion("v1"), meta.RESTScopeRoot)
+	return mapper
+}
+
+// installedCRD returns the ResourceGroup CRD with the passed version
+// label, or without label if version is negative.
+func installedCRD(t *testing.T, version int) *unstructured.Unstructured {
+	crd, err := resourceGroupCRD("v1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if version < 0 {
+		crd.SetLabels(nil)
+	} else {
+		crd.SetLabels(map[string]string{ResourceGroupCRDVersionLabel: strconv.Itoa(version)})
+	}
+	return crd
+}
+
+func newNamespace(name string) *unstructured.Unstructured {
+	ns := &unstructured.Unstructured{}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName(name)
+	return ns
+}
+
+func TestInstallResourceGroup(t *testing.T) {
+	testCases := map[string]struct {
+		objs            []runtime.Object
+		namespace       string
+		upgrade         bool
+		expected        InstallResult
+		expectedVersion int
+	}{
+		"CRD and namespace are created": {
+			namespace:       "test",
+			expected:        InstallResult{NamespaceCreated: true, Status: CRDNotInstalled},
+			expectedVersion: ResourceGroupCRDVersion,
+		},
+		"Existing namespace is not created": {
+			objs:            []runtime.Object{newNamespace("test")},
+			namespace:       "test",
+			expected:        InstallResult{Status: CRDNotInstalled},
+			expectedVersion: ResourceGroupCRDVersion,
+		},
+		"Current CRD is unchanged": {
+			objs: []runtime.Object{installedCRD(t, ResourceGroupCRDVersion)},
+			expected: InstallResult{
+				Status:           CRDCurrent,
+				InstalledVersion: ResourceGroupCRDVersion,
+			},
+			expectedVersion: ResourceGroupCRDVersion,
+		},
+		"Outdated CRD is not upgraded by default": {
+			objs:            []runtime.Object{installedCRD(t, -1)},
+			expected:        InstallResult{Status: CRDOutdated},
+			expectedVersion: 0,
+		},
+		"Outdated CRD is upgraded": {
+			objs:            []runtime.Object{installedCRD(t, -1)},
+			upgrade:         true,
+			expected:        InstallResult{Status: CRDOutdated, Upgraded: true},
+			expectedVersion: ResourceGroupCRDVersion,
+		},
+		"Newer CRD is never downgraded": {
+			objs:    []runtime.Object{installedCRD(t, ResourceGroupCRDVersion+1)},
+			upgrade: true,
+			expected: InstallResult{
+				Status:           CRDNewer,
+				InstalledVersion: ResourceGroupCRDVersion + 1,
+			},
+			expectedVersion: ResourceGroupCRDVersion + 1,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tc.objs...)
+			mapper := newInstallTestMapper()
+			result, err := InstallResourceGroup(context.TODO(), d, mapper, tc.namespace, tc.upgrade)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.expected, result)
+
+			status, version, err := ResourceGroupCRDStatus(context.TODO(), d, mapper)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedVersion, version)
+			assert.Equal(t, compareCRDVersion(tc.expectedVersion), status)
+			if tc.namespace != "" {
+				_, err := d.Resource(namespaceGVR).Get(context.TODO(), tc.namespace, metav1.GetOptions{})
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResourceGroupCRDStatus_NotInstalled(t *testing.T) {
+	d := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	status, _, err := ResourceGroupCRDStatus(context.TODO(), d, newInstallTestMapper())
+	assert.NoError(t, err)
+	assert.Equal(t, CRDNotInstalled, status)
+	_, err = d.Resource(crdGVR).Get(context.TODO(), resourceGroupCRDName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,370 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+// UnlockFunc releases a lock taken by InventoryClient.Lock.
+type UnlockFunc func() error
+
+// InventoryClient stores the set of objects applied for an inventory.
+// Implementations may keep the inventory records in the target cluster
+// or in external storage, such as a Secret in another cluster or a
+// key-value store.
+type InventoryClient interface {
+	// Load returns the objects stored for the inventory. An inventory
+	// which has never been stored has no objects.
+	Load(ctx context.Context, inv inventory.InventoryInfo) ([]object.ObjMetadata, error)
+	// Store replaces the objects stored for the inventory.
+	Store(ctx context.Context, inv inventory.InventoryInfo, objs []object.ObjMetadata) error
+	// Delete removes the inventory record.
+	Delete(ctx context.Context, inv inventory.InventoryInfo) error
+	// Lock takes an exclusive lock on the inventory, blocking until the
+	// lock is taken or the context is done. The returned UnlockFunc
+	// releases the lock.
+	Lock(ctx context.Context, inv inventory.InventoryInfo) (UnlockFunc, error)
+}
+
+// InventoryClientFactory creates an InventoryClient from the provider of
+// the target cluster.
+type InventoryClientFactory func(p provider.Provider) (InventoryClient, error)
+
+// ClusterInventoryClientName is the name of the InventoryClient storing
+// the inventory in the target cluster, which is the default.
+const ClusterInventoryClientName = "cluster"
+
+var (
+	inventoryClientsMu sync.RWMutex
+	inventoryClients   = map[string]InventoryClientFactory{
+		ClusterInventoryClientName: func(p provider.Provider) (InventoryClient, error) {
+			return NewClusterInventoryClient(p), nil
+		},
+	}
+)
+
+// RegisterInventoryClient registers an InventoryClient implementation
+// under the passed name. Returns an error if the name is already taken.
+func RegisterInventoryClient(name string, factory InventoryClientFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("inventory client name and factory are required")
+	}
+	inventoryClientsMu.Lock()
+	defer inventoryClientsMu.Unlock()
+	if _, found := inventoryClients[name]; found {
+		return fmt.Errorf("inventory client %q already registered", name)
+	}
+	inventoryClients[name] = factory
+	return nil
+}
+
+// NewInventoryClient returns the InventoryClient registered under the
+// passed name, or an error if no such client is registered. The cluster
+// InventoryClient is returned if the name is empty.
+func NewInventoryClient(name string, p provider.Provider) (InventoryClient, error) {
+	if name == "" {
+		name = ClusterInventoryClientName
+	}
+	inventoryClientsMu.RLock()
+	factory, found := inventoryClients[name]
+	inventoryClientsMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown inventory client %q; must be one of %v",
+			name, InventoryClientNames())
+	}
+	return factory(p)
+}
+
+// InventoryClientNames returns the sorted names of the registered
+// InventoryClient implementations.
+func InventoryClientNames() []string {
+	inventoryClientsMu.RLock()
+	defer inventoryClientsMu.RUnlock()
+	var names []string
+	for name := range inventoryClients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClusterInventoryClient stores the inventory in the target cluster,
+// using the inventory object (ConfigMap or ResourceGroup) returned by
+// the provider's inventory client.
+type ClusterInventoryClient struct {
+	provider provider.Provider
+	locks    inventoryLocks
+}
+
+var _ InventoryClient = &ClusterInventoryClient{}
+var _ RevisionStore = &ClusterInventoryClient{}
+var _ ActuationStore = &ClusterInventoryClient{}
+
+// NewClusterInventoryClient returns a ClusterInventoryClient using
+// the inventory client of the passed provider.
+func NewClusterInventoryClient(p provider.Provider) *ClusterInventoryClient {
+	return &ClusterInventoryClient{provider: p}
+}
+
+// Load returns the objects stored in the cluster inventory object.
+func (c *ClusterInventoryClient) Load(_ context.Context, inv inventory.InventoryInfo) ([]object.ObjMetadata, error) {
+	invClient, err := c.provider.InventoryClient()
+	if err != nil {
+		return nil, err
+	}
+	return invClient.GetClusterObjs(inv)
+}
+
+// Store replaces the objects stored in the cluster inventory object.
+// The inventory object is created first if it does not exist yet,
+// since only merging creates it.
+func (c *ClusterInventoryClient) Store(_ context.Context, inv inventory.InventoryInfo, objs []object.ObjMetadata) error {
+	invClient, err := c.provider.InventoryClient()
+	if err != nil {
+		return err
+	}
+	if _, err := invClient.Merge(inv, objs); err != nil {
+		return err
+	}
+	return invClient.Replace(inv, objs)
+}
+
+// Delete deletes the cluster inventory object.
+func (c *ClusterInventoryClient) Delete(_ context.Context, inv inventory.InventoryInfo) error {
+	invClient, err := c.provider.InventoryClient()
+	if err != nil {
+		return err
+	}
+	return invClient.DeleteInventoryObj(inv)
+}
+
+// Lock takes an exclusive lock on the inventory within this process.
+// The inventory object itself is protected against concurrent writers
+// by its resourceVersion.
+func (c *ClusterInventoryClient) Lock(ctx context.Context, inv inventory.InventoryInfo) (UnlockFunc, error) {
+	return c.locks.lock(ctx, inv.ID())
+}
+
+// ListRevisions returns the revisions of the inventory, which are
+// stored in Secrets in the namespace of the inventory.
+func (c *ClusterInventoryClient) ListRevisions(ctx context.Context, inv inventory.InventoryInfo) ([]Revision, error) {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return listSecretRevisions(ctx, cs, inv)
+}
+
+// SaveRevision stores the revision in a Secret in the namespace of the
+// inventory.
+func (c *ClusterInventoryClient) SaveRevision(ctx context.Context, inv inventory.InventoryInfo, rev Revision) error {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	return saveSecretRevision(ctx, cs, inv, rev)
+}
+
+// DeleteRevision deletes the Secret storing the revision.
+func (c *ClusterInventoryClient) DeleteRevision(ctx context.Context, inv inventory.InventoryInfo, number int) error {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	return deleteSecretRevision(ctx, cs, inv, number)
+}
+
+// LoadActuation returns the actuation record of the inventory, which is
+// stored in a Secret in the namespace of the inventory.
+func (c *ClusterInventoryClient) LoadActuation(ctx context.Context, inv inventory.InventoryInfo) (*ActuationRecord, error) {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return loadSecretActuation(ctx, cs, inv)
+}
+
+// SaveActuation stores the actuation record in a Secret in the namespace
+// of the inventory.
+func (c *ClusterInventoryClient) SaveActuation(ctx context.Context, inv inventory.InventoryInfo, rec ActuationRecord) error {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	return saveSecretActuation(ctx, cs, inv, rec)
+}
+
+// DeleteActuation deletes the Secret storing the actuation record.
+func (c *ClusterInventoryClient) DeleteActuation(ctx context.Context, inv inventory.InventoryInfo) error {
+	cs, err := c.provider.Factory().KubernetesClientSet()
+	if err != nil {
+		return err
+	}
+	return deleteSecretActuation(ctx, cs, inv)
+}
+
+// MemoryInventoryClient stores inventories in memory. It is useful for
+// testing and as an example of an external InventoryClient.
+type MemoryInventoryClient struct {
+	mu        sync.Mutex
+	objs      map[string][]object.ObjMetadata
+	revisions map[string][]Revision
+	actuation map[string]ActuationRecord
+	locks     inventoryLocks
+}
+
+var _ InventoryClient = &MemoryInventoryClient{}
+var _ RevisionStore = &MemoryInventoryClient{}
+var _ ActuationStore = &MemoryInventoryClient{}
+
+// NewMemoryInventoryClient returns an empty MemoryInventoryClient.
+func NewMemoryInventoryClient() *MemoryInventoryClient {
+	return &MemoryInventoryClient{
+		objs:      map[string][]object.ObjMetadata{},
+		revisions: map[string][]Revision{},
+		actuation: map[string]ActuationRecord{},
+	}
+}
+
+// Load returns a copy of the objects stored for the inventory.
+func (c *MemoryInventoryClient) Load(_ context.Context, inv inventory.InventoryInfo) ([]object.ObjMetadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]object.ObjMetadata{}, c.objs[inv.ID()]...), nil
+}
+
+// Store replaces the objects stored for the inventory.
+func (c *MemoryInventoryClient) Store(_ context.Context, inv inventory.InventoryInfo, objs []object.ObjMetadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objs[inv.ID()] = append([]object.ObjMetadata{}, objs...)
+	return nil
+}
+
+// Delete removes the objects stored for the inventory.
+func (c *MemoryInventoryClient) Delete(_ context.Context, inv inventory.InventoryInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.objs, inv.ID())
+	return nil
+}
+
+// Lock takes an exclusive lock on the inventory.
+func (c *MemoryInventoryClient) Lock(ctx context.Context, inv inventory.InventoryInfo) (UnlockFunc, error) {
+	return c.locks.lock(ctx, inv.ID())
+}
+
+// ListRevisions returns the revisions stored for the inventory, sorted
+// by increasing number.
+func (c *MemoryInventoryClient) ListRevisions(_ context.Context, inv inventory.InventoryInfo) ([]Revision, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	revisions := append([]Revision{}, c.revisions[inv.ID()]...)
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// SaveRevision stores a revision for the inventory.
+func (c *MemoryInventoryClient) SaveRevision(_ context.Context, inv inventory.InventoryInfo, rev Revision) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revisions[inv.ID()] = append(c.revisions[inv.ID()], rev)
+	return nil
+}
+
+// DeleteRevision deletes the revision of the inventory with the passed
+// number.
+func (c *MemoryInventoryClient) DeleteRevision(_ context.Context, inv inventory.InventoryInfo, number int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var revisions []Revision
+	for _, rev := range c.revisions[inv.ID()] {
+		if rev.Number != number {
+			revisions = append(revisions, rev)
+		}
+	}
+	c.revisions[inv.ID()] = revisions
+	return nil
+}
+
+// LoadActuation returns the actuation record of the inventory, or nil.
+func (c *MemoryInventoryClient) LoadActuation(_ context.Context, inv inventory.InventoryInfo) (*ActuationRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, found := c.actuation[inv.ID()]
+	if !found {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// SaveActuation stores the actuation record of the inventory.
+func (c *MemoryInventoryClient) SaveActuation(_ context.Context, inv inventory.InventoryInfo, rec ActuationRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actuation[inv.ID()] = rec
+	return nil
+}
+
+// DeleteActuation deletes the actuation record of the inventory.
+func (c *MemoryInventoryClient) DeleteActuation(_ context.Context, inv inventory.InventoryInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.actuation, inv.ID())
+	return nil
+}
+
+// inventoryLocks holds an exclusive lock per inventory ID. The zero
+// value is ready to use.
+type inventoryLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// lock blocks until the lock for the inventory ID is taken, or returns
+// an error if the context is done first.
+func (l *inventoryLocks) lock(ctx context.Context, id string) (UnlockFunc, error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]chan struct{}{}
+	}
+	ch, found := l.locks[id]
+	if !found {
+		ch = make(chan struct{}, 1)
+		l.locks[id] = ch
+	}
+	l.mu.Unlock()
+
+	select {
+	case ch <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("unable to lock inventory %q: %v", id, ctx.Err())
+	}
+	var once sync.Once
+	return func() error {
+		once.Do(func() { <-ch })
+		return nil
+	}, nil
+}
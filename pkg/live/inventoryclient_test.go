@@ -0,0 +1,110 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/provider"
+)
+
+func TestRegisterInventoryClient(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+	defer tf.Cleanup()
+	p := NewDualDelegatingProvider(tf)
+
+	defer func() {
+		inventoryClientsMu.Lock()
+		delete(inventoryClients, "memory-test")
+		inventoryClientsMu.Unlock()
+	}()
+	memory := NewMemoryInventoryClient()
+	err := RegisterInventoryClient("memory-test", func(provider.Provider) (InventoryClient, error) {
+		return memory, nil
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, InventoryClientNames(), ClusterInventoryClientName)
+	assert.Contains(t, InventoryClientNames(), "memory-test")
+
+	// Names can only be registered once.
+	err = RegisterInventoryClient(ClusterInventoryClientName, func(provider.Provider) (InventoryClient, error) {
+		return memory, nil
+	})
+	assert.Error(t, err)
+
+	c, err := NewInventoryClient("memory-test", p)
+	assert.NoError(t, err)
+	assert.Equal(t, memory, c)
+
+	c, err = NewInventoryClient(ClusterInventoryClientName, p)
+	assert.NoError(t, err)
+	assert.IsType(t, &ClusterInventoryClient{}, c)
+
+	// The cluster InventoryClient is the default.
+	c, err = NewInventoryClient("", p)
+	assert.NoError(t, err)
+	assert.IsType(t, &ClusterInventoryClient{}, c)
+
+	_, err = NewInventoryClient("does-not-exist", p)
+	assert.Error(t, err)
+}
+
+func TestMemoryInventoryClient(t *testing.T) {
+	ctx := context.Background()
+	inv := WrapInventoryInfoObj(inventoryObj)
+	c := NewMemoryInventoryClient()
+
+	objs, err := c.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+
+	stored := []object.ObjMetadata{testDeployment, testPod}
+	assert.NoError(t, c.Store(ctx, inv, stored))
+	objs, err = c.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, objs)
+
+	assert.NoError(t, c.Delete(ctx, inv))
+	objs, err = c.Load(ctx, inv)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+}
+
+func TestInventoryClient_Lock(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	c := NewMemoryInventoryClient()
+
+	unlock, err := c.Lock(context.Background(), inv)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// A second lock on the same inventory blocks until the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Lock(ctx, inv)
+	assert.Error(t, err)
+
+	assert.NoError(t, unlock())
+	// Unlocking twice is a no-op.
+	assert.NoError(t, unlock())
+	unlock, err = c.Lock(context.Background(), inv)
+	assert.NoError(t, err)
+	assert.NoError(t, unlock())
+}
@@ -16,6 +16,7 @@ package live
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -235,19 +236,10 @@ func ApplyResourceGroupCRD(factory cmdutil.Factory) error {
 	// v1beta1 and v1 versions of the apiextensions group of the CRD.
 	version := mapping.GroupVersionKind.Version
 	klog.V(4).Infof("using apiextensions.k8s.io version: %s", version)
-	rgCRDStr, ok := resourceGroupCRDs[version]
-	if !ok {
-		klog.V(4).Infof("ResourceGroup CRD version %s not found", version)
-		return err
-	}
-	crd, err := stringToUnstructured(rgCRDStr)
+	crd, err := resourceGroupCRD(version)
 	if err != nil {
 		return err
 	}
-	// Set the "last-applied-annotation" so future applies work correctly.
-	if err := util.CreateApplyAnnotation(crd, unstructured.UnstructuredJSONScheme); err != nil {
-		return err
-	}
 	// Apply the CRD to the cluster and ignore already exists error.
 	var clearResourceVersion = false
 	var emptyNamespace = ""
@@ -257,6 +249,31 @@ func ApplyResourceGroupCRD(factory cmdutil.Factory) error {
 	return err
 }
 
+// resourceGroupCRD returns the ResourceGroup custom resource definition
+// for the apiextensions version, labeled with the ResourceGroupCRDVersion
+// and with the "last-applied-annotation" set so future applies work
+// correctly.
+func resourceGroupCRD(version string) (*unstructured.Unstructured, error) {
+	rgCRDStr, ok := resourceGroupCRDs[version]
+	if !ok {
+		return nil, fmt.Errorf("ResourceGroup CRD for apiextensions.k8s.io version %s not found", version)
+	}
+	crd, err := stringToUnstructured(rgCRDStr)
+	if err != nil {
+		return nil, err
+	}
+	labels := crd.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ResourceGroupCRDVersionLabel] = strconv.Itoa(ResourceGroupCRDVersion)
+	crd.SetLabels(labels)
+	if err := util.CreateApplyAnnotation(crd, unstructured.UnstructuredJSONScheme); err != nil {
+		return nil, err
+	}
+	return crd, nil
+}
+
 // stringToUnstructured transforms a single resource represented by
 // the passed string into a pointer to an "Unstructured" object,
 // or an error if one occurred.
@@ -0,0 +1,169 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+const (
+	// InventoryTypeResourceGroup stores the inventory in a ResourceGroup
+	// custom resource. This is the default inventory type.
+	InventoryTypeResourceGroup = "resourcegroup"
+	// InventoryTypeSecret stores the inventory in a Secret, for clusters
+	// where ConfigMaps are readable by too many users.
+	InventoryTypeSecret = "secret"
+)
+
+// ValidateInventoryType returns an error if the passed inventory type
+// is not supported. The empty string selects the default type.
+func ValidateInventoryType(invType string) error {
+	switch invType {
+	case "", InventoryTypeResourceGroup, InventoryTypeSecret:
+		return nil
+	default:
+		return fmt.Errorf("unknown inventory type %q; must be one of %q or %q",
+			invType, InventoryTypeResourceGroup, InventoryTypeSecret)
+	}
+}
+
+// InventorySecret wraps a Secret resource and implements the Inventory
+// and InventoryInfo interface. The object metadata (inventory) is stored
+// as the keys of the Secret data, in the same format as the ConfigMap
+// inventory.
+type InventorySecret struct {
+	inv      *unstructured.Unstructured
+	objMetas []object.ObjMetadata
+}
+
+var _ inventory.Inventory = &InventorySecret{}
+var _ inventory.InventoryInfo = &InventorySecret{}
+
+// WrapInventorySecretObj takes a passed Secret, wraps it with the
+// InventorySecret and upcasts the wrapper as the Inventory interface.
+func WrapInventorySecretObj(obj *unstructured.Unstructured) inventory.Inventory {
+	if obj != nil {
+		klog.V(4).Infof("wrapping Inventory Secret: %s/%s\n", obj.GetNamespace(), obj.GetName())
+	}
+	return &InventorySecret{inv: obj}
+}
+
+// Name(), Namespace(), and ID() are InventorySecret functions to
+// implement the InventoryInfo interface.
+func (is *InventorySecret) Name() string {
+	return is.inv.GetName()
+}
+
+func (is *InventorySecret) Namespace() string {
+	return is.inv.GetNamespace()
+}
+
+func (is *InventorySecret) ID() string {
+	labels := is.inv.GetLabels()
+	if val, found := labels[common.InventoryLabel]; found {
+		return val
+	}
+	return ""
+}
+
+// Load is an Inventory interface function returning the set of
+// object metadata from the wrapped Secret, or an error.
+func (is *InventorySecret) Load() ([]object.ObjMetadata, error) {
+	objs := []object.ObjMetadata{}
+	if is.inv == nil {
+		return objs, fmt.Errorf("inventory info is nil")
+	}
+	data, _, err := unstructured.NestedMap(is.inv.Object, "data")
+	if err != nil {
+		return objs, fmt.Errorf("error retrieving object metadata from inventory Secret")
+	}
+	klog.V(4).Infof("loading %d inventory items from Secret", len(data))
+	for key := range data {
+		objMeta, err := object.ParseObjMetadata(key)
+		if err != nil {
+			return []object.ObjMetadata{}, err
+		}
+		objs = append(objs, objMeta)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].String() < objs[j].String()
+	})
+	return objs, nil
+}
+
+// Store is an Inventory interface function implemented to store
+// the object metadata in the wrapped Secret. Actual storing
+// happens in "GetObject".
+func (is *InventorySecret) Store(objMetas []object.ObjMetadata) error {
+	is.objMetas = objMetas
+	return nil
+}
+
+// GetObject returns a copy of the wrapped Secret storing the object
+// metadata, or an error if one occurs.
+func (is *InventorySecret) GetObject() (*unstructured.Unstructured, error) {
+	if is.inv == nil {
+		return nil, fmt.Errorf("inventory info is nil")
+	}
+	invCopy := is.inv.DeepCopy()
+	if len(is.objMetas) == 0 {
+		klog.V(4).Infoln("clearing inventory Secret data")
+		unstructured.RemoveNestedField(invCopy.UnstructuredContent(), "data")
+		return invCopy, nil
+	}
+	// The values are empty, so they are the same base64 encoded.
+	data := map[string]interface{}{}
+	for _, objMeta := range is.objMetas {
+		data[objMeta.String()] = ""
+	}
+	klog.V(4).Infof("storing inventory (%d) resources in Secret", len(data))
+	if err := unstructured.SetNestedMap(invCopy.UnstructuredContent(), data, "data"); err != nil {
+		return nil, err
+	}
+	return invCopy, nil
+}
+
+// IsSecretInventory returns true if the passed object is a Secret
+// inventory object; false otherwise.
+func IsSecretInventory(obj *unstructured.Unstructured) bool {
+	return obj != nil && inventory.IsInventoryObject(obj) &&
+		obj.GetAPIVersion() == "v1" && obj.GetKind() == "Secret"
+}
+
+// SecretInventoryUnstructured returns a Secret inventory object with
+// the passed name, namespace and inventory id.
+func SecretInventoryUnstructured(name, namespace, id string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					common.InventoryLabel: id,
+				},
+			},
+			"type": "Opaque",
+		},
+	}
+}
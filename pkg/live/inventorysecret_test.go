@@ -0,0 +1,84 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestValidateInventoryType(t *testing.T) {
+	assert.NoError(t, ValidateInventoryType(""))
+	assert.NoError(t, ValidateInventoryType(InventoryTypeResourceGroup))
+	assert.NoError(t, ValidateInventoryType(InventoryTypeSecret))
+	assert.Error(t, ValidateInventoryType("configmap"))
+}
+
+func TestInventorySecret(t *testing.T) {
+	testCases := map[string]struct {
+		objs []object.ObjMetadata
+	}{
+		"Empty inventory": {
+			objs: []object.ObjMetadata{},
+		},
+		"Inventory with objects": {
+			objs: []object.ObjMetadata{testDeployment, testPod},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			secret := SecretInventoryUnstructured(inventoryObjName, testNamespace, testInventoryLabel)
+			assert.True(t, IsSecretInventory(secret))
+			wrapped := WrapInventorySecretObj(secret)
+			assert.NoError(t, wrapped.Store(tc.objs))
+			invObj, err := wrapped.GetObject()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, "Secret", invObj.GetKind())
+			assert.Equal(t, testInventoryLabel, invObj.GetLabels()[common.InventoryLabel])
+
+			loaded, err := WrapInventorySecretObj(invObj).Load()
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.objs, loaded)
+
+			info := &InventorySecret{inv: invObj}
+			assert.Equal(t, inventoryObjName, info.Name())
+			assert.Equal(t, testNamespace, info.Namespace())
+			assert.Equal(t, testInventoryLabel, info.ID())
+		})
+	}
+}
+
+func TestIsSecretInventory(t *testing.T) {
+	assert.False(t, IsSecretInventory(nil))
+	assert.False(t, IsSecretInventory(inventoryObj))
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "not-an-inventory",
+				"namespace": testNamespace,
+			},
+		},
+	}
+	assert.False(t, IsSecretInventory(secret))
+}
@@ -0,0 +1,545 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// Operation is what the ParallelApplier did with a single object.
+type Operation string
+
+const (
+	// OperationApplied means the object was applied.
+	OperationApplied Operation = "Applied"
+	// OperationPruned means the object was deleted, because it was
+	// removed from the package.
+	OperationPruned Operation = "Pruned"
+	// OperationPruneSkipped means the object was removed from the
+	// package, but was kept because of its on-remove annotation, or
+	// because it is owned by another inventory.
+	OperationPruneSkipped Operation = "PruneSkipped"
+	// OperationApplySkipped means the object was left untouched, because
+	// applying it conflicts with fields managed by another field manager.
+	OperationApplySkipped Operation = "ApplySkipped"
+)
+
+// ConflictStrategy is what the ParallelApplier does when applying an
+// object conflicts with fields managed by another field manager.
+type ConflictStrategy string
+
+const (
+	// ConflictFail fails the apply of the object.
+	ConflictFail ConflictStrategy = "fail"
+	// ConflictForce takes the ownership of the conflicting fields.
+	ConflictForce ConflictStrategy = "force"
+	// ConflictSkip leaves the object untouched, and keeps it in the
+	// inventory if it already was.
+	ConflictSkip ConflictStrategy = "skip"
+)
+
+// ParseConflictStrategy returns the ConflictStrategy with the passed
+// name.
+func ParseConflictStrategy(s string) (ConflictStrategy, error) {
+	switch c := ConflictStrategy(s); c {
+	case ConflictFail, ConflictForce, ConflictSkip:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown conflict strategy %q; must be one of %s, %s or %s",
+			s, ConflictFail, ConflictForce, ConflictSkip)
+	}
+}
+
+// ActuationResult is the outcome of applying or pruning a single object.
+// Err is set if the operation failed.
+type ActuationResult struct {
+	Object    object.ObjMetadata
+	Operation Operation
+	Message   string
+	Err       error
+}
+
+// ActuationError is returned when objects failed to be applied or
+// pruned.
+type ActuationError struct {
+	Results []ActuationResult
+}
+
+func (e ActuationError) Error() string {
+	var objs []string
+	for _, r := range e.Results {
+		objs = append(objs, fmt.Sprintf("%s %s/%s: %v", r.Object.GroupKind.Kind,
+			r.Object.Namespace, r.Object.Name, r.Err))
+	}
+	return fmt.Sprintf("%d resources failed:\n%s", len(e.Results), strings.Join(objs, "\n"))
+}
+
+// DefaultConcurrency is the number of objects actuated at the same time
+// if the ParallelApplier does not set it.
+const DefaultConcurrency = 1
+
+// DefaultReconcilePollInterval is how often the ParallelApplier polls the
+// status of the objects it waits for, if it does not set PollInterval.
+const DefaultReconcilePollInterval = 2 * time.Second
+
+// ParallelApplier applies the objects of a package with server-side
+// apply, actuating up to Concurrency objects at the same time. Objects
+// are applied in the groups computed by SortByDependencies, so an object
+// is only applied once all the objects it depends on were applied and,
+// if ReconcileTimeout is set, are Current. After all objects are applied,
+// the objects of the inventory which are no longer in the package are
+// pruned, also concurrently, in the reverse order of their dependencies.
+type ParallelApplier struct {
+	Client          client.Client
+	InventoryClient InventoryClient
+	// Concurrency is the number of objects actuated at the same time.
+	Concurrency int
+	// FieldManager is the field manager of the applied fields. The
+	// field manager of the client is used if it is empty.
+	FieldManager string
+	// Conflicts is what to do when applying an object conflicts with
+	// fields managed by other field managers. Applies fail by default.
+	Conflicts ConflictStrategy
+	// NoPrune leaves the objects which were removed from the package
+	// in the cluster and in the inventory.
+	NoPrune bool
+	// ReconcileTimeout is how long to wait for the objects of a
+	// dependency group to be Current before the next group is applied,
+	// and for the pruned objects of a group to be deleted before the next
+	// group is pruned. Groups are not waited for if it is zero.
+	ReconcileTimeout time.Duration
+	// PollInterval is how often the status of the objects is polled while
+	// waiting for a group.
+	PollInterval time.Duration
+	// StatusRules computes the status of the objects handled by status
+	// readers. It can be nil.
+	StatusRules *StatusRules
+	// Progress is called after every object is actuated. Calls are
+	// serialized, so it does not need to be thread-safe.
+	Progress func(result ActuationResult)
+	// Resumed is called with the record of an interrupted apply of the
+	// inventory, before it is resumed.
+	Resumed func(rec ActuationRecord)
+}
+
+// Run applies the passed objects, and prunes the objects of the
+// inventory which are not in objs, holding the lock of the inventory.
+// The inventory stores the applied objects before anything is pruned,
+// and an actuation record is kept while the inventory is not up to date,
+// so an interrupted run is resumed by the next one. No object of a
+// dependency group is applied if an object of an earlier group failed or
+// did not reconcile, and no stale object is pruned while an object
+// depending on it could not be pruned. Returns the outcome for every
+// actuated object, and an ActuationError if objects failed.
+func (a *ParallelApplier) Run(ctx context.Context, inv inventory.InventoryInfo,
+	objs []*unstructured.Unstructured) ([]ActuationResult, error) {
+	var pkgObjs []*unstructured.Unstructured
+	for _, obj := range objs {
+		if !inventory.IsInventoryObject(obj) {
+			pkgObjs = append(pkgObjs, obj)
+		}
+	}
+	groups, err := SortByDependencies(pkgObjs)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := a.InventoryClient.Lock(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = unlock()
+	}()
+	var ids []object.ObjMetadata
+	for _, obj := range pkgObjs {
+		ids = append(ids, object.UnstructuredToObjMeta(obj))
+	}
+	interrupted, err := BeginActuation(ctx, a.InventoryClient, inv, ids)
+	if err != nil {
+		return nil, err
+	}
+	if interrupted != nil && a.Resumed != nil {
+		a.Resumed(*interrupted)
+	}
+	previous, err := a.InventoryClient.Load(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ActuationResult
+	applied := map[object.ObjMetadata]bool{}
+	skipped := map[object.ObjMetadata]bool{}
+	for i, group := range groups {
+		groupResults := a.actuate(len(group), func(j int) ActuationResult {
+			return a.apply(ctx, inv, group[j])
+		})
+		results = append(results, groupResults...)
+		var groupApplied []object.ObjMetadata
+		for _, r := range groupResults {
+			switch {
+			case r.Operation == OperationApplySkipped:
+				skipped[r.Object] = true
+			case r.Err == nil:
+				applied[r.Object] = true
+				groupApplied = append(groupApplied, r.Object)
+			}
+		}
+		var groupErr error
+		if failed := failedResults(groupResults); len(failed) > 0 {
+			groupErr = ActuationError{Results: failed}
+		} else if i < len(groups)-1 {
+			if err := a.waitFor(ctx, groupApplied, status.CurrentStatus); err != nil {
+				// The next group depends on these objects, so a timeout is
+				// an error whatever the exit policy.
+				groupErr = fmt.Errorf("dependency group %d of %d: %s", i+1, len(groups), err)
+			}
+		}
+		if groupErr != nil {
+			// Record the objects applied so far, so they are pruned or
+			// kept by the next apply.
+			if err := a.InventoryClient.Store(ctx, inv, union(previous, applied)); err != nil {
+				return results, err
+			}
+			return results, a.end(ctx, inv, groupErr)
+		}
+	}
+	if err := a.InventoryClient.Store(ctx, inv, union(previous, applied)); err != nil {
+		return results, err
+	}
+	if a.NoPrune {
+		return results, a.end(ctx, inv, nil)
+	}
+
+	var stale []object.ObjMetadata
+	for _, obj := range previous {
+		switch {
+		case skipped[obj]:
+			// Objects skipped because of conflicts are still in the
+			// package, so they stay in the inventory.
+			applied[obj] = true
+		case !applied[obj]:
+			stale = append(stale, obj)
+		}
+	}
+	// Objects which were not pruned stay in the inventory, so pruning
+	// them is retried by the next apply.
+	pruneGroups, err := a.pruneGroups(ctx, stale)
+	if err != nil {
+		return results, a.end(ctx, inv, err)
+	}
+	pruneResults, pruneErr := a.pruneStale(ctx, inv, pruneGroups, applied)
+	results = append(results, pruneResults...)
+	if err := a.InventoryClient.Store(ctx, inv, union(nil, applied)); err != nil {
+		return results, err
+	}
+	return results, a.end(ctx, inv, pruneErr)
+}
+
+// PruneDependents prunes the passed objects removed from the package
+// which depend on other passed objects, in the reverse order of their
+// dependencies, and waits for them to be deleted. The remaining objects
+// can then be pruned all at once, e.g. by the cli-utils applier, without
+// deleting an object before the objects depending on it.
+func (a *ParallelApplier) PruneDependents(ctx context.Context, inv inventory.InventoryInfo,
+	stale []object.ObjMetadata) ([]ActuationResult, error) {
+	groups, err := a.pruneGroups(ctx, stale)
+	if err != nil || len(groups) < 2 {
+		return nil, err
+	}
+	results, err := a.pruneStale(ctx, inv, groups[:len(groups)-1], map[object.ObjMetadata]bool{})
+	if err != nil {
+		return results, err
+	}
+	var pruned []object.ObjMetadata
+	for _, r := range results {
+		if r.Operation == OperationPruned {
+			pruned = append(pruned, r.Object)
+		}
+	}
+	return results, a.waitFor(ctx, pruned, status.NotFoundStatus)
+}
+
+// pruneStale prunes the passed groups of objects in order, so the objects
+// of a group are only pruned once the objects of the earlier groups are
+// deleted. The objects which failed to be pruned, or were not pruned
+// because an earlier group failed, are added to kept.
+func (a *ParallelApplier) pruneStale(ctx context.Context, inv inventory.InventoryInfo,
+	groups [][]object.ObjMetadata, kept map[object.ObjMetadata]bool) ([]ActuationResult, error) {
+	var results []ActuationResult
+	for i, group := range groups {
+		groupResults := a.actuate(len(group), func(j int) ActuationResult {
+			return a.prune(ctx, inv, group[j])
+		})
+		results = append(results, groupResults...)
+		var pruned []object.ObjMetadata
+		for _, r := range groupResults {
+			switch {
+			case r.Err != nil:
+				kept[r.Object] = true
+			case r.Operation == OperationPruned:
+				pruned = append(pruned, r.Object)
+			}
+		}
+		var groupErr error
+		if failed := failedResults(groupResults); len(failed) > 0 {
+			groupErr = ActuationError{Results: failed}
+		} else if i < len(groups)-1 {
+			if err := a.waitFor(ctx, pruned, status.NotFoundStatus); err != nil {
+				groupErr = fmt.Errorf("prune group %d of %d: %s", i+1, len(groups), err)
+			}
+		}
+		if groupErr != nil {
+			for _, rest := range groups[i+1:] {
+				for _, obj := range rest {
+					kept[obj] = true
+				}
+			}
+			return results, groupErr
+		}
+	}
+	return results, nil
+}
+
+// pruneGroups returns the passed objects grouped in the reverse order of
+// the dependencies of their live objects. Objects which can not be read
+// are in the first group, so pruning them reports why.
+func (a *ParallelApplier) pruneGroups(ctx context.Context,
+	objs []object.ObjMetadata) ([][]object.ObjMetadata, error) {
+	var unread []object.ObjMetadata
+	var liveObjs []*unstructured.Unstructured
+	ids := map[*unstructured.Unstructured]object.ObjMetadata{}
+	for _, objMeta := range objs {
+		liveObj, err := a.Client.Get(ctx, objMeta)
+		if err != nil {
+			unread = append(unread, objMeta)
+			continue
+		}
+		liveObjs = append(liveObjs, liveObj)
+		ids[liveObj] = objMeta
+	}
+	sorted, err := SortByDependencies(liveObjs)
+	if err != nil {
+		return nil, err
+	}
+	groups := [][]object.ObjMetadata{unread}
+	for i, group := range ReverseGroups(sorted) {
+		var groupIDs []object.ObjMetadata
+		for _, obj := range group {
+			groupIDs = append(groupIDs, ids[obj])
+		}
+		if i == 0 {
+			groups[0] = append(groups[0], groupIDs...)
+		} else {
+			groups = append(groups, groupIDs)
+		}
+	}
+	return groups, nil
+}
+
+// waitFor polls the status of the passed objects until all of them have
+// the wanted status, for at most the reconcile timeout. It returns
+// immediately if the reconcile timeout is not set. Returns a
+// ReconcileTimeoutError with the pending objects if the timeout expires,
+// and an error if an object failed to become Current.
+func (a *ParallelApplier) waitFor(ctx context.Context, objs []object.ObjMetadata, want status.Status) error {
+	if a.ReconcileTimeout <= 0 || len(objs) == 0 {
+		return nil
+	}
+	interval := a.PollInterval
+	if interval <= 0 {
+		interval = DefaultReconcilePollInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.ReconcileTimeout)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		statuses, err := ComputeStatuses(ctx, a.Client, a.StatusRules, objs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ReconcileTimeoutError{Objects: objs}
+			}
+			return err
+		}
+		var pending []object.ObjMetadata
+		for _, s := range statuses {
+			switch {
+			case s.Status == want:
+			case s.Status == status.FailedStatus && want == status.CurrentStatus:
+				return fmt.Errorf("%s %s/%s failed: %s", s.Object.GroupKind.Kind,
+					s.Object.Namespace, s.Object.Name, s.Message)
+			default:
+				pending = append(pending, s.Object)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ReconcileTimeoutError{Objects: pending}
+		case <-ticker.C:
+		}
+	}
+}
+
+// end deletes the actuation record once the inventory is up to date,
+// and returns the passed error of the run, or the error deleting the
+// record.
+func (a *ParallelApplier) end(ctx context.Context, inv inventory.InventoryInfo, runErr error) error {
+	if err := EndActuation(ctx, a.InventoryClient, inv); err != nil && runErr == nil {
+		return err
+	}
+	return runErr
+}
+
+// actuate calls actuate for the indexes 0 to n-1 with a bounded pool of
+// workers, and returns the results in the same order.
+func (a *ParallelApplier) actuate(n int, actuate func(i int) ActuationResult) []ActuationResult {
+	results := make([]ActuationResult, n)
+	workers := a.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	var mu sync.Mutex
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = actuate(index)
+				if a.Progress != nil {
+					mu.Lock()
+					a.Progress(results[index])
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// apply server-side applies a single object, setting its owning
+// inventory annotation to the inventory.
+func (a *ParallelApplier) apply(ctx context.Context, inv inventory.InventoryInfo,
+	obj *unstructured.Unstructured) ActuationResult {
+	objMeta := object.UnstructuredToObjMeta(obj)
+	result := ActuationResult{Object: objMeta, Operation: OperationApplied}
+	obj = obj.DeepCopy()
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[client.OwningInventoryAnnotation] = inv.ID()
+	obj.SetAnnotations(annotations)
+	opts := &metav1.PatchOptions{FieldManager: a.FieldManager}
+	if a.Conflicts == ConflictForce {
+		force := true
+		opts.Force = &force
+	}
+	result.Err = a.Client.Apply(ctx, objMeta, obj, opts)
+	if apierrors.IsConflict(result.Err) && a.Conflicts == ConflictSkip {
+		result.Operation = OperationApplySkipped
+		result.Message = result.Err.Error()
+		result.Err = nil
+	}
+	return result
+}
+
+// prune deletes a single object which was removed from the package,
+// unless it is kept on remove or owned by another inventory. Objects
+// which no longer exist are reported as pruned.
+func (a *ParallelApplier) prune(ctx context.Context, inv inventory.InventoryInfo,
+	objMeta object.ObjMetadata) ActuationResult {
+	result := ActuationResult{Object: objMeta, Operation: OperationPruned}
+	liveObj, err := a.Client.Get(ctx, objMeta)
+	if apierrors.IsNotFound(err) {
+		return result
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if IsKeptOnRemove(liveObj) {
+		result.Operation = OperationPruneSkipped
+		result.Message = fmt.Sprintf("annotated with %s: %s", OnRemoveAnnotation, OnRemoveKeep)
+		return result
+	}
+	if owner, found := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys); found &&
+		owner != "" && owner != inv.ID() {
+		result.Operation = OperationPruneSkipped
+		result.Message = fmt.Sprintf("owned by inventory %q", owner)
+		return result
+	}
+	err = a.Client.Delete(ctx, objMeta, nil)
+	if err != nil && !apierrors.IsNotFound(err) {
+		result.Err = err
+	}
+	return result
+}
+
+// failedResults returns the results whose operation failed.
+func failedResults(results []ActuationResult) []ActuationResult {
+	var failed []ActuationResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// union returns the objects which are in objs or in set, in the order
+// of objs followed by the sorted remaining objects of set.
+func union(objs []object.ObjMetadata, set map[object.ObjMetadata]bool) []object.ObjMetadata {
+	seen := map[object.ObjMetadata]bool{}
+	var result []object.ObjMetadata
+	for _, obj := range objs {
+		if !seen[obj] {
+			seen[obj] = true
+			result = append(result, obj)
+		}
+	}
+	var rest []object.ObjMetadata
+	for obj := range set {
+		if set[obj] && !seen[obj] {
+			rest = append(rest, obj)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].String() < rest[j].String()
+	})
+	return append(result, rest...)
+}
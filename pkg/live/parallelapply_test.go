@@ -0,0 +1,399 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// recordingClient records the order in which objects are applied and
+// the maximum number of concurrent applies, and fails to apply the
+// objects with the names in fail.
+type recordingClient struct {
+	*client.FakeClient
+	fail map[string]bool
+
+	mu          sync.Mutex
+	applied     []string
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *recordingClient) Apply(ctx context.Context, meta object.ObjMetadata,
+	obj *unstructured.Unstructured, options *metav1.PatchOptions) error {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	if c.fail[meta.Name] {
+		return fmt.Errorf("apply failed")
+	}
+	c.applied = append(c.applied, meta.Name)
+	return c.FakeClient.Apply(ctx, meta, obj, options)
+}
+
+// dependsOn returns a copy of the object depending on the ConfigMaps
+// with the passed names.
+func dependsOn(obj *unstructured.Unstructured, names ...string) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	var refs []string
+	for _, name := range names {
+		refs = append(refs, fmt.Sprintf("/namespaces/%s/ConfigMap/%s", testNamespace, name))
+	}
+	obj.SetAnnotations(map[string]string{DependsOnAnnotation: strings.Join(refs, ",")})
+	return obj
+}
+
+func TestParallelApplier_Apply(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	var objs []*unstructured.Unstructured
+	for i := 0; i < 8; i++ {
+		objs = append(objs, newPlanObj(fmt.Sprintf("independent-%d", i), "a"))
+	}
+	objs = append(objs, dependsOn(newPlanObj("dependent", "a"), "independent-0", "independent-7"))
+
+	c := &recordingClient{FakeClient: client.NewFakeClient()}
+	invClient := NewMemoryInventoryClient()
+	a := &ParallelApplier{Client: c, InventoryClient: invClient, Concurrency: 4}
+	results, err := a.Run(context.Background(), inv, append([]*unstructured.Unstructured{inventoryObj}, objs...))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, results, len(objs))
+	assert.LessOrEqual(t, c.maxInFlight, 4)
+	assert.Equal(t, "dependent", c.applied[len(c.applied)-1])
+
+	for _, obj := range objs {
+		liveObj, err := c.Get(context.Background(), object.UnstructuredToObjMeta(obj))
+		if assert.NoError(t, err) {
+			owner, _ := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys)
+			assert.Equal(t, inv.ID(), owner)
+		}
+	}
+	stored, err := invClient.Load(context.Background(), inv)
+	assert.NoError(t, err)
+	assert.Len(t, stored, len(objs))
+}
+
+func TestParallelApplier_ApplyFailure(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	failing := newPlanObj("failing", "a")
+	succeeding := newPlanObj("succeeding", "a")
+	dependent := dependsOn(newPlanObj("dependent", "a"), "failing")
+
+	c := &recordingClient{FakeClient: client.NewFakeClient(), fail: map[string]bool{"failing": true}}
+	invClient := NewMemoryInventoryClient()
+	a := &ParallelApplier{Client: c, InventoryClient: invClient, Concurrency: 2}
+	_, err := a.Run(context.Background(), inv, []*unstructured.Unstructured{failing, succeeding, dependent})
+	if assert.Error(t, err) {
+		actuationErr, ok := err.(ActuationError)
+		if assert.True(t, ok) && assert.Len(t, actuationErr.Results, 1) {
+			assert.Equal(t, "failing", actuationErr.Results[0].Object.Name)
+		}
+	}
+	assert.Equal(t, []string{"succeeding"}, c.applied)
+	stored, err := invClient.Load(context.Background(), inv)
+	assert.NoError(t, err)
+	assert.Equal(t, []object.ObjMetadata{object.UnstructuredToObjMeta(succeeding)}, stored)
+}
+
+func TestParallelApplier_Prune(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	kept := newPlanObj("kept", "a")
+	stale := withOwner(newPlanObj("stale", "a"), inv.ID())
+	keep := withOwner(newPlanObj("keep", "a"), inv.ID())
+	keep.SetAnnotations(map[string]string{OnRemoveAnnotation: OnRemoveKeep})
+	other := withOwner(newPlanObj("other", "a"), "other-inv")
+	previous := []object.ObjMetadata{
+		object.UnstructuredToObjMeta(kept),
+		object.UnstructuredToObjMeta(stale),
+		object.UnstructuredToObjMeta(keep),
+		object.UnstructuredToObjMeta(other),
+	}
+
+	testCases := map[string]struct {
+		noPrune    bool
+		operations map[string]Operation
+		remaining  []string
+		stored     []object.ObjMetadata
+	}{
+		"Stale objects are pruned": {
+			operations: map[string]Operation{
+				"kept":  OperationApplied,
+				"stale": OperationPruned,
+				"keep":  OperationPruneSkipped,
+				"other": OperationPruneSkipped,
+			},
+			remaining: []string{"kept", "keep", "other"},
+			stored:    previous[:1],
+		},
+		"Stale objects are kept with no prune": {
+			noPrune:    true,
+			operations: map[string]Operation{"kept": OperationApplied},
+			remaining:  []string{"kept", "stale", "keep", "other"},
+			stored:     previous,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			c := client.NewFakeClient(stale, keep, other)
+			invClient := NewMemoryInventoryClient()
+			if !assert.NoError(t, invClient.Store(context.Background(), inv, previous)) {
+				t.FailNow()
+			}
+			a := &ParallelApplier{Client: c, InventoryClient: invClient, Concurrency: 3, NoPrune: tc.noPrune}
+			results, err := a.Run(context.Background(), inv, []*unstructured.Unstructured{kept})
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			operations := map[string]Operation{}
+			for _, r := range results {
+				operations[r.Object.Name] = r.Operation
+			}
+			assert.Equal(t, tc.operations, operations)
+
+			var remaining []string
+			for _, obj := range c.Objects() {
+				remaining = append(remaining, obj.GetName())
+			}
+			assert.ElementsMatch(t, tc.remaining, remaining)
+			stored, err := invClient.Load(context.Background(), inv)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.stored, stored)
+		})
+	}
+}
+
+// reconcilingClient reports the objects with the names in pending as
+// reconciling until they were read the number of times in pending, and
+// records when objects are applied, become Current and are deleted.
+type reconcilingClient struct {
+	*client.FakeClient
+
+	mu      sync.Mutex
+	pending map[string]int
+	events  []string
+}
+
+func (c *reconcilingClient) Get(ctx context.Context, meta object.ObjMetadata) (*unstructured.Unstructured, error) {
+	obj, err := c.FakeClient.Get(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, found := c.pending[meta.Name]
+	switch {
+	case found && n > 0:
+		c.pending[meta.Name] = n - 1
+		obj = obj.DeepCopy()
+		obj.SetGeneration(2)
+		_ = unstructured.SetNestedField(obj.Object, int64(1), "status", "observedGeneration")
+	case found:
+		delete(c.pending, meta.Name)
+		c.events = append(c.events, "current "+meta.Name)
+	}
+	return obj, nil
+}
+
+func (c *reconcilingClient) Apply(ctx context.Context, meta object.ObjMetadata,
+	obj *unstructured.Unstructured, options *metav1.PatchOptions) error {
+	c.mu.Lock()
+	c.events = append(c.events, "apply "+meta.Name)
+	c.mu.Unlock()
+	return c.FakeClient.Apply(ctx, meta, obj, options)
+}
+
+func (c *reconcilingClient) Delete(ctx context.Context, meta object.ObjMetadata, options *metav1.DeleteOptions) error {
+	c.mu.Lock()
+	c.events = append(c.events, "delete "+meta.Name)
+	c.mu.Unlock()
+	return c.FakeClient.Delete(ctx, meta, options)
+}
+
+func TestParallelApplier_Reconcile(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	dependency := newPlanObj("dependency", "a")
+	dependent := dependsOn(newPlanObj("dependent", "a"), "dependency")
+	staleDependency := withOwner(newPlanObj("stale-dependency", "a"), inv.ID())
+	staleDependent := withOwner(newPlanObj("stale-dependent", "a"), inv.ID())
+	staleDependent.SetAnnotations(map[string]string{
+		client.OwningInventoryAnnotation: inv.ID(),
+		DependsOnAnnotation:              "/namespaces/" + testNamespace + "/ConfigMap/stale-dependency",
+	})
+	previous := []object.ObjMetadata{
+		object.UnstructuredToObjMeta(staleDependency),
+		object.UnstructuredToObjMeta(staleDependent),
+	}
+
+	testCases := map[string]struct {
+		reads    int
+		timeout  time.Duration
+		events   []string
+		stored   []string
+		timedOut bool
+	}{
+		"Dependents are applied once their dependencies are Current": {
+			reads:   1,
+			timeout: time.Minute,
+			events: []string{
+				"apply dependency",
+				"current dependency",
+				"apply dependent",
+				"delete stale-dependent",
+				"delete stale-dependency",
+			},
+			stored: []string{"dependency", "dependent"},
+		},
+		"Dependents are not applied if their dependencies do not reconcile": {
+			reads:    1000,
+			timeout:  20 * time.Millisecond,
+			events:   []string{"apply dependency"},
+			stored:   []string{"stale-dependency", "stale-dependent", "dependency"},
+			timedOut: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			c := &reconcilingClient{
+				FakeClient: client.NewFakeClient(staleDependency, staleDependent),
+				pending:    map[string]int{"dependency": tc.reads},
+			}
+			invClient := NewMemoryInventoryClient()
+			if !assert.NoError(t, invClient.Store(ctx, inv, previous)) {
+				t.FailNow()
+			}
+			a := &ParallelApplier{
+				Client:           c,
+				InventoryClient:  invClient,
+				Concurrency:      2,
+				ReconcileTimeout: tc.timeout,
+				PollInterval:     time.Millisecond,
+			}
+			_, err := a.Run(ctx, inv, []*unstructured.Unstructured{dependent, dependency})
+			if tc.timedOut {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.events, c.events)
+
+			stored, err := invClient.Load(ctx, inv)
+			assert.NoError(t, err)
+			var names []string
+			for _, obj := range stored {
+				names = append(names, obj.Name)
+			}
+			assert.Equal(t, tc.stored, names)
+		})
+	}
+}
+
+// conflictClient fails to apply the objects with the names in conflicts
+// with a conflict error, unless the apply is forced.
+type conflictClient struct {
+	*client.FakeClient
+	conflicts map[string]bool
+}
+
+func (c *conflictClient) Apply(ctx context.Context, meta object.ObjMetadata,
+	obj *unstructured.Unstructured, options *metav1.PatchOptions) error {
+	if c.conflicts[meta.Name] && (options.Force == nil || !*options.Force) {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, meta.Name,
+			fmt.Errorf("conflict with \"other\""))
+	}
+	return c.FakeClient.Apply(ctx, meta, obj, options)
+}
+
+func TestParallelApplier_Conflicts(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	shared := newPlanObj("shared", "a")
+	owned := newPlanObj("owned", "a")
+	sharedMeta := object.UnstructuredToObjMeta(shared)
+	ownedMeta := object.UnstructuredToObjMeta(owned)
+	testCases := map[string]struct {
+		conflicts ConflictStrategy
+		operation Operation
+		failed    bool
+	}{
+		"fail": {
+			conflicts: ConflictFail,
+			operation: OperationApplied,
+			failed:    true,
+		},
+		"force": {
+			conflicts: ConflictForce,
+			operation: OperationApplied,
+		},
+		"skip": {
+			conflicts: ConflictSkip,
+			operation: OperationApplySkipped,
+		},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			c := &conflictClient{FakeClient: client.NewFakeClient(shared), conflicts: map[string]bool{"shared": true}}
+			invClient := NewMemoryInventoryClient()
+			if !assert.NoError(t, invClient.Store(ctx, inv, []object.ObjMetadata{ownedMeta, sharedMeta})) {
+				t.FailNow()
+			}
+			a := &ParallelApplier{Client: c, InventoryClient: invClient, Conflicts: tc.conflicts}
+			results, err := a.Run(ctx, inv, []*unstructured.Unstructured{inventoryObj, owned, shared})
+			assert.Equal(t, tc.failed, err != nil)
+			for _, r := range results {
+				if r.Object == sharedMeta {
+					assert.Equal(t, tc.operation, r.Operation)
+					assert.Equal(t, tc.failed, r.Err != nil)
+				}
+			}
+			stored, err := invClient.Load(ctx, inv)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []object.ObjMetadata{ownedMeta, sharedMeta}, stored)
+			_, err = c.Get(ctx, sharedMeta)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseConflictStrategy(t *testing.T) {
+	c, err := ParseConflictStrategy("skip")
+	assert.NoError(t, err)
+	assert.Equal(t, ConflictSkip, c)
+	_, err = ParseConflictStrategy("merge")
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kubectl/pkg/util"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// PlanVersion is the version of the serialized plan format.
+const PlanVersion = "v1alpha1"
+
+// ActionType is the action a plan takes for an object.
+type ActionType string
+
+const (
+	// ActionCreate creates an object which does not exist.
+	ActionCreate ActionType = "Create"
+	// ActionUpdate updates an object which has changed.
+	ActionUpdate ActionType = "Update"
+	// ActionUnchanged leaves an object which has not changed.
+	ActionUnchanged ActionType = "Unchanged"
+	// ActionPrune deletes an object which was removed from the package.
+	ActionPrune ActionType = "Prune"
+	// ActionPruneSkip keeps an object which was removed from the
+	// package, because of its on-remove annotation.
+	ActionPruneSkip ActionType = "PruneSkip"
+)
+
+// Action is the action a plan takes for a single object. The
+// resourceVersion of the object at planning time is recorded to
+// detect changes made to the cluster after the plan was computed.
+type Action struct {
+	Type            ActionType         `json:"type"`
+	Object          object.ObjMetadata `json:"object"`
+	ResourceVersion string             `json:"resourceVersion,omitempty"`
+}
+
+// Plan is the serialized result of "kpt live plan". It contains the
+// manifests to apply, including the inventory object, and the action
+// computed for every object.
+type Plan struct {
+	Version   string                       `json:"version"`
+	Inventory *unstructured.Unstructured   `json:"inventory"`
+	Objects   []*unstructured.Unstructured `json:"objects"`
+	Actions   []Action                     `json:"actions"`
+}
+
+// ComputePlan computes the actions needed to apply the passed objects
+// to the cluster. The cluster objects are the objects currently stored
+// in the inventory, and the ones missing from objs are pruned. Created
+// and updated objects are validated with a server-side dry-run apply,
+// so the passed client must be in dry-run mode. Objects in a namespace,
+// or of a custom resource, whose Namespace or CRD is created by the plan
+// are created without a dry-run, since the API server can't validate
+// them before their Namespace or CRD exists.
+func ComputePlan(ctx context.Context, c client.Client, inv inventory.InventoryInfo,
+	objs []*unstructured.Unstructured, clusterObjs []object.ObjMetadata) (*Plan, error) {
+	invObj := invToUnstructuredFunc(inv)
+	if invObj == nil {
+		return nil, fmt.Errorf("unsupported inventory object")
+	}
+	plan := &Plan{
+		Version:   PlanVersion,
+		Inventory: invObj,
+		Objects:   objs,
+	}
+	// Namespaces and CRDs are planned first, to find the ones created
+	// by the plan.
+	actions := make([]*Action, len(objs))
+	createdNamespaces := map[string]bool{}
+	createdKinds := map[schema.GroupKind]bool{}
+	for i, obj := range objs {
+		gk := obj.GroupVersionKind().GroupKind()
+		if gk != namespaceGroupKind && gk != crdGroupKind {
+			continue
+		}
+		action, err := planAction(ctx, c, obj)
+		if err != nil {
+			return nil, err
+		}
+		actions[i] = &action
+		if action.Type != ActionCreate {
+			continue
+		}
+		if gk == namespaceGroupKind {
+			createdNamespaces[obj.GetName()] = true
+		} else if kind, err := crdKind(obj); err != nil {
+			return nil, err
+		} else {
+			createdKinds[kind] = true
+		}
+	}
+	local := map[object.ObjMetadata]bool{}
+	force := true
+	for i, obj := range objs {
+		objMeta := object.UnstructuredToObjMeta(obj)
+		local[objMeta] = true
+		if createdNamespaces[objMeta.Namespace] || createdKinds[objMeta.GroupKind] {
+			plan.Actions = append(plan.Actions, Action{Type: ActionCreate, Object: objMeta})
+			continue
+		}
+		action := actions[i]
+		if action == nil {
+			a, err := planAction(ctx, c, obj)
+			if err != nil {
+				return nil, err
+			}
+			action = &a
+		}
+		if action.Type != ActionUnchanged {
+			err := c.Apply(ctx, objMeta, obj, &metav1.PatchOptions{Force: &force})
+			if err != nil {
+				return nil, fmt.Errorf("dry-run of %s %s/%s failed: %v", obj.GetKind(),
+					obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+		plan.Actions = append(plan.Actions, *action)
+	}
+	for _, objMeta := range clusterObjs {
+		if local[objMeta] {
+			continue
+		}
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		action := Action{
+			Type:            ActionPrune,
+			Object:          objMeta,
+			ResourceVersion: liveObj.GetResourceVersion(),
+		}
+		if IsKeptOnRemove(liveObj) {
+			action.Type = ActionPruneSkip
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+	return plan, nil
+}
+
+// planAction returns the action creating, updating or leaving the passed
+// object, depending on the object stored in the cluster.
+func planAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (Action, error) {
+	action := Action{Object: object.UnstructuredToObjMeta(obj)}
+	liveObj, err := c.Get(ctx, action.Object)
+	switch {
+	case apierrors.IsNotFound(err):
+		action.Type = ActionCreate
+	case err != nil:
+		return action, err
+	default:
+		action.ResourceVersion = liveObj.GetResourceVersion()
+		action.Type = ActionUnchanged
+		if changed, err := hasChanged(liveObj, obj); err != nil {
+			return action, err
+		} else if changed {
+			action.Type = ActionUpdate
+		}
+	}
+	return action, nil
+}
+
+// crdKind returns the GroupKind of the custom resources defined by the
+// passed CRD.
+func crdKind(crd *unstructured.Unstructured) (schema.GroupKind, error) {
+	group, _, err := unstructured.NestedString(crd.Object, "spec", "group")
+	if err != nil {
+		return schema.GroupKind{}, err
+	}
+	kind, _, err := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	if err != nil {
+		return schema.GroupKind{}, err
+	}
+	return schema.GroupKind{Group: group, Kind: kind}, nil
+}
+
+// hasChanged returns true if the passed object differs from the last
+// applied configuration of the live object. Objects without a last
+// applied configuration are always considered changed.
+func hasChanged(liveObj, obj *unstructured.Unstructured) (bool, error) {
+	original, err := util.GetOriginalConfiguration(liveObj)
+	if err != nil || len(original) == 0 {
+		return true, err
+	}
+	var originalMap map[string]interface{}
+	if err := json.Unmarshal(original, &originalMap); err != nil {
+		return true, err
+	}
+	local := obj.DeepCopy()
+	annotations := local.GetAnnotations()
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	local.SetAnnotations(annotations)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(local.Object, "metadata", "annotations")
+	}
+	localJSON, err := local.MarshalJSON()
+	if err != nil {
+		return true, err
+	}
+	var localMap map[string]interface{}
+	if err := json.Unmarshal(localJSON, &localMap); err != nil {
+		return true, err
+	}
+	return !reflect.DeepEqual(originalMap, localMap), nil
+}
+
+// VerifyPlan returns an error if the cluster has changed since the plan
+// was computed, so the plan can not be applied exactly.
+func VerifyPlan(ctx context.Context, c client.Client, plan *Plan) error {
+	if plan.Version != PlanVersion {
+		return fmt.Errorf("unsupported plan version %q; expected %q", plan.Version, PlanVersion)
+	}
+	for _, action := range plan.Actions {
+		liveObj, err := c.Get(ctx, action.Object)
+		switch {
+		case apierrors.IsNotFound(err):
+			liveObj = nil
+		case meta.IsNoMatchError(err) && action.Type == ActionCreate:
+			// the CRD of the object is created by the plan
+			liveObj = nil
+		case err != nil:
+			return err
+		}
+		var stale bool
+		switch action.Type {
+		case ActionCreate:
+			stale = liveObj != nil
+		default:
+			stale = liveObj == nil || liveObj.GetResourceVersion() != action.ResourceVersion
+		}
+		if stale {
+			return fmt.Errorf("plan is stale: %s %s/%s changed since the plan was computed",
+				action.Object.GroupKind.Kind, action.Object.Namespace, action.Object.Name)
+		}
+	}
+	return nil
+}
+
+// Manifests returns the inventory and objects of the plan as a stream
+// of YAML documents, which can be read by a stream manifest reader.
+func (p *Plan) Manifests() (io.Reader, error) {
+	var buf bytes.Buffer
+	for i, obj := range append([]*unstructured.Unstructured{p.Inventory}, p.Objects...) {
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(b)
+	}
+	return &buf, nil
+}
+
+// WritePlan serializes the plan as JSON to the writer.
+func WritePlan(w io.Writer, plan *Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// ReadPlan deserializes a plan written by WritePlan.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	plan := &Plan{}
+	if err := json.NewDecoder(r).Decode(plan); err != nil {
+		return nil, fmt.Errorf("unable to read plan: %v", err)
+	}
+	if plan.Inventory == nil {
+		return nil, fmt.Errorf("unable to read plan: missing inventory object")
+	}
+	return plan, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/stretchr/testify/assert"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kubectl/pkg/util"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// newPlanObj returns a ConfigMap with the passed data.
+func newPlanObj(name, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": testNamespace,
+			},
+			"data": map[string]interface{}{
+				"key": value,
+			},
+		},
+	}
+}
+
+// newLiveObj returns the passed object as applied to the cluster
+// with kubectl apply, at the passed resourceVersion.
+func newLiveObj(t *testing.T, obj *unstructured.Unstructured, rv string) *unstructured.Unstructured {
+	liveObj := obj.DeepCopy()
+	if err := util.CreateApplyAnnotation(liveObj, unstructured.UnstructuredJSONScheme); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	liveObj.SetResourceVersion(rv)
+	return liveObj
+}
+
+func TestComputePlan(t *testing.T) {
+	created := newPlanObj("created", "a")
+	unchanged := newPlanObj("unchanged", "a")
+	updated := newPlanObj("updated", "b")
+	pruned := newPlanObj("pruned", "a")
+	kept := newPlanObj("kept", "a")
+	kept.SetAnnotations(map[string]string{OnRemoveAnnotation: OnRemoveKeep})
+
+	clusterObjs := []*unstructured.Unstructured{
+		newLiveObj(t, unchanged, "1"),
+		newLiveObj(t, newPlanObj("updated", "a"), "2"),
+		newLiveObj(t, pruned, "3"),
+		newLiveObj(t, kept, "4"),
+	}
+	inv := WrapInventoryInfoObj(inventoryObj)
+	objs := []*unstructured.Unstructured{created, unchanged, updated}
+	var invObjs []object.ObjMetadata
+	for _, obj := range clusterObjs {
+		invObjs = append(invObjs, object.UnstructuredToObjMeta(obj))
+	}
+
+	plan, err := ComputePlan(context.Background(), client.NewFakeClient(clusterObjs...), inv, objs, invObjs)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, PlanVersion, plan.Version)
+	assert.Equal(t, inventoryObj, plan.Inventory)
+	assert.Equal(t, objs, plan.Objects)
+	var actions []string
+	for _, action := range plan.Actions {
+		actions = append(actions, action.Object.Name+"="+string(action.Type)+"@"+action.ResourceVersion)
+	}
+	assert.Equal(t, []string{
+		"created=Create@",
+		"unchanged=Unchanged@1",
+		"updated=Update@2",
+		"pruned=Prune@3",
+		"kept=PruneSkip@4",
+	}, actions)
+
+	// The plan is still valid against the unchanged cluster.
+	assert.NoError(t, VerifyPlan(context.Background(), client.NewFakeClient(clusterObjs...), plan))
+
+	// The plan is stale once a planned object changed in the cluster.
+	changed := append([]*unstructured.Unstructured{}, clusterObjs[1:]...)
+	changed = append(changed, newLiveObj(t, unchanged, "5"))
+	err = VerifyPlan(context.Background(), client.NewFakeClient(changed...), plan)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unchanged")
+	}
+
+	// The plan is stale once an object to create exists.
+	existing := append([]*unstructured.Unstructured{newLiveObj(t, created, "6")}, clusterObjs...)
+	assert.Error(t, VerifyPlan(context.Background(), client.NewFakeClient(existing...), plan))
+}
+
+func TestPlan_ReadWrite(t *testing.T) {
+	plan := &Plan{
+		Version:   PlanVersion,
+		Inventory: inventoryObj,
+		Objects:   []*unstructured.Unstructured{newPlanObj("cm", "a")},
+		Actions: []Action{
+			{Type: ActionCreate, Object: object.UnstructuredToObjMeta(newPlanObj("cm", "a"))},
+		},
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, WritePlan(&buf, plan)) {
+		t.FailNow()
+	}
+	read, err := ReadPlan(&buf)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, plan.Actions, read.Actions)
+	assert.Equal(t, plan.Inventory.GetName(), read.Inventory.GetName())
+	assert.Equal(t, "cm", read.Objects[0].GetName())
+
+	manifests, err := read.Manifests()
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(manifests)
+	assert.NoError(t, err)
+	docs := strings.Split(string(b), "\n---\n")
+	assert.Equal(t, 2, len(docs))
+	assert.Contains(t, docs[0], "kind: ResourceGroup")
+	assert.Contains(t, docs[1], "kind: ConfigMap")
+
+	_, err = ReadPlan(strings.NewReader(`{"version": "v1alpha1"}`))
+	assert.Error(t, err)
+}
+
+// noMatchClient returns a no match error for the objects of the custom
+// resources whose CRD isn't installed, as the API server does, and records
+// the objects which are applied.
+type noMatchClient struct {
+	*client.FakeClient
+	kinds   map[schema.GroupKind]bool
+	applied []string
+}
+
+func (c *noMatchClient) Get(ctx context.Context, meta object.ObjMetadata) (*unstructured.Unstructured, error) {
+	if c.kinds[meta.GroupKind] {
+		return nil, &apimeta.NoKindMatchError{GroupKind: meta.GroupKind}
+	}
+	return c.FakeClient.Get(ctx, meta)
+}
+
+func (c *noMatchClient) Apply(ctx context.Context, meta object.ObjMetadata, obj *unstructured.Unstructured, options *metav1.PatchOptions) error {
+	c.applied = append(c.applied, meta.Name)
+	return c.FakeClient.Apply(ctx, meta, obj, options)
+}
+
+func TestComputePlan_createdNamespaceAndCRD(t *testing.T) {
+	ns := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": testNamespace},
+	}}
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "crontabs.example.com"},
+		"spec": map[string]interface{}{
+			"group": "example.com",
+			"names": map[string]interface{}{"kind": "CronTab", "plural": "crontabs"},
+		},
+	}}
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "CronTab",
+		"metadata":   map[string]interface{}{"name": "cron", "namespace": "default"},
+	}}
+	cm := newPlanObj("config", "a")
+	c := &noMatchClient{
+		FakeClient: client.NewFakeClient().WithDryRun(true),
+		kinds:      map[schema.GroupKind]bool{{Group: "example.com", Kind: "CronTab"}: true},
+	}
+	inv := WrapInventoryInfoObj(inventoryObj)
+	objs := []*unstructured.Unstructured{cm, cr, ns, crd}
+
+	plan, err := ComputePlan(context.Background(), c, inv, objs, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var actions []string
+	for _, action := range plan.Actions {
+		actions = append(actions, action.Object.Name+"="+string(action.Type))
+	}
+	assert.Equal(t, []string{
+		"config=Create",
+		"cron=Create",
+		testNamespace + "=Create",
+		"crontabs.example.com=Create",
+	}, actions)
+	// Only the Namespace and CRD are validated with a dry-run.
+	assert.Equal(t, []string{testNamespace, "crontabs.example.com"}, c.applied)
+	assert.NoError(t, VerifyPlan(context.Background(), c, plan))
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// OnRemoveAnnotation sets what happens to an object when it is removed
+	// from the package, or when the package is destroyed. The prune step
+	// of apply and destroy skip objects annotated with OnRemoveKeep, and
+	// report them as skipped.
+	OnRemoveAnnotation = "cli-utils.sigs.k8s.io/on-remove"
+	// OnRemoveKeep keeps the object in the cluster.
+	OnRemoveKeep = "keep"
+)
+
+// ValidatePrunePolicy returns an error if an object has an on-remove
+// annotation with an unknown value. A misspelled value would otherwise
+// be ignored, and the object pruned.
+func ValidatePrunePolicy(objs []*unstructured.Unstructured) error {
+	for _, obj := range objs {
+		value, found := obj.GetAnnotations()[OnRemoveAnnotation]
+		if !found || value == OnRemoveKeep {
+			continue
+		}
+		return fmt.Errorf("invalid %s annotation on %s %s/%s: %q; the only supported value is %q",
+			OnRemoveAnnotation, obj.GetKind(), obj.GetNamespace(), obj.GetName(), value, OnRemoveKeep)
+	}
+	return nil
+}
+
+// IsKeptOnRemove returns true if the object is kept in the cluster
+// when it is pruned or destroyed; false otherwise.
+func IsKeptOnRemove(obj *unstructured.Unstructured) bool {
+	return obj.GetAnnotations()[OnRemoveAnnotation] == OnRemoveKeep
+}
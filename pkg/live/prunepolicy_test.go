@@ -0,0 +1,60 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidatePrunePolicy(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		isError     bool
+		isKept      bool
+	}{
+		"No annotation is valid": {
+			annotations: nil,
+			isError:     false,
+			isKept:      false,
+		},
+		"Keep is valid": {
+			annotations: map[string]string{OnRemoveAnnotation: OnRemoveKeep},
+			isError:     false,
+			isKept:      true,
+		},
+		"Misspelled value is an error": {
+			annotations: map[string]string{OnRemoveAnnotation: "Keep"},
+			isError:     true,
+			isKept:      false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			obj := newWaveObj("pvc", "")
+			obj.SetAnnotations(tc.annotations)
+			err := ValidatePrunePolicy([]*unstructured.Unstructured{newWaveObj("cm", ""), obj})
+			if tc.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.isKept, IsKeptOnRemove(obj))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// DefaultProtectedKinds are the high-risk kinds, whose objects are only
+// pruned with confirmation, as pruning them deletes data or other objects.
+var DefaultProtectedKinds = []schema.GroupKind{
+	{Kind: "Namespace"},
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+	{Kind: "PersistentVolumeClaim"},
+	{Group: "apps", Kind: "StatefulSet"},
+}
+
+// PruneProtection selects the objects which are only pruned with
+// confirmation.
+type PruneProtection struct {
+	// Kinds are the protected kinds.
+	Kinds map[schema.GroupKind]bool
+}
+
+// NewPruneProtection returns a PruneProtection for the default protected
+// kinds, except the allowed kinds.
+func NewPruneProtection(allow []kptfile.ResourceKind) *PruneProtection {
+	p := &PruneProtection{Kinds: map[schema.GroupKind]bool{}}
+	for _, gk := range DefaultProtectedKinds {
+		p.Kinds[gk] = true
+	}
+	for _, kind := range allow {
+		delete(p.Kinds, schema.GroupKind{Group: kind.Group, Kind: kind.Kind})
+	}
+	return p
+}
+
+// Protected returns the passed objects of the protected kinds.
+func (p *PruneProtection) Protected(objs []object.ObjMetadata) []object.ObjMetadata {
+	var protected []object.ObjMetadata
+	for _, obj := range objs {
+		if p.Kinds[obj.GroupKind] {
+			protected = append(protected, obj)
+		}
+	}
+	return protected
+}
+
+// ReadPruneAllowlist returns the allowlist of the Kptfile of the package
+// directory, or nothing if the directory has no Kptfile.
+func ReadPruneAllowlist(dir string) ([]kptfile.ResourceKind, error) {
+	if _, err := os.Stat(filepath.Join(dir, kptfile.KptFileName)); err != nil {
+		return nil, nil
+	}
+	kf, err := kptfileutil.ReadFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	return kf.PruneAllowlist, nil
+}
+
+// PrunePreview returns the objects stored in the inventory which are
+// pruned by an apply of objs, in the order of the inventory. Objects
+// which no longer exist, are kept on remove, or are owned by another
+// inventory are not pruned, as by the ParallelApplier.
+func PrunePreview(ctx context.Context, c client.Client, inv inventory.InventoryInfo,
+	stored []object.ObjMetadata, objs []*unstructured.Unstructured) ([]object.ObjMetadata, error) {
+	inPackage := map[object.ObjMetadata]bool{}
+	for _, obj := range objs {
+		inPackage[object.UnstructuredToObjMeta(obj)] = true
+	}
+	var pruned []object.ObjMetadata
+	for _, objMeta := range stored {
+		if inPackage[objMeta] {
+			continue
+		}
+		liveObj, err := c.Get(ctx, objMeta)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if IsKeptOnRemove(liveObj) {
+			continue
+		}
+		if owner, found := client.OwningInventory(liveObj, client.DefaultOwningInventoryKeys); found &&
+			owner != "" && owner != inv.ID() {
+			continue
+		}
+		pruned = append(pruned, objMeta)
+	}
+	return pruned, nil
+}
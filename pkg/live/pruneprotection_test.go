@@ -0,0 +1,91 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestPruneProtection(t *testing.T) {
+	pvc := object.ObjMetadata{Name: "data", Namespace: testNamespace}
+	pvc.GroupKind.Kind = "PersistentVolumeClaim"
+	ns := object.ObjMetadata{Name: testNamespace}
+	ns.GroupKind.Kind = "Namespace"
+	cm := object.UnstructuredToObjMeta(newPlanObj("cm", "a"))
+	objs := []object.ObjMetadata{pvc, ns, cm}
+
+	p := NewPruneProtection(nil)
+	assert.Equal(t, []object.ObjMetadata{pvc, ns}, p.Protected(objs))
+
+	p = NewPruneProtection([]kptfile.ResourceKind{{Kind: "PersistentVolumeClaim"}})
+	assert.Equal(t, []object.ObjMetadata{ns}, p.Protected(objs))
+}
+
+func TestReadPruneAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prune-allowlist-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	allow, err := ReadPruneAllowlist(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, allow)
+
+	kptfileContent := `apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: pkg
+pruneAllowlist:
+- group: apps
+  kind: StatefulSet
+`
+	err = ioutil.WriteFile(filepath.Join(dir, kptfile.KptFileName), []byte(kptfileContent), 0600)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	allow, err = ReadPruneAllowlist(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []kptfile.ResourceKind{{Group: "apps", Kind: "StatefulSet"}}, allow)
+}
+
+func TestPrunePreview(t *testing.T) {
+	inv := WrapInventoryInfoObj(inventoryObj)
+	kept := newPlanObj("kept", "a")
+	kept.SetAnnotations(map[string]string{OnRemoveAnnotation: OnRemoveKeep})
+	other := newPlanObj("other", "a")
+	other.SetAnnotations(map[string]string{client.OwningInventoryAnnotation: "other"})
+	applied := newPlanObj("applied", "a")
+	removed := newPlanObj("removed", "a")
+	c := client.NewFakeClient(kept, other, applied, removed)
+
+	var stored []object.ObjMetadata
+	for _, obj := range []*unstructured.Unstructured{kept, other, applied, removed, newPlanObj("deleted", "a")} {
+		stored = append(stored, object.UnstructuredToObjMeta(obj))
+	}
+	pruned, err := PrunePreview(context.Background(), c, inv, stored, []*unstructured.Unstructured{applied})
+	assert.NoError(t, err)
+	assert.Equal(t, []object.ObjMetadata{object.UnstructuredToObjMeta(removed)}, pruned)
+}
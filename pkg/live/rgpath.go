@@ -18,6 +18,8 @@ import (
 // manifest reader.
 type ResourceGroupPathManifestReader struct {
 	pathReader *manifestreader.PathManifestReader
+	// inventoryType overrides the inventory type in the Kptfile if set.
+	inventoryType string
 }
 
 // Read reads the manifests and returns them as Info objects.
@@ -38,7 +40,7 @@ func (p *ResourceGroupPathManifestReader) Read() ([]*unstructured.Unstructured,
 		klog.V(4).Infof("unable to parse Kptfile for ResourceGroup inventory: %s", err)
 		return objs, nil
 	}
-	inv := kf.Inventory
+	inv := withInventoryType(kf.Inventory, p.inventoryType)
 	invObj, err := generateInventoryObj(inv)
 	if err == nil {
 		klog.V(4).Infof("from Kptfile generating ResourceGroup inventory object %s/%s/%s",
@@ -50,6 +52,38 @@ func (p *ResourceGroupPathManifestReader) Read() ([]*unstructured.Unstructured,
 	return objs, nil
 }
 
+// ReadResourceGroupInventory returns the ResourceGroup inventory object
+// generated from the Kptfile in the passed package directory, or nil if
+// the package does not specify a valid ResourceGroup inventory.
+func ReadResourceGroupInventory(dir string) *unstructured.Unstructured {
+	kf, err := kptfileutil.ReadFile(dir)
+	if err != nil {
+		klog.V(4).Infof("unable to parse Kptfile for ResourceGroup inventory: %s", err)
+		return nil
+	}
+	invObj, err := generateInventoryObj(kf.Inventory)
+	if err != nil {
+		klog.V(4).Infof("unable to generate ResourceGroup inventory: %s", err)
+		return nil
+	}
+	if invObj.GetKind() != ResourceGroupGVK.Kind {
+		return nil
+	}
+	return invObj
+}
+
+// withInventoryType returns a copy of the passed Kptfile inventory with
+// the type overridden by invType, or the inventory itself if invType is
+// empty.
+func withInventoryType(inv *kptfile.Inventory, invType string) *kptfile.Inventory {
+	if inv == nil || invType == "" {
+		return inv
+	}
+	invCopy := *inv
+	invCopy.Type = invType
+	return &invCopy
+}
+
 // generateInventoryObj returns the ResourceGroupInventory object using the
 // passed information.
 func generateInventoryObj(inv *kptfile.Inventory) (*unstructured.Unstructured, error) {
@@ -57,8 +91,15 @@ func generateInventoryObj(inv *kptfile.Inventory) (*unstructured.Unstructured, e
 	if isValid, err := kptfileutil.ValidateInventory(inv); !isValid {
 		return nil, err
 	}
-	// Create and return ResourceGroup custom resource as inventory object.
+	if err := ValidateInventoryType(inv.Type); err != nil {
+		return nil, err
+	}
+	// Create and return ResourceGroup custom resource (or Secret) as
+	// inventory object.
 	var inventoryObj = ResourceGroupUnstructured(inv.Name, inv.Namespace, inv.InventoryID)
+	if inv.Type == InventoryTypeSecret {
+		inventoryObj = SecretInventoryUnstructured(inv.Name, inv.Namespace, inv.InventoryID)
+	}
 	labels := inv.Labels
 	if labels == nil {
 		labels = make(map[string]string)
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -78,6 +79,18 @@ inventory:
     random-key: random-value
 `
 
+var kptFileSecretInventory = `
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: test1
+inventory:
+  namespace: test-namespace
+  name: inventory-obj-name
+  inventoryID: XXXXXXXXXX-FOOOOOO
+  type: secret
+`
+
 var podA = `
 apiVersion: v1
 kind: Pod
@@ -248,3 +261,72 @@ func TestResourceGroupUnstructured(t *testing.T) {
 		t.Fatalf("resourcegroup inventory id expected %s, but got %s", id, rg.GetLabels()[common.InventoryLabel])
 	}
 }
+
+func TestReadResourceGroupInventory(t *testing.T) {
+	testCases := map[string]struct {
+		kptfile string
+		isNil   bool
+	}{
+		"Kptfile with inventory returns ResourceGroup": {
+			kptfile: kptFile,
+			isNil:   false,
+		},
+		"Kptfile missing inventory id returns nil": {
+			kptfile: kptFileMissingID,
+			isNil:   true,
+		},
+		"Kptfile with Secret inventory returns nil": {
+			kptfile: kptFileSecretInventory,
+			isNil:   true,
+		},
+		"Missing Kptfile returns nil": {
+			isNil: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "rg-inventory-test")
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			if tc.kptfile != "" {
+				err := ioutil.WriteFile(filepath.Join(dir, "Kptfile"), []byte(tc.kptfile), 0600)
+				assert.NoError(t, err)
+			}
+			invObj := ReadResourceGroupInventory(dir)
+			if tc.isNil {
+				assert.Nil(t, invObj)
+				return
+			}
+			if !assert.NotNil(t, invObj) {
+				t.FailNow()
+			}
+			assert.Equal(t, ResourceGroupGVK.Kind, invObj.GetKind())
+			assert.Equal(t, inventoryName, invObj.GetName())
+			assert.Equal(t, inventoryNamespace, invObj.GetNamespace())
+			assert.Equal(t, inventoryID, invObj.GetLabels()[common.InventoryLabel])
+		})
+	}
+}
+
+func TestGenerateInventoryObj_Type(t *testing.T) {
+	inv := &kptfile.Inventory{
+		Namespace:   inventoryNamespace,
+		Name:        inventoryName,
+		InventoryID: inventoryID,
+	}
+	invObj, err := generateInventoryObj(inv)
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceGroupGVK.Kind, invObj.GetKind())
+
+	invObj, err = generateInventoryObj(withInventoryType(inv, InventoryTypeSecret))
+	assert.NoError(t, err)
+	assert.True(t, IsSecretInventory(invObj))
+	assert.Equal(t, inventoryName, invObj.GetName())
+	// The override does not modify the Kptfile inventory.
+	assert.Equal(t, "", inv.Type)
+
+	_, err = generateInventoryObj(withInventoryType(inv, "configmap"))
+	assert.Error(t, err)
+}
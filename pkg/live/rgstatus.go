@@ -0,0 +1,160 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// ResourceStatus is the status of a single inventory object, as
+// stored in the "status.resourceStatuses" field of a ResourceGroup.
+type ResourceStatus struct {
+	object.ObjMetadata
+	Status status.Status
+}
+
+// SetResourceStatuses stores the passed statuses in the status of the
+// ResourceGroup, and sets the observed generation to the generation of
+// the ResourceGroup. Returns an error if one occurs.
+func SetResourceStatuses(rg *unstructured.Unstructured, statuses []ResourceStatus) error {
+	if rg == nil {
+		return fmt.Errorf("inventory object is nil")
+	}
+	var items []interface{}
+	for _, s := range statuses {
+		items = append(items, map[string]interface{}{
+			"group":     s.GroupKind.Group,
+			"kind":      s.GroupKind.Kind,
+			"namespace": s.Namespace,
+			"name":      s.Name,
+			"status":    string(s.Status),
+		})
+	}
+	err := unstructured.SetNestedField(rg.Object, rg.GetGeneration(),
+		"status", "observedGeneration")
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		unstructured.RemoveNestedField(rg.Object, "status", "resourceStatuses")
+		return nil
+	}
+	return unstructured.SetNestedSlice(rg.Object, items, "status", "resourceStatuses")
+}
+
+// UpdateResourceGroupStatus computes the status of every object stored
+// in the passed ResourceGroup inventory, and writes the statuses to the
+// status subresource of the ResourceGroup in the cluster. Objects that
+// can not be found are recorded with the "NotFound" status.
+func UpdateResourceGroupStatus(ctx context.Context, d dynamic.Interface, mapper meta.RESTMapper,
+	inv inventory.InventoryInfo) error {
+	mapping, err := mapper.RESTMapping(ResourceGroupGVK.GroupKind(), ResourceGroupGVK.Version)
+	if err != nil {
+		return err
+	}
+	rgClient := d.Resource(mapping.Resource).Namespace(inv.Namespace())
+	rg, err := rgClient.Get(ctx, inv.Name(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	objMetas, err := WrapInventoryObj(rg).Load()
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("computing status of %d inventory objects", len(objMetas))
+	c := client.NewClient(d, mapper)
+	var statuses []ResourceStatus
+	for _, objMeta := range objMetas {
+		obj, err := c.Get(ctx, objMeta)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		statuses = append(statuses, ResourceStatus{
+			ObjMetadata: objMeta,
+			Status:      computeStatus(obj),
+		})
+	}
+	if err := SetResourceStatuses(rg, statuses); err != nil {
+		return err
+	}
+	klog.V(4).Infof("updating status of inventory %s/%s", rg.GetNamespace(), rg.GetName())
+	_, err = rgClient.UpdateStatus(ctx, rg, metav1.UpdateOptions{})
+	return err
+}
+
+// computeStatus returns the kstatus of the passed object, which
+// is nil if the object was not found in the cluster.
+func computeStatus(obj *unstructured.Unstructured) status.Status {
+	if obj == nil {
+		return status.NotFoundStatus
+	}
+	result, err := status.Compute(obj)
+	if err != nil {
+		klog.V(4).Infof("unable to compute status of %s/%s: %v",
+			obj.GetNamespace(), obj.GetName(), err)
+		return status.UnknownStatus
+	}
+	return result.Status
+}
+
+// ObjectStatus is the status of a single object, with a message
+// explaining it.
+type ObjectStatus struct {
+	Object  object.ObjMetadata
+	Status  status.Status
+	Message string
+}
+
+// ComputeStatuses computes the status of every passed object. The status
+// of objects handled by the status rules is computed with their status
+// reader; the rules can be nil. Objects that can not be found have the
+// "NotFound" status.
+func ComputeStatuses(ctx context.Context, c client.Client, rules *StatusRules,
+	objs []object.ObjMetadata) ([]ObjectStatus, error) {
+	var statuses []ObjectStatus
+	for _, objMeta := range objs {
+		s := ObjectStatus{Object: objMeta}
+		obj, err := c.Get(ctx, objMeta)
+		switch {
+		case apierrors.IsNotFound(err):
+			s.Status, s.Message = status.NotFoundStatus, "Resource not found"
+		case err != nil:
+			return nil, err
+		case rules != nil && rules.Handles(objMeta.GroupKind):
+			s.Status, s.Message = rules.Status(obj)
+		default:
+			result, err := status.Compute(obj)
+			if err != nil {
+				s.Status, s.Message = status.UnknownStatus, err.Error()
+			} else {
+				s.Status, s.Message = result.Status, result.Message
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestSetResourceStatuses(t *testing.T) {
+	testCases := map[string]struct {
+		statuses []ResourceStatus
+		expected []interface{}
+	}{
+		"No statuses clears the resource statuses": {
+			statuses: []ResourceStatus{},
+			expected: nil,
+		},
+		"Statuses are stored in order": {
+			statuses: []ResourceStatus{
+				{ObjMetadata: testDeployment, Status: status.CurrentStatus},
+				{ObjMetadata: testPod, Status: status.NotFoundStatus},
+			},
+			expected: []interface{}{
+				map[string]interface{}{
+					"group":     testDeployment.GroupKind.Group,
+					"kind":      testDeployment.GroupKind.Kind,
+					"namespace": testDeployment.Namespace,
+					"name":      testDeployment.Name,
+					"status":    "Current",
+				},
+				map[string]interface{}{
+					"group":     testPod.GroupKind.Group,
+					"kind":      testPod.GroupKind.Kind,
+					"namespace": testPod.Namespace,
+					"name":      testPod.Name,
+					"status":    "NotFound",
+				},
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			rg := inventoryObj.DeepCopy()
+			rg.SetGeneration(3)
+			err := SetResourceStatuses(rg, tc.statuses)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			generation, _, err := unstructured.NestedInt64(rg.Object, "status", "observedGeneration")
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), generation)
+			actual, _, err := unstructured.NestedSlice(rg.Object, "status", "resourceStatuses")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSetResourceStatuses_NilInventory(t *testing.T) {
+	err := SetResourceStatuses(nil, []ResourceStatus{})
+	assert.Error(t, err)
+}
+
+func TestComputeStatus(t *testing.T) {
+	assert.Equal(t, status.NotFoundStatus, computeStatus(nil))
+
+	cm := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      "cm",
+				"namespace": testNamespace,
+			},
+		},
+	}
+	assert.Equal(t, status.CurrentStatus, computeStatus(cm))
+}
+
+func TestComputeStatuses(t *testing.T) {
+	rules, err := NewStatusRules([]kptfile.StatusReader{databaseReader})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cm := newPlanObj("cm", "a")
+	db := newDatabase("db", "Pending")
+	missing := newPlanObj("missing", "a")
+	objs := []object.ObjMetadata{
+		object.UnstructuredToObjMeta(cm),
+		object.UnstructuredToObjMeta(db),
+		object.UnstructuredToObjMeta(missing),
+	}
+	c := client.NewFakeClient(cm, db)
+
+	statuses, err := ComputeStatuses(context.Background(), c, rules, objs)
+	assert.NoError(t, err)
+	var actual []status.Status
+	for i, s := range statuses {
+		assert.Equal(t, objs[i], s.Object)
+		actual = append(actual, s.Status)
+	}
+	assert.Equal(t, []status.Status{status.CurrentStatus, status.InProgressStatus,
+		status.NotFoundStatus}, actual)
+
+	// Without rules, the status of custom resources is computed from
+	// their conditions.
+	statuses, err = ComputeStatuses(context.Background(), c, nil, objs[1:2])
+	assert.NoError(t, err)
+	assert.Equal(t, status.CurrentStatus, statuses[0].Status)
+}
@@ -19,6 +19,8 @@ import (
 // manifest reader.
 type ResourceGroupStreamManifestReader struct {
 	streamReader *manifestreader.StreamManifestReader
+	// inventoryType overrides the inventory type in the Kptfile if set.
+	inventoryType string
 }
 
 var ResourceSeparator = []byte("\n---\n")
@@ -48,7 +50,7 @@ func (p *ResourceGroupStreamManifestReader) Read() ([]*unstructured.Unstructured
 			}
 		} else {
 			klog.V(4).Infoln("found Kptfile during stream Read()")
-			rgObj, err = transformKptfile(r)
+			rgObj, err = transformKptfile(r, p.inventoryType)
 			if err == nil {
 				klog.V(4).Infof("created ResourceGroup inventory from Kptfile: %s/%s",
 					rgObj.GetNamespace(), rgObj.GetName())
@@ -81,8 +83,9 @@ func isKptfile(resource []byte) bool {
 }
 
 // transformKptfile transforms the passed kptfile resource config
-// into the ResourceGroup inventory object, or an error.
-func transformKptfile(resource []byte) (*unstructured.Unstructured, error) {
+// into the ResourceGroup (or Secret) inventory object, or an error.
+// A non-empty invType overrides the inventory type of the Kptfile.
+func transformKptfile(resource []byte, invType string) (*unstructured.Unstructured, error) {
 	d := yaml.NewDecoder(bytes.NewReader(resource))
 	d.KnownFields(true)
 	if err := d.Decode(&kptFileTemplate); err != nil {
@@ -91,7 +94,7 @@ func transformKptfile(resource []byte) (*unstructured.Unstructured, error) {
 	if kptFileTemplate.ResourceMeta.TypeMeta != kptfile.TypeMeta.TypeMeta {
 		return nil, fmt.Errorf("invalid kptfile type: %s", kptFileTemplate.ResourceMeta.TypeMeta)
 	}
-	inv := kptFileTemplate.Inventory
+	inv := withInventoryType(kptFileTemplate.Inventory, invType)
 	klog.V(4).Infof("generating ResourceGroup inventory object %s/%s/%s", inv.Namespace, inv.Name, inv.InventoryID)
 	return generateInventoryObj(inv)
 }
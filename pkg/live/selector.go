@@ -0,0 +1,99 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+)
+
+// ResourceFilter selects a subset of the objects of a package. An object
+// is selected if it matches the label selector, one of the kinds, and
+// one of the names. Empty criteria match all objects.
+type ResourceFilter struct {
+	// Selector is a label selector.
+	Selector labels.Selector
+	// Kinds are kinds, or kinds qualified with their group as
+	// "<kind>.<group>", matched case-insensitively.
+	Kinds []string
+	// Names are object names.
+	Names []string
+}
+
+// NewResourceFilter returns a filter for the passed label selector,
+// kinds and names, or nil if all of them are empty. Returns an error if
+// the label selector is invalid.
+func NewResourceFilter(selector string, kinds, names []string) (*ResourceFilter, error) {
+	if selector == "" && len(kinds) == 0 && len(names) == 0 {
+		return nil, nil
+	}
+	f := &ResourceFilter{Selector: labels.Everything(), Kinds: kinds, Names: names}
+	if selector != "" {
+		var err error
+		if f.Selector, err = labels.Parse(selector); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+	}
+	return f, nil
+}
+
+// Matches returns true if the object is selected by the filter.
+func (f *ResourceFilter) Matches(obj *unstructured.Unstructured) bool {
+	if f.Selector != nil && !f.Selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if len(f.Kinds) > 0 {
+		gk := obj.GroupVersionKind().GroupKind()
+		found := false
+		for _, kind := range f.Kinds {
+			if strings.EqualFold(kind, gk.Kind) || strings.EqualFold(kind, gk.String()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Names) > 0 {
+		found := false
+		for _, name := range f.Names {
+			if name == obj.GetName() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the objects selected by the filter. Inventory objects
+// are always returned.
+func (f *ResourceFilter) Filter(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) || f.Matches(obj) {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestResourceFilter(t *testing.T) {
+	app := newPlanObj("app", "a")
+	app.SetLabels(map[string]string{"tier": "app"})
+	crd := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata": map[string]interface{}{
+				"name": "databases.example.com",
+			},
+		},
+	}
+	db := newDatabase("db", "")
+	objs := []*unstructured.Unstructured{inventoryObj, app, crd, db}
+
+	testCases := map[string]struct {
+		selector string
+		kinds    []string
+		names    []string
+		expected []string
+		isError  bool
+	}{
+		"No criteria selects all objects": {
+			expected: []string{inventoryObjName, "app", "databases.example.com", "db"},
+		},
+		"Label selector": {
+			selector: "tier=app",
+			expected: []string{inventoryObjName, "app"},
+		},
+		"Kinds are matched case-insensitively": {
+			kinds:    []string{"customresourcedefinition"},
+			expected: []string{inventoryObjName, "databases.example.com"},
+		},
+		"Kinds can be qualified with their group": {
+			kinds:    []string{"Database.example.com", "ConfigMap"},
+			expected: []string{inventoryObjName, "app", "db"},
+		},
+		"All criteria must match": {
+			kinds:    []string{"ConfigMap", "Database"},
+			names:    []string{"db"},
+			expected: []string{inventoryObjName, "db"},
+		},
+		"Invalid selector is an error": {
+			selector: "tier in (",
+			isError:  true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			f, err := NewResourceFilter(tc.selector, tc.kinds, tc.names)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			filtered := objs
+			if f != nil {
+				filtered = f.Filter(objs)
+			}
+			var names []string
+			for _, obj := range filtered {
+				names = append(names, obj.GetName())
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
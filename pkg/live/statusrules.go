@@ -0,0 +1,203 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile/kptfileutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// StatusConfigKind is the kind of the status config file, which declares
+// status readers outside of the Kptfile.
+const StatusConfigKind = "StatusConfig"
+
+// statusConfig is the content of a status config file.
+type statusConfig struct {
+	yaml.ResourceMeta `yaml:",inline"`
+	StatusReaders     []kptfile.StatusReader `yaml:"statusReaders,omitempty"`
+}
+
+// ReadStatusReaders returns the status readers declared in the Kptfile
+// of the package directory, followed by the ones declared in the status
+// config file. Either can be empty.
+func ReadStatusReaders(dir, configFile string) ([]kptfile.StatusReader, error) {
+	var readers []kptfile.StatusReader
+	if dir != "" {
+		if _, err := os.Stat(filepath.Join(dir, kptfile.KptFileName)); err == nil {
+			kf, err := kptfileutil.ReadFile(dir)
+			if err != nil {
+				return nil, err
+			}
+			readers = append(readers, kf.StatusReaders...)
+		}
+	}
+	if configFile != "" {
+		f, err := os.Open(configFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		config := statusConfig{}
+		d := yaml.NewDecoder(f)
+		d.KnownFields(true)
+		if err := d.Decode(&config); err != nil {
+			return nil, fmt.Errorf("unable to parse status config %s: %v", configFile, err)
+		}
+		if config.Kind != StatusConfigKind {
+			return nil, fmt.Errorf("unable to parse status config %s: kind must be %s",
+				configFile, StatusConfigKind)
+		}
+		readers = append(readers, config.StatusReaders...)
+	}
+	return readers, nil
+}
+
+// StatusRules computes the status of resources with the expressions of
+// status readers.
+type StatusRules struct {
+	rules map[schema.GroupKind]statusRule
+}
+
+type statusRule struct {
+	ready  *Expression
+	failed *Expression
+}
+
+// NewStatusRules compiles the expressions of the passed status readers.
+// Returns an error if an expression is invalid, or a kind has more than
+// one reader.
+func NewStatusRules(readers []kptfile.StatusReader) (*StatusRules, error) {
+	r := &StatusRules{rules: map[schema.GroupKind]statusRule{}}
+	for _, reader := range readers {
+		gk := schema.GroupKind{Group: reader.Group, Kind: reader.Kind}
+		if reader.Kind == "" || reader.Ready == "" {
+			return nil, fmt.Errorf("status reader for %q requires a kind and a ready expression", gk)
+		}
+		if _, found := r.rules[gk]; found {
+			return nil, fmt.Errorf("duplicate status reader for %q", gk)
+		}
+		var rule statusRule
+		var err error
+		if rule.ready, err = CompileExpression(reader.Ready); err != nil {
+			return nil, err
+		}
+		if reader.Failed != "" {
+			if rule.failed, err = CompileExpression(reader.Failed); err != nil {
+				return nil, err
+			}
+		}
+		r.rules[gk] = rule
+	}
+	return r, nil
+}
+
+// Handles returns true if the status of the passed kind is computed by
+// a status reader.
+func (r *StatusRules) Handles(gk schema.GroupKind) bool {
+	_, found := r.rules[gk]
+	return found
+}
+
+// Status computes the status of the passed object, and a message
+// explaining it. An expression which can not be evaluated, e.g. because
+// the status of the object was not set yet, means the object is in
+// progress.
+func (r *StatusRules) Status(obj *unstructured.Unstructured) (status.Status, string) {
+	rule, found := r.rules[obj.GroupVersionKind().GroupKind()]
+	if !found {
+		return status.UnknownStatus, "no status reader"
+	}
+	if rule.failed != nil {
+		if failed, err := rule.failed.EvalBool(obj.Object); err == nil && failed {
+			return status.FailedStatus, fmt.Sprintf("%s is true", rule.failed)
+		}
+	}
+	ready, err := rule.ready.EvalBool(obj.Object)
+	switch {
+	case err != nil:
+		return status.InProgressStatus, err.Error()
+	case ready:
+		return status.CurrentStatus, "Resource is Ready"
+	default:
+		return status.InProgressStatus, fmt.Sprintf("%s is false", rule.ready)
+	}
+}
+
+// WaitForStatus polls the passed objects handled by the status rules at
+// the interval until all of them are Current. Returns an error if an
+// object failed, or the context is done first.
+func WaitForStatus(ctx context.Context, c client.Client, rules *StatusRules,
+	objs []object.ObjMetadata, interval time.Duration) error {
+	pending := map[object.ObjMetadata]string{}
+	for _, obj := range objs {
+		if rules.Handles(obj.GroupKind) {
+			pending[obj] = "not checked yet"
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for obj := range pending {
+			liveObj, err := c.Get(ctx, obj)
+			if apierrors.IsNotFound(err) {
+				pending[obj] = "resource not found"
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			s, message := rules.Status(liveObj)
+			switch s {
+			case status.CurrentStatus:
+				delete(pending, obj)
+			case status.FailedStatus:
+				return fmt.Errorf("%s %s/%s failed: %s", obj.GroupKind.Kind,
+					obj.Namespace, obj.Name, message)
+			default:
+				pending[obj] = message
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			var reasons []string
+			for obj, message := range pending {
+				reasons = append(reasons, fmt.Sprintf("%s %s/%s: %s", obj.GroupKind.Kind,
+					obj.Namespace, obj.Name, message))
+			}
+			sort.Strings(reasons)
+			return fmt.Errorf("timed out waiting for resources to be ready:\n%s",
+				strings.Join(reasons, "\n"))
+		case <-ticker.C:
+		}
+	}
+}
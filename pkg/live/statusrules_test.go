@@ -0,0 +1,178 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/kpt/pkg/client"
+	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+var databaseReader = kptfile.StatusReader{
+	Group:  "example.com",
+	Kind:   "Database",
+	Ready:  `status.phase == "Ready"`,
+	Failed: `status.phase == "Failed"`,
+}
+
+func newDatabase(name string, phase string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Database",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": testNamespace,
+			},
+		},
+	}
+	if phase != "" {
+		obj.Object["status"] = map[string]interface{}{"phase": phase}
+	}
+	return obj
+}
+
+func TestStatusRules_Status(t *testing.T) {
+	rules, err := NewStatusRules([]kptfile.StatusReader{databaseReader})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	testCases := map[string]struct {
+		obj      *unstructured.Unstructured
+		expected status.Status
+	}{
+		"ready":         {obj: newDatabase("db", "Ready"), expected: status.CurrentStatus},
+		"failed":        {obj: newDatabase("db", "Failed"), expected: status.FailedStatus},
+		"pending":       {obj: newDatabase("db", "Pending"), expected: status.InProgressStatus},
+		"no status yet": {obj: newDatabase("db", ""), expected: status.InProgressStatus},
+		"no rule":       {obj: newPlanObj("cm", "a"), expected: status.UnknownStatus},
+	}
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			actual, _ := rules.Status(tc.obj)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestNewStatusRules_Error(t *testing.T) {
+	testCases := map[string][]kptfile.StatusReader{
+		"missing kind":    {{Group: "example.com", Ready: "true"}},
+		"missing ready":   {{Group: "example.com", Kind: "Database"}},
+		"invalid ready":   {{Kind: "Database", Ready: "status.phase =="}},
+		"invalid failed":  {{Kind: "Database", Ready: "true", Failed: "("}},
+		"duplicate kinds": {databaseReader, databaseReader},
+	}
+	for tn, readers := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			_, err := NewStatusRules(readers)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadStatusReaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "status-readers-test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	// A directory without a Kptfile has no status readers.
+	readers, err := ReadStatusReaders(dir, "")
+	assert.NoError(t, err)
+	assert.Empty(t, readers)
+
+	err = ioutil.WriteFile(filepath.Join(dir, kptfile.KptFileName), []byte(`
+apiVersion: kpt.dev/v1alpha1
+kind: Kptfile
+metadata:
+  name: pkg
+statusReaders:
+- group: example.com
+  kind: Database
+  ready: status.phase == "Ready"
+`), 0600)
+	assert.NoError(t, err)
+	configFile := filepath.Join(dir, "status.yaml")
+	err = ioutil.WriteFile(configFile, []byte(`
+apiVersion: kpt.dev/v1alpha1
+kind: StatusConfig
+statusReaders:
+- group: example.com
+  kind: Bucket
+  ready: has(status.url)
+`), 0600)
+	assert.NoError(t, err)
+
+	readers, err = ReadStatusReaders(dir, configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []kptfile.StatusReader{
+		{Group: "example.com", Kind: "Database", Ready: `status.phase == "Ready"`},
+		{Group: "example.com", Kind: "Bucket", Ready: `has(status.url)`},
+	}, readers)
+
+	err = ioutil.WriteFile(configFile, []byte("apiVersion: v1\nkind: ConfigMap\n"), 0600)
+	assert.NoError(t, err)
+	_, err = ReadStatusReaders("", configFile)
+	assert.Error(t, err)
+}
+
+func TestWaitForStatus(t *testing.T) {
+	rules, err := NewStatusRules([]kptfile.StatusReader{databaseReader})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ready := newDatabase("ready", "Ready")
+	pending := newDatabase("pending", "Pending")
+	failed := newDatabase("failed", "Failed")
+	cm := newPlanObj("cm", "a")
+	c := client.NewFakeClient(ready, pending, failed)
+	objMetas := func(objs ...*unstructured.Unstructured) []object.ObjMetadata {
+		var metas []object.ObjMetadata
+		for _, obj := range objs {
+			metas = append(metas, object.UnstructuredToObjMeta(obj))
+		}
+		return metas
+	}
+
+	// Objects without a status reader are not waited for, even if they
+	// do not exist.
+	err = WaitForStatus(context.Background(), c, rules, objMetas(ready, cm), time.Millisecond)
+	assert.NoError(t, err)
+
+	err = WaitForStatus(context.Background(), c, rules, objMetas(ready, failed), time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/failed failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = WaitForStatus(ctx, c, rules, objMetas(ready, pending), time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "pending")
+		assert.NotContains(t, err.Error(), "/ready")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// TargetsKind is the kind of the targets file, which lists the clusters
+// a package is applied to.
+const TargetsKind = "Targets"
+
+// Target is a cluster a package is applied to. The cluster is selected
+// by a context of a kubeconfig file. The default kubeconfig file is used
+// if Kubeconfig is empty, and the current context if Context is empty.
+// Name identifies the target in the output, and defaults to the context.
+type Target struct {
+	Name       string `yaml:"name,omitempty"`
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+	Context    string `yaml:"context,omitempty"`
+}
+
+// targetsFile is the content of a targets file.
+type targetsFile struct {
+	yaml.ResourceMeta `yaml:",inline"`
+	Targets           []Target `yaml:"targets,omitempty"`
+}
+
+// ReadTargets returns the targets listed in the targets file, followed
+// by a target for every passed context of the default kubeconfig file.
+// Returns an error if the file is invalid, or two targets have the same
+// name.
+func ReadTargets(file string, contexts []string) ([]Target, error) {
+	var targets []Target
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		content := targetsFile{}
+		d := yaml.NewDecoder(f)
+		d.KnownFields(true)
+		if err := d.Decode(&content); err != nil {
+			return nil, fmt.Errorf("unable to parse targets file %s: %v", file, err)
+		}
+		if content.Kind != TargetsKind {
+			return nil, fmt.Errorf("unable to parse targets file %s: kind must be %s", file, TargetsKind)
+		}
+		targets = append(targets, content.Targets...)
+	}
+	for _, context := range contexts {
+		targets = append(targets, Target{Context: context})
+	}
+	names := map[string]bool{}
+	for i := range targets {
+		if targets[i].Name == "" {
+			targets[i].Name = targets[i].Context
+		}
+		if targets[i].Name == "" {
+			return nil, fmt.Errorf("target %d requires a name or a context", i+1)
+		}
+		if names[targets[i].Name] {
+			return nil, fmt.Errorf("duplicate target %q", targets[i].Name)
+		}
+		names[targets[i].Name] = true
+	}
+	return targets, nil
+}
+
+// Factory returns a factory for the cluster of the target.
+func (t Target) Factory() cmdutil.Factory {
+	flags := genericclioptions.NewConfigFlags(true)
+	if t.Kubeconfig != "" {
+		kubeconfig := t.Kubeconfig
+		flags.KubeConfig = &kubeconfig
+	}
+	if t.Context != "" {
+		context := t.Context
+		flags.Context = &context
+	}
+	return cmdutil.NewFactory(cmdutil.NewMatchVersionFlags(flags))
+}
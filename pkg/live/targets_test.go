@@ -0,0 +1,95 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTargets(t *testing.T) {
+	testCases := map[string]struct {
+		file     string
+		contexts []string
+		expected []Target
+		isError  bool
+	}{
+		"Contexts are targets of the default kubeconfig": {
+			contexts: []string{"dev", "prod"},
+			expected: []Target{{Name: "dev", Context: "dev"}, {Name: "prod", Context: "prod"}},
+		},
+		"Targets file is read before the contexts": {
+			file: `
+apiVersion: kpt.dev/v1alpha1
+kind: Targets
+targets:
+- name: us
+  kubeconfig: /tmp/us.yaml
+- context: eu
+`,
+			contexts: []string{"dev"},
+			expected: []Target{
+				{Name: "us", Kubeconfig: "/tmp/us.yaml"},
+				{Name: "eu", Context: "eu"},
+				{Name: "dev", Context: "dev"},
+			},
+		},
+		"Targets file must have the Targets kind": {
+			file:    "apiVersion: v1\nkind: ConfigMap\n",
+			isError: true,
+		},
+		"Unknown fields are an error": {
+			file:    "kind: Targets\ntargets:\n- cluster: us\n",
+			isError: true,
+		},
+		"Targets require a name or a context": {
+			file:    "kind: Targets\ntargets:\n- kubeconfig: /tmp/us.yaml\n",
+			isError: true,
+		},
+		"Duplicate targets are an error": {
+			file:     "kind: Targets\ntargets:\n- context: dev\n",
+			contexts: []string{"dev"},
+			isError:  true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var file string
+			if tc.file != "" {
+				dir, err := ioutil.TempDir("", "targets-test")
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				defer os.RemoveAll(dir)
+				file = filepath.Join(dir, "targets.yaml")
+				if !assert.NoError(t, ioutil.WriteFile(file, []byte(tc.file), 0600)) {
+					t.FailNow()
+				}
+			}
+			targets, err := ReadTargets(file, tc.contexts)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, targets)
+		})
+	}
+}
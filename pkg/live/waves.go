@@ -0,0 +1,87 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+)
+
+// ApplyWaveAnnotation assigns an object to an apply wave. Waves are
+// applied in increasing order, and each wave is reconciled before the
+// next wave starts. The value is an integer, and objects without the
+// annotation belong to wave 0.
+const ApplyWaveAnnotation = "config.kubernetes.io/apply-wave"
+
+// ParseWave returns the apply wave of the passed object.
+func ParseWave(obj *unstructured.Unstructured) (int, error) {
+	value, found := obj.GetAnnotations()[ApplyWaveAnnotation]
+	if !found {
+		return 0, nil
+	}
+	wave, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation on %s/%s: %q is not an integer",
+			ApplyWaveAnnotation, obj.GetNamespace(), obj.GetName(), value)
+	}
+	return wave, nil
+}
+
+// Waves returns the distinct apply waves of the passed objects in
+// increasing order. Inventory objects do not belong to any wave.
+func Waves(objs []*unstructured.Unstructured) ([]int, error) {
+	seen := map[int]bool{}
+	var waves []int
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) {
+			continue
+		}
+		wave, err := ParseWave(obj)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[wave] {
+			seen[wave] = true
+			waves = append(waves, wave)
+		}
+	}
+	sort.Ints(waves)
+	return waves, nil
+}
+
+// FilterByWave returns the objects in the passed wave or an earlier
+// wave. Inventory objects are always returned.
+func FilterByWave(objs []*unstructured.Unstructured, maxWave int) ([]*unstructured.Unstructured, error) {
+	var filtered []*unstructured.Unstructured
+	for _, obj := range objs {
+		if inventory.IsInventoryObject(obj) {
+			filtered = append(filtered, obj)
+			continue
+		}
+		wave, err := ParseWave(obj)
+		if err != nil {
+			return nil, err
+		}
+		if wave <= maxWave {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered, nil
+}
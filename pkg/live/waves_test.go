@@ -0,0 +1,105 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newWaveObj(name, wave string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": testNamespace,
+			},
+		},
+	}
+	if wave != "" {
+		obj.SetAnnotations(map[string]string{ApplyWaveAnnotation: wave})
+	}
+	return obj
+}
+
+func TestParseWave(t *testing.T) {
+	testCases := map[string]struct {
+		wave     string
+		expected int
+		isError  bool
+	}{
+		"No annotation is wave 0": {
+			wave:     "",
+			expected: 0,
+		},
+		"Positive wave": {
+			wave:     "2",
+			expected: 2,
+		},
+		"Negative wave": {
+			wave:     " -1 ",
+			expected: -1,
+		},
+		"Non-integer wave is an error": {
+			wave:    "first",
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			wave, err := ParseWave(newWaveObj("cm", tc.wave))
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, wave)
+		})
+	}
+}
+
+func TestWavesAndFilterByWave(t *testing.T) {
+	a := newWaveObj("a", "2")
+	b := newWaveObj("b", "")
+	c := newWaveObj("c", "-1")
+	d := newWaveObj("d", "2")
+	inv := inventoryObj.DeepCopy()
+	inv.SetAnnotations(map[string]string{ApplyWaveAnnotation: "5"})
+	objs := []*unstructured.Unstructured{a, b, c, d, inv}
+
+	waves, err := Waves(objs)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{-1, 0, 2}, waves)
+
+	filtered, err := FilterByWave(objs, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*unstructured.Unstructured{c, inv}, filtered)
+
+	filtered, err = FilterByWave(objs, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*unstructured.Unstructured{b, c, inv}, filtered)
+
+	filtered, err = FilterByWave(objs, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, objs, filtered)
+
+	_, err = Waves([]*unstructured.Unstructured{newWaveObj("e", "x")})
+	assert.Error(t, err)
+}
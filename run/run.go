@@ -26,8 +26,10 @@ import (
 	kptcommands "github.com/GoogleContainerTools/kpt/commands"
 	"github.com/GoogleContainerTools/kpt/internal/cmdcomplete"
 	"github.com/GoogleContainerTools/kpt/internal/docs/generated/overview"
+	"github.com/GoogleContainerTools/kpt/internal/gitutil"
 	"github.com/GoogleContainerTools/kpt/internal/util/cfgflags"
 	"github.com/GoogleContainerTools/kpt/internal/util/cmdutil"
+	"github.com/GoogleContainerTools/kpt/internal/util/containerruntime"
 	kptopenapi "github.com/GoogleContainerTools/kpt/internal/util/openapi"
 	"github.com/GoogleContainerTools/kpt/pkg/kptfile"
 	"github.com/spf13/cobra"
@@ -43,6 +45,7 @@ var pgr []string
 func GetMain() *cobra.Command {
 	os.Setenv(commandutil.EnableAlphaCommmandsEnvName, "true")
 	installComp := false
+	var containerRuntime string
 	cmd := &cobra.Command{
 		Use:          "kpt",
 		Short:        overview.ReferenceShort,
@@ -88,7 +91,13 @@ func GetMain() *cobra.Command {
 			return err
 		}
 
-		return nil
+		// apply the proxies and certificate authorities of the kpt config
+		if err := gitutil.ConfigureNetwork(); err != nil {
+			return err
+		}
+
+		// run function containers with the selected container runtime
+		return containerruntime.Use(containerRuntime)
 	}
 
 	cmd.Flags().BoolVar(&installComp, "install-completion", false,
@@ -135,6 +144,9 @@ func GetMain() *cobra.Command {
 	// enable stack traces
 	cmd.PersistentFlags().BoolVar(&cmdutil.StackOnError, "stack-trace", false,
 		"print a stack-trace on failure")
+	cmd.PersistentFlags().StringVar(&containerRuntime, "container-runtime", "",
+		"container runtime to run functions with: docker, podman or nerdctl. "+
+			"Defaults to $"+containerruntime.Env+", or the first found on the PATH.")
 
 	cmd.PersistentFlags().StringVar(&cmdutil.K8sSchemaSource, "k8s-schema-source",
 		kptopenapi.SchemaSourceBuiltin, "source for the kubernetes openAPI schema")